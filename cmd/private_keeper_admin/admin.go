@@ -0,0 +1,7 @@
+package main
+
+import "github.com/DenisKhanov/PrivateKeeperV2/internal/app/admin"
+
+func main() {
+	admin.Run()
+}
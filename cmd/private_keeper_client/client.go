@@ -1,7 +1,16 @@
 package main
 
-import "github.com/DenisKhanov/PrivateKeeperV2/internal/app/client"
+import (
+	"os"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/app/client"
+)
 
 func main() {
+	// With arguments the client runs a single command for scripts, otherwise the interactive menu
+	if len(os.Args) > 1 {
+		os.Exit(client.RunCommand(os.Args[1:]))
+	}
+
 	client.Run()
 }
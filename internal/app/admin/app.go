@@ -0,0 +1,162 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/logcfg"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/cache"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/config"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/encryption"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/keyrotation"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/storage/postgresql"
+	userRepository "github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/repository"
+)
+
+const usage = `Usage: private_keeper_admin <command> [flags]
+
+Commands:
+  rewrap-master-key   re-wrap all user keys with the current master key version
+  rotate-user-key     rotate the key of one user (-user) or of all users with an expired key (-expired)
+`
+
+// Run executes the administrative command given in the command line arguments.
+// It uses the same configuration as the server.
+func Run() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		log.Println("Failed to initialize config", err.Error())
+		os.Exit(1)
+	}
+	logFileName := "keeperAdmin.log"
+	logcfg.RunLoggerConfig(cfg.EnvLogLevel, logFileName)
+
+	switch os.Args[1] {
+	case "rewrap-master-key":
+		err = rewrapMasterKey(cfg, os.Args[2:])
+	case "rotate-user-key":
+		err = rotateUserKey(cfg, os.Args[2:])
+	default:
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Command failed")
+		fmt.Println("Command failed:", err)
+		os.Exit(1)
+	}
+}
+
+// rewrapMasterKey re-wraps every user key not wrapped with the current master key version.
+func rewrapMasterKey(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("rewrap-master-key", flag.ExitOnError)
+	batchSize := flags.Int("batch", 100, "number of users processed per batch")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("parse flags: %w", err)
+	}
+
+	cryptService, err := encryption.NewKeyring(cfg.MasterKeys, cfg.MasterKeyVersion)
+	if err != nil {
+		return fmt.Errorf("init crypt service: %w", err)
+	}
+
+	ctx := context.Background()
+	postgresPool, err := initPostgresPool(ctx, cfg.DatabaseURI)
+	if err != nil {
+		return err
+	}
+	defer postgresPool.DB.Close()
+
+	rewrapper := keyrotation.NewMasterKeyRewrapper(userRepository.New(postgresPool), cryptService)
+	result, err := rewrapper.Rewrap(ctx, *batchSize)
+	fmt.Printf("Master key version %d: rewrapped %d, skipped %d, failed %d\n",
+		cryptService.Version(), result.Rewrapped, result.Skipped, result.Failed)
+	if err != nil {
+		return fmt.Errorf("rewrap: %w", err)
+	}
+	if result.Failed != 0 {
+		return fmt.Errorf("%d user keys could not be rewrapped", result.Failed)
+	}
+
+	return nil
+}
+
+// rotateUserKey rotates the key of the given user or of all users whose key is older than the configured maximum age.
+func rotateUserKey(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("rotate-user-key", flag.ExitOnError)
+	userID := flags.String("user", "", "id of the user whose key must be rotated")
+	expired := flags.Bool("expired", false, "rotate keys of all users with a key older than USER_KEY_MAX_AGE_HOURS")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("parse flags: %w", err)
+	}
+	if (*userID == "") == !*expired {
+		return errors.New("exactly one of -user or -expired must be set")
+	}
+
+	cryptService, err := encryption.NewKeyring(cfg.MasterKeys, cfg.MasterKeyVersion)
+	if err != nil {
+		return fmt.Errorf("init crypt service: %w", err)
+	}
+
+	redis, err := cache.NewRedis(cfg.RedisURL, cfg.RedisPassword, cfg.RedisDB, cfg.RedisTimeoutSec)
+	if err != nil {
+		return fmt.Errorf("init redis: %w", err)
+	}
+
+	ctx := context.Background()
+	postgresPool, err := initPostgresPool(ctx, cfg.DatabaseURI)
+	if err != nil {
+		return err
+	}
+	defer postgresPool.DB.Close()
+
+	rotator := keyrotation.NewUserKeyRotator(userRepository.New(postgresPool), cryptService, redis, cfg.UserKeyRotationBatchSize)
+	if *expired {
+		rotated, err := rotator.RotateExpired(ctx, time.Duration(cfg.UserKeyMaxAgeHours)*time.Hour)
+		fmt.Printf("Rotated %d user keys\n", rotated)
+		return err
+	}
+
+	count, err := rotator.Rotate(ctx, *userID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Rotated key of user %s, re-encrypted %d records\n", *userID, count)
+
+	return nil
+}
+
+// initPostgresPool connects to the database and applies pending migrations.
+func initPostgresPool(ctx context.Context, databaseURI string) (*postgresql.PostgresPool, error) {
+	connCtx, cancel := context.WithTimeout(ctx, time.Second*2)
+	defer cancel()
+
+	postgresPool, err := postgresql.NewPool(connCtx, databaseURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	migrations, err := postgresql.NewMigrations(postgresPool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize migrations: %w", err)
+	}
+
+	if err = migrations.Up(); err != nil {
+		return nil, fmt.Errorf("failed to up migrations: %w", err)
+	}
+
+	return postgresPool, nil
+}
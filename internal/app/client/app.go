@@ -8,31 +8,171 @@ import (
 	"github.com/sirupsen/logrus"
 	"log"
 	"os"
+	"os/exec"
+	"time"
 
 	"github.com/fatih/color"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/backup"
+	backupservice "github.com/DenisKhanov/PrivateKeeperV2/internal/client/backup/service"
 	binarypb "github.com/DenisKhanov/PrivateKeeperV2/internal/client/binary_data/pbclient"
 	binaryservice "github.com/DenisKhanov/PrivateKeeperV2/internal/client/binary_data/service"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/breach"
+	breachpb "github.com/DenisKhanov/PrivateKeeperV2/internal/client/breach/pbclient"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/cli"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/clipboard"
+	collectionpb "github.com/DenisKhanov/PrivateKeeperV2/internal/client/collection/pbclient"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/config"
+	credentialsoffline "github.com/DenisKhanov/PrivateKeeperV2/internal/client/credentials/offline"
 	credentialspb "github.com/DenisKhanov/PrivateKeeperV2/internal/client/credentials/pbclient"
 	credentialsservice "github.com/DenisKhanov/PrivateKeeperV2/internal/client/credentials/service"
+	creditcardoffline "github.com/DenisKhanov/PrivateKeeperV2/internal/client/credit_card/offline"
 	creditcardpb "github.com/DenisKhanov/PrivateKeeperV2/internal/client/credit_card/pbclient"
 	creditcardservice "github.com/DenisKhanov/PrivateKeeperV2/internal/client/credit_card/service"
+	customdatapb "github.com/DenisKhanov/PrivateKeeperV2/internal/client/custom_data/pbclient"
+	customdataservice "github.com/DenisKhanov/PrivateKeeperV2/internal/client/custom_data/service"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/encryption"
+	generatorservice "github.com/DenisKhanov/PrivateKeeperV2/internal/client/generator/service"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/health"
+	healthservice "github.com/DenisKhanov/PrivateKeeperV2/internal/client/health/service"
+	searchpb "github.com/DenisKhanov/PrivateKeeperV2/internal/client/search/pbclient"
+	searchservice "github.com/DenisKhanov/PrivateKeeperV2/internal/client/search/service"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/session"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+	syncpb "github.com/DenisKhanov/PrivateKeeperV2/internal/client/sync/pbclient"
+	textdataoffline "github.com/DenisKhanov/PrivateKeeperV2/internal/client/text_data/offline"
 	textdatapb "github.com/DenisKhanov/PrivateKeeperV2/internal/client/text_data/pbclient"
 	textdataservice "github.com/DenisKhanov/PrivateKeeperV2/internal/client/text_data/service"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/tui"
 	userpb "github.com/DenisKhanov/PrivateKeeperV2/internal/client/user/pbclient"
 	userservice "github.com/DenisKhanov/PrivateKeeperV2/internal/client/user/service"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/vault"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/proto/binary_data"
+	breachGrpc "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/breach"
+	collectionGrpc "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/collection"
 	credGrpc "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/credentials"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/proto/credit_card"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/proto/custom_data"
+	searchGrpc "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/search"
+	syncGrpc "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/sync"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/proto/text_data"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/proto/user"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/tlsconfig"
+	breachlist "github.com/DenisKhanov/PrivateKeeperV2/pkg/breach"
 )
 
+// app holds the clients of the services wired together with the client state.
+type app struct {
+	state             *state.ClientState
+	creditCardClient  *creditcardoffline.CreditCardClient
+	textDataClient    *textdataoffline.TextDataClient
+	credentialsClient *credentialsoffline.CredentialsClient
+	binaryClient      *binarypb.BinaryDataPBClient
+	customDataClient  *customdatapb.CustomDataPBClient
+	searchClient      *searchpb.SearchPBClient
+	collectionClient  *collectionpb.CollectionPBClient
+	breachChecker     *breach.Checker
+	userClient        *userpb.UserPBClient
+	userService       *userservice.UserProvider
+	refresher         *session.Refresher
+}
+
+// newApp initializes TLS and the gRPC connection to the server and wires the clients of all services.
+func newApp(cfg *config.Config) (*app, error) {
+	tls, err := tlsconfig.NewClientTLS(cfg.ClientCert, cfg.ClientKey, cfg.ClientCa)
+	if err != nil {
+		return nil, fmt.Errorf("initialize tls: %w", err)
+	}
+
+	clientState := state.NewClientState()
+	refresher := session.NewRefresher(clientState)
+
+	grpcClient, err := grpc.NewClient(cfg.GRPCServer,
+		grpc.WithTransportCredentials(credentials.NewTLS(tls)),
+		grpc.WithChainUnaryInterceptor(refresher.Unary),
+		grpc.WithChainStreamInterceptor(refresher.Stream))
+	if err != nil {
+		return nil, fmt.Errorf("initialize grpcClient: %w", err)
+	}
+
+	conflictPolicy, err := vault.ParseConflictPolicy(cfg.SyncConflictPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("initialize sync conflict policy: %w", err)
+	}
+
+	cipher := encryption.New(clientState)
+
+	a := &app{state: clientState, refresher: refresher}
+	a.creditCardClient = creditcardoffline.NewCreditCardClient(
+		creditcardpb.NewCreditCardPBClient(credit_card.NewCreditCardServiceClient(grpcClient), cipher), clientState, conflictPolicy)
+	a.textDataClient = textdataoffline.NewTextDataClient(
+		textdatapb.NewTextDataPBClient(text_data.NewTextDataServiceClient(grpcClient), cipher), clientState, conflictPolicy)
+	a.credentialsClient = credentialsoffline.NewCredentialsClient(
+		credentialspb.NewCredentialsPBClient(credGrpc.NewCredentialsServiceClient(grpcClient), cipher), clientState, conflictPolicy)
+
+	syncer := vault.NewSyncer(map[string]vault.Replayer{
+		"credit_card": a.creditCardClient,
+		"text_data":   a.textDataClient,
+		"credentials": a.credentialsClient,
+	}, syncpb.NewSyncPBClient(syncGrpc.NewSyncServiceClient(grpcClient)))
+
+	a.userClient = userpb.NewUserPBClient(user.NewUserServiceClient(grpcClient))
+	a.userService = userservice.NewUserService(a.userClient, clientState, cfg.E2E, cfg.VaultDir, syncer,
+		session.NewFile(cfg.SessionFile))
+	a.binaryClient = binarypb.NewBinaryDataPBClient(binary_data.NewBinaryDataServiceClient(grpcClient), cipher)
+	a.customDataClient = customdatapb.NewCustomDataPBClient(custom_data.NewCustomDataServiceClient(grpcClient), cipher)
+	a.searchClient = searchpb.NewSearchPBClient(searchGrpc.NewSearchServiceClient(grpcClient))
+	a.collectionClient = collectionpb.NewCollectionPBClient(collectionGrpc.NewCollectionServiceClient(grpcClient), cipher)
+
+	var breachList breachlist.Ranger
+	if cfg.BreachList != "" {
+		if breachList, err = breachlist.Open(cfg.BreachList); err != nil {
+			return nil, fmt.Errorf("initialize breach list: %w", err)
+		}
+	}
+	a.breachChecker = breach.NewChecker(breachList, breachpb.NewBreachPBClient(breachGrpc.NewBreachServiceClient(grpcClient)))
+
+	return a, nil
+}
+
+// RunCommand runs a single command of the non-interactive command line interface given by the arguments
+// and returns its exit code. The log is written to the log file only, so the output can be parsed by scripts.
+func RunCommand(args []string) int {
+	ctx := context.Background()
+	cfg, err := config.New()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "keeper: failed to initialize config:", err)
+		return cli.ExitError
+	}
+
+	logcfg.RunFileLoggerConfig(cfg.EnvLogLevel, "keeperClient.log")
+
+	a, err := newApp(cfg)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize client")
+		fmt.Fprintln(os.Stderr, "keeper: failed to initialize client:", err)
+		return cli.ExitError
+	}
+
+	return cli.New(cli.Services{
+		Auth:        a.userService,
+		Sessions:    a.userClient,
+		CreditCards: a.creditCardClient,
+		TextData:    a.textDataClient,
+		Credentials: a.credentialsClient,
+		Binaries:    a.binaryClient,
+		Search:      a.searchClient,
+		Breaches:    a.breachChecker,
+		Health: health.New(health.Services{
+			Credentials: a.credentialsClient,
+			CreditCards: a.creditCardClient,
+		}),
+		Collections: a.collectionClient,
+	}, a.state, os.Stdin, os.Stdout, os.Stderr).Run(ctx, args)
+}
+
 // Run initializes the client application, configures necessary services,
 // and provides an interactive command-line interface for the user.
 //
@@ -41,7 +181,7 @@ import (
 // - Configures logging to a specified log file.
 // - Initializes TLS for secure gRPC communication with the server.
 // - Establishes a gRPC client connection for communicating with various services.
-// - Sets up client-side state management and initializes service clients (user, credit card, text data, credentials, and binary data).
+// - Sets up client-side state management and initializes service clients (user, credit card, text data, credentials, binary data and custom data).
 // - Enters an interactive loop where the user can issue commands to perform various actions such as login, register, save, and load data.
 //
 // The user can interact with the application via the console input where different numbered options correspond to different functionalities.
@@ -58,48 +198,84 @@ func Run() {
 	logFileName := "keeperClient.log"
 	logcfg.RunLoggerConfig(cfg.EnvLogLevel, logFileName)
 
-	tls, err := tlsconfig.NewClientTLS(cfg.ClientCert, cfg.ClientKey, cfg.ClientCa)
+	a, err := newApp(cfg)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to initialize tls")
+		logrus.WithError(err).Error("Failed to initialize client")
 		os.Exit(1)
 	}
 
-	grpcClient, err := grpc.NewClient(cfg.GRPCServer, grpc.WithTransportCredentials(credentials.NewTLS(tls)))
+	clipBackend, err := clipboard.Detect(cfg.Clipboard, os.Getenv, exec.LookPath, os.Stdout)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to initialize grpcClient")
+		logrus.WithError(err).Error("Failed to initialize clipboard")
 		os.Exit(1)
 	}
+	clip := clipboard.New(clipBackend, time.Duration(cfg.ClipboardClearSecs)*time.Second)
+	defer func() {
+		if err = clip.Close(); err != nil {
+			logrus.WithError(err).Error("Failed to clear the clipboard")
+		}
+	}()
 
-	clientState := state.NewClientState()
-
-	userClient := userpb.NewUserPBClient(user.NewUserServiceClient(grpcClient))
-	userService := userservice.NewUserService(userClient, clientState)
-
-	creditCardClient := creditcardpb.NewCreditCardPBClient(credit_card.NewCreditCardServiceClient(grpcClient))
-	creditCardService := creditcardservice.NewUserService(creditCardClient, clientState)
-
-	textDataClient := textdatapb.NewTextDataPBClient(text_data.NewTextDataServiceClient(grpcClient))
-	textDataService := textdataservice.NewTextDataService(textDataClient, clientState)
-
-	credentialsClient := credentialspb.NewCredentialsPBClient(credGrpc.NewCredentialsServiceClient(grpcClient))
-	credentialsService := credentialsservice.NewCredentialsService(credentialsClient, clientState)
+	clientState := a.state
+	userService := a.userService
+	a.refresher.SetHooks(session.Hooks{
+		Refreshed: userService.SaveSession,
+		Expired:   userService.ExpireSession,
+	})
 
-	binaryClient := binarypb.NewBinaryDataPBClient(binary_data.NewBinaryDataServiceClient(grpcClient))
-	binaryService := binaryservice.NewBinaryDataService(binaryClient, clientState)
+	creditCardService := creditcardservice.NewUserService(a.creditCardClient, clientState, clip)
+	textDataService := textdataservice.NewTextDataService(a.textDataClient, clientState)
+	credentialsService := credentialsservice.NewCredentialsService(a.credentialsClient, clientState, clip, a.breachChecker)
+	binaryService := binaryservice.NewBinaryDataService(a.binaryClient, clientState)
+	customDataService := customdataservice.NewCustomDataService(a.customDataClient, clientState, clip)
+	searchService := searchservice.NewSearchService(a.searchClient, clientState)
+	generatorService := generatorservice.NewGeneratorService(clip)
+	backupService := backupservice.NewBackupService(backup.New(backup.Services{
+		CreditCards: a.creditCardClient,
+		TextData:    a.textDataClient,
+		Credentials: a.credentialsClient,
+		Binaries:    a.binaryClient,
+	}), clientState)
+	healthService := healthservice.NewHealthService(health.New(health.Services{
+		Credentials: a.credentialsClient,
+		CreditCards: a.creditCardClient,
+	}), clientState)
+	fullScreen := tui.NewUI(tui.Services{
+		CreditCards: a.creditCardClient,
+		TextData:    a.textDataClient,
+		Credentials: a.credentialsClient,
+		Binaries:    a.binaryClient,
+	}, clientState)
 
 	scanner := bufio.NewScanner(os.Stdin)
 
 	blue := color.New(color.FgBlue).SprintFunc()
 	red := color.New(color.FgRed).SprintFunc()
 	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	userService.RestoreSession(ctx)
 
 	for {
+		userService.CheckSession(ctx)
+
 		if clientState.IsAuthorized() {
 			fmt.Printf(green("\nYou are authorized as %s\n"), blue(clientState.GetLogin()))
 		} else {
 			fmt.Printf(red("\nYou are not authorized, please login or register\n\n"))
 		}
 
+		if clientState.IsOffline() {
+			fmt.Println(yellow("Server is unreachable, working offline with the local vault"))
+		}
+
+		if v := clientState.GetVault(); v != nil && len(v.Pending()) != 0 {
+			fmt.Printf(yellow("%d offline changes wait for sync\n"), len(v.Pending()))
+		}
+		if v := clientState.GetVault(); v != nil && len(v.Rejected()) != 0 {
+			fmt.Printf(red("%d offline changes were rejected by the server, see [45]\n"), len(v.Rejected()))
+		}
+
 		if clientState.GetDirPath() == "" {
 			fmt.Printf(red("Working directory is not set \n\n"))
 		} else {
@@ -113,20 +289,52 @@ func Run() {
 		fmt.Println("[3] - save credit card")
 		fmt.Println("[4] - load all credit cards information")
 		fmt.Println("[5] - load credit card data")
+		fmt.Println("[6] - update credit card")
+		fmt.Println("[7] - delete credit card")
+		fmt.Println(blue("---------------------------------------------"))
+		fmt.Println("[8] - save text data")
+		fmt.Println("[9] - load all text data information")
+		fmt.Println("[10] - load text data")
+		fmt.Println("[11] - update text data")
+		fmt.Println("[12] - delete text data")
 		fmt.Println(blue("---------------------------------------------"))
-		fmt.Println("[6] - save text data")
-		fmt.Println("[7] - load all text data information")
-		fmt.Println("[8] - load text data")
+		fmt.Println("[13] - save credentials")
+		fmt.Println("[14] - load all credentials information")
+		fmt.Println("[15] - load credentials data")
+		fmt.Println("[16] - update credentials")
+		fmt.Println("[17] - delete credentials")
 		fmt.Println(blue("---------------------------------------------"))
-		fmt.Println("[9] - save credentials")
-		fmt.Println("[10] - load all credentials information")
-		fmt.Println("[11] - load credentials data")
+		fmt.Println("[18] - save binary file")
+		fmt.Println("[19] - load all binary files information")
+		fmt.Println("[20] - load binary file")
+		fmt.Println("[21] - update binary file")
+		fmt.Println("[22] - delete binary file")
 		fmt.Println(blue("---------------------------------------------"))
-		fmt.Println("[12] - save binary file")
-		fmt.Println("[13] - load all binary files information")
-		fmt.Println("[14] - load binary file")
+		fmt.Println("[23] - set working directory")
+		fmt.Println("[24] - rotate encryption key")
+		fmt.Println("[25] - sync offline changes")
+		fmt.Println("[26] - logout")
+		fmt.Println("[27] - logout on all devices")
+		fmt.Println("[28] - enable two-factor authentication")
+		fmt.Println("[29] - disable two-factor authentication")
+		fmt.Println("[30] - search items")
+		fmt.Println("[31] - open full-screen interface")
+		fmt.Println("[32] - export vault")
+		fmt.Println("[33] - import vault")
+		fmt.Println("[34] - import from another password manager")
 		fmt.Println(blue("---------------------------------------------"))
-		fmt.Println("[15] - set working directory")
+		fmt.Println("[35] - save item template")
+		fmt.Println("[36] - load item templates")
+		fmt.Println("[37] - delete item template")
+		fmt.Println("[38] - save custom item")
+		fmt.Println("[39] - load all custom items information")
+		fmt.Println("[40] - load custom item")
+		fmt.Println("[41] - update custom item")
+		fmt.Println("[42] - delete custom item")
+		fmt.Println(blue("---------------------------------------------"))
+		fmt.Println("[43] - generate password")
+		fmt.Println("[44] - vault health report")
+		fmt.Println("[45] - rejected offline changes")
 		fmt.Println(blue("------------"))
 		fmt.Println(red("[0] - quit"), blue("|"))
 		fmt.Println(blue("------------"))
@@ -145,25 +353,86 @@ func Run() {
 		case "5":
 			creditCardService.LoadData(ctx)
 		case "6":
-			textDataService.Save(ctx)
+			creditCardService.Update(ctx)
 		case "7":
-			textDataService.LoadAllInfo(ctx)
+			creditCardService.Delete(ctx)
 		case "8":
-			textDataService.LoadData(ctx)
+			textDataService.Save(ctx)
 		case "9":
-			credentialsService.Save(ctx)
+			textDataService.LoadAllInfo(ctx)
 		case "10":
-			credentialsService.LoadAllInfo(ctx)
+			textDataService.LoadData(ctx)
 		case "11":
-			credentialsService.LoadData(ctx)
+			textDataService.Update(ctx)
 		case "12":
-			binaryService.Save(ctx)
+			textDataService.Delete(ctx)
 		case "13":
-			binaryService.LoadAllInfo(ctx)
+			credentialsService.Save(ctx)
 		case "14":
-			binaryService.LoadData(ctx)
+			credentialsService.LoadAllInfo(ctx)
 		case "15":
+			credentialsService.LoadData(ctx)
+		case "16":
+			credentialsService.Update(ctx)
+		case "17":
+			credentialsService.Delete(ctx)
+		case "18":
+			binaryService.Save(ctx)
+		case "19":
+			binaryService.LoadAllInfo(ctx)
+		case "20":
+			binaryService.LoadData(ctx)
+		case "21":
+			binaryService.Update(ctx)
+		case "22":
+			binaryService.Delete(ctx)
+		case "23":
 			clientState.SetWorkingDirectory()
+			userService.SaveSession()
+		case "24":
+			userService.RotateKey(ctx)
+		case "25":
+			userService.Sync(ctx)
+		case "26":
+			userService.Logout(ctx)
+		case "27":
+			userService.LogoutAll(ctx)
+		case "28":
+			userService.EnableTOTP(ctx)
+		case "29":
+			userService.DisableTOTP(ctx)
+		case "30":
+			searchService.Search(ctx)
+		case "31":
+			fullScreen.Run(ctx)
+		case "32":
+			backupService.ExportVault(ctx)
+		case "33":
+			backupService.ImportVault(ctx)
+		case "34":
+			backupService.ImportFrom(ctx)
+		case "35":
+			customDataService.SaveTemplate(ctx)
+		case "36":
+			customDataService.LoadTemplates(ctx)
+		case "37":
+			customDataService.DeleteTemplate(ctx)
+		case "38":
+			customDataService.Save(ctx)
+		case "39":
+			customDataService.LoadAllInfo(ctx)
+		case "40":
+			customDataService.LoadData(ctx)
+		case "41":
+			customDataService.Update(ctx)
+		case "42":
+			customDataService.Delete(ctx)
+		case "43":
+			generatorService.Generate(ctx)
+		case "44":
+			healthService.Report(ctx)
+		case "45":
+			userService.RejectedChanges(ctx)
 		case "0":
 			fmt.Println("Application shutdown.")
 			return
@@ -16,14 +16,27 @@ import (
 	"google.golang.org/grpc/reflection"
 
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/proto/binary_data"
+	breachpb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/breach"
+	collectionpb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/collection"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/proto/credentials"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/proto/credit_card"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/proto/custom_data"
+	generatorpb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/generator"
+	searchpb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/search"
+	syncpb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/sync"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/proto/text_data"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/proto/user"
 	binaryDataGRPCHandlers "github.com/DenisKhanov/PrivateKeeperV2/internal/server/binary_data/api/v1/grpchandlers"
 	binaryDataValidation "github.com/DenisKhanov/PrivateKeeperV2/internal/server/binary_data/api/v1/validation"
 	binaryDataService "github.com/DenisKhanov/PrivateKeeperV2/internal/server/binary_data/service"
+	breachGRPCHandlers "github.com/DenisKhanov/PrivateKeeperV2/internal/server/breach/api/v1/grpchandlers"
+	breachValidation "github.com/DenisKhanov/PrivateKeeperV2/internal/server/breach/api/v1/validation"
+	breachService "github.com/DenisKhanov/PrivateKeeperV2/internal/server/breach/service"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/cache"
+	collectionGRPCHandlers "github.com/DenisKhanov/PrivateKeeperV2/internal/server/collection/api/v1/grpchandlers"
+	collectionValidation "github.com/DenisKhanov/PrivateKeeperV2/internal/server/collection/api/v1/validation"
+	collectionRepository "github.com/DenisKhanov/PrivateKeeperV2/internal/server/collection/repository"
+	collectionService "github.com/DenisKhanov/PrivateKeeperV2/internal/server/collection/service"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/config"
 	credentialsGRPCHandlers "github.com/DenisKhanov/PrivateKeeperV2/internal/server/credentials/api/v1/grpchandlers"
 	credentialsValidation "github.com/DenisKhanov/PrivateKeeperV2/internal/server/credentials/api/v1/validation"
@@ -31,11 +44,24 @@ import (
 	creditCardGRPCHandlers "github.com/DenisKhanov/PrivateKeeperV2/internal/server/credit_card/api/v1/grpchandlers"
 	creditCardValidation "github.com/DenisKhanov/PrivateKeeperV2/internal/server/credit_card/api/v1/validation"
 	creditCardService "github.com/DenisKhanov/PrivateKeeperV2/internal/server/credit_card/service"
+	customDataGRPCHandlers "github.com/DenisKhanov/PrivateKeeperV2/internal/server/custom_data/api/v1/grpchandlers"
+	customDataValidation "github.com/DenisKhanov/PrivateKeeperV2/internal/server/custom_data/api/v1/validation"
+	templateRepository "github.com/DenisKhanov/PrivateKeeperV2/internal/server/custom_data/repository"
+	customDataService "github.com/DenisKhanov/PrivateKeeperV2/internal/server/custom_data/service"
 	repository "github.com/DenisKhanov/PrivateKeeperV2/internal/server/data_repository"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/encryption"
+	generatorGRPCHandlers "github.com/DenisKhanov/PrivateKeeperV2/internal/server/generator/api/v1/grpchandlers"
+	generatorValidation "github.com/DenisKhanov/PrivateKeeperV2/internal/server/generator/api/v1/validation"
+	generatorService "github.com/DenisKhanov/PrivateKeeperV2/internal/server/generator/service"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/interceptors/auth"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/interceptors/keyextraction"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/keyrotation"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/reminder"
+	searchGRPCHandlers "github.com/DenisKhanov/PrivateKeeperV2/internal/server/search/api/v1/grpchandlers"
+	searchService "github.com/DenisKhanov/PrivateKeeperV2/internal/server/search/service"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/storage/postgresql"
+	syncGRPCHandlers "github.com/DenisKhanov/PrivateKeeperV2/internal/server/sync/api/v1/grpchandlers"
+	syncService "github.com/DenisKhanov/PrivateKeeperV2/internal/server/sync/service"
 	textDataGRPCHandlers "github.com/DenisKhanov/PrivateKeeperV2/internal/server/text_data/api/v1/grpchandlers"
 	textDataValidation "github.com/DenisKhanov/PrivateKeeperV2/internal/server/text_data/api/v1/validation"
 	textDataService "github.com/DenisKhanov/PrivateKeeperV2/internal/server/text_data/service"
@@ -44,6 +70,7 @@ import (
 	userRepository "github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/repository"
 	userService "github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/service"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/tlsconfig"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/breach"
 	"github.com/DenisKhanov/PrivateKeeperV2/pkg/jwtmanager"
 )
 
@@ -54,10 +81,11 @@ import (
 // - Configures logging for the server to a log file.
 // - Initializes Redis for caching, cryptographic services for encryption, and Postgres for database operations.
 // - Sets up JWT authentication for securing API requests.
-// - Initializes various service components including user, credit card, text data, credentials, and binary data services.
+// - Initializes various service components including user, credit card, text data, credentials, binary data and custom data services.
+// - Starts background rotation of expired user keys.
 // - Creates validators for input data for each service.
 // - Configures and starts the gRPC server with TLS encryption and authentication middleware.
-// - Registers the gRPC services (user, credit card, text data, credentials, binary data) with the server.
+// - Registers the gRPC services (user, credit card, text data, credentials, binary data, custom data, sync, search, generator, breach, collection) with the server.
 // - Sets up a TCP listener and serves the gRPC server, blocking until an error occurs or the server shuts down.
 func Run() {
 
@@ -75,7 +103,7 @@ func Run() {
 		os.Exit(1)
 	}
 
-	cryptService, err := encryption.New([]byte("master-key"))
+	cryptService, err := encryption.NewKeyring(cfg.MasterKeys, cfg.MasterKeyVersion)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to initialize crypt service")
 		os.Exit(1)
@@ -87,16 +115,57 @@ func Run() {
 		os.Exit(1)
 	}
 
-	jwtManager := jwtmanager.New(cfg.TokenName, cfg.TokenSecret, cfg.TokenExpHours)
+	jwtManager := jwtmanager.New(cfg.TokenName, cfg.TokenSecret, time.Duration(cfg.TokenExpMinutes)*time.Minute)
 
 	userRepo := userRepository.New(postgresPool)
 	dataRepo := repository.New(postgresPool)
+	templateRepo := templateRepository.New(postgresPool)
+	collectionRepo := collectionRepository.New(postgresPool)
 
-	userServ := userService.New(userRepo, cryptService, jwtManager, redis)
+	keyRotator := keyrotation.NewUserKeyRotator(userRepo, cryptService, redis, cfg.UserKeyRotationBatchSize)
+	if cfg.UserKeyMaxAgeHours > 0 {
+		go keyRotator.Run(context.Background(),
+			time.Duration(cfg.UserKeyRotationIntervalMin)*time.Minute,
+			time.Duration(cfg.UserKeyMaxAgeHours)*time.Hour)
+	}
+
+	if cfg.ExpiryReminderWindowDays > 0 {
+		notifier, err := reminder.NewNotifier(reminder.NotifierConfig{
+			Kind:         cfg.ExpiryNotifier,
+			WebhookURL:   cfg.ExpiryWebhookURL,
+			SMTPAddr:     cfg.SMTPAddr,
+			SMTPUser:     cfg.SMTPUser,
+			SMTPPassword: cfg.SMTPPassword,
+			SMTPFrom:     cfg.SMTPFrom,
+		})
+		if err != nil {
+			logrus.WithError(err).Error("Failed to initialize expiry notifier")
+			os.Exit(1)
+		}
+		expiryReminder := reminder.NewExpiryReminder(dataRepo, notifier,
+			time.Duration(cfg.ExpiryReminderWindowDays)*24*time.Hour, reminder.DefaultBatchSize)
+		go expiryReminder.Run(context.Background(), time.Duration(cfg.ExpiryReminderIntervalMin)*time.Minute)
+	}
+
+	userServ := userService.New(userRepo, cryptService, jwtManager, redis, keyRotator, time.Duration(cfg.RefreshExpHours)*time.Hour)
 	creditCardServ := creditCardService.New(dataRepo, cryptService, jwtManager)
 	textDataServ := textDataService.New(dataRepo, cryptService, jwtManager)
 	credentialServ := credentialsService.New(dataRepo, cryptService, jwtManager)
 	binaryDataServ := binaryDataService.New(dataRepo, cryptService, jwtManager)
+	customDataServ := customDataService.New(dataRepo, templateRepo, cryptService, jwtManager)
+	syncServ := syncService.New(dataRepo)
+	searchServ := searchService.New(dataRepo)
+	generatorServ := generatorService.New()
+
+	var breachList breach.Ranger
+	if cfg.BreachList != "" {
+		if breachList, err = breach.Open(cfg.BreachList); err != nil {
+			logrus.WithError(err).Error("Failed to open breach list")
+			os.Exit(1)
+		}
+	}
+	breachServ := breachService.New(breachList)
+	collectionServ := collectionService.New(collectionRepo, cryptService)
 
 	tls, err := tlsconfig.NewServerTLS(cfg.ServerCert, cfg.ServerKey, cfg.ServerCa)
 	if err != nil {
@@ -125,18 +194,30 @@ func Run() {
 		logrus.WithError(err).Error("Failed to initialize binary data validator")
 		os.Exit(1)
 	}
+	customDataValidator, err := customDataValidation.New(validate)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize custom data validator")
+		os.Exit(1)
+	}
 
-	jwtAuth := auth.New(jwtManager)
+	jwtAuth := auth.New(jwtManager, redis)
 	userKeyExtractor := keyextraction.New(cryptService, userRepo, redis)
 
 	grpcServer := grpc.NewServer(grpc.Creds(tlsCreds.NewTLS(tls)),
-		grpc.ChainUnaryInterceptor(jwtAuth.GRPCJWTAuth, userKeyExtractor.ExtractUserKey))
+		grpc.ChainUnaryInterceptor(jwtAuth.GRPCJWTAuth, userKeyExtractor.ExtractUserKey),
+		grpc.ChainStreamInterceptor(jwtAuth.GRPCJWTAuthStream, userKeyExtractor.ExtractUserKeyStream))
 
 	user.RegisterUserServiceServer(grpcServer, userGRPCHandlers.New(userServ, userValidation.New(validate)))
 	credit_card.RegisterCreditCardServiceServer(grpcServer, creditCardGRPCHandlers.New(creditCardServ, creditCardValidator))
 	text_data.RegisterTextDataServiceServer(grpcServer, textDataGRPCHandlers.New(textDataServ, textDataValidator))
 	credentials.RegisterCredentialsServiceServer(grpcServer, credentialsGRPCHandlers.New(credentialServ, credentialsValidator))
 	binary_data.RegisterBinaryDataServiceServer(grpcServer, binaryDataGRPCHandlers.New(binaryDataServ, binaryDataValidator))
+	custom_data.RegisterCustomDataServiceServer(grpcServer, customDataGRPCHandlers.New(customDataServ, customDataValidator))
+	syncpb.RegisterSyncServiceServer(grpcServer, syncGRPCHandlers.New(syncServ))
+	searchpb.RegisterSearchServiceServer(grpcServer, searchGRPCHandlers.New(searchServ))
+	generatorpb.RegisterGeneratorServiceServer(grpcServer, generatorGRPCHandlers.New(generatorServ, generatorValidation.New(validate)))
+	breachpb.RegisterBreachServiceServer(grpcServer, breachGRPCHandlers.New(breachServ, breachValidation.New(validate)))
+	collectionpb.RegisterCollectionServiceServer(grpcServer, collectionGRPCHandlers.New(collectionServ, collectionValidation.New(validate)))
 
 	reflection.Register(grpcServer)
 
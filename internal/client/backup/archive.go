@@ -0,0 +1,293 @@
+// Package backup exports the whole vault of a user into a single archive encrypted with a passphrase
+// and imports it back into the same or another account.
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/encryption"
+)
+
+const (
+	// FormatVersion is the version of the archive format written by Writer.
+	FormatVersion = 1
+
+	magic     = "PKVAULT" // Signature at the start of an archive
+	saltSize  = 16        // Size of the salt of the passphrase key
+	chunkSize = 64 * 1024 // Size of the plain chunks the archive is encrypted in
+	sealSize  = chunkSize + chacha20poly1305.NonceSizeX + chacha20poly1305.Overhead
+)
+
+var (
+	// ErrNotArchive is returned when the file is not a vault archive.
+	ErrNotArchive = errors.New("not a vault archive")
+	// ErrWrongPassphrase is returned when the archive can't be decrypted with the passphrase.
+	ErrWrongPassphrase = errors.New("wrong passphrase or damaged archive")
+	// ErrTruncated is returned when the archive ends before its last chunk.
+	ErrTruncated = errors.New("archive is truncated")
+)
+
+// Data types of the archived items.
+const (
+	TypeCreditCard  = "credit_card"
+	TypeText        = "text_data"
+	TypeCredentials = "credentials"
+	TypeBinary      = "binary_data"
+)
+
+// Header describes the archive, it is the first record in the archive.
+type Header struct {
+	Version   int    `json:"version"`
+	Login     string `json:"login"`      // Login of the exported account
+	CreatedAt string `json:"created_at"` // Time of the export, RFC 3339
+}
+
+// Item is an archived record of any data type, only the payload of its type is set.
+type Item struct {
+	Type        string       `json:"type"`
+	MetaData    string       `json:"metadata"`
+	CreatedAt   string       `json:"created_at,omitempty"`
+	Card        *Card        `json:"card,omitempty"`
+	Credentials *Credentials `json:"credentials,omitempty"`
+	Text        *Text        `json:"text,omitempty"`
+	File        *File        `json:"file,omitempty"`
+}
+
+// Card is the payload of a credit card.
+type Card struct {
+	Number    string `json:"number"`
+	OwnerName string `json:"owner_name"`
+	ExpiresAt string `json:"expires_at"`
+	CVV       string `json:"cvv"`
+	PinCode   string `json:"pin_code"`
+}
+
+// Credentials is the payload of credentials.
+type Credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	TOTP     string `json:"totp,omitempty"`
+}
+
+// Text is the payload of text data.
+type Text struct {
+	Text string `json:"text"`
+}
+
+// File is the payload of a binary file.
+type File struct {
+	Name      string `json:"name"`
+	Extension string `json:"extension"`
+	Data      []byte `json:"data"`
+}
+
+// Writer writes an encrypted archive. The records are written as a stream of JSON values split into chunks,
+// every chunk is encrypted with the passphrase key and authenticated together with its index and the flag
+// of the last chunk, so reordered, dropped or truncated chunks are detected on reading.
+type Writer struct {
+	w     io.Writer
+	key   []byte
+	buf   bytes.Buffer // Plain data not yet encrypted
+	index int          // Index of the next chunk
+	enc   *json.Encoder
+}
+
+// NewWriter starts an archive in w encrypted with the passphrase and writes its header.
+func NewWriter(w io.Writer, passphrase string, header Header) (*Writer, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+
+	if _, err := w.Write(append([]byte(magic), FormatVersion)); err != nil {
+		return nil, fmt.Errorf("write signature: %w", err)
+	}
+	if _, err := w.Write(salt); err != nil {
+		return nil, fmt.Errorf("write salt: %w", err)
+	}
+
+	aw := &Writer{w: w, key: encryption.DerivePassphraseKey(passphrase, salt)}
+	aw.enc = json.NewEncoder(&aw.buf)
+
+	header.Version = FormatVersion
+	if err := aw.enc.Encode(header); err != nil {
+		return nil, fmt.Errorf("encode header: %w", err)
+	}
+
+	return aw, nil
+}
+
+// Write adds the item to the archive.
+func (w *Writer) Write(item Item) error {
+	if err := w.enc.Encode(item); err != nil {
+		return fmt.Errorf("encode item: %w", err)
+	}
+
+	// A full chunk is kept in the buffer, so the last chunk is always written by Close
+	for w.buf.Len() > chunkSize {
+		if err := w.writeChunk(w.buf.Next(chunkSize), false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close writes the last chunk of the archive. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	return w.writeChunk(w.buf.Next(w.buf.Len()), true)
+}
+
+// writeChunk encrypts the chunk and writes it prefixed with its length.
+func (w *Writer) writeChunk(chunk []byte, last bool) error {
+	sealed, err := encryption.EncryptChunk(w.key, chunk, "", w.index, last)
+	if err != nil {
+		return fmt.Errorf("encrypt chunk %d: %w", w.index, err)
+	}
+	w.index++
+
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err = w.w.Write(size[:]); err != nil {
+		return fmt.Errorf("write chunk: %w", err)
+	}
+	if _, err = w.w.Write(sealed); err != nil {
+		return fmt.Errorf("write chunk: %w", err)
+	}
+
+	return nil
+}
+
+// Reader reads an encrypted archive written by Writer.
+type Reader struct {
+	header Header
+	dec    *json.Decoder
+}
+
+// NewReader opens the archive in r with the passphrase and reads its header.
+func NewReader(r io.Reader, passphrase string) (*Reader, error) {
+	prefix := make([]byte, len(magic)+1+saltSize)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrNotArchive
+		}
+		return nil, fmt.Errorf("read signature: %w", err)
+	}
+	if string(prefix[:len(magic)]) != magic {
+		return nil, ErrNotArchive
+	}
+	if version := int(prefix[len(magic)]); version != FormatVersion {
+		return nil, fmt.Errorf("unsupported archive version %d", version)
+	}
+
+	salt := prefix[len(magic)+1:]
+	cr := &chunkReader{r: r, key: encryption.DerivePassphraseKey(passphrase, salt)}
+	ar := &Reader{dec: json.NewDecoder(cr)}
+
+	if err := ar.dec.Decode(&ar.header); err != nil {
+		return nil, decodeError(err)
+	}
+
+	return ar, nil
+}
+
+// Header returns the header of the archive.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Next returns the next item of the archive, io.EOF after the last one.
+func (r *Reader) Next() (Item, error) {
+	var item Item
+	if err := r.dec.Decode(&item); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Item{}, io.EOF
+		}
+		return Item{}, decodeError(err)
+	}
+
+	return item, nil
+}
+
+// decodeError returns the error of reading the chunks instead of the JSON error wrapping it.
+func decodeError(err error) error {
+	if errors.Is(err, ErrWrongPassphrase) || errors.Is(err, ErrTruncated) {
+		return err
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrTruncated
+	}
+
+	return fmt.Errorf("decode archive: %w", err)
+}
+
+// chunkReader decrypts the chunks of an archive. It returns io.EOF only after the last chunk,
+// an archive ending earlier results in ErrTruncated.
+type chunkReader struct {
+	r     io.Reader
+	key   []byte
+	plain []byte // Decrypted data not yet read
+	index int    // Index of the next chunk
+	done  bool   // The last chunk has been read
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.plain) == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if err := c.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, c.plain)
+	c.plain = c.plain[n:]
+	return n, nil
+}
+
+// next reads and decrypts the next chunk.
+func (c *chunkReader) next() error {
+	var size [4]byte
+	if _, err := io.ReadFull(c.r, size[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncated
+		}
+		return fmt.Errorf("read chunk %d: %w", c.index, err)
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	if n > sealSize {
+		return ErrWrongPassphrase
+	}
+
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(c.r, sealed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncated
+		}
+		return fmt.Errorf("read chunk %d: %w", c.index, err)
+	}
+
+	// The flag of the last chunk is authenticated, so a chunk decrypting as neither is damaged
+	plain, err := encryption.DecryptChunk(c.key, sealed, "", c.index, false)
+	if err != nil {
+		plain, err = encryption.DecryptChunk(c.key, sealed, "", c.index, true)
+		if err != nil {
+			return ErrWrongPassphrase
+		}
+		c.done = true
+	}
+
+	c.plain = plain
+	c.index++
+	return nil
+}
@@ -0,0 +1,408 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+const (
+	batchSize  = 500     // Largest number of records saved by one batch call
+	batchBytes = 1 << 20 // Approximate size of the records saved by one batch call, well below the message limit of gRPC
+)
+
+// Dedupe defines which archived items are skipped on import because the account already has them.
+type Dedupe string
+
+// Dedupe modes.
+const (
+	DedupeNone     Dedupe = "none"     // Every item is imported
+	DedupeMetadata Dedupe = "metadata" // Items with the type and metadata of an existing record are skipped
+	DedupeContent  Dedupe = "content"  // Items equal to an existing record are skipped
+)
+
+// ParseDedupe returns the dedupe mode with the given name, DedupeContent for an empty name.
+func ParseDedupe(name string) (Dedupe, error) {
+	switch mode := Dedupe(name); mode {
+	case "":
+		return DedupeContent, nil
+	case DedupeNone, DedupeMetadata, DedupeContent:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown dedupe mode %q", name)
+	}
+}
+
+// CreditCardService defines the credit card operations used by the backup.
+type CreditCardService interface {
+	LoadAllCreditCardDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	LoadCreditCardData(ctx context.Context, token string, dataID string) (model.CreditCard, error)
+	SaveCreditCardBatch(ctx context.Context, token string, items []model.CreditCardPostRequest, atomic bool) ([]model.BatchResult, error)
+}
+
+// TextDataService defines the text data operations used by the backup.
+type TextDataService interface {
+	LoadAllTextDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	LoadTextData(ctx context.Context, token string, dataID string) (model.TextData, error)
+	SaveTextDataBatch(ctx context.Context, token string, items []model.TextDataPostRequest, atomic bool) ([]model.BatchResult, error)
+}
+
+// CredentialsService defines the credentials operations used by the backup.
+type CredentialsService interface {
+	LoadAllCredentialsDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	LoadCredentialsData(ctx context.Context, token string, dataID string) (model.Credentials, error)
+	SaveCredentialsBatch(ctx context.Context, token string, items []model.CredentialsPostRequest, atomic bool) ([]model.BatchResult, error)
+}
+
+// BinaryDataService defines the binary data operations used by the backup.
+type BinaryDataService interface {
+	LoadAllBinaryDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	LoadBinaryDataStream(ctx context.Context, token string, dataID string, create func(model.BinaryData) (io.Writer, error)) (model.BinaryData, error)
+	SaveBinaryDataStream(ctx context.Context, token string, bData model.BinaryDataStreamRequest, r io.Reader) (model.BinaryData, error)
+}
+
+// Services holds the services of all data types.
+type Services struct {
+	CreditCards CreditCardService
+	TextData    TextDataService
+	Credentials CredentialsService
+	Binaries    BinaryDataService
+}
+
+// Stats counts the items of an export or import by data type.
+type Stats struct {
+	Items   map[string]int // Exported or imported items
+	Skipped map[string]int // Items skipped as duplicates on import
+}
+
+// Total returns the number of exported or imported items.
+func (s Stats) Total() int {
+	total := 0
+	for _, n := range s.Items {
+		total += n
+	}
+	return total
+}
+
+// Backup exports and imports the vault of the user.
+type Backup struct {
+	services Services
+}
+
+// New creates a new Backup using the services of all data types.
+func New(services Services) *Backup {
+	return &Backup{services: services}
+}
+
+// ExportVault writes all records of the user into an archive encrypted with the passphrase.
+func (b *Backup) ExportVault(ctx context.Context, token, login string, w io.Writer, passphrase string) (Stats, error) {
+	stats := Stats{Items: make(map[string]int)}
+
+	aw, err := NewWriter(w, passphrase, Header{Login: login, CreatedAt: time.Now().UTC().Format(time.RFC3339)})
+	if err != nil {
+		return stats, err
+	}
+
+	err = b.each(ctx, token, func(item Item) error {
+		if err := aw.Write(item); err != nil {
+			return err
+		}
+		stats.Items[item.Type]++
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	return stats, aw.Close()
+}
+
+// ImportVault saves the items of the archive as new records of the user, skipping the duplicates
+// of existing records according to the dedupe mode. Items are saved in batches as they are read, so a failed import
+// may leave a part of the archive imported, running it again with dedupe skips that part.
+func (b *Backup) ImportVault(ctx context.Context, token string, r io.Reader, passphrase string, dedupe Dedupe) (Stats, error) {
+	ar, err := NewReader(r, passphrase)
+	if err != nil {
+		return Stats{Items: make(map[string]int), Skipped: make(map[string]int)}, err
+	}
+
+	return b.importItems(ctx, token, ar.Next, dedupe, false)
+}
+
+// ImportItems saves the items as new records of the user like ImportVault does with an archive.
+// With dryRun nothing is saved, the stats count the items that would be imported and skipped.
+func (b *Backup) ImportItems(ctx context.Context, token string, items []Item, dedupe Dedupe, dryRun bool) (Stats, error) {
+	next := func() (Item, error) {
+		if len(items) == 0 {
+			return Item{}, io.EOF
+		}
+		item := items[0]
+		items = items[1:]
+		return item, nil
+	}
+
+	return b.importItems(ctx, token, next, dedupe, dryRun)
+}
+
+// importItems saves the items returned by next until it returns io.EOF, skipping the duplicates
+// of existing records and of the items saved before. Files are saved one by one, other records in batches.
+func (b *Backup) importItems(ctx context.Context, token string, next func() (Item, error), dedupe Dedupe, dryRun bool) (Stats, error) {
+	stats := Stats{Items: make(map[string]int), Skipped: make(map[string]int)}
+
+	existing, err := b.existing(ctx, token, dedupe)
+	if err != nil {
+		return stats, fmt.Errorf("load existing records: %w", err)
+	}
+
+	// Records other than files are saved in batches, the items read before a failure are still saved
+	var (
+		pending []Item
+		size    int
+	)
+	for {
+		item, err := next()
+		if errors.Is(err, io.EOF) {
+			return stats, b.saveBatch(ctx, token, pending, stats)
+		}
+		if err != nil {
+			if saveErr := b.saveBatch(ctx, token, pending, stats); saveErr != nil {
+				return stats, saveErr
+			}
+			return stats, err
+		}
+
+		if dedupe != DedupeNone {
+			key := item.key(dedupe)
+			if existing[key] {
+				stats.Skipped[item.Type]++
+				continue
+			}
+			existing[key] = true
+		}
+
+		switch {
+		case dryRun:
+			stats.Items[item.Type]++
+		case item.Type == TypeBinary:
+			if err = b.saveFile(ctx, token, item); err != nil {
+				return stats, fmt.Errorf("import %s %q: %w", item.Type, item.MetaData, err)
+			}
+			stats.Items[item.Type]++
+		default:
+			pending = append(pending, item)
+			size += item.size()
+			if len(pending) >= batchSize || size >= batchBytes {
+				if err = b.saveBatch(ctx, token, pending, stats); err != nil {
+					return stats, err
+				}
+				pending, size = pending[:0], 0
+			}
+		}
+	}
+}
+
+// existing returns the keys of the existing records of the user in the dedupe mode. Only the lists of records
+// are loaded to dedupe by metadata, while the content mode loads every record.
+func (b *Backup) existing(ctx context.Context, token string, dedupe Dedupe) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	switch dedupe {
+	case DedupeNone:
+		return keys, nil
+	case DedupeMetadata:
+		lists := map[string]func(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error){
+			TypeCreditCard:  b.services.CreditCards.LoadAllCreditCardDataInfo,
+			TypeCredentials: b.services.Credentials.LoadAllCredentialsDataInfo,
+			TypeText:        b.services.TextData.LoadAllTextDataInfo,
+			TypeBinary:      b.services.Binaries.LoadAllBinaryDataInfo,
+		}
+		for dataType, list := range lists {
+			infos, err := lib.LoadAllInfos(ctx, token, model.PageRequest{SortBy: model.SortByCreatedAt}, list)
+			if err != nil {
+				return nil, fmt.Errorf("list %s: %w", dataType, err)
+			}
+			for _, info := range infos {
+				keys[Item{Type: dataType, MetaData: info.MetaData}.key(dedupe)] = true
+			}
+		}
+		return keys, nil
+	default:
+		err := b.each(ctx, token, func(item Item) error {
+			keys[item.key(dedupe)] = true
+			return nil
+		})
+		return keys, err
+	}
+}
+
+// each loads every record of the user and passes it to fn as an item.
+func (b *Backup) each(ctx context.Context, token string, fn func(Item) error) error {
+	page := model.PageRequest{SortBy: model.SortByCreatedAt}
+
+	infos, err := lib.LoadAllInfos(ctx, token, page, b.services.CreditCards.LoadAllCreditCardDataInfo)
+	if err != nil {
+		return fmt.Errorf("list credit cards: %w", err)
+	}
+	for _, info := range infos {
+		card, err := b.services.CreditCards.LoadCreditCardData(ctx, token, info.ID)
+		if err != nil {
+			return fmt.Errorf("load credit card %s: %w", info.ID, err)
+		}
+		err = fn(Item{Type: TypeCreditCard, MetaData: card.MetaData, CreatedAt: info.CreatedAt, Card: &Card{
+			Number: card.Number, OwnerName: card.OwnerName, ExpiresAt: card.ExpiresAt, CVV: card.CVV, PinCode: card.PinCode,
+		}})
+		if err != nil {
+			return err
+		}
+	}
+
+	infos, err = lib.LoadAllInfos(ctx, token, page, b.services.Credentials.LoadAllCredentialsDataInfo)
+	if err != nil {
+		return fmt.Errorf("list credentials: %w", err)
+	}
+	for _, info := range infos {
+		cred, err := b.services.Credentials.LoadCredentialsData(ctx, token, info.ID)
+		if err != nil {
+			return fmt.Errorf("load credentials %s: %w", info.ID, err)
+		}
+		err = fn(Item{Type: TypeCredentials, MetaData: cred.MetaData, CreatedAt: info.CreatedAt, Credentials: &Credentials{
+			Login: cred.Login, Password: cred.Password, TOTP: cred.TOTP,
+		}})
+		if err != nil {
+			return err
+		}
+	}
+
+	infos, err = lib.LoadAllInfos(ctx, token, page, b.services.TextData.LoadAllTextDataInfo)
+	if err != nil {
+		return fmt.Errorf("list text data: %w", err)
+	}
+	for _, info := range infos {
+		text, err := b.services.TextData.LoadTextData(ctx, token, info.ID)
+		if err != nil {
+			return fmt.Errorf("load text data %s: %w", info.ID, err)
+		}
+		err = fn(Item{Type: TypeText, MetaData: text.MetaData, CreatedAt: info.CreatedAt, Text: &Text{Text: text.Text}})
+		if err != nil {
+			return err
+		}
+	}
+
+	infos, err = lib.LoadAllInfos(ctx, token, page, b.services.Binaries.LoadAllBinaryDataInfo)
+	if err != nil {
+		return fmt.Errorf("list binary data: %w", err)
+	}
+	for _, info := range infos {
+		var buf bytes.Buffer
+		data, err := b.services.Binaries.LoadBinaryDataStream(ctx, token, info.ID, func(model.BinaryData) (io.Writer, error) {
+			return &buf, nil
+		})
+		if err != nil {
+			return fmt.Errorf("load binary data %s: %w", info.ID, err)
+		}
+		err = fn(Item{Type: TypeBinary, MetaData: data.MetaData, CreatedAt: info.CreatedAt, File: &File{
+			Name: data.Name, Extension: data.Extension, Data: buf.Bytes(),
+		}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveFile saves the file item as a new record.
+func (b *Backup) saveFile(ctx context.Context, token string, item Item) error {
+	if item.File == nil {
+		return fmt.Errorf("missing payload")
+	}
+
+	_, err := b.services.Binaries.SaveBinaryDataStream(ctx, token, model.BinaryDataStreamRequest{
+		Name: item.File.Name, Extension: item.File.Extension, MetaData: item.MetaData,
+	}, bytes.NewReader(item.File.Data))
+
+	return err
+}
+
+// saveBatch saves the items as new records with one atomic batch call per data type and counts them in the stats.
+func (b *Backup) saveBatch(ctx context.Context, token string, items []Item, stats Stats) error {
+	var (
+		cards []model.CreditCardPostRequest
+		creds []model.CredentialsPostRequest
+		texts []model.TextDataPostRequest
+	)
+	for _, item := range items {
+		switch {
+		case item.Type == TypeCreditCard && item.Card != nil:
+			c := item.Card
+			cards = append(cards, model.CreditCardPostRequest{
+				Number: c.Number, OwnerName: c.OwnerName, ExpiresAt: c.ExpiresAt, CVV: c.CVV, PinCode: c.PinCode, MetaData: item.MetaData,
+			})
+		case item.Type == TypeCredentials && item.Credentials != nil:
+			creds = append(creds, model.CredentialsPostRequest{
+				Login: item.Credentials.Login, Password: item.Credentials.Password, TOTP: item.Credentials.TOTP, MetaData: item.MetaData,
+			})
+		case item.Type == TypeText && item.Text != nil:
+			texts = append(texts, model.TextDataPostRequest{Text: item.Text.Text, MetaData: item.MetaData})
+		default:
+			return fmt.Errorf("import %s %q: unknown item type or missing payload", item.Type, item.MetaData)
+		}
+	}
+
+	if len(cards) != 0 {
+		if _, err := b.services.CreditCards.SaveCreditCardBatch(ctx, token, cards, true); err != nil {
+			return fmt.Errorf("import %d %s: %w", len(cards), TypeCreditCard, err)
+		}
+		stats.Items[TypeCreditCard] += len(cards)
+	}
+	if len(creds) != 0 {
+		if _, err := b.services.Credentials.SaveCredentialsBatch(ctx, token, creds, true); err != nil {
+			return fmt.Errorf("import %d %s: %w", len(creds), TypeCredentials, err)
+		}
+		stats.Items[TypeCredentials] += len(creds)
+	}
+	if len(texts) != 0 {
+		if _, err := b.services.TextData.SaveTextDataBatch(ctx, token, texts, true); err != nil {
+			return fmt.Errorf("import %d %s: %w", len(texts), TypeText, err)
+		}
+		stats.Items[TypeText] += len(texts)
+	}
+
+	return nil
+}
+
+// size returns the approximate size of the item in a request.
+func (i Item) size() int {
+	n := len(i.MetaData)
+	switch {
+	case i.Card != nil:
+		n += len(i.Card.Number) + len(i.Card.OwnerName) + len(i.Card.ExpiresAt) + len(i.Card.CVV) + len(i.Card.PinCode)
+	case i.Credentials != nil:
+		n += len(i.Credentials.Login) + len(i.Credentials.Password) + len(i.Credentials.TOTP)
+	case i.Text != nil:
+		n += len(i.Text.Text)
+	}
+
+	return n
+}
+
+// key returns the key identifying duplicates of the item in the dedupe mode.
+// The content key is a hash of the item without its creation time, which changes on import.
+func (i Item) key(dedupe Dedupe) string {
+	if dedupe == DedupeMetadata {
+		return i.Type + "\x00" + i.MetaData
+	}
+
+	i.CreatedAt = ""
+	data, _ := json.Marshal(i)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,263 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+// account is an in-memory account implementing the services of all data types.
+type account struct {
+	cards []model.CreditCard
+	creds []model.Credentials
+	texts []model.TextData
+	files []model.BinaryData
+
+	batches int // Number of batch calls
+}
+
+func infoPage(n int, meta func(i int) string) model.DataInfoPage {
+	var page model.DataInfoPage
+	for i := 0; i < n; i++ {
+		page.Infos = append(page.Infos, model.DataInfo{ID: fmt.Sprint(i), MetaData: meta(i)})
+	}
+	return page
+}
+
+func index(id string) int {
+	var i int
+	_, _ = fmt.Sscan(id, &i)
+	return i
+}
+
+func (a *account) LoadAllCreditCardDataInfo(context.Context, string, model.PageRequest) (model.DataInfoPage, error) {
+	return infoPage(len(a.cards), func(i int) string { return a.cards[i].MetaData }), nil
+}
+
+func (a *account) LoadCreditCardData(_ context.Context, _ string, id string) (model.CreditCard, error) {
+	return a.cards[index(id)], nil
+}
+
+func (a *account) SaveCreditCardBatch(_ context.Context, _ string, cards []model.CreditCardPostRequest, _ bool) ([]model.BatchResult, error) {
+	a.batches++
+	for _, c := range cards {
+		a.cards = append(a.cards, model.CreditCard{Number: c.Number, OwnerName: c.OwnerName, ExpiresAt: c.ExpiresAt, CVV: c.CVV, PinCode: c.PinCode, MetaData: c.MetaData})
+	}
+	return make([]model.BatchResult, len(cards)), nil
+}
+
+func (a *account) LoadAllCredentialsDataInfo(context.Context, string, model.PageRequest) (model.DataInfoPage, error) {
+	return infoPage(len(a.creds), func(i int) string { return a.creds[i].MetaData }), nil
+}
+
+func (a *account) LoadCredentialsData(_ context.Context, _ string, id string) (model.Credentials, error) {
+	return a.creds[index(id)], nil
+}
+
+func (a *account) SaveCredentialsBatch(_ context.Context, _ string, creds []model.CredentialsPostRequest, _ bool) ([]model.BatchResult, error) {
+	a.batches++
+	for _, c := range creds {
+		a.creds = append(a.creds, model.Credentials{Login: c.Login, Password: c.Password, MetaData: c.MetaData})
+	}
+	return make([]model.BatchResult, len(creds)), nil
+}
+
+func (a *account) LoadAllTextDataInfo(context.Context, string, model.PageRequest) (model.DataInfoPage, error) {
+	return infoPage(len(a.texts), func(i int) string { return a.texts[i].MetaData }), nil
+}
+
+func (a *account) LoadTextData(_ context.Context, _ string, id string) (model.TextData, error) {
+	return a.texts[index(id)], nil
+}
+
+func (a *account) SaveTextDataBatch(_ context.Context, _ string, texts []model.TextDataPostRequest, _ bool) ([]model.BatchResult, error) {
+	a.batches++
+	for _, t := range texts {
+		a.texts = append(a.texts, model.TextData{Text: t.Text, MetaData: t.MetaData})
+	}
+	return make([]model.BatchResult, len(texts)), nil
+}
+
+func (a *account) LoadAllBinaryDataInfo(context.Context, string, model.PageRequest) (model.DataInfoPage, error) {
+	return infoPage(len(a.files), func(i int) string { return a.files[i].MetaData }), nil
+}
+
+func (a *account) LoadBinaryDataStream(_ context.Context, _ string, id string, create func(model.BinaryData) (io.Writer, error)) (model.BinaryData, error) {
+	file := a.files[index(id)]
+	w, err := create(file)
+	if err != nil {
+		return model.BinaryData{}, err
+	}
+	_, err = w.Write(file.Data)
+	file.Data = nil
+	return file, err
+}
+
+func (a *account) SaveBinaryDataStream(_ context.Context, _ string, b model.BinaryDataStreamRequest, r io.Reader) (model.BinaryData, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return model.BinaryData{}, err
+	}
+	file := model.BinaryData{Name: b.Name, Extension: b.Extension, MetaData: b.MetaData, Data: data}
+	a.files = append(a.files, file)
+	return file, nil
+}
+
+func (a *account) services() Services {
+	return Services{CreditCards: a, TextData: a, Credentials: a, Binaries: a}
+}
+
+type BackupTestSuite struct {
+	suite.Suite
+	ctx    context.Context
+	source *account
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(BackupTestSuite))
+}
+
+func (s *BackupTestSuite) SetupTest() {
+	s.ctx = context.Background()
+
+	// The file is larger than a chunk, so the archive has several chunks
+	data := make([]byte, 3*chunkSize/2)
+	_, err := rand.Read(data)
+	require.NoError(s.T(), err)
+
+	s.source = &account{
+		cards: []model.CreditCard{{Number: "4111 1111 1111 1111", OwnerName: "IVAN IVANOV", ExpiresAt: "31-12-2027", CVV: "123", PinCode: "0000", MetaData: "salary #bank"}},
+		creds: []model.Credentials{{Login: "admin", Password: "secret", MetaData: "db"}, {Login: "guest", Password: "qwerty", MetaData: "mail"}},
+		texts: []model.TextData{{Text: "recovery phrase", MetaData: "wallet"}},
+		files: []model.BinaryData{{Name: "backup", Extension: "tar", Data: data, MetaData: "#backup"}},
+	}
+}
+
+// export exports the source account and returns the archive.
+func (s *BackupTestSuite) export(passphrase string) []byte {
+	var archive bytes.Buffer
+	stats, err := New(s.source.services()).ExportVault(s.ctx, "token", "user@example.com", &archive, passphrase)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 5, stats.Total())
+	return archive.Bytes()
+}
+
+func (s *BackupTestSuite) Test_ExportImport() {
+	archive := s.export("passphrase")
+	assert.NotContains(s.T(), string(archive), "secret")
+
+	r, err := NewReader(bytes.NewReader(archive), "passphrase")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "user@example.com", r.Header().Login)
+	assert.Equal(s.T(), FormatVersion, r.Header().Version)
+
+	target := &account{}
+	stats, err := New(target.services()).ImportVault(s.ctx, "token", bytes.NewReader(archive), "passphrase", DedupeContent)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), map[string]int{TypeCreditCard: 1, TypeCredentials: 2, TypeText: 1, TypeBinary: 1}, stats.Items)
+
+	// Records other than files are saved with one batch call per data type
+	assert.Equal(s.T(), 3, target.batches)
+	target.batches = 0
+	assert.Equal(s.T(), s.source, target)
+}
+
+func (s *BackupTestSuite) Test_Dedupe() {
+	archive := s.export("passphrase")
+
+	// The password of one credentials record changed since the export
+	s.source.creds[0].Password = "changed"
+
+	stats, err := New(s.source.services()).ImportVault(s.ctx, "token", bytes.NewReader(archive), "passphrase", DedupeContent)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), map[string]int{TypeCredentials: 1}, stats.Items)
+	assert.Equal(s.T(), 4, stats.Skipped[TypeCredentials]+stats.Skipped[TypeCreditCard]+stats.Skipped[TypeText]+stats.Skipped[TypeBinary])
+	require.Len(s.T(), s.source.creds, 3)
+	assert.Equal(s.T(), "secret", s.source.creds[2].Password)
+
+	stats, err = New(s.source.services()).ImportVault(s.ctx, "token", bytes.NewReader(archive), "passphrase", DedupeMetadata)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 0, stats.Total())
+
+	stats, err = New(s.source.services()).ImportVault(s.ctx, "token", bytes.NewReader(archive), "passphrase", DedupeNone)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 5, stats.Total())
+	assert.Len(s.T(), s.source.creds, 5)
+}
+
+func (s *BackupTestSuite) Test_ImportItemsDryRun() {
+	items := []Item{
+		{Type: TypeCredentials, MetaData: "db", Credentials: &Credentials{Login: "admin", Password: "secret"}},
+		{Type: TypeCredentials, MetaData: "vpn", Credentials: &Credentials{Login: "admin", Password: "secret"}},
+		{Type: TypeCredentials, MetaData: "vpn", Credentials: &Credentials{Login: "admin", Password: "secret"}},
+	}
+
+	stats, err := New(s.source.services()).ImportItems(s.ctx, "token", items, DedupeContent, true)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), map[string]int{TypeCredentials: 1}, stats.Items)
+	assert.Equal(s.T(), map[string]int{TypeCredentials: 2}, stats.Skipped)
+	assert.Len(s.T(), s.source.creds, 2)
+
+	stats, err = New(s.source.services()).ImportItems(s.ctx, "token", items, DedupeContent, false)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, stats.Total())
+	require.Len(s.T(), s.source.creds, 3)
+	assert.Equal(s.T(), "vpn", s.source.creds[2].MetaData)
+}
+
+func (s *BackupTestSuite) Test_ImportBatches() {
+	items := make([]Item, 2*batchSize+1)
+	for i := range items {
+		items[i] = Item{Type: TypeCredentials, MetaData: fmt.Sprint(i), Credentials: &Credentials{Login: "admin", Password: "secret"}}
+	}
+
+	target := &account{}
+	stats, err := New(target.services()).ImportItems(s.ctx, "token", items, DedupeNone, false)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), len(items), stats.Total())
+	assert.Equal(s.T(), 3, target.batches)
+	require.Len(s.T(), target.creds, len(items))
+	assert.Equal(s.T(), fmt.Sprint(len(items)-1), target.creds[len(items)-1].MetaData)
+}
+
+func (s *BackupTestSuite) Test_DamagedArchive() {
+	archive := s.export("passphrase")
+	b := New((&account{}).services())
+
+	_, err := b.ImportVault(s.ctx, "token", bytes.NewReader(archive), "wrong", DedupeNone)
+	assert.ErrorIs(s.T(), err, ErrWrongPassphrase)
+
+	_, err = b.ImportVault(s.ctx, "token", bytes.NewReader(archive[:len(archive)-100]), "passphrase", DedupeNone)
+	assert.ErrorIs(s.T(), err, ErrTruncated)
+
+	// Dropping the last chunk is detected, although the archive ends on a chunk boundary
+	_, err = NewReader(bytes.NewReader(archive[:len(magic)+1+saltSize+4+sealSize]), "passphrase")
+	require.NoError(s.T(), err)
+	_, err = b.ImportVault(s.ctx, "token", bytes.NewReader(archive[:len(magic)+1+saltSize+4+sealSize]), "passphrase", DedupeNone)
+	assert.ErrorIs(s.T(), err, ErrTruncated)
+
+	_, err = NewReader(bytes.NewReader([]byte("not an archive at all")), "passphrase")
+	assert.ErrorIs(s.T(), err, ErrNotArchive)
+}
+
+func (s *BackupTestSuite) Test_ParseDedupe() {
+	mode, err := ParseDedupe("")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), DedupeContent, mode)
+
+	mode, err = ParseDedupe("metadata")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), DedupeMetadata, mode)
+
+	_, err = ParseDedupe("id")
+	assert.Error(s.T(), err)
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/backup"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+)
+
+const archivePerm = 0o600 // Permission for the exported archive, readable by the owner only
+
+// BackupService defines the interface for exporting and importing the whole vault.
+type BackupService interface {
+	ExportVault(ctx context.Context, token, login string, w io.Writer, passphrase string) (backup.Stats, error)
+	ImportVault(ctx context.Context, token string, r io.Reader, passphrase string, dedupe backup.Dedupe) (backup.Stats, error)
+	ImportItems(ctx context.Context, token string, items []backup.Item, dedupe backup.Dedupe, dryRun bool) (backup.Stats, error)
+}
+
+// BackupProvider provides the export and import commands of the client.
+// It holds a reference to a BackupService and maintains the client's state.
+type BackupProvider struct {
+	backupService BackupService      // Service to export and import the vault
+	state         *state.ClientState // Client's state, including authorization and directory information
+}
+
+// NewBackupService initializes a new BackupProvider with the given BackupService
+// and ClientState. It returns a pointer to the newly created BackupProvider.
+func NewBackupService(s BackupService, state *state.ClientState) *BackupProvider {
+	return &BackupProvider{
+		backupService: s,
+		state:         state,
+	}
+}
+
+// ExportVault prompts the user for the archive name and passphrase and exports all records into the archive
+// in the working directory.
+func (p *BackupProvider) ExportVault(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+	if !p.ready() {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input archive name and passphrase to export all your records:"))
+
+	fmt.Printf("Input archive name as %s: ", yellow("'example (keeper.backup)'"))
+	scanner.Scan()
+	name := strings.TrimSpace(scanner.Text())
+	if len(name) == 0 {
+		fmt.Println(red("Archive name must not be empty please try again"))
+		return
+	}
+
+	fmt.Printf("Input passphrase as %s: ", yellow("'text'"))
+	scanner.Scan()
+	passphrase := strings.TrimSpace(scanner.Text())
+	if len(passphrase) == 0 {
+		fmt.Println(red("Passphrase must not be empty please try again"))
+		return
+	}
+
+	fmt.Printf("Repeat passphrase as %s: ", yellow("'text'"))
+	scanner.Scan()
+	if strings.TrimSpace(scanner.Text()) != passphrase {
+		fmt.Println(red("Passphrases don't match please try again"))
+		return
+	}
+
+	path := filepath.Join(p.state.GetDirPath(), filepath.FromSlash(name))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, archivePerm)
+	if err != nil {
+		fmt.Println(red("Unable to create the archive, please choose another name: ", err))
+		return
+	}
+
+	stats, err := p.backupService.ExportVault(ctx, p.state.GetToken(), p.state.GetLogin(), file, passphrase)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Export failed")
+		fmt.Println(red("Export failed, please try again"))
+		lib.UnpackGRPCError(err)
+		if err = os.Remove(path); err != nil {
+			logrus.WithError(err).Error("Failed to remove the unfinished archive")
+		}
+		return
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Println(green(fmt.Sprintf("%d records exported to %s: %s", stats.Total(), path, formatCounts(stats.Items))))
+}
+
+// ImportVault prompts the user for the archive name, passphrase and dedupe mode and imports the records
+// of the archive in the working directory into the account of the user.
+func (p *BackupProvider) ImportVault(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+	if !p.ready() {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input archive name and passphrase to import the records:"))
+
+	fmt.Printf("Input archive name as %s: ", yellow("'example (keeper.backup)'"))
+	scanner.Scan()
+	name := strings.TrimSpace(scanner.Text())
+
+	fmt.Printf("Input passphrase as %s: ", yellow("'text'"))
+	scanner.Scan()
+	passphrase := strings.TrimSpace(scanner.Text())
+
+	fmt.Printf("Skip duplicates by %s, leave empty for content: ", yellow("'content', 'metadata' or 'none'"))
+	scanner.Scan()
+	dedupe, err := backup.ParseDedupe(strings.TrimSpace(scanner.Text()))
+	if err != nil {
+		fmt.Println(red("Invalid dedupe mode please try again: ", err))
+		return
+	}
+
+	file, err := os.Open(filepath.Join(p.state.GetDirPath(), filepath.FromSlash(name)))
+	if err != nil {
+		fmt.Println(red("Unable to open the archive, please try again: ", err))
+		return
+	}
+	defer file.Close()
+
+	stats, err := p.backupService.ImportVault(ctx, p.state.GetToken(), file, passphrase, dedupe)
+	printImport(stats, err)
+}
+
+// printImport prints the result of an import.
+func printImport(stats backup.Stats, err error) {
+	red := color.New(color.FgRed).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	if stats.Total() != 0 {
+		fmt.Println(green(fmt.Sprintf("%d records imported: %s", stats.Total(), formatCounts(stats.Items))))
+	}
+	if len(stats.Skipped) != 0 {
+		fmt.Println(yellow(fmt.Sprintf("Duplicates skipped: %s", formatCounts(stats.Skipped))))
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Import failed")
+		fmt.Println(red("Import is not finished, running it again with dedupe skips the imported records"))
+		lib.UnpackGRPCError(err)
+		return
+	}
+	if stats.Total() == 0 {
+		fmt.Println(yellow("Nothing to import"))
+	}
+}
+
+// ready checks that the user is authorized, online and has set the working directory.
+func (p *BackupProvider) ready() bool {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return false
+	}
+
+	if p.state.IsOffline() {
+		fmt.Println(red("You are working offline, please use 'login' again when the server is reachable"))
+		return false
+	}
+
+	if p.state.GetDirPath() == "" {
+		fmt.Println(red("To proceed you must set working directory"))
+		return false
+	}
+
+	return true
+}
+
+// formatCounts formats the numbers of records by data type.
+func formatCounts(counts map[string]int) string {
+	parts := make([]string, 0, len(counts))
+	for dataType, n := range counts {
+		parts = append(parts, fmt.Sprintf("%s %d", dataType, n))
+	}
+	sort.Strings(parts)
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/backup"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/importer"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+)
+
+// ImportFrom prompts the user for the export of another password manager in the working directory,
+// shows a preview of the records read from it and imports them after the user confirms.
+func (p *BackupProvider) ImportFrom(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+	if !p.ready() {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input the export of another password manager to import its records:"))
+
+	fmt.Printf("Input format as %s: ", yellow("'"+strings.Join(importer.Formats, "', '")+"'"))
+	scanner.Scan()
+	format := strings.ToLower(strings.TrimSpace(scanner.Text()))
+
+	fmt.Printf("Input file name as %s: ", yellow("'example (export.json)'"))
+	scanner.Scan()
+	name := strings.TrimSpace(scanner.Text())
+
+	var mapping importer.Mapping
+	if format == importer.FormatCSV {
+		fmt.Printf("Input column mapping as %s: ", yellow("'name=Title,login=User,password=Secret,notes=Notes'"))
+		scanner.Scan()
+		var err error
+		if mapping, err = importer.ParseMapping(scanner.Text()); err != nil {
+			fmt.Println(red("Invalid mapping please try again: ", err))
+			return
+		}
+	}
+
+	fmt.Printf("Skip duplicates by %s, leave empty for content: ", yellow("'content', 'metadata' or 'none'"))
+	scanner.Scan()
+	dedupe, err := backup.ParseDedupe(strings.TrimSpace(scanner.Text()))
+	if err != nil {
+		fmt.Println(red("Invalid dedupe mode please try again: ", err))
+		return
+	}
+
+	file, err := os.Open(filepath.Join(p.state.GetDirPath(), filepath.FromSlash(name)))
+	if err != nil {
+		fmt.Println(red("Unable to open the file, please try again: ", err))
+		return
+	}
+	res, err := importer.Parse(format, file, mapping)
+	_ = file.Close()
+	if err != nil {
+		fmt.Println(red("Unable to read the file, please try again: ", err))
+		return
+	}
+
+	for _, warning := range res.Warnings {
+		fmt.Println(yellow(warning))
+	}
+
+	// The dry run reports the records that would be imported and the duplicates without saving anything
+	stats, err := p.backupService.ImportItems(ctx, p.state.GetToken(), res.Items, dedupe, true)
+	if err != nil {
+		logrus.WithError(err).Error("Import preview failed")
+		fmt.Println(red("Unable to load your records to find duplicates, please try again"))
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	printPreview(res.Items)
+	if len(stats.Skipped) != 0 {
+		fmt.Println(yellow(fmt.Sprintf("Duplicates to skip: %s", formatCounts(stats.Skipped))))
+	}
+	if stats.Total() == 0 {
+		fmt.Println(yellow("Nothing to import"))
+		return
+	}
+
+	fmt.Printf("Import %d records: %s? %s: ", stats.Total(), formatCounts(stats.Items), yellow("'y/n'"))
+	scanner.Scan()
+	if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+		fmt.Println(yellow("Nothing imported"))
+		return
+	}
+
+	stats, err = p.backupService.ImportItems(ctx, p.state.GetToken(), res.Items, dedupe, false)
+	printImport(stats, err)
+}
+
+// printPreview prints the data type and the first line of the metadata of the items, secrets are not shown.
+func printPreview(items []backup.Item) {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Println(green("-------------------------------------"))
+	var sb strings.Builder
+	for _, item := range items {
+		meta, _, _ := strings.Cut(item.MetaData, "\n")
+		sb.WriteString(fmt.Sprintf("%-12s %s\n", item.Type, meta))
+	}
+	sb.WriteString(green("-------------------------------------"))
+	fmt.Println(sb.String())
+}
@@ -1,22 +1,41 @@
 package pbclient
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/encryption"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
 	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/binary_data"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/metadata"
+	"io"
 )
 
+// dataType is the type of the binary data, client side encrypted payloads are bound to it along with their id.
+const dataType = "binary_data"
+
+const chunkSize = 1 << 20 // Size of chunks used to stream binary data
+
 // BinaryDataPBClient is a client wrapper for interacting with the BinaryDataService.
 type BinaryDataPBClient struct {
 	binaryDataService pb.BinaryDataServiceClient
+	cipher            Cipher
+}
+
+// Cipher defines methods for client side encryption of the binary payload.
+type Cipher interface {
+	Enabled() bool
+	Seal(id, dataType string, v any) ([]byte, error)
+	Open(id, dataType string, data []byte, v any) error
 }
 
-// NewBinaryDataPBClient creates a new BinaryDataPBClient with the given BinaryDataServiceClient.
-func NewBinaryDataPBClient(u pb.BinaryDataServiceClient) *BinaryDataPBClient {
+// NewBinaryDataPBClient creates a new BinaryDataPBClient with the given BinaryDataServiceClient and Cipher.
+func NewBinaryDataPBClient(u pb.BinaryDataServiceClient, cipher Cipher) *BinaryDataPBClient {
 	return &BinaryDataPBClient{
 		binaryDataService: u,
+		cipher:            cipher,
 	}
 }
 
@@ -29,6 +48,15 @@ func (u *BinaryDataPBClient) SaveBinaryData(ctx context.Context, token string, b
 		Metadata:  bData.MetaData,
 	}
 
+	if u.cipher.Enabled() {
+		id := uuid.NewString()
+		cryptData, err := u.cipher.Seal(id, dataType, model.BinaryCryptData{Name: bData.Name, Extension: bData.Extension, Data: bData.Data})
+		if err != nil {
+			return model.BinaryData{}, fmt.Errorf("seal binary data: %w", err)
+		}
+		req = &pb.PostBinaryDataRequest{Id: id, Metadata: bData.MetaData, CryptData: cryptData}
+	}
+
 	md := metadata.New(map[string]string{"token": token})
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
@@ -38,24 +66,33 @@ func (u *BinaryDataPBClient) SaveBinaryData(ctx context.Context, token string, b
 	}
 
 	binaryData := model.BinaryData{
+		ID:        resp.Id,
 		Name:      resp.Name,
 		Extension: resp.Extension,
 		MetaData:  resp.Metadata,
 	}
+	if len(req.CryptData) != 0 {
+		binaryData.Name, binaryData.Extension = bData.Name, bData.Extension
+	}
 
 	return binaryData, nil
 }
 
-// LoadAllBinaryDataInfo retrieves all binary data info and returns a list of DataInfo or an error.
-func (u *BinaryDataPBClient) LoadAllBinaryDataInfo(ctx context.Context, token string) ([]model.DataInfo, error) {
-	req := &pb.GetAllBinaryInfoRequest{}
+// LoadAllBinaryDataInfo retrieves a page of binary data info and returns a page of DataInfo or an error.
+func (u *BinaryDataPBClient) LoadAllBinaryDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error) {
+	req := &pb.GetAllBinaryInfoRequest{
+		PageSize:   int32(page.PageSize),
+		PageToken:  page.PageToken,
+		SortBy:     page.SortBy,
+		Descending: page.Descending,
+	}
 
 	md := metadata.New(map[string]string{"token": token})
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
 	resp, err := u.binaryDataService.GetLoadAllBinaryDataInfo(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("load binary data: %w", err)
+		return model.DataInfoPage{}, fmt.Errorf("load binary data: %w", err)
 	}
 
 	binaries := make([]model.DataInfo, 0, len(resp.Binaries))
@@ -68,7 +105,7 @@ func (u *BinaryDataPBClient) LoadAllBinaryDataInfo(ctx context.Context, token st
 		})
 	}
 
-	return binaries, nil
+	return model.DataInfoPage{Infos: binaries, NextPageToken: resp.NextPageToken}, nil
 }
 
 // LoadBinaryData retrieves binary data by ID and returns the BinaryData or an error.
@@ -92,5 +129,253 @@ func (u *BinaryDataPBClient) LoadBinaryData(ctx context.Context, token string, d
 		MetaData:  data.Metadata,
 	}
 
+	if len(data.CryptData) != 0 {
+		var cryptData model.BinaryCryptData
+		if err = u.cipher.Open(data.Id, dataType, data.CryptData, &cryptData); err != nil {
+			return model.BinaryData{}, fmt.Errorf("open binary data: %w", err)
+		}
+		binaryData.Name, binaryData.Extension, binaryData.Data = cryptData.Name, cryptData.Extension, cryptData.Data
+	}
+
+	return binaryData, nil
+}
+
+// UpdateBinaryData sends a request to replace existing binary data and returns the updated data or an error.
+func (u *BinaryDataPBClient) UpdateBinaryData(ctx context.Context, token string, bData model.BinaryDataPutRequest) (model.BinaryData, error) {
+	req := &pb.PutBinaryDataRequest{
+		Id:        bData.ID,
+		Data:      bData.Data,
+		Name:      bData.Name,
+		Extension: bData.Extension,
+		Metadata:  bData.MetaData,
+	}
+
+	if u.cipher.Enabled() {
+		cryptData, err := u.cipher.Seal(bData.ID, dataType, model.BinaryCryptData{Name: bData.Name, Extension: bData.Extension, Data: bData.Data})
+		if err != nil {
+			return model.BinaryData{}, fmt.Errorf("seal binary data: %w", err)
+		}
+		req = &pb.PutBinaryDataRequest{Id: bData.ID, Metadata: bData.MetaData, CryptData: cryptData}
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.binaryDataService.PutUpdateBinaryData(ctx, req)
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("update binary data: %w", err)
+	}
+
+	binaryData := model.BinaryData{
+		ID:        resp.Id,
+		Name:      resp.Name,
+		Extension: resp.Extension,
+		MetaData:  resp.Metadata,
+	}
+	if len(req.CryptData) != 0 {
+		binaryData.Name, binaryData.Extension = bData.Name, bData.Extension
+	}
+
+	return binaryData, nil
+}
+
+// DeleteBinaryData deletes binary data by ID and returns an error if the operation fails.
+func (u *BinaryDataPBClient) DeleteBinaryData(ctx context.Context, token string, dataID string) error {
+	req := &pb.DeleteBinaryDataRequest{
+		Id: dataID,
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	_, err := u.binaryDataService.DeleteBinaryData(ctx, req)
+	if err != nil {
+		return fmt.Errorf("delete binary data: %w", err)
+	}
+
+	return nil
+}
+
+// SaveBinaryDataStream streams the content of r to the server in chunks and returns the saved data or an error.
+// With client side encryption every chunk is encrypted with a new random chunk key, which is sent sealed in the header.
+func (u *BinaryDataPBClient) SaveBinaryDataStream(ctx context.Context, token string, bData model.BinaryDataStreamRequest, r io.Reader) (model.BinaryData, error) {
+	header := &pb.BinaryDataStreamHeader{
+		Name:      bData.Name,
+		Extension: bData.Extension,
+		Metadata:  bData.MetaData,
+	}
+
+	var (
+		chunkKey []byte
+		id       string
+	)
+	if u.cipher.Enabled() {
+		var err error
+		chunkKey, err = encryption.GenerateDataKey()
+		if err != nil {
+			return model.BinaryData{}, fmt.Errorf("generate chunk key: %w", err)
+		}
+		id = uuid.NewString()
+		cryptData, err := u.cipher.Seal(id, dataType, model.BinaryCryptData{Name: bData.Name, Extension: bData.Extension, ChunkKey: chunkKey})
+		if err != nil {
+			return model.BinaryData{}, fmt.Errorf("seal binary data: %w", err)
+		}
+		header = &pb.BinaryDataStreamHeader{Id: id, Metadata: bData.MetaData, CryptData: cryptData}
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	stream, err := u.binaryDataService.PostSaveBinaryDataStream(ctx)
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("open stream: %w", err)
+	}
+
+	err = stream.Send(&pb.PostBinaryDataStreamRequest{Payload: &pb.PostBinaryDataStreamRequest_Header{Header: header}})
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("send header: %w", err)
+	}
+
+	// The reader peeks one byte ahead to know which chunk is the last one, an empty file is sent as one empty chunk
+	br := bufio.NewReaderSize(r, chunkSize)
+	for index := 0; ; index++ {
+		chunk := make([]byte, chunkSize)
+		n, err := io.ReadFull(br, chunk)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return model.BinaryData{}, fmt.Errorf("read chunk %d: %w", index, err)
+		}
+		chunk = chunk[:n]
+
+		_, err = br.Peek(1)
+		last := errors.Is(err, io.EOF)
+		if err != nil && !last {
+			return model.BinaryData{}, fmt.Errorf("read chunk %d: %w", index+1, err)
+		}
+
+		if chunkKey != nil {
+			chunk, err = encryption.EncryptChunk(chunkKey, chunk, id, index, last)
+			if err != nil {
+				return model.BinaryData{}, fmt.Errorf("encrypt chunk %d: %w", index, err)
+			}
+		}
+
+		err = stream.Send(&pb.PostBinaryDataStreamRequest{Payload: &pb.PostBinaryDataStreamRequest_Chunk{Chunk: chunk}})
+		if err != nil {
+			return model.BinaryData{}, fmt.Errorf("send chunk %d: %w", index, err)
+		}
+
+		if last {
+			break
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("save binary data: %w", err)
+	}
+
+	return model.BinaryData{
+		ID:        resp.Id,
+		Name:      bData.Name,
+		Extension: bData.Extension,
+		MetaData:  resp.Metadata,
+	}, nil
+}
+
+// LoadBinaryDataStream retrieves binary data by ID as a stream of chunks. The writer for the content
+// is obtained from create once the header is received. It returns the loaded data without its content or an error.
+func (u *BinaryDataPBClient) LoadBinaryDataStream(ctx context.Context, token string, dataID string, create func(model.BinaryData) (io.Writer, error)) (model.BinaryData, error) {
+	req := &pb.GetBinaryDataStreamRequest{
+		Id: dataID,
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	stream, err := u.binaryDataService.GetLoadBinaryDataStream(ctx, req)
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("open stream: %w", err)
+	}
+
+	msg, err := stream.Recv()
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("load binary data: %w", err)
+	}
+
+	data := msg.GetHeader()
+	if data == nil {
+		return model.BinaryData{}, errors.New("load binary data: missing header")
+	}
+
+	binaryData := model.BinaryData{
+		Name:      data.Name,
+		Extension: data.Extension,
+		MetaData:  data.Metadata,
+	}
+
+	var cryptData model.BinaryCryptData
+	if len(data.CryptData) != 0 {
+		if err = u.cipher.Open(data.Id, dataType, data.CryptData, &cryptData); err != nil {
+			return model.BinaryData{}, fmt.Errorf("open binary data: %w", err)
+		}
+		binaryData.Name, binaryData.Extension = cryptData.Name, cryptData.Extension
+	}
+
+	w, err := create(binaryData)
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("create writer: %w", err)
+	}
+
+	if len(data.CryptData) != 0 && len(cryptData.ChunkKey) == 0 {
+		// Binary data saved in one piece carries its content in the header
+		if _, err = w.Write(cryptData.Data); err != nil {
+			return model.BinaryData{}, fmt.Errorf("write binary data: %w", err)
+		}
+		return binaryData, nil
+	}
+
+	// Chunks are decrypted one message behind to know which chunk is the last one
+	var (
+		pending    []byte
+		hasPending bool
+		index      int
+		fileID     = data.Id
+	)
+	for {
+		msg, err = stream.Recv()
+		eof := errors.Is(err, io.EOF)
+		if err != nil && !eof {
+			return model.BinaryData{}, fmt.Errorf("receive chunk %d: %w", index, err)
+		}
+
+		if hasPending {
+			chunk := pending
+			if len(cryptData.ChunkKey) != 0 {
+				chunk, err = encryption.DecryptChunk(cryptData.ChunkKey, pending, fileID, index, eof)
+				if err != nil && index == 0 {
+					// Chunks of files streamed before they were bound to the file id are read without it
+					fileID = ""
+					chunk, err = encryption.DecryptChunk(cryptData.ChunkKey, pending, fileID, index, eof)
+				}
+				if err != nil {
+					return model.BinaryData{}, fmt.Errorf("decrypt chunk %d: %w", index, err)
+				}
+			}
+			if _, err = w.Write(chunk); err != nil {
+				return model.BinaryData{}, fmt.Errorf("write chunk %d: %w", index, err)
+			}
+			index++
+		}
+
+		if eof {
+			break
+		}
+		pending, hasPending = msg.GetChunk(), true
+	}
+
+	if len(cryptData.ChunkKey) != 0 && index == 0 {
+		return model.BinaryData{}, errors.New("load binary data: missing chunks")
+	}
+
 	return binaryData, nil
 }
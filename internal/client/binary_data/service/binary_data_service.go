@@ -9,6 +9,7 @@ import (
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
 	"github.com/fatih/color"
 	"github.com/sirupsen/logrus"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,7 +19,11 @@ import (
 type BinaryDataService interface {
 	SaveBinaryData(ctx context.Context, token string, bData model.BinaryDataPostRequest) (model.BinaryData, error)
 	LoadBinaryData(ctx context.Context, token string, dataID string) (model.BinaryData, error)
-	LoadAllBinaryDataInfo(ctx context.Context, token string) ([]model.DataInfo, error)
+	LoadAllBinaryDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateBinaryData(ctx context.Context, token string, bData model.BinaryDataPutRequest) (model.BinaryData, error)
+	DeleteBinaryData(ctx context.Context, token string, dataID string) error
+	SaveBinaryDataStream(ctx context.Context, token string, bData model.BinaryDataStreamRequest, r io.Reader) (model.BinaryData, error)
+	LoadBinaryDataStream(ctx context.Context, token string, dataID string, create func(model.BinaryData) (io.Writer, error)) (model.BinaryData, error)
 }
 
 // BinaryDataProvider implements the BinaryDataService interface and manages client-side operations.
@@ -48,7 +53,7 @@ func (p *BinaryDataProvider) Save(ctx context.Context) {
 	scanner := bufio.NewScanner(os.Stdin)
 
 	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
-	req := model.BinaryDataPostRequest{}
+	req := model.BinaryDataStreamRequest{}
 	fmt.Println(cyanBold("Input binary data to save 'path name extension metadata':"))
 
 	yellow := color.New(color.FgYellow).SprintFunc()
@@ -56,13 +61,12 @@ func (p *BinaryDataProvider) Save(ctx context.Context) {
 	scanner.Scan()
 	path := scanner.Text()
 
-	data, err := lib.LoadFromFile(path)
+	file, err := lib.OpenFile(path)
 	if err != nil {
 		fmt.Println("Error loading file please try again")
 		return
 	}
-
-	req.Data = data
+	defer file.Close()
 
 	fmt.Printf("Input file name as %s: ", yellow("'example (main)'"))
 	scanner.Scan()
@@ -76,7 +80,7 @@ func (p *BinaryDataProvider) Save(ctx context.Context) {
 	scanner.Scan()
 	req.MetaData = scanner.Text()
 
-	_, err = p.binaryDataService.SaveBinaryData(ctx, p.state.GetToken(), req)
+	_, err = p.binaryDataService.SaveBinaryDataStream(ctx, p.state.GetToken(), req, file)
 	if err != nil {
 		lib.UnpackGRPCError(err)
 		return
@@ -85,7 +89,7 @@ func (p *BinaryDataProvider) Save(ctx context.Context) {
 	fmt.Println(color.New(color.FgGreen).SprintFunc()("Binary data successfully saved"))
 }
 
-// LoadAllInfo retrieves and displays metadata for all saved binary data. The user must be authorized,
+// LoadAllInfo retrieves and displays metadata of the saved binary data page by page. The user must be authorized,
 // and a working directory must be set.
 func (p *BinaryDataProvider) LoadAllInfo(ctx context.Context) {
 	red := color.New(color.FgRed).SprintFunc()
@@ -100,30 +104,39 @@ func (p *BinaryDataProvider) LoadAllInfo(ctx context.Context) {
 		return
 	}
 
-	binariesDataInfo, err := p.binaryDataService.LoadAllBinaryDataInfo(ctx, p.state.GetToken())
-	if err != nil {
-		logrus.WithError(err).Error("All user data info load failed")
-		fmt.Println(red("All binaries data info load failed"), "please try again")
-		lib.UnpackGRPCError(err)
-		return
-	}
+	scanner := bufio.NewScanner(os.Stdin)
+	page := lib.ReadPageRequest(scanner)
+	for {
+		binariesDataInfo, err := p.binaryDataService.LoadAllBinaryDataInfo(ctx, p.state.GetToken(), page)
+		if err != nil {
+			logrus.WithError(err).Error("All user data info load failed")
+			fmt.Println(red("All binaries data info load failed"), "please try again")
+			lib.UnpackGRPCError(err)
+			return
+		}
 
-	green := color.New(color.FgGreen).SprintFunc()
-	yellow := color.New(color.FgYellow).SprintFunc()
-	fmt.Println(green("-------------------------------------"))
+		green := color.New(color.FgGreen).SprintFunc()
+		yellow := color.New(color.FgYellow).SprintFunc()
+		fmt.Println(green("-------------------------------------"))
 
-	var sb strings.Builder
-	for _, dataInfo := range binariesDataInfo {
-		sb.WriteString("Data ID: " + dataInfo.ID + "\n")
-		sb.WriteString("Data type: " + dataInfo.DataType + "\n")
-		sb.WriteString("Metadata : " + dataInfo.MetaData + "\n")
-		sb.WriteString("Created at: : " + dataInfo.CreatedAt + "\n")
-		sb.WriteString(green("-------------------------------------") + "\n")
-	}
-	if len(binariesDataInfo) > 0 {
-		fmt.Println(sb.String())
-	} else {
-		fmt.Println(yellow("Your haven't saved any data or data load filed, please try again"))
+		var sb strings.Builder
+		for _, dataInfo := range binariesDataInfo.Infos {
+			sb.WriteString("Data ID: " + dataInfo.ID + "\n")
+			sb.WriteString("Data type: " + dataInfo.DataType + "\n")
+			sb.WriteString("Metadata : " + dataInfo.MetaData + "\n")
+			sb.WriteString("Created at: : " + dataInfo.CreatedAt + "\n")
+			sb.WriteString(green("-------------------------------------") + "\n")
+		}
+		if len(binariesDataInfo.Infos) > 0 {
+			fmt.Println(sb.String())
+		} else if page.PageToken == "" {
+			fmt.Println(yellow("Your haven't saved any data or data load filed, please try again"))
+		}
+
+		if binariesDataInfo.NextPageToken == "" || !lib.ConfirmNextPage(scanner) {
+			return
+		}
+		page.PageToken = binariesDataInfo.NextPageToken
 	}
 }
 
@@ -153,19 +166,112 @@ func (p *BinaryDataProvider) LoadData(ctx context.Context) {
 	scanner.Scan()
 	req.ID = scanner.Text()
 
-	bData, err := p.binaryDataService.LoadBinaryData(ctx, p.state.GetToken(), req.ID)
+	var (
+		path string
+		file *os.File
+	)
+	create := func(bData model.BinaryData) (io.Writer, error) {
+		path = filepath.Join(p.state.GetDirPath(), "/", bData.Name+"."+bData.Extension)
+		f, err := lib.CreateFile(path)
+		file = f
+		return f, err
+	}
+
+	_, err := p.binaryDataService.LoadBinaryDataStream(ctx, p.state.GetToken(), req.ID, create)
+	if file != nil {
+		file.Close()
+	}
 	if err != nil {
+		if file != nil {
+			// Do not leave a partially written file behind
+			os.Remove(file.Name())
+			fmt.Printf("Error writing to file with path %s, please try again\n", red(path))
+		}
 		lib.UnpackGRPCError(err)
 		return
 	}
 
-	path := filepath.Join(p.state.GetDirPath(), "/", bData.Name+"."+bData.Extension)
-	err = lib.SaveBinaryToFile(path, bData.Data)
+	fmt.Printf("Data successfully written to your working dir %s\n", p.state.GetDirPath())
+}
+
+// Update prompts the user for the ID of an existing binary file and the new file details,
+// and replaces the stored binary data using the BinaryDataService. It ensures the user is authorized.
+func (p *BinaryDataProvider) Update(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	req := model.BinaryDataPutRequest{}
+	fmt.Println(cyanBold("Input binary data to update 'id path name extension metadata':"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input data ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	req.ID = scanner.Text()
+
+	fmt.Printf("Input path to your file as %s: ", yellow("'example (./downloads)'"))
+	scanner.Scan()
+	path := scanner.Text()
+
+	data, err := lib.LoadFromFile(path)
 	if err != nil {
-		fmt.Println(err)
-		fmt.Printf("Error writing to file with path %s, please try again\n", red(path))
+		fmt.Println("Error loading file please try again")
 		return
 	}
 
-	fmt.Printf("Data successfully written to your working dir %s\n", p.state.GetDirPath())
+	req.Data = data
+
+	fmt.Printf("Input file name as %s: ", yellow("'example (main)'"))
+	scanner.Scan()
+	req.Name = scanner.Text()
+
+	fmt.Printf("Input file extension as %s: ", yellow("'example (go)'"))
+	scanner.Scan()
+	req.Extension = scanner.Text()
+
+	fmt.Printf("Input file description as %s: ", yellow("'text'"))
+	scanner.Scan()
+	req.MetaData = scanner.Text()
+
+	_, err = p.binaryDataService.UpdateBinaryData(ctx, p.state.GetToken(), req)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Binary data successfully updated"))
+}
+
+// Delete prompts the user for a binary data ID and deletes it using the BinaryDataService.
+func (p *BinaryDataProvider) Delete(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input data ID to delete binary data:"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input data ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	dataID := scanner.Text()
+
+	err := p.binaryDataService.DeleteBinaryData(ctx, p.state.GetToken(), dataID)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Binary data successfully deleted"))
 }
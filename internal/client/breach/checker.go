@@ -0,0 +1,46 @@
+// Package breach checks the passwords of the vault against a breach list, either a local one
+// or the one of the server. Only the first 5 characters of the SHA-1 hash of a password are sent to the server.
+package breach
+
+import (
+	"context"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/breach"
+)
+
+// RangeService looks up ranges of the breach list of the server.
+type RangeService interface {
+	CheckPasswordBreach(ctx context.Context, token string, prefix string) ([]breach.Suffix, error)
+}
+
+// Checker checks passwords against the local breach list if there is one and against the list
+// of the server otherwise.
+type Checker struct {
+	local  breach.Ranger // Local breach list, nil if not configured
+	remote RangeService  // Breach list of the server
+}
+
+// NewChecker creates a Checker of the local breach list, which may be nil, and the list of the server.
+func NewChecker(local breach.Ranger, remote RangeService) *Checker {
+	return &Checker{local: local, remote: remote}
+}
+
+// Breached returns the number of times the password was seen in breaches, zero if it's not in the list.
+func (c *Checker) Breached(ctx context.Context, token string, password string) (int64, error) {
+	prefix, suffix := breach.Hash(password)
+
+	var (
+		suffixes []breach.Suffix
+		err      error
+	)
+	if c.local != nil {
+		suffixes, err = c.local.Range(ctx, prefix)
+	} else {
+		suffixes, err = c.remote.CheckPasswordBreach(ctx, token, prefix)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return breach.Find(suffixes, suffix), nil
+}
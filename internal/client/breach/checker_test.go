@@ -0,0 +1,67 @@
+package breach
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/breach"
+)
+
+// server is the breach list of the server backed by a local stand-in file.
+type server struct {
+	list     breach.Ranger
+	prefixes []string // Prefixes sent to the server
+}
+
+func (s *server) CheckPasswordBreach(ctx context.Context, _ string, prefix string) ([]breach.Suffix, error) {
+	s.prefixes = append(s.prefixes, prefix)
+	return s.list.Range(ctx, prefix)
+}
+
+type CheckerTestSuite struct {
+	suite.Suite
+	list   breach.Ranger
+	server *server
+}
+
+func (s *CheckerTestSuite) SetupTest() {
+	var err error
+	s.list, err = breach.Open("testdata/breached.txt")
+	require.NoError(s.T(), err)
+	s.server = &server{list: s.list}
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(CheckerTestSuite))
+}
+
+func (s *CheckerTestSuite) Test_Remote() {
+	checker := NewChecker(nil, s.server)
+
+	count, err := checker.Breached(context.Background(), "token", "password")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(9545824), count)
+
+	count, err = checker.Breached(context.Background(), "token", "x7#Kq9!vLm2@Wz4$Rt8&")
+	require.NoError(s.T(), err)
+	assert.Zero(s.T(), count)
+
+	// Only the prefixes of the hashes reach the server
+	prefix, _ := breach.Hash("password")
+	require.Len(s.T(), s.server.prefixes, 2)
+	assert.Equal(s.T(), prefix, s.server.prefixes[0])
+	assert.Len(s.T(), s.server.prefixes[1], breach.PrefixLen)
+}
+
+func (s *CheckerTestSuite) Test_Local() {
+	checker := NewChecker(s.list, s.server)
+
+	count, err := checker.Breached(context.Background(), "token", "qwerty")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(3912816), count)
+	assert.Empty(s.T(), s.server.prefixes)
+}
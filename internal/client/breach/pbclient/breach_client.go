@@ -0,0 +1,42 @@
+package pbclient
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/breach"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/breach"
+)
+
+// BreachPBClient is a client wrapper around the gRPC BreachServiceClient,
+// providing methods to look up ranges of the breach list of the server via gRPC.
+type BreachPBClient struct {
+	breachService pb.BreachServiceClient
+}
+
+// NewBreachPBClient initializes and returns a new instance of BreachPBClient
+// which will use the provided gRPC BreachServiceClient.
+func NewBreachPBClient(s pb.BreachServiceClient) *BreachPBClient {
+	return &BreachPBClient{breachService: s}
+}
+
+// CheckPasswordBreach retrieves the hashes of the breach list of the server starting with the prefix,
+// only the prefix of the hash of the password is sent.
+func (b *BreachPBClient) CheckPasswordBreach(ctx context.Context, token string, prefix string) ([]breach.Suffix, error) {
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := b.breachService.CheckPasswordBreach(ctx, &pb.CheckPasswordBreachRequest{Prefix: prefix})
+	if err != nil {
+		return nil, fmt.Errorf("check password breach: %w", err)
+	}
+
+	suffixes := make([]breach.Suffix, 0, len(resp.Suffixes))
+	for _, suffix := range resp.Suffixes {
+		suffixes = append(suffixes, breach.Suffix{Suffix: suffix.Suffix, Count: suffix.Count})
+	}
+
+	return suffixes, nil
+}
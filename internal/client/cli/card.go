@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"context"
+	"flag"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+// cardFields are the names of the fields of a credit card in the order they are printed.
+var cardFields = []string{"id", "number", "owner", "expires", "cvv", "pin", "metadata", "reminder"}
+
+// cardFlags holds the flags of the credit card fields.
+type cardFlags struct {
+	number, owner, expires, cvv, pin, metadata *string
+	remind                                     *bool
+}
+
+// newCardFlags defines the flags of the credit card fields.
+func newCardFlags(fs *flag.FlagSet) cardFlags {
+	return cardFlags{
+		number:   fs.String("number", "", "card number"),
+		owner:    fs.String("owner", "", "name of the card owner"),
+		expires:  fs.String("expires", "", "expiration date as DD-MM-YYYY"),
+		cvv:      fs.String("cvv", "", "CVV code"),
+		pin:      fs.String("pin", "", "PIN code"),
+		metadata: fs.String("metadata", "", "description of the card"),
+		remind:   fs.Bool("remind", false, "notify before the card expires"),
+	}
+}
+
+func runCardList(ctx context.Context, c *CLI, args []string) error {
+	return runList(ctx, c, args, c.services.CreditCards.LoadAllCreditCardDataInfo, false)
+}
+
+func runCardGet(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	field := fs.String("field", "", "print only the given field")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	card, err := c.services.CreditCards.LoadCreditCardData(ctx, c.state.GetToken(), pos[0])
+	if err != nil {
+		return err
+	}
+
+	values := map[string]any{
+		"id":       card.ID,
+		"number":   card.Number,
+		"owner":    card.OwnerName,
+		"expires":  card.ExpiresAt,
+		"cvv":      card.CVV,
+		"pin":      card.PinCode,
+		"metadata": card.MetaData,
+		"reminder": card.ExpiryReminder,
+	}
+	if *field == "" {
+		return c.printRecord(cardFields, values)
+	}
+
+	value, err := selectField(*field, cardFields, values)
+	if err != nil {
+		return err
+	}
+
+	return c.printField(value)
+}
+
+func runCardAdd(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	f := newCardFlags(fs)
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if err := required(fs, "number", "owner", "expires", "cvv", "pin"); err != nil {
+		return err
+	}
+
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	card, err := c.services.CreditCards.SaveCreditCard(ctx, c.state.GetToken(), model.CreditCardPostRequest{
+		Number:    *f.number,
+		OwnerName: *f.owner,
+		ExpiresAt: *f.expires,
+		CVV:       *f.cvv,
+		PinCode:   *f.pin,
+		MetaData:  *f.metadata,
+
+		ExpiryReminder: *f.remind,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.printID(card.ID)
+}
+
+// runCardUpdate replaces the fields given on the command line, the other fields keep their values.
+func runCardUpdate(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	f := newCardFlags(fs)
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	card, err := c.services.CreditCards.LoadCreditCardData(ctx, c.state.GetToken(), pos[0])
+	if err != nil {
+		return err
+	}
+
+	req := model.CreditCardPutRequest{
+		ID:        pos[0],
+		Number:    card.Number,
+		OwnerName: card.OwnerName,
+		ExpiresAt: card.ExpiresAt,
+		CVV:       card.CVV,
+		PinCode:   card.PinCode,
+		MetaData:  card.MetaData,
+		Revision:  card.Revision,
+
+		ExpiryReminder: card.ExpiryReminder,
+	}
+	override(fs, "number", *f.number, &req.Number)
+	override(fs, "owner", *f.owner, &req.OwnerName)
+	override(fs, "expires", *f.expires, &req.ExpiresAt)
+	override(fs, "cvv", *f.cvv, &req.CVV)
+	override(fs, "pin", *f.pin, &req.PinCode)
+	override(fs, "metadata", *f.metadata, &req.MetaData)
+	if isSet(fs, "remind") {
+		req.ExpiryReminder = *f.remind
+	}
+
+	updated, err := c.services.CreditCards.UpdateCreditCard(ctx, c.state.GetToken(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.printID(updated.ID)
+}
+
+func runCardDelete(ctx context.Context, c *CLI, args []string) error {
+	return runDelete(ctx, c, args, c.services.CreditCards.DeleteCreditCard)
+}
@@ -0,0 +1,393 @@
+// Package cli implements the non-interactive command line interface of the client, so the keeper
+// can be used from scripts: every command is given by the arguments, prints its result as text or JSON
+// and reports the outcome with the exit code.
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/health"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+)
+
+// Exit codes of the commands.
+const (
+	ExitOK          = 0 // Command succeeded
+	ExitError       = 1 // Command failed for another reason
+	ExitUsage       = 2 // Command line or data is invalid
+	ExitAuth        = 3 // Credentials are missing or rejected
+	ExitNotFound    = 4 // Record doesn't exist
+	ExitUnavailable = 5 // Server is unreachable
+	ExitConflict    = 6 // Record was changed on another device
+)
+
+// Environment variables holding the credentials of the user.
+const (
+	EnvLogin    = "KEEPER_LOGIN"    // Login of the user
+	EnvPassword = "KEEPER_PASSWORD" // Master password of the user
+	EnvTOTP     = "KEEPER_TOTP"     // Two-factor code of the user
+)
+
+const searchPageSize = 100 // Number of items loaded at once by the search command
+
+// Authenticator logs the user in without prompting.
+type Authenticator interface {
+	Authenticate(ctx context.Context, login, password, totpCode string) error
+}
+
+// SessionService ends the session opened by a command.
+type SessionService interface {
+	Logout(ctx context.Context, token string) error
+}
+
+// CreditCardService defines the credit card operations used by the commands.
+type CreditCardService interface {
+	SaveCreditCard(ctx context.Context, token string, card model.CreditCardPostRequest) (model.CreditCard, error)
+	LoadCreditCardData(ctx context.Context, token string, dataID string) (model.CreditCard, error)
+	LoadAllCreditCardDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCreditCard(ctx context.Context, token string, card model.CreditCardPutRequest) (model.CreditCard, error)
+	DeleteCreditCard(ctx context.Context, token string, dataID string) error
+}
+
+// TextDataService defines the text data operations used by the commands.
+type TextDataService interface {
+	SaveTextData(ctx context.Context, token string, text model.TextDataPostRequest) (model.TextData, error)
+	LoadTextData(ctx context.Context, token string, dataID string) (model.TextData, error)
+	LoadAllTextDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateTextData(ctx context.Context, token string, text model.TextDataPutRequest) (model.TextData, error)
+	DeleteTextData(ctx context.Context, token string, dataID string) error
+}
+
+// CredentialsService defines the credentials operations used by the commands.
+type CredentialsService interface {
+	SaveCredentials(ctx context.Context, token string, cred model.CredentialsPostRequest) (model.Credentials, error)
+	LoadCredentialsData(ctx context.Context, token string, dataID string) (model.Credentials, error)
+	LoadAllCredentialsDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCredentials(ctx context.Context, token string, cred model.CredentialsPutRequest) (model.Credentials, error)
+	DeleteCredentials(ctx context.Context, token string, dataID string) error
+}
+
+// BinaryDataService defines the binary data operations used by the commands.
+type BinaryDataService interface {
+	LoadAllBinaryDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	DeleteBinaryData(ctx context.Context, token string, dataID string) error
+	SaveBinaryDataStream(ctx context.Context, token string, bData model.BinaryDataStreamRequest, r io.Reader) (model.BinaryData, error)
+	LoadBinaryDataStream(ctx context.Context, token string, dataID string, create func(model.BinaryData) (io.Writer, error)) (model.BinaryData, error)
+}
+
+// SearchService defines the search of items across all data types.
+type SearchService interface {
+	SearchItems(ctx context.Context, token string, search model.SearchRequest) ([]model.DataInfo, bool, error)
+}
+
+// BreachChecker checks passwords against the breach list.
+type BreachChecker interface {
+	Breached(ctx context.Context, token string, password string) (int64, error)
+}
+
+// HealthService defines the audit of the vault.
+type HealthService interface {
+	Audit(ctx context.Context, token string, opts health.Options) (health.Report, error)
+}
+
+// CollectionService defines the operations on shared collections used by the commands.
+type CollectionService interface {
+	CreateCollection(ctx context.Context, token string, name string) (model.Collection, error)
+	LoadAllCollections(ctx context.Context, token string) ([]model.Collection, error)
+	InviteMember(ctx context.Context, token string, collectionID, login, role string) (model.CollectionMember, error)
+	AcceptInvitation(ctx context.Context, token string, collectionID string) (model.Collection, error)
+	RevokeMember(ctx context.Context, token string, collectionID, login string) error
+	LoadAllMembers(ctx context.Context, token string, collectionID string) ([]model.CollectionMember, error)
+	SaveCollectionItem(ctx context.Context, token string, item model.CollectionItemPostRequest) (model.CollectionItem, error)
+	LoadCollectionItem(ctx context.Context, token string, collectionID, itemID string) (model.CollectionItem, error)
+	LoadAllCollectionItems(ctx context.Context, token string, collectionID string) ([]model.CollectionItem, error)
+	UpdateCollectionItem(ctx context.Context, token string, item model.CollectionItemPutRequest) (model.CollectionItem, error)
+	DeleteCollectionItem(ctx context.Context, token string, collectionID, itemID string) error
+}
+
+// Services holds the services the commands are run with.
+type Services struct {
+	Auth        Authenticator
+	Sessions    SessionService
+	CreditCards CreditCardService
+	TextData    TextDataService
+	Credentials CredentialsService
+	Binaries    BinaryDataService
+	Search      SearchService
+	Breaches    BreachChecker
+	Health      HealthService
+	Collections CollectionService
+}
+
+// errUsage is returned when the command line is invalid, the usage of the command is printed to stderr.
+var errUsage = errors.New("invalid command line")
+
+// CLI runs the commands given on the command line.
+type CLI struct {
+	services Services
+	state    *state.ClientState
+	stdin    *bufio.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	getenv   func(string) string
+
+	usage  string      // Synopsis of the running command
+	common commonFlags // Flags accepted by every command
+}
+
+// commonFlags are the flags accepted by every command.
+type commonFlags struct {
+	login         string // Login of the user
+	passwordStdin bool   // Read the master password from stdin
+	totp          string // Two-factor code of the user
+	json          bool   // Print the result as JSON
+}
+
+// New creates a new CLI running the commands with the given services. Secrets may be read from stdin,
+// results are printed to stdout and errors to stderr.
+func New(services Services, state *state.ClientState, stdin io.Reader, stdout, stderr io.Writer) *CLI {
+	return &CLI{
+		services: services,
+		state:    state,
+		stdin:    bufio.NewReader(stdin),
+		stdout:   stdout,
+		stderr:   stderr,
+		getenv:   os.Getenv,
+	}
+}
+
+// command is a command of the CLI. Its run function parses the flags of the command, logs the user in and does the work.
+type command struct {
+	usage string // Synopsis of the command
+	run   func(ctx context.Context, c *CLI, args []string) error
+}
+
+// commands are the commands of the CLI by their group and action, the action of login and search is empty.
+var commands = map[string]map[string]command{
+	"login": {"": {usage: "login", run: runLogin}},
+	"card": {
+		"list":   {usage: "card list [--sort created_at|metadata] [--desc]", run: runCardList},
+		"get":    {usage: "card get <id> [--field number|owner|expires|cvv|pin|metadata|reminder]", run: runCardGet},
+		"add":    {usage: "card add --number N --owner NAME --expires DD-MM-YYYY --cvv CVV --pin PIN [--metadata TEXT] [--remind]", run: runCardAdd},
+		"update": {usage: "card update <id> [--number N] [--owner NAME] [--expires DD-MM-YYYY] [--cvv CVV] [--pin PIN] [--metadata TEXT] [--remind=true|false]", run: runCardUpdate},
+		"delete": {usage: "card delete <id>", run: runCardDelete},
+	},
+	"text": {
+		"list":   {usage: "text list [--sort created_at|metadata] [--desc]", run: runTextList},
+		"get":    {usage: "text get <id> [--field text|metadata]", run: runTextGet},
+		"add":    {usage: "text add --text TEXT|- [--metadata TEXT]", run: runTextAdd},
+		"update": {usage: "text update <id> [--text TEXT|-] [--metadata TEXT]", run: runTextUpdate},
+		"delete": {usage: "text delete <id>", run: runTextDelete},
+	},
+	"cred": {
+		"list":   {usage: "cred list [--sort created_at|metadata] [--desc] [--check-breach]", run: runCredList},
+		"get":    {usage: "cred get <id> [--field login|password|metadata|otp_secret|otp_code|otp_expires_in]", run: runCredGet},
+		"add":    {usage: "cred add --login LOGIN --password PASSWORD|- [--otp-secret SECRET] [--metadata TEXT]", run: runCredAdd},
+		"update": {usage: "cred update <id> [--login LOGIN] [--password PASSWORD|-] [--otp-secret SECRET] [--metadata TEXT]", run: runCredUpdate},
+		"delete": {usage: "cred delete <id>", run: runCredDelete},
+	},
+	"file": {
+		"list":   {usage: "file list [--sort created_at|metadata] [--desc]", run: runFileList},
+		"get":    {usage: "file get <id> [--out PATH|-]", run: runFileGet},
+		"put":    {usage: "file put <path> [--name NAME] [--ext EXT] [--metadata TEXT]", run: runFilePut},
+		"delete": {usage: "file delete <id>", run: runFileDelete},
+	},
+	"collection": {
+		"list":    {usage: "collection list", run: runCollectionList},
+		"create":  {usage: "collection create <name>", run: runCollectionCreate},
+		"members": {usage: "collection members <id>", run: runCollectionMembers},
+		"invite":  {usage: "collection invite <id> --login LOGIN [--role read|write|manage]", run: runCollectionInvite},
+		"accept":  {usage: "collection accept <id>", run: runCollectionAccept},
+		"revoke":  {usage: "collection revoke <id> [--login LOGIN]", run: runCollectionRevoke},
+		"items":   {usage: "collection items <id>", run: runCollectionItems},
+		"get":     {usage: "collection get <id> <item-id> [--field NAME]", run: runCollectionGet},
+		"add": {usage: "collection add <id> --type credentials|credit_card|text_data [--login LOGIN --password PASSWORD|- [--otp-secret SECRET]] " +
+			"[--number N --owner NAME --expires DD-MM-YYYY --cvv CVV --pin PIN] [--text TEXT|-] [--metadata TEXT]", run: runCollectionAdd},
+		"update": {usage: "collection update <id> <item-id> [--login LOGIN] [--password PASSWORD|-] [--otp-secret SECRET] " +
+			"[--number N] [--owner NAME] [--expires DD-MM-YYYY] [--cvv CVV] [--pin PIN] [--text TEXT|-] [--metadata TEXT]", run: runCollectionUpdate},
+		"delete": {usage: "collection delete <id> <item-id>", run: runCollectionDelete},
+	},
+	"search": {"": {usage: "search [--query TEXT] [--type T1,T2] [--tag TAG1,TAG2]", run: runSearch}},
+	"gen": {"": {usage: "gen [--length N] [--classes lower,upper,digits,symbols] [--exclude-ambiguous] " +
+		"[--words N [--separator S] [--capitalize] [--number]]", run: runGen}},
+	"health": {"": {usage: "health [--min-entropy BITS] [--max-age DAYS] [--expiry-days DAYS]", run: runHealth}},
+}
+
+// Run executes the command given by the arguments and returns its exit code.
+func (c *CLI) Run(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.printUsage(c.stdout)
+		return ExitOK
+	}
+
+	group, ok := commands[args[0]]
+	if !ok {
+		return c.fail(fmt.Errorf("%w: unknown command %q", errUsage, args[0]))
+	}
+
+	args0, action, args := args[0], "", args[1:]
+	if _, ok = group[""]; !ok {
+		if len(args) == 0 {
+			return c.fail(fmt.Errorf("%w: command %q needs an action", errUsage, args0))
+		}
+		action, args = args[0], args[1:]
+	}
+
+	cmd, ok := group[action]
+	if !ok {
+		return c.fail(fmt.Errorf("%w: unknown action %q", errUsage, action))
+	}
+
+	c.usage = cmd.usage
+	if err := cmd.run(ctx, c, args); err != nil {
+		return c.fail(err)
+	}
+
+	return ExitOK
+}
+
+// flagSet creates the flag set of the running command with the flags accepted by every command.
+func (c *CLI) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.usage, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&c.common.login, "user", "", "login of the user, defaults to $"+EnvLogin)
+	fs.BoolVar(&c.common.passwordStdin, "password-stdin", false, "read the master password from the first line of stdin")
+	fs.StringVar(&c.common.totp, "totp", "", "two-factor code or recovery code, defaults to $"+EnvTOTP)
+	fs.BoolVar(&c.common.json, "json", false, "print the result as JSON")
+
+	return fs
+}
+
+// parse parses the flags of the command, which may be mixed with the positional arguments,
+// and checks that the command got exactly n positional arguments.
+func (c *CLI) parse(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %w", errUsage, err)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(positional) != n {
+		return nil, fmt.Errorf("%w: expected %d arguments, got %d", errUsage, n, len(positional))
+	}
+
+	return positional, nil
+}
+
+// login logs the user in with the credentials from the flags, the environment and stdin.
+func (c *CLI) login(ctx context.Context) error {
+	login := c.common.login
+	if login == "" {
+		login = c.getenv(EnvLogin)
+	}
+	if login == "" {
+		return fmt.Errorf("%w: login is not set, use --user or $%s", errNoCredentials, EnvLogin)
+	}
+
+	password := c.getenv(EnvPassword)
+	if c.common.passwordStdin {
+		var err error
+		if password, err = c.readLine(); err != nil {
+			return fmt.Errorf("read password: %w", err)
+		}
+	}
+	if password == "" {
+		return fmt.Errorf("%w: password is not set, use --password-stdin or $%s", errNoCredentials, EnvPassword)
+	}
+
+	totp := c.common.totp
+	if totp == "" {
+		totp = c.getenv(EnvTOTP)
+	}
+
+	if err := c.services.Auth.Authenticate(ctx, login, password, totp); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+
+	return nil
+}
+
+// logout revokes the session opened by the command, a session left behind expires on its own.
+func (c *CLI) logout(ctx context.Context) {
+	if err := c.services.Sessions.Logout(ctx, c.state.GetToken()); err != nil {
+		fmt.Fprintf(c.stderr, "keeper: revoke session: %s\n", errorMessage(err))
+	}
+	c.state.SetToken("")
+	c.state.SetRefreshToken("")
+	c.state.SetIsAuthorized(false)
+	c.state.SetDataKey(nil)
+}
+
+// readLine reads a line of stdin without the line break.
+func (c *CLI) readLine() (string, error) {
+	line, err := c.stdin.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readAll reads the rest of stdin.
+func (c *CLI) readAll() (string, error) {
+	data, err := io.ReadAll(c.stdin)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// valueOrStdin returns the value of a flag, the value "-" is replaced with the rest of stdin.
+func (c *CLI) valueOrStdin(value string) (string, error) {
+	if value != "-" {
+		return value, nil
+	}
+
+	return c.readAll()
+}
+
+// secretOrStdin returns the value of a secret flag, the value "-" is replaced with the next line of stdin,
+// so secrets don't show up in the process list.
+func (c *CLI) secretOrStdin(value string) (string, error) {
+	if value != "-" {
+		return value, nil
+	}
+
+	return c.readLine()
+}
+
+// printUsage prints the usage of all commands.
+func (c *CLI) printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: keeper <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, group := range []string{"login", "card", "text", "cred", "file", "collection", "search", "gen", "health"} {
+		for _, action := range []string{"", "list", "create", "members", "invite", "accept", "revoke", "items",
+			"get", "add", "put", "update", "delete"} {
+			if cmd, ok := commands[group][action]; ok {
+				fmt.Fprintln(w, "  keeper "+cmd.usage)
+			}
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags of every command:")
+	fmt.Fprintln(w, "  --user LOGIN      login of the user, defaults to $"+EnvLogin)
+	fmt.Fprintln(w, "  --password-stdin  read the master password from the first line of stdin instead of $"+EnvPassword)
+	fmt.Fprintln(w, "  --totp CODE       two-factor code or recovery code, defaults to $"+EnvTOTP)
+	fmt.Fprintln(w, "  --json            print the result as JSON")
+}
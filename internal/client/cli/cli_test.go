@@ -0,0 +1,377 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/breach"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/health"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+	breachlist "github.com/DenisKhanov/PrivateKeeperV2/pkg/breach"
+)
+
+type fakeAuth struct {
+	state    *state.ClientState
+	err      error
+	password string
+	totp     string
+}
+
+func (f *fakeAuth) Authenticate(_ context.Context, login, password, totpCode string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.password, f.totp = password, totpCode
+	f.state.SetLogin(login)
+	f.state.SetToken("token")
+	f.state.SetIsAuthorized(true)
+	return nil
+}
+
+type fakeSessions struct {
+	revoked []string
+}
+
+func (f *fakeSessions) Logout(_ context.Context, token string) error {
+	f.revoked = append(f.revoked, token)
+	return nil
+}
+
+type fakeCredentials struct {
+	CredentialsService
+	records map[string]model.Credentials
+	updated model.CredentialsPutRequest
+	pages   []model.DataInfoPage
+}
+
+func (f *fakeCredentials) LoadCredentialsData(_ context.Context, _ string, id string) (model.Credentials, error) {
+	cred, ok := f.records[id]
+	if !ok {
+		return model.Credentials{}, fmt.Errorf("load credentials data: %w", status.Error(codes.NotFound, "data not found"))
+	}
+	return cred, nil
+}
+
+func (f *fakeCredentials) UpdateCredentials(_ context.Context, _ string, cred model.CredentialsPutRequest) (model.Credentials, error) {
+	f.updated = cred
+	return model.Credentials{ID: cred.ID}, nil
+}
+
+func (f *fakeCredentials) LoadAllCredentialsDataInfo(_ context.Context, _ string, page model.PageRequest) (model.DataInfoPage, error) {
+	if page.PageToken == "" {
+		return f.pages[0], nil
+	}
+	return f.pages[1], nil
+}
+
+type fakeHealth struct {
+	opts health.Options
+}
+
+func (f *fakeHealth) Audit(_ context.Context, _ string, opts health.Options) (health.Report, error) {
+	f.opts = opts
+	return health.Report{
+		Credentials: 2,
+		Reused:      [][]health.Item{{{ID: "id1", MetaData: "db"}, {ID: "id2", MetaData: "mail"}}},
+		ExpiringCards: []health.ExpiringCard{
+			{Item: health.Item{ID: "id3", MetaData: "visa"}, ExpiresAt: "01-07-2024", DaysLeft: 12},
+		},
+	}, nil
+}
+
+type fakeCollections struct {
+	CollectionService
+	items   map[string]model.CollectionItem
+	saved   model.CollectionItemPostRequest
+	updated model.CollectionItemPutRequest
+}
+
+func (f *fakeCollections) SaveCollectionItem(_ context.Context, _ string, item model.CollectionItemPostRequest) (model.CollectionItem, error) {
+	f.saved = item
+	return model.CollectionItem{ID: "item2"}, nil
+}
+
+func (f *fakeCollections) LoadCollectionItem(_ context.Context, _ string, collectionID, itemID string) (model.CollectionItem, error) {
+	item, ok := f.items[collectionID+"/"+itemID]
+	if !ok {
+		return model.CollectionItem{}, fmt.Errorf("load collection item: %w", status.Error(codes.NotFound, "collection not found"))
+	}
+	return item, nil
+}
+
+func (f *fakeCollections) UpdateCollectionItem(_ context.Context, _ string, item model.CollectionItemPutRequest) (model.CollectionItem, error) {
+	f.updated = item
+	return model.CollectionItem{ID: item.ID}, nil
+}
+
+type CLITestSuite struct {
+	suite.Suite
+	state    *state.ClientState
+	auth     *fakeAuth
+	sessions *fakeSessions
+	creds    *fakeCredentials
+	health   *fakeHealth
+	shared   *fakeCollections
+	breaches *breach.Checker
+	env      map[string]string
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(CLITestSuite))
+}
+
+func (s *CLITestSuite) SetupTest() {
+	s.state = state.NewClientState()
+	s.auth = &fakeAuth{state: s.state}
+	s.sessions = &fakeSessions{}
+	s.creds = &fakeCredentials{
+		records: map[string]model.Credentials{
+			"id1": {ID: "id1", Login: "admin", Password: "secret", MetaData: "db #prod", Revision: 3},
+		},
+		pages: []model.DataInfoPage{
+			{Infos: []model.DataInfo{{ID: "id1", DataType: "credentials"}}, NextPageToken: "next"},
+			{Infos: []model.DataInfo{{ID: "id2", DataType: "credentials"}}},
+		},
+	}
+	s.health = &fakeHealth{}
+	s.shared = &fakeCollections{items: map[string]model.CollectionItem{
+		"col1/item1": {ID: "item1", CollectionID: "col1", DataType: "credentials", MetaData: "prod db", Revision: 2,
+			Data: []byte(`{"Login":"postgres","Password":"secret","TOTP":""}`)},
+	}}
+	// The breach list is a local stand-in holding the password of id1
+	prefix, suffix := breachlist.Hash("secret")
+	list, err := breachlist.Load(strings.NewReader(prefix + suffix + ":42"))
+	s.Require().NoError(err)
+	s.breaches = breach.NewChecker(list, nil)
+	s.env = map[string]string{EnvLogin: "user@example.com", EnvPassword: "master"}
+}
+
+// run runs the command with the given stdin and returns its exit code, stdout and stderr.
+func (s *CLITestSuite) run(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	c := New(Services{Auth: s.auth, Sessions: s.sessions, Credentials: s.creds, Breaches: s.breaches, Health: s.health,
+		Collections: s.shared}, s.state, strings.NewReader(stdin), &stdout, &stderr)
+	c.getenv = func(key string) string { return s.env[key] }
+
+	code := c.Run(context.Background(), args)
+	return code, stdout.String(), stderr.String()
+}
+
+func (s *CLITestSuite) Test_Usage() {
+	code, stdout, _ := s.run("")
+	assert.Equal(s.T(), ExitOK, code)
+	assert.Contains(s.T(), stdout, "keeper cred get <id>")
+
+	code, _, stderr := s.run("", "vault")
+	assert.Equal(s.T(), ExitUsage, code)
+	assert.Contains(s.T(), stderr, `unknown command "vault"`)
+
+	code, _, stderr = s.run("", "cred", "get")
+	assert.Equal(s.T(), ExitUsage, code)
+	assert.Contains(s.T(), stderr, "usage: keeper cred get <id>")
+}
+
+func (s *CLITestSuite) Test_GetField() {
+	code, stdout, stderr := s.run("", "cred", "get", "id1", "--field", "password")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	assert.Equal(s.T(), "secret\n", stdout)
+	assert.Equal(s.T(), "master", s.auth.password)
+
+	// The session opened by the command is revoked
+	assert.Equal(s.T(), []string{"token"}, s.sessions.revoked)
+	assert.False(s.T(), s.state.IsAuthorized())
+
+	code, _, _ = s.run("", "cred", "get", "id1", "--field", "cvv")
+	assert.Equal(s.T(), ExitUsage, code)
+}
+
+func (s *CLITestSuite) Test_GetJSON() {
+	code, stdout, stderr := s.run("", "cred", "get", "--json", "id1")
+	require.Equal(s.T(), ExitOK, code, stderr)
+
+	var record map[string]string
+	require.NoError(s.T(), json.Unmarshal([]byte(stdout), &record))
+	assert.Equal(s.T(), map[string]string{"id": "id1", "login": "admin", "password": "secret", "metadata": "db #prod"}, record)
+}
+
+func (s *CLITestSuite) Test_UpdateKeepsFields() {
+	code, stdout, stderr := s.run("new-secret\n", "cred", "update", "id1", "--password", "-")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	assert.Equal(s.T(), "id1\n", stdout)
+
+	assert.Equal(s.T(), model.CredentialsPutRequest{
+		ID:       "id1",
+		Login:    "admin",
+		Password: "new-secret",
+		MetaData: "db #prod",
+		Revision: 3,
+	}, s.creds.updated)
+}
+
+func (s *CLITestSuite) Test_GetOneTimeCode() {
+	s.creds.records["id2"] = model.Credentials{ID: "id2", Login: "ops", Password: "secret", TOTP: "JBSWY3DPEHPK3PXP", Revision: 1}
+
+	code, stdout, stderr := s.run("", "cred", "get", "--json", "id2")
+	require.Equal(s.T(), ExitOK, code, stderr)
+
+	var record map[string]any
+	require.NoError(s.T(), json.Unmarshal([]byte(stdout), &record))
+	assert.Equal(s.T(), "JBSWY3DPEHPK3PXP", record["otp_secret"])
+	assert.Regexp(s.T(), `^\d{6}$`, record["otp_code"])
+	assert.InDelta(s.T(), 15, record["otp_expires_in"], 15)
+
+	// Credentials without a two-factor secret have no code to select
+	code, _, _ = s.run("", "cred", "get", "id1", "--field", "otp_code")
+	assert.Equal(s.T(), ExitUsage, code)
+
+	code, _, stderr = s.run("", "cred", "update", "id2", "--otp-secret", "")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	assert.Empty(s.T(), s.creds.updated.TOTP)
+	assert.Equal(s.T(), "secret", s.creds.updated.Password)
+}
+
+func (s *CLITestSuite) Test_Generate() {
+	code, stdout, stderr := s.run("", "gen", "--length", "32", "--classes", "lower,digits", "--field", "password")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	assert.Regexp(s.T(), `^[a-z0-9]{32}\n$`, stdout)
+
+	// Generation needs no login
+	assert.Empty(s.T(), s.auth.password)
+
+	code, stdout, stderr = s.run("", "gen", "--json", "--words", "5", "--separator", " ")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	var record map[string]any
+	require.NoError(s.T(), json.Unmarshal([]byte(stdout), &record))
+	assert.Len(s.T(), strings.Fields(record["password"].(string)), 5)
+	assert.Equal(s.T(), 64.6, record["entropy"])
+	assert.Equal(s.T(), "fair", record["strength"])
+
+	code, _, _ = s.run("", "gen", "--length", "4")
+	assert.Equal(s.T(), ExitUsage, code)
+	code, _, _ = s.run("", "gen", "--classes", "emoji")
+	assert.Equal(s.T(), ExitUsage, code)
+}
+
+func (s *CLITestSuite) Test_Health() {
+	code, stdout, stderr := s.run("", "health", "--max-age", "90")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	assert.Equal(s.T(), "checked: 2 credentials, 0 credit cards\n"+
+		"reused\tid1\tgroup 1\tdb\n"+
+		"reused\tid2\tgroup 1\tmail\n"+
+		"expiring\tid3\t12 days left\tvisa\n", stdout)
+	assert.Equal(s.T(), health.Options{MinEntropy: 50, MaxAgeDays: 90, ExpiryDays: 30}, s.health.opts)
+
+	code, stdout, stderr = s.run("", "health", "--json")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	var report health.Report
+	require.NoError(s.T(), json.Unmarshal([]byte(stdout), &report))
+	assert.Equal(s.T(), "id2", report.Reused[0][1].ID)
+	assert.Equal(s.T(), 12, report.ExpiringCards[0].DaysLeft)
+
+	code, _, _ = s.run("", "health", "--expiry-days", "-1")
+	assert.Equal(s.T(), ExitUsage, code)
+}
+
+func (s *CLITestSuite) Test_ListLoadsAllPages() {
+	code, stdout, stderr := s.run("", "cred", "list", "--json")
+	require.Equal(s.T(), ExitOK, code, stderr)
+
+	var infos []infoView
+	require.NoError(s.T(), json.Unmarshal([]byte(stdout), &infos))
+	require.Len(s.T(), infos, 2)
+	assert.Equal(s.T(), "id2", infos[1].ID)
+
+	code, _, _ = s.run("", "cred", "list", "--sort", "size")
+	assert.Equal(s.T(), ExitUsage, code)
+}
+
+func (s *CLITestSuite) Test_ListCheckBreach() {
+	s.creds.records["id2"] = model.Credentials{ID: "id2", Password: "x7#Kq9!vLm2@Wz4$Rt8&"}
+
+	code, stdout, stderr := s.run("", "cred", "list", "--check-breach")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	assert.Equal(s.T(), "id1\tcredentials\t\tbreached:42\t\nid2\tcredentials\t\tbreached:0\t\n", stdout)
+
+	code, stdout, stderr = s.run("", "cred", "list", "--check-breach", "--json")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	var infos []infoView
+	require.NoError(s.T(), json.Unmarshal([]byte(stdout), &infos))
+	require.Len(s.T(), infos, 2)
+	require.NotNil(s.T(), infos[1].Breached)
+	assert.Zero(s.T(), *infos[1].Breached)
+
+	// Without the flag the passwords are not loaded
+	code, stdout, _ = s.run("", "cred", "list", "--json")
+	require.Equal(s.T(), ExitOK, code)
+	assert.NotContains(s.T(), stdout, "breached")
+}
+
+func (s *CLITestSuite) Test_Credentials() {
+	delete(s.env, EnvPassword)
+	code, _, stderr := s.run("", "login")
+	assert.Equal(s.T(), ExitAuth, code)
+	assert.Contains(s.T(), stderr, "password is not set")
+
+	code, stdout, stderr := s.run("from-stdin\n", "login", "--password-stdin", "--user", "other@example.com", "--totp", "123456")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	assert.Equal(s.T(), "Logged in as other@example.com\n", stdout)
+	assert.Equal(s.T(), "from-stdin", s.auth.password)
+	assert.Equal(s.T(), "123456", s.auth.totp)
+}
+
+func (s *CLITestSuite) Test_ExitCodes() {
+	code, _, stderr := s.run("", "cred", "get", "missing")
+	assert.Equal(s.T(), ExitNotFound, code)
+	assert.Equal(s.T(), "keeper: load credentials data: data not found\n", stderr)
+
+	s.auth.err = status.Error(codes.Unauthenticated, "invalid login or password")
+	code, _, _ = s.run("", "cred", "get", "id1")
+	assert.Equal(s.T(), ExitAuth, code)
+
+	s.auth.err = status.Error(codes.Unavailable, "connection refused")
+	code, _, _ = s.run("", "cred", "get", "id1")
+	assert.Equal(s.T(), ExitUnavailable, code)
+
+	assert.Equal(s.T(), ExitConflict, exitCode(fmt.Errorf("update: %w", status.Error(codes.Aborted, "conflict"))))
+	assert.Equal(s.T(), ExitError, exitCode(io.ErrUnexpectedEOF))
+}
+
+func (s *CLITestSuite) Test_CollectionItems() {
+	code, stdout, stderr := s.run("", "collection", "get", "col1", "item1", "--field", "password")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	assert.Equal(s.T(), "secret\n", stdout)
+
+	code, stdout, stderr = s.run("p4ss\n", "collection", "add", "col1", "--type", "credentials",
+		"--login", "ops", "--password", "-", "--metadata", "ssh")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	assert.Equal(s.T(), "item2\n", stdout)
+	assert.Equal(s.T(), "col1", s.shared.saved.CollectionID)
+	assert.JSONEq(s.T(), `{"Login":"ops","Password":"p4ss","TOTP":""}`, string(s.shared.saved.Data))
+
+	// Fields of other data types are rejected
+	code, _, stderr = s.run("", "collection", "add", "col1", "--type", "text_data", "--text", "note", "--cvv", "123")
+	assert.Equal(s.T(), ExitUsage, code)
+	assert.Contains(s.T(), stderr, "flag --cvv doesn't apply to text_data")
+
+	// The update keeps the fields not given on the command line
+	code, _, stderr = s.run("", "collection", "update", "col1", "item1", "--password", "rotated")
+	require.Equal(s.T(), ExitOK, code, stderr)
+	assert.Equal(s.T(), int64(2), s.shared.updated.Revision)
+	assert.Equal(s.T(), "prod db", s.shared.updated.MetaData)
+	assert.JSONEq(s.T(), `{"Login":"postgres","Password":"rotated","TOTP":""}`, string(s.shared.updated.Data))
+
+	code, _, _ = s.run("", "collection", "get", "col1", "missing")
+	assert.Equal(s.T(), ExitNotFound, code)
+}
@@ -0,0 +1,517 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+// collectionItemFields are the names of the fields of the items of a collection by their data type,
+// in the order they are printed.
+var collectionItemFields = map[string][]string{
+	"credentials": {"login", "password", "otp_secret"},
+	"credit_card": {"number", "owner", "expires", "cvv", "pin"},
+	"text_data":   {"text"},
+}
+
+// collectionItemRequired are the fields required to add an item of the data type.
+var collectionItemRequired = map[string][]string{
+	"credentials": {"login", "password"},
+	"credit_card": {"number", "owner", "expires", "cvv", "pin"},
+	"text_data":   {"text"},
+}
+
+// collectionView is the JSON view of a collection.
+type collectionView struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+}
+
+// memberView is the JSON view of a member of a collection.
+type memberView struct {
+	Login     string `json:"login"`
+	Role      string `json:"role"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+}
+
+// itemFlags holds the flags of the fields of collection items by the field name.
+type itemFlags map[string]*string
+
+// newItemFlags defines the flags of the fields of all data types, the flag of a field is named
+// like the field with dashes.
+func newItemFlags(fs *flag.FlagSet) itemFlags {
+	return itemFlags{
+		"login":      fs.String("login", "", "login of the credentials"),
+		"password":   fs.String("password", "", "password of the credentials, - reads it from stdin"),
+		"otp_secret": fs.String("otp-secret", "", "two-factor secret as base32 or an otpauth://totp URI"),
+		"number":     fs.String("number", "", "card number"),
+		"owner":      fs.String("owner", "", "name of the card owner"),
+		"expires":    fs.String("expires", "", "expiration date as DD-MM-YYYY"),
+		"cvv":        fs.String("cvv", "", "CVV code"),
+		"pin":        fs.String("pin", "", "PIN code"),
+		"text":       fs.String("text", "", "text, - reads it from stdin"),
+	}
+}
+
+// apply sets the fields given on the command line, reading the password and the text from stdin when asked.
+// Fields not belonging to the data type are rejected.
+func (f itemFlags) apply(c *CLI, fs *flag.FlagSet, dataType string, values map[string]string) error {
+	for name, value := range f {
+		flagName := strings.ReplaceAll(name, "_", "-")
+		if !isSet(fs, flagName) {
+			continue
+		}
+		if !contains(collectionItemFields[dataType], name) {
+			return fmt.Errorf("%w: flag --%s doesn't apply to %s", errUsage, flagName, dataType)
+		}
+
+		var err error
+		switch name {
+		case "password":
+			values[name], err = c.secretOrStdin(*value)
+		case "text":
+			values[name], err = c.valueOrStdin(*value)
+		default:
+			values[name] = *value
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func runCollectionList(ctx context.Context, c *CLI, args []string) error {
+	if _, err := c.parse(c.flagSet(), args, 0); err != nil {
+		return err
+	}
+
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	collections, err := c.services.Collections.LoadAllCollections(ctx, c.state.GetToken())
+	if err != nil {
+		return err
+	}
+
+	views := make([]collectionView, 0, len(collections))
+	for _, collection := range collections {
+		views = append(views, collectionView(collection))
+	}
+
+	return c.print(views, func(w io.Writer) {
+		for _, v := range views {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.ID, v.Role, v.Status, v.Name)
+		}
+	})
+}
+
+func runCollectionCreate(ctx context.Context, c *CLI, args []string) error {
+	pos, err := c.parse(c.flagSet(), args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	collection, err := c.services.Collections.CreateCollection(ctx, c.state.GetToken(), pos[0])
+	if err != nil {
+		return err
+	}
+
+	return c.printID(collection.ID)
+}
+
+func runCollectionMembers(ctx context.Context, c *CLI, args []string) error {
+	pos, err := c.parse(c.flagSet(), args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	members, err := c.services.Collections.LoadAllMembers(ctx, c.state.GetToken(), pos[0])
+	if err != nil {
+		return err
+	}
+
+	views := make([]memberView, 0, len(members))
+	for _, member := range members {
+		views = append(views, memberView{
+			Login:     member.Login,
+			Role:      member.Role,
+			Status:    member.Status,
+			CreatedAt: member.CreatedAt,
+		})
+	}
+
+	return c.print(views, func(w io.Writer) {
+		for _, v := range views {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", v.Login, v.Role, v.Status)
+		}
+	})
+}
+
+func runCollectionInvite(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	login := fs.String("login", "", "login of the invited user")
+	role := fs.String("role", model.RoleRead, "role of the invited user, read, write or manage")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = required(fs, "login"); err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	member, err := c.services.Collections.InviteMember(ctx, c.state.GetToken(), pos[0], *login, *role)
+	if err != nil {
+		return err
+	}
+
+	return c.print(memberView{
+		Login:     member.Login,
+		Role:      member.Role,
+		Status:    member.Status,
+		CreatedAt: member.CreatedAt,
+	}, func(w io.Writer) {
+		fmt.Fprintf(w, "Invited %s as %s\n", member.Login, member.Role)
+	})
+}
+
+func runCollectionAccept(ctx context.Context, c *CLI, args []string) error {
+	pos, err := c.parse(c.flagSet(), args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	collection, err := c.services.Collections.AcceptInvitation(ctx, c.state.GetToken(), pos[0])
+	if err != nil {
+		return err
+	}
+
+	return c.print(collectionView(collection), func(w io.Writer) {
+		fmt.Fprintf(w, "Joined %s as %s\n", collection.Name, collection.Role)
+	})
+}
+
+// runCollectionRevoke removes a member from the collection, without --login the user leaves the collection.
+func runCollectionRevoke(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	login := fs.String("login", "", "login of the revoked member, defaults to the user")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	if err = c.services.Collections.RevokeMember(ctx, c.state.GetToken(), pos[0], *login); err != nil {
+		return err
+	}
+
+	revoked := *login
+	if revoked == "" {
+		revoked = c.state.GetLogin()
+	}
+
+	return c.print(map[string]string{"revoked": revoked}, func(w io.Writer) {
+		fmt.Fprintf(w, "Revoked %s\n", revoked)
+	})
+}
+
+func runCollectionItems(ctx context.Context, c *CLI, args []string) error {
+	pos, err := c.parse(c.flagSet(), args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	items, err := c.services.Collections.LoadAllCollectionItems(ctx, c.state.GetToken(), pos[0])
+	if err != nil {
+		return err
+	}
+
+	infos := make([]model.DataInfo, 0, len(items))
+	for _, item := range items {
+		infos = append(infos, model.DataInfo{
+			ID:        item.ID,
+			DataType:  item.DataType,
+			MetaData:  item.MetaData,
+			CreatedAt: item.CreatedAt,
+			UpdatedAt: item.UpdatedAt,
+			Revision:  item.Revision,
+		})
+	}
+
+	return c.printInfos(infos, nil)
+}
+
+func runCollectionGet(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	field := fs.String("field", "", "print only the given field")
+	pos, err := c.parse(fs, args, 2)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	item, err := c.services.Collections.LoadCollectionItem(ctx, c.state.GetToken(), pos[0], pos[1])
+	if err != nil {
+		return err
+	}
+
+	fields, err := decodeCollectionItem(item.DataType, item.Data)
+	if err != nil {
+		return err
+	}
+
+	names := append([]string{"id", "type"}, collectionItemFields[item.DataType]...)
+	names = append(names, "metadata")
+	values := map[string]any{
+		"id":       item.ID,
+		"type":     item.DataType,
+		"metadata": item.MetaData,
+	}
+	for name, value := range fields {
+		values[name] = value
+	}
+	if *field == "" {
+		return c.printRecord(names, values)
+	}
+
+	value, err := selectField(*field, names, values)
+	if err != nil {
+		return err
+	}
+
+	return c.printField(value)
+}
+
+func runCollectionAdd(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	dataType := fs.String("type", "", "data type of the item, credentials, credit_card or text_data")
+	item := newItemFlags(fs)
+	metadata := fs.String("metadata", "", "description of the item")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := collectionItemFields[*dataType]; !ok {
+		return fmt.Errorf("%w: unknown data type %q", errUsage, *dataType)
+	}
+	for _, name := range collectionItemRequired[*dataType] {
+		if err = required(fs, strings.ReplaceAll(name, "_", "-")); err != nil {
+			return err
+		}
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	fields := make(map[string]string)
+	if err = item.apply(c, fs, *dataType, fields); err != nil {
+		return err
+	}
+
+	data, err := encodeCollectionItem(*dataType, fields)
+	if err != nil {
+		return err
+	}
+
+	saved, err := c.services.Collections.SaveCollectionItem(ctx, c.state.GetToken(), model.CollectionItemPostRequest{
+		CollectionID: pos[0],
+		DataType:     *dataType,
+		Data:         data,
+		MetaData:     *metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.printID(saved.ID)
+}
+
+// runCollectionUpdate replaces the fields given on the command line, the other fields keep their values.
+func runCollectionUpdate(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	item := newItemFlags(fs)
+	metadata := fs.String("metadata", "", "new description of the item")
+	pos, err := c.parse(fs, args, 2)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	current, err := c.services.Collections.LoadCollectionItem(ctx, c.state.GetToken(), pos[0], pos[1])
+	if err != nil {
+		return err
+	}
+
+	fields, err := decodeCollectionItem(current.DataType, current.Data)
+	if err != nil {
+		return err
+	}
+	if err = item.apply(c, fs, current.DataType, fields); err != nil {
+		return err
+	}
+
+	data, err := encodeCollectionItem(current.DataType, fields)
+	if err != nil {
+		return err
+	}
+
+	req := model.CollectionItemPutRequest{
+		ID:           pos[1],
+		CollectionID: pos[0],
+		Data:         data,
+		MetaData:     current.MetaData,
+		Revision:     current.Revision,
+	}
+	override(fs, "metadata", *metadata, &req.MetaData)
+
+	updated, err := c.services.Collections.UpdateCollectionItem(ctx, c.state.GetToken(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.printID(updated.ID)
+}
+
+func runCollectionDelete(ctx context.Context, c *CLI, args []string) error {
+	pos, err := c.parse(c.flagSet(), args, 2)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	if err = c.services.Collections.DeleteCollectionItem(ctx, c.state.GetToken(), pos[0], pos[1]); err != nil {
+		return err
+	}
+
+	return c.print(map[string]string{"deleted": pos[1]}, func(w io.Writer) {
+		fmt.Fprintf(w, "Deleted %s\n", pos[1])
+	})
+}
+
+// encodeCollectionItem returns the data of an item of the data type, the JSON of its crypt data.
+func encodeCollectionItem(dataType string, fields map[string]string) ([]byte, error) {
+	var v any
+	switch dataType {
+	case "credentials":
+		v = model.CredentialsCryptData{Login: fields["login"], Password: fields["password"], TOTP: fields["otp_secret"]}
+	case "credit_card":
+		v = model.CreditCardCryptData{
+			Number:    fields["number"],
+			OwnerName: fields["owner"],
+			ExpiresAt: fields["expires"],
+			CVV:       fields["cvv"],
+			PinCode:   fields["pin"],
+		}
+	case "text_data":
+		v = model.TextCryptData{Text: fields["text"]}
+	default:
+		return nil, fmt.Errorf("unknown data type %q", dataType)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode item: %w", err)
+	}
+
+	return data, nil
+}
+
+// decodeCollectionItem returns the fields of the data of an item of the data type.
+func decodeCollectionItem(dataType string, data []byte) (map[string]string, error) {
+	switch dataType {
+	case "credentials":
+		var v model.CredentialsCryptData
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("decode item: %w", err)
+		}
+		return map[string]string{"login": v.Login, "password": v.Password, "otp_secret": v.TOTP}, nil
+	case "credit_card":
+		var v model.CreditCardCryptData
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("decode item: %w", err)
+		}
+		return map[string]string{
+			"number":  v.Number,
+			"owner":   v.OwnerName,
+			"expires": v.ExpiresAt,
+			"cvv":     v.CVV,
+			"pin":     v.PinCode,
+		}, nil
+	case "text_data":
+		var v model.TextCryptData
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("decode item: %w", err)
+		}
+		return map[string]string{"text": v.Text}, nil
+	default:
+		return nil, fmt.Errorf("unknown data type %q", dataType)
+	}
+}
+
+// contains reports whether the list holds the value.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+// loadPage loads a page of the infos of records of one data type.
+type loadPage func(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+
+// runLogin checks the credentials of the user, so scripts can verify them before running other commands.
+func runLogin(ctx context.Context, c *CLI, args []string) error {
+	if _, err := c.parse(c.flagSet(), args, 0); err != nil {
+		return err
+	}
+
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	return c.print(map[string]string{"login": c.state.GetLogin()}, func(w io.Writer) {
+		fmt.Fprintf(w, "Logged in as %s\n", c.state.GetLogin())
+	})
+}
+
+// runList prints the infos of all records of one data type. With breaches the command accepts --check-breach,
+// which checks the passwords of the listed credentials against the breach list.
+func runList(ctx context.Context, c *CLI, args []string, load loadPage, breaches bool) error {
+	fs := c.flagSet()
+	sortBy := fs.String("sort", model.SortByCreatedAt, "sort field, created_at or metadata")
+	desc := fs.Bool("desc", false, "sort in descending order")
+	checkBreach := new(bool)
+	if breaches {
+		checkBreach = fs.Bool("check-breach", false, "mark the credentials with passwords found in the breach list")
+	}
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *sortBy != model.SortByCreatedAt && *sortBy != model.SortByMetadata {
+		return fmt.Errorf("%w: unknown sort field %q", errUsage, *sortBy)
+	}
+
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	infos, err := lib.LoadAllInfos(ctx, c.state.GetToken(), model.PageRequest{SortBy: *sortBy, Descending: *desc}, load)
+	if err != nil {
+		return err
+	}
+
+	if !*checkBreach {
+		return c.printInfos(infos, nil)
+	}
+
+	breached := make(map[string]int64, len(infos))
+	for _, info := range infos {
+		cred, err := c.services.Credentials.LoadCredentialsData(ctx, c.state.GetToken(), info.ID)
+		if err != nil {
+			return err
+		}
+		if breached[info.ID], err = c.services.Breaches.Breached(ctx, c.state.GetToken(), cred.Password); err != nil {
+			return err
+		}
+	}
+
+	return c.printInfos(infos, breached)
+}
+
+// runSearch prints the items of all data types matching the filters.
+func runSearch(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	var req model.SearchRequest
+	fs.StringVar(&req.Query, "query", "", "text to search for in the metadata")
+	types := fs.String("type", "", "comma separated data types")
+	tags := fs.String("tag", "", "comma separated tags")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	req.Types, req.Tags = splitList(*types), splitList(*tags)
+
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	req.Limit = searchPageSize
+	var items []model.DataInfo
+	for {
+		found, hasMore, err := c.services.Search.SearchItems(ctx, c.state.GetToken(), req)
+		if err != nil {
+			return err
+		}
+		items = append(items, found...)
+
+		if !hasMore || len(found) == 0 {
+			break
+		}
+		req.Offset += len(found)
+	}
+
+	return c.printInfos(items, nil)
+}
+
+// selectField returns the value of the field requested with --field, the names are listed in the order of the record.
+func selectField(field string, names []string, values map[string]any) (string, error) {
+	v, ok := values[field]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown field %q, expected one of %s", errUsage, field, strings.Join(names, ", "))
+	}
+
+	return fmt.Sprint(v), nil
+}
+
+// isSet reports whether the flag was given on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
+// required checks that the flags were given on the command line.
+func required(fs *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if !isSet(fs, name) {
+			return fmt.Errorf("%w: flag --%s is required", errUsage, name)
+		}
+	}
+
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// runDelete deletes a record of one data type by its ID.
+func runDelete(ctx context.Context, c *CLI, args []string, del func(ctx context.Context, token string, dataID string) error) error {
+	pos, err := c.parse(c.flagSet(), args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	if err = del(ctx, c.state.GetToken(), pos[0]); err != nil {
+		return err
+	}
+
+	return c.print(map[string]string{"deleted": pos[0]}, func(w io.Writer) {
+		fmt.Fprintf(w, "Deleted %s\n", pos[0])
+	})
+}
+
+// override sets the field to the value of the flag when the flag was given on the command line.
+func override(fs *flag.FlagSet, name, value string, field *string) {
+	if isSet(fs, name) {
+		*field = value
+	}
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+// credentialsFields are the names of the fields of credentials in the order they are printed.
+var credentialsFields = []string{"id", "login", "password", "metadata"}
+
+// otpFields are the names of the fields of the two-factor secret, printed for the credentials that have one.
+var otpFields = []string{"otp_secret", "otp_code", "otp_expires_in"}
+
+func runCredList(ctx context.Context, c *CLI, args []string) error {
+	return runList(ctx, c, args, c.services.Credentials.LoadAllCredentialsDataInfo, true)
+}
+
+func runCredGet(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	field := fs.String("field", "", "print only the given field")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	cred, err := c.services.Credentials.LoadCredentialsData(ctx, c.state.GetToken(), pos[0])
+	if err != nil {
+		return err
+	}
+
+	names := credentialsFields
+	values := map[string]any{
+		"id":       cred.ID,
+		"login":    cred.Login,
+		"password": cred.Password,
+		"metadata": cred.MetaData,
+	}
+	if cred.TOTP != "" {
+		code, remaining, err := lib.OneTimeCode(cred.TOTP, time.Now())
+		if err != nil {
+			return err
+		}
+		names = append(names[:len(names):len(names)], otpFields...)
+		values["otp_secret"] = cred.TOTP
+		values["otp_code"] = code
+		values["otp_expires_in"] = int(remaining.Round(time.Second) / time.Second)
+	}
+	if *field == "" {
+		return c.printRecord(names, values)
+	}
+
+	value, err := selectField(*field, names, values)
+	if err != nil {
+		return err
+	}
+
+	return c.printField(value)
+}
+
+func runCredAdd(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	login := fs.String("login", "", "login to save")
+	password := fs.String("password", "", "password to save, - reads it from stdin")
+	otpSecret := fs.String("otp-secret", "", "two-factor secret as base32 or an otpauth://totp URI")
+	metadata := fs.String("metadata", "", "description of the credentials")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if err := required(fs, "login", "password"); err != nil {
+		return err
+	}
+
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	value, err := c.secretOrStdin(*password)
+	if err != nil {
+		return err
+	}
+
+	saved, err := c.services.Credentials.SaveCredentials(ctx, c.state.GetToken(), model.CredentialsPostRequest{
+		Login:    *login,
+		Password: value,
+		TOTP:     *otpSecret,
+		MetaData: *metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.printID(saved.ID)
+}
+
+// runCredUpdate replaces the fields given on the command line, the other fields keep their values.
+func runCredUpdate(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	login := fs.String("login", "", "new login")
+	password := fs.String("password", "", "new password, - reads it from stdin")
+	otpSecret := fs.String("otp-secret", "", "new two-factor secret, empty removes it")
+	metadata := fs.String("metadata", "", "new description of the credentials")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	current, err := c.services.Credentials.LoadCredentialsData(ctx, c.state.GetToken(), pos[0])
+	if err != nil {
+		return err
+	}
+
+	value, err := c.secretOrStdin(*password)
+	if err != nil {
+		return err
+	}
+
+	req := model.CredentialsPutRequest{
+		ID:       pos[0],
+		Login:    current.Login,
+		Password: current.Password,
+		TOTP:     current.TOTP,
+		MetaData: current.MetaData,
+		Revision: current.Revision,
+	}
+	override(fs, "login", *login, &req.Login)
+	override(fs, "password", value, &req.Password)
+	override(fs, "otp-secret", *otpSecret, &req.TOTP)
+	override(fs, "metadata", *metadata, &req.MetaData)
+
+	updated, err := c.services.Credentials.UpdateCredentials(ctx, c.state.GetToken(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.printID(updated.ID)
+}
+
+func runCredDelete(ctx context.Context, c *CLI, args []string) error {
+	return runDelete(ctx, c, args, c.services.Credentials.DeleteCredentials)
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+func runFileList(ctx context.Context, c *CLI, args []string) error {
+	return runList(ctx, c, args, c.services.Binaries.LoadAllBinaryDataInfo, false)
+}
+
+// runFileGet writes a stored file to the path given with --out. When the path is a directory or is not given,
+// the file is written into it under its stored name, the path "-" writes the file to stdout.
+func runFileGet(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	out := fs.String("out", ".", "file or directory to write to, - writes to stdout")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if *out == "-" && c.common.json {
+		return fmt.Errorf("%w: --json can't be used with --out -", errUsage)
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	var file *os.File
+	create := func(bData model.BinaryData) (io.Writer, error) {
+		if *out == "-" {
+			return c.stdout, nil
+		}
+
+		path := *out
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			path = filepath.Join(path, bData.Name+"."+bData.Extension)
+		}
+
+		f, err := lib.CreateFile(path)
+		file = f
+		return f, err
+	}
+
+	_, err = c.services.Binaries.LoadBinaryDataStream(ctx, c.state.GetToken(), pos[0], create)
+	if file != nil {
+		file.Close()
+	}
+	if err != nil {
+		if file != nil {
+			// Do not leave a partially written file behind
+			os.Remove(file.Name())
+		}
+		return err
+	}
+
+	if file == nil {
+		return nil
+	}
+
+	return c.print(map[string]string{"path": file.Name()}, func(w io.Writer) {
+		fmt.Fprintln(w, file.Name())
+	})
+}
+
+// runFilePut stores a file, its name and extension are taken from the path unless given with flags.
+func runFilePut(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	name := fs.String("name", "", "name of the file, defaults to the name in the path")
+	ext := fs.String("ext", "", "extension of the file, defaults to the extension in the path")
+	metadata := fs.String("metadata", "", "description of the file")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	base := filepath.Base(pos[0])
+	if !isSet(fs, "ext") {
+		*ext = strings.TrimPrefix(filepath.Ext(base), ".")
+	}
+	if !isSet(fs, "name") {
+		*name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	file, err := lib.OpenFile(pos[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	saved, err := c.services.Binaries.SaveBinaryDataStream(ctx, c.state.GetToken(), model.BinaryDataStreamRequest{
+		Name:      *name,
+		Extension: *ext,
+		MetaData:  *metadata,
+	}, file)
+	if err != nil {
+		return err
+	}
+
+	return c.printID(saved.ID)
+}
+
+func runFileDelete(ctx context.Context, c *CLI, args []string) error {
+	return runDelete(ctx, c, args, c.services.Binaries.DeleteBinaryData)
+}
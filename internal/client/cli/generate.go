@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/passgen"
+)
+
+// generatedFields are the names of the fields of a generated password in the order they are printed.
+var generatedFields = []string{"password", "entropy", "strength"}
+
+// runGen prints a generated password or, with --words, a passphrase. It needs neither the login nor the server.
+func runGen(_ context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	field := fs.String("field", "", "print only the given field")
+	length := fs.Int("length", passgen.DefaultPasswordOptions().Length, "number of characters of the password")
+	classes := fs.String("classes", strings.Join(passgen.Classes, ","), "comma separated character classes of the password")
+	excludeAmbiguous := fs.Bool("exclude-ambiguous", false, "leave out characters that are easy to confuse, like O and 0")
+	words := fs.Int("words", 0, "generate a passphrase of the given number of words instead of a password")
+	separator := fs.String("separator", passgen.DefaultPassphraseOptions().Separator, "separator of the words of the passphrase")
+	capitalize := fs.Bool("capitalize", false, "capitalize the words of the passphrase")
+	number := fs.Bool("number", false, "append a digit to one of the words of the passphrase")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	var (
+		secret passgen.Secret
+		err    error
+	)
+	if isSet(fs, "words") {
+		secret, err = passgen.Passphrase(passgen.PassphraseOptions{
+			Words: *words, Separator: *separator, Capitalize: *capitalize, Number: *number,
+		})
+	} else {
+		opts := passgen.PasswordOptions{Length: *length, ExcludeAmbiguous: *excludeAmbiguous}
+		if err = opts.SetClasses(splitList(*classes)); err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
+		}
+		secret, err = passgen.Password(opts)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	values := map[string]any{
+		"password": secret.Value,
+		"entropy":  math.Round(secret.Entropy*10) / 10,
+		"strength": passgen.Strength(secret.Entropy),
+	}
+	if *field == "" {
+		return c.printRecord(generatedFields, values)
+	}
+
+	value, err := selectField(*field, generatedFields, values)
+	if err != nil {
+		return err
+	}
+
+	return c.printField(value)
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/health"
+)
+
+// runHealth prints the weak, reused and old passwords and the cards that expire soon.
+func runHealth(ctx context.Context, c *CLI, args []string) error {
+	opts := health.DefaultOptions()
+	fs := c.flagSet()
+	fs.Float64Var(&opts.MinEntropy, "min-entropy", opts.MinEntropy, "passwords with less estimated entropy in bits are weak")
+	fs.IntVar(&opts.MaxAgeDays, "max-age", opts.MaxAgeDays, "passwords not changed for more days are old, 0 disables the check")
+	fs.IntVar(&opts.ExpiryDays, "expiry-days", opts.ExpiryDays, "report cards expiring within the number of days")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if opts.MinEntropy < 0 || opts.MaxAgeDays < 0 || opts.ExpiryDays < 0 {
+		return fmt.Errorf("%w: thresholds must not be negative", errUsage)
+	}
+
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	report, err := c.services.Health.Audit(ctx, c.state.GetToken(), opts)
+	if err != nil {
+		return err
+	}
+
+	return c.print(report, func(w io.Writer) {
+		fmt.Fprintf(w, "checked: %d credentials, %d credit cards\n", report.Credentials, report.CreditCards)
+		for _, weak := range report.Weak {
+			fmt.Fprintf(w, "weak\t%s\t%s (%.1f bits)\t%s\n", weak.ID, weak.Strength, weak.Entropy, weak.MetaData)
+		}
+		for i, group := range report.Reused {
+			for _, item := range group {
+				fmt.Fprintf(w, "reused\t%s\tgroup %d\t%s\n", item.ID, i+1, item.MetaData)
+			}
+		}
+		for _, old := range report.Old {
+			fmt.Fprintf(w, "old\t%s\t%d days\t%s\n", old.ID, old.AgeDays, old.MetaData)
+		}
+		for _, card := range report.ExpiringCards {
+			fmt.Fprintf(w, "expiring\t%s\t%d days left\t%s\n", card.ID, card.DaysLeft, card.MetaData)
+		}
+	})
+}
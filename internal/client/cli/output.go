@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+// errNoCredentials is returned when the login or the password of the user is not given.
+var errNoCredentials = errors.New("no credentials")
+
+// infoView is the JSON view of the info of a record.
+type infoView struct {
+	ID        string   `json:"id"`
+	DataType  string   `json:"data_type"`
+	MetaData  string   `json:"metadata"`
+	Tags      []string `json:"tags,omitempty"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at,omitempty"`
+	Revision  int64    `json:"revision,omitempty"`
+	Breached  *int64   `json:"breached,omitempty"` // Times the password was seen in breaches, set by --check-breach
+}
+
+// fail prints the error to stderr and returns the exit code matching it.
+func (c *CLI) fail(err error) int {
+	fmt.Fprintf(c.stderr, "keeper: %s\n", errorMessage(err))
+	if errors.Is(err, errUsage) && c.usage != "" {
+		fmt.Fprintf(c.stderr, "usage: keeper %s\n", c.usage)
+	}
+
+	return exitCode(err)
+}
+
+// exitCode returns the exit code matching the error.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, errUsage):
+		return ExitUsage
+	case errors.Is(err, errNoCredentials):
+		return ExitAuth
+	}
+
+	st, ok := grpcStatus(err)
+	if !ok {
+		return ExitError
+	}
+
+	switch st.Code() {
+	case codes.InvalidArgument:
+		return ExitUsage
+	case codes.Unauthenticated, codes.PermissionDenied, codes.FailedPrecondition:
+		return ExitAuth
+	case codes.NotFound:
+		return ExitNotFound
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return ExitUnavailable
+	case codes.Aborted:
+		return ExitConflict
+	default:
+		return ExitError
+	}
+}
+
+// errorMessage returns the message of the error, gRPC errors are reduced to their message and field violations.
+func errorMessage(err error) string {
+	st, ok := grpcStatus(err)
+	if !ok {
+		return err.Error()
+	}
+
+	msg := st.Message()
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range br.GetFieldViolations() {
+				msg += fmt.Sprintf("; %s %s", violation.GetField(), violation.GetDescription())
+			}
+		}
+	}
+
+	// The prefix added by the client is kept to tell which call failed
+	if prefix, _, found := strings.Cut(err.Error(), ": rpc error:"); found {
+		msg = prefix + ": " + msg
+	}
+
+	return msg
+}
+
+// grpcStatus returns the status of the gRPC error wrapped by the error.
+func grpcStatus(err error) (*status.Status, bool) {
+	var se interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &se) {
+		return se.GRPCStatus(), true
+	}
+
+	return nil, false
+}
+
+// print prints the value as JSON when --json is given, otherwise the text function prints it as text.
+func (c *CLI) print(v any, text func(w io.Writer)) error {
+	if !c.common.json {
+		text(c.stdout)
+		return nil
+	}
+
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode result: %w", err)
+	}
+
+	return nil
+}
+
+// printField prints a single field of a record, as a JSON string when --json is given and as is otherwise.
+func (c *CLI) printField(value string) error {
+	return c.print(value, func(w io.Writer) {
+		fmt.Fprintln(w, value)
+	})
+}
+
+// printID prints the ID of a saved record.
+func (c *CLI) printID(id string) error {
+	return c.print(map[string]string{"id": id}, func(w io.Writer) {
+		fmt.Fprintln(w, id)
+	})
+}
+
+// printInfos prints the infos of records, one per line as text. The breach counts by ID, if given,
+// are printed before the metadata.
+func (c *CLI) printInfos(infos []model.DataInfo, breached map[string]int64) error {
+	views := make([]infoView, 0, len(infos))
+	for _, info := range infos {
+		views = append(views, infoView{
+			ID:        info.ID,
+			DataType:  info.DataType,
+			MetaData:  info.MetaData,
+			Tags:      info.Tags,
+			CreatedAt: info.CreatedAt,
+			UpdatedAt: info.UpdatedAt,
+			Revision:  info.Revision,
+		})
+		if count, ok := breached[info.ID]; ok {
+			views[len(views)-1].Breached = &count
+		}
+	}
+
+	return c.print(views, func(w io.Writer) {
+		for _, v := range views {
+			if v.Breached != nil {
+				fmt.Fprintf(w, "%s\t%s\t%s\tbreached:%d\t%s\n", v.ID, v.DataType, v.CreatedAt, *v.Breached, v.MetaData)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.ID, v.DataType, v.CreatedAt, v.MetaData)
+		}
+	})
+}
+
+// printRecord prints the fields of a record in the given order, as a JSON object when --json is given
+// and as 'name: value' lines otherwise.
+func (c *CLI) printRecord(names []string, values map[string]any) error {
+	return c.print(values, func(w io.Writer) {
+		for _, name := range names {
+			fmt.Fprintf(w, "%s: %v\n", name, values[name])
+		}
+	})
+}
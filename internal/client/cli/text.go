@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+// textFields are the names of the fields of text data in the order they are printed.
+var textFields = []string{"id", "text", "metadata"}
+
+func runTextList(ctx context.Context, c *CLI, args []string) error {
+	return runList(ctx, c, args, c.services.TextData.LoadAllTextDataInfo, false)
+}
+
+func runTextGet(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	field := fs.String("field", "", "print only the given field")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	text, err := c.services.TextData.LoadTextData(ctx, c.state.GetToken(), pos[0])
+	if err != nil {
+		return err
+	}
+
+	values := map[string]any{
+		"id":       text.ID,
+		"text":     text.Text,
+		"metadata": text.MetaData,
+	}
+	if *field == "" {
+		return c.printRecord(textFields, values)
+	}
+
+	value, err := selectField(*field, textFields, values)
+	if err != nil {
+		return err
+	}
+
+	return c.printField(value)
+}
+
+func runTextAdd(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	text := fs.String("text", "", "text to save, - reads it from stdin")
+	metadata := fs.String("metadata", "", "description of the text")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if err := required(fs, "text"); err != nil {
+		return err
+	}
+
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	value, err := c.valueOrStdin(*text)
+	if err != nil {
+		return err
+	}
+
+	saved, err := c.services.TextData.SaveTextData(ctx, c.state.GetToken(), model.TextDataPostRequest{
+		Text:     value,
+		MetaData: *metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.printID(saved.ID)
+}
+
+// runTextUpdate replaces the fields given on the command line, the other fields keep their values.
+func runTextUpdate(ctx context.Context, c *CLI, args []string) error {
+	fs := c.flagSet()
+	text := fs.String("text", "", "new text, - reads it from stdin")
+	metadata := fs.String("metadata", "", "new description of the text")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = c.login(ctx); err != nil {
+		return err
+	}
+	defer c.logout(ctx)
+
+	current, err := c.services.TextData.LoadTextData(ctx, c.state.GetToken(), pos[0])
+	if err != nil {
+		return err
+	}
+
+	value, err := c.valueOrStdin(*text)
+	if err != nil {
+		return err
+	}
+
+	req := model.TextDataPutRequest{
+		ID:       pos[0],
+		Text:     current.Text,
+		MetaData: current.MetaData,
+		Revision: current.Revision,
+	}
+	override(fs, "text", value, &req.Text)
+	override(fs, "metadata", *metadata, &req.MetaData)
+
+	updated, err := c.services.TextData.UpdateTextData(ctx, c.state.GetToken(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.printID(updated.ID)
+}
+
+func runTextDelete(ctx context.Context, c *CLI, args []string) error {
+	return runDelete(ctx, c, args, c.services.TextData.DeleteTextData)
+}
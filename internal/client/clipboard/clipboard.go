@@ -0,0 +1,211 @@
+// Package clipboard copies secrets to the system clipboard and clears them after a timeout,
+// so they don't stay in the terminal scrollback or in the clipboard history for long.
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Names of the backends accepted by Detect.
+const (
+	BackendAuto   = "auto"
+	BackendXClip  = "xclip"
+	BackendWLCopy = "wl-copy"
+	BackendOSC52  = "osc52"
+	backendFile   = "file:" // Prefix of the file backend, followed by the path of the file
+)
+
+// Backend writes text to the clipboard. Writing an empty text clears the clipboard.
+type Backend interface {
+	Name() string
+	Write(text string) error
+}
+
+// Reader is implemented by the backends able to read the clipboard back. Such clipboards are cleared
+// only if they still hold the copied secret, so text copied by the user meanwhile is kept.
+type Reader interface {
+	Read() (string, error)
+}
+
+// Detect returns the backend with the given name. The auto backend is wl-copy in a Wayland session, xclip
+// in an X11 session and OSC 52 escape sequences written to out otherwise, e.g. over SSH.
+func Detect(name string, getenv func(string) string, lookPath func(string) (string, error), out io.Writer) (Backend, error) {
+	switch {
+	case name == "" || name == BackendAuto:
+		if getenv("WAYLAND_DISPLAY") != "" {
+			if _, err := lookPath("wl-copy"); err == nil {
+				return NewWLCopy(), nil
+			}
+		}
+		if getenv("DISPLAY") != "" {
+			if _, err := lookPath("xclip"); err == nil {
+				return NewXClip(), nil
+			}
+		}
+		return NewOSC52(out), nil
+	case name == BackendXClip:
+		return NewXClip(), nil
+	case name == BackendWLCopy:
+		return NewWLCopy(), nil
+	case name == BackendOSC52:
+		return NewOSC52(out), nil
+	case strings.HasPrefix(name, backendFile) && len(name) > len(backendFile):
+		return NewFile(strings.TrimPrefix(name, backendFile)), nil
+	default:
+		return nil, fmt.Errorf("unknown clipboard backend %q", name)
+	}
+}
+
+// Clipboard copies secrets with a backend and clears them after the timeout.
+type Clipboard struct {
+	mu      sync.Mutex
+	backend Backend       // Backend writing to the clipboard
+	timeout time.Duration // Time the secret stays in the clipboard, it is never cleared if zero
+	timer   *time.Timer   // Timer of the pending clearing
+	secret  string        // Secret to be cleared
+}
+
+// New creates a new Clipboard clearing the copied secrets after the timeout.
+func New(backend Backend, timeout time.Duration) *Clipboard {
+	return &Clipboard{backend: backend, timeout: timeout}
+}
+
+// Timeout returns the time the copied secrets stay in the clipboard.
+func (c *Clipboard) Timeout() time.Duration {
+	return c.timeout
+}
+
+// Copy writes the secret to the clipboard and schedules its clearing, replacing the pending one.
+func (c *Clipboard) Copy(secret string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.backend.Write(secret); err != nil {
+		return fmt.Errorf("copy with %s: %w", c.backend.Name(), err)
+	}
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.secret = secret
+	if c.timeout > 0 {
+		c.timer = time.AfterFunc(c.timeout, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if err := c.clearLocked(secret); err != nil {
+				logrus.WithError(err).Error("Failed to clear the clipboard")
+			}
+		})
+	}
+
+	return nil
+}
+
+// Close clears the pending secret right away, it must be called before the client exits.
+func (c *Clipboard) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.secret == "" {
+		return nil
+	}
+
+	return c.clearLocked(c.secret)
+}
+
+// clearLocked clears the clipboard if it still holds the secret. It must be called with the mutex held.
+func (c *Clipboard) clearLocked(secret string) error {
+	if c.secret != secret {
+		return nil
+	}
+	c.secret = ""
+
+	if r, ok := c.backend.(Reader); ok {
+		current, err := r.Read()
+		if err == nil && current != secret {
+			return nil
+		}
+	}
+
+	if err := c.backend.Write(""); err != nil {
+		return fmt.Errorf("clear with %s: %w", c.backend.Name(), err)
+	}
+
+	return nil
+}
+
+// Command is a backend running a clipboard tool, the text is passed on its standard input.
+type Command struct {
+	name  string   // Name of the backend
+	write []string // Command writing the clipboard
+	clear []string // Command clearing the clipboard, the write command with empty input if not set
+	read  []string // Command printing the clipboard
+}
+
+// NewXClip returns the backend of the X11 clipboard using xclip.
+func NewXClip() *Command {
+	return &Command{
+		name:  BackendXClip,
+		write: []string{"xclip", "-selection", "clipboard", "-in"},
+		read:  []string{"xclip", "-selection", "clipboard", "-out"},
+	}
+}
+
+// NewWLCopy returns the backend of the Wayland clipboard using wl-copy and wl-paste.
+func NewWLCopy() *Command {
+	return &Command{
+		name:  BackendWLCopy,
+		write: []string{"wl-copy"},
+		clear: []string{"wl-copy", "--clear"},
+		read:  []string{"wl-paste", "--no-newline"},
+	}
+}
+
+// Name returns the name of the backend.
+func (c *Command) Name() string {
+	return c.name
+}
+
+// Write runs the command writing the text to the clipboard.
+func (c *Command) Write(text string) error {
+	args := c.write
+	if text == "" && c.clear != nil {
+		args = c.clear
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// Read runs the command printing the clipboard.
+func (c *Command) Read() (string, error) {
+	out, err := exec.Command(c.read[0], c.read[1:]...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The tools fail when the clipboard is empty
+			return "", nil
+		}
+		return "", fmt.Errorf("%s: %w", c.read[0], err)
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,126 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClipboardTestSuite struct {
+	suite.Suite
+	file *File
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(ClipboardTestSuite))
+}
+
+func (c *ClipboardTestSuite) SetupTest() {
+	c.file = NewFile(filepath.Join(c.T().TempDir(), "clipboard"))
+}
+
+// content returns the content of the clipboard file.
+func (c *ClipboardTestSuite) content() string {
+	text, err := c.file.Read()
+	require.NoError(c.T(), err)
+	return text
+}
+
+func (c *ClipboardTestSuite) Test_ClearAfterTimeout() {
+	clip := New(c.file, 50*time.Millisecond)
+	require.NoError(c.T(), clip.Copy("secret"))
+	assert.Equal(c.T(), "secret", c.content())
+
+	assert.Eventually(c.T(), func() bool { return c.content() == "" }, time.Second, 10*time.Millisecond)
+}
+
+func (c *ClipboardTestSuite) Test_KeepTextCopiedByUser() {
+	clip := New(c.file, 50*time.Millisecond)
+	require.NoError(c.T(), clip.Copy("secret"))
+	require.NoError(c.T(), c.file.Write("copied by the user"))
+
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(c.T(), "copied by the user", c.content())
+}
+
+func (c *ClipboardTestSuite) Test_CopyReplacesPendingClear() {
+	clip := New(c.file, 100*time.Millisecond)
+	require.NoError(c.T(), clip.Copy("first"))
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(c.T(), clip.Copy("second"))
+
+	// The clearing of the first secret must not clear the second one early
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(c.T(), "second", c.content())
+	assert.Eventually(c.T(), func() bool { return c.content() == "" }, time.Second, 10*time.Millisecond)
+}
+
+func (c *ClipboardTestSuite) Test_Close() {
+	clip := New(c.file, time.Hour)
+	require.NoError(c.T(), clip.Copy("secret"))
+
+	require.NoError(c.T(), clip.Close())
+	assert.Equal(c.T(), "", c.content())
+	assert.NoError(c.T(), clip.Close())
+
+	// Secrets are kept with a zero timeout until the client exits
+	clip = New(c.file, 0)
+	require.NoError(c.T(), clip.Copy("secret"))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(c.T(), "secret", c.content())
+	require.NoError(c.T(), clip.Close())
+	assert.Equal(c.T(), "", c.content())
+}
+
+func (c *ClipboardTestSuite) Test_OSC52() {
+	var out bytes.Buffer
+	clip := New(NewOSC52(&out), time.Hour)
+	require.NoError(c.T(), clip.Copy("secret"))
+	assert.Equal(c.T(), "\x1b]52;c;c2VjcmV0\a", out.String())
+
+	out.Reset()
+	require.NoError(c.T(), clip.Close())
+	assert.Equal(c.T(), "\x1b]52;c;\a", out.String())
+}
+
+func (c *ClipboardTestSuite) Test_Detect() {
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+	tools := map[string]bool{}
+	lookPath := func(name string) (string, error) {
+		if tools[name] {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	detect := func(name string) string {
+		backend, err := Detect(name, getenv, lookPath, &bytes.Buffer{})
+		require.NoError(c.T(), err)
+		return backend.Name()
+	}
+
+	assert.Equal(c.T(), BackendOSC52, detect(BackendAuto))
+
+	env["DISPLAY"], tools["xclip"] = ":0", true
+	assert.Equal(c.T(), BackendXClip, detect(""))
+
+	env["WAYLAND_DISPLAY"] = "wayland-0"
+	assert.Equal(c.T(), BackendXClip, detect(BackendAuto))
+	tools["wl-copy"] = true
+	assert.Equal(c.T(), BackendWLCopy, detect(BackendAuto))
+
+	assert.Equal(c.T(), BackendOSC52, detect(BackendOSC52))
+	assert.Equal(c.T(), "file:/tmp/clipboard", detect("file:/tmp/clipboard"))
+
+	_, err := Detect("pbcopy", getenv, lookPath, &bytes.Buffer{})
+	assert.Error(c.T(), err)
+	_, err = Detect("file:", getenv, lookPath, &bytes.Buffer{})
+	assert.Error(c.T(), err)
+}
@@ -0,0 +1,47 @@
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const filePerm = 0o600 // Permission for the clipboard file, readable by the owner only
+
+// File is a backend keeping the clipboard in a file. It stands in for the system clipboard in tests
+// and on machines without one.
+type File struct {
+	path string // Path to the clipboard file
+}
+
+// NewFile returns the backend keeping the clipboard in the file at path.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Name returns the name of the backend.
+func (f *File) Name() string {
+	return backendFile + f.path
+}
+
+// Write replaces the content of the file with the text.
+func (f *File) Write(text string) error {
+	if err := os.WriteFile(f.path, []byte(text), filePerm); err != nil {
+		return fmt.Errorf("write clipboard file: %w", err)
+	}
+
+	return nil
+}
+
+// Read returns the content of the file, a missing file is an empty clipboard.
+func (f *File) Read() (string, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read clipboard file: %w", err)
+	}
+
+	return string(data), nil
+}
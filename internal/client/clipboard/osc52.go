@@ -0,0 +1,29 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// OSC52 is a backend writing OSC 52 escape sequences to the terminal. The terminal emulator sets its clipboard,
+// so it works over SSH without any tools installed. The clipboard can't be read back.
+type OSC52 struct {
+	out io.Writer // Terminal the sequences are written to
+}
+
+// NewOSC52 returns the backend writing OSC 52 escape sequences to out.
+func NewOSC52(out io.Writer) *OSC52 {
+	return &OSC52{out: out}
+}
+
+// Name returns the name of the backend.
+func (o *OSC52) Name() string {
+	return BackendOSC52
+}
+
+// Write sets the clipboard of the terminal, an empty text clears it.
+func (o *OSC52) Write(text string) error {
+	_, err := fmt.Fprintf(o.out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
@@ -0,0 +1,284 @@
+package pbclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/collection"
+)
+
+// ErrClientSideEncryption is returned for every request when client side encryption is enabled. The server
+// encrypts the items of shared collections itself, so they would reach it in plaintext.
+var ErrClientSideEncryption = errors.New("shared collections are not available with client side encryption")
+
+// CollectionPBClient is a client wrapper around the gRPC CollectionServiceClient,
+// providing methods to manage shared collections, their members and items via gRPC.
+type CollectionPBClient struct {
+	collectionService pb.CollectionServiceClient
+	cipher            Cipher
+}
+
+// Cipher reports whether client side encryption is enabled.
+type Cipher interface {
+	Enabled() bool
+}
+
+// NewCollectionPBClient initializes and returns a new instance of CollectionPBClient
+// which will use the provided gRPC CollectionServiceClient. No request is sent while the cipher is enabled.
+func NewCollectionPBClient(s pb.CollectionServiceClient, cipher Cipher) *CollectionPBClient {
+	return &CollectionPBClient{collectionService: s, cipher: cipher}
+}
+
+// CreateCollection creates a new shared collection managed by the user.
+func (c *CollectionPBClient) CreateCollection(ctx context.Context, token string, name string) (model.Collection, error) {
+	if c.cipher.Enabled() {
+		return model.Collection{}, ErrClientSideEncryption
+	}
+
+	resp, err := c.collectionService.PostCreateCollection(withToken(ctx, token), &pb.PostCollectionRequest{Name: name})
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("create collection: %w", err)
+	}
+
+	return toCollection(resp.GetCollection()), nil
+}
+
+// LoadAllCollections retrieves the collections the user is a member of or invited to.
+func (c *CollectionPBClient) LoadAllCollections(ctx context.Context, token string) ([]model.Collection, error) {
+	if c.cipher.Enabled() {
+		return nil, ErrClientSideEncryption
+	}
+
+	resp, err := c.collectionService.GetLoadAllCollections(withToken(ctx, token), &pb.GetAllCollectionsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("load collections: %w", err)
+	}
+
+	collections := make([]model.Collection, 0, len(resp.Collections))
+	for _, collection := range resp.Collections {
+		collections = append(collections, toCollection(collection))
+	}
+
+	return collections, nil
+}
+
+// InviteMember invites the user with the login into the collection with the role.
+func (c *CollectionPBClient) InviteMember(ctx context.Context, token string, collectionID, login, role string) (model.CollectionMember, error) {
+	if c.cipher.Enabled() {
+		return model.CollectionMember{}, ErrClientSideEncryption
+	}
+
+	req := &pb.PostInviteMemberRequest{
+		CollectionId: collectionID,
+		Login:        login,
+		Role:         role,
+	}
+
+	resp, err := c.collectionService.PostInviteMember(withToken(ctx, token), req)
+	if err != nil {
+		return model.CollectionMember{}, fmt.Errorf("invite member: %w", err)
+	}
+
+	return toMember(resp.GetMember()), nil
+}
+
+// AcceptInvitation accepts the invitation of the user into the collection.
+func (c *CollectionPBClient) AcceptInvitation(ctx context.Context, token string, collectionID string) (model.Collection, error) {
+	if c.cipher.Enabled() {
+		return model.Collection{}, ErrClientSideEncryption
+	}
+
+	req := &pb.PostAcceptInvitationRequest{CollectionId: collectionID}
+
+	resp, err := c.collectionService.PostAcceptInvitation(withToken(ctx, token), req)
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("accept invitation: %w", err)
+	}
+
+	return toCollection(resp.GetCollection()), nil
+}
+
+// RevokeMember removes the member with the login from the collection, an empty login leaves the collection.
+func (c *CollectionPBClient) RevokeMember(ctx context.Context, token string, collectionID, login string) error {
+	if c.cipher.Enabled() {
+		return ErrClientSideEncryption
+	}
+
+	req := &pb.DeleteRevokeMemberRequest{
+		CollectionId: collectionID,
+		Login:        login,
+	}
+
+	if _, err := c.collectionService.DeleteRevokeMember(withToken(ctx, token), req); err != nil {
+		return fmt.Errorf("revoke member: %w", err)
+	}
+
+	return nil
+}
+
+// LoadAllMembers retrieves the members of the collection, including the pending invitations.
+func (c *CollectionPBClient) LoadAllMembers(ctx context.Context, token string, collectionID string) ([]model.CollectionMember, error) {
+	if c.cipher.Enabled() {
+		return nil, ErrClientSideEncryption
+	}
+
+	req := &pb.GetAllMembersRequest{CollectionId: collectionID}
+
+	resp, err := c.collectionService.GetLoadAllMembers(withToken(ctx, token), req)
+	if err != nil {
+		return nil, fmt.Errorf("load members: %w", err)
+	}
+
+	members := make([]model.CollectionMember, 0, len(resp.Members))
+	for _, member := range resp.Members {
+		members = append(members, toMember(member))
+	}
+
+	return members, nil
+}
+
+// SaveCollectionItem saves a new item into the collection.
+func (c *CollectionPBClient) SaveCollectionItem(ctx context.Context, token string, item model.CollectionItemPostRequest) (model.CollectionItem, error) {
+	if c.cipher.Enabled() {
+		return model.CollectionItem{}, ErrClientSideEncryption
+	}
+
+	req := &pb.PostCollectionItemRequest{
+		CollectionId: item.CollectionID,
+		DataType:     item.DataType,
+		Data:         item.Data,
+		Metadata:     item.MetaData,
+	}
+
+	resp, err := c.collectionService.PostSaveCollectionItem(withToken(ctx, token), req)
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("save collection item: %w", err)
+	}
+
+	return toItem(resp.GetItem()), nil
+}
+
+// LoadCollectionItem retrieves the item of the collection with its data.
+func (c *CollectionPBClient) LoadCollectionItem(ctx context.Context, token string, collectionID, itemID string) (model.CollectionItem, error) {
+	if c.cipher.Enabled() {
+		return model.CollectionItem{}, ErrClientSideEncryption
+	}
+
+	req := &pb.GetCollectionItemRequest{
+		CollectionId: collectionID,
+		Id:           itemID,
+	}
+
+	resp, err := c.collectionService.GetLoadCollectionItem(withToken(ctx, token), req)
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("load collection item: %w", err)
+	}
+
+	return toItem(resp.GetItem()), nil
+}
+
+// LoadAllCollectionItems retrieves the items of the collection without their data.
+func (c *CollectionPBClient) LoadAllCollectionItems(ctx context.Context, token string, collectionID string) ([]model.CollectionItem, error) {
+	if c.cipher.Enabled() {
+		return nil, ErrClientSideEncryption
+	}
+
+	req := &pb.GetAllCollectionItemsRequest{CollectionId: collectionID}
+
+	resp, err := c.collectionService.GetLoadAllCollectionItems(withToken(ctx, token), req)
+	if err != nil {
+		return nil, fmt.Errorf("load collection items: %w", err)
+	}
+
+	items := make([]model.CollectionItem, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		items = append(items, toItem(item))
+	}
+
+	return items, nil
+}
+
+// UpdateCollectionItem replaces the data and metadata of the item, the revision must match the stored one.
+func (c *CollectionPBClient) UpdateCollectionItem(ctx context.Context, token string, item model.CollectionItemPutRequest) (model.CollectionItem, error) {
+	if c.cipher.Enabled() {
+		return model.CollectionItem{}, ErrClientSideEncryption
+	}
+
+	req := &pb.PutCollectionItemRequest{
+		CollectionId: item.CollectionID,
+		Id:           item.ID,
+		Data:         item.Data,
+		Metadata:     item.MetaData,
+		Revision:     item.Revision,
+	}
+
+	resp, err := c.collectionService.PutUpdateCollectionItem(withToken(ctx, token), req)
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("update collection item: %w", err)
+	}
+
+	return toItem(resp.GetItem()), nil
+}
+
+// DeleteCollectionItem deletes the item of the collection.
+func (c *CollectionPBClient) DeleteCollectionItem(ctx context.Context, token string, collectionID, itemID string) error {
+	if c.cipher.Enabled() {
+		return ErrClientSideEncryption
+	}
+
+	req := &pb.DeleteCollectionItemRequest{
+		CollectionId: collectionID,
+		Id:           itemID,
+	}
+
+	if _, err := c.collectionService.DeleteCollectionItem(withToken(ctx, token), req); err != nil {
+		return fmt.Errorf("delete collection item: %w", err)
+	}
+
+	return nil
+}
+
+// withToken returns the context carrying the token of the user in the outgoing metadata.
+func withToken(ctx context.Context, token string) context.Context {
+	md := metadata.New(map[string]string{"token": token})
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func toCollection(c *pb.Collection) model.Collection {
+	return model.Collection{
+		ID:        c.GetId(),
+		Name:      c.GetName(),
+		Role:      c.GetRole(),
+		Status:    c.GetStatus(),
+		CreatedAt: c.GetCreatedAt(),
+	}
+}
+
+func toMember(m *pb.CollectionMember) model.CollectionMember {
+	return model.CollectionMember{
+		UserID:    m.GetUserId(),
+		Login:     m.GetLogin(),
+		Role:      m.GetRole(),
+		Status:    m.GetStatus(),
+		InvitedBy: m.GetInvitedBy(),
+		CreatedAt: m.GetCreatedAt(),
+	}
+}
+
+func toItem(i *pb.CollectionItem) model.CollectionItem {
+	return model.CollectionItem{
+		ID:           i.GetId(),
+		CollectionID: i.GetCollectionId(),
+		DataType:     i.GetDataType(),
+		Data:         i.GetData(),
+		MetaData:     i.GetMetadata(),
+		CreatedBy:    i.GetCreatedBy(),
+		CreatedAt:    i.GetCreatedAt(),
+		UpdatedAt:    i.GetUpdatedAt(),
+		Revision:     i.GetRevision(),
+	}
+}
@@ -3,17 +3,30 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	defaultVaultDir              = "vault" // Default directory of local vaults
+	defaultClipboardClearSeconds = 30      // Default time secrets stay in the clipboard
+)
+
 // Config holds the application configuration settings, including logging level and client certificates.
 type Config struct {
-	EnvLogLevel string // Log level for the application
-	GRPCServer  string // Address of the gRPC server
-	ClientCert  string // Path to the client certificate file
-	ClientKey   string // Path to the client private key file
-	ClientCa    string // Path to the client CA certificate file
+	EnvLogLevel        string // Log level for the application
+	GRPCServer         string // Address of the gRPC server
+	ClientCert         string // Path to the client certificate file
+	ClientKey          string // Path to the client private key file
+	ClientCa           string // Path to the client CA certificate file
+	E2E                bool   // Enables client side end-to-end encryption
+	VaultDir           string // Directory of local encrypted vaults for offline work
+	SyncConflictPolicy string // Resolution of updates conflicting with changes made on another device
+	SessionFile        string // File the session is kept in between runs, sessions are not kept if empty
+	Clipboard          string // Clipboard backend: auto, xclip, wl-copy, osc52 or file:<path>
+	ClipboardClearSecs int    // Time in seconds secrets stay in the clipboard, never cleared if zero
+	BreachList         string // Local breached password hashes file or range directory, the server is asked if empty
 }
 
 // New loads the configuration from the "client.env" file using environment variables
@@ -26,11 +39,45 @@ func New() (*Config, error) {
 
 	// Initialize Config and assign environment variables
 	config := &Config{
-		EnvLogLevel: os.Getenv("LOG_LEVEL"),
-		GRPCServer:  os.Getenv("GRPC_SERVER"),
-		ClientCert:  os.Getenv("CLIENT_CERT_FILE"),
-		ClientKey:   os.Getenv("CLIENT_KEY_FILE"),
-		ClientCa:    os.Getenv("CLIENT_CA_FILE"),
+		EnvLogLevel:        os.Getenv("LOG_LEVEL"),
+		GRPCServer:         os.Getenv("GRPC_SERVER"),
+		ClientCert:         os.Getenv("CLIENT_CERT_FILE"),
+		ClientKey:          os.Getenv("CLIENT_KEY_FILE"),
+		ClientCa:           os.Getenv("CLIENT_CA_FILE"),
+		E2E:                os.Getenv("E2E_ENCRYPTION") == "true",
+		VaultDir:           os.Getenv("VAULT_DIR"),
+		SyncConflictPolicy: os.Getenv("SYNC_CONFLICT_POLICY"),
+		SessionFile:        os.Getenv("SESSION_FILE"),
+		BreachList:         os.Getenv("BREACH_LIST_FILE"),
+	}
+
+	if config.VaultDir == "" {
+		config.VaultDir = defaultVaultDir
+	}
+
+	config.Clipboard = os.Getenv("CLIPBOARD")
+	config.ClipboardClearSecs, err = atoiDefault("CLIPBOARD_CLEAR_SECONDS", defaultClipboardClearSeconds)
+	if err != nil {
+		return nil, err
+	}
+	if config.ClipboardClearSecs < 0 {
+		return nil, fmt.Errorf("CLIPBOARD_CLEAR_SECONDS must not be negative, got %d", config.ClipboardClearSecs)
 	}
+
 	return config, nil
 }
+
+// atoiDefault reads an integer environment variable, returning def if it is not set.
+func atoiDefault(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("atoi %s: %w", name, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,285 @@
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/vault"
+)
+
+const credentials = "credentials" // Data type of credentials
+
+// CredentialsService defines methods of the online credentials client.
+type CredentialsService interface {
+	SaveCredentials(ctx context.Context, token string, cred model.CredentialsPostRequest) (model.Credentials, error)
+	SaveCredentialsBatch(ctx context.Context, token string, items []model.CredentialsPostRequest, atomic bool) ([]model.BatchResult, error)
+	LoadCredentialsData(ctx context.Context, token string, dataID string) (model.Credentials, error)
+	LoadAllCredentialsDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCredentials(ctx context.Context, token string, cred model.CredentialsPutRequest) (model.Credentials, error)
+	DeleteCredentials(ctx context.Context, token string, dataID string) error
+}
+
+// CredentialsClient mirrors credentials into the local vault. When the server is unreachable
+// it serves reads from the vault and queues writes for sync. Updates conflicting with a change
+// made on another device are resolved by the conflict policy.
+type CredentialsClient struct {
+	online CredentialsService
+	state  *state.ClientState
+	policy vault.ConflictPolicy
+}
+
+// NewCredentialsClient creates a new instance of CredentialsClient wrapping the online client.
+func NewCredentialsClient(online CredentialsService, state *state.ClientState, policy vault.ConflictPolicy) *CredentialsClient {
+	return &CredentialsClient{
+		online: online,
+		state:  state,
+		policy: policy,
+	}
+}
+
+// SaveCredentials saves new credentials on the server and in the vault,
+// or only in the vault with a queued save when offline.
+func (c *CredentialsClient) SaveCredentials(ctx context.Context, token string, cred model.CredentialsPostRequest) (model.Credentials, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		saved, err := c.online.SaveCredentials(ctx, token, cred)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return saved, err
+		}
+		if err == nil {
+			return saved, v.PutPayload(credentials, saved.ID, saved.MetaData, saved.Revision, saved)
+		}
+	}
+
+	return c.saveLocal(v, cred)
+}
+
+// SaveCredentialsBatch saves new credentials on the server in one call and mirrors the saved ones into the vault,
+// or saves every item only in the vault with a queued save when offline.
+func (c *CredentialsClient) SaveCredentialsBatch(ctx context.Context, token string, items []model.CredentialsPostRequest, atomic bool) ([]model.BatchResult, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		results, err := c.online.SaveCredentialsBatch(ctx, token, items, atomic)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return results, err
+		}
+		if err == nil {
+			for i, result := range results {
+				if result.ID == "" {
+					continue
+				}
+				saved := model.Credentials{
+					ID:       result.ID,
+					Login:    items[i].Login,
+					Password: items[i].Password,
+					TOTP:     items[i].TOTP,
+					MetaData: items[i].MetaData,
+					Revision: result.Revision,
+				}
+				if err = v.PutPayload(credentials, saved.ID, saved.MetaData, saved.Revision, saved); err != nil {
+					return results, err
+				}
+			}
+			return results, nil
+		}
+	}
+
+	results := make([]model.BatchResult, 0, len(items))
+	for _, item := range items {
+		saved, err := c.saveLocal(v, item)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, model.BatchResult{ID: saved.ID})
+	}
+
+	return results, nil
+}
+
+// saveLocal saves new credentials only in the vault and queues the save for sync.
+func (c *CredentialsClient) saveLocal(v *vault.Vault, cred model.CredentialsPostRequest) (model.Credentials, error) {
+	saved := model.Credentials{
+		ID:       vault.NewLocalID(),
+		Login:    cred.Login,
+		Password: cred.Password,
+		TOTP:     cred.TOTP,
+		MetaData: cred.MetaData,
+	}
+	if err := v.PutPayload(credentials, saved.ID, saved.MetaData, 0, saved); err != nil {
+		return model.Credentials{}, err
+	}
+
+	return saved, v.EnqueuePayload(vault.OpSave, credentials, saved.ID, cred)
+}
+
+// LoadCredentialsData loads credentials from the server and stores them in the vault,
+// or from the vault when offline.
+func (c *CredentialsClient) LoadCredentialsData(ctx context.Context, token string, dataID string) (model.Credentials, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		cred, err := c.online.LoadCredentialsData(ctx, token, dataID)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return cred, err
+		}
+		if err == nil {
+			return cred, v.PutPayload(credentials, dataID, cred.MetaData, cred.Revision, cred)
+		}
+	}
+
+	var cred model.Credentials
+	if err := v.Payload(credentials, dataID, &cred); err != nil {
+		return model.Credentials{}, err
+	}
+	cred.ID = dataID
+
+	return cred, nil
+}
+
+// LoadAllCredentialsDataInfo loads a page of the info of credentials from the server and merges it into the vault,
+// or from the vault when offline. The vault drops the records missing on the server
+// only when the whole list fits in one page, otherwise deletions are picked up from the change feed.
+func (c *CredentialsClient) LoadAllCredentialsDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		infos, err := c.online.LoadAllCredentialsDataInfo(ctx, token, page)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return infos, err
+		}
+		if err == nil && page.PageToken == "" && infos.NextPageToken == "" {
+			return infos, v.MergeInfos(credentials, infos.Infos)
+		}
+		if err == nil {
+			return infos, v.UpdateInfos(credentials, infos.Infos)
+		}
+	}
+
+	return v.InfoPage(credentials, page)
+}
+
+// UpdateCredentials updates credentials on the server and in the vault,
+// or only in the vault with a queued update when offline.
+// The update is made against the revision of the credentials stored in the vault.
+func (c *CredentialsClient) UpdateCredentials(ctx context.Context, token string, cred model.CredentialsPutRequest) (model.Credentials, error) {
+	v := c.state.GetVault()
+	if v != nil && cred.Revision == 0 {
+		cred.Revision = v.Revision(credentials, cred.ID)
+	}
+
+	if !c.state.IsOffline() {
+		updated, err := c.update(ctx, token, cred)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return updated, err
+		}
+		if err == nil {
+			return updated, v.PutPayload(credentials, updated.ID, updated.MetaData, updated.Revision, updated)
+		}
+	}
+
+	updated := model.Credentials{
+		ID:       cred.ID,
+		Login:    cred.Login,
+		Password: cred.Password,
+		TOTP:     cred.TOTP,
+		MetaData: cred.MetaData,
+		Revision: cred.Revision,
+	}
+	if err := v.PutPayload(credentials, cred.ID, cred.MetaData, 0, updated); err != nil {
+		return model.Credentials{}, err
+	}
+
+	return updated, v.EnqueuePayload(vault.OpUpdate, credentials, cred.ID, cred)
+}
+
+// DeleteCredentials deletes credentials on the server and in the vault,
+// or only in the vault with a queued delete when offline.
+func (c *CredentialsClient) DeleteCredentials(ctx context.Context, token string, dataID string) error {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		err := c.online.DeleteCredentials(ctx, token, dataID)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return err
+		}
+		if err == nil {
+			return v.Delete(credentials, dataID)
+		}
+	}
+
+	if err := v.Delete(credentials, dataID); err != nil {
+		return err
+	}
+
+	return v.EnqueuePayload(vault.OpDelete, credentials, dataID, nil)
+}
+
+// Replay replays a queued credentials write on the server.
+func (c *CredentialsClient) Replay(ctx context.Context, token string, op vault.Operation) (string, error) {
+	switch op.Kind {
+	case vault.OpSave:
+		var cred model.CredentialsPostRequest
+		if err := json.Unmarshal(op.Payload, &cred); err != nil {
+			return "", fmt.Errorf("unmarshal credentials: %w", err)
+		}
+		saved, err := c.online.SaveCredentials(ctx, token, cred)
+		return saved.ID, err
+	case vault.OpUpdate:
+		var cred model.CredentialsPutRequest
+		if err := json.Unmarshal(op.Payload, &cred); err != nil {
+			return "", fmt.Errorf("unmarshal credentials: %w", err)
+		}
+		cred.ID = op.RecordID
+		updated, err := c.update(ctx, token, cred)
+		if err != nil {
+			return op.RecordID, err
+		}
+		return op.RecordID, c.state.GetVault().PutPayload(credentials, updated.ID, updated.MetaData, updated.Revision, updated)
+	case vault.OpDelete:
+		return op.RecordID, c.online.DeleteCredentials(ctx, token, op.RecordID)
+	default:
+		return "", fmt.Errorf("unknown operation %s", op.Kind)
+	}
+}
+
+// Fetch loads credentials from the server into the vault.
+func (c *CredentialsClient) Fetch(ctx context.Context, token string, id string) error {
+	v := c.state.GetVault()
+	if v == nil {
+		return nil
+	}
+
+	cred, err := c.online.LoadCredentialsData(ctx, token, id)
+	if err != nil {
+		return err
+	}
+
+	return v.PutPayload(credentials, id, cred.MetaData, cred.Revision, cred)
+}
+
+// update updates credentials on the server. An update rejected because the credentials were changed
+// on another device is resolved by the conflict policy: the local version either overwrites the server one
+// or is saved as new credentials, which are returned, while the vault gets the server version.
+func (c *CredentialsClient) update(ctx context.Context, token string, cred model.CredentialsPutRequest) (model.Credentials, error) {
+	updated, err := c.online.UpdateCredentials(ctx, token, cred)
+	if !vault.IsConflict(err) {
+		return updated, err
+	}
+
+	if c.policy == vault.LastWriterWins {
+		cred.Revision = 0
+		return c.online.UpdateCredentials(ctx, token, cred)
+	}
+
+	saved, err := c.online.SaveCredentials(ctx, token, model.CredentialsPostRequest{
+		Login:    cred.Login,
+		Password: cred.Password,
+		TOTP:     cred.TOTP,
+		MetaData: cred.MetaData + vault.ConflictCopySuffix,
+	})
+	if err != nil {
+		return model.Credentials{}, err
+	}
+
+	return saved, c.Fetch(ctx, token, cred.ID)
+}
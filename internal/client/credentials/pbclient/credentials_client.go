@@ -5,20 +5,33 @@ import (
 	"fmt"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
 	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/credentials"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/metadata"
 )
 
+// dataType is the type of the credentials, client side encrypted payloads are bound to it along with their id.
+const dataType = "credentials"
+
 // CredentialsPBClient is a client wrapper around the gRPC CredentialsServiceClient,
 // providing methods to interact with the credentials-related operations via gRPC.
 type CredentialsPBClient struct {
 	credentialsService pb.CredentialsServiceClient
+	cipher             Cipher
+}
+
+// Cipher defines methods for client side encryption of the credentials payload.
+type Cipher interface {
+	Enabled() bool
+	Seal(id, dataType string, v any) ([]byte, error)
+	Open(id, dataType string, data []byte, v any) error
 }
 
 // NewCredentialsPBClient initializes and returns a new instance of CredentialsPBClient
-// which will use the provided gRPC CredentialsServiceClient.
-func NewCredentialsPBClient(u pb.CredentialsServiceClient) *CredentialsPBClient {
+// which will use the provided gRPC CredentialsServiceClient and cipher for client side encryption.
+func NewCredentialsPBClient(u pb.CredentialsServiceClient, cipher Cipher) *CredentialsPBClient {
 	return &CredentialsPBClient{
 		credentialsService: u,
+		cipher:             cipher,
 	}
 }
 
@@ -29,9 +42,19 @@ func (u *CredentialsPBClient) SaveCredentials(ctx context.Context, token string,
 	req := &pb.PostCredentialsRequest{
 		Login:    cred.Login,
 		Password: cred.Password,
+		Totp:     cred.TOTP,
 		Metadata: cred.MetaData,
 	}
 
+	if u.cipher.Enabled() {
+		id := uuid.NewString()
+		cryptData, err := u.cipher.Seal(id, dataType, model.CredentialsCryptData{Login: cred.Login, Password: cred.Password, TOTP: cred.TOTP})
+		if err != nil {
+			return model.Credentials{}, fmt.Errorf("seal credentials: %w", err)
+		}
+		req = &pb.PostCredentialsRequest{Id: id, Metadata: cred.MetaData, CryptData: cryptData}
+	}
+
 	md := metadata.New(map[string]string{"token": token})
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
@@ -41,25 +64,83 @@ func (u *CredentialsPBClient) SaveCredentials(ctx context.Context, token string,
 	}
 
 	credential := model.Credentials{
+		ID:       resp.Id,
 		Login:    resp.Login,
 		Password: resp.Password,
+		TOTP:     resp.Totp,
 		MetaData: resp.Metadata,
+		Revision: resp.Revision,
+	}
+	if len(req.CryptData) != 0 {
+		credential.Login, credential.Password, credential.TOTP = cred.Login, cred.Password, cred.TOTP
 	}
 
 	return credential, nil
 }
 
-// LoadAllCredentialsDataInfo fetches all stored credentials metadata from the gRPC service.
-// It accepts a context and an authentication token, and returns a list of credential info or an error.
-func (u *CredentialsPBClient) LoadAllCredentialsDataInfo(ctx context.Context, token string) ([]model.DataInfo, error) {
-	req := &pb.GetAllCredentialsInfoRequest{}
+// SaveCredentialsBatch saves many credentials at once using the credentials service. The results are in the order
+// of the requests. Items failing the validation of the server are not saved and have the violations of their fields set,
+// an atomic batch is rejected as a whole with an InvalidArgument error instead.
+func (u *CredentialsPBClient) SaveCredentialsBatch(ctx context.Context, token string, items []model.CredentialsPostRequest, atomic bool) ([]model.BatchResult, error) {
+	req := &pb.PostCredentialsBatchRequest{Credentials: make([]*pb.PostCredentialsRequest, 0, len(items)), Atomic: atomic}
+	for _, item := range items {
+		in := &pb.PostCredentialsRequest{
+			Login:    item.Login,
+			Password: item.Password,
+			Totp:     item.TOTP,
+			Metadata: item.MetaData,
+		}
+
+		if u.cipher.Enabled() {
+			id := uuid.NewString()
+			cryptData, err := u.cipher.Seal(id, dataType, model.CredentialsCryptData{Login: item.Login, Password: item.Password, TOTP: item.TOTP})
+			if err != nil {
+				return nil, fmt.Errorf("seal credentials: %w", err)
+			}
+			in = &pb.PostCredentialsRequest{Id: id, Metadata: item.MetaData, CryptData: cryptData}
+		}
+		req.Credentials = append(req.Credentials, in)
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.credentialsService.PostSaveCredentialsBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]model.BatchResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		result := model.BatchResult{ID: r.GetSaved().GetId(), Revision: r.GetSaved().GetRevision()}
+		for _, violation := range r.Violations {
+			if result.Violations == nil {
+				result.Violations = make(map[string]string)
+			}
+			result.Violations[violation.Field] = violation.Description
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// LoadAllCredentialsDataInfo fetches a page of stored credentials metadata from the gRPC service.
+// It accepts a context, an authentication token and the requested page, and returns a page of credential info or an error.
+func (u *CredentialsPBClient) LoadAllCredentialsDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error) {
+	req := &pb.GetAllCredentialsInfoRequest{
+		PageSize:   int32(page.PageSize),
+		PageToken:  page.PageToken,
+		SortBy:     page.SortBy,
+		Descending: page.Descending,
+	}
 
 	md := metadata.New(map[string]string{"token": token})
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
 	resp, err := u.credentialsService.GetLoadAllCredentialsDataInfo(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("load credentials data: %w", err)
+		return model.DataInfoPage{}, fmt.Errorf("load credentials data: %w", err)
 	}
 
 	credentials := make([]model.DataInfo, 0, len(resp.Creds))
@@ -68,11 +149,13 @@ func (u *CredentialsPBClient) LoadAllCredentialsDataInfo(ctx context.Context, to
 			ID:        data.Id,
 			DataType:  data.DataType,
 			MetaData:  data.Metadata,
+			Revision:  data.Revision,
+			UpdatedAt: data.UpdatedAt,
 			CreatedAt: data.CreatedAt,
 		})
 	}
 
-	return credentials, nil
+	return model.DataInfoPage{Infos: credentials, NextPageToken: resp.NextPageToken}, nil
 }
 
 // LoadCredentialsData retrieves specific credentials data by its ID from the gRPC service.
@@ -92,10 +175,83 @@ func (u *CredentialsPBClient) LoadCredentialsData(ctx context.Context, token str
 	}
 	data := resp.CredentialsData
 	credentialsData := model.Credentials{
+		ID:       data.Id,
 		Login:    data.Login,
 		Password: data.Password,
+		TOTP:     data.Totp,
 		MetaData: data.Metadata,
+		Revision: data.Revision,
+	}
+
+	if len(data.CryptData) != 0 {
+		var cryptData model.CredentialsCryptData
+		if err = u.cipher.Open(data.Id, dataType, data.CryptData, &cryptData); err != nil {
+			return model.Credentials{}, fmt.Errorf("open credentials: %w", err)
+		}
+		credentialsData.Login, credentialsData.Password, credentialsData.TOTP = cryptData.Login, cryptData.Password, cryptData.TOTP
 	}
 
 	return credentialsData, nil
 }
+
+// UpdateCredentials sends a request to replace existing credentials in the gRPC credentials service.
+// It accepts a context, an authentication token, and a model containing the ID, login, password, and metadata.
+// It returns the updated credentials or an error if the operation fails.
+func (u *CredentialsPBClient) UpdateCredentials(ctx context.Context, token string, cred model.CredentialsPutRequest) (model.Credentials, error) {
+	req := &pb.PutCredentialsRequest{
+		Id:       cred.ID,
+		Revision: cred.Revision,
+		Login:    cred.Login,
+		Password: cred.Password,
+		Totp:     cred.TOTP,
+		Metadata: cred.MetaData,
+	}
+
+	if u.cipher.Enabled() {
+		cryptData, err := u.cipher.Seal(cred.ID, dataType, model.CredentialsCryptData{Login: cred.Login, Password: cred.Password, TOTP: cred.TOTP})
+		if err != nil {
+			return model.Credentials{}, fmt.Errorf("seal credentials: %w", err)
+		}
+		req = &pb.PutCredentialsRequest{Id: cred.ID, Revision: cred.Revision, Metadata: cred.MetaData, CryptData: cryptData}
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.credentialsService.PutUpdateCredentials(ctx, req)
+	if err != nil {
+		return model.Credentials{}, err
+	}
+
+	credential := model.Credentials{
+		ID:       resp.Id,
+		Login:    resp.Login,
+		Password: resp.Password,
+		TOTP:     resp.Totp,
+		MetaData: resp.Metadata,
+		Revision: resp.Revision,
+	}
+	if len(req.CryptData) != 0 {
+		credential.Login, credential.Password, credential.TOTP = cred.Login, cred.Password, cred.TOTP
+	}
+
+	return credential, nil
+}
+
+// DeleteCredentials deletes specific credentials data by its ID in the gRPC service.
+// It accepts a context, an authentication token, and the ID of the data to be deleted.
+func (u *CredentialsPBClient) DeleteCredentials(ctx context.Context, token string, dataID string) error {
+	req := &pb.DeleteCredentialsRequest{
+		Id: dataID,
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	_, err := u.credentialsService.DeleteCredentials(ctx, req)
+	if err != nil {
+		return fmt.Errorf("delete credentials data: %w", err)
+	}
+
+	return nil
+}
@@ -7,10 +7,12 @@ import (
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/passgen"
 	"github.com/fatih/color"
 	"github.com/sirupsen/logrus"
 	"os"
 	"strings"
+	"time"
 )
 
 // CredentialsService defines the interface for interacting with credentials-related operations.
@@ -18,7 +20,14 @@ import (
 type CredentialsService interface {
 	SaveCredentials(ctx context.Context, token string, cred model.CredentialsPostRequest) (model.Credentials, error)
 	LoadCredentialsData(ctx context.Context, token string, dataID string) (model.Credentials, error)
-	LoadAllCredentialsDataInfo(ctx context.Context, token string) ([]model.DataInfo, error)
+	LoadAllCredentialsDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCredentials(ctx context.Context, token string, cred model.CredentialsPutRequest) (model.Credentials, error)
+	DeleteCredentials(ctx context.Context, token string, dataID string) error
+}
+
+// BreachChecker checks passwords against a list of breached passwords.
+type BreachChecker interface {
+	Breached(ctx context.Context, token string, password string) (int64, error)
 }
 
 // CredentialsProvider provides methods for managing user credentials.
@@ -26,18 +35,23 @@ type CredentialsService interface {
 type CredentialsProvider struct {
 	credentialsService CredentialsService // Service to handle credentials operations
 	state              *state.ClientState // Client's state, including authorization and directory information
+	clipboard          lib.Clipboard      // Clipboard the secrets are copied to
+	breaches           BreachChecker      // Breached password check of the listed credentials, skipped if nil
 }
 
-// NewCredentialsService initializes a new CredentialsProvider with the given CredentialsService
-// and ClientState. It returns a pointer to the newly created CredentialsProvider.
-func NewCredentialsService(u CredentialsService, state *state.ClientState) *CredentialsProvider {
+// NewCredentialsService initializes a new CredentialsProvider with the given CredentialsService,
+// ClientState, clipboard and breach checker. It returns a pointer to the newly created CredentialsProvider.
+func NewCredentialsService(u CredentialsService, state *state.ClientState, clipboard lib.Clipboard,
+	breaches BreachChecker) *CredentialsProvider {
 	return &CredentialsProvider{
 		credentialsService: u,
 		state:              state,
+		clipboard:          clipboard,
+		breaches:           breaches,
 	}
 }
 
-// Save prompts the user for credentials data (login, password, metadata) and saves it
+// Save prompts the user for credentials data (login, password, two-factor secret, metadata) and saves it
 // using the credentialsService. It checks if the user is authorized before proceeding.
 func (p *CredentialsProvider) Save(ctx context.Context) {
 	red := color.New(color.FgRed).SprintFunc()
@@ -51,16 +65,28 @@ func (p *CredentialsProvider) Save(ctx context.Context) {
 
 	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
 	req := model.CredentialsPostRequest{}
-	fmt.Println(cyanBold("Input credentials data 'login password metadata':"))
+	fmt.Println(cyanBold("Input credentials data 'login password two-factor-secret metadata':"))
 
 	yellow := color.New(color.FgYellow).SprintFunc()
 	fmt.Printf("Input login as %s: ", yellow("'text'"))
 	scanner.Scan()
 	req.Login = scanner.Text()
 
-	fmt.Printf("Input password as %s: ", yellow("'text'"))
+	fmt.Printf("Input password as %s or leave empty to generate one: ", yellow("'text'"))
 	scanner.Scan()
 	req.Password = scanner.Text()
+	if req.Password == "" {
+		generated, err := generatePassword()
+		if err != nil {
+			fmt.Println(red(err))
+			return
+		}
+		req.Password = generated
+	}
+
+	fmt.Printf("Input two-factor secret as %s or leave empty: ", yellow("'base32 secret or otpauth://totp URI'"))
+	scanner.Scan()
+	req.TOTP = strings.TrimSpace(scanner.Text())
 
 	fmt.Printf("Input metadata as %s: ", yellow("'text'"))
 	scanner.Scan()
@@ -75,7 +101,8 @@ func (p *CredentialsProvider) Save(ctx context.Context) {
 	fmt.Println(color.New(color.FgGreen).SprintFunc()("Credentials successfully saved"))
 }
 
-// LoadAllInfo retrieves and displays information about all saved credentials.
+// LoadAllInfo retrieves and displays information about the saved credentials page by page.
+// The password of every listed credentials is checked against the breach list and breached ones are marked.
 // It checks for user authorization and a valid working directory before loading the data.
 func (p *CredentialsProvider) LoadAllInfo(ctx context.Context) {
 	red := color.New(color.FgRed).SprintFunc()
@@ -90,35 +117,68 @@ func (p *CredentialsProvider) LoadAllInfo(ctx context.Context) {
 		return
 	}
 
-	credentialsDataInfo, err := p.credentialsService.LoadAllCredentialsDataInfo(ctx, p.state.GetToken())
-	if err != nil {
-		logrus.WithError(err).Error("All user data info load failed")
-		fmt.Println(red("All credentials data info load failed"), "please try again")
-		lib.UnpackGRPCError(err)
-		return
-	}
+	scanner := bufio.NewScanner(os.Stdin)
+	page := lib.ReadPageRequest(scanner)
+	checkBreaches := p.breaches != nil
+	for {
+		credentialsDataInfo, err := p.credentialsService.LoadAllCredentialsDataInfo(ctx, p.state.GetToken(), page)
+		if err != nil {
+			logrus.WithError(err).Error("All user data info load failed")
+			fmt.Println(red("All credentials data info load failed"), "please try again")
+			lib.UnpackGRPCError(err)
+			return
+		}
 
-	green := color.New(color.FgGreen).SprintFunc()
-	yellow := color.New(color.FgYellow).SprintFunc()
-	fmt.Println(green("-------------------------------------"))
+		green := color.New(color.FgGreen).SprintFunc()
+		yellow := color.New(color.FgYellow).SprintFunc()
+		fmt.Println(green("-------------------------------------"))
+
+		var sb strings.Builder
+		for _, dataInfo := range credentialsDataInfo.Infos {
+			sb.WriteString("Data ID: " + dataInfo.ID + "\n")
+			sb.WriteString("Data type: " + dataInfo.DataType + "\n")
+			sb.WriteString("Metadata : " + dataInfo.MetaData + "\n")
+			sb.WriteString("Created at: : " + dataInfo.CreatedAt + "\n")
+			if checkBreaches {
+				breached, err := p.breached(ctx, dataInfo.ID)
+				if err != nil {
+					logrus.WithError(err).Error("Breached password check failed")
+					fmt.Println(yellow("Unable to check the passwords against the breach list:"))
+					lib.UnpackGRPCError(err)
+					checkBreaches = false
+				} else if breached > 0 {
+					sb.WriteString("Breached: " + red(fmt.Sprintf("password found %d times in known breaches, please change it", breached)) + "\n")
+				} else {
+					sb.WriteString("Breached: " + green("no") + "\n")
+				}
+			}
+			sb.WriteString(green(green("-------------------------------------")) + "\n")
+		}
+		if len(credentialsDataInfo.Infos) > 0 {
+			fmt.Println(sb.String())
+		} else if page.PageToken == "" {
+			fmt.Println(yellow("Your haven't saved any data or data load filed, please try again"))
+		}
 
-	var sb strings.Builder
-	for _, dataInfo := range credentialsDataInfo {
-		sb.WriteString("Data ID: " + dataInfo.ID + "\n")
-		sb.WriteString("Data type: " + dataInfo.DataType + "\n")
-		sb.WriteString("Metadata : " + dataInfo.MetaData + "\n")
-		sb.WriteString("Created at: : " + dataInfo.CreatedAt + "\n")
-		sb.WriteString(green(green("-------------------------------------")) + "\n")
+		if credentialsDataInfo.NextPageToken == "" || !lib.ConfirmNextPage(scanner) {
+			return
+		}
+		page.PageToken = credentialsDataInfo.NextPageToken
 	}
-	if len(credentialsDataInfo) > 0 {
-		fmt.Println(sb.String())
-	} else {
-		fmt.Println(yellow("Your haven't saved any data or data load filed, please try again"))
+}
+
+// breached returns the number of times the password of the credentials was seen in breaches.
+func (p *CredentialsProvider) breached(ctx context.Context, dataID string) (int64, error) {
+	cred, err := p.credentialsService.LoadCredentialsData(ctx, p.state.GetToken(), dataID)
+	if err != nil {
+		return 0, err
 	}
+
+	return p.breaches.Breached(ctx, p.state.GetToken(), cred.Password)
 }
 
-// LoadData retrieves specific credentials data based on the provided ID and displays it.
-// It allows the user to print the information or save it to a file.
+// LoadData retrieves specific credentials data based on the provided ID and displays it with the password masked
+// and the current two-factor code, if the credentials have a two-factor secret. It allows the user to print the information, save it to a file or copy a field to the clipboard.
 func (p *CredentialsProvider) LoadData(ctx context.Context) {
 	red := color.New(color.FgRed).SprintFunc()
 
@@ -151,19 +211,46 @@ func (p *CredentialsProvider) LoadData(ctx context.Context) {
 
 	green := color.New(color.FgGreen).SprintFunc()
 
-	var sb strings.Builder
-	sb.WriteString(green("-------------------------------------") + "\n")
-	sb.WriteString("Credential login: " + credentialsData.Login + "\n")
-	sb.WriteString("Credential password: " + credentialsData.Password + "\n")
-	sb.WriteString("Credential metadata: " + credentialsData.MetaData + "\n")
-	sb.WriteString(green("-------------------------------------") + "\n")
-	fmt.Println(sb.String())
-	fmt.Print(green("Write info to file or print (leave empty or write to file): "))
+	format := func(mask func(string) string) string {
+		var sb strings.Builder
+		sb.WriteString(green("-------------------------------------") + "\n")
+		sb.WriteString("Credential login: " + credentialsData.Login + "\n")
+		sb.WriteString("Credential password: " + mask(credentialsData.Password) + "\n")
+		if credentialsData.TOTP != "" {
+			sb.WriteString("Credential two-factor secret: " + mask(credentialsData.TOTP) + "\n")
+			sb.WriteString("Credential two-factor code: " + lib.FormatOneTimeCode(credentialsData.TOTP, time.Now()) + "\n")
+		}
+		sb.WriteString("Credential metadata: " + credentialsData.MetaData + "\n")
+		sb.WriteString(green("-------------------------------------") + "\n")
+		return sb.String()
+	}
+
+	reveal := func(s string) string { return s }
+
+	fmt.Println(format(lib.Mask))
+	fmt.Printf(green("Print, write to file or copy a field (leave empty to print, input file name or %s): "),
+		yellow("'copy login|password|code'"))
 	scanner.Scan()
 	path := scanner.Text()
 
 	if len(path) == 0 {
-		fmt.Print(sb.String())
+		fmt.Print(format(reveal))
+		return
+	}
+
+	if field, ok := strings.CutPrefix(path, lib.CopyCommand); ok {
+		fields := map[string]string{
+			"login":    credentialsData.Login,
+			"password": credentialsData.Password,
+		}
+		if credentialsData.TOTP != "" {
+			// The code is generated at the time of copying, the one shown above may have expired
+			code, _, err := lib.OneTimeCode(credentialsData.TOTP, time.Now())
+			if err == nil {
+				fields["code"] = code
+			}
+		}
+		lib.CopyField(p.clipboard, fields, field)
 		return
 	}
 
@@ -171,10 +258,106 @@ func (p *CredentialsProvider) LoadData(ctx context.Context) {
 		path = p.state.GetDirPath() + "/" + path
 	}
 
-	err = lib.SaveToFile(path, sb.String())
+	err = lib.SaveToFile(path, format(reveal))
 	if err != nil {
 		fmt.Println(err)
 		fmt.Printf("Error writing to file with path %s, please try again\n", red(path))
 		return
 	}
 }
+
+// Update prompts the user for the ID of existing credentials and the new login, password,
+// two-factor secret and metadata, then replaces them using the credentialsService.
+func (p *CredentialsProvider) Update(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	req := model.CredentialsPutRequest{}
+	fmt.Println(cyanBold("Input credentials data to update 'id login password two-factor-secret metadata':"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input data ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	req.ID = scanner.Text()
+
+	fmt.Printf("Input login as %s: ", yellow("'text'"))
+	scanner.Scan()
+	req.Login = scanner.Text()
+
+	fmt.Printf("Input password as %s or leave empty to generate one: ", yellow("'text'"))
+	scanner.Scan()
+	req.Password = scanner.Text()
+	if req.Password == "" {
+		generated, err := generatePassword()
+		if err != nil {
+			fmt.Println(red(err))
+			return
+		}
+		req.Password = generated
+	}
+
+	fmt.Printf("Input two-factor secret as %s or leave empty: ", yellow("'base32 secret or otpauth://totp URI'"))
+	scanner.Scan()
+	req.TOTP = strings.TrimSpace(scanner.Text())
+
+	fmt.Printf("Input metadata as %s: ", yellow("'text'"))
+	scanner.Scan()
+	req.MetaData = scanner.Text()
+
+	_, err := p.credentialsService.UpdateCredentials(ctx, p.state.GetToken(), req)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Credentials successfully updated"))
+}
+
+// generatePassword generates a password with the default options of the generator and tells its strength,
+// the password itself is shown by 'load credentials data'.
+func generatePassword() (string, error) {
+	secret, err := passgen.Password(passgen.DefaultPasswordOptions())
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf(color.New(color.FgGreen).SprintFunc()("Generated a password of %d characters, %.0f bits of entropy\n"),
+		len(secret.Value), secret.Entropy)
+
+	return secret.Value, nil
+}
+
+// Delete prompts the user for a credentials ID and deletes it using the credentialsService.
+func (p *CredentialsProvider) Delete(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input data ID to delete credentials data:"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input data ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	dataID := scanner.Text()
+
+	err := p.credentialsService.DeleteCredentials(ctx, p.state.GetToken(), dataID)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Credentials successfully deleted"))
+}
@@ -0,0 +1,301 @@
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/vault"
+)
+
+const creditCard = "credit_card" // Data type of credit cards
+
+// CreditCardService defines methods of the online credit card client.
+type CreditCardService interface {
+	SaveCreditCard(ctx context.Context, token string, card model.CreditCardPostRequest) (model.CreditCard, error)
+	SaveCreditCardBatch(ctx context.Context, token string, items []model.CreditCardPostRequest, atomic bool) ([]model.BatchResult, error)
+	LoadCreditCardData(ctx context.Context, token string, dataID string) (model.CreditCard, error)
+	LoadAllCreditCardDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCreditCard(ctx context.Context, token string, card model.CreditCardPutRequest) (model.CreditCard, error)
+	DeleteCreditCard(ctx context.Context, token string, dataID string) error
+}
+
+// CreditCardClient mirrors credit cards into the local vault. When the server is unreachable
+// it serves reads from the vault and queues writes for sync. Updates conflicting with a change
+// made on another device are resolved by the conflict policy.
+type CreditCardClient struct {
+	online CreditCardService
+	state  *state.ClientState
+	policy vault.ConflictPolicy
+}
+
+// NewCreditCardClient creates a new instance of CreditCardClient wrapping the online client.
+func NewCreditCardClient(online CreditCardService, state *state.ClientState, policy vault.ConflictPolicy) *CreditCardClient {
+	return &CreditCardClient{
+		online: online,
+		state:  state,
+		policy: policy,
+	}
+}
+
+// SaveCreditCard saves a new credit card on the server and in the vault,
+// or only in the vault with a queued save when offline.
+func (c *CreditCardClient) SaveCreditCard(ctx context.Context, token string, card model.CreditCardPostRequest) (model.CreditCard, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		saved, err := c.online.SaveCreditCard(ctx, token, card)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return saved, err
+		}
+		if err == nil {
+			return saved, v.PutPayload(creditCard, saved.ID, saved.MetaData, saved.Revision, saved)
+		}
+	}
+
+	return c.saveLocal(v, card)
+}
+
+// SaveCreditCardBatch saves new credit cards on the server in one call and mirrors the saved ones into the vault,
+// or saves every item only in the vault with a queued save when offline.
+func (c *CreditCardClient) SaveCreditCardBatch(ctx context.Context, token string, items []model.CreditCardPostRequest, atomic bool) ([]model.BatchResult, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		results, err := c.online.SaveCreditCardBatch(ctx, token, items, atomic)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return results, err
+		}
+		if err == nil {
+			for i, result := range results {
+				if result.ID == "" {
+					continue
+				}
+				saved := model.CreditCard{
+					ID:        result.ID,
+					Number:    items[i].Number,
+					OwnerName: items[i].OwnerName,
+					ExpiresAt: items[i].ExpiresAt,
+					CVV:       items[i].CVV,
+					PinCode:   items[i].PinCode,
+					MetaData:  items[i].MetaData,
+					Revision:  result.Revision,
+
+					ExpiryReminder: items[i].ExpiryReminder,
+				}
+				if err = v.PutPayload(creditCard, saved.ID, saved.MetaData, saved.Revision, saved); err != nil {
+					return results, err
+				}
+			}
+			return results, nil
+		}
+	}
+
+	results := make([]model.BatchResult, 0, len(items))
+	for _, item := range items {
+		saved, err := c.saveLocal(v, item)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, model.BatchResult{ID: saved.ID})
+	}
+
+	return results, nil
+}
+
+// saveLocal saves a new credit card only in the vault and queues the save for sync.
+func (c *CreditCardClient) saveLocal(v *vault.Vault, card model.CreditCardPostRequest) (model.CreditCard, error) {
+	saved := model.CreditCard{
+		ID:        vault.NewLocalID(),
+		Number:    card.Number,
+		OwnerName: card.OwnerName,
+		ExpiresAt: card.ExpiresAt,
+		CVV:       card.CVV,
+		PinCode:   card.PinCode,
+		MetaData:  card.MetaData,
+
+		ExpiryReminder: card.ExpiryReminder,
+	}
+	if err := v.PutPayload(creditCard, saved.ID, saved.MetaData, 0, saved); err != nil {
+		return model.CreditCard{}, err
+	}
+
+	return saved, v.EnqueuePayload(vault.OpSave, creditCard, saved.ID, card)
+}
+
+// LoadCreditCardData loads a credit card from the server and stores it in the vault,
+// or from the vault when offline.
+func (c *CreditCardClient) LoadCreditCardData(ctx context.Context, token string, dataID string) (model.CreditCard, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		card, err := c.online.LoadCreditCardData(ctx, token, dataID)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return card, err
+		}
+		if err == nil {
+			return card, v.PutPayload(creditCard, dataID, card.MetaData, card.Revision, card)
+		}
+	}
+
+	var card model.CreditCard
+	if err := v.Payload(creditCard, dataID, &card); err != nil {
+		return model.CreditCard{}, err
+	}
+	card.ID = dataID
+
+	return card, nil
+}
+
+// LoadAllCreditCardDataInfo loads a page of the info of credit cards from the server and merges it into the vault,
+// or from the vault when offline. The vault drops the records missing on the server
+// only when the whole list fits in one page, otherwise deletions are picked up from the change feed.
+func (c *CreditCardClient) LoadAllCreditCardDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		infos, err := c.online.LoadAllCreditCardDataInfo(ctx, token, page)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return infos, err
+		}
+		if err == nil && page.PageToken == "" && infos.NextPageToken == "" {
+			return infos, v.MergeInfos(creditCard, infos.Infos)
+		}
+		if err == nil {
+			return infos, v.UpdateInfos(creditCard, infos.Infos)
+		}
+	}
+
+	return v.InfoPage(creditCard, page)
+}
+
+// UpdateCreditCard updates a credit card on the server and in the vault,
+// or only in the vault with a queued update when offline.
+// The update is made against the revision of the credit card stored in the vault.
+func (c *CreditCardClient) UpdateCreditCard(ctx context.Context, token string, card model.CreditCardPutRequest) (model.CreditCard, error) {
+	v := c.state.GetVault()
+	if v != nil && card.Revision == 0 {
+		card.Revision = v.Revision(creditCard, card.ID)
+	}
+
+	if !c.state.IsOffline() {
+		updated, err := c.update(ctx, token, card)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return updated, err
+		}
+		if err == nil {
+			return updated, v.PutPayload(creditCard, updated.ID, updated.MetaData, updated.Revision, updated)
+		}
+	}
+
+	updated := model.CreditCard{
+		ID:        card.ID,
+		Number:    card.Number,
+		OwnerName: card.OwnerName,
+		ExpiresAt: card.ExpiresAt,
+		CVV:       card.CVV,
+		PinCode:   card.PinCode,
+		MetaData:  card.MetaData,
+		Revision:  card.Revision,
+
+		ExpiryReminder: card.ExpiryReminder,
+	}
+	if err := v.PutPayload(creditCard, card.ID, card.MetaData, 0, updated); err != nil {
+		return model.CreditCard{}, err
+	}
+
+	return updated, v.EnqueuePayload(vault.OpUpdate, creditCard, card.ID, card)
+}
+
+// DeleteCreditCard deletes a credit card on the server and in the vault,
+// or only in the vault with a queued delete when offline.
+func (c *CreditCardClient) DeleteCreditCard(ctx context.Context, token string, dataID string) error {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		err := c.online.DeleteCreditCard(ctx, token, dataID)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return err
+		}
+		if err == nil {
+			return v.Delete(creditCard, dataID)
+		}
+	}
+
+	if err := v.Delete(creditCard, dataID); err != nil {
+		return err
+	}
+
+	return v.EnqueuePayload(vault.OpDelete, creditCard, dataID, nil)
+}
+
+// Replay replays a queued credit card write on the server.
+func (c *CreditCardClient) Replay(ctx context.Context, token string, op vault.Operation) (string, error) {
+	switch op.Kind {
+	case vault.OpSave:
+		var card model.CreditCardPostRequest
+		if err := json.Unmarshal(op.Payload, &card); err != nil {
+			return "", fmt.Errorf("unmarshal credit card: %w", err)
+		}
+		saved, err := c.online.SaveCreditCard(ctx, token, card)
+		return saved.ID, err
+	case vault.OpUpdate:
+		var card model.CreditCardPutRequest
+		if err := json.Unmarshal(op.Payload, &card); err != nil {
+			return "", fmt.Errorf("unmarshal credit card: %w", err)
+		}
+		card.ID = op.RecordID
+		updated, err := c.update(ctx, token, card)
+		if err != nil {
+			return op.RecordID, err
+		}
+		return op.RecordID, c.state.GetVault().PutPayload(creditCard, updated.ID, updated.MetaData, updated.Revision, updated)
+	case vault.OpDelete:
+		return op.RecordID, c.online.DeleteCreditCard(ctx, token, op.RecordID)
+	default:
+		return "", fmt.Errorf("unknown operation %s", op.Kind)
+	}
+}
+
+// Fetch loads a credit card from the server into the vault.
+func (c *CreditCardClient) Fetch(ctx context.Context, token string, id string) error {
+	v := c.state.GetVault()
+	if v == nil {
+		return nil
+	}
+
+	card, err := c.online.LoadCreditCardData(ctx, token, id)
+	if err != nil {
+		return err
+	}
+
+	return v.PutPayload(creditCard, id, card.MetaData, card.Revision, card)
+}
+
+// update updates a credit card on the server. An update rejected because the credit card was changed
+// on another device is resolved by the conflict policy: the local version either overwrites the server one
+// or is saved as new credit card, which is returned, while the vault gets the server version.
+func (c *CreditCardClient) update(ctx context.Context, token string, card model.CreditCardPutRequest) (model.CreditCard, error) {
+	updated, err := c.online.UpdateCreditCard(ctx, token, card)
+	if !vault.IsConflict(err) {
+		return updated, err
+	}
+
+	if c.policy == vault.LastWriterWins {
+		card.Revision = 0
+		return c.online.UpdateCreditCard(ctx, token, card)
+	}
+
+	saved, err := c.online.SaveCreditCard(ctx, token, model.CreditCardPostRequest{
+		Number:    card.Number,
+		OwnerName: card.OwnerName,
+		ExpiresAt: card.ExpiresAt,
+		CVV:       card.CVV,
+		PinCode:   card.PinCode,
+		MetaData:  card.MetaData + vault.ConflictCopySuffix,
+
+		ExpiryReminder: card.ExpiryReminder,
+	})
+	if err != nil {
+		return model.CreditCard{}, err
+	}
+
+	return saved, c.Fetch(ctx, token, card.ID)
+}
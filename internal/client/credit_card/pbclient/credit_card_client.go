@@ -3,22 +3,43 @@ package pbclient
 import (
 	"context"
 	"fmt"
+	"time"
+
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
 	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/credit_card"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/metadata"
 )
 
+// dataType is the type of the credit card, client side encrypted payloads are bound to it along with their id.
+const dataType = "credit_card"
+
+const (
+	expiresAtLayout   = "02-01-2006" // Layout of the expiry date of cards
+	expiryMonthLayout = "01-2006"    // Layout of the expiry month sent along with client side encrypted cards
+)
+
 // CreditCardPBClient is a client for interacting with the credit card service over gRPC.
 // It provides methods to save and load credit card data.
 type CreditCardPBClient struct {
 	creditCardService pb.CreditCardServiceClient
+	cipher            Cipher
+}
+
+// Cipher defines methods for client side encryption of the card payload.
+type Cipher interface {
+	Enabled() bool
+	Seal(id, dataType string, v any) ([]byte, error)
+	Open(id, dataType string, data []byte, v any) error
 }
 
 // NewCreditCardPBClient creates a new instance of CreditCardPBClient.
-// It takes a gRPC client for the credit card service as an argument and returns a pointer to the new client.
-func NewCreditCardPBClient(u pb.CreditCardServiceClient) *CreditCardPBClient {
+// It takes a gRPC client for the credit card service and a cipher for client side encryption
+// as arguments and returns a pointer to the new client.
+func NewCreditCardPBClient(u pb.CreditCardServiceClient, cipher Cipher) *CreditCardPBClient {
 	return &CreditCardPBClient{
 		creditCardService: u,
+		cipher:            cipher,
 	}
 }
 
@@ -33,6 +54,29 @@ func (u *CreditCardPBClient) SaveCreditCard(ctx context.Context, token string, c
 		CvvCode:   card.CVV,
 		PinCode:   card.PinCode,
 		Metadata:  card.MetaData,
+
+		ExpiryReminder: card.ExpiryReminder,
+	}
+
+	if u.cipher.Enabled() {
+		id := uuid.NewString()
+		cryptData, err := u.cipher.Seal(id, dataType, model.CreditCardCryptData{
+			Number:    card.Number,
+			OwnerName: card.OwnerName,
+			ExpiresAt: card.ExpiresAt,
+			CVV:       card.CVV,
+			PinCode:   card.PinCode,
+		})
+		if err != nil {
+			return model.CreditCard{}, fmt.Errorf("seal credit card: %w", err)
+		}
+		req = &pb.PostCreditCardRequest{
+			Id:             id,
+			Metadata:       card.MetaData,
+			CryptData:      cryptData,
+			ExpiryReminder: card.ExpiryReminder,
+			ExpiryMonth:    reminderMonth(card.ExpiryReminder, card.ExpiresAt),
+		}
 	}
 
 	md := metadata.New(map[string]string{"token": token})
@@ -43,30 +87,117 @@ func (u *CreditCardPBClient) SaveCreditCard(ctx context.Context, token string, c
 		return model.CreditCard{}, err
 	}
 
+	if len(req.CryptData) != 0 {
+		return model.CreditCard{
+			ID:        resp.Id,
+			Number:    card.Number,
+			OwnerName: card.OwnerName,
+			ExpiresAt: card.ExpiresAt,
+			CVV:       card.CVV,
+			PinCode:   card.PinCode,
+			MetaData:  resp.Metadata,
+			Revision:  resp.Revision,
+
+			ExpiryReminder: card.ExpiryReminder,
+		}, nil
+	}
+
 	creditCard := model.CreditCard{
+		ID:        resp.Id,
 		Number:    resp.Number,
 		OwnerName: resp.OwnerName,
 		ExpiresAt: resp.ExpiresAt,
 		CVV:       resp.CvvCode,
 		PinCode:   resp.PinCode,
 		MetaData:  resp.Metadata,
+		Revision:  resp.Revision,
+
+		ExpiryReminder: card.ExpiryReminder,
 	}
 
 	return creditCard, nil
 }
 
-// LoadAllCreditCardDataInfo retrieves information about all saved credit cards.
-// It takes a context and an authentication token as arguments.
-// It returns a slice of DataInfo containing the details of each credit card and any error encountered.
-func (u *CreditCardPBClient) LoadAllCreditCardDataInfo(ctx context.Context, token string) ([]model.DataInfo, error) {
-	req := &pb.GetAllCreditCardInfoRequest{}
+// SaveCreditCardBatch saves many credit cards at once using the credit card service. The results are in the order
+// of the requests. Items failing the validation of the server are not saved and have the violations of their fields set,
+// an atomic batch is rejected as a whole with an InvalidArgument error instead.
+func (u *CreditCardPBClient) SaveCreditCardBatch(ctx context.Context, token string, items []model.CreditCardPostRequest, atomic bool) ([]model.BatchResult, error) {
+	req := &pb.PostCreditCardBatchRequest{Cards: make([]*pb.PostCreditCardRequest, 0, len(items)), Atomic: atomic}
+	for _, item := range items {
+		in := &pb.PostCreditCardRequest{
+			Number:    item.Number,
+			OwnerName: item.OwnerName,
+			ExpiresAt: item.ExpiresAt,
+			CvvCode:   item.CVV,
+			PinCode:   item.PinCode,
+			Metadata:  item.MetaData,
+
+			ExpiryReminder: item.ExpiryReminder,
+		}
+
+		if u.cipher.Enabled() {
+			id := uuid.NewString()
+			cryptData, err := u.cipher.Seal(id, dataType, model.CreditCardCryptData{
+				Number:    item.Number,
+				OwnerName: item.OwnerName,
+				ExpiresAt: item.ExpiresAt,
+				CVV:       item.CVV,
+				PinCode:   item.PinCode,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("seal credit card: %w", err)
+			}
+			in = &pb.PostCreditCardRequest{
+				Id:             id,
+				Metadata:       item.MetaData,
+				CryptData:      cryptData,
+				ExpiryReminder: item.ExpiryReminder,
+				ExpiryMonth:    reminderMonth(item.ExpiryReminder, item.ExpiresAt),
+			}
+		}
+		req.Cards = append(req.Cards, in)
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.creditCardService.PostSaveCreditCardBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]model.BatchResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		result := model.BatchResult{ID: r.GetSaved().GetId(), Revision: r.GetSaved().GetRevision()}
+		for _, violation := range r.Violations {
+			if result.Violations == nil {
+				result.Violations = make(map[string]string)
+			}
+			result.Violations[violation.Field] = violation.Description
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// LoadAllCreditCardDataInfo retrieves a page of information about the saved credit cards.
+// It takes a context, an authentication token and the requested page as arguments.
+// It returns a page of DataInfo containing the details of each credit card and any error encountered.
+func (u *CreditCardPBClient) LoadAllCreditCardDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error) {
+	req := &pb.GetAllCreditCardInfoRequest{
+		PageSize:   int32(page.PageSize),
+		PageToken:  page.PageToken,
+		SortBy:     page.SortBy,
+		Descending: page.Descending,
+	}
 
 	md := metadata.New(map[string]string{"token": token})
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
 	resp, err := u.creditCardService.GetLoadAllCreditCardDataInfo(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("load credit card data: %w", err)
+		return model.DataInfoPage{}, fmt.Errorf("load credit card data: %w", err)
 	}
 
 	cards := make([]model.DataInfo, 0, len(resp.Cards))
@@ -75,11 +206,13 @@ func (u *CreditCardPBClient) LoadAllCreditCardDataInfo(ctx context.Context, toke
 			ID:        data.Id,
 			DataType:  data.DataType,
 			MetaData:  data.Metadata,
+			Revision:  data.Revision,
+			UpdatedAt: data.UpdatedAt,
 			CreatedAt: data.CreatedAt,
 		})
 	}
 
-	return cards, nil
+	return model.DataInfoPage{Infos: cards, NextPageToken: resp.NextPageToken}, nil
 }
 
 // LoadCreditCardData retrieves the details of a specific credit card using its ID.
@@ -99,13 +232,146 @@ func (u *CreditCardPBClient) LoadCreditCardData(ctx context.Context, token strin
 	}
 	data := resp.CardData
 	creditCard := model.CreditCard{
+		ID:        data.Id,
 		Number:    data.Number,
 		OwnerName: data.OwnerName,
 		ExpiresAt: data.ExpiresAt,
 		CVV:       data.CvvCode,
 		PinCode:   data.PinCode,
 		MetaData:  data.Metadata,
+		Revision:  data.Revision,
+
+		ExpiryReminder: data.ExpiryReminder,
+	}
+
+	if len(data.CryptData) != 0 {
+		var cryptData model.CreditCardCryptData
+		if err = u.cipher.Open(data.Id, dataType, data.CryptData, &cryptData); err != nil {
+			return model.CreditCard{}, fmt.Errorf("open credit card: %w", err)
+		}
+		creditCard = model.CreditCard{
+			ID:        data.Id,
+			Number:    cryptData.Number,
+			OwnerName: cryptData.OwnerName,
+			ExpiresAt: cryptData.ExpiresAt,
+			CVV:       cryptData.CVV,
+			PinCode:   cryptData.PinCode,
+			MetaData:  data.Metadata,
+			Revision:  data.Revision,
+
+			ExpiryReminder: data.ExpiryReminder,
+		}
 	}
 
 	return creditCard, nil
 }
+
+// UpdateCreditCard replaces the details of an existing credit card using the credit card service.
+// It takes a context, an authentication token, and a CreditCardPutRequest containing the card ID and new details.
+// It returns the updated CreditCard and any error encountered during the process.
+func (u *CreditCardPBClient) UpdateCreditCard(ctx context.Context, token string, card model.CreditCardPutRequest) (model.CreditCard, error) {
+	req := &pb.PutCreditCardRequest{
+		Id:        card.ID,
+		Revision:  card.Revision,
+		Number:    card.Number,
+		OwnerName: card.OwnerName,
+		ExpiresAt: card.ExpiresAt,
+		CvvCode:   card.CVV,
+		PinCode:   card.PinCode,
+		Metadata:  card.MetaData,
+
+		ExpiryReminder: card.ExpiryReminder,
+	}
+
+	if u.cipher.Enabled() {
+		cryptData, err := u.cipher.Seal(card.ID, dataType, model.CreditCardCryptData{
+			Number:    card.Number,
+			OwnerName: card.OwnerName,
+			ExpiresAt: card.ExpiresAt,
+			CVV:       card.CVV,
+			PinCode:   card.PinCode,
+		})
+		if err != nil {
+			return model.CreditCard{}, fmt.Errorf("seal credit card: %w", err)
+		}
+		req = &pb.PutCreditCardRequest{
+			Id:             card.ID,
+			Revision:       card.Revision,
+			Metadata:       card.MetaData,
+			CryptData:      cryptData,
+			ExpiryReminder: card.ExpiryReminder,
+			ExpiryMonth:    reminderMonth(card.ExpiryReminder, card.ExpiresAt),
+		}
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.creditCardService.PutUpdateCreditCard(ctx, req)
+	if err != nil {
+		return model.CreditCard{}, err
+	}
+
+	if len(req.CryptData) != 0 {
+		return model.CreditCard{
+			ID:        resp.Id,
+			Number:    card.Number,
+			OwnerName: card.OwnerName,
+			ExpiresAt: card.ExpiresAt,
+			CVV:       card.CVV,
+			PinCode:   card.PinCode,
+			MetaData:  resp.Metadata,
+			Revision:  resp.Revision,
+
+			ExpiryReminder: card.ExpiryReminder,
+		}, nil
+	}
+
+	creditCard := model.CreditCard{
+		ID:        resp.Id,
+		Number:    resp.Number,
+		OwnerName: resp.OwnerName,
+		ExpiresAt: resp.ExpiresAt,
+		CVV:       resp.CvvCode,
+		PinCode:   resp.PinCode,
+		MetaData:  resp.Metadata,
+		Revision:  resp.Revision,
+
+		ExpiryReminder: card.ExpiryReminder,
+	}
+
+	return creditCard, nil
+}
+
+// DeleteCreditCard deletes a specific credit card using its ID.
+// It takes a context, an authentication token, and the credit card ID as arguments.
+func (u *CreditCardPBClient) DeleteCreditCard(ctx context.Context, token string, dataID string) error {
+	req := &pb.DeleteCreditCardRequest{
+		Id: dataID,
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	_, err := u.creditCardService.DeleteCreditCard(ctx, req)
+	if err != nil {
+		return fmt.Errorf("delete credit card: %w", err)
+	}
+
+	return nil
+}
+
+// reminderMonth returns the expiry month of a client side encrypted card the server needs for its expiry reminder.
+// The month is not sent without a reminder, an expiry date it cannot be taken from is left for the server to reject.
+func reminderMonth(reminder bool, expiresAt string) string {
+	if !reminder {
+		return ""
+	}
+
+	t, err := time.Parse(expiresAtLayout, expiresAt)
+	if err != nil {
+		return ""
+	}
+
+	return t.Format(expiryMonthLayout)
+}
@@ -18,7 +18,9 @@ import (
 type CreditCardService interface {
 	SaveCreditCard(ctx context.Context, token string, card model.CreditCardPostRequest) (model.CreditCard, error)
 	LoadCreditCardData(ctx context.Context, token string, dataID string) (model.CreditCard, error)
-	LoadAllCreditCardDataInfo(ctx context.Context, token string) ([]model.DataInfo, error)
+	LoadAllCreditCardDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCreditCard(ctx context.Context, token string, card model.CreditCardPutRequest) (model.CreditCard, error)
+	DeleteCreditCard(ctx context.Context, token string, dataID string) error
 }
 
 // CreditCardProvider provides methods for interacting with credit card services.
@@ -26,14 +28,16 @@ type CreditCardService interface {
 type CreditCardProvider struct {
 	creditCardService CreditCardService  // The service responsible for credit card operations
 	state             *state.ClientState // Client state to manage user session
+	clipboard         lib.Clipboard      // Clipboard the secrets are copied to
 }
 
 // NewUserService creates a new CreditCardProvider instance.
-// It takes a CreditCardService and ClientState as parameters and returns the provider instance.
-func NewUserService(u CreditCardService, state *state.ClientState) *CreditCardProvider {
+// It takes a CreditCardService, ClientState and clipboard as parameters and returns the provider instance.
+func NewUserService(u CreditCardService, state *state.ClientState, clipboard lib.Clipboard) *CreditCardProvider {
 	return &CreditCardProvider{
 		creditCardService: u,
 		state:             state,
+		clipboard:         clipboard,
 	}
 }
 
@@ -84,6 +88,10 @@ func (p *CreditCardProvider) Save(ctx context.Context) {
 	data = scanner.Text()
 	req.MetaData = data
 
+	fmt.Printf("Remind before the card expires? %s: ", yellow("'y' or leave empty"))
+	scanner.Scan()
+	req.ExpiryReminder = strings.EqualFold(strings.TrimSpace(scanner.Text()), "y")
+
 	_, err := p.creditCardService.SaveCreditCard(ctx, p.state.GetToken(), req)
 	if err != nil {
 		lib.UnpackGRPCError(err)
@@ -93,7 +101,7 @@ func (p *CreditCardProvider) Save(ctx context.Context) {
 	fmt.Println(color.New(color.FgGreen).SprintFunc()("Card successfully saved"))
 }
 
-// LoadAllInfo retrieves and displays information about all saved credit cards.
+// LoadAllInfo retrieves and displays information about the saved credit cards page by page.
 // It checks for user authorization and working directory setup before loading the data.
 func (p *CreditCardProvider) LoadAllInfo(ctx context.Context) {
 	red := color.New(color.FgRed).SprintFunc()
@@ -108,30 +116,39 @@ func (p *CreditCardProvider) LoadAllInfo(ctx context.Context) {
 		return
 	}
 
-	cardDataInfo, err := p.creditCardService.LoadAllCreditCardDataInfo(ctx, p.state.GetToken())
-	if err != nil {
-		logrus.WithError(err).Error("All user data info load failed")
-		fmt.Println(red("All credit card data info load failed"), "please try again")
-		lib.UnpackGRPCError(err)
-		return
-	}
-
-	green := color.New(color.FgGreen).SprintFunc()
-	yellow := color.New(color.FgYellow).SprintFunc()
-	fmt.Println(green("-------------------------------------"))
-
-	var sb strings.Builder
-	for _, dataInfo := range cardDataInfo {
-		sb.WriteString("Data ID: " + dataInfo.ID + "\n")
-		sb.WriteString("Data type: " + dataInfo.DataType + "\n")
-		sb.WriteString("Metadata : " + dataInfo.MetaData + "\n")
-		sb.WriteString("Created at: : " + dataInfo.CreatedAt + "\n")
-		sb.WriteString(green("-------------------------------------") + "\n")
-	}
-	if len(cardDataInfo) > 0 {
-		fmt.Println(sb.String())
-	} else {
-		fmt.Println(yellow("Your haven't saved any data or data load filed, please try again"))
+	scanner := bufio.NewScanner(os.Stdin)
+	page := lib.ReadPageRequest(scanner)
+	for {
+		cardDataInfo, err := p.creditCardService.LoadAllCreditCardDataInfo(ctx, p.state.GetToken(), page)
+		if err != nil {
+			logrus.WithError(err).Error("All user data info load failed")
+			fmt.Println(red("All credit card data info load failed"), "please try again")
+			lib.UnpackGRPCError(err)
+			return
+		}
+
+		green := color.New(color.FgGreen).SprintFunc()
+		yellow := color.New(color.FgYellow).SprintFunc()
+		fmt.Println(green("-------------------------------------"))
+
+		var sb strings.Builder
+		for _, dataInfo := range cardDataInfo.Infos {
+			sb.WriteString("Data ID: " + dataInfo.ID + "\n")
+			sb.WriteString("Data type: " + dataInfo.DataType + "\n")
+			sb.WriteString("Metadata : " + dataInfo.MetaData + "\n")
+			sb.WriteString("Created at: : " + dataInfo.CreatedAt + "\n")
+			sb.WriteString(green("-------------------------------------") + "\n")
+		}
+		if len(cardDataInfo.Infos) > 0 {
+			fmt.Println(sb.String())
+		} else if page.PageToken == "" {
+			fmt.Println(yellow("Your haven't saved any data or data load filed, please try again"))
+		}
+
+		if cardDataInfo.NextPageToken == "" || !lib.ConfirmNextPage(scanner) {
+			return
+		}
+		page.PageToken = cardDataInfo.NextPageToken
 	}
 }
 
@@ -169,22 +186,42 @@ func (p *CreditCardProvider) LoadData(ctx context.Context) {
 
 	green := color.New(color.FgGreen).SprintFunc()
 
-	var sb strings.Builder
-	sb.WriteString(red("-------------------------------------") + "\n")
-	sb.WriteString("Card number: " + cardData.Number + "\n")
-	sb.WriteString("Card owner: " + cardData.OwnerName + "\n")
-	sb.WriteString("Card expires at: " + cardData.ExpiresAt + "\n")
-	sb.WriteString("Card cvv: " + cardData.CVV + "\n")
-	sb.WriteString("Card in code: " + cardData.PinCode + "\n")
-	sb.WriteString("Card metadata: " + cardData.MetaData + "\n")
-	sb.WriteString(red("-------------------------------------") + "\n")
-	fmt.Println(sb.String())
-	fmt.Print(green("Write info to file or print (leave empty or write to file): "))
+	format := func(mask func(string) string) string {
+		var sb strings.Builder
+		sb.WriteString(red("-------------------------------------") + "\n")
+		sb.WriteString("Card number: " + mask(cardData.Number) + "\n")
+		sb.WriteString("Card owner: " + cardData.OwnerName + "\n")
+		sb.WriteString("Card expires at: " + cardData.ExpiresAt + "\n")
+		sb.WriteString("Card cvv: " + mask(cardData.CVV) + "\n")
+		sb.WriteString("Card in code: " + mask(cardData.PinCode) + "\n")
+		sb.WriteString("Card metadata: " + cardData.MetaData + "\n")
+		if cardData.ExpiryReminder {
+			sb.WriteString("Expiry reminder: on\n")
+		}
+		sb.WriteString(red("-------------------------------------") + "\n")
+		return sb.String()
+	}
+	reveal := func(s string) string { return s }
+
+	fmt.Println(format(lib.Mask))
+	fmt.Printf(green("Print, write to file or copy a field (leave empty to print, input file name or %s): "),
+		yellow("'copy number|owner|expires|cvv|pin'"))
 	scanner.Scan()
 	path := scanner.Text()
 
 	if len(path) == 0 {
-		fmt.Print(sb.String())
+		fmt.Print(format(reveal))
+		return
+	}
+
+	if field, ok := strings.CutPrefix(path, lib.CopyCommand); ok {
+		lib.CopyField(p.clipboard, map[string]string{
+			"number":  strings.ReplaceAll(cardData.Number, " ", ""),
+			"owner":   cardData.OwnerName,
+			"expires": cardData.ExpiresAt,
+			"cvv":     cardData.CVV,
+			"pin":     cardData.PinCode,
+		}, field)
 		return
 	}
 
@@ -192,7 +229,7 @@ func (p *CreditCardProvider) LoadData(ctx context.Context) {
 		path = p.state.GetDirPath() + "/" + path
 	}
 
-	err = lib.SaveToFile(path, sb.String())
+	err = lib.SaveToFile(path, format(reveal))
 	if err != nil {
 		fmt.Println(err)
 		fmt.Printf("Error writing to file with path %s, please try again\n", red(path))
@@ -201,3 +238,89 @@ func (p *CreditCardProvider) LoadData(ctx context.Context) {
 
 	fmt.Printf("Data successfully written to file %s\n", green(path))
 }
+
+// Update prompts the user for the ID of an existing credit card and its new details,
+// then replaces the stored card using the credit card service.
+func (p *CreditCardProvider) Update(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	req := model.CreditCardPutRequest{}
+	fmt.Println(cyanBold("Input credit card data to update 'id number owner expires cvv pin metadata':"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input data ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	req.ID = scanner.Text()
+
+	fmt.Printf("Input number in format %s: ", yellow("'dddd dddd dddd dddd'"))
+	scanner.Scan()
+	req.Number = scanner.Text()
+
+	fmt.Printf("Input owner in format %s: ", yellow("'name surname'"))
+	scanner.Scan()
+	req.OwnerName = scanner.Text()
+
+	fmt.Printf("Input expiry date in format %s: ", yellow("'dd-mm-yyyy'"))
+	scanner.Scan()
+	req.ExpiresAt = scanner.Text()
+
+	fmt.Printf("Input pin in format %s: ", yellow("'dddd'"))
+	scanner.Scan()
+	req.PinCode = scanner.Text()
+
+	fmt.Printf("Input cvv in format %s: ", yellow("'ddd'"))
+	scanner.Scan()
+	req.CVV = scanner.Text()
+
+	fmt.Printf("Input data description as %s: ", yellow("'text'"))
+	scanner.Scan()
+	req.MetaData = scanner.Text()
+
+	fmt.Printf("Remind before the card expires? %s: ", yellow("'y' or leave empty"))
+	scanner.Scan()
+	req.ExpiryReminder = strings.EqualFold(strings.TrimSpace(scanner.Text()), "y")
+
+	_, err := p.creditCardService.UpdateCreditCard(ctx, p.state.GetToken(), req)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Card successfully updated"))
+}
+
+// Delete prompts the user for a credit card ID and deletes the card using the credit card service.
+func (p *CreditCardProvider) Delete(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input data ID to delete credit card:"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input data ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	dataID := scanner.Text()
+
+	err := p.creditCardService.DeleteCreditCard(ctx, p.state.GetToken(), dataID)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Card successfully deleted"))
+}
@@ -0,0 +1,330 @@
+package pbclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/custom_data"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+)
+
+// dataType is the type of the custom data, client side encrypted payloads are bound to it along with their id.
+const dataType = "custom_data"
+
+// CustomDataPBClient is a client for interacting with the custom data gRPC service.
+// It provides methods to manage item templates and the custom data items built from them.
+type CustomDataPBClient struct {
+	customDataService pb.CustomDataServiceClient
+	cipher            Cipher
+}
+
+// Cipher defines methods for client side encryption of the values of the fields.
+type Cipher interface {
+	Enabled() bool
+	Seal(id, dataType string, v any) ([]byte, error)
+	Open(id, dataType string, data []byte, v any) error
+}
+
+// NewCustomDataPBClient initializes a new CustomDataPBClient with the provided gRPC service client
+// and cipher for client side encryption.
+// It returns a pointer to the CustomDataPBClient instance.
+func NewCustomDataPBClient(u pb.CustomDataServiceClient, cipher Cipher) *CustomDataPBClient {
+	return &CustomDataPBClient{
+		customDataService: u,
+		cipher:            cipher,
+	}
+}
+
+// SaveTemplate saves a new item template using the custom data service.
+// It returns the saved template and any error encountered during the process.
+func (u *CustomDataPBClient) SaveTemplate(ctx context.Context, token string, template model.TemplateRequest) (model.Template, error) {
+	req := &pb.PostTemplateRequest{
+		Name:   template.Name,
+		Fields: toPBTemplateFields(template.Fields),
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.customDataService.PostSaveTemplate(ctx, req)
+	if err != nil {
+		return model.Template{}, err
+	}
+
+	return fromPBTemplate(resp.Template), nil
+}
+
+// LoadAllTemplates retrieves all item templates of the user ordered by name.
+func (u *CustomDataPBClient) LoadAllTemplates(ctx context.Context, token string) ([]model.Template, error) {
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.customDataService.GetLoadAllTemplates(ctx, &pb.GetAllTemplatesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("load templates: %w", err)
+	}
+
+	templates := make([]model.Template, 0, len(resp.Templates))
+	for _, template := range resp.Templates {
+		templates = append(templates, fromPBTemplate(template))
+	}
+
+	return templates, nil
+}
+
+// UpdateTemplate replaces the name and the fields of an existing item template using the custom data service.
+func (u *CustomDataPBClient) UpdateTemplate(ctx context.Context, token string, template model.TemplateRequest) (model.Template, error) {
+	req := &pb.PutTemplateRequest{
+		Id:     template.ID,
+		Name:   template.Name,
+		Fields: toPBTemplateFields(template.Fields),
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.customDataService.PutUpdateTemplate(ctx, req)
+	if err != nil {
+		return model.Template{}, err
+	}
+
+	return fromPBTemplate(resp.Template), nil
+}
+
+// DeleteTemplate deletes an item template by its ID.
+func (u *CustomDataPBClient) DeleteTemplate(ctx context.Context, token string, templateID string) error {
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	_, err := u.customDataService.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Id: templateID})
+	if err != nil {
+		return fmt.Errorf("delete template: %w", err)
+	}
+
+	return nil
+}
+
+// SaveCustomData saves a new custom data item using the custom data service.
+// It takes a context, a token for authorization, and the item to be saved.
+// It returns the saved item and any error encountered during the process.
+func (u *CustomDataPBClient) SaveCustomData(ctx context.Context, token string, custom model.CustomDataPostRequest) (model.CustomData, error) {
+	req := &pb.PostCustomDataRequest{
+		TemplateId: custom.TemplateID,
+		Fields:     toPBCustomFields(custom.Fields),
+		Metadata:   custom.MetaData,
+	}
+
+	if u.cipher.Enabled() {
+		id := uuid.NewString()
+		cryptData, err := u.cipher.Seal(id, dataType, model.CustomCryptData{TemplateID: custom.TemplateID, Fields: custom.Fields})
+		if err != nil {
+			return model.CustomData{}, fmt.Errorf("seal custom data: %w", err)
+		}
+		req = &pb.PostCustomDataRequest{Id: id, TemplateId: custom.TemplateID, Metadata: custom.MetaData, CryptData: cryptData}
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.customDataService.PostSaveCustomData(ctx, req)
+	if err != nil {
+		return model.CustomData{}, err
+	}
+
+	return model.CustomData{
+		ID:         resp.Id,
+		TemplateID: resp.TemplateId,
+		Fields:     custom.Fields,
+		MetaData:   resp.Metadata,
+		Revision:   resp.Revision,
+	}, nil
+}
+
+// LoadAllCustomDataInfo retrieves a page of information about the custom data items stored in the service.
+// It takes a context, a token for authorization and the requested page.
+// It returns a page of DataInfo models and any error encountered.
+func (u *CustomDataPBClient) LoadAllCustomDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error) {
+	req := &pb.GetAllCustomInfoRequest{
+		PageSize:   int32(page.PageSize),
+		PageToken:  page.PageToken,
+		SortBy:     page.SortBy,
+		Descending: page.Descending,
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.customDataService.GetLoadAllCustomDataInfo(ctx, req)
+	if err != nil {
+		return model.DataInfoPage{}, fmt.Errorf("load custom data: %w", err)
+	}
+
+	customInfos := make([]model.DataInfo, 0, len(resp.Custom))
+	for _, data := range resp.Custom {
+		customInfos = append(customInfos, model.DataInfo{
+			ID:        data.Id,
+			DataType:  data.DataType,
+			MetaData:  data.Metadata,
+			Revision:  data.Revision,
+			UpdatedAt: data.UpdatedAt,
+			CreatedAt: data.CreatedAt,
+		})
+	}
+
+	return model.DataInfoPage{Infos: customInfos, NextPageToken: resp.NextPageToken}, nil
+}
+
+// LoadCustomData retrieves a specific custom data item by its ID.
+// It takes a context, a token for authorization, and the ID of the data to load.
+// It returns the corresponding CustomData model and any error encountered.
+func (u *CustomDataPBClient) LoadCustomData(ctx context.Context, token string, dataID string) (model.CustomData, error) {
+	req := &pb.GetCustomDataRequest{
+		Id: dataID,
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.customDataService.GetLoadCustomData(ctx, req)
+	if err != nil {
+		return model.CustomData{}, fmt.Errorf("load custom data: %w", err)
+	}
+	data := resp.CustomData
+	custom := model.CustomData{
+		ID:         data.Id,
+		TemplateID: data.TemplateId,
+		Fields:     fromPBCustomFields(data.Fields),
+		MetaData:   data.Metadata,
+		Revision:   data.Revision,
+	}
+
+	if len(data.CryptData) != 0 {
+		var cryptData model.CustomCryptData
+		if err = u.cipher.Open(data.Id, dataType, data.CryptData, &cryptData); err != nil {
+			return model.CustomData{}, fmt.Errorf("open custom data: %w", err)
+		}
+		custom.TemplateID, custom.Fields = cryptData.TemplateID, cryptData.Fields
+	}
+
+	return custom, nil
+}
+
+// UpdateCustomData replaces an existing custom data item using the custom data service.
+// It takes a context, a token for authorization, and the item with its ID.
+// It returns the updated item and any error encountered during the process.
+func (u *CustomDataPBClient) UpdateCustomData(ctx context.Context, token string, custom model.CustomDataPutRequest) (model.CustomData, error) {
+	req := &pb.PutCustomDataRequest{
+		Id:         custom.ID,
+		Revision:   custom.Revision,
+		TemplateId: custom.TemplateID,
+		Fields:     toPBCustomFields(custom.Fields),
+		Metadata:   custom.MetaData,
+	}
+
+	if u.cipher.Enabled() {
+		cryptData, err := u.cipher.Seal(custom.ID, dataType, model.CustomCryptData{TemplateID: custom.TemplateID, Fields: custom.Fields})
+		if err != nil {
+			return model.CustomData{}, fmt.Errorf("seal custom data: %w", err)
+		}
+		req = &pb.PutCustomDataRequest{Id: custom.ID, Revision: custom.Revision, TemplateId: custom.TemplateID,
+			Metadata: custom.MetaData, CryptData: cryptData}
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.customDataService.PutUpdateCustomData(ctx, req)
+	if err != nil {
+		return model.CustomData{}, err
+	}
+
+	return model.CustomData{
+		ID:         resp.Id,
+		TemplateID: resp.TemplateId,
+		Fields:     custom.Fields,
+		MetaData:   resp.Metadata,
+		Revision:   resp.Revision,
+	}, nil
+}
+
+// DeleteCustomData deletes a specific custom data item by its ID.
+// It takes a context, a token for authorization, and the ID of the data to delete.
+func (u *CustomDataPBClient) DeleteCustomData(ctx context.Context, token string, dataID string) error {
+	req := &pb.DeleteCustomDataRequest{
+		Id: dataID,
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	_, err := u.customDataService.DeleteCustomData(ctx, req)
+	if err != nil {
+		return fmt.Errorf("delete custom data: %w", err)
+	}
+
+	return nil
+}
+
+// fromPBTemplate converts a template from its gRPC message.
+func fromPBTemplate(in *pb.Template) model.Template {
+	fields := make([]model.TemplateField, 0, len(in.GetFields()))
+	for _, f := range in.GetFields() {
+		fields = append(fields, model.TemplateField{
+			Name:     f.Name,
+			Type:     f.Type,
+			Required: f.Required,
+			Secret:   f.Secret,
+		})
+	}
+
+	return model.Template{
+		ID:        in.GetId(),
+		Name:      in.GetName(),
+		Fields:    fields,
+		CreatedAt: in.GetCreatedAt(),
+	}
+}
+
+// toPBTemplateFields converts the fields of a template into their gRPC messages.
+func toPBTemplateFields(fields []model.TemplateField) []*pb.TemplateField {
+	out := make([]*pb.TemplateField, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, &pb.TemplateField{
+			Name:     f.Name,
+			Type:     f.Type,
+			Required: f.Required,
+			Secret:   f.Secret,
+		})
+	}
+
+	return out
+}
+
+// toPBCustomFields converts the values of the fields of an item into their gRPC messages sorted by name.
+func toPBCustomFields(fields map[string]string) []*pb.CustomField {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]*pb.CustomField, 0, len(fields))
+	for _, name := range names {
+		out = append(out, &pb.CustomField{Name: name, Value: fields[name]})
+	}
+
+	return out
+}
+
+// fromPBCustomFields converts the values of the fields of an item from their gRPC messages.
+func fromPBCustomFields(in []*pb.CustomField) map[string]string {
+	fields := make(map[string]string, len(in))
+	for _, f := range in {
+		fields[f.Name] = f.Value
+	}
+
+	return fields
+}
@@ -0,0 +1,609 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CustomDataService defines the interface for interacting with item templates and custom data items.
+type CustomDataService interface {
+	SaveTemplate(ctx context.Context, token string, template model.TemplateRequest) (model.Template, error)
+	LoadAllTemplates(ctx context.Context, token string) ([]model.Template, error)
+	DeleteTemplate(ctx context.Context, token string, templateID string) error
+	SaveCustomData(ctx context.Context, token string, custom model.CustomDataPostRequest) (model.CustomData, error)
+	LoadCustomData(ctx context.Context, token string, dataID string) (model.CustomData, error)
+	LoadAllCustomDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCustomData(ctx context.Context, token string, custom model.CustomDataPutRequest) (model.CustomData, error)
+	DeleteCustomData(ctx context.Context, token string, dataID string) error
+}
+
+// presets are the templates offered when a template is created, for the secrets that don't fit the other data types.
+var presets = map[string][]model.TemplateField{
+	"ssh-key": {
+		{Name: "Host", Type: model.FieldTypeText},
+		{Name: "User", Type: model.FieldTypeText},
+		{Name: "Private key", Type: model.FieldTypeText, Required: true, Secret: true},
+		{Name: "Passphrase", Type: model.FieldTypeText, Secret: true},
+		{Name: "Public key", Type: model.FieldTypeText},
+	},
+	"api-token": {
+		{Name: "Service", Type: model.FieldTypeURL},
+		{Name: "Token", Type: model.FieldTypeText, Required: true, Secret: true},
+		{Name: "Expires", Type: model.FieldTypeDate},
+	},
+	"wifi": {
+		{Name: "SSID", Type: model.FieldTypeText, Required: true},
+		{Name: "Password", Type: model.FieldTypeText, Required: true, Secret: true},
+		{Name: "Security", Type: model.FieldTypeText},
+	},
+	"license": {
+		{Name: "Product", Type: model.FieldTypeText, Required: true},
+		{Name: "License key", Type: model.FieldTypeText, Required: true, Secret: true},
+		{Name: "Licensed to", Type: model.FieldTypeEmail},
+		{Name: "Seats", Type: model.FieldTypeNumber},
+		{Name: "Expires", Type: model.FieldTypeDate},
+	},
+}
+
+// CustomDataProvider provides methods for managing item templates and custom data items.
+// It holds a reference to a CustomDataService and maintains the client's state.
+type CustomDataProvider struct {
+	customDataService CustomDataService  // Service to handle template and custom data operations
+	state             *state.ClientState // Client's state, including authorization and directory information
+	clipboard         lib.Clipboard      // Clipboard the secrets are copied to
+}
+
+// NewCustomDataService initializes a new CustomDataProvider with the given CustomDataService,
+// ClientState and clipboard. It returns a pointer to the newly created CustomDataProvider.
+func NewCustomDataService(u CustomDataService, state *state.ClientState, clipboard lib.Clipboard) *CustomDataProvider {
+	return &CustomDataProvider{
+		customDataService: u,
+		state:             state,
+		clipboard:         clipboard,
+	}
+}
+
+// SaveTemplate prompts the user for the name of a template and either a preset or its fields,
+// then saves it using the customDataService.
+func (p *CustomDataProvider) SaveTemplate(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	req := model.TemplateRequest{}
+	fmt.Println(cyanBold("Input template data 'name fields':"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input name as %s: ", yellow("'example (Home Wi-Fi)'"))
+	scanner.Scan()
+	req.Name = strings.TrimSpace(scanner.Text())
+
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("Input preset as %s, leave empty to input the fields: ", yellow("'"+strings.Join(names, "', '")+"'"))
+	scanner.Scan()
+	if preset := strings.ToLower(strings.TrimSpace(scanner.Text())); preset != "" {
+		fields, ok := presets[preset]
+		if !ok {
+			fmt.Println(red(fmt.Sprintf("Unknown preset %q, please try again", preset)))
+			return
+		}
+		req.Fields = fields
+	}
+
+	for custom := len(req.Fields) == 0; custom; {
+		fmt.Printf("Input field as %s, leave empty to finish: ",
+			yellow("'name:text|number|url|email|date[:required][:secret]'"))
+		scanner.Scan()
+		spec := strings.TrimSpace(scanner.Text())
+		if spec == "" {
+			break
+		}
+
+		field, err := parseField(spec)
+		if err != nil {
+			fmt.Println(red("Invalid field please try again: ", err))
+			continue
+		}
+		req.Fields = append(req.Fields, field)
+	}
+
+	template, err := p.customDataService.SaveTemplate(ctx, p.state.GetToken(), req)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()(fmt.Sprintf("Template %s successfully saved with ID %s", template.Name, template.ID)))
+}
+
+// LoadTemplates retrieves and displays all item templates of the user with their fields.
+func (p *CustomDataProvider) LoadTemplates(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	templates, err := p.customDataService.LoadAllTemplates(ctx, p.state.GetToken())
+	if err != nil {
+		logrus.WithError(err).Error("Templates load failed")
+		fmt.Println(red("Templates load failed"), "please try again")
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	if len(templates) == 0 {
+		fmt.Println(yellow("Your haven't saved any templates"))
+		return
+	}
+
+	fmt.Println(green("-------------------------------------"))
+	var sb strings.Builder
+	for _, template := range templates {
+		sb.WriteString("Template ID: " + template.ID + "\n")
+		sb.WriteString("Template name: " + template.Name + "\n")
+		for _, field := range template.Fields {
+			sb.WriteString("  " + formatField(field) + "\n")
+		}
+		sb.WriteString(green("-------------------------------------") + "\n")
+	}
+	fmt.Println(sb.String())
+}
+
+// DeleteTemplate prompts the user for a template ID and deletes it using the customDataService.
+// The items of the template keep their values.
+func (p *CustomDataProvider) DeleteTemplate(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input template ID to delete the template, its items keep their values:"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input template ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	templateID := strings.TrimSpace(scanner.Text())
+
+	err := p.customDataService.DeleteTemplate(ctx, p.state.GetToken(), templateID)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Template successfully deleted"))
+}
+
+// Save prompts the user for a template and the values of its fields and metadata, then saves the item
+// using the customDataService. It checks if the user is authorized before proceeding.
+func (p *CustomDataProvider) Save(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input custom item data 'template fields metadata':"))
+
+	template, ok := p.chooseTemplate(ctx, scanner)
+	if !ok {
+		return
+	}
+
+	req := model.CustomDataPostRequest{TemplateID: template.ID, Fields: readFields(scanner, template, nil)}
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input metadata as %s: ", yellow("'text'"))
+	scanner.Scan()
+	req.MetaData = scanner.Text()
+
+	_, err := p.customDataService.SaveCustomData(ctx, p.state.GetToken(), req)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Custom item successfully saved"))
+}
+
+// LoadAllInfo retrieves and displays information about the saved custom items page by page.
+// It checks for user authorization and a valid working directory before loading the data.
+func (p *CustomDataProvider) LoadAllInfo(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	if p.state.GetDirPath() == "" {
+		fmt.Println(red("To proceed you must set working directory"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	page := lib.ReadPageRequest(scanner)
+	for {
+		customDataInfo, err := p.customDataService.LoadAllCustomDataInfo(ctx, p.state.GetToken(), page)
+		if err != nil {
+			logrus.WithError(err).Error("All user data info load failed")
+			fmt.Println(red("All custom items info load failed"), "please try again")
+			lib.UnpackGRPCError(err)
+			return
+		}
+
+		green := color.New(color.FgGreen).SprintFunc()
+		yellow := color.New(color.FgYellow).SprintFunc()
+		fmt.Println(green("-------------------------------------"))
+
+		var sb strings.Builder
+		for _, dataInfo := range customDataInfo.Infos {
+			sb.WriteString("Data ID: " + dataInfo.ID + "\n")
+			sb.WriteString("Data type: " + dataInfo.DataType + "\n")
+			sb.WriteString("Metadata : " + dataInfo.MetaData + "\n")
+			sb.WriteString("Created at: : " + dataInfo.CreatedAt + "\n")
+			sb.WriteString(green(green("-------------------------------------")) + "\n")
+		}
+		if len(customDataInfo.Infos) > 0 {
+			fmt.Println(sb.String())
+		} else if page.PageToken == "" {
+			fmt.Println(yellow("Your haven't saved any data or data load filed, please try again"))
+		}
+
+		if customDataInfo.NextPageToken == "" || !lib.ConfirmNextPage(scanner) {
+			return
+		}
+		page.PageToken = customDataInfo.NextPageToken
+	}
+}
+
+// LoadData retrieves a custom item based on the provided ID and displays it with the secret fields masked.
+// It allows the user to print the information, save it to a file or copy a field to the clipboard.
+// The fields of an item whose template was deleted are all treated as secret.
+func (p *CustomDataProvider) LoadData(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	if p.state.GetDirPath() == "" {
+		fmt.Println(red("To proceed you must set working directory"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input data ID to load custom item data:"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input data ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	dataID := scanner.Text()
+
+	customData, err := p.customDataService.LoadCustomData(ctx, p.state.GetToken(), dataID)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	template, err := p.loadTemplate(ctx, customData.TemplateID)
+	if err != nil {
+		logrus.WithError(err).Error("Template load failed")
+		fmt.Println(yellow("The template of the item is not available, all fields are masked"))
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fields := itemFields(template, customData.Fields)
+
+	format := func(reveal bool) string {
+		var sb strings.Builder
+		sb.WriteString(green("-------------------------------------") + "\n")
+		if template.Name != "" {
+			sb.WriteString("Template: " + template.Name + "\n")
+		}
+		for _, field := range fields {
+			value := customData.Fields[field.Name]
+			if field.Secret && !reveal {
+				value = lib.Mask(value)
+			}
+			sb.WriteString(field.Name + ": " + value + "\n")
+		}
+		sb.WriteString("Metadata: " + customData.MetaData + "\n")
+		sb.WriteString(green("-------------------------------------") + "\n")
+		return sb.String()
+	}
+
+	fmt.Println(format(false))
+	fmt.Printf(green("Print, write to file or copy a field (leave empty to print, input file name or %s): "),
+		yellow("'copy field name'"))
+	scanner.Scan()
+	path := scanner.Text()
+
+	if len(path) == 0 {
+		fmt.Print(format(true))
+		return
+	}
+
+	if field, ok := strings.CutPrefix(path, lib.CopyCommand); ok {
+		values := make(map[string]string, len(customData.Fields))
+		for name, value := range customData.Fields {
+			values[strings.ToLower(name)] = value
+		}
+		lib.CopyField(p.clipboard, values, field)
+		return
+	}
+
+	if p.state.GetDirPath() != "" {
+		path = p.state.GetDirPath() + "/" + path
+	}
+
+	err = lib.SaveToFile(path, format(true))
+	if err != nil {
+		fmt.Println(err)
+		fmt.Printf("Error writing to file with path %s, please try again\n", red(path))
+		return
+	}
+}
+
+// Update prompts the user for the ID of an existing custom item and the new values of its fields
+// and metadata, then replaces them using the customDataService. Empty answers keep the current values.
+func (p *CustomDataProvider) Update(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input custom item data to update 'id fields metadata', leave empty to keep the values:"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input data ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	dataID := scanner.Text()
+
+	customData, err := p.customDataService.LoadCustomData(ctx, p.state.GetToken(), dataID)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	template, err := p.loadTemplate(ctx, customData.TemplateID)
+	if err != nil {
+		logrus.WithError(err).Error("Template load failed")
+		fmt.Println(red("The template of the item is not available, the item can't be updated"))
+		return
+	}
+
+	req := model.CustomDataPutRequest{
+		ID:         customData.ID,
+		TemplateID: customData.TemplateID,
+		Fields:     readFields(scanner, template, customData.Fields),
+		Revision:   customData.Revision,
+	}
+
+	fmt.Printf("Input metadata as %s: ", yellow("'text'"))
+	scanner.Scan()
+	req.MetaData = scanner.Text()
+	if req.MetaData == "" {
+		req.MetaData = customData.MetaData
+	}
+
+	_, err = p.customDataService.UpdateCustomData(ctx, p.state.GetToken(), req)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Custom item successfully updated"))
+}
+
+// Delete prompts the user for a custom item ID and deletes it using the customDataService.
+func (p *CustomDataProvider) Delete(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input data ID to delete custom item data:"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input data ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	dataID := scanner.Text()
+
+	err := p.customDataService.DeleteCustomData(ctx, p.state.GetToken(), dataID)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Custom item successfully deleted"))
+}
+
+// chooseTemplate lists the templates of the user and prompts for one of them by name or ID.
+func (p *CustomDataProvider) chooseTemplate(ctx context.Context, scanner *bufio.Scanner) (model.Template, bool) {
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	templates, err := p.customDataService.LoadAllTemplates(ctx, p.state.GetToken())
+	if err != nil {
+		logrus.WithError(err).Error("Templates load failed")
+		fmt.Println(red("Templates load failed"), "please try again")
+		lib.UnpackGRPCError(err)
+		return model.Template{}, false
+	}
+
+	if len(templates) == 0 {
+		fmt.Println(yellow("Your haven't saved any templates, please save a template first"))
+		return model.Template{}, false
+	}
+
+	names := make([]string, 0, len(templates))
+	for _, template := range templates {
+		names = append(names, template.Name)
+	}
+
+	fmt.Printf("Input template as %s: ", yellow("'"+strings.Join(names, "', '")+"'"))
+	scanner.Scan()
+	choice := strings.TrimSpace(scanner.Text())
+	for _, template := range templates {
+		if strings.EqualFold(template.Name, choice) || template.ID == choice {
+			return template, true
+		}
+	}
+
+	fmt.Println(red(fmt.Sprintf("Unknown template %q, please try again", choice)))
+	return model.Template{}, false
+}
+
+// loadTemplate returns the template of the user with the ID.
+func (p *CustomDataProvider) loadTemplate(ctx context.Context, templateID string) (model.Template, error) {
+	templates, err := p.customDataService.LoadAllTemplates(ctx, p.state.GetToken())
+	if err != nil {
+		return model.Template{}, err
+	}
+
+	for _, template := range templates {
+		if template.ID == templateID {
+			return template, nil
+		}
+	}
+
+	return model.Template{}, errors.New("template " + templateID + " not found")
+}
+
+// readFields prompts the user for the values of the fields of the template. An empty answer keeps
+// the current value of the field, if any.
+func readFields(scanner *bufio.Scanner, template model.Template, current map[string]string) map[string]string {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fields := make(map[string]string, len(template.Fields))
+	for _, field := range template.Fields {
+		hint := field.Type
+		if field.Required {
+			hint += ", required"
+		}
+		fmt.Printf("Input %s as %s: ", field.Name, yellow("'"+hint+"'"))
+		scanner.Scan()
+
+		value := scanner.Text()
+		if value == "" {
+			value = current[field.Name]
+		}
+		if value != "" {
+			fields[field.Name] = value
+		}
+	}
+
+	return fields
+}
+
+// itemFields returns the fields of the item in the order of its template. Fields the template doesn't define,
+// or all fields when the template is not available, follow in the order of their names and are treated as secret.
+func itemFields(template model.Template, values map[string]string) []model.TemplateField {
+	fields := make([]model.TemplateField, 0, len(values))
+	known := make(map[string]struct{}, len(template.Fields))
+	for _, field := range template.Fields {
+		known[field.Name] = struct{}{}
+		if _, ok := values[field.Name]; ok {
+			fields = append(fields, field)
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if _, ok := known[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fields = append(fields, model.TemplateField{Name: name, Type: model.FieldTypeText, Secret: true})
+	}
+
+	return fields
+}
+
+// parseField parses a field of a template written as 'name:type[:required][:secret]', the type defaults to text.
+func parseField(spec string) (model.TemplateField, error) {
+	parts := strings.Split(spec, ":")
+	field := model.TemplateField{Name: strings.TrimSpace(parts[0]), Type: model.FieldTypeText}
+	if field.Name == "" {
+		return model.TemplateField{}, errors.New("field name is empty")
+	}
+
+	if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" {
+		field.Type = strings.ToLower(strings.TrimSpace(parts[1]))
+	}
+
+	for _, flag := range parts[min(2, len(parts)):] {
+		switch strings.ToLower(strings.TrimSpace(flag)) {
+		case "required":
+			field.Required = true
+		case "secret":
+			field.Secret = true
+		default:
+			return model.TemplateField{}, fmt.Errorf("unknown flag %q, expected required or secret", flag)
+		}
+	}
+
+	return field, nil
+}
+
+// formatField returns the field as 'name (type, required, secret)'.
+func formatField(field model.TemplateField) string {
+	attrs := []string{field.Type}
+	if field.Required {
+		attrs = append(attrs, "required")
+	}
+	if field.Secret {
+		attrs = append(attrs, "secret")
+	}
+
+	return field.Name + " (" + strings.Join(attrs, ", ") + ")"
+}
@@ -0,0 +1,227 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	argonTime    = 3         // Argon2id number of passes
+	argonMemory  = 64 * 1024 // Argon2id memory in KiB
+	argonThreads = 4         // Argon2id parallelism
+	saltPrefix   = "privatekeeper-v2:"
+)
+
+// ErrNoDataKey is returned when the data key has not been unlocked yet.
+var ErrNoDataKey = errors.New("data key is not unlocked, please login")
+
+// KeyHolder provides access to the unwrapped data key of the current session.
+type KeyHolder interface {
+	GetDataKey() []byte
+}
+
+// Service encrypts and decrypts user payloads on the client side,
+// so the server only ever receives opaque blobs.
+type Service struct {
+	keys KeyHolder // Holder of the session data key
+}
+
+// New initializes a new Service with the provided key holder.
+func New(keys KeyHolder) *Service {
+	return &Service{keys: keys}
+}
+
+// Enabled reports whether a data key is available and payloads must be encrypted.
+func (s *Service) Enabled() bool {
+	return len(s.keys.GetDataKey()) != 0
+}
+
+// Seal marshals v to JSON and encrypts it with the session data key. The ID and the data type of the record
+// are authenticated as the associated data, so the server can't swap the payloads of two records.
+func (s *Service) Seal(id, dataType string, v any) ([]byte, error) {
+	key := s.keys.GetDataKey()
+	if len(key) == 0 {
+		return nil, ErrNoDataKey
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	return encrypt(key, data, recordAD(id, dataType))
+}
+
+// Open decrypts the payload of the record with the given ID and data type with the session data key
+// and unmarshals the JSON into v. Payloads sealed before records were bound to their ID have no associated
+// data, they are still opened and get bound on their next update.
+func (s *Service) Open(id, dataType string, data []byte, v any) error {
+	key := s.keys.GetDataKey()
+	if len(key) == 0 {
+		return ErrNoDataKey
+	}
+
+	dec, err := decrypt(key, data, recordAD(id, dataType))
+	if err != nil {
+		var legacyErr error
+		if dec, legacyErr = Decrypt(key, data); legacyErr != nil {
+			return err
+		}
+	}
+
+	if err = json.Unmarshal(dec, v); err != nil {
+		return fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return nil
+}
+
+// recordAD builds the additional data of a record payload from its ID and data type.
+func recordAD(id, dataType string) []byte {
+	return []byte(id + "|" + dataType)
+}
+
+// DeriveKeys derives the authentication key and the key-encryption key from the master password.
+// The authentication key is sent to the server instead of the password, the key-encryption key never leaves the client.
+func DeriveKeys(login, password string) (string, []byte, error) {
+	masterKey := deriveMasterKey(login, password)
+
+	authKey := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, masterKey, nil, []byte("auth")), authKey); err != nil {
+		return "", nil, fmt.Errorf("hkdf auth key: %w", err)
+	}
+
+	kek := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, masterKey, nil, []byte("kek")), kek); err != nil {
+		return "", nil, fmt.Errorf("hkdf kek: %w", err)
+	}
+
+	return hex.EncodeToString(authKey), kek, nil
+}
+
+// DeriveVaultKey derives the key of the local offline vault from the master password.
+// The key never leaves the client and allows to unlock the vault without the server.
+func DeriveVaultKey(login, password string) ([]byte, error) {
+	masterKey := deriveMasterKey(login, password)
+
+	vaultKey := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, masterKey, nil, []byte("vault")), vaultKey); err != nil {
+		return nil, fmt.Errorf("hkdf vault key: %w", err)
+	}
+
+	return vaultKey, nil
+}
+
+// DerivePassphraseKey derives a key from a local passphrase with Argon2id salted by the given random salt.
+// Unlike the master password the passphrase is never sent anywhere, it only protects files on the device.
+func DerivePassphraseKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, chacha20poly1305.KeySize)
+}
+
+// deriveMasterKey stretches the master password with Argon2id salted by the login.
+func deriveMasterKey(login, password string) []byte {
+	salt := sha256.Sum256([]byte(saltPrefix + strings.ToLower(login)))
+	return argon2.IDKey([]byte(password), salt[:], argonTime, argonMemory, argonThreads, chacha20poly1305.KeySize)
+}
+
+// GenerateDataKey generates a new random data key.
+func GenerateDataKey() ([]byte, error) {
+	key := make([]byte, chacha20poly1305.KeySize)
+	_, err := rand.Read(key)
+	if err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+	return key, nil
+}
+
+// WrapKey encrypts the data key with the key-encryption key.
+func WrapKey(kek, dataKey []byte) ([]byte, error) {
+	return Encrypt(kek, dataKey)
+}
+
+// UnwrapKey decrypts the wrapped data key with the key-encryption key.
+func UnwrapKey(kek, wrappedKey []byte) ([]byte, error) {
+	return Decrypt(kek, wrappedKey)
+}
+
+// Encrypt encrypts the given data using the provided key.
+func Encrypt(key, data []byte) ([]byte, error) {
+	return encrypt(key, data, nil)
+}
+
+// Decrypt decrypts the given data using the provided key.
+func Decrypt(key, data []byte) ([]byte, error) {
+	return decrypt(key, data, nil)
+}
+
+// EncryptChunk encrypts a chunk of a stream using the provided key.
+// The ID of the file, the chunk index and the last chunk flag are authenticated, so chunks moved
+// between files, reordered, dropped or truncated fail to decrypt. Streams that are not files,
+// like archives, pass an empty ID.
+func EncryptChunk(key, data []byte, fileID string, index int, last bool) ([]byte, error) {
+	return encrypt(key, data, chunkAD(fileID, index, last))
+}
+
+// DecryptChunk decrypts a chunk of a stream using the provided key.
+func DecryptChunk(key, data []byte, fileID string, index int, last bool) ([]byte, error) {
+	return decrypt(key, data, chunkAD(fileID, index, last))
+}
+
+// encrypt encrypts the given data with the additional data using the provided key.
+func encrypt(key, data, ad []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("chacha20poly1305.NewX: %w", err)
+	}
+
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+
+	ciphertext := aead.Seal(nonce, nonce, data, ad)
+	return ciphertext, nil
+}
+
+// decrypt decrypts the given data with the additional data using the provided key.
+func decrypt(key, data, ad []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("chacha20poly1305.NewX: %w", err)
+	}
+
+	if len(data) < chacha20poly1305.NonceSizeX {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:chacha20poly1305.NonceSizeX], data[chacha20poly1305.NonceSizeX:]
+	dec, err := aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, fmt.Errorf("aead.Open: %w", err)
+	}
+
+	return dec, nil
+}
+
+// chunkAD builds the additional data of a stream chunk from its index, the last chunk flag and the file ID.
+// An empty file ID gives the additional data of the chunks of files streamed before they were bound to their ID.
+func chunkAD(fileID string, index int, last bool) []byte {
+	ad := make([]byte, 9, 9+len(fileID))
+	binary.BigEndian.PutUint64(ad, uint64(index))
+	if last {
+		ad[8] = 1
+	}
+	return append(ad, fileID...)
+}
@@ -0,0 +1,171 @@
+package encryption
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type keyHolder struct {
+	key []byte
+}
+
+func (k *keyHolder) GetDataKey() []byte {
+	return k.key
+}
+
+type CryptServiceTestSuite struct {
+	suite.Suite
+	keys         *keyHolder
+	cryptService *Service
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(CryptServiceTestSuite))
+}
+
+func (c *CryptServiceTestSuite) SetupTest() {
+	key, err := GenerateDataKey()
+	require.NoError(c.T(), err)
+	c.keys = &keyHolder{key: key}
+	c.cryptService = New(c.keys)
+}
+
+func (c *CryptServiceTestSuite) Test_DeriveKeys() {
+	authKey, kek, err := DeriveKeys("User@Mail.ru", "password")
+	assert.NoError(c.T(), err)
+	assert.Len(c.T(), authKey, 64)
+	assert.Len(c.T(), kek, 32)
+	assert.NotContains(c.T(), authKey, "password")
+
+	sameAuthKey, sameKek, err := DeriveKeys("user@mail.ru", "password")
+	assert.NoError(c.T(), err)
+	assert.Equal(c.T(), authKey, sameAuthKey)
+	assert.Equal(c.T(), kek, sameKek)
+
+	otherAuthKey, otherKek, err := DeriveKeys("user@mail.ru", "other password")
+	assert.NoError(c.T(), err)
+	assert.NotEqual(c.T(), authKey, otherAuthKey)
+	assert.NotEqual(c.T(), kek, otherKek)
+}
+
+func (c *CryptServiceTestSuite) Test_WrapUnwrapKey() {
+	_, kek, err := DeriveKeys("user@mail.ru", "password")
+	require.NoError(c.T(), err)
+
+	wrapped, err := WrapKey(kek, c.keys.key)
+	assert.NoError(c.T(), err)
+	assert.NotEqual(c.T(), c.keys.key, wrapped)
+
+	unwrapped, err := UnwrapKey(kek, wrapped)
+	assert.NoError(c.T(), err)
+	assert.Equal(c.T(), c.keys.key, unwrapped)
+
+	_, wrongKek, err := DeriveKeys("user@mail.ru", "wrong password")
+	require.NoError(c.T(), err)
+	_, err = UnwrapKey(wrongKek, wrapped)
+	assert.Error(c.T(), err)
+}
+
+func (c *CryptServiceTestSuite) Test_SealOpen() {
+	type payload struct {
+		Login    string
+		Password string
+	}
+	data := payload{Login: "login", Password: "secret"}
+
+	assert.True(c.T(), c.cryptService.Enabled())
+	sealed, err := c.cryptService.Seal("id-1", "credentials", data)
+	assert.NoError(c.T(), err)
+	assert.NotContains(c.T(), string(sealed), "secret")
+
+	var opened payload
+	err = c.cryptService.Open("id-1", "credentials", sealed, &opened)
+	assert.NoError(c.T(), err)
+	assert.Equal(c.T(), data, opened)
+}
+
+// Test_OpenSwappedPayload checks that a payload doesn't open as the payload of another record or type.
+func (c *CryptServiceTestSuite) Test_OpenSwappedPayload() {
+	sealed, err := c.cryptService.Seal("id-1", "credentials", "secret")
+	require.NoError(c.T(), err)
+
+	err = c.cryptService.Open("id-2", "credentials", sealed, new(string))
+	assert.Error(c.T(), err)
+
+	err = c.cryptService.Open("id-1", "text_data", sealed, new(string))
+	assert.Error(c.T(), err)
+}
+
+// Test_OpenLegacyPayload opens a payload sealed without the ID and the data type of the record.
+func (c *CryptServiceTestSuite) Test_OpenLegacyPayload() {
+	data, err := json.Marshal("secret")
+	require.NoError(c.T(), err)
+	legacy, err := Encrypt(c.keys.key, data)
+	require.NoError(c.T(), err)
+
+	var opened string
+	err = c.cryptService.Open("id-1", "credentials", legacy, &opened)
+	assert.NoError(c.T(), err)
+	assert.Equal(c.T(), "secret", opened)
+}
+
+func (c *CryptServiceTestSuite) Test_NoDataKey() {
+	c.keys.key = nil
+	assert.False(c.T(), c.cryptService.Enabled())
+
+	_, err := c.cryptService.Seal("id-1", "text_data", "data")
+	assert.ErrorIs(c.T(), err, ErrNoDataKey)
+
+	err = c.cryptService.Open("id-1", "text_data", []byte("data"), new(string))
+	assert.ErrorIs(c.T(), err, ErrNoDataKey)
+}
+
+func (c *CryptServiceTestSuite) Test_DecryptShortData() {
+	_, err := Decrypt(c.keys.key, []byte("short"))
+	assert.Error(c.T(), err)
+}
+
+func (c *CryptServiceTestSuite) Test_EncryptDecryptChunk() {
+	data := []byte("chunk data")
+	cryptData, err := EncryptChunk(c.keys.key, data, "file-1", 0, true)
+	assert.NoError(c.T(), err)
+
+	decryptedData, err := DecryptChunk(c.keys.key, cryptData, "file-1", 0, true)
+	assert.NoError(c.T(), err)
+	assert.Equal(c.T(), data, decryptedData)
+
+	_, err = DecryptChunk(c.keys.key, cryptData, "file-1", 0, false)
+	assert.Error(c.T(), err)
+
+	_, err = DecryptChunk(c.keys.key, cryptData, "file-1", 1, true)
+	assert.Error(c.T(), err)
+
+	_, err = DecryptChunk(c.keys.key, cryptData, "file-2", 0, true)
+	assert.Error(c.T(), err, "a chunk must not decrypt as a chunk of another file")
+}
+
+func (c *CryptServiceTestSuite) Test_DeriveVaultKey() {
+	vaultKey, err := DeriveVaultKey("user@mail.ru", "password")
+	assert.NoError(c.T(), err)
+	assert.Len(c.T(), vaultKey, 32)
+
+	_, kek, err := DeriveKeys("user@mail.ru", "password")
+	require.NoError(c.T(), err)
+	assert.NotEqual(c.T(), kek, vaultKey)
+
+	otherVaultKey, err := DeriveVaultKey("user@mail.ru", "other password")
+	assert.NoError(c.T(), err)
+	assert.NotEqual(c.T(), vaultKey, otherVaultKey)
+}
+
+func (c *CryptServiceTestSuite) Test_DerivePassphraseKey() {
+	key := DerivePassphraseKey("passphrase", []byte("salt-1"))
+	assert.Len(c.T(), key, 32)
+	assert.Equal(c.T(), key, DerivePassphraseKey("passphrase", []byte("salt-1")))
+	assert.NotEqual(c.T(), key, DerivePassphraseKey("passphrase", []byte("salt-2")))
+	assert.NotEqual(c.T(), key, DerivePassphraseKey("other passphrase", []byte("salt-1")))
+}
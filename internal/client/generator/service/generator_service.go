@@ -0,0 +1,146 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/passgen"
+	"github.com/fatih/color"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GeneratorProvider provides the password generator command of the client.
+// Passwords are generated locally, so the command works offline and without login.
+type GeneratorProvider struct {
+	clipboard lib.Clipboard // Clipboard the passwords are copied to
+}
+
+// NewGeneratorService initializes a new GeneratorProvider with the given clipboard.
+// It returns a pointer to the newly created GeneratorProvider.
+func NewGeneratorService(clipboard lib.Clipboard) *GeneratorProvider {
+	return &GeneratorProvider{clipboard: clipboard}
+}
+
+// Generate prompts the user for the options of a password or a passphrase, prints the generated one
+// with its entropy and allows copying it to the clipboard.
+func (p *GeneratorProvider) Generate(_ context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input generator options, leave an option empty to use the default:"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input mode as %s: ", yellow("'password' (default) or 'passphrase'"))
+	scanner.Scan()
+	mode := strings.ToLower(strings.TrimSpace(scanner.Text()))
+
+	var (
+		secret passgen.Secret
+		err    error
+	)
+	switch mode {
+	case "", "password":
+		secret, err = p.password(scanner)
+	case "passphrase":
+		secret, err = p.passphrase(scanner)
+	default:
+		fmt.Println(red("Unknown mode, please use 'password' or 'passphrase'"))
+		return
+	}
+	if err != nil {
+		fmt.Println(red(err))
+		return
+	}
+
+	printSecret(secret)
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf(green("Copy it to the clipboard? (%s): "), yellow("'y' or leave empty to skip"))
+	scanner.Scan()
+	if strings.EqualFold(strings.TrimSpace(scanner.Text()), "y") {
+		lib.CopyField(p.clipboard, map[string]string{"password": secret.Value}, "password")
+	}
+}
+
+// password prompts the user for the options of a password and generates it.
+func (p *GeneratorProvider) password(scanner *bufio.Scanner) (passgen.Secret, error) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	opts := passgen.DefaultPasswordOptions()
+
+	fmt.Printf("Input length as %s: ", yellow(fmt.Sprintf("'%d..%d' (default %d)", passgen.MinLength, passgen.MaxLength, opts.Length)))
+	scanner.Scan()
+	if err := parseInt(scanner.Text(), &opts.Length); err != nil {
+		return passgen.Secret{}, err
+	}
+
+	fmt.Printf("Input character classes as %s: ", yellow("'"+strings.Join(passgen.Classes, ",")+"' (default all)"))
+	scanner.Scan()
+	if classes := strings.TrimSpace(scanner.Text()); classes != "" {
+		if err := opts.SetClasses(strings.Split(classes, ",")); err != nil {
+			return passgen.Secret{}, err
+		}
+	}
+
+	fmt.Printf("Exclude ambiguous characters like O and 0? %s: ", yellow("'y' or leave empty"))
+	scanner.Scan()
+	opts.ExcludeAmbiguous = strings.EqualFold(strings.TrimSpace(scanner.Text()), "y")
+
+	return passgen.Password(opts)
+}
+
+// passphrase prompts the user for the options of a passphrase and generates it.
+func (p *GeneratorProvider) passphrase(scanner *bufio.Scanner) (passgen.Secret, error) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	opts := passgen.DefaultPassphraseOptions()
+
+	fmt.Printf("Input number of words as %s: ", yellow(fmt.Sprintf("'%d..%d' (default %d)", passgen.MinWords, passgen.MaxWords, opts.Words)))
+	scanner.Scan()
+	if err := parseInt(scanner.Text(), &opts.Words); err != nil {
+		return passgen.Secret{}, err
+	}
+
+	fmt.Printf("Input separator as %s: ", yellow(fmt.Sprintf("'text' (default %q)", opts.Separator)))
+	scanner.Scan()
+	if separator := scanner.Text(); separator != "" {
+		opts.Separator = separator
+	}
+
+	fmt.Printf("Capitalize the words? %s: ", yellow("'y' or leave empty"))
+	scanner.Scan()
+	opts.Capitalize = strings.EqualFold(strings.TrimSpace(scanner.Text()), "y")
+
+	fmt.Printf("Add a digit? %s: ", yellow("'y' or leave empty"))
+	scanner.Scan()
+	opts.Number = strings.EqualFold(strings.TrimSpace(scanner.Text()), "y")
+
+	return passgen.Passphrase(opts)
+}
+
+// printSecret prints the generated password with the estimate of its entropy.
+func printSecret(secret passgen.Secret) {
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Println(green("-------------------------------------"))
+	fmt.Println("Generated: " + secret.Value)
+	fmt.Printf("Entropy: %.0f bits, %s\n", secret.Entropy, passgen.Strength(secret.Entropy))
+	fmt.Println(green("-------------------------------------"))
+}
+
+// parseInt parses a number typed by the user, empty input keeps the default.
+func parseInt(s string, n *int) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a number, please try again", s)
+	}
+	*n = v
+
+	return nil
+}
@@ -0,0 +1,192 @@
+// Package health audits the decrypted vault on the client: weak, reused and old passwords
+// of credentials and credit cards that expire soon.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/passgen"
+)
+
+const expiresAtLayout = "02-01-2006" // Layout of the card expiry date
+
+// CredentialsService defines the credentials operations used by the audit.
+type CredentialsService interface {
+	LoadAllCredentialsDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	LoadCredentialsData(ctx context.Context, token string, dataID string) (model.Credentials, error)
+}
+
+// CreditCardService defines the credit card operations used by the audit.
+type CreditCardService interface {
+	LoadAllCreditCardDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	LoadCreditCardData(ctx context.Context, token string, dataID string) (model.CreditCard, error)
+}
+
+// Services holds the services of the audited data types.
+type Services struct {
+	Credentials CredentialsService
+	CreditCards CreditCardService
+}
+
+// Options holds the thresholds of the audit.
+type Options struct {
+	MinEntropy float64 // Passwords with less estimated entropy in bits are weak
+	MaxAgeDays int     // Passwords not changed for more days are old
+	ExpiryDays int     // Cards expiring within the number of days are reported, expired ones always are
+}
+
+// DefaultOptions returns the thresholds used when the user doesn't set them: passwords rated weak
+// by the generator, older than a year and cards expiring within 30 days.
+func DefaultOptions() Options {
+	return Options{MinEntropy: 50, MaxAgeDays: 365, ExpiryDays: 30}
+}
+
+// Item identifies an audited record, the secrets are never part of the report.
+type Item struct {
+	ID       string `json:"id"`
+	MetaData string `json:"metadata"`
+}
+
+// WeakPassword is credentials with a password of low entropy.
+type WeakPassword struct {
+	Item
+	Entropy  float64 `json:"entropy"`
+	Strength string  `json:"strength"`
+}
+
+// OldPassword is credentials not changed for longer than the maximum age.
+type OldPassword struct {
+	Item
+	UpdatedAt string `json:"updated_at"`
+	AgeDays   int    `json:"age_days"`
+}
+
+// ExpiringCard is a credit card that expires soon or has expired.
+type ExpiringCard struct {
+	Item
+	ExpiresAt string `json:"expires_at"`
+	DaysLeft  int    `json:"days_left"` // Negative for expired cards
+}
+
+// Report is the result of the audit.
+type Report struct {
+	CheckedAt     time.Time      `json:"checked_at"`
+	Credentials   int            `json:"credentials"`    // Number of audited credentials
+	CreditCards   int            `json:"credit_cards"`   // Number of audited cards
+	Weak          []WeakPassword `json:"weak"`           // Weakest first
+	Reused        [][]Item       `json:"reused"`         // Groups of credentials sharing a password, largest first
+	Old           []OldPassword  `json:"old"`            // Oldest first
+	ExpiringCards []ExpiringCard `json:"expiring_cards"` // Soonest first
+}
+
+// Issues returns the number of problems found.
+func (r Report) Issues() int {
+	n := len(r.Weak) + len(r.Old) + len(r.ExpiringCards)
+	for _, group := range r.Reused {
+		n += len(group)
+	}
+
+	return n
+}
+
+// Auditor loads and audits the vault of the user.
+type Auditor struct {
+	services Services
+	now      func() time.Time
+}
+
+// New creates an Auditor of the data of the services.
+func New(services Services) *Auditor {
+	return &Auditor{services: services, now: time.Now}
+}
+
+// Audit loads and decrypts all credentials and credit cards of the user and checks them against the options.
+func (a *Auditor) Audit(ctx context.Context, token string, opts Options) (Report, error) {
+	now := a.now()
+	report := Report{
+		CheckedAt:     now.UTC(),
+		Weak:          []WeakPassword{},
+		Reused:        [][]Item{},
+		Old:           []OldPassword{},
+		ExpiringCards: []ExpiringCard{},
+	}
+	page := model.PageRequest{SortBy: model.SortByCreatedAt}
+
+	infos, err := lib.LoadAllInfos(ctx, token, page, a.services.Credentials.LoadAllCredentialsDataInfo)
+	if err != nil {
+		return Report{}, fmt.Errorf("list credentials: %w", err)
+	}
+	byPassword := make(map[string][]Item)
+	var passwords []string // Passwords in the order they were found, so the report doesn't depend on map order
+	for _, info := range infos {
+		cred, err := a.services.Credentials.LoadCredentialsData(ctx, token, info.ID)
+		if err != nil {
+			return Report{}, fmt.Errorf("load credentials %s: %w", info.ID, err)
+		}
+		report.Credentials++
+		item := Item{ID: info.ID, MetaData: cred.MetaData}
+
+		if entropy := passgen.Entropy(cred.Password); entropy < opts.MinEntropy {
+			report.Weak = append(report.Weak, WeakPassword{Item: item, Entropy: round(entropy), Strength: passgen.Strength(entropy)})
+		}
+
+		if _, ok := byPassword[cred.Password]; !ok {
+			passwords = append(passwords, cred.Password)
+		}
+		byPassword[cred.Password] = append(byPassword[cred.Password], item)
+
+		// Records saved offline and not synced yet have no update time
+		if updatedAt, err := time.Parse(time.RFC3339, info.UpdatedAt); err == nil {
+			if age := int(now.Sub(updatedAt).Hours() / 24); opts.MaxAgeDays > 0 && age > opts.MaxAgeDays {
+				report.Old = append(report.Old, OldPassword{Item: item, UpdatedAt: info.UpdatedAt, AgeDays: age})
+			}
+		}
+	}
+	for _, password := range passwords {
+		if group := byPassword[password]; len(group) > 1 {
+			report.Reused = append(report.Reused, group)
+		}
+	}
+
+	infos, err = lib.LoadAllInfos(ctx, token, page, a.services.CreditCards.LoadAllCreditCardDataInfo)
+	if err != nil {
+		return Report{}, fmt.Errorf("list credit cards: %w", err)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for _, info := range infos {
+		card, err := a.services.CreditCards.LoadCreditCardData(ctx, token, info.ID)
+		if err != nil {
+			return Report{}, fmt.Errorf("load credit card %s: %w", info.ID, err)
+		}
+		report.CreditCards++
+
+		expiresAt, err := time.Parse(expiresAtLayout, card.ExpiresAt)
+		if err != nil {
+			continue
+		}
+		if daysLeft := int(expiresAt.Sub(today).Hours() / 24); daysLeft <= opts.ExpiryDays {
+			report.ExpiringCards = append(report.ExpiringCards, ExpiringCard{
+				Item: Item{ID: info.ID, MetaData: card.MetaData}, ExpiresAt: card.ExpiresAt, DaysLeft: daysLeft,
+			})
+		}
+	}
+
+	sort.SliceStable(report.Weak, func(i, j int) bool { return report.Weak[i].Entropy < report.Weak[j].Entropy })
+	sort.SliceStable(report.Reused, func(i, j int) bool { return len(report.Reused[i]) > len(report.Reused[j]) })
+	sort.SliceStable(report.Old, func(i, j int) bool { return report.Old[i].AgeDays > report.Old[j].AgeDays })
+	sort.SliceStable(report.ExpiringCards, func(i, j int) bool {
+		return report.ExpiringCards[i].DaysLeft < report.ExpiringCards[j].DaysLeft
+	})
+
+	return report, nil
+}
+
+// round rounds the entropy to one decimal for the report.
+func round(entropy float64) float64 {
+	return float64(int(entropy*10+0.5)) / 10
+}
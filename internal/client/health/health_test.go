@@ -0,0 +1,132 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+// record is a credentials or a credit card of the account with its update time.
+type record struct {
+	cred      model.Credentials
+	card      model.CreditCard
+	updatedAt string
+}
+
+// account is an in-memory account implementing the audited services.
+type account struct {
+	creds []record
+	cards []record
+	err   error
+}
+
+func infoPage(records []record) model.DataInfoPage {
+	var page model.DataInfoPage
+	for i, r := range records {
+		page.Infos = append(page.Infos, model.DataInfo{ID: fmt.Sprint(i), UpdatedAt: r.updatedAt})
+	}
+	return page
+}
+
+func index(id string) int {
+	var i int
+	_, _ = fmt.Sscan(id, &i)
+	return i
+}
+
+func (a *account) LoadAllCredentialsDataInfo(context.Context, string, model.PageRequest) (model.DataInfoPage, error) {
+	return infoPage(a.creds), a.err
+}
+
+func (a *account) LoadCredentialsData(_ context.Context, _ string, id string) (model.Credentials, error) {
+	return a.creds[index(id)].cred, nil
+}
+
+func (a *account) LoadAllCreditCardDataInfo(context.Context, string, model.PageRequest) (model.DataInfoPage, error) {
+	return infoPage(a.cards), nil
+}
+
+func (a *account) LoadCreditCardData(_ context.Context, _ string, id string) (model.CreditCard, error) {
+	return a.cards[index(id)].card, nil
+}
+
+type HealthTestSuite struct {
+	suite.Suite
+	now time.Time
+}
+
+func (s *HealthTestSuite) SetupTest() {
+	s.now = time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(HealthTestSuite))
+}
+
+func (s *HealthTestSuite) auditor(a *account) *Auditor {
+	auditor := New(Services{Credentials: a, CreditCards: a})
+	auditor.now = func() time.Time { return s.now }
+	return auditor
+}
+
+func (s *HealthTestSuite) Test_Audit() {
+	recent := s.now.AddDate(0, -1, 0).Format(time.RFC3339)
+	a := &account{
+		creds: []record{
+			{cred: model.Credentials{Password: "password123", MetaData: "mail"}, updatedAt: recent},
+			{cred: model.Credentials{Password: "password123", MetaData: "forum"}, updatedAt: recent},
+			{cred: model.Credentials{Password: "x7#Kq9!vLm2@Wz4$Rt8&", MetaData: "bank"}, updatedAt: "2022-01-10T08:00:00Z"},
+			{cred: model.Credentials{Password: "Unsynced-Pass-Phrase-42!", MetaData: "local"}},
+		},
+		cards: []record{
+			{card: model.CreditCard{ExpiresAt: "01-07-2024", MetaData: "visa"}},
+			{card: model.CreditCard{ExpiresAt: "01-01-2024", MetaData: "expired"}},
+			{card: model.CreditCard{ExpiresAt: "01-01-2030", MetaData: "mir"}},
+		},
+	}
+
+	report, err := s.auditor(a).Audit(context.Background(), "token", DefaultOptions())
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), s.now, report.CheckedAt)
+	assert.Equal(s.T(), 4, report.Credentials)
+	assert.Equal(s.T(), 3, report.CreditCards)
+
+	require.Len(s.T(), report.Weak, 2)
+	assert.Equal(s.T(), Item{ID: "0", MetaData: "mail"}, report.Weak[0].Item)
+	assert.Equal(s.T(), "weak", report.Weak[0].Strength)
+
+	assert.Equal(s.T(), [][]Item{{{ID: "0", MetaData: "mail"}, {ID: "1", MetaData: "forum"}}}, report.Reused)
+
+	require.Len(s.T(), report.Old, 1)
+	assert.Equal(s.T(), OldPassword{Item: Item{ID: "2", MetaData: "bank"}, UpdatedAt: "2022-01-10T08:00:00Z", AgeDays: 887}, report.Old[0])
+
+	assert.Equal(s.T(), []ExpiringCard{
+		{Item: Item{ID: "1", MetaData: "expired"}, ExpiresAt: "01-01-2024", DaysLeft: -166},
+		{Item: Item{ID: "0", MetaData: "visa"}, ExpiresAt: "01-07-2024", DaysLeft: 16},
+	}, report.ExpiringCards)
+
+	assert.Equal(s.T(), 7, report.Issues())
+}
+
+func (s *HealthTestSuite) Test_AuditEmpty() {
+	report, err := s.auditor(&account{}).Audit(context.Background(), "token", DefaultOptions())
+	require.NoError(s.T(), err)
+	assert.Zero(s.T(), report.Issues())
+	assert.NotNil(s.T(), report.Weak)
+	assert.NotNil(s.T(), report.Reused)
+}
+
+func (s *HealthTestSuite) Test_AuditError() {
+	errLoad := errors.New("connection refused")
+	_, err := s.auditor(&account{err: errLoad}).Audit(context.Background(), "token", DefaultOptions())
+	assert.ErrorIs(s.T(), err, errLoad)
+}
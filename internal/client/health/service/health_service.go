@@ -0,0 +1,141 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/health"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+)
+
+// HealthService defines the interface for auditing the vault.
+type HealthService interface {
+	Audit(ctx context.Context, token string, opts health.Options) (health.Report, error)
+}
+
+// HealthProvider provides the vault health report of the client.
+// It holds a reference to a HealthService and maintains the client's state.
+type HealthProvider struct {
+	healthService HealthService      // Service to audit the vault
+	state         *state.ClientState // Client's state, including authorization information
+}
+
+// NewHealthService initializes a new HealthProvider with the given HealthService
+// and ClientState. It returns a pointer to the newly created HealthProvider.
+func NewHealthService(s HealthService, state *state.ClientState) *HealthProvider {
+	return &HealthProvider{
+		healthService: s,
+		state:         state,
+	}
+}
+
+// Report prompts the user for the maximum password age and the card expiry window and prints
+// the weak, reused and old passwords and the cards that expire soon.
+func (p *HealthProvider) Report(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	yellow := color.New(color.FgYellow).SprintFunc()
+	opts := health.DefaultOptions()
+
+	fmt.Printf("Input maximum password age in days as %s, leave empty for %d: ", yellow("'365'"), opts.MaxAgeDays)
+	scanner.Scan()
+	if !readDays(scanner.Text(), &opts.MaxAgeDays) {
+		fmt.Println(red("Invalid number of days please try again"))
+		return
+	}
+
+	fmt.Printf("Input card expiry warning in days as %s, leave empty for %d: ", yellow("'30'"), opts.ExpiryDays)
+	scanner.Scan()
+	if !readDays(scanner.Text(), &opts.ExpiryDays) {
+		fmt.Println(red("Invalid number of days please try again"))
+		return
+	}
+
+	report, err := p.healthService.Audit(ctx, p.state.GetToken(), opts)
+	if err != nil {
+		logrus.WithError(err).Error("Vault audit failed")
+		fmt.Println(red("Vault audit failed, please try again"))
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	printReport(report, opts)
+}
+
+// readDays sets the days to the number of the input unless it's empty and reports whether the input is valid.
+func readDays(input string, days *int) bool {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return true
+	}
+
+	n, err := strconv.Atoi(input)
+	if err != nil || n < 0 {
+		return false
+	}
+	*days = n
+
+	return true
+}
+
+// printReport prints the sections of the report.
+func printReport(report health.Report, opts health.Options) {
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	fmt.Println(cyanBold(fmt.Sprintf("Checked %d credentials and %d credit cards", report.Credentials, report.CreditCards)))
+	if report.Issues() == 0 {
+		fmt.Println(green("No issues found"))
+		return
+	}
+
+	if len(report.Weak) != 0 {
+		fmt.Println(cyanBold(fmt.Sprintf("Weak passwords, less than %.0f bits:", opts.MinEntropy)))
+		for _, w := range report.Weak {
+			fmt.Printf("  %s %s %s\n", w.ID, w.MetaData, red(fmt.Sprintf("(%s, %.1f bits)", w.Strength, w.Entropy)))
+		}
+	}
+
+	if len(report.Reused) != 0 {
+		fmt.Println(cyanBold("Reused passwords:"))
+		for i, group := range report.Reused {
+			fmt.Printf("  group %d:\n", i+1)
+			for _, item := range group {
+				fmt.Printf("    %s %s\n", item.ID, item.MetaData)
+			}
+		}
+	}
+
+	if len(report.Old) != 0 {
+		fmt.Println(cyanBold(fmt.Sprintf("Passwords not changed for more than %d days:", opts.MaxAgeDays)))
+		for _, o := range report.Old {
+			fmt.Printf("  %s %s %s\n", o.ID, o.MetaData, yellow(fmt.Sprintf("(%d days)", o.AgeDays)))
+		}
+	}
+
+	if len(report.ExpiringCards) != 0 {
+		fmt.Println(cyanBold(fmt.Sprintf("Credit cards expiring within %d days:", opts.ExpiryDays)))
+		for _, c := range report.ExpiringCards {
+			status := yellow(fmt.Sprintf("(expires %s, %d days left)", c.ExpiresAt, c.DaysLeft))
+			if c.DaysLeft < 0 {
+				status = red(fmt.Sprintf("(expired %s)", c.ExpiresAt))
+			}
+			fmt.Printf("  %s %s %s\n", c.ID, c.MetaData, status)
+		}
+	}
+}
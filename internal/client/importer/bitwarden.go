@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrEncryptedExport is returned for an encrypted Bitwarden export, its items can't be read without the account.
+var ErrEncryptedExport = errors.New("encrypted export is not supported, export the vault as unencrypted JSON")
+
+// Types of the Bitwarden items.
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+	bitwardenSSHKey     = 5
+)
+
+// bitwardenExport is the unencrypted JSON export of a Bitwarden vault.
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []bitwardenItem `json:"items"`
+}
+
+// bitwardenItem is an item of a Bitwarden export, only the part of its type is set.
+type bitwardenItem struct {
+	Type     int    `json:"type"`
+	Name     string `json:"name"`
+	Notes    string `json:"notes"`
+	FolderID string `json:"folderId"`
+	Fields   []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"fields"`
+	Login *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		TOTP     string `json:"totp"`
+		URIs     []struct {
+			URI string `json:"uri"`
+		} `json:"uris"`
+	} `json:"login"`
+	Card *struct {
+		CardholderName string `json:"cardholderName"`
+		Number         string `json:"number"`
+		ExpMonth       string `json:"expMonth"`
+		ExpYear        string `json:"expYear"`
+		Code           string `json:"code"`
+	} `json:"card"`
+	Identity map[string]any `json:"identity"`
+	SSHKey   *struct {
+		PrivateKey     string `json:"privateKey"`
+		PublicKey      string `json:"publicKey"`
+		KeyFingerprint string `json:"keyFingerprint"`
+	} `json:"sshKey"`
+}
+
+// identityFields are the fields of a Bitwarden identity in the order they are written to the note.
+var identityFields = []string{
+	"title", "firstName", "middleName", "lastName", "company", "email", "phone",
+	"address1", "address2", "address3", "city", "state", "postalCode", "country",
+	"username", "ssn", "passportNumber", "licenseNumber",
+}
+
+// parseBitwarden reads an unencrypted Bitwarden JSON export. Logins become credentials, cards become credit cards
+// with the PIN taken from a custom field named PIN, secure notes, identities and SSH keys become text notes.
+// The folder of an item becomes a tag.
+func parseBitwarden(r io.Reader) (Result, error) {
+	var export bitwardenExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return Result{}, fmt.Errorf("decode bitwarden export: %w", err)
+	}
+	if export.Encrypted {
+		return Result{}, ErrEncryptedExport
+	}
+
+	folders := make(map[string]string, len(export.Folders))
+	for _, f := range export.Folders {
+		folders[f.ID] = f.Name
+	}
+
+	var res Result
+	for i, item := range export.Items {
+		e := entry{
+			label:  fmt.Sprintf("item %d %q", i+1, item.Name),
+			name:   item.Name,
+			folder: folders[item.FolderID],
+			notes:  item.Notes,
+		}
+		for _, f := range item.Fields {
+			// Bitwarden cards have no PIN, it is commonly kept in a custom field
+			if item.Type == bitwardenCard && strings.EqualFold(f.Name, "pin") {
+				e.pin = f.Value
+				continue
+			}
+			e.extra = append(e.extra, f.Name+": "+f.Value)
+		}
+
+		switch {
+		case item.Type == bitwardenLogin && item.Login != nil:
+			e.kind, e.login, e.pass = kindCredentials, item.Login.Username, item.Login.Password
+			if len(item.Login.URIs) != 0 {
+				e.url = item.Login.URIs[0].URI
+			}
+			for _, uri := range item.Login.URIs[min(1, len(item.Login.URIs)):] {
+				e.extra = append(e.extra, "URL: "+uri.URI)
+			}
+			e.totp = item.Login.TOTP
+		case item.Type == bitwardenCard && item.Card != nil:
+			e.kind, e.owner, e.number, e.cvv = kindCard, item.Card.CardholderName, item.Card.Number, item.Card.Code
+			if item.Card.ExpMonth != "" || item.Card.ExpYear != "" {
+				e.expires = item.Card.ExpMonth + "/" + item.Card.ExpYear
+			}
+		case item.Type == bitwardenSecureNote:
+			e.kind = kindNote
+		case item.Type == bitwardenIdentity && item.Identity != nil:
+			e.kind = kindNote
+			var lines []string
+			for _, name := range identityFields {
+				if v, ok := item.Identity[name].(string); ok && v != "" {
+					lines = append(lines, name+": "+v)
+				}
+			}
+			e.extra = append(lines, e.extra...)
+		case item.Type == bitwardenSSHKey && item.SSHKey != nil:
+			e.kind = kindNote
+			e.extra = append([]string{
+				"Fingerprint: " + item.SSHKey.KeyFingerprint,
+				"Public key: " + item.SSHKey.PublicKey,
+				strings.TrimSpace(item.SSHKey.PrivateKey),
+			}, e.extra...)
+		default:
+			res.warn(e, fmt.Sprintf("has unknown type %d, skipped", item.Type))
+			continue
+		}
+
+		res.add(e)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,196 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Fields of the entries that can be mapped to CSV columns.
+const (
+	FieldType     = "type"     // Kind of the entry: credentials, card or note, guessed from the other fields when not mapped
+	FieldName     = "name"     // Title of the entry
+	FieldFolder   = "folder"   // Folder or group of the entry, it becomes a tag
+	FieldURL      = "url"      // Address of the site
+	FieldNotes    = "notes"    // Notes, the text of a note
+	FieldLogin    = "login"    // Login of the credentials
+	FieldPassword = "password" // Password of the credentials
+	FieldTOTP     = "totp"     // Two-factor secret of the credentials
+	FieldNumber   = "number"   // Number of the card
+	FieldOwner    = "owner"    // Owner of the card
+	FieldExpires  = "expires"  // Expiry date of the card
+	FieldCVV      = "cvv"      // CVV of the card
+	FieldPin      = "pin"      // PIN of the card
+)
+
+// fields lists the fields in the order they are shown to the user.
+var fields = []string{
+	FieldType, FieldName, FieldFolder, FieldURL, FieldNotes, FieldLogin, FieldPassword, FieldTOTP,
+	FieldNumber, FieldOwner, FieldExpires, FieldCVV, FieldPin,
+}
+
+// keePassColumns are the columns of the fields in the exports of KeePass 2 and KeePassXC, the first column
+// found in the header is used.
+var keePassColumns = map[string][]string{
+	FieldName:     {"Title", "Account"},
+	FieldFolder:   {"Group"},
+	FieldURL:      {"URL", "Web Site"},
+	FieldNotes:    {"Notes", "Comments"},
+	FieldLogin:    {"Username", "User Name", "Login Name"},
+	FieldPassword: {"Password"},
+	FieldTOTP:     {"TOTP"},
+}
+
+// Mapping maps the fields of the entries to the names of the CSV columns holding them.
+type Mapping map[string]string
+
+// ParseMapping parses a mapping written as 'field=column' pairs separated by commas,
+// like 'name=Title,login=User,password=Secret'. Column names are matched case-insensitively.
+func ParseMapping(s string) (Mapping, error) {
+	mapping := make(Mapping)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected 'field=column'", strings.TrimSpace(pair))
+		}
+		if !isField(field) {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(fields, ", "))
+		}
+		mapping[field] = column
+	}
+
+	if len(mapping) == 0 {
+		return nil, errors.New("mapping is empty")
+	}
+
+	return mapping, nil
+}
+
+// isField reports whether the name is a field of the entries.
+func isField(name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseCSV reads a CSV file with a header row. The columns of the fields are taken from the mapping,
+// a nil mapping reads a KeePass export.
+func parseCSV(r io.Reader, mapping Mapping) (Result, error) {
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); string(bom) == "\ufeff" { // Byte order mark written by spreadsheets
+		_, _ = br.Discard(3)
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return Result{}, errors.New("csv file is empty")
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("read csv header: %w", err)
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := positions[name]; !ok {
+			positions[name] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	if mapping == nil {
+		for field, names := range keePassColumns {
+			for _, name := range names {
+				if i, ok := positions[strings.ToLower(name)]; ok {
+					columns[field] = i
+					break
+				}
+			}
+		}
+		if _, ok := columns[FieldPassword]; !ok {
+			return Result{}, errors.New("not a KeePass export, the csv file has no Password column")
+		}
+	} else {
+		for field, name := range mapping {
+			i, ok := positions[strings.ToLower(name)]
+			if !ok {
+				return Result{}, fmt.Errorf("column %q of field %s is not in the csv header", name, field)
+			}
+			columns[field] = i
+		}
+	}
+
+	var res Result
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("read csv: %w", err)
+		}
+
+		value := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		e := entry{
+			name:    value(FieldName),
+			folder:  value(FieldFolder),
+			url:     value(FieldURL),
+			notes:   value(FieldNotes),
+			login:   value(FieldLogin),
+			pass:    value(FieldPassword),
+			totp:    value(FieldTOTP),
+			number:  value(FieldNumber),
+			owner:   value(FieldOwner),
+			expires: value(FieldExpires),
+			cvv:     value(FieldCVV),
+			pin:     value(FieldPin),
+		}
+		e.label = fmt.Sprintf("row %d %q", row, e.name)
+		if mapping == nil {
+			// Only the innermost group is kept, the Root group of KeePassXC holds every entry
+			e.folder = e.folder[strings.LastIndex(e.folder, "/")+1:]
+			if strings.EqualFold(e.folder, "Root") {
+				e.folder = ""
+			}
+		}
+
+		switch kind := strings.ToLower(value(FieldType)); kind {
+		case "":
+			e.kind = kindAuto
+		case "credentials", "login":
+			e.kind = kindCredentials
+		case "credit_card", "card":
+			e.kind = kindCard
+		case "text_data", "note", "text":
+			e.kind = kindNote
+		default:
+			res.warn(e, fmt.Sprintf("has unknown type %q, skipped", kind))
+			continue
+		}
+
+		res.add(e)
+	}
+}
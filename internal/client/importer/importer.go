@@ -0,0 +1,272 @@
+// Package importer reads the exports of other password managers into items of the vault,
+// so they can be saved with the backup import.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/backup"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/totp"
+)
+
+// Formats of the exports.
+const (
+	FormatBitwarden = "bitwarden-json" // Unencrypted JSON export of Bitwarden
+	FormatKeePass   = "keepass-csv"    // CSV export of KeePass 2 or KeePassXC
+	FormatCSV       = "csv"            // CSV file with a header row, read with a column mapping
+)
+
+// Formats lists the supported formats.
+var Formats = []string{FormatBitwarden, FormatKeePass, FormatCSV}
+
+const expiresAtLayout = "02-01-2006" // Layout of the card expiry date accepted by the server
+
+// notTag matches the characters that can't be a part of a tag in the metadata.
+var notTag = regexp.MustCompile(`[^[:alnum:]_-]+`)
+
+// Result holds the items read from an export and the warnings about the entries
+// that were changed or skipped on the way.
+type Result struct {
+	Items    []backup.Item
+	Warnings []string
+}
+
+// Parse reads the export in the format. The mapping is used by FormatCSV only.
+func Parse(format string, r io.Reader, mapping Mapping) (Result, error) {
+	switch format {
+	case FormatBitwarden:
+		return parseBitwarden(r)
+	case FormatKeePass:
+		return parseCSV(r, nil)
+	case FormatCSV:
+		if len(mapping) == 0 {
+			return Result{}, fmt.Errorf("format %s needs a column mapping", FormatCSV)
+		}
+		return parseCSV(r, mapping)
+	default:
+		return Result{}, fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Kinds of the entries, they become the data types of the items.
+const (
+	kindAuto        = ""            // The kind is guessed from the fields that are set
+	kindCredentials = "credentials" // Login and password
+	kindCard        = "card"        // Credit card
+	kindNote        = "note"        // Text note
+)
+
+// entry is a record of an export with its fields in a common form.
+type entry struct {
+	label   string // Label of the entry in the warnings, like `row 3 "Mail"`
+	kind    string
+	name    string
+	folder  string
+	url     string
+	notes   string
+	login   string
+	pass    string
+	totp    string // Two-factor secret of the credentials, a base32 secret or an otpauth URI
+	number  string
+	owner   string
+	expires string // Expiry date of the card, see parseExpiry for the accepted layouts
+	cvv     string
+	pin     string
+	extra   []string // Other fields of the entry as 'name: value' lines, kept with the notes
+}
+
+// add converts the entry into items. Entries that fail the checks of the server are kept as text notes
+// with all their fields, so nothing is lost on import.
+func (r *Result) add(e entry) {
+	kind := e.kind
+	if kind == kindAuto {
+		switch {
+		case e.number != "":
+			kind = kindCard
+		case e.login != "" || e.pass != "":
+			kind = kindCredentials
+		default:
+			kind = kindNote
+		}
+	}
+
+	switch kind {
+	case kindCredentials:
+		if e.login == "" || e.pass == "" {
+			r.warn(e, "has no login or password, imported as a note")
+			r.addNote(e, e.describe())
+			return
+		}
+		cred := &backup.Credentials{Login: e.login, Password: e.pass}
+		if e.totp != "" {
+			if _, err := totp.ParseKey(e.totp); err != nil {
+				r.warn(e, "two-factor secret is not valid, kept with the notes")
+				e.extra = append(e.extra, "TOTP: "+e.totp)
+			} else {
+				cred.TOTP = e.totp
+			}
+		}
+		r.Items = append(r.Items, backup.Item{Type: backup.TypeCredentials, MetaData: e.metadata(), Credentials: cred})
+	case kindCard:
+		card, err := e.card()
+		if err != nil {
+			r.warn(e, err.Error()+", imported as a note")
+			r.addNote(e, e.describe())
+			return
+		}
+		r.Items = append(r.Items, backup.Item{Type: backup.TypeCreditCard, MetaData: e.metadata(), Card: card})
+	default:
+		r.addNote(e, e.describe())
+		return
+	}
+
+	// Notes are kept in a separate text note, the metadata is stored unencrypted and must not hold secrets
+	if notes := e.notesText(); notes != "" {
+		r.Items = append(r.Items, backup.Item{Type: backup.TypeText, MetaData: e.metadata() + " (notes)", Text: &backup.Text{Text: notes}})
+	}
+}
+
+// addNote adds the text as a text note of the entry, empty entries are skipped.
+func (r *Result) addNote(e entry, text string) {
+	if text == "" {
+		r.warn(e, "is empty, skipped")
+		return
+	}
+	r.Items = append(r.Items, backup.Item{Type: backup.TypeText, MetaData: e.metadata(), Text: &backup.Text{Text: text}})
+}
+
+// warn adds a warning about the entry.
+func (r *Result) warn(e entry, msg string) {
+	r.Warnings = append(r.Warnings, e.label+" "+msg)
+}
+
+// metadata returns the metadata of the items of the entry: its name, URL and folder as a tag.
+func (e entry) metadata() string {
+	parts := make([]string, 0, 3)
+	if e.name != "" {
+		parts = append(parts, e.name)
+	}
+	if e.url != "" {
+		parts = append(parts, e.url)
+	}
+	if tag := strings.Trim(notTag.ReplaceAllString(e.folder, "-"), "-"); tag != "" {
+		parts = append(parts, "#"+tag)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// notesText returns the notes of the entry followed by its other fields.
+func (e entry) notesText() string {
+	lines := make([]string, 0, len(e.extra)+1)
+	if e.notes != "" {
+		lines = append(lines, e.notes)
+	}
+	lines = append(lines, e.extra...)
+
+	return strings.Join(lines, "\n")
+}
+
+// describe returns all fields of the entry as text, the notes come last.
+func (e entry) describe() string {
+	fields := []struct{ name, value string }{
+		{"Login", e.login},
+		{"Password", e.pass},
+		{"TOTP", e.totp},
+		{"Number", e.number},
+		{"Owner", e.owner},
+		{"Expires", e.expires},
+		{"CVV", e.cvv},
+		{"PIN", e.pin},
+	}
+
+	lines := make([]string, 0, len(fields)+1)
+	for _, f := range fields {
+		if f.value != "" {
+			lines = append(lines, f.name+": "+f.value)
+		}
+	}
+	if notes := e.notesText(); notes != "" {
+		lines = append(lines, notes)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// card returns the card of the entry in the formats checked by the server.
+func (e entry) card() (*backup.Card, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, e.number)
+	if len(digits) != 16 || strings.IndexFunc(digits, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0 {
+		return nil, fmt.Errorf("card number is not 16 digits")
+	}
+
+	owner := strings.Join(strings.Fields(e.owner), " ")
+	if len(strings.Fields(owner)) != 2 {
+		return nil, fmt.Errorf("card owner is not a first and last name")
+	}
+
+	expires, err := parseExpiry(e.expires)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isCode(e.cvv, 3) {
+		return nil, fmt.Errorf("card has no 3 digit CVV")
+	}
+	if !isCode(e.pin, 4) {
+		return nil, fmt.Errorf("card has no 4 digit PIN")
+	}
+
+	return &backup.Card{
+		Number:    digits[:4] + " " + digits[4:8] + " " + digits[8:12] + " " + digits[12:],
+		OwnerName: owner,
+		ExpiresAt: expires,
+		CVV:       e.cvv,
+		PinCode:   e.pin,
+	}, nil
+}
+
+// parseExpiry parses the expiry date of a card as MM/YY, MM/YYYY, MM-YYYY, YYYY-MM or DD-MM-YYYY.
+// A date without a day becomes the last day of the month, the card is valid until its end.
+func parseExpiry(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("card has no expiry date")
+	}
+
+	if t, err := time.Parse(expiresAtLayout, s); err == nil {
+		return t.Format(expiresAtLayout), nil
+	}
+
+	for _, layout := range []string{"1/06", "1/2006", "1-2006", "2006-1", "1-06"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.AddDate(0, 1, -1).Format(expiresAtLayout), nil
+		}
+	}
+
+	return "", fmt.Errorf("card expiry date %q is not recognized", s)
+}
+
+// isCode reports whether s is a code of n digits.
+func isCode(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/backup"
+)
+
+const bitwardenJSON = `{
+  "encrypted": false,
+  "folders": [{"id": "f1", "name": "Work Mail"}],
+  "items": [
+    {"type": 1, "name": "Mail", "folderId": "f1", "notes": "shared with the team",
+     "login": {"username": "admin", "password": "secret", "totp": "JBSWY3DPEHPK3PXP",
+               "uris": [{"uri": "https://mail.example.com"}, {"uri": "https://mail2.example.com"}]}},
+    {"type": 1, "name": "Router", "login": {"username": "admin", "password": ""}},
+    {"type": 2, "name": "Wi-Fi", "notes": "guest / qwerty", "secureNote": {"type": 0}},
+    {"type": 3, "name": "Salary", "fields": [{"name": "PIN", "value": "1234", "type": 1}],
+     "card": {"cardholderName": "IVAN  IVANOV", "number": "4111-1111-1111-1111", "expMonth": "2", "expYear": "2028", "code": "123"}},
+    {"type": 3, "name": "Travel", "card": {"cardholderName": "IVAN IVANOV", "number": "5500000000000004", "expMonth": "12", "expYear": "2027", "code": "321"}},
+    {"type": 9, "name": "Unknown"}
+  ]
+}`
+
+type ImporterTestSuite struct {
+	suite.Suite
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(ImporterTestSuite))
+}
+
+func (s *ImporterTestSuite) Test_Bitwarden() {
+	res, err := Parse(FormatBitwarden, strings.NewReader(bitwardenJSON), nil)
+	require.NoError(s.T(), err)
+
+	require.Len(s.T(), res.Items, 6)
+	assert.Equal(s.T(), backup.Item{Type: backup.TypeCredentials, MetaData: "Mail https://mail.example.com #Work-Mail",
+		Credentials: &backup.Credentials{Login: "admin", Password: "secret", TOTP: "JBSWY3DPEHPK3PXP"}}, res.Items[0])
+	assert.Equal(s.T(), backup.Item{Type: backup.TypeText, MetaData: "Mail https://mail.example.com #Work-Mail (notes)",
+		Text: &backup.Text{Text: "shared with the team\nURL: https://mail2.example.com"}}, res.Items[1])
+
+	// Credentials without a password are kept as a note
+	assert.Equal(s.T(), backup.Item{Type: backup.TypeText, MetaData: "Router", Text: &backup.Text{Text: "Login: admin"}}, res.Items[2])
+	assert.Equal(s.T(), backup.Item{Type: backup.TypeText, MetaData: "Wi-Fi", Text: &backup.Text{Text: "guest / qwerty"}}, res.Items[3])
+
+	assert.Equal(s.T(), backup.Item{Type: backup.TypeCreditCard, MetaData: "Salary", Card: &backup.Card{
+		Number: "4111 1111 1111 1111", OwnerName: "IVAN IVANOV", ExpiresAt: "29-02-2028", CVV: "123", PinCode: "1234",
+	}}, res.Items[4])
+
+	// A card without a PIN fails the checks of the server
+	assert.Equal(s.T(), backup.TypeText, res.Items[5].Type)
+	assert.Contains(s.T(), res.Items[5].Text.Text, "Number: 5500000000000004")
+
+	assert.Equal(s.T(), []string{
+		`item 2 "Router" has no login or password, imported as a note`,
+		`item 5 "Travel" card has no 4 digit PIN, imported as a note`,
+		`item 6 "Unknown" has unknown type 9, skipped`,
+	}, res.Warnings)
+
+	_, err = Parse(FormatBitwarden, strings.NewReader(`{"encrypted": true, "items": []}`), nil)
+	assert.ErrorIs(s.T(), err, ErrEncryptedExport)
+}
+
+func (s *ImporterTestSuite) Test_KeePass() {
+	export := "\ufeff\"Group\",\"Title\",\"Username\",\"Password\",\"URL\",\"Notes\",\"TOTP\"\n" +
+		"\"Root\",\"Mail\",\"admin\",\"secret\",\"https://mail.example.com\",\"\",\"otpauth://totp/Mail?secret=JBSWY3DPEHPK3PXP\"\n" +
+		"\"Root/Servers\",\"DB\",\"postgres\",\"p,a\"\"ss\",\"\",\"line 1\nline 2\",\"otpauth://totp/db\"\n" +
+		"\"Root\",\"\",\"\",\"\",\"\",\"\",\"\"\n"
+
+	res, err := Parse(FormatKeePass, strings.NewReader(export), nil)
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), []backup.Item{
+		{Type: backup.TypeCredentials, MetaData: "Mail https://mail.example.com", Credentials: &backup.Credentials{
+			Login: "admin", Password: "secret", TOTP: "otpauth://totp/Mail?secret=JBSWY3DPEHPK3PXP",
+		}},
+		{Type: backup.TypeCredentials, MetaData: "DB #Servers", Credentials: &backup.Credentials{Login: "postgres", Password: `p,a"ss`}},
+		{Type: backup.TypeText, MetaData: "DB #Servers (notes)", Text: &backup.Text{Text: "line 1\nline 2\nTOTP: otpauth://totp/db"}},
+	}, res.Items)
+	// A secret without the secret parameter can't generate codes
+	assert.Equal(s.T(), []string{`row 3 "DB" two-factor secret is not valid, kept with the notes`, `row 4 "" is empty, skipped`}, res.Warnings)
+
+	// KeePass 2 names the columns differently
+	res, err = Parse(FormatKeePass, strings.NewReader("Account,Login Name,Password,Web Site,Comments\nMail,admin,secret,,\n"), nil)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []backup.Item{
+		{Type: backup.TypeCredentials, MetaData: "Mail", Credentials: &backup.Credentials{Login: "admin", Password: "secret"}},
+	}, res.Items)
+
+	_, err = Parse(FormatKeePass, strings.NewReader("name,value\n"), nil)
+	assert.Error(s.T(), err)
+}
+
+func (s *ImporterTestSuite) Test_CSVMapping() {
+	mapping, err := ParseMapping("type=Kind, name=Title,login=User,password=Secret,notes=Body,number=Card,owner=Holder,expires=Exp,cvv=CVC,pin=PIN")
+	require.NoError(s.T(), err)
+
+	export := "Kind,Title,User,Secret,Body,Card,Holder,Exp,CVC,PIN\n" +
+		"login,VPN,ivan,hunter2,,,,,,\n" +
+		"card,Salary,,,,4111111111111111,Ivan Ivanov,12/27,123,0000\n" +
+		"note,Recovery,,,word1 word2,,,,,\n" +
+		",Guess,ivan,pass,,,,,,\n" +
+		"identity,Passport,,,,,,,,\n"
+
+	res, err := Parse(FormatCSV, strings.NewReader(export), mapping)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []backup.Item{
+		{Type: backup.TypeCredentials, MetaData: "VPN", Credentials: &backup.Credentials{Login: "ivan", Password: "hunter2"}},
+		{Type: backup.TypeCreditCard, MetaData: "Salary", Card: &backup.Card{
+			Number: "4111 1111 1111 1111", OwnerName: "Ivan Ivanov", ExpiresAt: "31-12-2027", CVV: "123", PinCode: "0000",
+		}},
+		{Type: backup.TypeText, MetaData: "Recovery", Text: &backup.Text{Text: "word1 word2"}},
+		{Type: backup.TypeCredentials, MetaData: "Guess", Credentials: &backup.Credentials{Login: "ivan", Password: "pass"}},
+	}, res.Items)
+	assert.Equal(s.T(), []string{`row 6 "Passport" has unknown type "identity", skipped`}, res.Warnings)
+
+	_, err = Parse(FormatCSV, strings.NewReader(export), Mapping{FieldLogin: "Missing"})
+	assert.ErrorContains(s.T(), err, `column "Missing"`)
+
+	_, err = Parse(FormatCSV, strings.NewReader(export), nil)
+	assert.Error(s.T(), err)
+
+	_, err = ParseMapping("login")
+	assert.Error(s.T(), err)
+	_, err = ParseMapping("email=Mail")
+	assert.Error(s.T(), err)
+}
+
+func (s *ImporterTestSuite) Test_ParseExpiry() {
+	for input, expected := range map[string]string{
+		"12/27":      "31-12-2027",
+		"2/2028":     "29-02-2028",
+		"04-2030":    "30-04-2030",
+		"2030-04":    "30-04-2030",
+		"15-06-2029": "15-06-2029",
+	} {
+		actual, err := parseExpiry(input)
+		require.NoError(s.T(), err, input)
+		assert.Equal(s.T(), expected, actual, input)
+	}
+
+	_, err := parseExpiry("soon")
+	assert.Error(s.T(), err)
+	_, err = parseExpiry("")
+	assert.Error(s.T(), err)
+}
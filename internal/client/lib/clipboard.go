@@ -0,0 +1,61 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// CopyCommand starts the answer choosing to copy a field to the clipboard, e.g. 'copy password'.
+	CopyCommand = "copy "
+	// secretMask is shown instead of secrets, independent of their length.
+	secretMask = "********"
+)
+
+// Clipboard copies secrets to the system clipboard and clears them after a timeout.
+type Clipboard interface {
+	Copy(secret string) error
+	Timeout() time.Duration
+}
+
+// CopyField copies the value of the field named by the user to the clipboard and tells when it is cleared.
+func CopyField(clip Clipboard, fields map[string]string, name string) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	value, ok := fields[name]
+	if !ok {
+		names := make([]string, 0, len(fields))
+		for n := range fields {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		fmt.Println(red(fmt.Sprintf("Unknown field %q, please use one of: %s", name, strings.Join(names, ", "))))
+		return
+	}
+
+	if err := clip.Copy(value); err != nil {
+		fmt.Println(red("Failed to copy to clipboard: ", err))
+		return
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	if clip.Timeout() > 0 {
+		fmt.Println(green(fmt.Sprintf("Field %s copied to clipboard, it is cleared in %s", name, clip.Timeout())))
+		return
+	}
+	fmt.Println(green(fmt.Sprintf("Field %s copied to clipboard", name)))
+}
+
+// Mask hides a secret shown on the screen, empty values stay empty.
+func Mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return secretMask
+}
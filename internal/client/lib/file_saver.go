@@ -88,3 +88,37 @@ func LoadFromFile(path string) ([]byte, error) {
 
 	return data, nil
 }
+
+// OpenFile opens the file at the specified path for reading.
+// The caller is responsible for closing the file.
+func OpenFile(path string) (*os.File, error) {
+	path = filepath.FromSlash(path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %s %w", path, err)
+	}
+
+	return file, nil
+}
+
+// CreateFile creates or truncates the file at the specified path for writing.
+// If the directory does not exist, it attempts to create it. The caller is responsible for closing the file.
+func CreateFile(path string) (*os.File, error) {
+	path = filepath.FromSlash(path)
+
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		err = os.Mkdir(dir, perm)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create directory: %s %w", dir, err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create file: %s %w", path, err)
+	}
+
+	return file, nil
+}
@@ -0,0 +1,34 @@
+package lib
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/totp"
+)
+
+// OneTimeCode returns the current code of the two-factor secret of credentials and the time left until it changes.
+// The secret is a raw base32 secret or an otpauth://totp URI.
+func OneTimeCode(secret string, t time.Time) (string, time.Duration, error) {
+	key, err := totp.ParseKey(secret)
+	if err != nil {
+		return "", 0, fmt.Errorf("parse two-factor secret: %w", err)
+	}
+
+	return key.Generate(t)
+}
+
+// FormatOneTimeCode returns the current code of the two-factor secret with the seconds left, like '492039 (17s left)'.
+// Empty secrets give an empty string and invalid ones the error.
+func FormatOneTimeCode(secret string, t time.Time) string {
+	if secret == "" {
+		return ""
+	}
+
+	code, remaining, err := OneTimeCode(secret, t)
+	if err != nil {
+		return err.Error()
+	}
+
+	return fmt.Sprintf("%s (%ds left)", code, int(remaining.Round(time.Second)/time.Second))
+}
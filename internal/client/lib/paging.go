@@ -0,0 +1,65 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	"github.com/fatih/color"
+)
+
+const (
+	pageSize        = 20  // Number of records shown at once in data listings
+	loadAllPageSize = 100 // Number of records loaded at once when the whole list is needed
+)
+
+// ReadPageRequest prompts the user for the sort order of a data listing and returns the request of its first page.
+// An empty answer keeps the default order, the oldest records first.
+func ReadPageRequest(scanner *bufio.Scanner) model.PageRequest {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	page := model.PageRequest{PageSize: pageSize, SortBy: model.SortByCreatedAt}
+
+	fmt.Printf("Sort by %s or %s, leave empty for created_at: ", yellow("'created_at'"), yellow("'metadata'"))
+	scanner.Scan()
+	if strings.TrimSpace(scanner.Text()) == model.SortByMetadata {
+		page.SortBy = model.SortByMetadata
+	}
+
+	fmt.Printf("Sort descending? %s: ", yellow("'y/n'"))
+	scanner.Scan()
+	page.Descending = strings.ToLower(strings.TrimSpace(scanner.Text())) == "y"
+
+	return page
+}
+
+// ConfirmNextPage asks the user whether to show the next page of a data listing.
+func ConfirmNextPage(scanner *bufio.Scanner) bool {
+	fmt.Printf("Show next %d records? %s: ", pageSize, color.New(color.FgYellow).SprintFunc()("'y/n'"))
+	scanner.Scan()
+
+	return strings.ToLower(strings.TrimSpace(scanner.Text())) == "y"
+}
+
+// LoadAllInfos loads the infos of all records of one data type page by page in the order of the page request.
+func LoadAllInfos(ctx context.Context, token string, page model.PageRequest,
+	load func(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)) ([]model.DataInfo, error) {
+	if page.PageSize == 0 {
+		page.PageSize = loadAllPageSize
+	}
+
+	var infos []model.DataInfo
+	for {
+		resp, err := load(ctx, token, page)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, resp.Infos...)
+
+		if resp.NextPageToken == "" {
+			return infos, nil
+		}
+		page.PageToken = resp.NextPageToken
+	}
+}
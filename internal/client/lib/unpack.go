@@ -29,3 +29,23 @@ func UnpackGRPCError(err error) {
 		fmt.Printf("Please try again: %s\n", red(st.Message()))
 	}
 }
+
+// FieldViolations returns the descriptions of the invalid fields of an InvalidArgument gRPC error by the field names,
+// nil for other errors.
+func FieldViolations(err error) map[string]string {
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		return nil
+	}
+
+	violations := make(map[string]string)
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range br.GetFieldViolations() {
+				violations[violation.GetField()] = violation.GetDescription()
+			}
+		}
+	}
+
+	return violations
+}
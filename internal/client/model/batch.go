@@ -0,0 +1,9 @@
+package model
+
+// BatchResult is the result of saving an item of a batch. A saved item has its ID and revision set,
+// an item failing the validation is not saved and has the violations of its fields set by the path of the field.
+type BatchResult struct {
+	ID         string
+	Revision   int64
+	Violations map[string]string
+}
@@ -7,7 +7,22 @@ type BinaryDataPostRequest struct {
 	MetaData  string
 }
 
+type BinaryDataPutRequest struct {
+	ID        string
+	Name      string
+	Extension string
+	Data      []byte
+	MetaData  string
+}
+
+type BinaryDataStreamRequest struct {
+	Name      string
+	Extension string
+	MetaData  string
+}
+
 type BinaryData struct {
+	ID        string
 	Name      string
 	Extension string
 	Data      []byte
@@ -17,3 +32,10 @@ type BinaryData struct {
 type BinaryDataLoadRequest struct {
 	ID string
 }
+
+type BinaryCryptData struct {
+	Name      string
+	Extension string
+	Data      []byte
+	ChunkKey  []byte `json:",omitempty"`
+}
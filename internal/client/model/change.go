@@ -0,0 +1,11 @@
+package model
+
+type Change struct {
+	ID        string
+	DataType  string
+	MetaData  string
+	Revision  int64
+	CreatedAt string
+	UpdatedAt string
+	Deleted   bool
+}
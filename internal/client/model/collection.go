@@ -0,0 +1,58 @@
+package model
+
+// Roles of the members of a shared collection, each role includes the permissions of the previous one.
+const (
+	RoleRead   = "read"   // Load the items and the members
+	RoleWrite  = "write"  // Save, update and delete the items
+	RoleManage = "manage" // Invite and revoke members
+)
+
+// Collection is a shared collection as seen by one of its members.
+type Collection struct {
+	ID        string
+	Name      string
+	Role      string // Role of the user in the collection
+	Status    string // invited until the user accepts the invitation, active afterwards
+	CreatedAt string
+}
+
+// CollectionMember is a member of a shared collection.
+type CollectionMember struct {
+	UserID    string
+	Login     string
+	Role      string
+	Status    string
+	InvitedBy string
+	CreatedAt string
+}
+
+// CollectionItemPostRequest holds an item saved into a collection. Data is the JSON of the crypt data
+// of the data type, such as CredentialsCryptData.
+type CollectionItemPostRequest struct {
+	CollectionID string
+	DataType     string
+	Data         []byte
+	MetaData     string
+}
+
+// CollectionItemPutRequest holds the new content of an item of a collection.
+type CollectionItemPutRequest struct {
+	ID           string
+	CollectionID string
+	Data         []byte
+	MetaData     string
+	Revision     int64
+}
+
+// CollectionItem is an item of a collection, Data is empty when the items are listed.
+type CollectionItem struct {
+	ID           string
+	CollectionID string
+	DataType     string
+	Data         []byte
+	MetaData     string
+	CreatedBy    string
+	CreatedAt    string
+	UpdatedAt    string
+	Revision     int64
+}
@@ -3,17 +3,37 @@ package model
 type CredentialsPostRequest struct {
 	Login    string
 	Password string
+	TOTP     string
 	MetaData string
 }
 
+type CredentialsPutRequest struct {
+	ID       string
+	Login    string
+	Password string
+	TOTP     string
+	MetaData string
+	Revision int64
+}
+
 type Credentials struct {
+	ID       string
 	Login    string
 	Password string
+	TOTP     string
 	MetaData string
+	Revision int64
 }
 
 type CredentialsLoadRequest struct {
 	Login    string
 	Password string
+	TOTP     string
 	MetaData string
 }
+
+type CredentialsCryptData struct {
+	Login    string
+	Password string
+	TOTP     string // Raw base32 secret or otpauth URI of the two-factor codes of the account
+}
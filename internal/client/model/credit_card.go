@@ -7,6 +7,21 @@ type CreditCardPostRequest struct {
 	CVV       string
 	PinCode   string
 	MetaData  string
+
+	ExpiryReminder bool
+}
+
+type CreditCardPutRequest struct {
+	ID        string
+	Number    string
+	OwnerName string
+	ExpiresAt string
+	CVV       string
+	PinCode   string
+	MetaData  string
+	Revision  int64
+
+	ExpiryReminder bool
 }
 
 type CreditCardLoadRequest struct {
@@ -20,10 +35,22 @@ type CreditCardLoadRequest struct {
 }
 
 type CreditCard struct {
+	ID        string
 	Number    string
 	OwnerName string
 	ExpiresAt string
 	CVV       string
 	PinCode   string
 	MetaData  string
+	Revision  int64
+
+	ExpiryReminder bool
+}
+
+type CreditCardCryptData struct {
+	Number    string
+	OwnerName string
+	ExpiresAt string
+	CVV       string
+	PinCode   string
 }
@@ -0,0 +1,59 @@
+package model
+
+// Types of the fields of item templates.
+const (
+	FieldTypeText   = "text"   // Any text
+	FieldTypeNumber = "number" // Integer or decimal number
+	FieldTypeURL    = "url"    // Absolute URL with a scheme and a host
+	FieldTypeEmail  = "email"  // Email address
+	FieldTypeDate   = "date"   // Date as DD-MM-YYYY
+)
+
+// TemplateField describes a field of the items of a template.
+type TemplateField struct {
+	Name     string
+	Type     string
+	Required bool
+	Secret   bool // Secret values are masked until revealed
+}
+
+// Template is a named schema of the fields of custom data items.
+type Template struct {
+	ID        string
+	Name      string
+	Fields    []TemplateField
+	CreatedAt string
+}
+
+type TemplateRequest struct {
+	ID     string
+	Name   string
+	Fields []TemplateField
+}
+
+type CustomDataPostRequest struct {
+	TemplateID string
+	Fields     map[string]string
+	MetaData   string
+}
+
+type CustomDataPutRequest struct {
+	ID         string
+	TemplateID string
+	Fields     map[string]string
+	MetaData   string
+	Revision   int64
+}
+
+type CustomData struct {
+	ID         string
+	TemplateID string
+	Fields     map[string]string
+	MetaData   string
+	Revision   int64
+}
+
+type CustomCryptData struct {
+	TemplateID string
+	Fields     map[string]string
+}
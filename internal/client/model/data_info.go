@@ -1,8 +1,31 @@
 package model
 
 type DataInfo struct {
-	ID        string `json:"id"`
-	DataType  string `json:"data_type"`
-	MetaData  string `json:"meta_data"`
-	CreatedAt string `json:"created_at"`
+	ID        string   `json:"id"`
+	DataType  string   `json:"data_type"`
+	MetaData  string   `json:"meta_data"`
+	CreatedAt string   `json:"created_at"`
+	Revision  int64    `json:"revision"`
+	UpdatedAt string   `json:"updated_at"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// Sort fields of data listings.
+const (
+	SortByCreatedAt = "created_at"
+	SortByMetadata  = "metadata"
+)
+
+// PageRequest describes a page of a data listing. An empty page token requests the first page.
+type PageRequest struct {
+	PageSize   int
+	PageToken  string
+	SortBy     string
+	Descending bool
+}
+
+// DataInfoPage is a page of a data listing.
+type DataInfoPage struct {
+	Infos         []DataInfo
+	NextPageToken string // Token of the next page, empty on the last page
 }
@@ -0,0 +1,15 @@
+package model
+
+// SearchRequest describes which items are searched for. Empty fields don't filter,
+// times are in RFC 3339 format.
+type SearchRequest struct {
+	Query       string
+	Types       []string
+	Tags        []string
+	CreatedFrom string
+	CreatedTo   string
+	UpdatedFrom string
+	UpdatedTo   string
+	Limit       int
+	Offset      int
+}
@@ -5,12 +5,25 @@ type TextDataPostRequest struct {
 	MetaData string
 }
 
+type TextDataPutRequest struct {
+	ID       string
+	Text     string
+	MetaData string
+	Revision int64
+}
+
 type TextDataLoadRequest struct {
 	Text     string
 	MetaData string
 }
 
 type TextData struct {
+	ID       string
 	Text     string
 	MetaData string
+	Revision int64
+}
+
+type TextCryptData struct {
+	Text string
 }
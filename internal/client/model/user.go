@@ -0,0 +1,26 @@
+package model
+
+type UserRegisterRequest struct {
+	Login                string
+	Password             string
+	ClientSideEncryption bool
+	WrappedDataKey       []byte
+}
+
+type UserLoginResponse struct {
+	Token          string
+	RefreshToken   string
+	WrappedDataKey []byte
+}
+
+type TokenPair struct {
+	Token        string
+	RefreshToken string
+}
+
+// TOTPEnrollment holds what is needed to set up an authenticator app for two-factor authentication.
+type TOTPEnrollment struct {
+	URI           string
+	Secret        string
+	RecoveryCodes []string
+}
@@ -0,0 +1,62 @@
+package pbclient
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/search"
+)
+
+// SearchPBClient is a client wrapper around the gRPC SearchServiceClient,
+// providing methods to search the items of the user via gRPC.
+type SearchPBClient struct {
+	searchService pb.SearchServiceClient
+}
+
+// NewSearchPBClient initializes and returns a new instance of SearchPBClient
+// which will use the provided gRPC SearchServiceClient.
+func NewSearchPBClient(s pb.SearchServiceClient) *SearchPBClient {
+	return &SearchPBClient{searchService: s}
+}
+
+// SearchItems retrieves a page of the items matching the request across all data types.
+// It returns the items and whether there are more items after this page.
+func (s *SearchPBClient) SearchItems(ctx context.Context, token string, search model.SearchRequest) ([]model.DataInfo, bool, error) {
+	req := &pb.SearchItemsRequest{
+		Query:       search.Query,
+		Types:       search.Types,
+		Tags:        search.Tags,
+		CreatedFrom: search.CreatedFrom,
+		CreatedTo:   search.CreatedTo,
+		UpdatedFrom: search.UpdatedFrom,
+		UpdatedTo:   search.UpdatedTo,
+		Limit:       int32(search.Limit),
+		Offset:      int32(search.Offset),
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := s.searchService.SearchItems(ctx, req)
+	if err != nil {
+		return nil, false, fmt.Errorf("search items: %w", err)
+	}
+
+	items := make([]model.DataInfo, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		items = append(items, model.DataInfo{
+			ID:        item.Id,
+			DataType:  item.DataType,
+			MetaData:  item.Metadata,
+			CreatedAt: item.CreatedAt,
+			Revision:  item.Revision,
+			UpdatedAt: item.UpdatedAt,
+			Tags:      item.Tags,
+		})
+	}
+
+	return items, resp.HasMore, nil
+}
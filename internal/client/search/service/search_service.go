@@ -0,0 +1,190 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	pageSize   = 20           // Number of items shown at once
+	dateLayout = "2006-01-02" // Layout of dates typed by the user
+)
+
+// SearchService defines the interface for searching items across all data types.
+type SearchService interface {
+	SearchItems(ctx context.Context, token string, search model.SearchRequest) ([]model.DataInfo, bool, error)
+}
+
+// SearchProvider provides the search command of the client.
+// It holds a reference to a SearchService and maintains the client's state.
+type SearchProvider struct {
+	searchService SearchService      // Service to handle search operations
+	state         *state.ClientState // Client's state, including authorization information
+}
+
+// NewSearchService initializes a new SearchProvider with the given SearchService
+// and ClientState. It returns a pointer to the newly created SearchProvider.
+func NewSearchService(s SearchService, state *state.ClientState) *SearchProvider {
+	return &SearchProvider{
+		searchService: s,
+		state:         state,
+	}
+}
+
+// Search prompts the user for the search filters and prints the found items page by page.
+func (p *SearchProvider) Search(ctx context.Context) {
+	scanner := bufio.NewScanner(os.Stdin)
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	if p.state.IsOffline() {
+		fmt.Println(red("You are working offline, please use 'login' again when the server is reachable"))
+		return
+	}
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input search filters, leave a filter empty to skip it:"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	var req model.SearchRequest
+
+	fmt.Printf("Input metadata text as %s: ", yellow("'text'"))
+	scanner.Scan()
+	req.Query = strings.TrimSpace(scanner.Text())
+
+	fmt.Printf("Input types as %s: ", yellow("'credit_card,text_data,credentials,binary_data,custom_data'"))
+	scanner.Scan()
+	req.Types = splitList(scanner.Text())
+
+	fmt.Printf("Input tags as %s: ", yellow("'#tag1,#tag2'"))
+	scanner.Scan()
+	req.Tags = splitList(scanner.Text())
+
+	var err error
+	fmt.Printf("Input created dates as %s: ", yellow("'YYYY-MM-DD..YYYY-MM-DD'"))
+	scanner.Scan()
+	req.CreatedFrom, req.CreatedTo, err = parseDateRange(scanner.Text())
+	if err != nil {
+		fmt.Println(red("Invalid dates please try again: ", err))
+		return
+	}
+
+	fmt.Printf("Input updated dates as %s: ", yellow("'YYYY-MM-DD..YYYY-MM-DD'"))
+	scanner.Scan()
+	req.UpdatedFrom, req.UpdatedTo, err = parseDateRange(scanner.Text())
+	if err != nil {
+		fmt.Println(red("Invalid dates please try again: ", err))
+		return
+	}
+
+	req.Limit = pageSize
+	for {
+		items, hasMore, err := p.searchService.SearchItems(ctx, p.state.GetToken(), req)
+		if err != nil {
+			logrus.WithError(err).Error("Search failed")
+			fmt.Println(red("Search failed"), "please try again")
+			lib.UnpackGRPCError(err)
+			return
+		}
+
+		printItems(items, req.Offset == 0)
+		if !hasMore {
+			return
+		}
+
+		fmt.Printf("Show next %d items? %s: ", pageSize, yellow("'y/n'"))
+		scanner.Scan()
+		if strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+			return
+		}
+		req.Offset += len(items)
+	}
+}
+
+// printItems prints a page of found items.
+func printItems(items []model.DataInfo, first bool) {
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	if len(items) == 0 {
+		if first {
+			fmt.Println(yellow("Nothing found"))
+		}
+		return
+	}
+
+	fmt.Println(green("-------------------------------------"))
+
+	var sb strings.Builder
+	for _, item := range items {
+		sb.WriteString("Data ID: " + item.ID + "\n")
+		sb.WriteString("Data type: " + item.DataType + "\n")
+		sb.WriteString("Metadata : " + item.MetaData + "\n")
+		if len(item.Tags) != 0 {
+			sb.WriteString("Tags: #" + strings.Join(item.Tags, " #") + "\n")
+		}
+		sb.WriteString("Created at: " + item.CreatedAt + "\n")
+		sb.WriteString("Updated at: " + item.UpdatedAt + "\n")
+		sb.WriteString(green("-------------------------------------") + "\n")
+	}
+	fmt.Println(sb.String())
+}
+
+// splitList splits a comma separated list typed by the user, dropping empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// parseDateRange parses a range of dates 'from..to' where either side may be omitted, a single date
+// means that day. Both dates are inclusive. It returns the bounds as RFC 3339 times in UTC,
+// the upper bound is the start of the day after the last date.
+func parseDateRange(s string) (string, string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", "", nil
+	}
+
+	fromStr, toStr, isRange := strings.Cut(s, "..")
+	if !isRange {
+		toStr = fromStr
+	}
+
+	var from, to string
+	if fromStr = strings.TrimSpace(fromStr); fromStr != "" {
+		t, err := time.Parse(dateLayout, fromStr)
+		if err != nil {
+			return "", "", fmt.Errorf("parse %s: %w", fromStr, err)
+		}
+		from = t.Format(time.RFC3339)
+	}
+
+	if toStr = strings.TrimSpace(toStr); toStr != "" {
+		t, err := time.Parse(dateLayout, toStr)
+		if err != nil {
+			return "", "", fmt.Errorf("parse %s: %w", toStr, err)
+		}
+		to = t.AddDate(0, 0, 1).Format(time.RFC3339)
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,172 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/encryption"
+)
+
+const (
+	fileVersion = 2     // Version of the session file format
+	keysVersion = 1     // Version of the session file format that also held the unwrapped keys, they are dropped on read
+	filePerm    = 0o600 // Permission for the session file, readable by the owner only
+	dirPerm     = 0o700 // Permission for the directory of the session file
+	saltSize    = 16    // Size of the salt of the passphrase key
+)
+
+var (
+	// ErrWrongPassphrase is returned when the session file can't be decrypted with the passphrase.
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	// ErrLocked is returned when the session is saved before a passphrase is set.
+	ErrLocked = errors.New("session file is locked, set a passphrase first")
+)
+
+// Saved is the session of the user restored on the next start of the client.
+// It holds no keys: the data key and the vault key are derived from the master password again on restore,
+// so a stolen session file together with the passphrase doesn't reveal the end-to-end encrypted data.
+// WrappedDataKey is the data key wrapped with the key derived from the master password, as the server keeps it.
+type Saved struct {
+	Login          string `json:"login"`
+	Token          string `json:"token"`
+	RefreshToken   string `json:"refresh_token"`
+	DirPath        string `json:"dir_path,omitempty"`
+	WrappedDataKey []byte `json:"wrapped_data_key,omitempty"`
+}
+
+// envelope is the content of the session file, the session is encrypted with the passphrase key.
+type envelope struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Data    []byte `json:"data"`
+}
+
+// File keeps the session of the user between runs of the client in a file encrypted with a key derived from
+// a local passphrase. The key is kept in memory once the passphrase is given, so the session can be saved again
+// whenever it changes.
+type File struct {
+	path string // Path to the session file, empty if sessions are not saved
+	salt []byte // Salt of the passphrase key
+	key  []byte // Key derived from the passphrase, nil until the passphrase is given
+}
+
+// NewFile creates a new File at the given path. An empty path disables saving sessions.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Enabled reports whether sessions are saved.
+func (f *File) Enabled() bool {
+	return f.path != ""
+}
+
+// Exists reports whether a saved session exists.
+func (f *File) Exists() bool {
+	if !f.Enabled() {
+		return false
+	}
+	_, err := os.Stat(f.path)
+	return err == nil
+}
+
+// Unlocked reports whether the passphrase is given and the session can be saved.
+func (f *File) Unlocked() bool {
+	return f.key != nil
+}
+
+// Lock sets a new passphrase of the session file, the session is encrypted with it on the next save.
+func (f *File) Lock(passphrase string) error {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("rand.Read: %w", err)
+	}
+
+	f.salt, f.key = salt, encryption.DerivePassphraseKey(passphrase, salt)
+	return nil
+}
+
+// Unlock decrypts the saved session with the passphrase and keeps the key to save the session again.
+// The keys saved by the previous format are ignored and removed from the file on the next save.
+func (f *File) Unlock(passphrase string) (Saved, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return Saved{}, fmt.Errorf("read session file: %w", err)
+	}
+
+	var env envelope
+	if err = json.Unmarshal(data, &env); err != nil {
+		return Saved{}, fmt.Errorf("unmarshal session file: %w", err)
+	}
+	if env.Version != fileVersion && env.Version != keysVersion {
+		return Saved{}, fmt.Errorf("unsupported session file version %d", env.Version)
+	}
+
+	key := encryption.DerivePassphraseKey(passphrase, env.Salt)
+	dec, err := encryption.Decrypt(key, env.Data)
+	if err != nil {
+		return Saved{}, ErrWrongPassphrase
+	}
+
+	var saved Saved
+	if err = json.Unmarshal(dec, &saved); err != nil {
+		return Saved{}, fmt.Errorf("unmarshal session: %w", err)
+	}
+
+	f.salt, f.key = env.Salt, key
+	return saved, nil
+}
+
+// Save encrypts the session and atomically replaces the session file.
+func (f *File) Save(saved Saved) error {
+	if !f.Unlocked() {
+		return ErrLocked
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("marshal session: %w", err)
+	}
+
+	cryptData, err := encryption.Encrypt(f.key, data)
+	if err != nil {
+		return fmt.Errorf("encrypt session: %w", err)
+	}
+
+	data, err = json.Marshal(envelope{Version: fileVersion, Salt: f.salt, Data: cryptData})
+	if err != nil {
+		return fmt.Errorf("marshal session file: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(f.path), dirPerm); err != nil {
+		return fmt.Errorf("create session directory: %w", err)
+	}
+
+	tmp := f.path + ".tmp"
+	if err = os.WriteFile(tmp, data, filePerm); err != nil {
+		return fmt.Errorf("write session file: %w", err)
+	}
+
+	if err = os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("replace session file: %w", err)
+	}
+
+	return nil
+}
+
+// Remove deletes the saved session and forgets the passphrase.
+func (f *File) Remove() error {
+	f.salt, f.key = nil, nil
+	if !f.Enabled() {
+		return nil
+	}
+
+	if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove session file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/encryption"
+)
+
+type FileTestSuite struct {
+	suite.Suite
+	path  string
+	saved Saved
+}
+
+func TestFileSuite(t *testing.T) {
+	suite.Run(t, new(FileTestSuite))
+}
+
+func (f *FileTestSuite) SetupTest() {
+	f.path = filepath.Join(f.T().TempDir(), "keeper", "session.keeper")
+	f.saved = Saved{
+		Login:          "user@example.com",
+		Token:          "token",
+		RefreshToken:   "session.secret",
+		DirPath:        "./downloads",
+		WrappedDataKey: []byte("wrapped data key"),
+	}
+}
+
+func (f *FileTestSuite) Test_SaveAndUnlock() {
+	file := NewFile(f.path)
+	assert.False(f.T(), file.Exists())
+	assert.ErrorIs(f.T(), file.Save(f.saved), ErrLocked)
+
+	require.NoError(f.T(), file.Lock("passphrase"))
+	require.NoError(f.T(), file.Save(f.saved))
+
+	info, err := os.Stat(f.path)
+	require.NoError(f.T(), err)
+	assert.Equal(f.T(), os.FileMode(0o600), info.Mode().Perm())
+
+	data, err := os.ReadFile(f.path)
+	require.NoError(f.T(), err)
+	assert.NotContains(f.T(), string(data), "session.secret")
+
+	restored := NewFile(f.path)
+	_, err = restored.Unlock("wrong")
+	assert.ErrorIs(f.T(), err, ErrWrongPassphrase)
+	assert.False(f.T(), restored.Unlocked())
+
+	saved, err := restored.Unlock("passphrase")
+	require.NoError(f.T(), err)
+	assert.Equal(f.T(), f.saved, saved)
+
+	// The unlocked file is saved again with the same passphrase
+	f.saved.RefreshToken = "session.rotated"
+	require.NoError(f.T(), restored.Save(f.saved))
+	saved, err = NewFile(f.path).Unlock("passphrase")
+	require.NoError(f.T(), err)
+	assert.Equal(f.T(), "session.rotated", saved.RefreshToken)
+}
+
+// Test_DropsSavedKeys reads a session file of the previous format with the unwrapped keys,
+// the keys must not be written back.
+func (f *FileTestSuite) Test_DropsSavedKeys() {
+	salt := make([]byte, saltSize)
+	_, err := rand.Read(salt)
+	require.NoError(f.T(), err)
+	data, err := json.Marshal(map[string]any{
+		"login":         f.saved.Login,
+		"token":         f.saved.Token,
+		"refresh_token": f.saved.RefreshToken,
+		"data_key":      []byte("data key"),
+		"vault_key":     []byte("vault key"),
+	})
+	require.NoError(f.T(), err)
+	cryptData, err := encryption.Encrypt(encryption.DerivePassphraseKey("passphrase", salt), data)
+	require.NoError(f.T(), err)
+	data, err = json.Marshal(envelope{Version: keysVersion, Salt: salt, Data: cryptData})
+	require.NoError(f.T(), err)
+	require.NoError(f.T(), os.MkdirAll(filepath.Dir(f.path), dirPerm))
+	require.NoError(f.T(), os.WriteFile(f.path, data, filePerm))
+
+	file := NewFile(f.path)
+	saved, err := file.Unlock("passphrase")
+	require.NoError(f.T(), err)
+	assert.Equal(f.T(), f.saved.RefreshToken, saved.RefreshToken)
+	require.NoError(f.T(), file.Save(saved))
+
+	data, err = os.ReadFile(f.path)
+	require.NoError(f.T(), err)
+	var env envelope
+	require.NoError(f.T(), json.Unmarshal(data, &env))
+	assert.Equal(f.T(), fileVersion, env.Version)
+	dec, err := encryption.Decrypt(file.key, env.Data)
+	require.NoError(f.T(), err)
+	assert.NotContains(f.T(), string(dec), "data_key")
+	assert.NotContains(f.T(), string(dec), "vault_key")
+}
+
+func (f *FileTestSuite) Test_Remove() {
+	file := NewFile(f.path)
+	require.NoError(f.T(), file.Lock("passphrase"))
+	require.NoError(f.T(), file.Save(f.saved))
+
+	require.NoError(f.T(), file.Remove())
+	assert.False(f.T(), file.Exists())
+	assert.False(f.T(), file.Unlocked())
+	assert.NoError(f.T(), file.Remove())
+}
+
+func (f *FileTestSuite) Test_Disabled() {
+	file := NewFile("")
+	assert.False(f.T(), file.Enabled())
+	assert.False(f.T(), file.Exists())
+	assert.NoError(f.T(), file.Remove())
+}
@@ -0,0 +1,131 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/user"
+)
+
+const (
+	tokenKey      = "token"                               // Metadata key of the access token
+	refreshMethod = "/proto.UserService/PostRefreshToken" // Method exchanging the refresh token
+	refreshMargin = time.Minute                           // Access tokens expiring sooner are refreshed before a call
+)
+
+// Refresher is a gRPC client interceptor keeping the access token fresh. Before a call with an access token
+// expiring soon it exchanges the refresh token for new tokens and sends the call with the new access token.
+type Refresher struct {
+	mu    sync.Mutex
+	state *state.ClientState // Client state holding the tokens
+	hooks Hooks              // Listeners of the session changes
+}
+
+// Hooks are called by the Refresher when the session changes. Both hooks are optional.
+type Hooks struct {
+	Refreshed func() // The tokens in the client state were exchanged for new ones
+	Expired   func() // The refresh token was rejected, the user has to log in again
+}
+
+// NewRefresher creates a new Refresher updating the tokens in the client state.
+func NewRefresher(state *state.ClientState) *Refresher {
+	return &Refresher{state: state}
+}
+
+// SetHooks sets the hooks called when the session changes.
+func (r *Refresher) SetHooks(hooks Hooks) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = hooks
+}
+
+// Unary refreshes the access token of a unary call.
+func (r *Refresher) Unary(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, err := r.refresh(ctx, method, cc)
+	if err != nil {
+		return err
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// Stream refreshes the access token of a streaming call.
+func (r *Refresher) Stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, err := r.refresh(ctx, method, cc)
+	if err != nil {
+		return nil, err
+	}
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// refresh returns the context of the call with a fresh access token in its metadata.
+// Calls without an access token and calls made while the refresh token is unknown are left as they are.
+func (r *Refresher) refresh(ctx context.Context, method string, cc grpc.ClientConnInterface) (context.Context, error) {
+	if method == refreshMethod {
+		return ctx, nil
+	}
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+
+	tokens := md.Get(tokenKey)
+	if len(tokens) == 0 || !expiresSoon(tokens[0]) {
+		return ctx, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The token may have been refreshed by another call while this one was waiting
+	token := r.state.GetToken()
+	if expiresSoon(token) {
+		refreshToken := r.state.GetRefreshToken()
+		if refreshToken == "" {
+			return ctx, nil
+		}
+
+		resp, err := pb.NewUserServiceClient(cc).PostRefreshToken(ctx, &pb.PostRefreshTokenRequest{RefreshToken: refreshToken})
+		if err != nil {
+			if status.Code(err) == codes.Unauthenticated && r.hooks.Expired != nil {
+				r.hooks.Expired()
+			}
+			return nil, fmt.Errorf("refresh token: %w", err)
+		}
+
+		r.state.SetToken(resp.Token)
+		r.state.SetRefreshToken(resp.RefreshToken)
+		token = resp.Token
+		if r.hooks.Refreshed != nil {
+			r.hooks.Refreshed()
+		}
+	}
+
+	md = md.Copy()
+	md.Set(tokenKey, token)
+	return metadata.NewOutgoingContext(ctx, md), nil
+}
+
+// expiresSoon reports whether the access token expires within the refresh margin.
+// The signature is not verified, the client only reads the expiration time of its own token.
+func expiresSoon(token string) bool {
+	if token == "" {
+		return false
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil || claims.ExpiresAt == nil {
+		return false
+	}
+
+	return time.Until(claims.ExpiresAt.Time) < refreshMargin
+}
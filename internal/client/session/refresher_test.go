@@ -0,0 +1,136 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/user"
+)
+
+type conn struct {
+	grpc.ClientConnInterface
+	refreshed []string
+	token     string
+	err       error
+}
+
+func (c *conn) Invoke(_ context.Context, _ string, args, reply any, _ ...grpc.CallOption) error {
+	c.refreshed = append(c.refreshed, args.(*pb.PostRefreshTokenRequest).RefreshToken)
+	if c.err != nil {
+		return c.err
+	}
+	resp := reply.(*pb.PostRefreshTokenResponse)
+	resp.Token, resp.RefreshToken = c.token, "new-refresh"
+	return nil
+}
+
+type RefresherTestSuite struct {
+	suite.Suite
+	state     *state.ClientState
+	refresher *Refresher
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(RefresherTestSuite))
+}
+
+func (r *RefresherTestSuite) SetupTest() {
+	r.state = state.NewClientState()
+	r.refresher = NewRefresher(r.state)
+}
+
+func (r *RefresherTestSuite) token(exp time.Duration) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(exp)),
+	}).SignedString([]byte("secret"))
+	require.NoError(r.T(), err)
+	return token
+}
+
+func (r *RefresherTestSuite) Test_RefreshExpiringToken() {
+	expiring, fresh := r.token(10*time.Second), r.token(time.Hour)
+	r.state.SetToken(expiring)
+	r.state.SetRefreshToken("refresh")
+
+	c := &conn{token: fresh}
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(tokenKey, expiring))
+	ctx, err := r.refresher.refresh(ctx, "/proto.TextDataService/GetLoadTextData", c)
+	require.NoError(r.T(), err)
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	assert.Equal(r.T(), []string{fresh}, md.Get(tokenKey))
+	assert.Equal(r.T(), []string{"refresh"}, c.refreshed)
+	assert.Equal(r.T(), fresh, r.state.GetToken())
+	assert.Equal(r.T(), "new-refresh", r.state.GetRefreshToken())
+
+	// A call made with the old token while it was refreshed gets the new token without another refresh
+	ctx = metadata.NewOutgoingContext(context.Background(), metadata.Pairs(tokenKey, expiring))
+	ctx, err = r.refresher.refresh(ctx, "/proto.TextDataService/GetLoadTextData", c)
+	require.NoError(r.T(), err)
+	md, _ = metadata.FromOutgoingContext(ctx)
+	assert.Equal(r.T(), []string{fresh}, md.Get(tokenKey))
+	assert.Len(r.T(), c.refreshed, 1)
+}
+
+func (r *RefresherTestSuite) Test_KeepFreshToken() {
+	fresh := r.token(time.Hour)
+	r.state.SetToken(fresh)
+	r.state.SetRefreshToken("refresh")
+
+	c := &conn{}
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(tokenKey, fresh))
+	_, err := r.refresher.refresh(ctx, "/proto.TextDataService/GetLoadTextData", c)
+	assert.NoError(r.T(), err)
+
+	_, err = r.refresher.refresh(context.Background(), "/proto.UserService/PostLoginUser", c)
+	assert.NoError(r.T(), err)
+	assert.Empty(r.T(), c.refreshed)
+}
+
+func (r *RefresherTestSuite) Test_Hooks() {
+	var refreshed, expired int
+	r.refresher.SetHooks(Hooks{
+		Refreshed: func() { refreshed++ },
+		Expired:   func() { expired++ },
+	})
+
+	expiring := r.token(10 * time.Second)
+	r.state.SetToken(expiring)
+	r.state.SetRefreshToken("refresh")
+
+	c := &conn{token: r.token(time.Hour)}
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(tokenKey, expiring))
+	_, err := r.refresher.refresh(ctx, "/proto.TextDataService/GetLoadTextData", c)
+	require.NoError(r.T(), err)
+	assert.Equal(r.T(), 1, refreshed)
+
+	r.state.SetToken(expiring)
+	c.err = status.Error(codes.Unavailable, "connection refused")
+	_, err = r.refresher.refresh(ctx, "/proto.TextDataService/GetLoadTextData", c)
+	require.Error(r.T(), err)
+	assert.Equal(r.T(), 0, expired)
+
+	c.err = status.Error(codes.Unauthenticated, "invalid refresh token")
+	_, err = r.refresher.refresh(ctx, "/proto.TextDataService/GetLoadTextData", c)
+	require.Error(r.T(), err)
+	assert.Equal(r.T(), 1, expired)
+	assert.Equal(r.T(), 1, refreshed)
+}
+
+func (r *RefresherTestSuite) Test_ExpiresSoon() {
+	assert.False(r.T(), expiresSoon(""))
+	assert.False(r.T(), expiresSoon("not a token"))
+	assert.False(r.T(), expiresSoon(r.token(time.Hour)))
+	assert.True(r.T(), expiresSoon(r.token(-time.Hour)))
+}
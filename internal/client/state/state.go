@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 
 	"github.com/fatih/color"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/vault"
 )
 
 const (
@@ -16,10 +18,14 @@ const (
 // ClientState holds the state information of the client,
 // including authorization status, token, login, and working directory.
 type ClientState struct {
-	token        string // Token for authorized access
-	isAuthorized bool   // Flag to indicate if the user is authorized
-	login        string // User login
-	dirPath      string // Path to the working directory
+	token        string       // Token for authorized access
+	refreshToken string       // Token for renewing the access token
+	isAuthorized bool         // Flag to indicate if the user is authorized
+	login        string       // User login
+	dirPath      string       // Path to the working directory
+	dataKey      []byte       // Unwrapped data key for client side encryption
+	vault        *vault.Vault // Local encrypted vault of the user
+	offline      bool         // Flag to indicate that the server is unreachable and the vault is used
 }
 
 // NewClientState creates and returns a new instance of ClientState.
@@ -47,6 +53,16 @@ func (c *ClientState) SetToken(token string) {
 	c.token = token
 }
 
+// GetRefreshToken retrieves the current refresh token of the client.
+func (c *ClientState) GetRefreshToken() string {
+	return c.refreshToken
+}
+
+// SetRefreshToken sets the refresh token for the client.
+func (c *ClientState) SetRefreshToken(token string) {
+	c.refreshToken = token
+}
+
 // GetLogin retrieves the current login of the client.
 func (c *ClientState) GetLogin() string {
 	return c.login
@@ -57,6 +73,36 @@ func (c *ClientState) SetLogin(login string) {
 	c.login = login
 }
 
+// GetDataKey retrieves the unwrapped data key of the client.
+func (c *ClientState) GetDataKey() []byte {
+	return c.dataKey
+}
+
+// SetDataKey sets the unwrapped data key of the client.
+func (c *ClientState) SetDataKey(key []byte) {
+	c.dataKey = key
+}
+
+// GetVault retrieves the local vault of the client, nil if it is not opened.
+func (c *ClientState) GetVault() *vault.Vault {
+	return c.vault
+}
+
+// SetVault sets the local vault of the client.
+func (c *ClientState) SetVault(v *vault.Vault) {
+	c.vault = v
+}
+
+// IsOffline returns whether the client works with the local vault only.
+func (c *ClientState) IsOffline() bool {
+	return c.offline
+}
+
+// SetOffline sets the offline status of the client.
+func (c *ClientState) SetOffline(offline bool) {
+	c.offline = offline
+}
+
 // SetWorkingDirectory prompts the user to enter a path for the working directory.
 // It creates the directory if it doesn't exist.
 func (c *ClientState) SetWorkingDirectory() {
@@ -90,6 +136,11 @@ func (c *ClientState) SetWorkingDirectory() {
 	fmt.Println("Working directory set to:", path)
 }
 
+// SetDirPath sets the path of the working directory without prompting, e.g. when a saved session is restored.
+func (c *ClientState) SetDirPath(path string) {
+	c.dirPath = path
+}
+
 // GetDirPath retrieves the current working directory path.
 func (c *ClientState) GetDirPath() string {
 	return c.dirPath
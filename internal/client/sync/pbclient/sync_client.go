@@ -0,0 +1,55 @@
+package pbclient
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/sync"
+)
+
+// SyncPBClient is a client wrapper around the gRPC SyncServiceClient,
+// providing methods to read the change feed of the user via gRPC.
+type SyncPBClient struct {
+	syncService pb.SyncServiceClient
+}
+
+// NewSyncPBClient initializes and returns a new instance of SyncPBClient
+// which will use the provided gRPC SyncServiceClient.
+func NewSyncPBClient(s pb.SyncServiceClient) *SyncPBClient {
+	return &SyncPBClient{syncService: s}
+}
+
+// LoadChangesSince retrieves up to limit data changes made after the cursor.
+// It returns the changes, the cursor to continue from and whether there are more changes to load.
+func (s *SyncPBClient) LoadChangesSince(ctx context.Context, token string, cursor int64, limit int) ([]model.Change, int64, bool, error) {
+	req := &pb.GetChangesSinceRequest{
+		Cursor: cursor,
+		Limit:  int32(limit),
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := s.syncService.GetChangesSince(ctx, req)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("load changes: %w", err)
+	}
+
+	changes := make([]model.Change, 0, len(resp.Changes))
+	for _, c := range resp.Changes {
+		changes = append(changes, model.Change{
+			ID:        c.Id,
+			DataType:  c.DataType,
+			MetaData:  c.Metadata,
+			Revision:  c.Revision,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+			Deleted:   c.Deleted,
+		})
+	}
+
+	return changes, resp.Cursor, resp.HasMore, nil
+}
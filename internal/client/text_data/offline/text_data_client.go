@@ -0,0 +1,277 @@
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/vault"
+)
+
+const textData = "text_data" // Data type of text data
+
+// TextDataService defines methods of the online text data client.
+type TextDataService interface {
+	SaveTextData(ctx context.Context, token string, text model.TextDataPostRequest) (model.TextData, error)
+	SaveTextDataBatch(ctx context.Context, token string, items []model.TextDataPostRequest, atomic bool) ([]model.BatchResult, error)
+	LoadTextData(ctx context.Context, token string, dataID string) (model.TextData, error)
+	LoadAllTextDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateTextData(ctx context.Context, token string, text model.TextDataPutRequest) (model.TextData, error)
+	DeleteTextData(ctx context.Context, token string, dataID string) error
+}
+
+// TextDataClient mirrors text data into the local vault. When the server is unreachable
+// it serves reads from the vault and queues writes for sync. Updates conflicting with a change
+// made on another device are resolved by the conflict policy.
+type TextDataClient struct {
+	online TextDataService
+	state  *state.ClientState
+	policy vault.ConflictPolicy
+}
+
+// NewTextDataClient creates a new instance of TextDataClient wrapping the online client.
+func NewTextDataClient(online TextDataService, state *state.ClientState, policy vault.ConflictPolicy) *TextDataClient {
+	return &TextDataClient{
+		online: online,
+		state:  state,
+		policy: policy,
+	}
+}
+
+// SaveTextData saves new text data on the server and in the vault,
+// or only in the vault with a queued save when offline.
+func (c *TextDataClient) SaveTextData(ctx context.Context, token string, text model.TextDataPostRequest) (model.TextData, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		saved, err := c.online.SaveTextData(ctx, token, text)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return saved, err
+		}
+		if err == nil {
+			return saved, v.PutPayload(textData, saved.ID, saved.MetaData, saved.Revision, saved)
+		}
+	}
+
+	return c.saveLocal(v, text)
+}
+
+// SaveTextDataBatch saves new text data on the server in one call and mirrors the saved ones into the vault,
+// or saves every item only in the vault with a queued save when offline.
+func (c *TextDataClient) SaveTextDataBatch(ctx context.Context, token string, items []model.TextDataPostRequest, atomic bool) ([]model.BatchResult, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		results, err := c.online.SaveTextDataBatch(ctx, token, items, atomic)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return results, err
+		}
+		if err == nil {
+			for i, result := range results {
+				if result.ID == "" {
+					continue
+				}
+				saved := model.TextData{
+					ID:       result.ID,
+					Text:     items[i].Text,
+					MetaData: items[i].MetaData,
+					Revision: result.Revision,
+				}
+				if err = v.PutPayload(textData, saved.ID, saved.MetaData, saved.Revision, saved); err != nil {
+					return results, err
+				}
+			}
+			return results, nil
+		}
+	}
+
+	results := make([]model.BatchResult, 0, len(items))
+	for _, item := range items {
+		saved, err := c.saveLocal(v, item)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, model.BatchResult{ID: saved.ID})
+	}
+
+	return results, nil
+}
+
+// saveLocal saves new text data only in the vault and queues the save for sync.
+func (c *TextDataClient) saveLocal(v *vault.Vault, text model.TextDataPostRequest) (model.TextData, error) {
+	saved := model.TextData{
+		ID:       vault.NewLocalID(),
+		Text:     text.Text,
+		MetaData: text.MetaData,
+	}
+	if err := v.PutPayload(textData, saved.ID, saved.MetaData, 0, saved); err != nil {
+		return model.TextData{}, err
+	}
+
+	return saved, v.EnqueuePayload(vault.OpSave, textData, saved.ID, text)
+}
+
+// LoadTextData loads text data from the server and stores it in the vault,
+// or from the vault when offline.
+func (c *TextDataClient) LoadTextData(ctx context.Context, token string, dataID string) (model.TextData, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		text, err := c.online.LoadTextData(ctx, token, dataID)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return text, err
+		}
+		if err == nil {
+			return text, v.PutPayload(textData, dataID, text.MetaData, text.Revision, text)
+		}
+	}
+
+	var text model.TextData
+	if err := v.Payload(textData, dataID, &text); err != nil {
+		return model.TextData{}, err
+	}
+	text.ID = dataID
+
+	return text, nil
+}
+
+// LoadAllTextDataInfo loads a page of the info of text data from the server and merges it into the vault,
+// or from the vault when offline. The vault drops the records missing on the server
+// only when the whole list fits in one page, otherwise deletions are picked up from the change feed.
+func (c *TextDataClient) LoadAllTextDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error) {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		infos, err := c.online.LoadAllTextDataInfo(ctx, token, page)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return infos, err
+		}
+		if err == nil && page.PageToken == "" && infos.NextPageToken == "" {
+			return infos, v.MergeInfos(textData, infos.Infos)
+		}
+		if err == nil {
+			return infos, v.UpdateInfos(textData, infos.Infos)
+		}
+	}
+
+	return v.InfoPage(textData, page)
+}
+
+// UpdateTextData updates text data on the server and in the vault,
+// or only in the vault with a queued update when offline.
+// The update is made against the revision of the text data stored in the vault.
+func (c *TextDataClient) UpdateTextData(ctx context.Context, token string, text model.TextDataPutRequest) (model.TextData, error) {
+	v := c.state.GetVault()
+	if v != nil && text.Revision == 0 {
+		text.Revision = v.Revision(textData, text.ID)
+	}
+
+	if !c.state.IsOffline() {
+		updated, err := c.update(ctx, token, text)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return updated, err
+		}
+		if err == nil {
+			return updated, v.PutPayload(textData, updated.ID, updated.MetaData, updated.Revision, updated)
+		}
+	}
+
+	updated := model.TextData{
+		ID:       text.ID,
+		Text:     text.Text,
+		MetaData: text.MetaData,
+		Revision: text.Revision,
+	}
+	if err := v.PutPayload(textData, text.ID, text.MetaData, 0, updated); err != nil {
+		return model.TextData{}, err
+	}
+
+	return updated, v.EnqueuePayload(vault.OpUpdate, textData, text.ID, text)
+}
+
+// DeleteTextData deletes text data on the server and in the vault,
+// or only in the vault with a queued delete when offline.
+func (c *TextDataClient) DeleteTextData(ctx context.Context, token string, dataID string) error {
+	v := c.state.GetVault()
+	if !c.state.IsOffline() {
+		err := c.online.DeleteTextData(ctx, token, dataID)
+		if v == nil || (err != nil && !vault.IsOffline(err)) {
+			return err
+		}
+		if err == nil {
+			return v.Delete(textData, dataID)
+		}
+	}
+
+	if err := v.Delete(textData, dataID); err != nil {
+		return err
+	}
+
+	return v.EnqueuePayload(vault.OpDelete, textData, dataID, nil)
+}
+
+// Replay replays a queued text data write on the server.
+func (c *TextDataClient) Replay(ctx context.Context, token string, op vault.Operation) (string, error) {
+	switch op.Kind {
+	case vault.OpSave:
+		var text model.TextDataPostRequest
+		if err := json.Unmarshal(op.Payload, &text); err != nil {
+			return "", fmt.Errorf("unmarshal text data: %w", err)
+		}
+		saved, err := c.online.SaveTextData(ctx, token, text)
+		return saved.ID, err
+	case vault.OpUpdate:
+		var text model.TextDataPutRequest
+		if err := json.Unmarshal(op.Payload, &text); err != nil {
+			return "", fmt.Errorf("unmarshal text data: %w", err)
+		}
+		text.ID = op.RecordID
+		updated, err := c.update(ctx, token, text)
+		if err != nil {
+			return op.RecordID, err
+		}
+		return op.RecordID, c.state.GetVault().PutPayload(textData, updated.ID, updated.MetaData, updated.Revision, updated)
+	case vault.OpDelete:
+		return op.RecordID, c.online.DeleteTextData(ctx, token, op.RecordID)
+	default:
+		return "", fmt.Errorf("unknown operation %s", op.Kind)
+	}
+}
+
+// Fetch loads text data from the server into the vault.
+func (c *TextDataClient) Fetch(ctx context.Context, token string, id string) error {
+	v := c.state.GetVault()
+	if v == nil {
+		return nil
+	}
+
+	text, err := c.online.LoadTextData(ctx, token, id)
+	if err != nil {
+		return err
+	}
+
+	return v.PutPayload(textData, id, text.MetaData, text.Revision, text)
+}
+
+// update updates text data on the server. An update rejected because the text data was changed
+// on another device is resolved by the conflict policy: the local version either overwrites the server one
+// or is saved as new text data, which is returned, while the vault gets the server version.
+func (c *TextDataClient) update(ctx context.Context, token string, text model.TextDataPutRequest) (model.TextData, error) {
+	updated, err := c.online.UpdateTextData(ctx, token, text)
+	if !vault.IsConflict(err) {
+		return updated, err
+	}
+
+	if c.policy == vault.LastWriterWins {
+		text.Revision = 0
+		return c.online.UpdateTextData(ctx, token, text)
+	}
+
+	saved, err := c.online.SaveTextData(ctx, token, model.TextDataPostRequest{
+		Text:     text.Text,
+		MetaData: text.MetaData + vault.ConflictCopySuffix,
+	})
+	if err != nil {
+		return model.TextData{}, err
+	}
+
+	return saved, c.Fetch(ctx, token, text.ID)
+}
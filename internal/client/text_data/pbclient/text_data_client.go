@@ -5,20 +5,34 @@ import (
 	"fmt"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
 	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/text_data"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/metadata"
 )
 
+// dataType is the type of the text data, client side encrypted payloads are bound to it along with their id.
+const dataType = "text_data"
+
 // TextDataPBClient is a client for interacting with the text data gRPC service.
 // It provides methods to save and load text data.
 type TextDataPBClient struct {
 	textDataService pb.TextDataServiceClient
+	cipher          Cipher
+}
+
+// Cipher defines methods for client side encryption of the text payload.
+type Cipher interface {
+	Enabled() bool
+	Seal(id, dataType string, v any) ([]byte, error)
+	Open(id, dataType string, data []byte, v any) error
 }
 
-// NewTextDataPBClient initializes a new TextDataPBClient with the provided gRPC service client.
+// NewTextDataPBClient initializes a new TextDataPBClient with the provided gRPC service client
+// and cipher for client side encryption.
 // It returns a pointer to the TextDataPBClient instance.
-func NewTextDataPBClient(u pb.TextDataServiceClient) *TextDataPBClient {
+func NewTextDataPBClient(u pb.TextDataServiceClient, cipher Cipher) *TextDataPBClient {
 	return &TextDataPBClient{
 		textDataService: u,
+		cipher:          cipher,
 	}
 }
 
@@ -31,6 +45,15 @@ func (u *TextDataPBClient) SaveTextData(ctx context.Context, token string, text
 		Metadata: text.MetaData,
 	}
 
+	if u.cipher.Enabled() {
+		id := uuid.NewString()
+		cryptData, err := u.cipher.Seal(id, dataType, model.TextCryptData{Text: text.Text})
+		if err != nil {
+			return model.TextData{}, fmt.Errorf("seal text data: %w", err)
+		}
+		req = &pb.PostTextDataRequest{Id: id, Metadata: text.MetaData, CryptData: cryptData}
+	}
+
 	md := metadata.New(map[string]string{"token": token})
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
@@ -40,25 +63,80 @@ func (u *TextDataPBClient) SaveTextData(ctx context.Context, token string, text
 	}
 
 	txt := model.TextData{
+		ID:       resp.Id,
 		Text:     resp.Text,
 		MetaData: resp.Metadata,
+		Revision: resp.Revision,
+	}
+	if len(req.CryptData) != 0 {
+		txt.Text = text.Text
 	}
 
 	return txt, nil
 }
 
-// LoadAllTextDataInfo retrieves information about all text data stored in the service.
-// It takes a context and a token for authorization.
-// It returns a slice of DataInfo models and any error encountered.
-func (u *TextDataPBClient) LoadAllTextDataInfo(ctx context.Context, token string) ([]model.DataInfo, error) {
-	req := &pb.GetAllTextInfoRequest{}
+// SaveTextDataBatch saves many text data at once using the text data service. The results are in the order
+// of the requests. Items failing the validation of the server are not saved and have the violations of their fields set,
+// an atomic batch is rejected as a whole with an InvalidArgument error instead.
+func (u *TextDataPBClient) SaveTextDataBatch(ctx context.Context, token string, items []model.TextDataPostRequest, atomic bool) ([]model.BatchResult, error) {
+	req := &pb.PostTextDataBatchRequest{Texts: make([]*pb.PostTextDataRequest, 0, len(items)), Atomic: atomic}
+	for _, item := range items {
+		in := &pb.PostTextDataRequest{
+			Text:     item.Text,
+			Metadata: item.MetaData,
+		}
+
+		if u.cipher.Enabled() {
+			id := uuid.NewString()
+			cryptData, err := u.cipher.Seal(id, dataType, model.TextCryptData{Text: item.Text})
+			if err != nil {
+				return nil, fmt.Errorf("seal text data: %w", err)
+			}
+			in = &pb.PostTextDataRequest{Id: id, Metadata: item.MetaData, CryptData: cryptData}
+		}
+		req.Texts = append(req.Texts, in)
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.textDataService.PostSaveTextDataBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]model.BatchResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		result := model.BatchResult{ID: r.GetSaved().GetId(), Revision: r.GetSaved().GetRevision()}
+		for _, violation := range r.Violations {
+			if result.Violations == nil {
+				result.Violations = make(map[string]string)
+			}
+			result.Violations[violation.Field] = violation.Description
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// LoadAllTextDataInfo retrieves a page of information about the text data stored in the service.
+// It takes a context, a token for authorization and the requested page.
+// It returns a page of DataInfo models and any error encountered.
+func (u *TextDataPBClient) LoadAllTextDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error) {
+	req := &pb.GetAllTextInfoRequest{
+		PageSize:   int32(page.PageSize),
+		PageToken:  page.PageToken,
+		SortBy:     page.SortBy,
+		Descending: page.Descending,
+	}
 
 	md := metadata.New(map[string]string{"token": token})
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
 	resp, err := u.textDataService.GetLoadAllTextDataInfo(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("load text data: %w", err)
+		return model.DataInfoPage{}, fmt.Errorf("load text data: %w", err)
 	}
 
 	textInfos := make([]model.DataInfo, 0, len(resp.Text))
@@ -67,11 +145,13 @@ func (u *TextDataPBClient) LoadAllTextDataInfo(ctx context.Context, token string
 			ID:        data.Id,
 			DataType:  data.DataType,
 			MetaData:  data.Metadata,
+			Revision:  data.Revision,
+			UpdatedAt: data.UpdatedAt,
 			CreatedAt: data.CreatedAt,
 		})
 	}
 
-	return textInfos, nil
+	return model.DataInfoPage{Infos: textInfos, NextPageToken: resp.NextPageToken}, nil
 }
 
 // LoadTextData retrieves a specific text data entry by its ID.
@@ -91,9 +171,77 @@ func (u *TextDataPBClient) LoadTextData(ctx context.Context, token string, dataI
 	}
 	data := resp.TextData
 	binaryData := model.TextData{
+		ID:       data.Id,
 		Text:     data.Text,
 		MetaData: data.Metadata,
+		Revision: data.Revision,
+	}
+
+	if len(data.CryptData) != 0 {
+		var cryptData model.TextCryptData
+		if err = u.cipher.Open(data.Id, dataType, data.CryptData, &cryptData); err != nil {
+			return model.TextData{}, fmt.Errorf("open text data: %w", err)
+		}
+		binaryData.Text = cryptData.Text
 	}
 
 	return binaryData, nil
 }
+
+// UpdateTextData replaces an existing text data entry using the text data service.
+// It takes a context, a token for authorization, and the text data with its ID.
+// It returns the updated text data and any error encountered during the process.
+func (u *TextDataPBClient) UpdateTextData(ctx context.Context, token string, text model.TextDataPutRequest) (model.TextData, error) {
+	req := &pb.PutTextDataRequest{
+		Id:       text.ID,
+		Revision: text.Revision,
+		Text:     text.Text,
+		Metadata: text.MetaData,
+	}
+
+	if u.cipher.Enabled() {
+		cryptData, err := u.cipher.Seal(text.ID, dataType, model.TextCryptData{Text: text.Text})
+		if err != nil {
+			return model.TextData{}, fmt.Errorf("seal text data: %w", err)
+		}
+		req = &pb.PutTextDataRequest{Id: text.ID, Revision: text.Revision, Metadata: text.MetaData, CryptData: cryptData}
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.textDataService.PutUpdateTextData(ctx, req)
+	if err != nil {
+		return model.TextData{}, err
+	}
+
+	txt := model.TextData{
+		ID:       resp.Id,
+		Text:     resp.Text,
+		MetaData: resp.Metadata,
+		Revision: resp.Revision,
+	}
+	if len(req.CryptData) != 0 {
+		txt.Text = text.Text
+	}
+
+	return txt, nil
+}
+
+// DeleteTextData deletes a specific text data entry by its ID.
+// It takes a context, a token for authorization, and the ID of the data to delete.
+func (u *TextDataPBClient) DeleteTextData(ctx context.Context, token string, dataID string) error {
+	req := &pb.DeleteTextDataRequest{
+		Id: dataID,
+	}
+
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	_, err := u.textDataService.DeleteTextData(ctx, req)
+	if err != nil {
+		return fmt.Errorf("delete text data: %w", err)
+	}
+
+	return nil
+}
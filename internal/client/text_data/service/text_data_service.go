@@ -18,7 +18,9 @@ import (
 type TextDataService interface {
 	SaveTextData(ctx context.Context, token string, text model.TextDataPostRequest) (model.TextData, error)
 	LoadTextData(ctx context.Context, token string, dataID string) (model.TextData, error)
-	LoadAllTextDataInfo(ctx context.Context, token string) ([]model.DataInfo, error)
+	LoadAllTextDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateTextData(ctx context.Context, token string, text model.TextDataPutRequest) (model.TextData, error)
+	DeleteTextData(ctx context.Context, token string, dataID string) error
 }
 
 // TextDataProvider implements the TextDataService interface and holds the state for user sessions.
@@ -72,7 +74,7 @@ func (p *TextDataProvider) Save(ctx context.Context) {
 	fmt.Println(color.New(color.FgGreen).SprintFunc()("Text data successfully saved"))
 }
 
-// LoadAllInfo retrieves and displays information about all text data stored by the user.
+// LoadAllInfo retrieves and displays information about the text data stored by the user page by page.
 // It checks for authorization and the working directory before proceeding.
 func (p *TextDataProvider) LoadAllInfo(ctx context.Context) {
 	red := color.New(color.FgRed).SprintFunc()
@@ -87,32 +89,40 @@ func (p *TextDataProvider) LoadAllInfo(ctx context.Context) {
 		return
 	}
 
-	textDataInfo, err := p.textDataService.LoadAllTextDataInfo(ctx, p.state.GetToken())
-	if err != nil {
-		logrus.WithError(err).Error("All user data info load failed")
-		fmt.Println(red("All text data info load failed"), "please try again")
-		lib.UnpackGRPCError(err)
-		return
-	}
-
-	green := color.New(color.FgGreen).SprintFunc()
-	yellow := color.New(color.FgYellow).SprintFunc()
-	fmt.Println(green("-------------------------------------"))
-
-	var sb strings.Builder
-	for _, dataInfo := range textDataInfo {
-		sb.WriteString("Data ID: " + dataInfo.ID + "\n")
-		sb.WriteString("Data type: " + dataInfo.DataType + "\n")
-		sb.WriteString("Metadata : " + dataInfo.MetaData + "\n")
-		sb.WriteString("Created at: : " + dataInfo.CreatedAt + "\n")
-		sb.WriteString(green("-------------------------------------" + "\n"))
-	}
-	if len(textDataInfo) > 0 {
-		fmt.Println(sb.String())
-	} else {
-		fmt.Println(yellow("Your haven't saved any data or data load filed, please try again"))
+	scanner := bufio.NewScanner(os.Stdin)
+	page := lib.ReadPageRequest(scanner)
+	for {
+		textDataInfo, err := p.textDataService.LoadAllTextDataInfo(ctx, p.state.GetToken(), page)
+		if err != nil {
+			logrus.WithError(err).Error("All user data info load failed")
+			fmt.Println(red("All text data info load failed"), "please try again")
+			lib.UnpackGRPCError(err)
+			return
+		}
+
+		green := color.New(color.FgGreen).SprintFunc()
+		yellow := color.New(color.FgYellow).SprintFunc()
+		fmt.Println(green("-------------------------------------"))
+
+		var sb strings.Builder
+		for _, dataInfo := range textDataInfo.Infos {
+			sb.WriteString("Data ID: " + dataInfo.ID + "\n")
+			sb.WriteString("Data type: " + dataInfo.DataType + "\n")
+			sb.WriteString("Metadata : " + dataInfo.MetaData + "\n")
+			sb.WriteString("Created at: : " + dataInfo.CreatedAt + "\n")
+			sb.WriteString(green("-------------------------------------" + "\n"))
+		}
+		if len(textDataInfo.Infos) > 0 {
+			fmt.Println(sb.String())
+		} else if page.PageToken == "" {
+			fmt.Println(yellow("Your haven't saved any data or data load filed, please try again"))
+		}
+
+		if textDataInfo.NextPageToken == "" || !lib.ConfirmNextPage(scanner) {
+			return
+		}
+		page.PageToken = textDataInfo.NextPageToken
 	}
-
 }
 
 // LoadData retrieves and displays a specific text data entry based on the provided data ID.
@@ -176,3 +186,69 @@ func (p *TextDataProvider) LoadData(ctx context.Context) {
 		return
 	}
 }
+
+// Update prompts the user for the ID of existing text data and its new content,
+// then replaces it using the text data service.
+func (p *TextDataProvider) Update(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	req := model.TextDataPutRequest{}
+	fmt.Println(cyanBold("Input text data to update 'id text metadata':"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input data ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	req.ID = scanner.Text()
+
+	fmt.Printf("Input text data as %s: ", yellow("'your text'"))
+	scanner.Scan()
+	req.Text = scanner.Text()
+
+	fmt.Printf("Input data description as %s: ", yellow("'text'"))
+	scanner.Scan()
+	req.MetaData = scanner.Text()
+
+	_, err := p.textDataService.UpdateTextData(ctx, p.state.GetToken(), req)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Text data successfully updated"))
+}
+
+// Delete prompts the user for a text data ID and deletes it using the text data service.
+func (p *TextDataProvider) Delete(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !p.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Input data ID to delete text data:"))
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("Input data ID as %s: ", yellow("'example (b7fa5761-7e83-11ef-a610-0242ac140004)'"))
+	scanner.Scan()
+	dataID := scanner.Text()
+
+	err := p.textDataService.DeleteTextData(ctx, p.state.GetToken(), dataID)
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Text data successfully deleted"))
+}
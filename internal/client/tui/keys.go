@@ -0,0 +1,100 @@
+package tui
+
+import "unicode/utf8"
+
+// KeyCode identifies a key pressed by the user.
+type KeyCode int
+
+// Keys recognized by the interface, printable characters are KeyRune.
+const (
+	KeyRune KeyCode = iota
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyTab
+	KeyBackTab
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyCtrlC
+	KeyCtrlS
+)
+
+// Key is a key pressed by the user.
+type Key struct {
+	Code KeyCode
+	Rune rune // Character of KeyRune
+}
+
+// escapeSequences maps the escape sequences sent by terminals to keys.
+var escapeSequences = map[string]KeyCode{
+	"\x1b[A": KeyUp,
+	"\x1b[B": KeyDown,
+	"\x1b[C": KeyRight,
+	"\x1b[D": KeyLeft,
+	"\x1bOA": KeyUp,
+	"\x1bOB": KeyDown,
+	"\x1bOC": KeyRight,
+	"\x1bOD": KeyLeft,
+	"\x1b[Z": KeyBackTab,
+}
+
+// parseKeys decodes the keys in a chunk of terminal input. An escape byte not starting a known sequence
+// is the Esc key, unknown sequences are dropped.
+func parseKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			n, code, ok := parseEscape(b)
+			if ok {
+				keys = append(keys, Key{Code: code})
+			}
+			b = b[n:]
+			continue
+		}
+
+		switch b[0] {
+		case '\r', '\n':
+			keys = append(keys, Key{Code: KeyEnter})
+		case 0x7f, 0x08:
+			keys = append(keys, Key{Code: KeyBackspace})
+		case '\t':
+			keys = append(keys, Key{Code: KeyTab})
+		case 0x03:
+			keys = append(keys, Key{Code: KeyCtrlC})
+		case 0x13:
+			keys = append(keys, Key{Code: KeyCtrlS})
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r != utf8.RuneError && r >= ' ' {
+				keys = append(keys, Key{Code: KeyRune, Rune: r})
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+
+	return keys
+}
+
+// parseEscape decodes the escape sequence at the start of b and returns its length.
+func parseEscape(b []byte) (int, KeyCode, bool) {
+	if len(b) == 1 || (b[1] != '[' && b[1] != 'O') {
+		return 1, KeyEsc, true
+	}
+
+	// A CSI sequence ends with a byte in the range 0x40-0x7e
+	n := 2
+	for n < len(b) && (b[n] < 0x40 || b[n] > 0x7e) {
+		n++
+	}
+	if n == len(b) {
+		return n, 0, false
+	}
+	n++
+
+	code, ok := escapeSequences[string(b[:n])]
+	return n, code, ok
+}
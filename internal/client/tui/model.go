@@ -0,0 +1,370 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+// pane is a part of the screen focused by the user.
+type pane int
+
+const (
+	paneSidebar pane = iota
+	paneList
+	paneDetail
+	paneCount
+)
+
+// mode is the kind of input expected from the user.
+type mode int
+
+const (
+	modeBrowse  mode = iota // Moving between panes and records
+	modeFilter              // Typing the filter of the list
+	modeForm                // Filling in the form of a record
+	modeConfirm             // Confirming the deletion of a record
+)
+
+// Model is the state of the interface. It is changed by the keys pressed by the user and drawn by view.
+type Model struct {
+	kinds []kind        // Data types in the sidebar
+	token func() string // Access token of the user
+	title string        // Title shown at the top of the screen
+
+	kind     int              // Selected data type
+	infos    []model.DataInfo // Infos of the records of the selected data type
+	filter   string           // Filter of the list by metadata and ID
+	cursor   int              // Selected record in the filtered list
+	detail   *record          // Record shown in the detail pane
+	revealed bool             // Secrets of the detail pane are shown
+	form     *form            // Form shown in the detail pane
+
+	pane    pane   // Focused pane
+	mode    mode   // Expected input
+	status  string // Message shown in the status line
+	isError bool   // The status message is an error
+	quit    bool   // The user quits the interface
+}
+
+// form is the form of a record being added or edited.
+type form struct {
+	record  record            // Record with the values typed by the user
+	cursor  int               // Focused field
+	errors  map[string]string // Validation errors by the field names
+	general string            // Error not related to a field
+}
+
+// newModel creates a new Model with the data types shown in the sidebar.
+func newModel(kinds []kind, token func() string, title string) *Model {
+	return &Model{
+		kinds: kinds,
+		token: token,
+		title: title,
+		pane:  paneList,
+	}
+}
+
+// reload loads the list of records of the selected data type again.
+func (m *Model) reload(ctx context.Context) {
+	infos, err := m.kinds[m.kind].store.list(ctx, m.token())
+	m.detail, m.revealed = nil, false
+	if err != nil {
+		m.infos = nil
+		m.setError("Unable to load the list: " + errorText(err))
+		return
+	}
+
+	m.infos = infos
+	m.clampCursor()
+}
+
+// visible returns the records of the list matching the filter.
+func (m *Model) visible() []model.DataInfo {
+	if m.filter == "" {
+		return m.infos
+	}
+
+	filter := strings.ToLower(m.filter)
+	var infos []model.DataInfo
+	for _, info := range m.infos {
+		if strings.Contains(strings.ToLower(info.MetaData), filter) || strings.Contains(strings.ToLower(info.ID), filter) {
+			infos = append(infos, info)
+		}
+	}
+
+	return infos
+}
+
+// selected returns the info of the selected record.
+func (m *Model) selected() (model.DataInfo, bool) {
+	infos := m.visible()
+	if m.cursor < 0 || m.cursor >= len(infos) {
+		return model.DataInfo{}, false
+	}
+
+	return infos[m.cursor], true
+}
+
+// update changes the model according to the key pressed by the user.
+func (m *Model) update(ctx context.Context, key Key) {
+	if key.Code == KeyCtrlC {
+		m.quit = true
+		return
+	}
+
+	switch m.mode {
+	case modeFilter:
+		m.updateFilter(key)
+	case modeForm:
+		m.updateForm(ctx, key)
+	case modeConfirm:
+		m.updateConfirm(ctx, key)
+	default:
+		m.updateBrowse(ctx, key)
+	}
+}
+
+// updateBrowse handles the keys moving between panes and records and starting the actions.
+func (m *Model) updateBrowse(ctx context.Context, key Key) {
+	m.status, m.isError = "", false
+
+	switch {
+	case key.Code == KeyTab || key.Code == KeyRight:
+		m.pane = (m.pane + 1) % paneCount
+	case key.Code == KeyBackTab || key.Code == KeyLeft:
+		m.pane = (m.pane + paneCount - 1) % paneCount
+	case key.Code == KeyUp || key.Rune == 'k':
+		m.move(ctx, -1)
+	case key.Code == KeyDown || key.Rune == 'j':
+		m.move(ctx, 1)
+	case key.Code == KeyEnter:
+		if m.pane == paneSidebar {
+			m.pane = paneList
+			return
+		}
+		m.open(ctx)
+	case key.Code == KeyEsc:
+		m.detail, m.revealed = nil, false
+		m.pane = paneList
+	case key.Code != KeyRune:
+	case key.Rune == 'q':
+		m.quit = true
+	case key.Rune == '/':
+		m.mode, m.pane = modeFilter, paneList
+	case (key.Rune == 'a' || key.Rune == 'e') && m.kinds[m.kind].readOnly:
+		m.setError(errReadOnly.Error())
+	case key.Rune == 'a':
+		m.startForm(record{Values: make([]string, len(m.kinds[m.kind].fields))})
+	case key.Rune == 'e':
+		if m.open(ctx) {
+			m.startForm(*m.detail)
+		}
+	case key.Rune == 'd':
+		if info, ok := m.selected(); ok {
+			m.mode = modeConfirm
+			m.status = "Delete " + label(info) + "? Press 'y' to confirm"
+		}
+	case key.Rune == 'r':
+		m.revealed = !m.revealed
+	case key.Rune == 'R':
+		m.reload(ctx)
+	}
+}
+
+// move moves the selection of the focused pane.
+func (m *Model) move(ctx context.Context, delta int) {
+	switch m.pane {
+	case paneSidebar:
+		next := m.kind + delta
+		if next < 0 || next >= len(m.kinds) {
+			return
+		}
+		m.kind, m.cursor, m.filter = next, 0, ""
+		m.reload(ctx)
+	case paneList:
+		m.cursor += delta
+		m.clampCursor()
+		m.detail, m.revealed = nil, false
+	}
+}
+
+// open loads the selected record into the detail pane and reports whether it is shown.
+func (m *Model) open(ctx context.Context) bool {
+	info, ok := m.selected()
+	if !ok {
+		return false
+	}
+	if m.detail != nil && m.detail.ID == info.ID {
+		m.pane = paneDetail
+		return true
+	}
+
+	r, err := m.kinds[m.kind].store.load(ctx, m.token(), info)
+	if err != nil {
+		m.setError("Unable to load the record: " + errorText(err))
+		return false
+	}
+
+	m.detail, m.revealed = &r, false
+	m.pane = paneDetail
+	return true
+}
+
+// updateFilter handles the keys typing the filter of the list.
+func (m *Model) updateFilter(key Key) {
+	switch key.Code {
+	case KeyRune:
+		m.filter += string(key.Rune)
+	case KeyBackspace:
+		m.filter = dropLastRune(m.filter)
+	case KeyEnter:
+		m.mode = modeBrowse
+	case KeyEsc:
+		m.filter, m.mode = "", modeBrowse
+	default:
+		return
+	}
+
+	m.cursor = 0
+	m.detail, m.revealed = nil, false
+}
+
+// updateConfirm deletes the selected record when the user confirms it.
+func (m *Model) updateConfirm(ctx context.Context, key Key) {
+	m.mode = modeBrowse
+	if key.Rune != 'y' {
+		m.setStatus("Deletion cancelled")
+		return
+	}
+
+	info, ok := m.selected()
+	if !ok {
+		return
+	}
+
+	if err := m.kinds[m.kind].store.delete(ctx, m.token(), info.ID); err != nil {
+		m.setError("Unable to delete the record: " + errorText(err))
+		return
+	}
+
+	m.reload(ctx)
+	m.setStatus("Deleted " + label(info))
+}
+
+// startForm shows the form of the record in the detail pane.
+func (m *Model) startForm(r record) {
+	r.Values = append([]string(nil), r.Values...)
+	m.form = &form{record: r}
+	m.mode, m.pane = modeForm, paneDetail
+}
+
+// updateForm handles the keys filling in the form.
+func (m *Model) updateForm(ctx context.Context, key Key) {
+	f := m.form
+	last := len(f.record.Values) - 1
+
+	switch key.Code {
+	case KeyRune:
+		f.record.Values[f.cursor] += string(key.Rune)
+	case KeyBackspace:
+		f.record.Values[f.cursor] = dropLastRune(f.record.Values[f.cursor])
+	case KeyTab, KeyDown:
+		f.cursor = min(f.cursor+1, last)
+	case KeyBackTab, KeyUp:
+		f.cursor = max(f.cursor-1, 0)
+	case KeyEnter:
+		if f.cursor < last {
+			f.cursor++
+			return
+		}
+		m.submit(ctx)
+	case KeyCtrlS:
+		m.submit(ctx)
+	case KeyEsc:
+		m.form, m.mode = nil, modeBrowse
+		m.setStatus("Changes discarded")
+	}
+}
+
+// submit saves the record of the form. The validation errors reported by the server are shown next to the fields.
+func (m *Model) submit(ctx context.Context) {
+	f := m.form
+	k := m.kinds[m.kind]
+
+	id, err := k.store.save(ctx, m.token(), f.record)
+	if err != nil {
+		f.errors, f.general = nil, errorText(err)
+		if violations := lib.FieldViolations(err); len(violations) != 0 {
+			f.errors = violations
+			f.general = "Please fix the invalid fields"
+			for i, fd := range k.fields {
+				if _, ok := violations[fd.name]; ok {
+					f.cursor = i
+					break
+				}
+			}
+		}
+		return
+	}
+
+	m.form, m.mode = nil, modeBrowse
+	m.reload(ctx)
+	m.filter = ""
+	for i, info := range m.infos {
+		if info.ID == id {
+			m.cursor = i
+		}
+	}
+	m.open(ctx)
+	m.setStatus("Saved")
+}
+
+// clampCursor keeps the selection within the filtered list.
+func (m *Model) clampCursor() {
+	m.cursor = max(min(m.cursor, len(m.visible())-1), 0)
+}
+
+// setStatus shows an informational message in the status line.
+func (m *Model) setStatus(msg string) {
+	m.status, m.isError = msg, false
+}
+
+// setError shows an error in the status line.
+func (m *Model) setError(msg string) {
+	m.status, m.isError = msg, true
+}
+
+// label returns the text identifying a record in the list.
+func label(info model.DataInfo) string {
+	if info.MetaData != "" {
+		return info.MetaData
+	}
+
+	return info.ID
+}
+
+// errorText returns the message of the error to show to the user, gRPC errors are reduced to their message.
+func errorText(err error) string {
+	var se interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &se) {
+		return se.GRPCStatus().Message()
+	}
+
+	return err.Error()
+}
+
+// dropLastRune removes the last character of the string.
+func dropLastRune(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+
+	return string(r[:len(r)-1])
+}
@@ -0,0 +1,248 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+// errReadOnly is returned when records of a data type can't be edited in the interface.
+var errReadOnly = errors.New("records of this type can't be edited here, please use the menu or the command line")
+
+// field describes a field of the records of a data type.
+type field struct {
+	label  string // Label shown to the user
+	name   string // Name of the field in the validation errors reported by the server
+	secret bool   // Value is masked until revealed
+}
+
+// record is a record of any data type with the values of its fields in the order of the fields of its kind.
+type record struct {
+	ID       string
+	Revision int64
+	Values   []string
+}
+
+// store loads and saves the records of one data type.
+type store interface {
+	list(ctx context.Context, token string) ([]model.DataInfo, error)
+	load(ctx context.Context, token string, info model.DataInfo) (record, error)
+	save(ctx context.Context, token string, r record) (string, error)
+	delete(ctx context.Context, token string, id string) error
+}
+
+// kind is a data type shown in the sidebar.
+type kind struct {
+	title    string                         // Title in the sidebar
+	fields   []field                        // Fields of the records
+	store    store                          // Store of the records
+	readOnly bool                           // Records can't be added or edited
+	extra    func(values []string) []string // Rows computed from the values, shown under an opened record
+}
+
+var (
+	cardFields = []field{
+		{label: "Number", name: "Number", secret: true},
+		{label: "Owner", name: "OwnerName"},
+		{label: "Expires (DD-MM-YYYY)", name: "ExpiresAt"},
+		{label: "CVV", name: "CVV", secret: true},
+		{label: "PIN", name: "PinCode", secret: true},
+		{label: "Metadata", name: "MetaData"},
+		{label: "Expiry reminder (y/n)", name: "ExpiryReminder"},
+	}
+	textFields = []field{
+		{label: "Text", name: "Text", secret: true},
+		{label: "Metadata", name: "MetaData"},
+	}
+	credentialsFields = []field{
+		{label: "Login", name: "Login"},
+		{label: "Password", name: "Password", secret: true},
+		{label: "Metadata", name: "MetaData"},
+		{label: "Two-factor secret", name: "TOTP", secret: true},
+	}
+	fileFields = []field{
+		{label: "Metadata", name: "MetaData"},
+		{label: "Created at", name: "CreatedAt"},
+	}
+)
+
+// newKinds returns the data types shown in the sidebar.
+func newKinds(s Services) []kind {
+	return []kind{
+		{title: "Credit cards", fields: cardFields, store: cardStore{s.CreditCards}},
+		{title: "Text", fields: textFields, store: textStore{s.TextData}},
+		{title: "Credentials", fields: credentialsFields, store: credentialsStore{s.Credentials}, extra: credentialsExtra},
+		{title: "Files", fields: fileFields, store: fileStore{s.Binaries}, readOnly: true},
+	}
+}
+
+// loadAll loads the infos of all records of one data type, the oldest first.
+func loadAll(ctx context.Context, token string,
+	load func(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)) ([]model.DataInfo, error) {
+	return lib.LoadAllInfos(ctx, token, model.PageRequest{SortBy: model.SortByCreatedAt}, load)
+}
+
+// cardStore stores credit cards.
+type cardStore struct {
+	service CreditCardService
+}
+
+func (s cardStore) list(ctx context.Context, token string) ([]model.DataInfo, error) {
+	return loadAll(ctx, token, s.service.LoadAllCreditCardDataInfo)
+}
+
+func (s cardStore) load(ctx context.Context, token string, info model.DataInfo) (record, error) {
+	card, err := s.service.LoadCreditCardData(ctx, token, info.ID)
+	if err != nil {
+		return record{}, err
+	}
+
+	return record{
+		ID:       info.ID,
+		Revision: card.Revision,
+		Values:   []string{card.Number, card.OwnerName, card.ExpiresAt, card.CVV, card.PinCode, card.MetaData, yesNo(card.ExpiryReminder)},
+	}, nil
+}
+
+func (s cardStore) save(ctx context.Context, token string, r record) (string, error) {
+	v := r.Values
+	if r.ID == "" {
+		card, err := s.service.SaveCreditCard(ctx, token, model.CreditCardPostRequest{
+			Number: v[0], OwnerName: v[1], ExpiresAt: v[2], CVV: v[3], PinCode: v[4], MetaData: v[5],
+			ExpiryReminder: isYes(v[6]),
+		})
+		return card.ID, err
+	}
+
+	card, err := s.service.UpdateCreditCard(ctx, token, model.CreditCardPutRequest{
+		ID: r.ID, Number: v[0], OwnerName: v[1], ExpiresAt: v[2], CVV: v[3], PinCode: v[4], MetaData: v[5], Revision: r.Revision,
+		ExpiryReminder: isYes(v[6]),
+	})
+	return card.ID, err
+}
+
+func (s cardStore) delete(ctx context.Context, token string, id string) error {
+	return s.service.DeleteCreditCard(ctx, token, id)
+}
+
+// textStore stores text data.
+type textStore struct {
+	service TextDataService
+}
+
+func (s textStore) list(ctx context.Context, token string) ([]model.DataInfo, error) {
+	return loadAll(ctx, token, s.service.LoadAllTextDataInfo)
+}
+
+func (s textStore) load(ctx context.Context, token string, info model.DataInfo) (record, error) {
+	text, err := s.service.LoadTextData(ctx, token, info.ID)
+	if err != nil {
+		return record{}, err
+	}
+
+	return record{ID: info.ID, Revision: text.Revision, Values: []string{text.Text, text.MetaData}}, nil
+}
+
+func (s textStore) save(ctx context.Context, token string, r record) (string, error) {
+	if r.ID == "" {
+		text, err := s.service.SaveTextData(ctx, token, model.TextDataPostRequest{Text: r.Values[0], MetaData: r.Values[1]})
+		return text.ID, err
+	}
+
+	text, err := s.service.UpdateTextData(ctx, token, model.TextDataPutRequest{
+		ID: r.ID, Text: r.Values[0], MetaData: r.Values[1], Revision: r.Revision,
+	})
+	return text.ID, err
+}
+
+func (s textStore) delete(ctx context.Context, token string, id string) error {
+	return s.service.DeleteTextData(ctx, token, id)
+}
+
+// credentialsStore stores credentials.
+type credentialsStore struct {
+	service CredentialsService
+}
+
+func (s credentialsStore) list(ctx context.Context, token string) ([]model.DataInfo, error) {
+	return loadAll(ctx, token, s.service.LoadAllCredentialsDataInfo)
+}
+
+func (s credentialsStore) load(ctx context.Context, token string, info model.DataInfo) (record, error) {
+	cred, err := s.service.LoadCredentialsData(ctx, token, info.ID)
+	if err != nil {
+		return record{}, err
+	}
+
+	return record{
+		ID:       info.ID,
+		Revision: cred.Revision,
+		Values:   []string{cred.Login, cred.Password, cred.MetaData, cred.TOTP},
+	}, nil
+}
+
+func (s credentialsStore) save(ctx context.Context, token string, r record) (string, error) {
+	if r.ID == "" {
+		cred, err := s.service.SaveCredentials(ctx, token, model.CredentialsPostRequest{
+			Login: r.Values[0], Password: r.Values[1], MetaData: r.Values[2], TOTP: r.Values[3],
+		})
+		return cred.ID, err
+	}
+
+	cred, err := s.service.UpdateCredentials(ctx, token, model.CredentialsPutRequest{
+		ID: r.ID, Login: r.Values[0], Password: r.Values[1], MetaData: r.Values[2], TOTP: r.Values[3], Revision: r.Revision,
+	})
+	return cred.ID, err
+}
+
+func (s credentialsStore) delete(ctx context.Context, token string, id string) error {
+	return s.service.DeleteCredentials(ctx, token, id)
+}
+
+// credentialsExtra shows the current two-factor code of credentials with a two-factor secret.
+func credentialsExtra(values []string) []string {
+	if values[3] == "" {
+		return nil
+	}
+
+	return []string{"Two-factor code: " + lib.FormatOneTimeCode(values[3], time.Now())}
+}
+
+// fileStore lists binary files. The content of files is not loaded, they are downloaded with the menu
+// or the command line, so the record holds the info of the file only.
+type fileStore struct {
+	service BinaryDataService
+}
+
+func (s fileStore) list(ctx context.Context, token string) ([]model.DataInfo, error) {
+	return loadAll(ctx, token, s.service.LoadAllBinaryDataInfo)
+}
+
+func (s fileStore) load(_ context.Context, _ string, info model.DataInfo) (record, error) {
+	return record{ID: info.ID, Revision: info.Revision, Values: []string{info.MetaData, info.CreatedAt}}, nil
+}
+
+func (s fileStore) save(context.Context, string, record) (string, error) {
+	return "", errReadOnly
+}
+
+func (s fileStore) delete(ctx context.Context, token string, id string) error {
+	return s.service.DeleteBinaryData(ctx, token, id)
+}
+
+// yesNo formats a flag as the value of a y/n field.
+func yesNo(v bool) string {
+	if v {
+		return "y"
+	}
+	return "n"
+}
+
+// isYes reports whether the value of a y/n field is set.
+func isYes(v string) bool {
+	return strings.EqualFold(strings.TrimSpace(v), "y")
+}
@@ -0,0 +1,9 @@
+package tui
+
+import "golang.org/x/sys/unix"
+
+// Requests of the terminal mode ioctls.
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
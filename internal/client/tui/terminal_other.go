@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package tui
+
+import "errors"
+
+// errUnsupported is returned on platforms without a raw terminal mode implementation.
+var errUnsupported = errors.New("full-screen interface is not supported on this platform")
+
+// makeRaw is not supported on this platform.
+func makeRaw(int) (func(), error) {
+	return nil, errUnsupported
+}
+
+// terminalSize is not supported on this platform.
+func terminalSize(int) (int, int, error) {
+	return 0, 0, errUnsupported
+}
@@ -0,0 +1,45 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package tui
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// makeRaw puts the terminal into raw mode, so keys are read one by one without echo,
+// and returns the function restoring the previous mode.
+func makeRaw(fd int) (func(), error) {
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, fmt.Errorf("get terminal mode: %w", err)
+	}
+	saved := *termios
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	if err = unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		return nil, fmt.Errorf("set terminal mode: %w", err)
+	}
+
+	return func() {
+		_ = unix.IoctlSetTermios(fd, ioctlSetTermios, &saved)
+	}, nil
+}
+
+// terminalSize returns the width and the height of the terminal.
+func terminalSize(fd int) (int, int, error) {
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, fmt.Errorf("get terminal size: %w", err)
+	}
+
+	return int(ws.Col), int(ws.Row), nil
+}
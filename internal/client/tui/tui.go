@@ -0,0 +1,151 @@
+// Package tui implements the full-screen terminal interface of the client. It shows a sidebar of data types,
+// a filterable list of the records of the selected type and a detail pane, which masks secrets until revealed
+// and turns into a form for adding and editing records.
+package tui
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+)
+
+// Escape sequences controlling the terminal.
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l" // Switch to the alternate screen and hide the cursor
+	leaveScreen = "\x1b[?25h\x1b[?1049l" // Show the cursor and switch back to the main screen
+	cursorHome  = "\x1b[H"               // Move the cursor to the top left corner
+	clearLine   = "\x1b[K"               // Clear the rest of the line
+)
+
+// CreditCardService defines the credit card operations used by the interface.
+type CreditCardService interface {
+	SaveCreditCard(ctx context.Context, token string, card model.CreditCardPostRequest) (model.CreditCard, error)
+	LoadCreditCardData(ctx context.Context, token string, dataID string) (model.CreditCard, error)
+	LoadAllCreditCardDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCreditCard(ctx context.Context, token string, card model.CreditCardPutRequest) (model.CreditCard, error)
+	DeleteCreditCard(ctx context.Context, token string, dataID string) error
+}
+
+// TextDataService defines the text data operations used by the interface.
+type TextDataService interface {
+	SaveTextData(ctx context.Context, token string, text model.TextDataPostRequest) (model.TextData, error)
+	LoadTextData(ctx context.Context, token string, dataID string) (model.TextData, error)
+	LoadAllTextDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateTextData(ctx context.Context, token string, text model.TextDataPutRequest) (model.TextData, error)
+	DeleteTextData(ctx context.Context, token string, dataID string) error
+}
+
+// CredentialsService defines the credentials operations used by the interface.
+type CredentialsService interface {
+	SaveCredentials(ctx context.Context, token string, cred model.CredentialsPostRequest) (model.Credentials, error)
+	LoadCredentialsData(ctx context.Context, token string, dataID string) (model.Credentials, error)
+	LoadAllCredentialsDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCredentials(ctx context.Context, token string, cred model.CredentialsPutRequest) (model.Credentials, error)
+	DeleteCredentials(ctx context.Context, token string, dataID string) error
+}
+
+// BinaryDataService defines the binary data operations used by the interface.
+type BinaryDataService interface {
+	LoadAllBinaryDataInfo(ctx context.Context, token string, page model.PageRequest) (model.DataInfoPage, error)
+	DeleteBinaryData(ctx context.Context, token string, dataID string) error
+}
+
+// Services holds the services of the data types shown in the interface.
+type Services struct {
+	CreditCards CreditCardService
+	TextData    TextDataService
+	Credentials CredentialsService
+	Binaries    BinaryDataService
+}
+
+// UI is the full-screen terminal interface of the client.
+type UI struct {
+	services Services           // Services of the data types
+	state    *state.ClientState // Client's state, including authorization information
+}
+
+// NewUI creates a new UI working with the given services and client state.
+func NewUI(services Services, state *state.ClientState) *UI {
+	return &UI{
+		services: services,
+		state:    state,
+	}
+}
+
+// Run takes over the terminal until the user quits the interface. The user must be authorized.
+func (u *UI) Run(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !u.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	title := u.state.GetLogin()
+	if u.state.IsOffline() {
+		title += " (offline)"
+	}
+
+	m := newModel(newKinds(u.services), u.state.GetToken, title)
+	if err := run(ctx, m, os.Stdin, os.Stdout); err != nil {
+		fmt.Println(red("Full-screen interface failed: ", err))
+	}
+}
+
+// run draws the model on the terminal and feeds it with the keys pressed by the user until it quits.
+func run(ctx context.Context, m *Model, in *os.File, out *os.File) error {
+	restore, err := makeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	w := bufio.NewWriter(out)
+	fmt.Fprint(w, enterScreen)
+	defer func() {
+		fmt.Fprint(w, leaveScreen)
+		w.Flush()
+	}()
+
+	m.reload(ctx)
+
+	buf := make([]byte, 256)
+	for !m.quit {
+		width, height, err := terminalSize(int(out.Fd()))
+		if err != nil {
+			return err
+		}
+		if err = draw(w, m.view(width, height)); err != nil {
+			return err
+		}
+
+		n, err := in.Read(buf)
+		if err != nil {
+			return fmt.Errorf("read input: %w", err)
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			m.update(ctx, key)
+		}
+	}
+
+	return nil
+}
+
+// draw writes the lines of a frame over the previous one.
+func draw(w *bufio.Writer, lines []string) error {
+	fmt.Fprint(w, cursorHome)
+	for i, line := range lines {
+		if i > 0 {
+			fmt.Fprint(w, "\r\n")
+		}
+		fmt.Fprint(w, line, clearLine)
+	}
+
+	return w.Flush()
+}
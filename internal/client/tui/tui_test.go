@@ -0,0 +1,178 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+type fakeStore struct {
+	records map[string]record
+	infos   []model.DataInfo
+	saveErr error
+	deleted []string
+}
+
+func (f *fakeStore) list(context.Context, string) ([]model.DataInfo, error) {
+	return f.infos, nil
+}
+
+func (f *fakeStore) load(_ context.Context, _ string, info model.DataInfo) (record, error) {
+	return f.records[info.ID], nil
+}
+
+func (f *fakeStore) save(_ context.Context, _ string, r record) (string, error) {
+	if f.saveErr != nil {
+		return "", f.saveErr
+	}
+	if r.ID == "" {
+		r.ID = fmt.Sprintf("id%d", len(f.infos)+1)
+		f.infos = append(f.infos, model.DataInfo{ID: r.ID, MetaData: r.Values[2]})
+	}
+	f.records[r.ID] = r
+	return r.ID, nil
+}
+
+func (f *fakeStore) delete(_ context.Context, _ string, id string) error {
+	f.deleted = append(f.deleted, id)
+	for i, info := range f.infos {
+		if info.ID == id {
+			f.infos = append(f.infos[:i], f.infos[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+type TUITestSuite struct {
+	suite.Suite
+	ctx   context.Context
+	store *fakeStore
+	model *Model
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(TUITestSuite))
+}
+
+func (s *TUITestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.store = &fakeStore{
+		records: map[string]record{
+			"id1": {ID: "id1", Revision: 1, Values: []string{"admin", "secret", "db #prod", ""}},
+			"id2": {ID: "id2", Revision: 1, Values: []string{"guest", "qwerty", "mail", "JBSWY3DPEHPK3PXP"}},
+		},
+		infos: []model.DataInfo{{ID: "id1", MetaData: "db #prod"}, {ID: "id2", MetaData: "mail"}},
+	}
+	kinds := []kind{{title: "Credentials", fields: credentialsFields, store: s.store, extra: credentialsExtra}}
+	s.model = newModel(kinds, func() string { return "token" }, "user@example.com")
+	s.model.reload(s.ctx)
+}
+
+// press sends the keys of the terminal input to the model.
+func (s *TUITestSuite) press(input string) {
+	for _, key := range parseKeys([]byte(input)) {
+		s.model.update(s.ctx, key)
+	}
+}
+
+// screen draws the model and returns the screen as text.
+func (s *TUITestSuite) screen() string {
+	return strings.Join(s.model.view(120, 20), "\n")
+}
+
+func (s *TUITestSuite) Test_ParseKeys() {
+	keys := parseKeys([]byte("aж\r\x1b[A\x1b[Z\t\x7f\x03\x13\x1b"))
+	assert.Equal(s.T(), []Key{
+		{Code: KeyRune, Rune: 'a'},
+		{Code: KeyRune, Rune: 'ж'},
+		{Code: KeyEnter},
+		{Code: KeyUp},
+		{Code: KeyBackTab},
+		{Code: KeyTab},
+		{Code: KeyBackspace},
+		{Code: KeyCtrlC},
+		{Code: KeyCtrlS},
+		{Code: KeyEsc},
+	}, keys)
+}
+
+func (s *TUITestSuite) Test_Filter() {
+	s.press("/PROD\r")
+	require.Len(s.T(), s.model.visible(), 1)
+	assert.Equal(s.T(), "id1", s.model.visible()[0].ID)
+	assert.Contains(s.T(), s.screen(), "/PROD")
+
+	s.press("/\x1b")
+	assert.Len(s.T(), s.model.visible(), 2)
+}
+
+func (s *TUITestSuite) Test_RevealSecrets() {
+	s.press("j\r")
+	screen := s.screen()
+	assert.Contains(s.T(), screen, "Login: guest")
+	assert.Contains(s.T(), screen, "Password: "+mask)
+	assert.NotContains(s.T(), screen, "qwerty")
+	assert.Contains(s.T(), screen, "Two-factor secret: "+mask)
+	assert.Regexp(s.T(), `Two-factor code: \d{6} \(\d+s left\)`, screen)
+
+	s.press("r")
+	assert.Contains(s.T(), s.screen(), "Password: qwerty")
+
+	// Moving to another record masks the secrets again
+	s.press("\x1bk\r")
+	assert.Contains(s.T(), s.screen(), "Password: "+mask)
+}
+
+func (s *TUITestSuite) Test_FormValidation() {
+	st, err := status.New(codes.InvalidArgument, "invalid request").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "Password", Description: "is required"}},
+	})
+	require.NoError(s.T(), err)
+	s.store.saveErr = fmt.Errorf("save credentials: %w", st.Err())
+
+	s.press("aroot\x13")
+	require.NotNil(s.T(), s.model.form)
+	assert.Equal(s.T(), 1, s.model.form.cursor)
+	assert.Contains(s.T(), s.screen(), "Password is required")
+
+	s.store.saveErr = nil
+	s.press("toor\tbackup\x13")
+	assert.Nil(s.T(), s.model.form)
+	assert.Equal(s.T(), "Saved", s.model.status)
+	require.NotNil(s.T(), s.model.detail)
+	assert.Equal(s.T(), []string{"root", "toor", "backup", ""}, s.model.detail.Values)
+}
+
+func (s *TUITestSuite) Test_Edit() {
+	s.press("e\x7f\x7f\x7f\x7f\x7fuser\x13")
+	assert.Equal(s.T(), record{ID: "id1", Revision: 1, Values: []string{"user", "secret", "db #prod", ""}}, s.store.records["id1"])
+
+	s.press("e\tchanged\x1b")
+	assert.Equal(s.T(), "secret", s.store.records["id1"].Values[1])
+	assert.Equal(s.T(), "Changes discarded", s.model.status)
+}
+
+func (s *TUITestSuite) Test_Delete() {
+	s.press("dn")
+	assert.Empty(s.T(), s.store.deleted)
+
+	s.press("dy")
+	assert.Equal(s.T(), []string{"id1"}, s.store.deleted)
+	require.Len(s.T(), s.model.infos, 1)
+	assert.Equal(s.T(), "Deleted db #prod", s.model.status)
+}
+
+func (s *TUITestSuite) Test_TooSmall() {
+	assert.Contains(s.T(), s.model.view(40, 5)[0], "Terminal is too small")
+}
@@ -0,0 +1,213 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Styles of the text, as SGR escape sequences.
+const (
+	styleReset   = "\x1b[0m"
+	styleBold    = "\x1b[1m"
+	styleReverse = "\x1b[7m"
+	styleRed     = "\x1b[31m"
+	styleGreen   = "\x1b[32m"
+	styleDim     = "\x1b[2m"
+)
+
+const (
+	sidebarWidth = 16         // Width of the sidebar of data types
+	minWidth     = 60         // Smallest terminal width the interface is drawn in
+	minHeight    = 10         // Smallest terminal height the interface is drawn in
+	mask         = "••••••••" // Shown instead of secrets, independent of their length
+)
+
+// helps are the key hints shown at the bottom of the screen in every mode.
+var helps = map[mode]string{
+	modeBrowse:  "Tab pane  ↑↓ move  Enter open  / filter  a add  e edit  d delete  r reveal  R reload  q quit",
+	modeFilter:  "Type to filter  Enter done  Esc clear",
+	modeForm:    "Tab/↑↓ field  Enter next  Ctrl+S save  Esc cancel",
+	modeConfirm: "y delete  any other key cancels",
+}
+
+// view draws the model into lines of the given width, one per row of the screen.
+func (m *Model) view(width, height int) []string {
+	lines := make([]string, height)
+	if width < minWidth || height < minHeight {
+		lines[0] = fit(fmt.Sprintf("Terminal is too small, at least %dx%d is needed", minWidth, minHeight), width)
+		return lines
+	}
+
+	lines[0] = styleReverse + fit(" PrivateKeeper │ "+m.title, width) + styleReset
+
+	listWidth := (width - sidebarWidth - 2) * 2 / 5
+	detailWidth := width - sidebarWidth - listWidth - 2
+	bodyHeight := height - 3
+
+	sidebar := m.viewSidebar(sidebarWidth, bodyHeight)
+	list := m.viewList(listWidth, bodyHeight)
+	detail := m.viewDetail(detailWidth, bodyHeight)
+	for i := 0; i < bodyHeight; i++ {
+		lines[i+1] = sidebar[i] + styleDim + "│" + styleReset + list[i] + styleDim + "│" + styleReset + detail[i]
+	}
+
+	switch {
+	case m.status == "":
+		lines[height-2] = fit("", width)
+	case m.isError:
+		lines[height-2] = styleRed + fit(m.status, width) + styleReset
+	default:
+		lines[height-2] = styleGreen + fit(m.status, width) + styleReset
+	}
+	lines[height-1] = styleDim + fit(helps[m.mode], width) + styleReset
+
+	return lines
+}
+
+// viewSidebar draws the data types, the selected one is highlighted.
+func (m *Model) viewSidebar(width, height int) []string {
+	lines := blank(width, height)
+	for i, k := range m.kinds {
+		if i >= height {
+			break
+		}
+		lines[i] = m.highlight(fit(" "+k.title, width), i == m.kind, m.pane == paneSidebar)
+	}
+
+	return lines
+}
+
+// viewList draws the filtered records of the selected data type, scrolled to keep the selection visible.
+func (m *Model) viewList(width, height int) []string {
+	lines := blank(width, height)
+
+	top := 0
+	if m.filter != "" || m.mode == modeFilter {
+		cursor := ""
+		if m.mode == modeFilter {
+			cursor = "_"
+		}
+		lines[0] = styleBold + fit(" /"+m.filter+cursor, width) + styleReset
+		top = 1
+	}
+
+	infos := m.visible()
+	if len(infos) == 0 {
+		lines[top] = styleDim + fit(" No records", width) + styleReset
+		return lines
+	}
+
+	rows := height - top
+	offset := max(m.cursor-rows+1, 0)
+	for i := offset; i < len(infos) && i-offset < rows; i++ {
+		lines[top+i-offset] = m.highlight(fit(" "+label(infos[i]), width), i == m.cursor, m.pane == paneList)
+	}
+
+	return lines
+}
+
+// viewDetail draws the form or the opened record, secrets are masked until revealed.
+func (m *Model) viewDetail(width, height int) []string {
+	lines := blank(width, height)
+	fields := m.kinds[m.kind].fields
+
+	var rows []string
+	switch {
+	case m.form != nil:
+		title := " New record"
+		if m.form.record.ID != "" {
+			title = " Edit " + m.form.record.ID
+		}
+		rows = append(rows, styleBold+fit(title, width)+styleReset)
+		for i, fd := range fields {
+			value := m.form.record.Values[i]
+			if fd.secret && !m.revealed && i != m.form.cursor {
+				value = maskValue(value)
+			}
+			row := fit(fmt.Sprintf(" %s: %s", fd.label, value), width)
+			if i == m.form.cursor {
+				row = styleReverse + row + styleReset
+			}
+			rows = append(rows, row)
+			if msg, ok := m.form.errors[fd.name]; ok {
+				rows = append(rows, styleRed+fit("   ↳ "+fd.name+" "+msg, width)+styleReset)
+			}
+		}
+		if m.form.general != "" {
+			rows = append(rows, "", styleRed+fit(" "+m.form.general, width)+styleReset)
+		}
+	case m.detail != nil:
+		rows = append(rows, styleBold+fit(" ID: "+m.detail.ID, width)+styleReset)
+		for i, fd := range fields {
+			value := m.detail.Values[i]
+			if fd.secret && !m.revealed {
+				value = maskValue(value)
+			}
+			rows = append(rows, fit(fmt.Sprintf(" %s: %s", fd.label, value), width))
+		}
+		if extra := m.kinds[m.kind].extra; extra != nil {
+			for _, row := range extra(m.detail.Values) {
+				rows = append(rows, styleBold+fit(" "+row, width)+styleReset)
+			}
+		}
+	default:
+		rows = append(rows, styleDim+fit(" Press Enter to open the selected record", width)+styleReset)
+	}
+
+	for i, row := range rows {
+		if i >= height {
+			break
+		}
+		if row == "" {
+			continue
+		}
+		lines[i] = row
+	}
+
+	return lines
+}
+
+// highlight marks the selected row of a pane, reversed in the focused pane and bold otherwise.
+func (m *Model) highlight(row string, selected, focused bool) string {
+	switch {
+	case selected && focused:
+		return styleReverse + row + styleReset
+	case selected:
+		return styleBold + row + styleReset
+	default:
+		return row
+	}
+}
+
+// maskValue hides a secret value, empty values stay empty.
+func maskValue(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return mask
+}
+
+// blank returns the given number of empty rows of the given width.
+func blank(width, height int) []string {
+	lines := make([]string, height)
+	for i := range lines {
+		lines[i] = strings.Repeat(" ", width)
+	}
+
+	return lines
+}
+
+// fit pads or truncates the text to exactly the given width.
+func fit(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	if width <= 1 {
+		return string([]rune(s)[:width])
+	}
+
+	return string([]rune(s)[:width-1]) + "…"
+}
@@ -3,7 +3,9 @@ package pbclient
 import (
 	"context"
 
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
 	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/user"
+	"google.golang.org/grpc/metadata"
 )
 
 // UserPBClient is a client for interacting with the user service via gRPC.
@@ -21,33 +23,124 @@ func NewUserPBClient(u pb.UserServiceClient) *UserPBClient {
 }
 
 // LoginUser attempts to log in a user with the provided login credentials.
-// It sends a login request to the user service and returns the user's token if successful.
-func (u *UserPBClient) LoginUser(ctx context.Context, login, password string) (string, error) {
+// The two-factor code may be empty if two-factor authentication is not enabled for the user.
+// It sends a login request to the user service and returns the user's token
+// and wrapped data key if successful.
+func (u *UserPBClient) LoginUser(ctx context.Context, login, password, totpCode string) (model.UserLoginResponse, error) {
 	req := &pb.PostUserLoginRequest{
 		Login:    login,
 		Password: password,
+		TotpCode: totpCode,
 	}
 
 	resp, err := u.userService.PostLoginUser(ctx, req)
 	if err != nil {
-		return "", err
+		return model.UserLoginResponse{}, err
 	}
 
-	return resp.Token, nil
+	return model.UserLoginResponse{
+		Token:          resp.Token,
+		RefreshToken:   resp.RefreshToken,
+		WrappedDataKey: resp.WrappedDataKey,
+	}, nil
 }
 
 // RegisterUser registers a new user with the provided login credentials.
-// It sends a registration request to the user service and returns the user's token if successful.
-func (u *UserPBClient) RegisterUser(ctx context.Context, login, password string) (string, error) {
+// It sends a registration request to the user service and returns the user's tokens if successful.
+func (u *UserPBClient) RegisterUser(ctx context.Context, user model.UserRegisterRequest) (model.TokenPair, error) {
 	req := &pb.PostUserRegisterRequest{
-		Login:    login,
-		Password: password,
+		Login:                user.Login,
+		Password:             user.Password,
+		ClientSideEncryption: user.ClientSideEncryption,
+		WrappedDataKey:       user.WrappedDataKey,
 	}
 
 	resp, err := u.userService.PostRegisterUser(ctx, req)
 	if err != nil {
-		return "", err
+		return model.TokenPair{}, err
+	}
+
+	return model.TokenPair{Token: resp.Token, RefreshToken: resp.RefreshToken}, nil
+}
+
+// RefreshToken exchanges the refresh token for a new pair of access and refresh tokens of the same session.
+func (u *UserPBClient) RefreshToken(ctx context.Context, refreshToken string) (model.TokenPair, error) {
+	resp, err := u.userService.PostRefreshToken(ctx, &pb.PostRefreshTokenRequest{RefreshToken: refreshToken})
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	return model.TokenPair{Token: resp.Token, RefreshToken: resp.RefreshToken}, nil
+}
+
+// RotateKey asks the server to rotate the key of the authorized user.
+// It returns the number of records re-encrypted with the new key.
+func (u *UserPBClient) RotateKey(ctx context.Context, token string) (int, error) {
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.userService.PostRotateUserKey(ctx, &pb.PostRotateUserKeyRequest{})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(resp.Reencrypted), nil
+}
+
+// Logout asks the server to revoke the session of the token.
+func (u *UserPBClient) Logout(ctx context.Context, token string) error {
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	_, err := u.userService.PostLogout(ctx, &pb.PostLogoutRequest{})
+	return err
+}
+
+// LogoutAll asks the server to revoke all sessions of the authorized user on all devices.
+// It returns the number of revoked sessions.
+func (u *UserPBClient) LogoutAll(ctx context.Context, token string) (int, error) {
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.userService.PostLogoutAllSessions(ctx, &pb.PostLogoutAllSessionsRequest{})
+	if err != nil {
+		return 0, err
 	}
 
-	return resp.Token, nil
+	return int(resp.Revoked), nil
+}
+
+// EnrollTOTP asks the server to generate a new two-factor secret and recovery codes of the authorized user.
+func (u *UserPBClient) EnrollTOTP(ctx context.Context, token string) (model.TOTPEnrollment, error) {
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := u.userService.PostEnrollTOTP(ctx, &pb.PostEnrollTOTPRequest{})
+	if err != nil {
+		return model.TOTPEnrollment{}, err
+	}
+
+	return model.TOTPEnrollment{
+		URI:           resp.Uri,
+		Secret:        resp.Secret,
+		RecoveryCodes: resp.RecoveryCodes,
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication of the authorized user with the first code from the authenticator app.
+func (u *UserPBClient) ConfirmTOTP(ctx context.Context, token, code string) error {
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	_, err := u.userService.PostConfirmTOTP(ctx, &pb.PostConfirmTOTPRequest{Code: code})
+	return err
+}
+
+// DisableTOTP turns off two-factor authentication of the authorized user with a code or a recovery code.
+func (u *UserPBClient) DisableTOTP(ctx context.Context, token, code string) error {
+	md := metadata.New(map[string]string{"token": token})
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	_, err := u.userService.PostDisableTOTP(ctx, &pb.PostDisableTOTPRequest{Code: code})
+	return err
 }
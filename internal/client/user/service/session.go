@@ -0,0 +1,225 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/encryption"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/session"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/vault"
+)
+
+// errWrongPassword is returned when the master password doesn't unlock the keys of the saved session.
+var errWrongPassword = errors.New("wrong password")
+
+// RestoreSession restores the session saved on the previous run after the user enters the passphrase
+// of the session file and the master password, the keys are derived from the password as on login.
+// The tokens are refreshed right away, so an expired or revoked session is detected on start and the user
+// is asked to log in again. When the server is unreachable the local vault is opened.
+func (u *UserProvider) RestoreSession(ctx context.Context) {
+	if !u.sessionFile.Exists() {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Printf("Input %s of the saved session, leave empty to skip: ", yellow("'passphrase'"))
+	scanner.Scan()
+	passphrase := strings.TrimSpace(scanner.Text())
+	if len(passphrase) == 0 {
+		return
+	}
+
+	saved, err := u.sessionFile.Unlock(passphrase)
+	if errors.Is(err, session.ErrWrongPassphrase) {
+		fmt.Println(red("Wrong passphrase, the saved session is not restored"))
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read the saved session")
+		fmt.Println(red("Failed to read the saved session: ", err))
+		return
+	}
+
+	if u.e2e && len(saved.WrappedDataKey) == 0 {
+		u.clearSession()
+		fmt.Println(yellow("Your saved session has no data key, please login again"))
+		u.LoginUser(ctx)
+		return
+	}
+
+	fmt.Printf("Input %s of %s to unlock the data, leave empty to skip: ", yellow("'password'"), saved.Login)
+	scanner.Scan()
+	password := strings.TrimSpace(scanner.Text())
+	if len(password) == 0 {
+		return
+	}
+
+	dataKey, vaultKey, err := u.deriveSessionKeys(saved, password)
+	if errors.Is(err, errWrongPassword) {
+		fmt.Println(red("Wrong password, the saved session is not restored"))
+		return
+	}
+	if err != nil {
+		fmt.Println(red("Failed to derive encryption keys: ", err))
+		return
+	}
+
+	u.state.SetLogin(saved.Login)
+	u.state.SetDataKey(dataKey)
+	u.state.SetDirPath(saved.DirPath)
+	u.wrappedDataKey = saved.WrappedDataKey
+
+	tokens, err := u.userService.RefreshToken(ctx, saved.RefreshToken)
+	if vault.IsOffline(err) {
+		u.restoreOffline(saved.Login, vaultKey)
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Info("Saved session is rejected by the server")
+		u.clearSession()
+		fmt.Println(yellow("Your saved session has expired, please login again"))
+		u.LoginUser(ctx)
+		return
+	}
+
+	u.state.SetToken(tokens.Token)
+	u.state.SetRefreshToken(tokens.RefreshToken)
+	u.state.SetIsAuthorized(true)
+	// Without end-to-end encryption only an existing vault verifies the password,
+	// a new one is created on the next login when the server checks it
+	if (u.e2e || vault.Exists(vault.PathFor(u.vaultDir, saved.Login))) && u.useVault(saved.Login, vaultKey) {
+		u.sync(ctx)
+	}
+
+	// The refresh token can be used once, so the new one must be saved right away
+	u.SaveSession()
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Saved session restored"))
+}
+
+// deriveSessionKeys derives the keys of the saved session from the master password. The password is verified
+// by unlocking the wrapped data key in end-to-end encryption mode or by opening the existing local vault otherwise,
+// errWrongPassword is returned if it doesn't match.
+func (u *UserProvider) deriveSessionKeys(saved session.Saved, password string) ([]byte, []byte, error) {
+	vaultKey, err := encryption.DeriveVaultKey(saved.Login, password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("derive vault key: %w", err)
+	}
+
+	var dataKey []byte
+	if u.e2e {
+		_, kek, err := encryption.DeriveKeys(saved.Login, password)
+		if err != nil {
+			return nil, nil, fmt.Errorf("derive keys: %w", err)
+		}
+		dataKey, err = encryption.UnwrapKey(kek, saved.WrappedDataKey)
+		if err != nil {
+			return nil, nil, errWrongPassword
+		}
+		return dataKey, vaultKey, nil
+	}
+
+	path := vault.PathFor(u.vaultDir, saved.Login)
+	if vault.Exists(path) {
+		if _, err = vault.Open(path, vaultKey); err != nil {
+			return nil, nil, errWrongPassword
+		}
+	}
+
+	return dataKey, vaultKey, nil
+}
+
+// restoreOffline unlocks the local vault with the vault key when the server is unreachable.
+// The session file is kept as it is, so the session is restored online on the next run.
+func (u *UserProvider) restoreOffline(login string, vaultKey []byte) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	path := vault.PathFor(u.vaultDir, login)
+	if !vault.Exists(path) {
+		fmt.Println(red("Server is unreachable and there is no local vault for this user, please try again later"))
+		return
+	}
+
+	v, err := vault.Open(path, vaultKey)
+	if err != nil {
+		fmt.Println(red("Failed to open local vault: ", err))
+		return
+	}
+
+	u.state.SetIsAuthorized(true)
+	u.state.SetVault(v)
+	u.state.SetOffline(true)
+
+	fmt.Println(color.New(color.FgYellow).SprintFunc()("Server is unreachable, working offline with the local vault"))
+}
+
+// SaveSession writes the current session to the session file. It does nothing if the user didn't choose
+// to keep the session or works offline, when the state holds no tokens.
+func (u *UserProvider) SaveSession() {
+	if !u.sessionFile.Unlocked() || !u.state.IsAuthorized() || u.state.IsOffline() {
+		return
+	}
+
+	err := u.sessionFile.Save(session.Saved{
+		Login:          u.state.GetLogin(),
+		Token:          u.state.GetToken(),
+		RefreshToken:   u.state.GetRefreshToken(),
+		DirPath:        u.state.GetDirPath(),
+		WrappedDataKey: u.wrappedDataKey,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to save the session")
+	}
+}
+
+// ExpireSession ends the session rejected by the server. The user is asked to log in again by CheckSession.
+func (u *UserProvider) ExpireSession() {
+	u.clearSession()
+	u.expired = true
+}
+
+// CheckSession asks the user to log in again when the session has expired.
+func (u *UserProvider) CheckSession(ctx context.Context) {
+	if !u.expired {
+		return
+	}
+
+	u.expired = false
+	fmt.Println(color.New(color.FgYellow).SprintFunc()("Your session has expired, please login again"))
+	u.LoginUser(ctx)
+}
+
+// rememberSession offers to keep the session of the logged in user in the session file.
+// The passphrase is asked once, later logins are saved with the same passphrase.
+func (u *UserProvider) rememberSession(scanner *bufio.Scanner) {
+	if !u.sessionFile.Enabled() || !u.state.IsAuthorized() {
+		return
+	}
+
+	if !u.sessionFile.Unlocked() {
+		yellow := color.New(color.FgYellow).SprintFunc()
+		fmt.Printf("Input %s to keep the session on this device, leave empty to skip: ", yellow("'passphrase'"))
+		scanner.Scan()
+		passphrase := strings.TrimSpace(scanner.Text())
+		if len(passphrase) == 0 {
+			return
+		}
+
+		if err := u.sessionFile.Lock(passphrase); err != nil {
+			fmt.Println(color.New(color.FgRed).SprintFunc()("Failed to keep the session: ", err))
+			return
+		}
+	}
+
+	u.SaveSession()
+}
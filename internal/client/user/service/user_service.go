@@ -4,33 +4,64 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/encryption"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/session"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/state"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/vault"
 	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"os"
+	"strconv"
 	"strings"
 )
 
 // UserService is an interface that defines methods for user registration and login.
 // Implementations of this interface should provide the actual functionality.
 type UserService interface {
-	RegisterUser(ctx context.Context, login, password string) (string, error)
-	LoginUser(ctx context.Context, login, password string) (string, error)
+	RegisterUser(ctx context.Context, user model.UserRegisterRequest) (model.TokenPair, error)
+	LoginUser(ctx context.Context, login, password, totpCode string) (model.UserLoginResponse, error)
+	RefreshToken(ctx context.Context, refreshToken string) (model.TokenPair, error)
+	RotateKey(ctx context.Context, token string) (int, error)
+	Logout(ctx context.Context, token string) error
+	LogoutAll(ctx context.Context, token string) (int, error)
+	EnrollTOTP(ctx context.Context, token string) (model.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, token, code string) error
+	DisableTOTP(ctx context.Context, token, code string) error
+}
+
+// Syncer is an interface that defines the method for syncing the local vault with the server.
+type Syncer interface {
+	Sync(ctx context.Context, v *vault.Vault, token string) (vault.SyncResult, error)
 }
 
 // UserProvider is a struct that provides user-related functionalities.
 // It contains a reference to a UserService and a ClientState.
 type UserProvider struct {
-	userService UserService        // The user service implementation
-	state       *state.ClientState // Client state management
+	userService    UserService        // The user service implementation
+	state          *state.ClientState // Client state management
+	e2e            bool               // Client side end-to-end encryption mode
+	vaultDir       string             // Directory of local vault files
+	syncer         Syncer             // Syncer of offline writes
+	sessionFile    *session.File      // File the session is kept in between runs
+	wrappedDataKey []byte             // Data key wrapped with the key derived from the master password, saved with the session
+	expired        bool               // The session expired and the user has to log in again
 }
 
-// NewUserService initializes a new UserProvider with the given user service and client state.
+// NewUserService initializes a new UserProvider with the given user service, client state,
+// client side encryption mode, directory of local vaults, syncer of offline writes and file of the saved session.
 // It returns a pointer to the newly created UserProvider.
-func NewUserService(u UserService, state *state.ClientState) *UserProvider {
+func NewUserService(u UserService, state *state.ClientState, e2e bool, vaultDir string, syncer Syncer, sessionFile *session.File) *UserProvider {
 	return &UserProvider{
 		userService: u,
 		state:       state,
+		e2e:         e2e,
+		vaultDir:    vaultDir,
+		syncer:      syncer,
+		sessionFile: sessionFile,
 	}
 }
 
@@ -64,13 +95,45 @@ func (u *UserProvider) RegisterUser(ctx context.Context) {
 		return
 	}
 
-	token, err := u.userService.RegisterUser(ctx, login, password)
+	req := model.UserRegisterRequest{
+		Login:    login,
+		Password: password,
+	}
+
+	var dataKey []byte
+	if u.e2e {
+		authKey, kek, err := encryption.DeriveKeys(login, password)
+		if err != nil {
+			fmt.Println(red("Failed to derive encryption keys: ", err))
+			return
+		}
+		dataKey, err = encryption.GenerateDataKey()
+		if err != nil {
+			fmt.Println(red("Failed to generate data key: ", err))
+			return
+		}
+		wrappedKey, err := encryption.WrapKey(kek, dataKey)
+		if err != nil {
+			fmt.Println(red("Failed to wrap data key: ", err))
+			return
+		}
+		req.Password = authKey
+		req.ClientSideEncryption = true
+		req.WrappedDataKey = wrappedKey
+	}
+
+	tokens, err := u.userService.RegisterUser(ctx, req)
 	if err != nil {
 		lib.UnpackGRPCError(err)
 	} else {
-		u.state.SetToken(token)
+		u.state.SetToken(tokens.Token)
+		u.state.SetRefreshToken(tokens.RefreshToken)
 		u.state.SetIsAuthorized(true)
 		u.state.SetLogin(login)
+		u.state.SetDataKey(dataKey)
+		u.wrappedDataKey = req.WrappedDataKey
+		u.openVault(ctx, login, password)
+		u.rememberSession(scanner)
 	}
 }
 
@@ -104,12 +167,399 @@ func (u *UserProvider) LoginUser(ctx context.Context) {
 		return
 	}
 
-	token, err := u.userService.LoginUser(ctx, login, password)
+	err := u.Authenticate(ctx, login, password, "")
+	if isTOTPRequired(err) {
+		fmt.Printf("Input %s from the authenticator app or a recovery code: ", yellow("'two-factor code'"))
+		scanner.Scan()
+		code := strings.TrimSpace(scanner.Text())
+		if len(code) == 0 {
+			fmt.Println(red("Two-factor code must not be empty please try again"))
+			return
+		}
+		err = u.Authenticate(ctx, login, password, code)
+	}
+
+	if vault.IsOffline(err) {
+		u.loginOffline(login, password)
+		return
+	}
+
 	if err != nil {
 		lib.UnpackGRPCError(err)
-	} else {
-		u.state.SetToken(token)
-		u.state.SetIsAuthorized(true)
-		u.state.SetLogin(login)
+		return
+	}
+
+	u.openVault(ctx, login, password)
+	u.rememberSession(scanner)
+}
+
+// Authenticate logs the user in on the server with the given credentials and keeps the session in the client state.
+// In end-to-end encryption mode the password is turned into the authentication key and the data key is unlocked.
+// Unlike LoginUser it neither prompts nor prints anything and doesn't open the local vault.
+func (u *UserProvider) Authenticate(ctx context.Context, login, password, totpCode string) error {
+	secret := password
+	var kek []byte
+	if u.e2e {
+		authKey, key, err := encryption.DeriveKeys(login, password)
+		if err != nil {
+			return fmt.Errorf("derive encryption keys: %w", err)
+		}
+		secret, kek = authKey, key
+	}
+
+	resp, err := u.userService.LoginUser(ctx, login, secret, totpCode)
+	if err != nil {
+		return err
+	}
+
+	var dataKey []byte
+	if len(resp.WrappedDataKey) != 0 {
+		dataKey, err = encryption.UnwrapKey(kek, resp.WrappedDataKey)
+		if err != nil {
+			return fmt.Errorf("unlock data key: %w", err)
+		}
+	}
+
+	u.state.SetToken(resp.Token)
+	u.state.SetRefreshToken(resp.RefreshToken)
+	u.state.SetIsAuthorized(true)
+	u.state.SetLogin(login)
+	u.state.SetDataKey(dataKey)
+	u.wrappedDataKey = resp.WrappedDataKey
+
+	return nil
+}
+
+// RotateKey asks the server to rotate the encryption key of the authorized user.
+func (u *UserProvider) RotateKey(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !u.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	count, err := u.userService.RotateKey(ctx, u.state.GetToken())
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()(fmt.Sprintf("Encryption key rotated, %d records re-encrypted", count)))
+}
+
+// Sync replays the writes made offline on the server and pulls the changes made on other devices.
+func (u *UserProvider) Sync(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !u.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	if u.state.IsOffline() {
+		fmt.Println(red("You are working offline, please use 'login' again when the server is reachable"))
+		return
+	}
+
+	if u.state.GetVault() == nil {
+		fmt.Println(red("Local vault is not opened"))
+		return
+	}
+
+	u.sync(ctx)
+}
+
+// RejectedChanges lists the offline writes rejected by the server on sync and lets the user retry
+// or discard them. Retried writes are synced right away when the server is reachable.
+func (u *UserProvider) RejectedChanges(ctx context.Context) {
+	scanner := bufio.NewScanner(os.Stdin)
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	v := u.state.GetVault()
+	if v == nil {
+		fmt.Println(red("Local vault is not opened"))
+		return
+	}
+
+	rejected := v.Rejected()
+	if len(rejected) == 0 {
+		fmt.Println("No rejected offline changes")
+		return
+	}
+
+	for i, op := range rejected {
+		fmt.Printf("[%d] %s %s %s at %s: %s\n", i+1, op.Kind, op.DataType, op.RecordID, op.RejectedAt, red(op.Reason))
+	}
+
+	fmt.Printf("Input %s or %s, leave empty to keep them: ", yellow("'retry <number|all>'"), yellow("'discard <number|all>'"))
+	scanner.Scan()
+	action, target, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+	if action == "" {
+		return
+	}
+
+	ops := rejected
+	if target != "all" {
+		n, err := strconv.Atoi(target)
+		if err != nil || n < 1 || n > len(rejected) {
+			fmt.Println(red("Unknown change number, please try again"))
+			return
+		}
+		ops = rejected[n-1 : n]
+	}
+
+	apply := v.Retry
+	switch action {
+	case "retry":
+	case "discard":
+		apply = v.Discard
+	default:
+		fmt.Println(red("Unknown action, please try again"))
+		return
+	}
+
+	for _, op := range ops {
+		if err := apply(op.ID); err != nil {
+			fmt.Println(red(fmt.Sprintf("Failed to %s the change: ", action), err))
+			return
+		}
+	}
+
+	if action == "retry" && u.state.IsAuthorized() && !u.state.IsOffline() {
+		u.sync(ctx)
+		return
+	}
+	fmt.Println(color.New(color.FgGreen).SprintFunc()(fmt.Sprintf("%d rejected changes processed", len(ops))))
+}
+
+// Logout ends the session of the user on this device. The session is revoked on the server when it is reachable.
+func (u *UserProvider) Logout(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !u.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	if !u.state.IsOffline() {
+		if err := u.userService.Logout(ctx, u.state.GetToken()); err != nil {
+			fmt.Println(red("Failed to revoke the session on the server, it expires on its own"))
+			lib.UnpackGRPCError(err)
+		}
+	}
+
+	u.clearSession()
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("You are logged out"))
+}
+
+// LogoutAll revokes the sessions of the user on all devices, including this one.
+func (u *UserProvider) LogoutAll(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !u.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	if u.state.IsOffline() {
+		fmt.Println(red("You are working offline, please use 'login' again when the server is reachable"))
+		return
+	}
+
+	count, err := u.userService.LogoutAll(ctx, u.state.GetToken())
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	u.clearSession()
+	fmt.Println(color.New(color.FgGreen).SprintFunc()(fmt.Sprintf("%d sessions revoked on all devices, you are logged out", count)))
+}
+
+// EnableTOTP enrolls two-factor authentication of the authorized user. It shows the secret to add
+// to an authenticator app together with the recovery codes and enables the second factor once
+// the user enters the first code generated by the app.
+func (u *UserProvider) EnableTOTP(ctx context.Context) {
+	scanner := bufio.NewScanner(os.Stdin)
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	if !u.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
 	}
+
+	if u.state.IsOffline() {
+		fmt.Println(red("You are working offline, please use 'login' again when the server is reachable"))
+		return
+	}
+
+	enrollment, err := u.userService.EnrollTOTP(ctx, u.state.GetToken())
+	if err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	cyanBold := color.New(color.FgCyan, color.Bold).SprintFunc()
+	fmt.Println(cyanBold("Add the account to your authenticator app with the URI or the secret:"))
+	fmt.Println("URI:    ", enrollment.URI)
+	fmt.Println("Secret: ", enrollment.Secret)
+	fmt.Println(cyanBold("Save the recovery codes, each of them logs you in once without the app:"))
+	for _, code := range enrollment.RecoveryCodes {
+		fmt.Println(code)
+	}
+
+	fmt.Printf("Input %s from the authenticator app to confirm: ", yellow("'two-factor code'"))
+	scanner.Scan()
+	code := strings.TrimSpace(scanner.Text())
+	if len(code) == 0 {
+		fmt.Println(red("Two-factor code must not be empty, two-factor authentication is not enabled"))
+		return
+	}
+
+	if err = u.userService.ConfirmTOTP(ctx, u.state.GetToken(), code); err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Two-factor authentication enabled"))
+}
+
+// DisableTOTP turns off two-factor authentication of the authorized user with a code or a recovery code.
+func (u *UserProvider) DisableTOTP(ctx context.Context) {
+	scanner := bufio.NewScanner(os.Stdin)
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	if !u.state.IsAuthorized() {
+		fmt.Println(red("You are not authorized, please use 'login' or 'register'"))
+		return
+	}
+
+	if u.state.IsOffline() {
+		fmt.Println(red("You are working offline, please use 'login' again when the server is reachable"))
+		return
+	}
+
+	fmt.Printf("Input %s from the authenticator app or a recovery code: ", yellow("'two-factor code'"))
+	scanner.Scan()
+	code := strings.TrimSpace(scanner.Text())
+	if len(code) == 0 {
+		fmt.Println(red("Two-factor code must not be empty please try again"))
+		return
+	}
+
+	if err := u.userService.DisableTOTP(ctx, u.state.GetToken(), code); err != nil {
+		lib.UnpackGRPCError(err)
+		return
+	}
+
+	fmt.Println(color.New(color.FgGreen).SprintFunc()("Two-factor authentication disabled"))
+}
+
+// clearSession forgets the tokens, keys and local vault of the logged out user and removes the saved session.
+func (u *UserProvider) clearSession() {
+	if err := u.sessionFile.Remove(); err != nil {
+		logrus.WithError(err).Error("Failed to remove the saved session")
+	}
+
+	u.wrappedDataKey = nil
+	u.state.SetToken("")
+	u.state.SetRefreshToken("")
+	u.state.SetIsAuthorized(false)
+	u.state.SetLogin("")
+	u.state.SetDataKey(nil)
+	u.state.SetVault(nil)
+	u.state.SetOffline(false)
+}
+
+// openVault opens the local vault of the user with the key derived from the master password
+// and syncs the writes made offline. The client keeps working online if the vault can't be opened.
+func (u *UserProvider) openVault(ctx context.Context, login, password string) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	key, err := encryption.DeriveVaultKey(login, password)
+	if err != nil {
+		fmt.Println(red("Failed to derive vault key: ", err))
+		return
+	}
+
+	if u.useVault(login, key) {
+		u.sync(ctx)
+	}
+}
+
+// useVault opens the local vault of the user with the vault key and reports whether it is opened.
+func (u *UserProvider) useVault(login string, key []byte) bool {
+	v, err := vault.Open(vault.PathFor(u.vaultDir, login), key)
+	if err != nil {
+		fmt.Println(color.New(color.FgRed).SprintFunc()("Failed to open local vault, offline mode is unavailable: ", err))
+		return false
+	}
+
+	u.state.SetVault(v)
+	u.state.SetOffline(false)
+	return true
+}
+
+// loginOffline unlocks the local vault of the user when the server is unreachable.
+// The master password is verified by decrypting the vault.
+func (u *UserProvider) loginOffline(login, password string) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	path := vault.PathFor(u.vaultDir, login)
+	if !vault.Exists(path) {
+		fmt.Println(red("Server is unreachable and there is no local vault for this user, please try again later"))
+		return
+	}
+
+	key, err := encryption.DeriveVaultKey(login, password)
+	if err != nil {
+		fmt.Println(red("Failed to derive vault key: ", err))
+		return
+	}
+
+	v, err := vault.Open(path, key)
+	if err != nil {
+		fmt.Println(red("Wrong login or password, please try again"))
+		return
+	}
+
+	u.state.SetToken("")
+	u.state.SetRefreshToken("")
+	u.state.SetIsAuthorized(true)
+	u.state.SetLogin(login)
+	u.state.SetDataKey(nil)
+	u.state.SetVault(v)
+	u.state.SetOffline(true)
+
+	fmt.Println(color.New(color.FgYellow).SprintFunc()("Server is unreachable, working offline with the local vault"))
+}
+
+// sync replays the writes made offline, pulls the changes made on other devices and prints the result.
+func (u *UserProvider) sync(ctx context.Context) {
+	red := color.New(color.FgRed).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+
+	result, err := u.syncer.Sync(ctx, u.state.GetVault(), u.state.GetToken())
+	if result.Synced != 0 {
+		fmt.Println(green(fmt.Sprintf("%d offline changes synced", result.Synced)))
+	}
+	if result.Pulled != 0 {
+		fmt.Println(green(fmt.Sprintf("%d changes from other devices applied", result.Pulled)))
+	}
+	if result.Failed != 0 {
+		fmt.Println(red(fmt.Sprintf("%d offline changes were rejected by the server, use 'rejected offline changes' to retry or discard them", result.Failed)))
+	}
+	if err != nil {
+		fmt.Println(red("Sync is not finished, please try again: ", err))
+	}
+}
+
+// isTOTPRequired reports whether the login was rejected because the two-factor code is missing.
+func isTOTPRequired(err error) bool {
+	st, ok := status.FromError(err)
+	return ok && st.Code() == codes.FailedPrecondition
 }
@@ -0,0 +1,157 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+const changesPageSize = 100 // Number of changes loaded from the server at once
+
+// Replayer replays an offline write of one data type on the server.
+// For a save operation it returns the ID assigned to the record by the server.
+// Fetch loads the record from the server into the vault.
+type Replayer interface {
+	Replay(ctx context.Context, token string, op Operation) (string, error)
+	Fetch(ctx context.Context, token string, id string) error
+}
+
+// ChangeLoader loads the change feed of the user from the server.
+type ChangeLoader interface {
+	LoadChangesSince(ctx context.Context, token string, cursor int64, limit int) ([]model.Change, int64, bool, error)
+}
+
+// SyncResult holds the outcome of a sync run.
+type SyncResult struct {
+	Synced  int // Number of operations replayed on the server
+	Failed  int // Number of operations rejected by the server and moved to the rejected operations of the vault
+	Pending int // Number of operations left in the queue
+	Pulled  int // Number of changes made on other devices applied to the vault
+}
+
+// Syncer replays the queued offline writes on the server and pulls the changes made on other devices.
+type Syncer struct {
+	replayers map[string]Replayer // Replayers by data type
+	changes   ChangeLoader        // Loader of the server change feed
+}
+
+// NewSyncer creates a new Syncer with replayers for every data type and the loader of the change feed.
+func NewSyncer(replayers map[string]Replayer, changes ChangeLoader) *Syncer {
+	return &Syncer{
+		replayers: replayers,
+		changes:   changes,
+	}
+}
+
+// Sync replays the queued operations in order and then pulls the changes made on other devices.
+// It stops at the first operation that fails because the server is unreachable, so the remaining
+// operations are kept for the next run. Operations rejected by the server are moved to the rejected operations
+// of the vault, where the user retries or discards them.
+func (s *Syncer) Sync(ctx context.Context, v *Vault, token string) (SyncResult, error) {
+	var result SyncResult
+
+	// The queue is re-read after every operation, since a replayed save remaps the IDs of the following ones
+	for pending := v.Pending(); len(pending) != 0; pending = v.Pending() {
+		op := pending[0]
+		replayer, ok := s.replayers[op.DataType]
+		if !ok {
+			return result, fmt.Errorf("no replayer for data type %s", op.DataType)
+		}
+
+		id, err := replayer.Replay(ctx, token, op)
+		if IsOffline(err) {
+			result.Pending = len(pending)
+			return result, fmt.Errorf("replay %s %s: %w", op.Kind, op.DataType, err)
+		}
+
+		if err != nil {
+			logrus.WithError(err).Errorf("Unable to sync %s of %s %s, operation rejected", op.Kind, op.DataType, op.RecordID)
+			result.Failed++
+			if err = v.Reject(op.ID, status.Convert(err).Message()); err != nil {
+				return result, fmt.Errorf("reject %s: %w", op.ID, err)
+			}
+			continue
+		}
+		result.Synced++
+
+		if op.Kind == OpSave && id != "" && id != op.RecordID {
+			if err = v.Remap(op.RecordID, id); err != nil {
+				return result, fmt.Errorf("remap %s: %w", op.RecordID, err)
+			}
+		}
+
+		if err = v.Done(op.ID); err != nil {
+			return result, fmt.Errorf("done %s: %w", op.ID, err)
+		}
+	}
+
+	pulled, err := s.pull(ctx, v, token)
+	result.Pulled = pulled
+	if err != nil {
+		return result, fmt.Errorf("pull changes: %w", err)
+	}
+
+	return result, nil
+}
+
+// pull applies the server changes made after the vault cursor and loads again the outdated payloads,
+// so the records available offline stay up to date. It returns the number of applied changes.
+func (s *Syncer) pull(ctx context.Context, v *Vault, token string) (int, error) {
+	pulled := 0
+	for hasMore := true; hasMore; {
+		var changes []model.Change
+		var cursor int64
+		var err error
+		changes, cursor, hasMore, err = s.changes.LoadChangesSince(ctx, token, v.Cursor(), changesPageSize)
+		if err != nil {
+			return pulled, err
+		}
+
+		stale, err := v.ApplyChanges(changes, cursor)
+		if err != nil {
+			return pulled, err
+		}
+		pulled += len(changes)
+
+		for _, r := range stale {
+			replayer, ok := s.replayers[r.DataType]
+			if !ok {
+				continue
+			}
+			if err = replayer.Fetch(ctx, token, r.ID); err != nil {
+				if IsOffline(err) {
+					return pulled, err
+				}
+				logrus.WithError(err).Errorf("Unable to load changed %s %s", r.DataType, r.ID)
+			}
+		}
+	}
+
+	return pulled, nil
+}
+
+// IsOffline reports whether the error means the server could not be reached.
+func IsOffline(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+
+	return st.Code() == codes.Unavailable || st.Code() == codes.DeadlineExceeded
+}
+
+// IsConflict reports whether the error means the record was changed on another device
+// since its revision was read.
+func IsConflict(err error) bool {
+	st, ok := status.FromError(err)
+	return ok && st.Code() == codes.Aborted
+}
@@ -0,0 +1,622 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/encryption"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+const (
+	dirPerm       = 0o700    // Permission for the vault directory
+	filePerm      = 0o600    // Permission for the vault file
+	fileExtension = ".vault" // Extension of vault files
+	localIDPrefix = "local-" // Prefix of IDs of records created offline and not yet synced
+
+	localPageTokenPrefix = "local:" // Prefix of page tokens of listings served from the vault
+	defaultPageSize      = 50       // Number of records in a page when the page size is not set
+)
+
+// Kinds of queued operations.
+const (
+	OpSave   = "save"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+var (
+	// ErrNotAvailableOffline is returned when a record has not been loaded while online.
+	ErrNotAvailableOffline = errors.New("data is not available offline, load it once while online")
+	// ErrInvalidPageToken is returned when a page token was not issued by the vault, e.g. by the server
+	// before the client went offline.
+	ErrInvalidPageToken = errors.New("invalid page token, please start the listing again")
+	// ErrOperationNotFound is returned when a rejected operation to retry or discard is not in the vault.
+	ErrOperationNotFound = errors.New("rejected operation not found")
+)
+
+// ConflictPolicy defines how an update rejected because the record was changed on another device is resolved.
+type ConflictPolicy string
+
+// Conflict resolution policies.
+const (
+	LastWriterWins ConflictPolicy = "last-writer-wins" // The local version overwrites the server one
+	KeepBoth       ConflictPolicy = "keep-both"        // The local version is saved as a copy next to the server one
+)
+
+// ConflictCopySuffix is appended to the metadata of a local version saved as a copy by the KeepBoth policy.
+const ConflictCopySuffix = " (conflict copy)"
+
+// ParseConflictPolicy returns the conflict policy with the given name, KeepBoth for an empty name.
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(name); policy {
+	case "":
+		return KeepBoth, nil
+	case LastWriterWins, KeepBoth:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q", name)
+	}
+}
+
+// Record is a local copy of a user record. Payload holds the decrypted client model as JSON,
+// it is empty until the record has been loaded at least once.
+type Record struct {
+	ID        string          `json:"id"`
+	DataType  string          `json:"data_type"`
+	MetaData  string          `json:"meta_data"`
+	CreatedAt string          `json:"created_at"`
+	UpdatedAt string          `json:"updated_at,omitempty"`
+	Revision  int64           `json:"revision,omitempty"` // Server revision of the record, 0 until it is synced
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+// Operation is a write made offline and waiting to be replayed on the server.
+// Payload holds the request model as JSON.
+type Operation struct {
+	ID       string          `json:"id"`
+	Kind     string          `json:"kind"`
+	DataType string          `json:"data_type"`
+	RecordID string          `json:"record_id"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+}
+
+// RejectedOperation is an offline write rejected by the server on sync. It is kept until the user
+// retries or discards it, so the local change is not lost.
+type RejectedOperation struct {
+	Operation
+	Reason     string `json:"reason"`      // Error returned by the server
+	RejectedAt string `json:"rejected_at"` // Time of the rejection
+}
+
+// content is the decrypted content of the vault file.
+type content struct {
+	Records  map[string]Record   `json:"records"`
+	Queue    []Operation         `json:"queue"`
+	Rejected []RejectedOperation `json:"rejected,omitempty"`
+	Cursor   int64               `json:"cursor"` // Position in the server change feed the records are synced up to
+}
+
+// Vault is a local encrypted mirror of the user records with a queue of offline writes.
+// The whole vault is kept in memory and written to disk encrypted with the vault key after every change.
+type Vault struct {
+	mu      sync.Mutex
+	path    string  // Path to the vault file
+	key     []byte  // Key the vault file is encrypted with
+	content content // Decrypted vault content
+}
+
+// PathFor returns the path of the vault file of the given user in dir.
+func PathFor(dir, login string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(login)))
+	return filepath.Join(dir, hex.EncodeToString(sum[:16])+fileExtension)
+}
+
+// Exists reports whether the vault file exists.
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Open decrypts and loads the vault file at path. A missing file results in an empty vault,
+// which is created on the first change. A wrong key results in an error.
+func Open(path string, key []byte) (*Vault, error) {
+	v := &Vault{
+		path:    path,
+		key:     key,
+		content: content{Records: make(map[string]Record)},
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read vault: %w", err)
+	}
+
+	dec, err := encryption.Decrypt(key, data)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt vault: %w", err)
+	}
+
+	if err = json.Unmarshal(dec, &v.content); err != nil {
+		return nil, fmt.Errorf("unmarshal vault: %w", err)
+	}
+	if v.content.Records == nil {
+		v.content.Records = make(map[string]Record)
+	}
+
+	return v, nil
+}
+
+// NewLocalID returns an ID for a record created offline.
+func NewLocalID() string {
+	return localIDPrefix + uuid.NewString()
+}
+
+// IsLocalID reports whether the ID belongs to a record that has not been synced yet.
+func IsLocalID(id string) bool {
+	return strings.HasPrefix(id, localIDPrefix)
+}
+
+// Record returns the local copy of a record.
+func (v *Vault) Record(dataType, id string) (Record, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	r, ok := v.content.Records[id]
+	if !ok || r.DataType != dataType {
+		return Record{}, false
+	}
+	return r, true
+}
+
+// Infos returns the info of all local records of the given type ordered by creation time.
+func (v *Vault) Infos(dataType string) []model.DataInfo {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	infos := make([]model.DataInfo, 0)
+	for _, r := range v.content.Records {
+		if r.DataType != dataType {
+			continue
+		}
+		infos = append(infos, model.DataInfo{
+			ID:        r.ID,
+			DataType:  r.DataType,
+			MetaData:  r.MetaData,
+			CreatedAt: r.CreatedAt,
+			Revision:  r.Revision,
+			UpdatedAt: r.UpdatedAt,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].CreatedAt < infos[j].CreatedAt
+	})
+
+	return infos
+}
+
+// Put stores the local copy of a record. An empty payload and a zero revision keep the already stored ones,
+// records created offline get the current time as their creation time.
+func (v *Vault) Put(r Record) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if old, ok := v.content.Records[r.ID]; ok {
+		if len(r.Payload) == 0 {
+			r.Payload = old.Payload
+		}
+		if r.CreatedAt == "" {
+			r.CreatedAt = old.CreatedAt
+		}
+		if r.UpdatedAt == "" {
+			r.UpdatedAt = old.UpdatedAt
+		}
+		if r.Revision == 0 {
+			r.Revision = old.Revision
+		}
+	} else if r.CreatedAt == "" && IsLocalID(r.ID) {
+		r.CreatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	v.content.Records[r.ID] = r
+
+	return v.flush()
+}
+
+// Delete removes the local copy of a record.
+func (v *Vault) Delete(dataType, id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if r, ok := v.content.Records[id]; ok && r.DataType == dataType {
+		delete(v.content.Records, id)
+	}
+
+	return v.flush()
+}
+
+// MergeInfos makes the local records of the given type match the list loaded from the server.
+// Payloads of known records are kept unless the record has a newer revision on the server,
+// records missing on the server are removed unless they were created offline and are waiting for sync.
+// Records with writes waiting for sync are left as they are.
+func (v *Vault) MergeInfos(dataType string, infos []model.DataInfo) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.updateInfosLocked(dataType, infos)
+
+	remote := make(map[string]struct{}, len(infos))
+	for _, info := range infos {
+		remote[info.ID] = struct{}{}
+	}
+
+	for id, r := range v.content.Records {
+		if _, ok := remote[id]; ok || r.DataType != dataType || IsLocalID(id) {
+			continue
+		}
+		delete(v.content.Records, id)
+	}
+
+	return v.flush()
+}
+
+// UpdateInfos updates the local records of the given type with a page of the list loaded from the server.
+// Unlike MergeInfos it keeps the records missing from the page, deletions are picked up from the change feed.
+func (v *Vault) UpdateInfos(dataType string, infos []model.DataInfo) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.updateInfosLocked(dataType, infos)
+
+	return v.flush()
+}
+
+// InfoPage returns a page of the infos of the local records of the given type, sorted as requested.
+// Page tokens issued by the vault are positions in the sorted list.
+func (v *Vault) InfoPage(dataType string, page model.PageRequest) (model.DataInfoPage, error) {
+	offset := 0
+	if page.PageToken != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(page.PageToken, localPageTokenPrefix))
+		if err != nil || n < 0 || !strings.HasPrefix(page.PageToken, localPageTokenPrefix) {
+			return model.DataInfoPage{}, ErrInvalidPageToken
+		}
+		offset = n
+	}
+
+	if page.PageSize <= 0 {
+		page.PageSize = defaultPageSize
+	}
+
+	infos := v.Infos(dataType)
+	sort.SliceStable(infos, func(i, j int) bool {
+		a, b := infos[i], infos[j]
+		if page.Descending {
+			a, b = b, a
+		}
+		ka, kb := a.CreatedAt, b.CreatedAt
+		if page.SortBy == model.SortByMetadata {
+			ka, kb = a.MetaData, b.MetaData
+		}
+		if ka != kb {
+			return ka < kb
+		}
+		return a.ID < b.ID
+	})
+
+	if offset > len(infos) {
+		offset = len(infos)
+	}
+	end := offset + page.PageSize
+	if end >= len(infos) {
+		return model.DataInfoPage{Infos: infos[offset:]}, nil
+	}
+
+	return model.DataInfoPage{
+		Infos:         infos[offset:end],
+		NextPageToken: localPageTokenPrefix + strconv.Itoa(end),
+	}, nil
+}
+
+// updateInfosLocked updates the info of the local records of the given type from the server.
+// Payloads of known records are kept unless the record has a newer revision on the server.
+// Records with writes waiting for sync are left as they are.
+func (v *Vault) updateInfosLocked(dataType string, infos []model.DataInfo) {
+	for _, info := range infos {
+		if v.pendingLocked(info.ID) {
+			continue
+		}
+		r := v.content.Records[info.ID]
+		if r.Revision != 0 && r.Revision != info.Revision {
+			r.Payload = nil
+		}
+		r.ID, r.DataType, r.MetaData, r.CreatedAt = info.ID, dataType, info.MetaData, info.CreatedAt
+		r.UpdatedAt, r.Revision = info.UpdatedAt, info.Revision
+		v.content.Records[info.ID] = r
+	}
+}
+
+// PutPayload stores the local copy of a record with the given client model as its payload.
+// A zero revision keeps the already stored one.
+func (v *Vault) PutPayload(dataType, id, metaData string, revision int64, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	return v.Put(Record{ID: id, DataType: dataType, MetaData: metaData, Revision: revision, Payload: data})
+}
+
+// Revision returns the server revision of the local copy of a record, 0 if it is unknown.
+func (v *Vault) Revision(dataType, id string) int64 {
+	r, ok := v.Record(dataType, id)
+	if !ok {
+		return 0
+	}
+	return r.Revision
+}
+
+// Cursor returns the position in the server change feed the vault is synced up to.
+func (v *Vault) Cursor() int64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.content.Cursor
+}
+
+// ApplyChanges applies the changes loaded from the server change feed and moves the cursor.
+// Deleted records are removed, info of changed records is updated. It returns the records
+// which payload is outdated by the change and has to be loaded again.
+func (v *Vault) ApplyChanges(changes []model.Change, cursor int64) ([]Record, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var stale []Record
+	for _, c := range changes {
+		if v.pendingLocked(c.ID) {
+			continue
+		}
+
+		if c.Deleted {
+			delete(v.content.Records, c.ID)
+			continue
+		}
+
+		r, ok := v.content.Records[c.ID]
+		if ok && len(r.Payload) != 0 && r.Revision != 0 && r.Revision != c.Revision {
+			r.Payload = nil
+			stale = append(stale, Record{ID: c.ID, DataType: c.DataType})
+		}
+		r.ID, r.DataType, r.MetaData, r.CreatedAt = c.ID, c.DataType, c.MetaData, c.CreatedAt
+		r.UpdatedAt, r.Revision = c.UpdatedAt, c.Revision
+		v.content.Records[c.ID] = r
+	}
+	v.content.Cursor = cursor
+
+	return stale, v.flush()
+}
+
+// Payload unmarshals the payload of the local copy of a record into dst.
+func (v *Vault) Payload(dataType, id string, dst any) error {
+	r, ok := v.Record(dataType, id)
+	if !ok || len(r.Payload) == 0 {
+		return ErrNotAvailableOffline
+	}
+
+	if err := json.Unmarshal(r.Payload, dst); err != nil {
+		return fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueuePayload adds an offline write with the given request model as its payload to the sync queue.
+func (v *Vault) EnqueuePayload(kind, dataType, recordID string, payload any) error {
+	var data []byte
+	if payload != nil {
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("marshal payload: %w", err)
+		}
+	}
+
+	return v.Enqueue(Operation{Kind: kind, DataType: dataType, RecordID: recordID, Payload: data})
+}
+
+// Enqueue adds an offline write to the sync queue.
+func (v *Vault) Enqueue(op Operation) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if op.ID == "" {
+		op.ID = uuid.NewString()
+	}
+	v.content.Queue = append(v.content.Queue, op)
+
+	return v.flush()
+}
+
+// Pending returns a copy of the sync queue in the order the writes were made.
+func (v *Vault) Pending() []Operation {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return append([]Operation(nil), v.content.Queue...)
+}
+
+// Done removes a replayed operation from the sync queue.
+func (v *Vault) Done(opID string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for i, op := range v.content.Queue {
+		if op.ID == opID {
+			v.content.Queue = append(v.content.Queue[:i], v.content.Queue[i+1:]...)
+			break
+		}
+	}
+
+	return v.flush()
+}
+
+// Reject moves an operation rejected by the server from the sync queue to the rejected operations.
+func (v *Vault) Reject(opID, reason string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for i, op := range v.content.Queue {
+		if op.ID == opID {
+			v.content.Queue = append(v.content.Queue[:i], v.content.Queue[i+1:]...)
+			v.content.Rejected = append(v.content.Rejected, RejectedOperation{
+				Operation:  op,
+				Reason:     reason,
+				RejectedAt: time.Now().UTC().Format(time.RFC3339Nano),
+			})
+			break
+		}
+	}
+
+	return v.flush()
+}
+
+// Rejected returns a copy of the operations rejected by the server in the order they were rejected.
+func (v *Vault) Rejected() []RejectedOperation {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return append([]RejectedOperation(nil), v.content.Rejected...)
+}
+
+// Retry moves a rejected operation back to the end of the sync queue, it is replayed on the next sync.
+func (v *Vault) Retry(opID string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	op, ok := v.takeRejectedLocked(opID)
+	if !ok {
+		return ErrOperationNotFound
+	}
+	v.content.Queue = append(v.content.Queue, op.Operation)
+
+	return v.flush()
+}
+
+// Discard drops a rejected operation. Once the record has no other writes waiting, the local change
+// is dropped too: a record created offline is removed, the payload of another one is loaded again from the server.
+func (v *Vault) Discard(opID string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	op, ok := v.takeRejectedLocked(opID)
+	if !ok {
+		return ErrOperationNotFound
+	}
+
+	if r, ok := v.content.Records[op.RecordID]; ok && !v.pendingLocked(op.RecordID) {
+		if IsLocalID(op.RecordID) {
+			delete(v.content.Records, op.RecordID)
+		} else {
+			r.Payload = nil
+			v.content.Records[op.RecordID] = r
+		}
+	}
+
+	return v.flush()
+}
+
+// takeRejectedLocked removes a rejected operation and returns it.
+// It must be called with the mutex held.
+func (v *Vault) takeRejectedLocked(opID string) (RejectedOperation, bool) {
+	for i, op := range v.content.Rejected {
+		if op.ID == opID {
+			v.content.Rejected = append(v.content.Rejected[:i], v.content.Rejected[i+1:]...)
+			return op, true
+		}
+	}
+	return RejectedOperation{}, false
+}
+
+// Remap replaces the local ID of a record created offline with the ID assigned by the server,
+// both in the records and in the operations still waiting in the queue or rejected.
+func (v *Vault) Remap(localID, id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if r, ok := v.content.Records[localID]; ok {
+		delete(v.content.Records, localID)
+		r.ID = id
+		v.content.Records[id] = r
+	}
+
+	for i := range v.content.Queue {
+		if v.content.Queue[i].RecordID == localID {
+			v.content.Queue[i].RecordID = id
+		}
+	}
+	for i := range v.content.Rejected {
+		if v.content.Rejected[i].RecordID == localID {
+			v.content.Rejected[i].RecordID = id
+		}
+	}
+
+	return v.flush()
+}
+
+// pendingLocked reports whether the record has writes waiting in the sync queue or rejected ones,
+// the local copy keeps the change then. It must be called with the mutex held.
+func (v *Vault) pendingLocked(id string) bool {
+	for _, op := range v.content.Queue {
+		if op.RecordID == id {
+			return true
+		}
+	}
+	for _, op := range v.content.Rejected {
+		if op.RecordID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// flush encrypts the vault content and atomically replaces the vault file.
+// It must be called with the mutex held.
+func (v *Vault) flush() error {
+	data, err := json.Marshal(v.content)
+	if err != nil {
+		return fmt.Errorf("marshal vault: %w", err)
+	}
+
+	cryptData, err := encryption.Encrypt(v.key, data)
+	if err != nil {
+		return fmt.Errorf("encrypt vault: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(v.path), dirPerm); err != nil {
+		return fmt.Errorf("create vault directory: %w", err)
+	}
+
+	tmp := v.path + ".tmp"
+	if err = os.WriteFile(tmp, cryptData, filePerm); err != nil {
+		return fmt.Errorf("write vault: %w", err)
+	}
+
+	if err = os.Rename(tmp, v.path); err != nil {
+		return fmt.Errorf("replace vault: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,328 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/encryption"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/client/model"
+)
+
+type replayer struct {
+	replayed []Operation
+	fetched  []string
+	err      error
+}
+
+func (r *replayer) Replay(_ context.Context, _ string, op Operation) (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	r.replayed = append(r.replayed, op)
+	if op.Kind == OpSave {
+		return "server-id", nil
+	}
+	return op.RecordID, nil
+}
+
+func (r *replayer) Fetch(_ context.Context, _ string, id string) error {
+	r.fetched = append(r.fetched, id)
+	return r.err
+}
+
+type changeLoader struct {
+	pages  [][]model.Change
+	cursor int64
+}
+
+func (c *changeLoader) LoadChangesSince(_ context.Context, _ string, cursor int64, _ int) ([]model.Change, int64, bool, error) {
+	c.cursor = cursor
+	if len(c.pages) == 0 {
+		return nil, cursor, false, nil
+	}
+	page := c.pages[0]
+	c.pages = c.pages[1:]
+	return page, cursor + int64(len(page)), len(c.pages) != 0, nil
+}
+
+type VaultTestSuite struct {
+	suite.Suite
+	path  string
+	key   []byte
+	vault *Vault
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(VaultTestSuite))
+}
+
+func (v *VaultTestSuite) SetupTest() {
+	key, err := encryption.GenerateDataKey()
+	require.NoError(v.T(), err)
+	v.key = key
+	v.path = PathFor(filepath.Join(v.T().TempDir(), "vault"), "user@mail.ru")
+
+	v.vault, err = Open(v.path, v.key)
+	require.NoError(v.T(), err)
+}
+
+func (v *VaultTestSuite) Test_PersistAndReopen() {
+	card := model.CreditCard{ID: "id", Number: "4111111111111111", MetaData: "bank"}
+	assert.NoError(v.T(), v.vault.PutPayload("credit_card", card.ID, card.MetaData, 0, card))
+	assert.True(v.T(), Exists(v.path))
+
+	reopened, err := Open(v.path, v.key)
+	require.NoError(v.T(), err)
+
+	var loaded model.CreditCard
+	assert.NoError(v.T(), reopened.Payload("credit_card", "id", &loaded))
+	assert.Equal(v.T(), card, loaded)
+
+	assert.ErrorIs(v.T(), reopened.Payload("text_data", "id", &loaded), ErrNotAvailableOffline)
+
+	otherKey, err := encryption.GenerateDataKey()
+	require.NoError(v.T(), err)
+	_, err = Open(v.path, otherKey)
+	assert.Error(v.T(), err)
+}
+
+func (v *VaultTestSuite) Test_MergeInfos() {
+	localID := NewLocalID()
+	require.NoError(v.T(), v.vault.PutPayload("text_data", "kept", "kept", 1, model.TextData{Text: "kept"}))
+	require.NoError(v.T(), v.vault.PutPayload("text_data", "removed", "removed", 1, model.TextData{Text: "removed"}))
+	require.NoError(v.T(), v.vault.PutPayload("text_data", localID, "local", 0, model.TextData{Text: "local"}))
+	require.NoError(v.T(), v.vault.PutPayload("text_data", "changed", "changed", 1, model.TextData{Text: "changed"}))
+
+	err := v.vault.MergeInfos("text_data", []model.DataInfo{
+		{ID: "kept", MetaData: "kept", CreatedAt: "2024-01-01", Revision: 1},
+		{ID: "changed", MetaData: "changed", CreatedAt: "2024-01-01", Revision: 2},
+		{ID: "new", MetaData: "new", CreatedAt: "2024-01-02", Revision: 1},
+	})
+	assert.NoError(v.T(), err)
+
+	infos := v.vault.Infos("text_data")
+	ids := make([]string, 0, len(infos))
+	for _, info := range infos {
+		ids = append(ids, info.ID)
+	}
+	assert.ElementsMatch(v.T(), []string{"kept", "changed", "new", localID}, ids)
+
+	var text model.TextData
+	assert.NoError(v.T(), v.vault.Payload("text_data", "kept", &text))
+	assert.Equal(v.T(), "kept", text.Text)
+	assert.ErrorIs(v.T(), v.vault.Payload("text_data", "new", &text), ErrNotAvailableOffline)
+	assert.ErrorIs(v.T(), v.vault.Payload("text_data", "changed", &text), ErrNotAvailableOffline)
+	assert.Equal(v.T(), int64(2), v.vault.Revision("text_data", "changed"))
+}
+
+func (v *VaultTestSuite) Test_UpdateInfos() {
+	require.NoError(v.T(), v.vault.PutPayload("text_data", "kept", "kept", 1, model.TextData{Text: "kept"}))
+	require.NoError(v.T(), v.vault.PutPayload("text_data", "changed", "changed", 1, model.TextData{Text: "changed"}))
+
+	err := v.vault.UpdateInfos("text_data", []model.DataInfo{
+		{ID: "changed", MetaData: "changed", CreatedAt: "2024-01-01", Revision: 2},
+	})
+	assert.NoError(v.T(), err)
+
+	// A page of the server list doesn't remove the records of other pages
+	_, ok := v.vault.Record("text_data", "kept")
+	assert.True(v.T(), ok)
+
+	var text model.TextData
+	assert.ErrorIs(v.T(), v.vault.Payload("text_data", "changed", &text), ErrNotAvailableOffline)
+	assert.Equal(v.T(), int64(2), v.vault.Revision("text_data", "changed"))
+}
+
+func (v *VaultTestSuite) Test_InfoPage() {
+	require.NoError(v.T(), v.vault.MergeInfos("credentials", []model.DataInfo{
+		{ID: "a", MetaData: "zulu", CreatedAt: "2024-01-01", Revision: 1},
+		{ID: "b", MetaData: "alpha", CreatedAt: "2024-01-02", Revision: 1},
+		{ID: "c", MetaData: "mike", CreatedAt: "2024-01-03", Revision: 1},
+	}))
+
+	ids := func(infos []model.DataInfo) []string {
+		result := make([]string, 0, len(infos))
+		for _, info := range infos {
+			result = append(result, info.ID)
+		}
+		return result
+	}
+
+	page, err := v.vault.InfoPage("credentials", model.PageRequest{PageSize: 2})
+	require.NoError(v.T(), err)
+	assert.Equal(v.T(), []string{"a", "b"}, ids(page.Infos))
+	require.NotEmpty(v.T(), page.NextPageToken)
+
+	page, err = v.vault.InfoPage("credentials", model.PageRequest{PageSize: 2, PageToken: page.NextPageToken})
+	require.NoError(v.T(), err)
+	assert.Equal(v.T(), []string{"c"}, ids(page.Infos))
+	assert.Empty(v.T(), page.NextPageToken)
+
+	page, err = v.vault.InfoPage("credentials", model.PageRequest{SortBy: model.SortByMetadata, Descending: true})
+	require.NoError(v.T(), err)
+	assert.Equal(v.T(), []string{"a", "c", "b"}, ids(page.Infos))
+
+	_, err = v.vault.InfoPage("credentials", model.PageRequest{PageToken: "server-token"})
+	assert.ErrorIs(v.T(), err, ErrInvalidPageToken)
+}
+
+func (v *VaultTestSuite) Test_SyncRemapsLocalIDs() {
+	localID := NewLocalID()
+	require.NoError(v.T(), v.vault.PutPayload("credentials", localID, "meta", 0, model.Credentials{Login: "login"}))
+	require.NoError(v.T(), v.vault.EnqueuePayload(OpSave, "credentials", localID, model.CredentialsPostRequest{Login: "login"}))
+	require.NoError(v.T(), v.vault.EnqueuePayload(OpUpdate, "credentials", localID, model.CredentialsPutRequest{Login: "new login"}))
+	require.NoError(v.T(), v.vault.EnqueuePayload(OpDelete, "credentials", localID, nil))
+
+	r := &replayer{}
+	result, err := NewSyncer(map[string]Replayer{"credentials": r}, &changeLoader{}).Sync(context.Background(), v.vault, "token")
+	assert.NoError(v.T(), err)
+	assert.Equal(v.T(), 3, result.Synced)
+	assert.Empty(v.T(), v.vault.Pending())
+
+	require.Len(v.T(), r.replayed, 3)
+	assert.Equal(v.T(), localID, r.replayed[0].RecordID)
+	assert.Equal(v.T(), "server-id", r.replayed[1].RecordID)
+	assert.Equal(v.T(), "server-id", r.replayed[2].RecordID)
+
+	_, ok := v.vault.Record("credentials", "server-id")
+	assert.True(v.T(), ok)
+}
+
+func (v *VaultTestSuite) Test_SyncStopsWhenOffline() {
+	require.NoError(v.T(), v.vault.EnqueuePayload(OpDelete, "credentials", "id", nil))
+
+	r := &replayer{err: status.Error(codes.Unavailable, "connection refused")}
+	result, err := NewSyncer(map[string]Replayer{"credentials": r}, &changeLoader{}).Sync(context.Background(), v.vault, "token")
+	assert.Error(v.T(), err)
+	assert.Equal(v.T(), 1, result.Pending)
+	assert.Len(v.T(), v.vault.Pending(), 1)
+
+	r.err = errors.New("rejected")
+	result, err = NewSyncer(map[string]Replayer{"credentials": r}, &changeLoader{}).Sync(context.Background(), v.vault, "token")
+	assert.NoError(v.T(), err)
+	assert.Equal(v.T(), 1, result.Failed)
+	assert.Empty(v.T(), v.vault.Pending())
+	assert.Len(v.T(), v.vault.Rejected(), 1)
+}
+
+// Test_SyncKeepsRejected checks that writes rejected by the server are kept with the local changes
+// until the user retries or discards them.
+func (v *VaultTestSuite) Test_SyncKeepsRejected() {
+	localID := NewLocalID()
+	require.NoError(v.T(), v.vault.PutPayload("credentials", localID, "new", 0, model.Credentials{Login: "new"}))
+	require.NoError(v.T(), v.vault.EnqueuePayload(OpSave, "credentials", localID, model.CredentialsPostRequest{Login: "new"}))
+	require.NoError(v.T(), v.vault.Put(Record{ID: "id", DataType: "credentials", MetaData: "local", Revision: 1, Payload: []byte(`{"Login":"local"}`)}))
+	require.NoError(v.T(), v.vault.EnqueuePayload(OpUpdate, "credentials", "id", model.CredentialsPutRequest{Login: "local"}))
+
+	r := &replayer{err: status.Error(codes.InvalidArgument, "login is too long")}
+	syncer := NewSyncer(map[string]Replayer{"credentials": r}, &changeLoader{
+		pages: [][]model.Change{{{ID: "id", DataType: "credentials", MetaData: "server", Revision: 2}}},
+	})
+	result, err := syncer.Sync(context.Background(), v.vault, "token")
+	require.NoError(v.T(), err)
+	assert.Equal(v.T(), 2, result.Failed)
+	assert.Empty(v.T(), v.vault.Pending())
+
+	// The rejected writes and the local changes survive reopening the vault and the changes from the server
+	reopened, err := Open(v.path, v.key)
+	require.NoError(v.T(), err)
+	rejected := reopened.Rejected()
+	require.Len(v.T(), rejected, 2)
+	assert.Equal(v.T(), localID, rejected[0].RecordID)
+	assert.Equal(v.T(), "login is too long", rejected[1].Reason)
+	record, ok := reopened.Record("credentials", "id")
+	require.True(v.T(), ok)
+	assert.Equal(v.T(), "local", record.MetaData)
+	assert.JSONEq(v.T(), `{"Login":"local"}`, string(record.Payload))
+
+	// A retried write is replayed on the next sync
+	require.NoError(v.T(), reopened.Retry(rejected[0].ID))
+	r.err = nil
+	result, err = syncer.Sync(context.Background(), reopened, "token")
+	require.NoError(v.T(), err)
+	assert.Equal(v.T(), 1, result.Synced)
+	_, ok = reopened.Record("credentials", "server-id")
+	assert.True(v.T(), ok)
+
+	// A discarded write drops the local change, so the record is loaded from the server again
+	require.NoError(v.T(), reopened.Discard(rejected[1].ID))
+	assert.Empty(v.T(), reopened.Rejected())
+	record, ok = reopened.Record("credentials", "id")
+	require.True(v.T(), ok)
+	assert.Empty(v.T(), record.Payload)
+
+	assert.ErrorIs(v.T(), reopened.Discard(rejected[1].ID), ErrOperationNotFound)
+	assert.ErrorIs(v.T(), reopened.Retry(rejected[1].ID), ErrOperationNotFound)
+}
+
+func (v *VaultTestSuite) Test_SyncPullsChanges() {
+	require.NoError(v.T(), v.vault.PutPayload("text_data", "changed", "old", 1, model.TextData{Text: "old"}))
+	require.NoError(v.T(), v.vault.PutPayload("text_data", "same", "same", 1, model.TextData{Text: "same"}))
+	require.NoError(v.T(), v.vault.PutPayload("text_data", "deleted", "deleted", 1, model.TextData{Text: "deleted"}))
+
+	loader := &changeLoader{pages: [][]model.Change{
+		{
+			{ID: "changed", DataType: "text_data", MetaData: "new", Revision: 2},
+			{ID: "same", DataType: "text_data", MetaData: "same", Revision: 1},
+		},
+		{
+			{ID: "deleted", DataType: "text_data", Revision: 2, Deleted: true},
+			{ID: "created", DataType: "text_data", MetaData: "created", Revision: 1},
+		},
+	}}
+
+	r := &replayer{}
+	result, err := NewSyncer(map[string]Replayer{"text_data": r}, loader).Sync(context.Background(), v.vault, "token")
+	assert.NoError(v.T(), err)
+	assert.Equal(v.T(), 4, result.Pulled)
+	assert.Equal(v.T(), int64(4), v.vault.Cursor())
+	assert.Equal(v.T(), []string{"changed"}, r.fetched)
+
+	record, ok := v.vault.Record("text_data", "changed")
+	require.True(v.T(), ok)
+	assert.Equal(v.T(), "new", record.MetaData)
+	assert.Equal(v.T(), int64(2), record.Revision)
+
+	_, ok = v.vault.Record("text_data", "deleted")
+	assert.False(v.T(), ok)
+	_, ok = v.vault.Record("text_data", "created")
+	assert.True(v.T(), ok)
+
+	var text model.TextData
+	assert.NoError(v.T(), v.vault.Payload("text_data", "same", &text))
+
+	_, err = NewSyncer(map[string]Replayer{"text_data": r}, loader).Sync(context.Background(), v.vault, "token")
+	assert.NoError(v.T(), err)
+	assert.Equal(v.T(), int64(4), loader.cursor)
+}
+
+func (v *VaultTestSuite) Test_ParseConflictPolicy() {
+	policy, err := ParseConflictPolicy("")
+	assert.NoError(v.T(), err)
+	assert.Equal(v.T(), KeepBoth, policy)
+
+	policy, err = ParseConflictPolicy("last-writer-wins")
+	assert.NoError(v.T(), err)
+	assert.Equal(v.T(), LastWriterWins, policy)
+
+	_, err = ParseConflictPolicy("first-writer-wins")
+	assert.Error(v.T(), err)
+}
+
+func (v *VaultTestSuite) Test_IsOffline() {
+	assert.False(v.T(), IsOffline(nil))
+	assert.False(v.T(), IsOffline(errors.New("error")))
+	assert.False(v.T(), IsOffline(status.Error(codes.NotFound, "not found")))
+	assert.True(v.T(), IsOffline(status.Error(codes.Unavailable, "unavailable")))
+
+	assert.False(v.T(), IsConflict(nil))
+	assert.True(v.T(), IsConflict(status.Error(codes.Aborted, "changed")))
+}
@@ -19,6 +19,17 @@ import (
 //   - EnvLogs: The log level to set, provided as a string.
 //     Valid log levels are "panic", "fatal", "errors", "warn", "info", and "debug".
 func RunLoggerConfig(EnvLogsLevel, logFileName string) {
+	configure(EnvLogsLevel, io.MultiWriter(os.Stdout, newFileWriter(logFileName)))
+}
+
+// RunFileLoggerConfig configures the application logger like RunLoggerConfig, but writes the log to the file only,
+// so the output of commands run from scripts is not mixed with log records.
+func RunFileLoggerConfig(EnvLogsLevel, logFileName string) {
+	configure(EnvLogsLevel, newFileWriter(logFileName))
+}
+
+// configure sets the log level, the formatter and the output of the logger.
+func configure(EnvLogsLevel string, out io.Writer) {
 	// Parse log level from the environment variable
 	logLevel, err := logrus.ParseLevel(EnvLogsLevel)
 	if err != nil {
@@ -39,13 +50,16 @@ func RunLoggerConfig(EnvLogsLevel, logFileName string) {
 		},
 	})
 
-	// Configure log file rotation using lumberjack
-	mw := io.MultiWriter(os.Stdout, &lumberjack.Logger{
+	logrus.SetOutput(out)
+	logrus.Infof("Logrus set level: %s", EnvLogsLevel)
+}
+
+// newFileWriter returns a writer to the log file with rotation configured using lumberjack.
+func newFileWriter(logFileName string) io.Writer {
+	return &lumberjack.Logger{
 		Filename:   logFileName,
 		MaxSize:    50,
 		MaxBackups: 3,
 		MaxAge:     30,
-	})
-	logrus.SetOutput(mw)
-	logrus.Infof("Logrus set level: %s", EnvLogsLevel)
+	}
 }
@@ -2,7 +2,9 @@ package grpchandlers
 
 import (
 	"context"
+	"errors"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -16,12 +18,20 @@ import (
 type BinaryDataService interface {
 	SaveBinaryData(ctx context.Context, req model.BinaryDataPostRequest) (model.BinaryData, error)
 	LoadBinaryData(ctx context.Context, dataID string) (model.BinaryData, error)
-	LoadAllBinaryInfo(ctx context.Context) ([]model.DataInfo, error)
+	LoadAllBinaryInfo(ctx context.Context, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateBinaryData(ctx context.Context, req model.BinaryDataPutRequest) (model.BinaryData, error)
+	DeleteBinaryData(ctx context.Context, dataID string) error
+	SaveBinaryDataStream(ctx context.Context, req model.BinaryDataStreamHeader, recv func() ([]byte, error)) (model.BinaryData, error)
+	LoadBinaryDataStream(ctx context.Context, dataID string, sendHeader func(model.BinaryData) error, sendChunk func([]byte) error) error
 }
 
-// Validator defines the method for validating binary data post requests.
+// Validator defines the methods for validating binary data requests.
 type Validator interface {
 	ValidatePostRequest(req *model.BinaryDataPostRequest) (map[string]string, bool)
+	ValidatePutRequest(req *model.BinaryDataPutRequest) (map[string]string, bool)
+	ValidateDeleteRequest(req *model.DataDeleteRequest) (map[string]string, bool)
+	ValidateStreamHeader(req *model.BinaryDataStreamHeader) (map[string]string, bool)
+	ValidatePageRequest(req *model.PageRequest) (map[string]string, bool)
 }
 
 // BinaryDataHandler implements the gRPC server for handling binary data requests.
@@ -42,20 +52,32 @@ func New(binaryDataService BinaryDataService, validator Validator) *BinaryDataHa
 // PostSaveBinaryData handles the gRPC request for saving binary data.
 func (h *BinaryDataHandler) PostSaveBinaryData(ctx context.Context, in *pb.PostBinaryDataRequest) (*pb.PostBinaryDataResponse, error) {
 	req := model.BinaryDataPostRequest{
+		ID:        in.Id,
 		Name:      in.Name,
 		Extension: in.Extension,
 		Data:      in.Data,
 		MetaData:  in.Metadata,
+		CryptData: in.CryptData,
 	}
 
 	report, ok := h.validator.ValidatePostRequest(&req)
 	if !ok {
-		logrus.Info("Unable to register user: invalid user request")
+		logrus.Info("Unable to save binary_data: invalid binary_data request")
 		logrus.Infof("violated_fields %v", report)
-		return nil, lib.ProcessValidationError("invalid text_data post request", report)
+		return nil, lib.ProcessValidationError("invalid binary_data post request", report)
 	}
 
 	binary, err := h.binaryDataService.SaveBinaryData(ctx, req)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to save binary_data: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataExists) {
+		logrus.Info("Unable to save binary_data: id is taken")
+		return nil, status.Error(codes.AlreadyExists, "data with this id already exists")
+	}
+
 	if err != nil {
 		logrus.WithError(err).Error("Unable to save binary_data")
 		return nil, status.Error(codes.Internal, "internal error")
@@ -67,29 +89,50 @@ func (h *BinaryDataHandler) PostSaveBinaryData(ctx context.Context, in *pb.PostB
 		Extension: binary.Extension,
 		Metadata:  binary.MetaData,
 		CreatedAt: binary.CreatedAt.Format(time.RFC3339Nano),
+		Revision:  binary.Revision,
 	}, nil
 }
 
-// GetLoadAllBinaryDataInfo handles the gRPC request for loading all binary data information.
-func (h *BinaryDataHandler) GetLoadAllBinaryDataInfo(ctx context.Context, _ *pb.GetAllBinaryInfoRequest) (*pb.GetAllBinaryInfoResponse, error) {
+// GetLoadAllBinaryDataInfo handles the gRPC call for loading a page of binary data information.
+func (h *BinaryDataHandler) GetLoadAllBinaryDataInfo(ctx context.Context, in *pb.GetAllBinaryInfoRequest) (*pb.GetAllBinaryInfoResponse, error) {
+	req := model.PageRequest{
+		PageSize:   int(in.PageSize),
+		PageToken:  in.PageToken,
+		SortBy:     in.SortBy,
+		Descending: in.Descending,
+	}
+
+	report, ok := h.validator.ValidatePageRequest(&req)
+	if !ok {
+		logrus.Info("Unable to load binary data info: invalid page request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid page request", report)
+	}
+
+	binariesPage, err := h.binaryDataService.LoadAllBinaryInfo(ctx, req)
+	if errors.Is(err, cerrors.ErrInvalidPageToken) {
+		logrus.Info("Unable to load binary data info: invalid page token")
+		return nil, lib.ProcessValidationError("invalid page request", map[string]string{"PageToken": "is invalid or issued for another sort order"})
+	}
 
-	binariesInfo, err := h.binaryDataService.LoadAllBinaryInfo(ctx)
 	if err != nil {
 		logrus.WithError(err).Error("Error while loading binary data: ")
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	binaryInfos := make([]*pb.BinaryInfo, 0, len(binariesInfo))
-	for _, v := range binariesInfo {
+	binaryInfos := make([]*pb.BinaryInfo, 0, len(binariesPage.Infos))
+	for _, v := range binariesPage.Infos {
 		binaryInfos = append(binaryInfos, &pb.BinaryInfo{
 			Id:        v.ID,
 			DataType:  v.DataType,
 			Metadata:  v.MetaData,
 			CreatedAt: v.CreatedAt.Format(time.RFC3339),
+			Revision:  v.Revision,
+			UpdatedAt: v.UpdatedAt.Format(time.RFC3339),
 		})
 	}
 
-	return &pb.GetAllBinaryInfoResponse{Binaries: binaryInfos}, nil
+	return &pb.GetAllBinaryInfoResponse{Binaries: binaryInfos, NextPageToken: binariesPage.NextPageToken}, nil
 }
 
 // GetLoadBinaryData handles the gRPC request for loading specific binary data.
@@ -97,6 +140,11 @@ func (h *BinaryDataHandler) GetLoadBinaryData(ctx context.Context, in *pb.GetBin
 	dataID := in.Id
 
 	binaryData, err := h.binaryDataService.LoadBinaryData(ctx, dataID)
+	if errors.Is(err, cerrors.ErrChunkedData) {
+		logrus.Infof("Unable to load binary_data: binary_data %s is stored in chunks", dataID)
+		return nil, status.Error(codes.FailedPrecondition, "binary data is stored in chunks, use streaming download")
+	}
+
 	if err != nil {
 		logrus.WithError(err).Error("Error while loading binary data: ")
 		return nil, status.Error(codes.Internal, "internal error")
@@ -110,6 +158,188 @@ func (h *BinaryDataHandler) GetLoadBinaryData(ctx context.Context, in *pb.GetBin
 		Extension: binaryData.Extension,
 		Metadata:  binaryData.MetaData,
 		CreatedAt: binaryData.CreatedAt.Format(time.RFC3339Nano),
+		Revision:  binaryData.Revision,
+		CryptData: binaryData.CryptData,
 	}
 	return &pb.GetBinaryDataResponse{BinaryData: bin}, nil
 }
+
+// PutUpdateBinaryData handles the gRPC request for updating existing binary data.
+func (h *BinaryDataHandler) PutUpdateBinaryData(ctx context.Context, in *pb.PutBinaryDataRequest) (*pb.PutBinaryDataResponse, error) {
+	req := model.BinaryDataPutRequest{
+		ID:        in.Id,
+		Revision:  in.Revision,
+		Name:      in.Name,
+		Extension: in.Extension,
+		Data:      in.Data,
+		MetaData:  in.Metadata,
+		CryptData: in.CryptData,
+	}
+
+	report, ok := h.validator.ValidatePutRequest(&req)
+	if !ok {
+		logrus.Info("Unable to update binary_data: invalid binary_data request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid binary_data put request", report)
+	}
+
+	binary, err := h.binaryDataService.UpdateBinaryData(ctx, req)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to update binary_data: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to update binary_data: binary_data %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "binary data not found")
+	}
+
+	if errors.Is(err, cerrors.ErrRevisionConflict) {
+		logrus.Infof("Unable to update binary_data: binary_data %s has a newer revision", req.ID)
+		return nil, status.Error(codes.Aborted, "binary data was changed by another client")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to update binary_data")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PutBinaryDataResponse{
+		Id:        binary.ID,
+		Name:      binary.Name,
+		Extension: binary.Extension,
+		Metadata:  binary.MetaData,
+		CreatedAt: binary.CreatedAt.Format(time.RFC3339Nano),
+		Revision:  binary.Revision,
+	}, nil
+}
+
+// DeleteBinaryData handles the gRPC request for deleting specific binary data.
+func (h *BinaryDataHandler) DeleteBinaryData(ctx context.Context, in *pb.DeleteBinaryDataRequest) (*pb.DeleteBinaryDataResponse, error) {
+	req := model.DataDeleteRequest{ID: in.Id}
+
+	report, ok := h.validator.ValidateDeleteRequest(&req)
+	if !ok {
+		logrus.Info("Unable to delete binary_data: invalid binary_data request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid binary_data delete request", report)
+	}
+
+	err := h.binaryDataService.DeleteBinaryData(ctx, req.ID)
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to delete binary_data: binary_data %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "binary data not found")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to delete binary_data")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.DeleteBinaryDataResponse{}, nil
+}
+
+// PostSaveBinaryDataStream handles the gRPC client stream for saving large binary data.
+// The first message must contain the header, all following messages contain chunks of the file.
+func (h *BinaryDataHandler) PostSaveBinaryDataStream(stream pb.BinaryDataService_PostSaveBinaryDataStreamServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		logrus.WithError(err).Error("Unable to receive binary_data header")
+		return status.Error(codes.InvalidArgument, "missing binary data header")
+	}
+
+	header := first.GetHeader()
+	if header == nil {
+		logrus.Info("Unable to save binary_data stream: first message is not a header")
+		return status.Error(codes.InvalidArgument, "first message must be a binary data header")
+	}
+
+	req := model.BinaryDataStreamHeader{
+		ID:        header.Id,
+		Name:      header.Name,
+		Extension: header.Extension,
+		MetaData:  header.Metadata,
+		CryptData: header.CryptData,
+	}
+
+	report, ok := h.validator.ValidateStreamHeader(&req)
+	if !ok {
+		logrus.Info("Unable to save binary_data stream: invalid binary_data header")
+		logrus.Infof("violated_fields %v", report)
+		return lib.ProcessValidationError("invalid binary_data stream header", report)
+	}
+
+	recv := func() ([]byte, error) {
+		msg, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		chunk, ok := msg.Payload.(*pb.PostBinaryDataStreamRequest_Chunk)
+		if !ok {
+			return nil, errors.New("expected binary data chunk")
+		}
+		return chunk.Chunk, nil
+	}
+
+	binary, err := h.binaryDataService.SaveBinaryDataStream(stream.Context(), req, recv)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to save binary_data stream: user key was rotated")
+		return status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataExists) {
+		logrus.Info("Unable to save binary_data stream: id is taken")
+		return status.Error(codes.AlreadyExists, "data with this id already exists")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to save binary_data stream")
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return stream.SendAndClose(&pb.PostBinaryDataResponse{
+		Id:        binary.ID,
+		Name:      binary.Name,
+		Extension: binary.Extension,
+		Metadata:  binary.MetaData,
+		CreatedAt: binary.CreatedAt.Format(time.RFC3339Nano),
+		Revision:  binary.Revision,
+	})
+}
+
+// GetLoadBinaryDataStream handles the gRPC server stream for loading large binary data.
+// The first message contains the header, all following messages contain chunks of the file.
+func (h *BinaryDataHandler) GetLoadBinaryDataStream(in *pb.GetBinaryDataStreamRequest, stream pb.BinaryDataService_GetLoadBinaryDataStreamServer) error {
+	sendHeader := func(binaryData model.BinaryData) error {
+		return stream.Send(&pb.GetBinaryDataStreamResponse{
+			Payload: &pb.GetBinaryDataStreamResponse_Header{Header: &pb.BinaryData{
+				Id:        binaryData.ID,
+				OwnerId:   binaryData.OwnerID,
+				Name:      binaryData.Name,
+				Extension: binaryData.Extension,
+				Metadata:  binaryData.MetaData,
+				CreatedAt: binaryData.CreatedAt.Format(time.RFC3339Nano),
+				Revision:  binaryData.Revision,
+				CryptData: binaryData.CryptData,
+			}},
+		})
+	}
+	sendChunk := func(chunk []byte) error {
+		return stream.Send(&pb.GetBinaryDataStreamResponse{
+			Payload: &pb.GetBinaryDataStreamResponse_Chunk{Chunk: chunk},
+		})
+	}
+
+	err := h.binaryDataService.LoadBinaryDataStream(stream.Context(), in.Id, sendHeader, sendChunk)
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to load binary_data: binary_data %s not found", in.Id)
+		return status.Error(codes.NotFound, "binary data not found")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to load binary_data stream")
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package validation
 import (
 	"errors"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/pagination"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -20,6 +21,88 @@ func New(validator *validator.Validate) (*Validator, error) {
 
 // ValidatePostRequest validates the BinaryDataPostRequest struct.
 func (v *Validator) ValidatePostRequest(req *model.BinaryDataPostRequest) (map[string]string, bool) {
+	var err error
+	if len(req.CryptData) > 0 {
+		// Client side encrypted payload is opaque to the server, only the ID it is bound to can be checked
+		err = v.validator.StructPartial(req, "ID")
+	} else {
+		err = v.validator.Struct(req)
+	}
+	report := make(map[string]string)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				switch validationErr.Tag() {
+				case "required":
+					report[validationErr.Field()] = "is required"
+				case "uuid":
+					report[validationErr.Field()] = "must be a UUID"
+				}
+			}
+			return report, false
+		}
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+	return nil, true
+}
+
+// ValidatePutRequest validates the BinaryDataPutRequest struct.
+func (v *Validator) ValidatePutRequest(req *model.BinaryDataPutRequest) (map[string]string, bool) {
+	var err error
+	if len(req.CryptData) > 0 {
+		// Client side encrypted payload is opaque to the server, only the ID can be checked
+		err = v.validator.StructPartial(req, "ID")
+	} else {
+		err = v.validator.Struct(req)
+	}
+	report := make(map[string]string)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				switch validationErr.Tag() {
+				case "required":
+					report[validationErr.Field()] = "is required"
+				}
+			}
+			return report, false
+		}
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+	return nil, true
+}
+
+// ValidateStreamHeader validates the BinaryDataStreamHeader struct.
+func (v *Validator) ValidateStreamHeader(req *model.BinaryDataStreamHeader) (map[string]string, bool) {
+	var err error
+	if len(req.CryptData) > 0 {
+		// Client side encrypted payload is opaque to the server, only the ID it is bound to can be checked
+		err = v.validator.StructPartial(req, "ID")
+	} else {
+		err = v.validator.Struct(req)
+	}
+	report := make(map[string]string)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				switch validationErr.Tag() {
+				case "required":
+					report[validationErr.Field()] = "is required"
+				case "uuid":
+					report[validationErr.Field()] = "must be a UUID"
+				}
+			}
+			return report, false
+		}
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+	return nil, true
+}
+
+// ValidateDeleteRequest validates the DataDeleteRequest struct for binary data.
+func (v *Validator) ValidateDeleteRequest(req *model.DataDeleteRequest) (map[string]string, bool) {
 	err := v.validator.Struct(req)
 	report := make(map[string]string)
 	if err != nil {
@@ -37,3 +120,8 @@ func (v *Validator) ValidatePostRequest(req *model.BinaryDataPostRequest) (map[s
 	}
 	return nil, true
 }
+
+// ValidatePageRequest validates the incoming PageRequest of a data listing.
+func (v *Validator) ValidatePageRequest(req *model.PageRequest) (map[string]string, bool) {
+	return pagination.Validate(v.validator, req)
+}
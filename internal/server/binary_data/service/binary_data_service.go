@@ -3,21 +3,33 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/google/uuid"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+	"io"
 
 	"github.com/DenisKhanov/PrivateKeeperV2/pkg/jwtmanager"
 
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/pagination"
 )
 
-const binaryData = "binary_data" // Define a constant for binary data type
+const (
+	binaryData = "binary_data" // Define a constant for binary data type
+	chunkSize  = 1 << 20       // Size of chunks used to stream binary data stored in one piece
+)
 
 // DataRepository defines methods for interacting with the data storage layer.
 type DataRepository interface {
 	Insert(ctx context.Context, data model.Data) (model.Data, error)
-	SelectAll(ctx context.Context, userID, dataType string) ([]model.Data, error)
+	SelectPage(ctx context.Context, userID, dataType string, page model.PageRequest, after *model.PageCursor, limit int) ([]model.Data, error)
 	SelectByID(ctx context.Context, userID, dataType, dataID string) (model.Data, error)
+	Update(ctx context.Context, data model.Data) (model.Data, error)
+	Delete(ctx context.Context, userID, dataType, dataID string) error
+	InsertWithChunks(ctx context.Context, data model.Data, next func() ([]byte, error)) (model.Data, error)
+	SelectChunks(ctx context.Context, userID, dataID string, yield func(index, total int, chunk []byte) error) error
+	UpdateInline(ctx context.Context, data model.Data) (model.Data, error)
 }
 
 // CryptService defines methods for cryptographic operations.
@@ -25,6 +37,8 @@ type CryptService interface {
 	Encrypt(key, data []byte) ([]byte, error)
 	Decrypt(key, data []byte) ([]byte, error)
 	GenerateKey() ([]byte, error)
+	EncryptChunk(key, data []byte, index int, last bool) ([]byte, error)
+	DecryptChunk(key, data []byte, index int, last bool) ([]byte, error)
 }
 
 // BinaryDataService handles operations related to binary data.
@@ -57,9 +71,9 @@ func (s *BinaryDataService) SaveBinaryData(ctx context.Context, req model.Binary
 		return model.BinaryData{}, fmt.Errorf("failed to get userKey from context")
 	}
 
-	id, err := uuid.NewUUID()
+	id, err := lib.NewDataID(req.ID)
 	if err != nil {
-		return model.BinaryData{}, fmt.Errorf("new uuid: %w", err)
+		return model.BinaryData{}, fmt.Errorf("new data id: %w", err)
 	}
 
 	binary := model.BinaryCryptData{
@@ -68,22 +82,18 @@ func (s *BinaryDataService) SaveBinaryData(ctx context.Context, req model.Binary
 		Data:      req.Data,
 	}
 
-	data, err := json.Marshal(binary)
-	if err != nil {
-		return model.BinaryData{}, fmt.Errorf("marshal: %w", err)
-	}
-
-	cryptData, err := s.crypt.Encrypt(userKey, data)
+	cryptData, clientEncrypted, err := s.encryptPayload(userKey, req.CryptData, binary)
 	if err != nil {
-		return model.BinaryData{}, fmt.Errorf("encrypt data: %w", err)
+		return model.BinaryData{}, fmt.Errorf("encrypt payload: %w", err)
 	}
 
 	dataToSave := model.Data{
-		ID:       id.String(),
-		OwnerID:  userID,
-		Type:     s.dataType,
-		Data:     cryptData,
-		MetaData: req.MetaData,
+		ID:              id,
+		OwnerID:         userID,
+		Type:            s.dataType,
+		Data:            cryptData,
+		MetaData:        req.MetaData,
+		ClientEncrypted: clientEncrypted,
 	}
 
 	savedBinaryData, err := s.repository.Insert(ctx, dataToSave)
@@ -99,21 +109,30 @@ func (s *BinaryDataService) SaveBinaryData(ctx context.Context, req model.Binary
 		Data:      req.Data,
 		MetaData:  savedBinaryData.MetaData,
 		CreatedAt: savedBinaryData.CreatedAt,
+		Revision:  savedBinaryData.Revision,
+		CryptData: req.CryptData,
 	}, nil
 }
 
-// LoadAllBinaryInfo retrieves metadata for all binary data entries for the user.
-func (s *BinaryDataService) LoadAllBinaryInfo(ctx context.Context) ([]model.DataInfo, error) {
+// LoadAllBinaryInfo retrieves a page of binary data entries information for the user, sorted as requested.
+func (s *BinaryDataService) LoadAllBinaryInfo(ctx context.Context, page model.PageRequest) (model.DataInfoPage, error) {
 	userID, ok := ctx.Value(model.UserIDKey).(string)
 	if !ok {
-		return nil, fmt.Errorf("failed to get userID from context")
+		return model.DataInfoPage{}, fmt.Errorf("failed to get userID from context")
 	}
 
-	encryptedBinaryData, err := s.repository.SelectAll(ctx, userID, s.dataType)
+	page = pagination.Normalize(page)
+	after, err := pagination.DecodeToken(page)
 	if err != nil {
-		return nil, fmt.Errorf("select all binary_data: %w", err)
+		return model.DataInfoPage{}, err
 	}
 
+	encryptedBinaryData, err := s.repository.SelectPage(ctx, userID, s.dataType, page, after, page.PageSize+1)
+	if err != nil {
+		return model.DataInfoPage{}, fmt.Errorf("select page binary_data: %w", err)
+	}
+	encryptedBinaryData, nextPageToken := pagination.Page(page, encryptedBinaryData)
+
 	binaryDataInfo := make([]model.DataInfo, 0, len(encryptedBinaryData))
 	for _, encryptedBinary := range encryptedBinaryData {
 		binaryDataInfo = append(binaryDataInfo, model.DataInfo{
@@ -121,9 +140,11 @@ func (s *BinaryDataService) LoadAllBinaryInfo(ctx context.Context) ([]model.Data
 			DataType:  s.dataType,
 			MetaData:  encryptedBinary.MetaData,
 			CreatedAt: encryptedBinary.CreatedAt,
+			Revision:  encryptedBinary.Revision,
+			UpdatedAt: encryptedBinary.UpdatedAt,
 		})
 	}
-	return binaryDataInfo, nil
+	return model.DataInfoPage{Infos: binaryDataInfo, NextPageToken: nextPageToken}, nil
 }
 
 // LoadBinaryData retrieves and decrypts a binary data entry by its ID.
@@ -142,6 +163,17 @@ func (s *BinaryDataService) LoadBinaryData(ctx context.Context, dataID string) (
 	if err != nil {
 		return model.BinaryData{}, fmt.Errorf("select all binary_data: %w", err)
 	}
+	if encryptedBinaryData.ClientEncrypted {
+		return model.BinaryData{
+			ID:        encryptedBinaryData.ID,
+			OwnerID:   encryptedBinaryData.OwnerID,
+			MetaData:  encryptedBinaryData.MetaData,
+			CreatedAt: encryptedBinaryData.CreatedAt,
+			Revision:  encryptedBinaryData.Revision,
+			CryptData: encryptedBinaryData.Data,
+		}, nil
+	}
+
 	decryptedData, err := s.crypt.Decrypt(userKey, encryptedBinaryData.Data)
 	if err != nil {
 		return model.BinaryData{}, fmt.Errorf("decrypt binary: %w", err)
@@ -153,6 +185,10 @@ func (s *BinaryDataService) LoadBinaryData(ctx context.Context, dataID string) (
 		return model.BinaryData{}, fmt.Errorf("unmarshal binary: %w", err)
 	}
 
+	if len(decryptedBinaryData.ChunkKey) > 0 {
+		return model.BinaryData{}, cerrors.ErrChunkedData
+	}
+
 	binary := model.BinaryData{
 		ID:        encryptedBinaryData.ID,
 		OwnerID:   encryptedBinaryData.OwnerID,
@@ -161,7 +197,280 @@ func (s *BinaryDataService) LoadBinaryData(ctx context.Context, dataID string) (
 		Data:      decryptedBinaryData.Data,
 		MetaData:  encryptedBinaryData.MetaData,
 		CreatedAt: encryptedBinaryData.CreatedAt,
+		Revision:  encryptedBinaryData.Revision,
 	}
 
 	return binary, nil
 }
+
+// UpdateBinaryData re-encrypts and replaces an existing binary data entry.
+func (s *BinaryDataService) UpdateBinaryData(ctx context.Context, req model.BinaryDataPutRequest) (model.BinaryData, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.BinaryData{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return model.BinaryData{}, fmt.Errorf("failed to get userKey from context")
+	}
+
+	binary := model.BinaryCryptData{
+		Name:      req.Name,
+		Extension: req.Extension,
+		Data:      req.Data,
+	}
+
+	cryptData, clientEncrypted, err := s.encryptPayload(userKey, req.CryptData, binary)
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("encrypt payload: %w", err)
+	}
+
+	dataToUpdate := model.Data{
+		ID:              req.ID,
+		OwnerID:         userID,
+		Type:            s.dataType,
+		Data:            cryptData,
+		MetaData:        req.MetaData,
+		ClientEncrypted: clientEncrypted,
+		Revision:        req.Revision,
+	}
+
+	// Chunks of previous content saved as a stream are removed along with the update
+	updatedBinaryData, err := s.repository.UpdateInline(ctx, dataToUpdate)
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("update binary data: %w", err)
+	}
+
+	return model.BinaryData{
+		ID:        updatedBinaryData.ID,
+		OwnerID:   updatedBinaryData.OwnerID,
+		Name:      req.Name,
+		Extension: req.Extension,
+		Data:      req.Data,
+		MetaData:  updatedBinaryData.MetaData,
+		CreatedAt: updatedBinaryData.CreatedAt,
+		Revision:  updatedBinaryData.Revision,
+		CryptData: req.CryptData,
+	}, nil
+}
+
+// DeleteBinaryData removes a binary data entry by its ID.
+func (s *BinaryDataService) DeleteBinaryData(ctx context.Context, dataID string) error {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get userID from context")
+	}
+
+	err := s.repository.Delete(ctx, userID, s.dataType, dataID)
+	if err != nil {
+		return fmt.Errorf("delete binary data: %w", err)
+	}
+
+	return nil
+}
+
+// SaveBinaryDataStream saves a new binary data entry received as a stream of chunks.
+// Each chunk read from recv is encrypted on its own with a random chunk key stored in the encrypted header,
+// chunks of a client side encrypted entry are stored as is. recv returns io.EOF after the last chunk.
+func (s *BinaryDataService) SaveBinaryDataStream(ctx context.Context, req model.BinaryDataStreamHeader, recv func() ([]byte, error)) (model.BinaryData, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.BinaryData{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return model.BinaryData{}, fmt.Errorf("failed to get userKey from context")
+	}
+
+	id, err := lib.NewDataID(req.ID)
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("new data id: %w", err)
+	}
+
+	var chunkKey []byte
+	if len(req.CryptData) == 0 {
+		chunkKey, err = s.crypt.GenerateKey()
+		if err != nil {
+			return model.BinaryData{}, fmt.Errorf("generate chunk key: %w", err)
+		}
+	}
+
+	binary := model.BinaryCryptData{
+		Name:      req.Name,
+		Extension: req.Extension,
+		ChunkKey:  chunkKey,
+	}
+
+	cryptData, clientEncrypted, err := s.encryptPayload(userKey, req.CryptData, binary)
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("encrypt payload: %w", err)
+	}
+
+	next := recv
+	if !clientEncrypted {
+		next = s.chunkEncryptor(chunkKey, recv)
+	}
+
+	dataToSave := model.Data{
+		ID:              id,
+		OwnerID:         userID,
+		Type:            s.dataType,
+		Data:            cryptData,
+		MetaData:        req.MetaData,
+		ClientEncrypted: clientEncrypted,
+	}
+
+	savedBinaryData, err := s.repository.InsertWithChunks(ctx, dataToSave, next)
+	if err != nil {
+		return model.BinaryData{}, fmt.Errorf("insert binary data: %w", err)
+	}
+
+	return model.BinaryData{
+		ID:        savedBinaryData.ID,
+		OwnerID:   savedBinaryData.OwnerID,
+		Name:      req.Name,
+		Extension: req.Extension,
+		MetaData:  savedBinaryData.MetaData,
+		CreatedAt: savedBinaryData.CreatedAt,
+		Revision:  savedBinaryData.Revision,
+		CryptData: req.CryptData,
+	}, nil
+}
+
+// LoadBinaryDataStream retrieves a binary data entry by its ID and passes its header to sendHeader
+// and its content chunk by chunk to sendChunk. Entries saved in one piece are sent in chunks as well.
+func (s *BinaryDataService) LoadBinaryDataStream(ctx context.Context, dataID string, sendHeader func(model.BinaryData) error, sendChunk func([]byte) error) error {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get userID from context")
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return fmt.Errorf("failed to get userKey from context")
+	}
+
+	encryptedBinaryData, err := s.repository.SelectByID(ctx, userID, s.dataType, dataID)
+	if err != nil {
+		return fmt.Errorf("select binary_data: %w", err)
+	}
+
+	header := model.BinaryData{
+		ID:        encryptedBinaryData.ID,
+		OwnerID:   encryptedBinaryData.OwnerID,
+		MetaData:  encryptedBinaryData.MetaData,
+		CreatedAt: encryptedBinaryData.CreatedAt,
+		Revision:  encryptedBinaryData.Revision,
+	}
+
+	if encryptedBinaryData.ClientEncrypted {
+		header.CryptData = encryptedBinaryData.Data
+		if err = sendHeader(header); err != nil {
+			return err
+		}
+		return s.repository.SelectChunks(ctx, userID, dataID, func(_, _ int, chunk []byte) error {
+			return sendChunk(chunk)
+		})
+	}
+
+	decryptedData, err := s.crypt.Decrypt(userKey, encryptedBinaryData.Data)
+	if err != nil {
+		return fmt.Errorf("decrypt binary: %w", err)
+	}
+
+	var decryptedBinaryData model.BinaryCryptData
+	err = json.Unmarshal(decryptedData, &decryptedBinaryData)
+	if err != nil {
+		return fmt.Errorf("unmarshal binary: %w", err)
+	}
+
+	header.Name = decryptedBinaryData.Name
+	header.Extension = decryptedBinaryData.Extension
+	if err = sendHeader(header); err != nil {
+		return err
+	}
+
+	if len(decryptedBinaryData.ChunkKey) == 0 {
+		data := decryptedBinaryData.Data
+		for len(data) > 0 {
+			n := min(chunkSize, len(data))
+			if err = sendChunk(data[:n]); err != nil {
+				return err
+			}
+			data = data[n:]
+		}
+		return nil
+	}
+
+	position := 0
+	return s.repository.SelectChunks(ctx, userID, dataID, func(_, total int, chunk []byte) error {
+		dec, err := s.crypt.DecryptChunk(decryptedBinaryData.ChunkKey, chunk, position, position == total-1)
+		if err != nil {
+			return fmt.Errorf("decrypt chunk %d: %w", position, err)
+		}
+		position++
+		return sendChunk(dec)
+	})
+}
+
+// chunkEncryptor wraps recv so that every chunk is encrypted with the chunk key.
+// It reads one chunk ahead to know which chunk is the last one.
+func (s *BinaryDataService) chunkEncryptor(chunkKey []byte, recv func() ([]byte, error)) func() ([]byte, error) {
+	var (
+		pending []byte
+		index   int
+		started bool
+		done    bool
+	)
+
+	return func() ([]byte, error) {
+		if done {
+			return nil, io.EOF
+		}
+
+		if !started {
+			chunk, err := recv()
+			if err != nil {
+				return nil, err
+			}
+			pending, started = chunk, true
+		}
+
+		chunk, err := recv()
+		last := errors.Is(err, io.EOF)
+		if err != nil && !last {
+			return nil, err
+		}
+
+		cryptChunk, err := s.crypt.EncryptChunk(chunkKey, pending, index, last)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt chunk %d: %w", index, err)
+		}
+
+		pending, done = chunk, last
+		index++
+		return cryptChunk, nil
+	}
+}
+
+// encryptPayload returns the payload to be stored for the binary data. A payload that was
+// already encrypted on the client side is stored as is, otherwise the data is encrypted with the user key.
+func (s *BinaryDataService) encryptPayload(userKey, clientCryptData []byte, binary model.BinaryCryptData) ([]byte, bool, error) {
+	if len(clientCryptData) > 0 {
+		return clientCryptData, true, nil
+	}
+
+	data, err := json.Marshal(binary)
+	if err != nil {
+		return nil, false, fmt.Errorf("marshal: %w", err)
+	}
+
+	cryptData, err := s.crypt.Encrypt(userKey, data)
+	if err != nil {
+		return nil, false, fmt.Errorf("encrypt data: %w", err)
+	}
+
+	return cryptData, false, nil
+}
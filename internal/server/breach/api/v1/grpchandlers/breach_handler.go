@@ -0,0 +1,69 @@
+package grpchandlers
+
+import (
+	"context"
+	"errors"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/breach"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// BreachService is an interface that defines the method for looking up ranges of the breach list.
+type BreachService interface {
+	CheckPasswordBreach(ctx context.Context, req model.BreachRangeRequest) ([]model.BreachSuffix, error)
+}
+
+// Validator is an interface for validating incoming requests.
+type Validator interface {
+	ValidateRangeRequest(req *model.BreachRangeRequest) (map[string]string, bool)
+}
+
+// BreachHandler implements the gRPC server of the breached password check.
+// It holds references to the breach service and validator.
+type BreachHandler struct {
+	breachService                       BreachService // Service for looking up the breach list
+	pb.UnimplementedBreachServiceServer               // Embed the unimplemented server for compliance with the gRPC server interface
+	validator                           Validator     // Validator for incoming requests
+}
+
+// New creates a new instance of BreachHandler with the provided services.
+func New(breachService BreachService, validator Validator) *BreachHandler {
+	return &BreachHandler{
+		breachService: breachService,
+		validator:     validator,
+	}
+}
+
+// CheckPasswordBreach handles the gRPC call to look up the hashes of the breach list starting with
+// the prefix of the SHA-1 hash of a password. Neither the password nor its full hash reach the server.
+func (h *BreachHandler) CheckPasswordBreach(ctx context.Context, in *pb.CheckPasswordBreachRequest) (*pb.CheckPasswordBreachResponse, error) {
+	req := model.BreachRangeRequest{Prefix: in.Prefix}
+
+	report, ok := h.validator.ValidateRangeRequest(&req)
+	if !ok {
+		logrus.Info("Unable to check password breach: invalid prefix")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid hash prefix", report)
+	}
+
+	suffixes, err := h.breachService.CheckPasswordBreach(ctx, req)
+	if err != nil {
+		if errors.Is(err, cerrors.ErrNoBreachList) {
+			return nil, status.Error(codes.Unimplemented, err.Error())
+		}
+		logrus.WithError(err).Error("Error while checking password breach: ")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &pb.CheckPasswordBreachResponse{Suffixes: make([]*pb.BreachSuffix, 0, len(suffixes))}
+	for _, suffix := range suffixes {
+		resp.Suffixes = append(resp.Suffixes, &pb.BreachSuffix{Suffix: suffix.Suffix, Count: suffix.Count})
+	}
+
+	return resp, nil
+}
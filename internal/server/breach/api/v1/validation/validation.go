@@ -0,0 +1,39 @@
+package validation
+
+import (
+	"errors"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/go-playground/validator/v10"
+)
+
+// Validator struct holds an instance of the validator.
+// It is used to validate the prefixes of breach list lookups.
+type Validator struct {
+	validator *validator.Validate // The validator instance for performing validation checks
+}
+
+// New initializes a new Validator instance with a validator.
+func New(validator *validator.Validate) *Validator {
+	return &Validator{validator: validator}
+}
+
+// ValidateRangeRequest validates the incoming BreachRangeRequest.
+// It checks that the prefix is 5 hex characters and returns a map of validation errors if any exist.
+func (v *Validator) ValidateRangeRequest(req *model.BreachRangeRequest) (map[string]string, bool) {
+	err := v.validator.Struct(req)
+	report := make(map[string]string)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				switch validationErr.Tag() {
+				case "len", "hexadecimal":
+					report[validationErr.Field()] = "must be the first 5 hex characters of the SHA-1 hash"
+				}
+			}
+			return report, false
+		}
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+	return nil, true
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/breach"
+)
+
+// BreachService answers k-anonymity range lookups of the breach list loaded by the server.
+type BreachService struct {
+	list breach.Ranger // Breach list, nil if the server has none
+}
+
+// New initializes a new BreachService instance with the breach list, which may be nil.
+func New(list breach.Ranger) *BreachService {
+	return &BreachService{list: list}
+}
+
+// CheckPasswordBreach returns the hashes of the breach list starting with the prefix of the request,
+// the client compares them with the rest of the hash of the password.
+func (s *BreachService) CheckPasswordBreach(ctx context.Context, req model.BreachRangeRequest) ([]model.BreachSuffix, error) {
+	if s.list == nil {
+		return nil, cerrors.ErrNoBreachList
+	}
+
+	suffixes, err := s.list.Range(ctx, req.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("breach list range: %w", err)
+	}
+
+	result := make([]model.BreachSuffix, 0, len(suffixes))
+	for _, suffix := range suffixes {
+		result = append(result, model.BreachSuffix{Suffix: suffix.Suffix, Count: suffix.Count})
+	}
+
+	return result, nil
+}
@@ -41,6 +41,22 @@ func NewRedis(redisURL, password string, db, setTimeout int) (*Redis, error) {
 	}, nil
 }
 
+// RevokedSessionKey returns the key marking the session with the given ID as revoked.
+func RevokedSessionKey(sessionID string) string {
+	return "revoked_session:" + sessionID
+}
+
+// UserKeyKey returns the key caching the unwrapped key of the user with the given ID and key generation.
+// A rotated key gets a new generation, so a key cached before the rotation is never picked up after it.
+func UserKeyKey(userID string, generation int64) string {
+	return fmt.Sprintf("user_key:%s:%d", userID, generation)
+}
+
+// SecondFactorAttemptsKey returns the key counting the recent second factor attempts of the user with the given ID.
+func SecondFactorAttemptsKey(userID string) string {
+	return "second_factor_attempts:" + userID
+}
+
 // HSetWithTTL sets a hash value in Redis with a specified TTL (time-to-live).
 // It takes a key, the data to be stored, and the TTL duration.
 func (r *Redis) HSetWithTTL(key string, data any, ttl time.Duration) error {
@@ -0,0 +1,397 @@
+package grpchandlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/collection"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// CollectionService interface defines the methods for shared collection management.
+type CollectionService interface {
+	CreateCollection(ctx context.Context, req model.CollectionRequest) (model.Collection, error)
+	LoadCollections(ctx context.Context) ([]model.Collection, error)
+	InviteMember(ctx context.Context, req model.CollectionInviteRequest) (model.CollectionMember, error)
+	AcceptInvitation(ctx context.Context, collectionID string) (model.Collection, error)
+	RevokeMember(ctx context.Context, req model.CollectionMemberRequest) error
+	LoadMembers(ctx context.Context, collectionID string) ([]model.CollectionMember, error)
+	SaveItem(ctx context.Context, req model.CollectionItemPostRequest) (model.CollectionItem, error)
+	LoadItems(ctx context.Context, collectionID string) ([]model.CollectionItem, error)
+	LoadItem(ctx context.Context, req model.CollectionItemRequest) (model.CollectionItem, error)
+	UpdateItem(ctx context.Context, req model.CollectionItemPutRequest) (model.CollectionItem, error)
+	DeleteItem(ctx context.Context, req model.CollectionItemRequest) error
+}
+
+// Validator interface defines the methods for validating requests.
+type Validator interface {
+	ValidateCollectionRequest(req *model.CollectionRequest) (map[string]string, bool)
+	ValidateInviteRequest(req *model.CollectionInviteRequest) (map[string]string, bool)
+	ValidateMemberRequest(req *model.CollectionMemberRequest) (map[string]string, bool)
+	ValidateItemPostRequest(req *model.CollectionItemPostRequest) (map[string]string, bool)
+	ValidateItemPutRequest(req *model.CollectionItemPutRequest) (map[string]string, bool)
+	ValidateItemRequest(req *model.CollectionItemRequest) (map[string]string, bool)
+}
+
+// CollectionHandler struct implements the gRPC handler for shared collections.
+type CollectionHandler struct {
+	collectionService CollectionService
+	pb.UnimplementedCollectionServiceServer
+	validator Validator
+}
+
+// New creates a new instance of CollectionHandler.
+func New(collectionService CollectionService, validator Validator) *CollectionHandler {
+	return &CollectionHandler{
+		collectionService: collectionService,
+		validator:         validator,
+	}
+}
+
+// PostCreateCollection handles the gRPC request to create a collection managed by the user.
+func (h *CollectionHandler) PostCreateCollection(ctx context.Context, in *pb.PostCollectionRequest) (*pb.PostCollectionResponse, error) {
+	req := model.CollectionRequest{Name: in.Name}
+
+	report, ok := h.validator.ValidateCollectionRequest(&req)
+	if !ok {
+		logrus.Info("Unable to create collection: invalid collection request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid collection post request", report)
+	}
+
+	collection, err := h.collectionService.CreateCollection(ctx, req)
+	if errors.Is(err, cerrors.ErrClientSideEncryption) {
+		logrus.Info("Unable to create collection: client side encryption is enabled")
+		return nil, status.Error(codes.FailedPrecondition, "shared collections are not available with client side encryption")
+	}
+
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to create collection: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("failed to create collection")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PostCollectionResponse{Collection: toPBCollection(collection)}, nil
+}
+
+// GetLoadAllCollections handles the gRPC request to load the collections the user is a member of or invited to.
+func (h *CollectionHandler) GetLoadAllCollections(ctx context.Context, _ *pb.GetAllCollectionsRequest) (*pb.GetAllCollectionsResponse, error) {
+	collections, err := h.collectionService.LoadCollections(ctx)
+	if errors.Is(err, cerrors.ErrClientSideEncryption) {
+		logrus.Info("Unable to load collections: client side encryption is enabled")
+		return nil, status.Error(codes.FailedPrecondition, "shared collections are not available with client side encryption")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Error while loading collections: ")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	pbCollections := make([]*pb.Collection, 0, len(collections))
+	for _, collection := range collections {
+		pbCollections = append(pbCollections, toPBCollection(collection))
+	}
+
+	return &pb.GetAllCollectionsResponse{Collections: pbCollections}, nil
+}
+
+// PostInviteMember handles the gRPC request to invite a user to a collection.
+func (h *CollectionHandler) PostInviteMember(ctx context.Context, in *pb.PostInviteMemberRequest) (*pb.PostInviteMemberResponse, error) {
+	req := model.CollectionInviteRequest{
+		CollectionID: in.CollectionId,
+		Login:        in.Login,
+		Role:         in.Role,
+	}
+
+	report, ok := h.validator.ValidateInviteRequest(&req)
+	if !ok {
+		logrus.Info("Unable to invite member: invalid invite request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid invite request", report)
+	}
+
+	member, err := h.collectionService.InviteMember(ctx, req)
+	if errors.Is(err, cerrors.ErrUserNotFound) {
+		logrus.Infof("Unable to invite member: user %s not found", req.Login)
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	if errors.Is(err, cerrors.ErrMemberExists) {
+		logrus.Infof("Unable to invite member: user %s is already a member of collection %s", req.Login, req.CollectionID)
+		return nil, status.Error(codes.AlreadyExists, "user is already a member of the collection")
+	}
+
+	if err != nil {
+		return nil, collectionError("invite member", req.CollectionID, err)
+	}
+
+	return &pb.PostInviteMemberResponse{Member: toPBMember(member)}, nil
+}
+
+// PostAcceptInvitation handles the gRPC request to accept an invitation to a collection.
+func (h *CollectionHandler) PostAcceptInvitation(ctx context.Context, in *pb.PostAcceptInvitationRequest) (*pb.PostAcceptInvitationResponse, error) {
+	req := model.CollectionMemberRequest{CollectionID: in.CollectionId}
+
+	report, ok := h.validator.ValidateMemberRequest(&req)
+	if !ok {
+		logrus.Info("Unable to accept invitation: invalid request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid accept invitation request", report)
+	}
+
+	collection, err := h.collectionService.AcceptInvitation(ctx, req.CollectionID)
+	if errors.Is(err, cerrors.ErrClientSideEncryption) {
+		logrus.Info("Unable to accept invitation: client side encryption is enabled")
+		return nil, status.Error(codes.FailedPrecondition, "shared collections are not available with client side encryption")
+	}
+
+	if errors.Is(err, cerrors.ErrCollectionNotFound) {
+		logrus.Infof("Unable to accept invitation: no invitation to collection %s", req.CollectionID)
+		return nil, status.Error(codes.NotFound, "invitation not found")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("failed to accept invitation")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PostAcceptInvitationResponse{Collection: toPBCollection(collection)}, nil
+}
+
+// DeleteRevokeMember handles the gRPC request to revoke a member or an invitation of a collection.
+func (h *CollectionHandler) DeleteRevokeMember(ctx context.Context, in *pb.DeleteRevokeMemberRequest) (*pb.DeleteRevokeMemberResponse, error) {
+	req := model.CollectionMemberRequest{CollectionID: in.CollectionId, Login: in.Login}
+
+	report, ok := h.validator.ValidateMemberRequest(&req)
+	if !ok {
+		logrus.Info("Unable to revoke member: invalid request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid revoke member request", report)
+	}
+
+	err := h.collectionService.RevokeMember(ctx, req)
+	if errors.Is(err, cerrors.ErrMemberNotFound) {
+		logrus.Infof("Unable to revoke member: %s is not a member of collection %s", req.Login, req.CollectionID)
+		return nil, status.Error(codes.NotFound, "member not found")
+	}
+
+	if errors.Is(err, cerrors.ErrLastManager) {
+		logrus.Infof("Unable to revoke member: last manager of collection %s", req.CollectionID)
+		return nil, status.Error(codes.FailedPrecondition, "collection must keep at least one manager")
+	}
+
+	if err != nil {
+		return nil, collectionError("revoke member", req.CollectionID, err)
+	}
+
+	return &pb.DeleteRevokeMemberResponse{}, nil
+}
+
+// GetLoadAllMembers handles the gRPC request to load the members of a collection.
+func (h *CollectionHandler) GetLoadAllMembers(ctx context.Context, in *pb.GetAllMembersRequest) (*pb.GetAllMembersResponse, error) {
+	req := model.CollectionMemberRequest{CollectionID: in.CollectionId}
+
+	report, ok := h.validator.ValidateMemberRequest(&req)
+	if !ok {
+		logrus.Info("Unable to load members: invalid request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid load members request", report)
+	}
+
+	members, err := h.collectionService.LoadMembers(ctx, req.CollectionID)
+	if err != nil {
+		return nil, collectionError("load members", req.CollectionID, err)
+	}
+
+	pbMembers := make([]*pb.CollectionMember, 0, len(members))
+	for _, member := range members {
+		pbMembers = append(pbMembers, toPBMember(member))
+	}
+
+	return &pb.GetAllMembersResponse{Members: pbMembers}, nil
+}
+
+// PostSaveCollectionItem handles the gRPC request to save an item into a collection.
+func (h *CollectionHandler) PostSaveCollectionItem(ctx context.Context, in *pb.PostCollectionItemRequest) (*pb.PostCollectionItemResponse, error) {
+	req := model.CollectionItemPostRequest{
+		CollectionID: in.CollectionId,
+		DataType:     in.DataType,
+		Data:         in.Data,
+		MetaData:     in.Metadata,
+	}
+
+	report, ok := h.validator.ValidateItemPostRequest(&req)
+	if !ok {
+		logrus.Info("Unable to save collection item: invalid item request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid collection item post request", report)
+	}
+
+	item, err := h.collectionService.SaveItem(ctx, req)
+	if err != nil {
+		return nil, collectionError("save collection item", req.CollectionID, err)
+	}
+
+	return &pb.PostCollectionItemResponse{Item: toPBItem(item)}, nil
+}
+
+// GetLoadCollectionItem handles the gRPC request to load an item of a collection.
+func (h *CollectionHandler) GetLoadCollectionItem(ctx context.Context, in *pb.GetCollectionItemRequest) (*pb.GetCollectionItemResponse, error) {
+	req := model.CollectionItemRequest{CollectionID: in.CollectionId, ID: in.Id}
+
+	report, ok := h.validator.ValidateItemRequest(&req)
+	if !ok {
+		logrus.Info("Unable to load collection item: invalid item request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid collection item get request", report)
+	}
+
+	item, err := h.collectionService.LoadItem(ctx, req)
+	if err != nil {
+		return nil, collectionError("load collection item", req.CollectionID, err)
+	}
+
+	return &pb.GetCollectionItemResponse{Item: toPBItem(item)}, nil
+}
+
+// GetLoadAllCollectionItems handles the gRPC request to load the items of a collection without their payload.
+func (h *CollectionHandler) GetLoadAllCollectionItems(ctx context.Context, in *pb.GetAllCollectionItemsRequest) (*pb.GetAllCollectionItemsResponse, error) {
+	req := model.CollectionMemberRequest{CollectionID: in.CollectionId}
+
+	report, ok := h.validator.ValidateMemberRequest(&req)
+	if !ok {
+		logrus.Info("Unable to load collection items: invalid request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid collection items get request", report)
+	}
+
+	items, err := h.collectionService.LoadItems(ctx, req.CollectionID)
+	if err != nil {
+		return nil, collectionError("load collection items", req.CollectionID, err)
+	}
+
+	pbItems := make([]*pb.CollectionItem, 0, len(items))
+	for _, item := range items {
+		pbItems = append(pbItems, toPBItem(item))
+	}
+
+	return &pb.GetAllCollectionItemsResponse{Items: pbItems}, nil
+}
+
+// PutUpdateCollectionItem handles the gRPC request to update an item of a collection.
+func (h *CollectionHandler) PutUpdateCollectionItem(ctx context.Context, in *pb.PutCollectionItemRequest) (*pb.PutCollectionItemResponse, error) {
+	req := model.CollectionItemPutRequest{
+		ID:           in.Id,
+		CollectionID: in.CollectionId,
+		Data:         in.Data,
+		MetaData:     in.Metadata,
+		Revision:     in.Revision,
+	}
+
+	report, ok := h.validator.ValidateItemPutRequest(&req)
+	if !ok {
+		logrus.Info("Unable to update collection item: invalid item request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid collection item put request", report)
+	}
+
+	item, err := h.collectionService.UpdateItem(ctx, req)
+	if errors.Is(err, cerrors.ErrRevisionConflict) {
+		logrus.Infof("Unable to update collection item: item %s has a newer revision", req.ID)
+		return nil, status.Error(codes.Aborted, "collection item was changed by another client")
+	}
+
+	if err != nil {
+		return nil, collectionError("update collection item", req.CollectionID, err)
+	}
+
+	return &pb.PutCollectionItemResponse{Item: toPBItem(item)}, nil
+}
+
+// DeleteCollectionItem handles the gRPC request to delete an item of a collection.
+func (h *CollectionHandler) DeleteCollectionItem(ctx context.Context, in *pb.DeleteCollectionItemRequest) (*pb.DeleteCollectionItemResponse, error) {
+	req := model.CollectionItemRequest{CollectionID: in.CollectionId, ID: in.Id}
+
+	report, ok := h.validator.ValidateItemRequest(&req)
+	if !ok {
+		logrus.Info("Unable to delete collection item: invalid item request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid collection item delete request", report)
+	}
+
+	if err := h.collectionService.DeleteItem(ctx, req); err != nil {
+		return nil, collectionError("delete collection item", req.CollectionID, err)
+	}
+
+	return &pb.DeleteCollectionItemResponse{}, nil
+}
+
+// collectionError converts the errors shared by the collection operations into gRPC errors.
+func collectionError(action, collectionID string, err error) error {
+	switch {
+	case errors.Is(err, cerrors.ErrCollectionNotFound):
+		logrus.Infof("Unable to %s: collection %s not found", action, collectionID)
+		return status.Error(codes.NotFound, "collection not found")
+	case errors.Is(err, cerrors.ErrPermissionDenied):
+		logrus.Infof("Unable to %s: role in collection %s does not permit it", action, collectionID)
+		return status.Error(codes.PermissionDenied, "role in the collection does not permit this")
+	case errors.Is(err, cerrors.ErrDataNotFound):
+		logrus.Infof("Unable to %s: item of collection %s not found", action, collectionID)
+		return status.Error(codes.NotFound, "collection item not found")
+	case errors.Is(err, cerrors.ErrClientSideEncryption):
+		logrus.Infof("Unable to %s: client side encryption is enabled", action)
+		return status.Error(codes.FailedPrecondition, "shared collections are not available with client side encryption")
+	default:
+		logrus.WithError(err).Errorf("failed to %s", action)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// toPBCollection converts a collection to its protobuf representation.
+func toPBCollection(collection model.Collection) *pb.Collection {
+	return &pb.Collection{
+		Id:        collection.ID,
+		Name:      collection.Name,
+		Role:      collection.Role,
+		Status:    collection.Status,
+		CreatedAt: collection.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// toPBMember converts a member of a collection to its protobuf representation, without the wrapped collection key.
+func toPBMember(member model.CollectionMember) *pb.CollectionMember {
+	return &pb.CollectionMember{
+		UserId:    member.UserID,
+		Login:     member.Login,
+		Role:      member.Role,
+		Status:    member.Status,
+		InvitedBy: member.InvitedBy,
+		CreatedAt: member.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// toPBItem converts an item of a collection to its protobuf representation.
+func toPBItem(item model.CollectionItem) *pb.CollectionItem {
+	return &pb.CollectionItem{
+		Id:           item.ID,
+		CollectionId: item.CollectionID,
+		DataType:     item.Type,
+		Data:         item.Data,
+		Metadata:     item.MetaData,
+		CreatedBy:    item.CreatedBy,
+		CreatedAt:    item.CreatedAt.Format(time.RFC3339Nano),
+		UpdatedAt:    item.UpdatedAt.Format(time.RFC3339Nano),
+		Revision:     item.Revision,
+	}
+}
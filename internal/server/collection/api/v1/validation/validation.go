@@ -0,0 +1,80 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// Validator struct holds an instance of the validator.
+// It is used to validate the requests of shared collections.
+type Validator struct {
+	validator *validator.Validate // The validator instance for performing validation checks
+}
+
+// New initializes a new Validator instance with a validator.
+func New(validator *validator.Validate) *Validator {
+	return &Validator{validator: validator}
+}
+
+// ValidateCollectionRequest validates the incoming request for creating a collection.
+func (v *Validator) ValidateCollectionRequest(req *model.CollectionRequest) (map[string]string, bool) {
+	return v.validateStruct(req)
+}
+
+// ValidateInviteRequest validates the incoming request for inviting a user to a collection.
+func (v *Validator) ValidateInviteRequest(req *model.CollectionInviteRequest) (map[string]string, bool) {
+	return v.validateStruct(req)
+}
+
+// ValidateMemberRequest validates the incoming request addressing a collection or a member of it.
+func (v *Validator) ValidateMemberRequest(req *model.CollectionMemberRequest) (map[string]string, bool) {
+	return v.validateStruct(req)
+}
+
+// ValidateItemPostRequest validates the incoming request for saving an item into a collection.
+func (v *Validator) ValidateItemPostRequest(req *model.CollectionItemPostRequest) (map[string]string, bool) {
+	return v.validateStruct(req)
+}
+
+// ValidateItemPutRequest validates the incoming request for updating an item of a collection.
+func (v *Validator) ValidateItemPutRequest(req *model.CollectionItemPutRequest) (map[string]string, bool) {
+	return v.validateStruct(req)
+}
+
+// ValidateItemRequest validates the incoming request for loading or deleting an item of a collection.
+func (v *Validator) ValidateItemRequest(req *model.CollectionItemRequest) (map[string]string, bool) {
+	return v.validateStruct(req)
+}
+
+// validateStruct validates the request against its tags and returns a map of validation errors if any exist.
+func (v *Validator) validateStruct(req any) (map[string]string, bool) {
+	err := v.validator.Struct(req)
+	report := make(map[string]string)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				switch validationErr.Tag() {
+				case "required":
+					report[validationErr.Field()] = "is required"
+				case "max":
+					unit := " characters"
+					if validationErr.Kind() == reflect.Slice {
+						unit = " bytes"
+					}
+					report[validationErr.Field()] = "must be at most " + validationErr.Param() + unit
+				case "oneof":
+					report[validationErr.Field()] = "must be one of " + strings.ReplaceAll(validationErr.Param(), " ", ", ")
+				}
+			}
+			return report, false
+		}
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+	return nil, true
+}
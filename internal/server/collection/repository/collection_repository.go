@@ -0,0 +1,537 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/storage/postgresql"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// PostgresCollectionRepository defines a repository that interacts with PostgreSQL to manage shared collections,
+// their members and items.
+type PostgresCollectionRepository struct {
+	postgresPool *postgresql.PostgresPool // Connection pool to PostgreSQL database
+}
+
+// New creates a new PostgresCollectionRepository instance with the provided PostgreSQL connection pool.
+func New(postgresPool *postgresql.PostgresPool) *PostgresCollectionRepository {
+	return &PostgresCollectionRepository{postgresPool: postgresPool}
+}
+
+// Insert saves a new collection together with its first member in a single transaction
+// and returns the collection as seen by the member.
+func (r *PostgresCollectionRepository) Insert(ctx context.Context, collection model.Collection, member model.CollectionMember) (model.Collection, error) {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	// The collection key of the member is wrapped with the key of the user
+	if err = postgresql.LockUserKey(ctx, tx, member.UserID); err != nil {
+		return model.Collection{}, err
+	}
+
+	err = tx.QueryRow(ctx,
+		`
+			insert into privatekeeper.collection
+			    (id, name, created_at)
+			values
+				($1, $2, now())
+			returning created_at;
+			`,
+		collection.ID,
+		collection.Name).Scan(&collection.CreatedAt)
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("insert collection: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`
+			insert into privatekeeper.collection_member
+			    (collection_id, user_id, role, status, crypt_key, invited_by)
+			values
+				($1, $2, $3, $4, $5, $6);
+			`,
+		collection.ID,
+		member.UserID,
+		member.Role,
+		member.Status,
+		member.CryptKey,
+		member.InvitedBy)
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("insert member: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return model.Collection{}, fmt.Errorf("commit tx: %w", err)
+	}
+
+	collection.Role = member.Role
+	collection.Status = member.Status
+
+	return collection, nil
+}
+
+// SelectAll retrieves the collections the user is a member of or invited to, ordered by name.
+func (r *PostgresCollectionRepository) SelectAll(ctx context.Context, userID string) ([]model.Collection, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    c.id, c.name, m.role, m.status, c.created_at
+			from privatekeeper.collection c
+			join privatekeeper.collection_member m on m.collection_id = c.id
+			where m.user_id = $1
+			order by c.name, c.id;
+			`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("make query: %w", err)
+	}
+
+	collections, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.Collection])
+	if err != nil {
+		return nil, fmt.Errorf("collect row: %w", err)
+	}
+
+	return collections, nil
+}
+
+// SelectByID retrieves a collection as seen by the user. It returns cerrors.ErrCollectionNotFound
+// if the collection doesn't exist or the user is neither a member of it nor invited to it.
+func (r *PostgresCollectionRepository) SelectByID(ctx context.Context, collectionID, userID string) (model.Collection, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    c.id, c.name, m.role, m.status, c.created_at
+			from privatekeeper.collection c
+			join privatekeeper.collection_member m on m.collection_id = c.id
+			where c.id = $1 and m.user_id = $2;
+			`,
+		collectionID, userID)
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("make query: %w", err)
+	}
+
+	collection, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.Collection])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Collection{}, fmt.Errorf("collect row: %w", cerrors.ErrCollectionNotFound)
+	}
+
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return collection, nil
+}
+
+// SelectMember retrieves the membership of the user in a collection. It returns cerrors.ErrCollectionNotFound
+// if the collection doesn't exist or the user is neither a member of it nor invited to it.
+func (r *PostgresCollectionRepository) SelectMember(ctx context.Context, collectionID, userID string) (model.CollectionMember, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    m.collection_id, m.user_id, u.login, m.role, m.status, m.crypt_key, m.invited_by, m.created_at
+			from privatekeeper.collection_member m
+			join privatekeeper.user u on u.id = m.user_id
+			where m.collection_id = $1 and m.user_id = $2;
+			`,
+		collectionID, userID)
+	if err != nil {
+		return model.CollectionMember{}, fmt.Errorf("make query: %w", err)
+	}
+
+	member, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.CollectionMember])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CollectionMember{}, fmt.Errorf("collect row: %w", cerrors.ErrCollectionNotFound)
+	}
+
+	if err != nil {
+		return model.CollectionMember{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return member, nil
+}
+
+// SelectMembers retrieves the members of a collection, including the invited users, ordered by login.
+// The wrapped collection keys are not selected.
+func (r *PostgresCollectionRepository) SelectMembers(ctx context.Context, collectionID string) ([]model.CollectionMember, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    m.collection_id, m.user_id, u.login, m.role, m.status, null::bytea, m.invited_by, m.created_at
+			from privatekeeper.collection_member m
+			join privatekeeper.user u on u.id = m.user_id
+			where m.collection_id = $1
+			order by u.login;
+			`,
+		collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("make query: %w", err)
+	}
+
+	members, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.CollectionMember])
+	if err != nil {
+		return nil, fmt.Errorf("collect row: %w", err)
+	}
+
+	return members, nil
+}
+
+// InsertInvitation invites the user with the login to a collection. The key of the user is locked while wrap
+// wraps the collection key with it, so a concurrent rotation of the user key can't leave the invitation
+// wrapped with a replaced key. It returns cerrors.ErrUserNotFound if there is no user with the login,
+// cerrors.ErrClientSideEncryption if the user has client side encryption enabled
+// and cerrors.ErrMemberExists if the user is already a member or invited.
+func (r *PostgresCollectionRepository) InsertInvitation(ctx context.Context, member model.CollectionMember, login string, wrap func(key model.UserCryptKey) ([]byte, error)) (model.CollectionMember, error) {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return model.CollectionMember{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var (
+		key        model.UserCryptKey
+		clientSide bool
+	)
+	err = tx.QueryRow(ctx,
+		`
+			select
+				id, crypt_key, crypt_key_version, crypt_key_generation, client_side_encryption
+			from privatekeeper.user
+			where login = $1
+			for share;
+			`,
+		login).Scan(&key.UserID, &key.CryptKey, &key.Version, &key.Generation, &clientSide)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CollectionMember{}, fmt.Errorf("select user: %w", cerrors.ErrUserNotFound)
+	}
+
+	if err != nil {
+		return model.CollectionMember{}, fmt.Errorf("select user: %w", err)
+	}
+
+	if clientSide {
+		return model.CollectionMember{}, fmt.Errorf("select user: %w", cerrors.ErrClientSideEncryption)
+	}
+
+	member.UserID = key.UserID
+	member.Login = login
+	member.CryptKey, err = wrap(key)
+	if err != nil {
+		return model.CollectionMember{}, fmt.Errorf("wrap collection key: %w", err)
+	}
+
+	err = tx.QueryRow(ctx,
+		`
+			insert into privatekeeper.collection_member
+			    (collection_id, user_id, role, status, crypt_key, invited_by)
+			values
+				($1, $2, $3, $4, $5, $6)
+			returning created_at;
+			`,
+		member.CollectionID,
+		member.UserID,
+		member.Role,
+		member.Status,
+		member.CryptKey,
+		member.InvitedBy).Scan(&member.CreatedAt)
+	var e *pgconn.PgError
+	if errors.As(err, &e) && e.Code == pgerrcode.UniqueViolation {
+		return model.CollectionMember{}, fmt.Errorf("insert member: %w", cerrors.ErrMemberExists)
+	}
+
+	if err != nil {
+		return model.CollectionMember{}, fmt.Errorf("insert member: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return model.CollectionMember{}, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return member, nil
+}
+
+// SelectClientSideEncryption reports whether the user has client side encryption enabled.
+func (r *PostgresCollectionRepository) SelectClientSideEncryption(ctx context.Context, userID string) (bool, error) {
+	var clientSide bool
+	err := r.postgresPool.DB.QueryRow(ctx,
+		`
+			select
+				client_side_encryption
+			from privatekeeper.user
+			where id = $1;
+			`,
+		userID).Scan(&clientSide)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("select user: %w", cerrors.ErrUserNotFound)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("select user: %w", err)
+	}
+
+	return clientSide, nil
+}
+
+// ActivateMember accepts the invitation of the user to a collection.
+// It returns cerrors.ErrCollectionNotFound if the user has no pending invitation to the collection.
+func (r *PostgresCollectionRepository) ActivateMember(ctx context.Context, collectionID, userID string) error {
+	tag, err := r.postgresPool.DB.Exec(ctx,
+		`
+			update privatekeeper.collection_member
+			set status = $3, updated_at = now()
+			where collection_id = $1 and user_id = $2 and status = $4;
+			`,
+		collectionID, userID, model.MemberActive, model.MemberInvited)
+	if err != nil {
+		return fmt.Errorf("make query: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("activate member: %w", cerrors.ErrCollectionNotFound)
+	}
+
+	return nil
+}
+
+// DeleteMember removes the user from a collection or withdraws the invitation of the user on behalf of revokedBy.
+// Users other than the removed one must be active managers of the collection, otherwise cerrors.ErrPermissionDenied
+// is returned. The last active manager can leave the collection only if no other users are members of it or invited,
+// the collection and its items are deleted then, otherwise cerrors.ErrLastManager is returned.
+// The collection row is locked and the roles are checked within the transaction, so concurrent removals
+// of managers are serialized and can't leave the collection without one.
+func (r *PostgresCollectionRepository) DeleteMember(ctx context.Context, collectionID, userID, revokedBy string) error {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var id string
+	err = tx.QueryRow(ctx,
+		`
+			select
+				id
+			from privatekeeper.collection
+			where id = $1
+			for update;
+			`,
+		collectionID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("lock collection: %w", cerrors.ErrCollectionNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("lock collection: %w", err)
+	}
+
+	if revokedBy != userID {
+		var isManager bool
+		err = tx.QueryRow(ctx,
+			`
+				select
+					exists(select 1 from privatekeeper.collection_member
+					       where collection_id = $1 and user_id = $2 and role = $3 and status = $4);
+				`,
+			collectionID, revokedBy, model.RoleManage, model.MemberActive).Scan(&isManager)
+		if err != nil {
+			return fmt.Errorf("select revoking member: %w", err)
+		}
+		if !isManager {
+			return fmt.Errorf("delete member: %w", cerrors.ErrPermissionDenied)
+		}
+	}
+
+	var (
+		role, status      string
+		managers, members int
+	)
+	err = tx.QueryRow(ctx,
+		`
+			select
+			    m.role, m.status,
+			    (select count(*) from privatekeeper.collection_member
+			     where collection_id = $1 and user_id <> $2 and role = $3 and status = $4),
+			    (select count(*) from privatekeeper.collection_member where collection_id = $1)
+			from privatekeeper.collection_member m
+			where m.collection_id = $1 and m.user_id = $2;
+			`,
+		collectionID, userID, model.RoleManage, model.MemberActive).Scan(&role, &status, &managers, &members)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("select member: %w", cerrors.ErrMemberNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("select member: %w", err)
+	}
+
+	// managers holds the active managers remaining after the removal
+	lastManager := role == model.RoleManage && status == model.MemberActive && managers == 0
+	switch {
+	case lastManager && members > 1:
+		return fmt.Errorf("delete member: %w", cerrors.ErrLastManager)
+	case lastManager:
+		_, err = tx.Exec(ctx,
+			`
+				delete from privatekeeper.collection
+				where id = $1;
+				`,
+			collectionID)
+	default:
+		_, err = tx.Exec(ctx,
+			`
+				delete from privatekeeper.collection_member
+				where collection_id = $1 and user_id = $2;
+				`,
+			collectionID, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("delete member: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
+
+// InsertItem saves a new item into a collection and returns the saved item.
+func (r *PostgresCollectionRepository) InsertItem(ctx context.Context, item model.CollectionItem) (model.CollectionItem, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			insert into privatekeeper.collection_item
+			    (id, collection_id, type, data, metadata, created_by, created_at, updated_at)
+			values
+				($1, $2, $3, $4, $5, $6, now(), now())
+			returning id, collection_id, type, data, metadata, created_by, created_at, updated_at, revision;
+			`,
+		item.ID,
+		item.CollectionID,
+		item.Type,
+		item.Data,
+		item.MetaData,
+		item.CreatedBy)
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("make query: %w", err)
+	}
+
+	savedItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.CollectionItem])
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return savedItem, nil
+}
+
+// SelectItems retrieves the items of a collection without their payload, ordered by creation time.
+func (r *PostgresCollectionRepository) SelectItems(ctx context.Context, collectionID string) ([]model.CollectionItem, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    id, collection_id, type, null::bytea, coalesce(metadata, ''), created_by, created_at, updated_at, revision
+			from privatekeeper.collection_item
+			where collection_id = $1
+			order by created_at, id;
+			`,
+		collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("make query: %w", err)
+	}
+
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.CollectionItem])
+	if err != nil {
+		return nil, fmt.Errorf("collect row: %w", err)
+	}
+
+	return items, nil
+}
+
+// SelectItem retrieves a specific item of a collection.
+func (r *PostgresCollectionRepository) SelectItem(ctx context.Context, collectionID, itemID string) (model.CollectionItem, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    id, collection_id, type, data, coalesce(metadata, ''), created_by, created_at, updated_at, revision
+			from privatekeeper.collection_item
+			where collection_id = $1 and id = $2;
+			`,
+		collectionID, itemID)
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("make query: %w", err)
+	}
+
+	item, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.CollectionItem])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CollectionItem{}, fmt.Errorf("collect row: %w", cerrors.ErrDataNotFound)
+	}
+
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return item, nil
+}
+
+// UpdateItem replaces the payload and the metadata of an item of a collection and returns the updated item.
+// An item with a revision other than the given one is not updated, unless the given revision is zero.
+func (r *PostgresCollectionRepository) UpdateItem(ctx context.Context, item model.CollectionItem) (model.CollectionItem, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			update privatekeeper.collection_item
+			set data = $3, metadata = $4, revision = revision + 1, updated_at = now()
+			where collection_id = $1 and id = $2 and ($5 = 0 or revision = $5)
+			returning id, collection_id, type, data, metadata, created_by, created_at, updated_at, revision;
+			`,
+		item.CollectionID,
+		item.ID,
+		item.Data,
+		item.MetaData,
+		item.Revision)
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("make query: %w", err)
+	}
+
+	updatedItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.CollectionItem])
+	if errors.Is(err, pgx.ErrNoRows) && item.Revision != 0 {
+		if _, err = r.SelectItem(ctx, item.CollectionID, item.ID); err != nil {
+			return model.CollectionItem{}, fmt.Errorf("select item: %w", err)
+		}
+		return model.CollectionItem{}, fmt.Errorf("collect row: %w", cerrors.ErrRevisionConflict)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CollectionItem{}, fmt.Errorf("collect row: %w", cerrors.ErrDataNotFound)
+	}
+
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return updatedItem, nil
+}
+
+// DeleteItem removes a specific item from a collection.
+func (r *PostgresCollectionRepository) DeleteItem(ctx context.Context, collectionID, itemID string) error {
+	tag, err := r.postgresPool.DB.Exec(ctx,
+		`
+			delete from privatekeeper.collection_item
+			where collection_id = $1 and id = $2;
+			`,
+		collectionID, itemID)
+	if err != nil {
+		return fmt.Errorf("make query: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete item: %w", cerrors.ErrDataNotFound)
+	}
+
+	return nil
+}
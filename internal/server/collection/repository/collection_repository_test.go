@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/storage/postgresql"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// envTestDatabaseURI holds the database the tests of the repository are run against, they are skipped without it.
+const envTestDatabaseURI = "TEST_DATABASE_URI"
+
+type CollectionRepositoryTestSuite struct {
+	suite.Suite
+	pool         *postgresql.PostgresPool
+	repo         *PostgresCollectionRepository
+	collectionID string
+	users        map[string]string // User IDs by login
+}
+
+func TestSuite(t *testing.T) {
+	if os.Getenv(envTestDatabaseURI) == "" {
+		t.Skipf("%s is not set", envTestDatabaseURI)
+	}
+	suite.Run(t, new(CollectionRepositoryTestSuite))
+}
+
+func (s *CollectionRepositoryTestSuite) SetupSuite() {
+	ctx := context.Background()
+	pool, err := postgresql.NewPool(ctx, os.Getenv(envTestDatabaseURI))
+	s.Require().NoError(err)
+	s.pool = pool
+
+	migrations, err := postgresql.NewMigrations(pool)
+	s.Require().NoError(err)
+	s.Require().NoError(migrations.Up())
+
+	s.repo = New(pool)
+}
+
+func (s *CollectionRepositoryTestSuite) TearDownSuite() {
+	s.pool.DB.Close()
+}
+
+// SetupTest creates a collection managed by alice and bob with carol as a reader.
+func (s *CollectionRepositoryTestSuite) SetupTest() {
+	ctx := context.Background()
+	s.users = map[string]string{}
+	for _, login := range []string{"alice", "bob", "carol"} {
+		id := uuid.NewString()
+		_, err := s.pool.DB.Exec(ctx,
+			`
+				insert into privatekeeper.user (id, login, password, crypt_key, created_at, crypt_key_version)
+				values ($1, $2, '', '', now(), 0);
+				`,
+			id, login+"-"+id)
+		s.Require().NoError(err)
+		s.users[login] = id
+	}
+
+	s.collectionID = uuid.NewString()
+	_, err := s.pool.DB.Exec(ctx,
+		`insert into privatekeeper.collection (id, name, created_at) values ($1, 'team', now());`, s.collectionID)
+	s.Require().NoError(err)
+	for login, role := range map[string]string{"alice": model.RoleManage, "bob": model.RoleManage, "carol": model.RoleRead} {
+		_, err = s.pool.DB.Exec(ctx,
+			`
+				insert into privatekeeper.collection_member (collection_id, user_id, role, status, crypt_key, invited_by)
+				values ($1, $2, $3, $4, '', $5);
+				`,
+			s.collectionID, s.users[login], role, model.MemberActive, s.users["alice"])
+		s.Require().NoError(err)
+	}
+}
+
+func (s *CollectionRepositoryTestSuite) TearDownTest() {
+	ctx := context.Background()
+	_, err := s.pool.DB.Exec(ctx, `delete from privatekeeper.collection where id = $1;`, s.collectionID)
+	s.NoError(err)
+	for _, id := range s.users {
+		_, err = s.pool.DB.Exec(ctx, `delete from privatekeeper.user where id = $1;`, id)
+		s.NoError(err)
+	}
+}
+
+// Test_LastManagerLeaves checks that a manager leaves while another one remains and that the last one can't.
+func (s *CollectionRepositoryTestSuite) Test_LastManagerLeaves() {
+	ctx := context.Background()
+	alice, bob := s.users["alice"], s.users["bob"]
+
+	require.NoError(s.T(), s.repo.DeleteMember(ctx, s.collectionID, bob, bob))
+
+	err := s.repo.DeleteMember(ctx, s.collectionID, alice, alice)
+	assert.ErrorIs(s.T(), err, cerrors.ErrLastManager)
+	assert.Equal(s.T(), 1, s.managers())
+}
+
+// Test_RevokeManager checks that only active managers revoke others and that managers revoking each other
+// concurrently leave one of them.
+func (s *CollectionRepositoryTestSuite) Test_RevokeManager() {
+	ctx := context.Background()
+	alice, bob, carol := s.users["alice"], s.users["bob"], s.users["carol"]
+
+	err := s.repo.DeleteMember(ctx, s.collectionID, alice, carol)
+	assert.ErrorIs(s.T(), err, cerrors.ErrPermissionDenied)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, revoke := range [][2]string{{alice, bob}, {bob, alice}} {
+		wg.Add(1)
+		go func(i int, userID, revokedBy string) {
+			defer wg.Done()
+			errs[i] = s.repo.DeleteMember(ctx, s.collectionID, userID, revokedBy)
+		}(i, revoke[0], revoke[1])
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err = range errs {
+		if err != nil {
+			assert.ErrorIs(s.T(), err, cerrors.ErrPermissionDenied)
+			failed++
+		}
+	}
+	assert.Equal(s.T(), 1, failed, "one of the managers must be revoked first")
+	assert.Equal(s.T(), 1, s.managers())
+}
+
+// Test_SoleManagerLeaves deletes the collection when its last manager is its last member.
+func (s *CollectionRepositoryTestSuite) Test_SoleManagerLeaves() {
+	ctx := context.Background()
+	alice := s.users["alice"]
+
+	require.NoError(s.T(), s.repo.DeleteMember(ctx, s.collectionID, s.users["carol"], alice))
+	require.NoError(s.T(), s.repo.DeleteMember(ctx, s.collectionID, s.users["bob"], alice))
+	require.NoError(s.T(), s.repo.DeleteMember(ctx, s.collectionID, alice, alice))
+
+	_, err := s.repo.SelectByID(ctx, s.collectionID, alice)
+	assert.ErrorIs(s.T(), err, cerrors.ErrCollectionNotFound)
+}
+
+func (s *CollectionRepositoryTestSuite) managers() int {
+	var managers int
+	err := s.pool.DB.QueryRow(context.Background(),
+		`
+			select count(*) from privatekeeper.collection_member
+			where collection_id = $1 and role = $2 and status = $3;
+			`,
+		s.collectionID, model.RoleManage, model.MemberActive).Scan(&managers)
+	s.Require().NoError(err)
+	return managers
+}
@@ -0,0 +1,413 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// CollectionRepository interface defines methods for access to shared collections, their members and items.
+type CollectionRepository interface {
+	Insert(ctx context.Context, collection model.Collection, member model.CollectionMember) (model.Collection, error)
+	SelectAll(ctx context.Context, userID string) ([]model.Collection, error)
+	SelectByID(ctx context.Context, collectionID, userID string) (model.Collection, error)
+	SelectMember(ctx context.Context, collectionID, userID string) (model.CollectionMember, error)
+	SelectMembers(ctx context.Context, collectionID string) ([]model.CollectionMember, error)
+	InsertInvitation(ctx context.Context, member model.CollectionMember, login string, wrap func(key model.UserCryptKey) ([]byte, error)) (model.CollectionMember, error)
+	SelectClientSideEncryption(ctx context.Context, userID string) (bool, error)
+	ActivateMember(ctx context.Context, collectionID, userID string) error
+	DeleteMember(ctx context.Context, collectionID, userID, revokedBy string) error
+	InsertItem(ctx context.Context, item model.CollectionItem) (model.CollectionItem, error)
+	SelectItems(ctx context.Context, collectionID string) ([]model.CollectionItem, error)
+	SelectItem(ctx context.Context, collectionID, itemID string) (model.CollectionItem, error)
+	UpdateItem(ctx context.Context, item model.CollectionItem) (model.CollectionItem, error)
+	DeleteItem(ctx context.Context, collectionID, itemID string) error
+}
+
+// CryptService interface defines methods for encryption and decryption of collection keys and items.
+type CryptService interface {
+	Encrypt(key, data []byte) ([]byte, error)
+	Decrypt(key, data []byte) ([]byte, error)
+	GenerateKey() ([]byte, error)
+	DecryptWithMasterKeyVersion(version int, data []byte) ([]byte, error)
+}
+
+// CollectionService struct manages shared collections. Every collection has its own key the items are encrypted
+// with, the key is stored wrapped with the key of every member and unwrapped with the key of the requesting user.
+// As the server sees the items in plaintext, users with client side encryption can't use collections,
+// they can only leave the collections they joined before.
+type CollectionService struct {
+	repository CollectionRepository // Repository of collections
+	crypt      CryptService         // Service for cryptographic operations
+}
+
+// New creates a new instance of CollectionService.
+func New(repository CollectionRepository, crypt CryptService) *CollectionService {
+	return &CollectionService{
+		repository: repository,
+		crypt:      crypt,
+	}
+}
+
+// CreateCollection creates a new collection with a new key, the user becomes its manager.
+func (s *CollectionService) CreateCollection(ctx context.Context, req model.CollectionRequest) (model.Collection, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.Collection{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	if err := s.checkEncryption(ctx, userID); err != nil {
+		return model.Collection{}, err
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return model.Collection{}, fmt.Errorf("failed to get userKey from context")
+	}
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("new uuid: %w", err)
+	}
+
+	collectionKey, err := s.crypt.GenerateKey()
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("generate collection key: %w", err)
+	}
+
+	cryptKey, err := s.crypt.Encrypt(userKey, collectionKey)
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("wrap collection key: %w", err)
+	}
+
+	collection, err := s.repository.Insert(ctx, model.Collection{ID: id.String(), Name: req.Name}, model.CollectionMember{
+		UserID:    userID,
+		Role:      model.RoleManage,
+		Status:    model.MemberActive,
+		CryptKey:  cryptKey,
+		InvitedBy: userID,
+	})
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("insert collection: %w", err)
+	}
+
+	return collection, nil
+}
+
+// LoadCollections retrieves the collections the user is a member of or invited to.
+func (s *CollectionService) LoadCollections(ctx context.Context) ([]model.Collection, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to get userID from context")
+	}
+
+	if err := s.checkEncryption(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	collections, err := s.repository.SelectAll(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select collections: %w", err)
+	}
+
+	return collections, nil
+}
+
+// InviteMember invites the user with the login to the collection with the role. The collection key is wrapped
+// with the key of the invited user right away, the user gets access once the invitation is accepted.
+// Only managers can invite, users with client side encryption can't be invited.
+func (s *CollectionService) InviteMember(ctx context.Context, req model.CollectionInviteRequest) (model.CollectionMember, error) {
+	member, err := s.member(ctx, req.CollectionID, model.RoleManage)
+	if err != nil {
+		return model.CollectionMember{}, err
+	}
+
+	collectionKey, err := s.collectionKey(ctx, member)
+	if err != nil {
+		return model.CollectionMember{}, err
+	}
+
+	invited, err := s.repository.InsertInvitation(ctx, model.CollectionMember{
+		CollectionID: req.CollectionID,
+		Role:         req.Role,
+		Status:       model.MemberInvited,
+		InvitedBy:    member.UserID,
+	}, req.Login, func(key model.UserCryptKey) ([]byte, error) {
+		userKey, err := s.crypt.DecryptWithMasterKeyVersion(key.Version, key.CryptKey)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt with master key: %w", err)
+		}
+		return s.crypt.Encrypt(userKey, collectionKey)
+	})
+	if err != nil {
+		return model.CollectionMember{}, fmt.Errorf("insert invitation: %w", err)
+	}
+	invited.CryptKey = nil
+
+	return invited, nil
+}
+
+// AcceptInvitation accepts the pending invitation of the user to the collection.
+func (s *CollectionService) AcceptInvitation(ctx context.Context, collectionID string) (model.Collection, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.Collection{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	if err := s.checkEncryption(ctx, userID); err != nil {
+		return model.Collection{}, err
+	}
+
+	if err := s.repository.ActivateMember(ctx, collectionID, userID); err != nil {
+		return model.Collection{}, fmt.Errorf("activate member: %w", err)
+	}
+
+	collection, err := s.repository.SelectByID(ctx, collectionID, userID)
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("select collection: %w", err)
+	}
+
+	return collection, nil
+}
+
+// RevokeMember removes the member with the login from the collection or withdraws the invitation.
+// Managers can revoke anyone, other users can only leave the collection or decline their invitation,
+// which is done by revoking their own login or an empty one.
+func (s *CollectionService) RevokeMember(ctx context.Context, req model.CollectionMemberRequest) error {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get userID from context")
+	}
+
+	requester, err := s.repository.SelectMember(ctx, req.CollectionID, userID)
+	if err != nil {
+		return fmt.Errorf("select member: %w", err)
+	}
+
+	targetID := userID
+	if req.Login != "" && req.Login != requester.Login {
+		if requester.Status != model.MemberActive {
+			return fmt.Errorf("revoke member: %w", cerrors.ErrCollectionNotFound)
+		}
+		if !model.RoleAllows(requester.Role, model.RoleManage) {
+			return fmt.Errorf("revoke member: %w", cerrors.ErrPermissionDenied)
+		}
+
+		members, err := s.repository.SelectMembers(ctx, req.CollectionID)
+		if err != nil {
+			return fmt.Errorf("select members: %w", err)
+		}
+		targetID = ""
+		for _, m := range members {
+			if m.Login == req.Login {
+				targetID = m.UserID
+			}
+		}
+		if targetID == "" {
+			return fmt.Errorf("revoke member: %w", cerrors.ErrMemberNotFound)
+		}
+	}
+
+	if err = s.repository.DeleteMember(ctx, req.CollectionID, targetID, userID); err != nil {
+		return fmt.Errorf("delete member: %w", err)
+	}
+
+	return nil
+}
+
+// LoadMembers retrieves the members and the invited users of the collection.
+func (s *CollectionService) LoadMembers(ctx context.Context, collectionID string) ([]model.CollectionMember, error) {
+	if _, err := s.member(ctx, collectionID, model.RoleRead); err != nil {
+		return nil, err
+	}
+
+	members, err := s.repository.SelectMembers(ctx, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("select members: %w", err)
+	}
+
+	return members, nil
+}
+
+// SaveItem encrypts the payload with the collection key and saves a new item into the collection.
+func (s *CollectionService) SaveItem(ctx context.Context, req model.CollectionItemPostRequest) (model.CollectionItem, error) {
+	member, err := s.member(ctx, req.CollectionID, model.RoleWrite)
+	if err != nil {
+		return model.CollectionItem{}, err
+	}
+
+	collectionKey, err := s.collectionKey(ctx, member)
+	if err != nil {
+		return model.CollectionItem{}, err
+	}
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("new uuid: %w", err)
+	}
+
+	cryptData, err := s.crypt.Encrypt(collectionKey, req.Data)
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("encrypt item: %w", err)
+	}
+
+	saved, err := s.repository.InsertItem(ctx, model.CollectionItem{
+		ID:           id.String(),
+		CollectionID: req.CollectionID,
+		Type:         req.DataType,
+		Data:         cryptData,
+		MetaData:     req.MetaData,
+		CreatedBy:    member.UserID,
+	})
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("insert item: %w", err)
+	}
+	saved.Data = req.Data
+
+	return saved, nil
+}
+
+// LoadItems retrieves the items of the collection without their payload.
+func (s *CollectionService) LoadItems(ctx context.Context, collectionID string) ([]model.CollectionItem, error) {
+	if _, err := s.member(ctx, collectionID, model.RoleRead); err != nil {
+		return nil, err
+	}
+
+	items, err := s.repository.SelectItems(ctx, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("select items: %w", err)
+	}
+
+	return items, nil
+}
+
+// LoadItem retrieves an item of the collection and decrypts its payload with the collection key.
+func (s *CollectionService) LoadItem(ctx context.Context, req model.CollectionItemRequest) (model.CollectionItem, error) {
+	member, err := s.member(ctx, req.CollectionID, model.RoleRead)
+	if err != nil {
+		return model.CollectionItem{}, err
+	}
+
+	collectionKey, err := s.collectionKey(ctx, member)
+	if err != nil {
+		return model.CollectionItem{}, err
+	}
+
+	item, err := s.repository.SelectItem(ctx, req.CollectionID, req.ID)
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("select item: %w", err)
+	}
+
+	item.Data, err = s.crypt.Decrypt(collectionKey, item.Data)
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("decrypt item: %w", err)
+	}
+
+	return item, nil
+}
+
+// UpdateItem encrypts the new payload with the collection key and replaces the item of the collection.
+func (s *CollectionService) UpdateItem(ctx context.Context, req model.CollectionItemPutRequest) (model.CollectionItem, error) {
+	member, err := s.member(ctx, req.CollectionID, model.RoleWrite)
+	if err != nil {
+		return model.CollectionItem{}, err
+	}
+
+	collectionKey, err := s.collectionKey(ctx, member)
+	if err != nil {
+		return model.CollectionItem{}, err
+	}
+
+	cryptData, err := s.crypt.Encrypt(collectionKey, req.Data)
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("encrypt item: %w", err)
+	}
+
+	updated, err := s.repository.UpdateItem(ctx, model.CollectionItem{
+		ID:           req.ID,
+		CollectionID: req.CollectionID,
+		Data:         cryptData,
+		MetaData:     req.MetaData,
+		Revision:     req.Revision,
+	})
+	if err != nil {
+		return model.CollectionItem{}, fmt.Errorf("update item: %w", err)
+	}
+	updated.Data = req.Data
+
+	return updated, nil
+}
+
+// DeleteItem deletes an item of the collection.
+func (s *CollectionService) DeleteItem(ctx context.Context, req model.CollectionItemRequest) error {
+	if _, err := s.member(ctx, req.CollectionID, model.RoleWrite); err != nil {
+		return err
+	}
+
+	if err := s.repository.DeleteItem(ctx, req.CollectionID, req.ID); err != nil {
+		return fmt.Errorf("delete item: %w", err)
+	}
+
+	return nil
+}
+
+// member returns the membership of the user in the collection if the user is an active member with the required role.
+// Invited users are treated as non-members until they accept the invitation.
+func (s *CollectionService) member(ctx context.Context, collectionID, required string) (model.CollectionMember, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.CollectionMember{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	if err := s.checkEncryption(ctx, userID); err != nil {
+		return model.CollectionMember{}, err
+	}
+
+	member, err := s.repository.SelectMember(ctx, collectionID, userID)
+	if err != nil {
+		return model.CollectionMember{}, fmt.Errorf("select member: %w", err)
+	}
+
+	if member.Status != model.MemberActive {
+		return model.CollectionMember{}, fmt.Errorf("select member: %w", cerrors.ErrCollectionNotFound)
+	}
+
+	if !model.RoleAllows(member.Role, required) {
+		return model.CollectionMember{}, fmt.Errorf("check role: %w", cerrors.ErrPermissionDenied)
+	}
+
+	return member, nil
+}
+
+// checkEncryption returns cerrors.ErrClientSideEncryption if the user has client side encryption enabled.
+func (s *CollectionService) checkEncryption(ctx context.Context, userID string) error {
+	clientSide, err := s.repository.SelectClientSideEncryption(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("select client side encryption: %w", err)
+	}
+
+	if clientSide {
+		return cerrors.ErrClientSideEncryption
+	}
+
+	return nil
+}
+
+// collectionKey unwraps the collection key of the member with the key of the user.
+func (s *CollectionService) collectionKey(ctx context.Context, member model.CollectionMember) ([]byte, error) {
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return nil, fmt.Errorf("failed to get userKey from context")
+	}
+
+	key, err := s.crypt.Decrypt(userKey, member.CryptKey)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap collection key: %w", err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/encryption"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// fakeRepository keeps collections in memory.
+type fakeRepository struct {
+	users       map[string]model.UserCryptKey // Wrapped user keys by login
+	collections map[string]model.Collection
+	members     map[string]map[string]model.CollectionMember // Members by collection and user ID
+	items       map[string]model.CollectionItem
+	clientSide  map[string]bool // Users with client side encryption by ID
+}
+
+func (f *fakeRepository) Insert(_ context.Context, collection model.Collection, member model.CollectionMember) (model.Collection, error) {
+	f.collections[collection.ID] = collection
+	member.CollectionID = collection.ID
+	member.Login = strings.TrimSuffix(member.UserID, "-id")
+	f.members[collection.ID] = map[string]model.CollectionMember{member.UserID: member}
+	collection.Role, collection.Status = member.Role, member.Status
+	return collection, nil
+}
+
+func (f *fakeRepository) SelectAll(_ context.Context, userID string) ([]model.Collection, error) {
+	var collections []model.Collection
+	for id, members := range f.members {
+		if m, ok := members[userID]; ok {
+			c := f.collections[id]
+			c.Role, c.Status = m.Role, m.Status
+			collections = append(collections, c)
+		}
+	}
+	return collections, nil
+}
+
+func (f *fakeRepository) SelectByID(_ context.Context, collectionID, userID string) (model.Collection, error) {
+	m, ok := f.members[collectionID][userID]
+	if !ok {
+		return model.Collection{}, cerrors.ErrCollectionNotFound
+	}
+	c := f.collections[collectionID]
+	c.Role, c.Status = m.Role, m.Status
+	return c, nil
+}
+
+func (f *fakeRepository) SelectMember(_ context.Context, collectionID, userID string) (model.CollectionMember, error) {
+	m, ok := f.members[collectionID][userID]
+	if !ok {
+		return model.CollectionMember{}, cerrors.ErrCollectionNotFound
+	}
+	return m, nil
+}
+
+func (f *fakeRepository) SelectMembers(_ context.Context, collectionID string) ([]model.CollectionMember, error) {
+	var members []model.CollectionMember
+	for _, m := range f.members[collectionID] {
+		m.CryptKey = nil
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func (f *fakeRepository) InsertInvitation(_ context.Context, member model.CollectionMember, login string, wrap func(key model.UserCryptKey) ([]byte, error)) (model.CollectionMember, error) {
+	key, ok := f.users[login]
+	if !ok {
+		return model.CollectionMember{}, cerrors.ErrUserNotFound
+	}
+	if f.clientSide[key.UserID] {
+		return model.CollectionMember{}, cerrors.ErrClientSideEncryption
+	}
+	if _, ok = f.members[member.CollectionID][key.UserID]; ok {
+		return model.CollectionMember{}, cerrors.ErrMemberExists
+	}
+
+	cryptKey, err := wrap(key)
+	if err != nil {
+		return model.CollectionMember{}, err
+	}
+	member.UserID, member.Login, member.CryptKey = key.UserID, login, cryptKey
+	f.members[member.CollectionID][key.UserID] = member
+	return member, nil
+}
+
+func (f *fakeRepository) SelectClientSideEncryption(_ context.Context, userID string) (bool, error) {
+	return f.clientSide[userID], nil
+}
+
+func (f *fakeRepository) ActivateMember(_ context.Context, collectionID, userID string) error {
+	m, ok := f.members[collectionID][userID]
+	if !ok || m.Status != model.MemberInvited {
+		return cerrors.ErrCollectionNotFound
+	}
+	m.Status = model.MemberActive
+	f.members[collectionID][userID] = m
+	return nil
+}
+
+func (f *fakeRepository) DeleteMember(_ context.Context, collectionID, userID, revokedBy string) error {
+	if revokedBy != userID {
+		r, ok := f.members[collectionID][revokedBy]
+		if !ok || r.Role != model.RoleManage || r.Status != model.MemberActive {
+			return cerrors.ErrPermissionDenied
+		}
+	}
+	m, ok := f.members[collectionID][userID]
+	if !ok {
+		return cerrors.ErrMemberNotFound
+	}
+	managers := 0
+	for id, other := range f.members[collectionID] {
+		if id != userID && other.Role == model.RoleManage && other.Status == model.MemberActive {
+			managers++
+		}
+	}
+	if m.Role == model.RoleManage && m.Status == model.MemberActive && managers == 0 {
+		if len(f.members[collectionID]) > 1 {
+			return cerrors.ErrLastManager
+		}
+		delete(f.collections, collectionID)
+		delete(f.members, collectionID)
+		return nil
+	}
+	delete(f.members[collectionID], userID)
+	return nil
+}
+
+func (f *fakeRepository) InsertItem(_ context.Context, item model.CollectionItem) (model.CollectionItem, error) {
+	item.Revision = 1
+	f.items[item.ID] = item
+	return item, nil
+}
+
+func (f *fakeRepository) SelectItems(_ context.Context, collectionID string) ([]model.CollectionItem, error) {
+	var items []model.CollectionItem
+	for _, item := range f.items {
+		if item.CollectionID == collectionID {
+			item.Data = nil
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (f *fakeRepository) SelectItem(_ context.Context, collectionID, itemID string) (model.CollectionItem, error) {
+	item, ok := f.items[itemID]
+	if !ok || item.CollectionID != collectionID {
+		return model.CollectionItem{}, cerrors.ErrDataNotFound
+	}
+	return item, nil
+}
+
+func (f *fakeRepository) UpdateItem(_ context.Context, item model.CollectionItem) (model.CollectionItem, error) {
+	saved, ok := f.items[item.ID]
+	if !ok || saved.CollectionID != item.CollectionID {
+		return model.CollectionItem{}, cerrors.ErrDataNotFound
+	}
+	if item.Revision != 0 && item.Revision != saved.Revision {
+		return model.CollectionItem{}, cerrors.ErrRevisionConflict
+	}
+	saved.Data, saved.MetaData, saved.Revision = item.Data, item.MetaData, saved.Revision+1
+	f.items[item.ID] = saved
+	return saved, nil
+}
+
+func (f *fakeRepository) DeleteItem(_ context.Context, collectionID, itemID string) error {
+	if item, ok := f.items[itemID]; !ok || item.CollectionID != collectionID {
+		return cerrors.ErrDataNotFound
+	}
+	delete(f.items, itemID)
+	return nil
+}
+
+type CollectionServiceTestSuite struct {
+	suite.Suite
+	repository *fakeRepository
+	service    *CollectionService
+	userKeys   map[string][]byte // Unwrapped user keys by login
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(CollectionServiceTestSuite))
+}
+
+func (c *CollectionServiceTestSuite) SetupTest() {
+	crypt, err := encryption.New([]byte("test-master-key"))
+	require.NoError(c.T(), err)
+
+	c.repository = &fakeRepository{
+		users:       map[string]model.UserCryptKey{},
+		collections: map[string]model.Collection{},
+		members:     map[string]map[string]model.CollectionMember{},
+		items:       map[string]model.CollectionItem{},
+		clientSide:  map[string]bool{},
+	}
+	c.userKeys = map[string][]byte{}
+	for _, login := range []string{"alice", "bob", "carol"} {
+		key, err := crypt.GenerateKey()
+		require.NoError(c.T(), err)
+		cryptKey, err := crypt.EncryptWithMasterKey(key)
+		require.NoError(c.T(), err)
+		c.repository.users[login] = model.UserCryptKey{UserID: login + "-id", CryptKey: cryptKey, Version: crypt.Version()}
+		c.userKeys[login] = key
+	}
+
+	c.service = New(c.repository, crypt)
+}
+
+// ctx returns the context of a request of the user with the login.
+func (c *CollectionServiceTestSuite) ctx(login string) context.Context {
+	ctx := context.WithValue(context.Background(), model.UserIDKey, login+"-id")
+	return context.WithValue(ctx, model.UserKey, c.userKeys[login])
+}
+
+func (c *CollectionServiceTestSuite) Test_SharedItems() {
+	collection, err := c.service.CreateCollection(c.ctx("alice"), model.CollectionRequest{Name: "prod database"})
+	require.NoError(c.T(), err)
+	assert.Equal(c.T(), model.RoleManage, collection.Role)
+
+	item, err := c.service.SaveItem(c.ctx("alice"), model.CollectionItemPostRequest{
+		CollectionID: collection.ID, DataType: "credentials", Data: []byte(`{"Login":"postgres"}`), MetaData: "db",
+	})
+	require.NoError(c.T(), err)
+	assert.NotEqual(c.T(), []byte(`{"Login":"postgres"}`), c.repository.items[item.ID].Data, "items are stored encrypted")
+
+	_, err = c.service.InviteMember(c.ctx("alice"), model.CollectionInviteRequest{
+		CollectionID: collection.ID, Login: "bob", Role: model.RoleRead,
+	})
+	require.NoError(c.T(), err)
+
+	// Invited users have no access until they accept the invitation.
+	_, err = c.service.LoadItems(c.ctx("bob"), collection.ID)
+	assert.ErrorIs(c.T(), err, cerrors.ErrCollectionNotFound)
+
+	collections, err := c.service.LoadCollections(c.ctx("bob"))
+	require.NoError(c.T(), err)
+	require.Len(c.T(), collections, 1)
+	assert.Equal(c.T(), model.MemberInvited, collections[0].Status)
+
+	_, err = c.service.AcceptInvitation(c.ctx("bob"), collection.ID)
+	require.NoError(c.T(), err)
+
+	// The collection key wrapped for the invited user decrypts the items.
+	loaded, err := c.service.LoadItem(c.ctx("bob"), model.CollectionItemRequest{CollectionID: collection.ID, ID: item.ID})
+	require.NoError(c.T(), err)
+	assert.Equal(c.T(), []byte(`{"Login":"postgres"}`), loaded.Data)
+
+	// Readers can neither change items nor invite.
+	_, err = c.service.SaveItem(c.ctx("bob"), model.CollectionItemPostRequest{
+		CollectionID: collection.ID, DataType: "text_data", Data: []byte("x"),
+	})
+	assert.ErrorIs(c.T(), err, cerrors.ErrPermissionDenied)
+	_, err = c.service.InviteMember(c.ctx("bob"), model.CollectionInviteRequest{
+		CollectionID: collection.ID, Login: "carol", Role: model.RoleRead,
+	})
+	assert.ErrorIs(c.T(), err, cerrors.ErrPermissionDenied)
+
+	// Non-members can't tell the collection exists.
+	_, err = c.service.LoadMembers(c.ctx("carol"), collection.ID)
+	assert.ErrorIs(c.T(), err, cerrors.ErrCollectionNotFound)
+}
+
+func (c *CollectionServiceTestSuite) Test_InviteErrors() {
+	collection, err := c.service.CreateCollection(c.ctx("alice"), model.CollectionRequest{Name: "team"})
+	require.NoError(c.T(), err)
+
+	_, err = c.service.InviteMember(c.ctx("alice"), model.CollectionInviteRequest{
+		CollectionID: collection.ID, Login: "dave", Role: model.RoleRead,
+	})
+	assert.ErrorIs(c.T(), err, cerrors.ErrUserNotFound)
+
+	_, err = c.service.InviteMember(c.ctx("alice"), model.CollectionInviteRequest{
+		CollectionID: collection.ID, Login: "alice", Role: model.RoleRead,
+	})
+	assert.ErrorIs(c.T(), err, cerrors.ErrMemberExists)
+}
+
+func (c *CollectionServiceTestSuite) Test_RevokeMember() {
+	collection, err := c.service.CreateCollection(c.ctx("alice"), model.CollectionRequest{Name: "team"})
+	require.NoError(c.T(), err)
+	for _, login := range []string{"bob", "carol"} {
+		_, err = c.service.InviteMember(c.ctx("alice"), model.CollectionInviteRequest{
+			CollectionID: collection.ID, Login: login, Role: model.RoleWrite,
+		})
+		require.NoError(c.T(), err)
+		_, err = c.service.AcceptInvitation(c.ctx(login), collection.ID)
+		require.NoError(c.T(), err)
+	}
+
+	// Only managers revoke other members.
+	err = c.service.RevokeMember(c.ctx("bob"), model.CollectionMemberRequest{CollectionID: collection.ID, Login: "carol"})
+	assert.ErrorIs(c.T(), err, cerrors.ErrPermissionDenied)
+
+	err = c.service.RevokeMember(c.ctx("alice"), model.CollectionMemberRequest{CollectionID: collection.ID, Login: "bob"})
+	require.NoError(c.T(), err)
+	_, err = c.service.LoadItems(c.ctx("bob"), collection.ID)
+	assert.ErrorIs(c.T(), err, cerrors.ErrCollectionNotFound)
+
+	// The last manager can't leave while other members remain.
+	err = c.service.RevokeMember(c.ctx("alice"), model.CollectionMemberRequest{CollectionID: collection.ID})
+	assert.ErrorIs(c.T(), err, cerrors.ErrLastManager)
+
+	// Members leave by revoking themselves.
+	err = c.service.RevokeMember(c.ctx("carol"), model.CollectionMemberRequest{CollectionID: collection.ID})
+	require.NoError(c.T(), err)
+
+	err = c.service.RevokeMember(c.ctx("alice"), model.CollectionMemberRequest{CollectionID: collection.ID, Login: "alice"})
+	require.NoError(c.T(), err)
+	assert.Empty(c.T(), c.repository.collections)
+}
+
+// Test_RevokeManager checks that neither a manager leaving nor managers revoking each other leave
+// a collection with members but without a manager.
+func (c *CollectionServiceTestSuite) Test_RevokeManager() {
+	collection, err := c.service.CreateCollection(c.ctx("alice"), model.CollectionRequest{Name: "team"})
+	require.NoError(c.T(), err)
+	for login, role := range map[string]string{"bob": model.RoleManage, "carol": model.RoleRead} {
+		_, err = c.service.InviteMember(c.ctx("alice"), model.CollectionInviteRequest{
+			CollectionID: collection.ID, Login: login, Role: role,
+		})
+		require.NoError(c.T(), err)
+		_, err = c.service.AcceptInvitation(c.ctx(login), collection.ID)
+		require.NoError(c.T(), err)
+	}
+
+	// A manager leaves while another one remains.
+	err = c.service.RevokeMember(c.ctx("bob"), model.CollectionMemberRequest{CollectionID: collection.ID})
+	require.NoError(c.T(), err)
+
+	// A manager revoked concurrently with the request can't revoke the last manager any more.
+	err = c.repository.DeleteMember(c.ctx("bob"), collection.ID, "alice-id", "bob-id")
+	assert.ErrorIs(c.T(), err, cerrors.ErrPermissionDenied)
+
+	err = c.service.RevokeMember(c.ctx("alice"), model.CollectionMemberRequest{CollectionID: collection.ID})
+	assert.ErrorIs(c.T(), err, cerrors.ErrLastManager)
+
+	// A manager revokes another manager.
+	_, err = c.service.InviteMember(c.ctx("alice"), model.CollectionInviteRequest{
+		CollectionID: collection.ID, Login: "bob", Role: model.RoleManage,
+	})
+	require.NoError(c.T(), err)
+	_, err = c.service.AcceptInvitation(c.ctx("bob"), collection.ID)
+	require.NoError(c.T(), err)
+	err = c.service.RevokeMember(c.ctx("bob"), model.CollectionMemberRequest{CollectionID: collection.ID, Login: "alice"})
+	require.NoError(c.T(), err)
+
+	err = c.service.RevokeMember(c.ctx("bob"), model.CollectionMemberRequest{CollectionID: collection.ID})
+	assert.ErrorIs(c.T(), err, cerrors.ErrLastManager)
+	assert.Equal(c.T(), model.RoleManage, c.repository.members[collection.ID]["bob-id"].Role)
+}
+
+// Test_ClientSideEncryption checks that users with client side encryption neither use collections nor are invited,
+// the server would see the plaintext of their items otherwise.
+func (c *CollectionServiceTestSuite) Test_ClientSideEncryption() {
+	collection, err := c.service.CreateCollection(c.ctx("alice"), model.CollectionRequest{Name: "team"})
+	require.NoError(c.T(), err)
+	c.repository.clientSide["carol-id"] = true
+
+	_, err = c.service.CreateCollection(c.ctx("carol"), model.CollectionRequest{Name: "private"})
+	assert.ErrorIs(c.T(), err, cerrors.ErrClientSideEncryption)
+	_, err = c.service.LoadCollections(c.ctx("carol"))
+	assert.ErrorIs(c.T(), err, cerrors.ErrClientSideEncryption)
+
+	_, err = c.service.InviteMember(c.ctx("alice"), model.CollectionInviteRequest{
+		CollectionID: collection.ID, Login: "carol", Role: model.RoleRead,
+	})
+	assert.ErrorIs(c.T(), err, cerrors.ErrClientSideEncryption)
+	assert.NotContains(c.T(), c.repository.members[collection.ID], "carol-id")
+
+	// A member who turned client side encryption on can't read the items any more
+	_, err = c.service.InviteMember(c.ctx("alice"), model.CollectionInviteRequest{
+		CollectionID: collection.ID, Login: "bob", Role: model.RoleWrite,
+	})
+	require.NoError(c.T(), err)
+	_, err = c.service.AcceptInvitation(c.ctx("bob"), collection.ID)
+	require.NoError(c.T(), err)
+	c.repository.clientSide["bob-id"] = true
+
+	_, err = c.service.SaveItem(c.ctx("bob"), model.CollectionItemPostRequest{
+		CollectionID: collection.ID, DataType: "text_data", Data: []byte("secret"),
+	})
+	assert.ErrorIs(c.T(), err, cerrors.ErrClientSideEncryption)
+	assert.Empty(c.T(), c.repository.items)
+
+	// but can leave the collection
+	err = c.service.RevokeMember(c.ctx("bob"), model.CollectionMemberRequest{CollectionID: collection.ID})
+	require.NoError(c.T(), err)
+}
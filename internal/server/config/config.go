@@ -1,9 +1,13 @@
 package config
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -16,7 +20,8 @@ type Config struct {
 	GRPCServer      string // Address of the gRPC server
 	TokenName       string // Name of the authentication token
 	TokenSecret     string // Secret key for signing tokens
-	TokenExpHours   int    // Token expiration time in hours
+	TokenExpMinutes int    // Access token expiration time in minutes
+	RefreshExpHours int    // Refresh token expiration time in hours
 	ServerCert      string // Path to the server's SSL certificate
 	ServerKey       string // Path to the server's SSL key
 	ServerCa        string // Path to the server's CA file
@@ -24,6 +29,31 @@ type Config struct {
 	RedisPassword   string // Password for the Redis server
 	RedisDB         int    // Redis database number
 	RedisTimeoutSec int    // Timeout for Redis operations in seconds
+
+	MasterKeys       map[int][]byte // Master key material by version
+	MasterKeyVersion int            // Master key version used to wrap new user keys
+
+	UserKeyMaxAgeHours         int // Maximum age of a user key before automatic rotation, 0 disables it
+	UserKeyRotationIntervalMin int // Interval between checks for expired user keys in minutes
+	UserKeyRotationBatchSize   int // Number of records re-encrypted per batch during key rotation
+
+	BreachList string // Path to the breached password hashes file or range directory, lookups are disabled if empty
+
+	ExpiryReminderWindowDays  int    // Owners are notified about cards expiring within this number of days, 0 disables it
+	ExpiryReminderIntervalMin int    // Interval between checks for expiring cards in minutes
+	ExpiryNotifier            string // Channel expiry notifications are sent through: log, webhook or smtp
+	ExpiryWebhookURL          string // URL expiry notifications are posted to by the webhook notifier
+	SMTPAddr                  string // Address of the SMTP server used by the smtp notifier
+	SMTPUser                  string // SMTP user, no authentication is done if empty
+	SMTPPassword              string // SMTP password
+	SMTPFrom                  string // Sender address of notification emails
+}
+
+// keyring is the format of the local master keyring file.
+// Keys are base64 encoded and indexed by version.
+type keyring struct {
+	CurrentVersion int               `json:"current_version"`
+	Keys           map[string]string `json:"keys"`
 }
 
 // New initializes a new Config instance by loading environment variables from a .env file.
@@ -39,11 +69,14 @@ func New() (*Config, error) {
 	config.DatabaseURI = os.Getenv("DATABASE_URI")
 	config.GRPCServer = os.Getenv("GRPC_SERVER")
 	config.TokenName = os.Getenv("TOKEN_NAME")
-	expHours, err := strconv.Atoi(os.Getenv("TOKEN_EXP_HOURS"))
+	config.TokenExpMinutes, err = atoiDefault("TOKEN_EXP_MINUTES", 15)
+	if err != nil {
+		return nil, err
+	}
+	config.RefreshExpHours, err = atoiDefault("REFRESH_TOKEN_EXP_HOURS", 24*30)
 	if err != nil {
-		return nil, fmt.Errorf("atoi TOKEN_EXP_HOURS: %w", err)
+		return nil, err
 	}
-	config.TokenExpHours = expHours
 	config.TokenSecret = os.Getenv("TOKEN_SECRET")
 	config.ServerCert = os.Getenv("SERVER_CERT_FILE")
 	config.ServerKey = os.Getenv("SERVER_KEY_FILE")
@@ -62,5 +95,127 @@ func New() (*Config, error) {
 		return nil, fmt.Errorf("atoi REDIS_TIMEOUT_SEC: %w", err)
 	}
 
+	config.MasterKeys, config.MasterKeyVersion, err = loadMasterKeys()
+	if err != nil {
+		return nil, fmt.Errorf("load master keys: %w", err)
+	}
+
+	config.UserKeyMaxAgeHours, err = atoiDefault("USER_KEY_MAX_AGE_HOURS", 24*365)
+	if err != nil {
+		return nil, err
+	}
+	config.UserKeyRotationIntervalMin, err = atoiDefault("USER_KEY_ROTATION_INTERVAL_MIN", 60)
+	if err != nil {
+		return nil, err
+	}
+	config.UserKeyRotationBatchSize, err = atoiDefault("USER_KEY_ROTATION_BATCH_SIZE", 100)
+	if err != nil {
+		return nil, err
+	}
+
+	config.BreachList = os.Getenv("BREACH_LIST_FILE")
+
+	config.ExpiryReminderWindowDays, err = atoiDefault("EXPIRY_REMINDER_WINDOW_DAYS", 30)
+	if err != nil {
+		return nil, err
+	}
+	config.ExpiryReminderIntervalMin, err = atoiDefault("EXPIRY_REMINDER_INTERVAL_MIN", 60)
+	if err != nil {
+		return nil, err
+	}
+	config.ExpiryNotifier = os.Getenv("EXPIRY_NOTIFIER")
+	config.ExpiryWebhookURL = os.Getenv("EXPIRY_WEBHOOK_URL")
+	config.SMTPAddr = os.Getenv("SMTP_ADDR")
+	config.SMTPUser = os.Getenv("SMTP_USER")
+	config.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	config.SMTPFrom = os.Getenv("SMTP_FROM")
+
 	return config, nil
 }
+
+// atoiDefault reads an integer environment variable, returning def if it is not set.
+func atoiDefault(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("atoi %s: %w", name, err)
+	}
+
+	return n, nil
+}
+
+// loadMasterKeys loads the master keys from the keyring file set in MASTER_KEYRING_FILE,
+// from the key file set in MASTER_KEY_FILE or from the MASTER_KEY variable, in this order.
+// For a single key MASTER_KEY_VERSION sets its version (1 by default) and MASTER_KEY_LEGACY
+// adds the key used before master key versioning was introduced as version 0.
+func loadMasterKeys() (map[int][]byte, int, error) {
+	if path := os.Getenv("MASTER_KEYRING_FILE"); path != "" {
+		return loadKeyringFile(path)
+	}
+
+	var key []byte
+	if path := os.Getenv("MASTER_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, 0, fmt.Errorf("read MASTER_KEY_FILE: %w", err)
+		}
+		key = []byte(strings.TrimSpace(string(data)))
+	} else {
+		key = []byte(os.Getenv("MASTER_KEY"))
+	}
+	if len(key) == 0 {
+		return nil, 0, errors.New("master key is not set")
+	}
+
+	version := 1
+	if v := os.Getenv("MASTER_KEY_VERSION"); v != "" {
+		var err error
+		version, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, 0, fmt.Errorf("atoi MASTER_KEY_VERSION: %w", err)
+		}
+	}
+
+	keys := map[int][]byte{version: key}
+	if legacy := os.Getenv("MASTER_KEY_LEGACY"); legacy != "" && version != 0 {
+		keys[0] = []byte(legacy)
+	}
+
+	return keys, version, nil
+}
+
+// loadKeyringFile reads the master keyring from the JSON file at path.
+func loadKeyringFile(path string) (map[int][]byte, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read MASTER_KEYRING_FILE: %w", err)
+	}
+
+	var ring keyring
+	if err = json.Unmarshal(data, &ring); err != nil {
+		return nil, 0, fmt.Errorf("unmarshal keyring: %w", err)
+	}
+
+	keys := make(map[int][]byte, len(ring.Keys))
+	for v, encoded := range ring.Keys {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, 0, fmt.Errorf("keyring version %q: %w", v, err)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, 0, fmt.Errorf("keyring key %d: %w", version, err)
+		}
+		keys[version] = key
+	}
+
+	if _, ok := keys[ring.CurrentVersion]; !ok {
+		return nil, 0, fmt.Errorf("keyring has no key for current version %d", ring.CurrentVersion)
+	}
+
+	return keys, ring.CurrentVersion, nil
+}
@@ -2,7 +2,10 @@ package grpchandlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -16,13 +19,19 @@ import (
 // It encapsulates the logic for saving and loading credentials data.
 type CredentialsService interface {
 	SaveCredentials(ctx context.Context, req model.CredentialsPostRequest) (model.Credentials, error)
+	SaveCredentialsBatch(ctx context.Context, reqs []model.CredentialsPostRequest) ([]model.Credentials, error)
 	LoadCredentialsData(ctx context.Context, dataID string) (model.Credentials, error)
-	LoadAllCredentialsDataInfo(ctx context.Context) ([]model.DataInfo, error)
+	LoadAllCredentialsDataInfo(ctx context.Context, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCredentials(ctx context.Context, req model.CredentialsPutRequest) (model.Credentials, error)
+	DeleteCredentials(ctx context.Context, dataID string) error
 }
 
 // Validator is an interface for validating incoming requests.
 type Validator interface {
 	ValidatePostRequest(req *model.CredentialsPostRequest) (map[string]string, bool)
+	ValidatePutRequest(req *model.CredentialsPutRequest) (map[string]string, bool)
+	ValidateDeleteRequest(req *model.DataDeleteRequest) (map[string]string, bool)
+	ValidatePageRequest(req *model.PageRequest) (map[string]string, bool)
 }
 
 // CredentialsHandler implements the gRPC server for credentials-related operations.
@@ -45,9 +54,12 @@ func New(credentialsService CredentialsService, validator Validator) *Credential
 // It processes the incoming request, validates it, and invokes the service to save the data.
 func (h *CredentialsHandler) PostSaveCredentials(ctx context.Context, in *pb.PostCredentialsRequest) (*pb.PostCredentialsResponse, error) {
 	req := model.CredentialsPostRequest{
-		Login:    in.Login,
-		Password: in.Password,
-		MetaData: in.Metadata,
+		ID:        in.Id,
+		Login:     in.Login,
+		Password:  in.Password,
+		TOTP:      in.Totp,
+		MetaData:  in.Metadata,
+		CryptData: in.CryptData,
 	}
 
 	report, ok := h.validator.ValidatePostRequest(&req)
@@ -58,6 +70,16 @@ func (h *CredentialsHandler) PostSaveCredentials(ctx context.Context, in *pb.Pos
 	}
 
 	cred, err := h.credentialsService.SaveCredentials(ctx, req)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to save credentials: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataExists) {
+		logrus.Info("Unable to save credentials: id is taken")
+		return nil, status.Error(codes.AlreadyExists, "data with this id already exists")
+	}
+
 	if err != nil {
 		logrus.WithError(err).Error("Unable to save credentials")
 		return nil, status.Error(codes.Internal, "internal error")
@@ -66,32 +88,132 @@ func (h *CredentialsHandler) PostSaveCredentials(ctx context.Context, in *pb.Pos
 		Id:        cred.ID,
 		Login:     cred.Login,
 		Password:  cred.Password,
+		Totp:      cred.TOTP,
 		Metadata:  cred.MetaData,
 		CreatedAt: cred.CreatedAt.Format(time.RFC3339),
+		Revision:  cred.Revision,
 	}, nil
 }
 
-// GetLoadAllCredentialsDataInfo handles the gRPC call to load all credentials data information.
+// PostSaveCredentialsBatch handles the gRPC call to save many credentials at once. Every item is validated and
+// the violations are reported per item with the path of the field, like credentials[3].Password.
+// The valid items are saved in a single transaction, an atomic batch is rejected as a whole when any item is invalid.
+func (h *CredentialsHandler) PostSaveCredentialsBatch(ctx context.Context, in *pb.PostCredentialsBatchRequest) (*pb.PostCredentialsBatchResponse, error) {
+	if len(in.Credentials) > lib.MaxBatchSize {
+		logrus.Infof("Unable to save credentials batch: %d items", len(in.Credentials))
+		return nil, lib.ProcessValidationError("invalid credentials batch request",
+			map[string]string{"credentials": fmt.Sprintf("must hold at most %d items", lib.MaxBatchSize)})
+	}
+
+	results := make([]*pb.CredentialsBatchResult, len(in.Credentials))
+	reqs := make([]model.CredentialsPostRequest, 0, len(in.Credentials))
+	indexes := make([]int, 0, len(in.Credentials))
+	batchReport := make(map[string]string)
+	for i, item := range in.Credentials {
+		req := model.CredentialsPostRequest{
+			ID:        item.Id,
+			Login:     item.Login,
+			Password:  item.Password,
+			TOTP:      item.Totp,
+			MetaData:  item.Metadata,
+			CryptData: item.CryptData,
+		}
+
+		report, ok := h.validator.ValidatePostRequest(&req)
+		if ok {
+			reqs = append(reqs, req)
+			indexes = append(indexes, i)
+			continue
+		}
+
+		results[i] = &pb.CredentialsBatchResult{}
+		for _, violation := range lib.BatchViolations("credentials", i, report) {
+			batchReport[violation.Field] = violation.Description
+			results[i].Violations = append(results[i].Violations, &pb.CredentialsFieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+	}
+
+	if len(batchReport) != 0 && in.Atomic {
+		logrus.Info("Unable to save credentials batch: invalid items in an atomic batch")
+		logrus.Infof("violated_fields %v", batchReport)
+		return nil, lib.ProcessValidationError("invalid credentials batch request", batchReport)
+	}
+
+	saved, err := h.credentialsService.SaveCredentialsBatch(ctx, reqs)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to save credentials batch: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataExists) {
+		logrus.Info("Unable to save credentials batch: id is taken")
+		return nil, status.Error(codes.AlreadyExists, "data with this id already exists")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to save credentials batch")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	for j, v := range saved {
+		results[indexes[j]] = &pb.CredentialsBatchResult{Saved: &pb.PostCredentialsResponse{
+			Id:        v.ID,
+			Login:     v.Login,
+			Password:  v.Password,
+			Totp:      v.TOTP,
+			Metadata:  v.MetaData,
+			CreatedAt: v.CreatedAt.Format(time.RFC3339),
+			Revision:  v.Revision,
+		}}
+	}
+
+	return &pb.PostCredentialsBatchResponse{Results: results}, nil
+}
+
+// GetLoadAllCredentialsDataInfo handles the gRPC call for loading a page of credentials data information.
 // It retrieves metadata for all credentials and constructs a response.
-func (h *CredentialsHandler) GetLoadAllCredentialsDataInfo(ctx context.Context, _ *pb.GetAllCredentialsInfoRequest) (*pb.GetAllCredentialsInfoResponse, error) {
+func (h *CredentialsHandler) GetLoadAllCredentialsDataInfo(ctx context.Context, in *pb.GetAllCredentialsInfoRequest) (*pb.GetAllCredentialsInfoResponse, error) {
+	req := model.PageRequest{
+		PageSize:   int(in.PageSize),
+		PageToken:  in.PageToken,
+		SortBy:     in.SortBy,
+		Descending: in.Descending,
+	}
+
+	report, ok := h.validator.ValidatePageRequest(&req)
+	if !ok {
+		logrus.Info("Unable to load credentials data info: invalid page request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid page request", report)
+	}
+
+	credentialsPage, err := h.credentialsService.LoadAllCredentialsDataInfo(ctx, req)
+	if errors.Is(err, cerrors.ErrInvalidPageToken) {
+		logrus.Info("Unable to load credentials data info: invalid page token")
+		return nil, lib.ProcessValidationError("invalid page request", map[string]string{"PageToken": "is invalid or issued for another sort order"})
+	}
 
-	credentialsInfo, err := h.credentialsService.LoadAllCredentialsDataInfo(ctx)
 	if err != nil {
 		logrus.WithError(err).Error("Error while loading credentials data: ")
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	credentialsInfos := make([]*pb.CredentialsInfo, 0, len(credentialsInfo))
-	for _, v := range credentialsInfo {
+	credentialsInfos := make([]*pb.CredentialsInfo, 0, len(credentialsPage.Infos))
+	for _, v := range credentialsPage.Infos {
 		credentialsInfos = append(credentialsInfos, &pb.CredentialsInfo{
 			Id:        v.ID,
 			DataType:  v.DataType,
 			Metadata:  v.MetaData,
 			CreatedAt: v.CreatedAt.Format(time.RFC3339),
+			Revision:  v.Revision,
+			UpdatedAt: v.UpdatedAt.Format(time.RFC3339),
 		})
 	}
 
-	return &pb.GetAllCredentialsInfoResponse{Creds: credentialsInfos}, nil
+	return &pb.GetAllCredentialsInfoResponse{Creds: credentialsInfos, NextPageToken: credentialsPage.NextPageToken}, nil
 }
 
 // GetLoadCredentials handles the gRPC call to load specific credentials data by ID.
@@ -110,8 +232,87 @@ func (h *CredentialsHandler) GetLoadCredentials(ctx context.Context, in *pb.GetC
 		OwnerId:   credentialsData.OwnerID,
 		Login:     credentialsData.Login,
 		Password:  credentialsData.Password,
+		Totp:      credentialsData.TOTP,
 		Metadata:  credentialsData.MetaData,
 		CreatedAt: credentialsData.CreatedAt.Format(time.RFC3339Nano),
+		Revision:  credentialsData.Revision,
+		CryptData: credentialsData.CryptData,
 	}
 	return &pb.GetCredentialsResponse{CredentialsData: bin}, nil
 }
+
+// PutUpdateCredentials handles the gRPC call to update existing credentials.
+// It validates the incoming request and invokes the service to replace the stored data.
+func (h *CredentialsHandler) PutUpdateCredentials(ctx context.Context, in *pb.PutCredentialsRequest) (*pb.PutCredentialsResponse, error) {
+	req := model.CredentialsPutRequest{
+		ID:        in.Id,
+		Revision:  in.Revision,
+		Login:     in.Login,
+		Password:  in.Password,
+		TOTP:      in.Totp,
+		MetaData:  in.Metadata,
+		CryptData: in.CryptData,
+	}
+
+	report, ok := h.validator.ValidatePutRequest(&req)
+	if !ok {
+		logrus.Info("Unable to update credentials: invalid credentials request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid credentials put request", report)
+	}
+
+	cred, err := h.credentialsService.UpdateCredentials(ctx, req)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to update credentials: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to update credentials: credentials %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "credentials not found")
+	}
+
+	if errors.Is(err, cerrors.ErrRevisionConflict) {
+		logrus.Infof("Unable to update credentials: credentials %s has a newer revision", req.ID)
+		return nil, status.Error(codes.Aborted, "credentials was changed by another client")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to update credentials")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &pb.PutCredentialsResponse{
+		Id:        cred.ID,
+		Login:     cred.Login,
+		Password:  cred.Password,
+		Totp:      cred.TOTP,
+		Metadata:  cred.MetaData,
+		CreatedAt: cred.CreatedAt.Format(time.RFC3339),
+		Revision:  cred.Revision,
+	}, nil
+}
+
+// DeleteCredentials handles the gRPC call to delete specific credentials data by ID.
+func (h *CredentialsHandler) DeleteCredentials(ctx context.Context, in *pb.DeleteCredentialsRequest) (*pb.DeleteCredentialsResponse, error) {
+	req := model.DataDeleteRequest{ID: in.Id}
+
+	report, ok := h.validator.ValidateDeleteRequest(&req)
+	if !ok {
+		logrus.Info("Unable to delete credentials: invalid credentials request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid credentials delete request", report)
+	}
+
+	err := h.credentialsService.DeleteCredentials(ctx, req.ID)
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to delete credentials: credentials %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "credentials not found")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to delete credentials")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.DeleteCredentialsResponse{}, nil
+}
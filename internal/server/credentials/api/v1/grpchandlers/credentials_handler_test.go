@@ -0,0 +1,147 @@
+package grpchandlers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/credentials"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/credentials/api/v1/validation"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// fakeService saves batches in memory and keeps the requests of every batch it was called with.
+type fakeService struct {
+	CredentialsService
+	batches [][]model.CredentialsPostRequest
+}
+
+func (f *fakeService) SaveCredentialsBatch(_ context.Context, reqs []model.CredentialsPostRequest) ([]model.Credentials, error) {
+	f.batches = append(f.batches, reqs)
+	creds := make([]model.Credentials, 0, len(reqs))
+	for i, req := range reqs {
+		creds = append(creds, model.Credentials{ID: fmt.Sprintf("id-%d", i), Login: req.Login, MetaData: req.MetaData, Revision: 1})
+	}
+	return creds, nil
+}
+
+type CredentialsHandlerTestSuite struct {
+	suite.Suite
+	service *fakeService
+	handler *CredentialsHandler
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(CredentialsHandlerTestSuite))
+}
+
+func (s *CredentialsHandlerTestSuite) SetupTest() {
+	v, err := validation.New(validator.New())
+	s.Require().NoError(err)
+	s.service = &fakeService{}
+	s.handler = New(s.service, v)
+}
+
+// Test_PartialBatch saves only the valid items and reports the violations of the others by the path of the field.
+func (s *CredentialsHandlerTestSuite) Test_PartialBatch() {
+	resp, err := s.handler.PostSaveCredentialsBatch(context.Background(), &pb.PostCredentialsBatchRequest{
+		Credentials: []*pb.PostCredentialsRequest{
+			{Login: "alice", Password: "secret", Metadata: "first"},
+			{Login: "bob"},
+			{Login: "carol", Password: "secret", Metadata: "third"},
+			{Id: "not-a-uuid", Metadata: "fourth", CryptData: []byte("sealed")},
+		},
+	})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), resp.Results, 4)
+
+	require.Len(s.T(), s.service.batches, 1)
+	saved := s.service.batches[0]
+	require.Len(s.T(), saved, 2)
+	assert.Equal(s.T(), "first", saved[0].MetaData)
+	assert.Equal(s.T(), "third", saved[1].MetaData)
+
+	// The saved items are returned at the index of their request
+	require.NotNil(s.T(), resp.Results[0].Saved)
+	assert.Equal(s.T(), "first", resp.Results[0].Saved.Metadata)
+	require.NotNil(s.T(), resp.Results[2].Saved)
+	assert.Equal(s.T(), "third", resp.Results[2].Saved.Metadata)
+
+	assert.Nil(s.T(), resp.Results[1].Saved)
+	assert.Equal(s.T(), []*pb.CredentialsFieldViolation{
+		{Field: "credentials[1].Password", Description: "is required"},
+	}, resp.Results[1].Violations)
+	assert.Nil(s.T(), resp.Results[3].Saved)
+	assert.Equal(s.T(), []*pb.CredentialsFieldViolation{
+		{Field: "credentials[3].ID", Description: "must be a UUID"},
+	}, resp.Results[3].Violations)
+}
+
+// Test_AtomicBatch rejects the whole batch when one of its items is invalid.
+func (s *CredentialsHandlerTestSuite) Test_AtomicBatch() {
+	_, err := s.handler.PostSaveCredentialsBatch(context.Background(), &pb.PostCredentialsBatchRequest{
+		Credentials: []*pb.PostCredentialsRequest{
+			{Login: "alice", Password: "secret"},
+			{Password: "secret"},
+		},
+		Atomic: true,
+	})
+	assert.Equal(s.T(), map[string]string{"credentials[1].Login": "is required"}, s.violations(err))
+	assert.Empty(s.T(), s.service.batches)
+
+	resp, err := s.handler.PostSaveCredentialsBatch(context.Background(), &pb.PostCredentialsBatchRequest{
+		Credentials: []*pb.PostCredentialsRequest{
+			{Login: "alice", Password: "secret"},
+			{Login: "bob", Password: "secret"},
+		},
+		Atomic: true,
+	})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), resp.Results, 2)
+	require.Len(s.T(), s.service.batches, 1)
+	assert.Len(s.T(), s.service.batches[0], 2)
+}
+
+// Test_MaxBatchSize rejects batches of more than lib.MaxBatchSize items before validating any of them.
+func (s *CredentialsHandlerTestSuite) Test_MaxBatchSize() {
+	items := make([]*pb.PostCredentialsRequest, lib.MaxBatchSize+1)
+	for i := range items {
+		items[i] = &pb.PostCredentialsRequest{Login: "alice", Password: "secret"}
+	}
+
+	_, err := s.handler.PostSaveCredentialsBatch(context.Background(), &pb.PostCredentialsBatchRequest{Credentials: items})
+	assert.Equal(s.T(), map[string]string{
+		"credentials": fmt.Sprintf("must hold at most %d items", lib.MaxBatchSize),
+	}, s.violations(err))
+	assert.Empty(s.T(), s.service.batches)
+
+	_, err = s.handler.PostSaveCredentialsBatch(context.Background(), &pb.PostCredentialsBatchRequest{Credentials: items[:lib.MaxBatchSize]})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), s.service.batches, 1)
+	assert.Len(s.T(), s.service.batches[0], lib.MaxBatchSize)
+}
+
+// violations returns the field violations of an InvalidArgument error.
+func (s *CredentialsHandlerTestSuite) violations(err error) map[string]string {
+	st := status.Convert(err)
+	s.Require().Equal(codes.InvalidArgument, st.Code())
+
+	violations := make(map[string]string)
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range br.FieldViolations {
+				violations[v.Field] = v.Description
+			}
+		}
+	}
+	return violations
+}
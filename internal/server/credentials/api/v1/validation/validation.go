@@ -2,7 +2,10 @@ package validation
 
 import (
 	"errors"
+	"fmt"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/pagination"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/totp"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -12,16 +15,87 @@ type Validator struct {
 	validator *validator.Validate // The validator instance for performing validation checks
 }
 
-// New initializes a new Validator instance with a validator.
+// New initializes a new Validator instance with a validator and registers custom validation rules.
 func New(validator *validator.Validate) (*Validator, error) {
 	v := &Validator{validator: validator}
 
+	err := v.validator.RegisterValidation("totp", totpKey)
+	if err != nil {
+		return nil, fmt.Errorf("register totp: %w", err)
+	}
+
 	return v, nil
 }
 
+// totpKey checks that the two-factor secret is a base32 secret or an otpauth URI of a TOTP key.
+func totpKey(fl validator.FieldLevel) bool {
+	_, err := totp.ParseKey(fl.Field().String())
+	return err == nil
+}
+
 // ValidatePostRequest validates the incoming CredentialsPostRequest.
 // It checks required fields and returns a map of validation errors if any exist.
 func (v *Validator) ValidatePostRequest(req *model.CredentialsPostRequest) (map[string]string, bool) {
+	var err error
+	if len(req.CryptData) > 0 {
+		// Client side encrypted payload is opaque to the server, only the ID it is bound to can be checked
+		err = v.validator.StructPartial(req, "ID")
+	} else {
+		err = v.validator.Struct(req)
+	}
+	report := make(map[string]string)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				switch validationErr.Tag() {
+				case "required":
+					report[validationErr.Field()] = "is required"
+				case "uuid":
+					report[validationErr.Field()] = "must be a UUID"
+				case "totp":
+					report[validationErr.Field()] = "must be a base32 secret or an otpauth://totp URI"
+				}
+			}
+			return report, false
+		}
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+	return nil, true
+}
+
+// ValidatePutRequest validates the incoming CredentialsPutRequest.
+// It checks required fields and returns a map of validation errors if any exist.
+func (v *Validator) ValidatePutRequest(req *model.CredentialsPutRequest) (map[string]string, bool) {
+	var err error
+	if len(req.CryptData) > 0 {
+		// Client side encrypted payload is opaque to the server, only the ID can be checked
+		err = v.validator.StructPartial(req, "ID")
+	} else {
+		err = v.validator.Struct(req)
+	}
+	report := make(map[string]string)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				switch validationErr.Tag() {
+				case "required":
+					report[validationErr.Field()] = "is required"
+				case "totp":
+					report[validationErr.Field()] = "must be a base32 secret or an otpauth://totp URI"
+				}
+			}
+			return report, false
+		}
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+	return nil, true
+}
+
+// ValidateDeleteRequest validates the incoming DataDeleteRequest for credentials.
+// It checks required fields and returns a map of validation errors if any exist.
+func (v *Validator) ValidateDeleteRequest(req *model.DataDeleteRequest) (map[string]string, bool) {
 	err := v.validator.Struct(req)
 	report := make(map[string]string)
 	if err != nil {
@@ -39,3 +113,8 @@ func (v *Validator) ValidatePostRequest(req *model.CredentialsPostRequest) (map[
 	}
 	return nil, true
 }
+
+// ValidatePageRequest validates the incoming PageRequest of a data listing.
+func (v *Validator) ValidatePageRequest(req *model.PageRequest) (map[string]string, bool) {
+	return pagination.Validate(v.validator, req)
+}
@@ -4,10 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/pagination"
 	"github.com/DenisKhanov/PrivateKeeperV2/pkg/jwtmanager"
 )
 
@@ -16,8 +17,11 @@ const credentials = "credentials" // Constant to define the data type for creden
 // DataRepository defines the methods for data operations on the repository level.
 type DataRepository interface {
 	Insert(ctx context.Context, data model.Data) (model.Data, error)
-	SelectAll(ctx context.Context, userID, dataType string) ([]model.Data, error)
+	InsertBatch(ctx context.Context, data []model.Data) ([]model.Data, error)
+	SelectPage(ctx context.Context, userID, dataType string, page model.PageRequest, after *model.PageCursor, limit int) ([]model.Data, error)
 	SelectByID(ctx context.Context, userID, dataType, dataID string) (model.Data, error)
+	Update(ctx context.Context, data model.Data) (model.Data, error)
+	Delete(ctx context.Context, userID, dataType, dataID string) error
 }
 
 // CryptService defines methods for encryption and decryption operations.
@@ -58,32 +62,9 @@ func (s *CredentialsService) SaveCredentials(ctx context.Context, req model.Cred
 		return model.Credentials{}, fmt.Errorf("failed to get userKey from context")
 	}
 
-	id, err := uuid.NewUUID()
+	dataToSave, err := s.newData(userID, userKey, req)
 	if err != nil {
-		return model.Credentials{}, fmt.Errorf("new uuid: %w", err)
-	}
-
-	card := model.CredentialsCryptData{
-		Login:    req.Login,
-		Password: req.Password,
-	}
-
-	data, err := json.Marshal(card)
-	if err != nil {
-		return model.Credentials{}, fmt.Errorf("marshal: %w", err)
-	}
-
-	cryptData, err := s.crypt.Encrypt(userKey, data)
-	if err != nil {
-		return model.Credentials{}, fmt.Errorf("encrypt data: %w", err)
-	}
-
-	dataToSave := model.Data{
-		ID:       id.String(),
-		OwnerID:  userID,
-		Type:     s.dataType,
-		Data:     cryptData,
-		MetaData: req.MetaData,
+		return model.Credentials{}, err
 	}
 
 	savedCredentials, err := s.repository.Insert(ctx, dataToSave)
@@ -96,24 +77,83 @@ func (s *CredentialsService) SaveCredentials(ctx context.Context, req model.Cred
 		OwnerID:   savedCredentials.OwnerID,
 		Login:     req.Login,
 		Password:  req.Password,
+		TOTP:      req.TOTP,
 		MetaData:  savedCredentials.MetaData,
 		CreatedAt: savedCredentials.CreatedAt,
+		Revision:  savedCredentials.Revision,
+		CryptData: req.CryptData,
 	}, nil
 }
 
-// LoadAllCredentialsDataInfo retrieves all credentials data information for the user.
-func (s *CredentialsService) LoadAllCredentialsDataInfo(ctx context.Context) ([]model.DataInfo, error) {
+// SaveCredentialsBatch encrypts the provided credentials and saves them to the repository in a single transaction.
+// It returns the saved Credentials objects in the order of the requests, either all of them are saved or none.
+func (s *CredentialsService) SaveCredentialsBatch(ctx context.Context, reqs []model.CredentialsPostRequest) ([]model.Credentials, error) {
 	userID, ok := ctx.Value(model.UserIDKey).(string)
-	logrus.Info("UserID", userID)
 	if !ok {
 		return nil, fmt.Errorf("failed to get userID from context")
 	}
 
-	encryptedCredentialsData, err := s.repository.SelectAll(ctx, userID, s.dataType)
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return nil, fmt.Errorf("failed to get userKey from context")
+	}
+
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	dataToSave := make([]model.Data, 0, len(reqs))
+	for _, req := range reqs {
+		data, err := s.newData(userID, userKey, req)
+		if err != nil {
+			return nil, err
+		}
+		dataToSave = append(dataToSave, data)
+	}
+
+	savedData, err := s.repository.InsertBatch(ctx, dataToSave)
 	if err != nil {
-		return nil, fmt.Errorf("select all credentials_data: %w", err)
+		return nil, fmt.Errorf("insert credentials batch: %w", err)
+	}
+
+	creds := make([]model.Credentials, 0, len(savedData))
+	for i, saved := range savedData {
+		creds = append(creds, model.Credentials{
+			ID:        saved.ID,
+			OwnerID:   saved.OwnerID,
+			Login:     reqs[i].Login,
+			Password:  reqs[i].Password,
+			TOTP:      reqs[i].TOTP,
+			MetaData:  saved.MetaData,
+			CreatedAt: saved.CreatedAt,
+			Revision:  saved.Revision,
+			CryptData: reqs[i].CryptData,
+		})
 	}
 
+	return creds, nil
+}
+
+// LoadAllCredentialsDataInfo retrieves a page of credentials information for the user, sorted as requested.
+func (s *CredentialsService) LoadAllCredentialsDataInfo(ctx context.Context, page model.PageRequest) (model.DataInfoPage, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	logrus.Info("UserID", userID)
+	if !ok {
+		return model.DataInfoPage{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	page = pagination.Normalize(page)
+	after, err := pagination.DecodeToken(page)
+	if err != nil {
+		return model.DataInfoPage{}, err
+	}
+
+	encryptedCredentialsData, err := s.repository.SelectPage(ctx, userID, s.dataType, page, after, page.PageSize+1)
+	if err != nil {
+		return model.DataInfoPage{}, fmt.Errorf("select page credentials_data: %w", err)
+	}
+	encryptedCredentialsData, nextPageToken := pagination.Page(page, encryptedCredentialsData)
+
 	credentialsDataInfo := make([]model.DataInfo, 0, len(encryptedCredentialsData))
 	for _, encryptedCredentials := range encryptedCredentialsData {
 		credentialsDataInfo = append(credentialsDataInfo, model.DataInfo{
@@ -121,9 +161,11 @@ func (s *CredentialsService) LoadAllCredentialsDataInfo(ctx context.Context) ([]
 			DataType:  s.dataType,
 			MetaData:  encryptedCredentials.MetaData,
 			CreatedAt: encryptedCredentials.CreatedAt,
+			Revision:  encryptedCredentials.Revision,
+			UpdatedAt: encryptedCredentials.UpdatedAt,
 		})
 	}
-	return credentialsDataInfo, nil
+	return model.DataInfoPage{Infos: credentialsDataInfo, NextPageToken: nextPageToken}, nil
 }
 
 // LoadCredentialsData retrieves and decrypts the credentials data for a given dataID.
@@ -142,6 +184,17 @@ func (s *CredentialsService) LoadCredentialsData(ctx context.Context, dataID str
 	if err != nil {
 		return model.Credentials{}, fmt.Errorf("select all credentials_data: %w", err)
 	}
+	if encryptedBinaryData.ClientEncrypted {
+		return model.Credentials{
+			ID:        encryptedBinaryData.ID,
+			OwnerID:   encryptedBinaryData.OwnerID,
+			MetaData:  encryptedBinaryData.MetaData,
+			CreatedAt: encryptedBinaryData.CreatedAt,
+			Revision:  encryptedBinaryData.Revision,
+			CryptData: encryptedBinaryData.Data,
+		}, nil
+	}
+
 	decryptedData, err := s.crypt.Decrypt(userKey, encryptedBinaryData.Data)
 	if err != nil {
 		return model.Credentials{}, fmt.Errorf("decrypt credentials: %w", err)
@@ -158,9 +211,126 @@ func (s *CredentialsService) LoadCredentialsData(ctx context.Context, dataID str
 		OwnerID:   encryptedBinaryData.OwnerID,
 		Login:     decryptedCredentialsData.Login,
 		Password:  decryptedCredentialsData.Password,
+		TOTP:      decryptedCredentialsData.TOTP,
 		MetaData:  encryptedBinaryData.MetaData,
 		CreatedAt: encryptedBinaryData.CreatedAt,
+		Revision:  encryptedBinaryData.Revision,
 	}
 
 	return cred, nil
 }
+
+// UpdateCredentials re-encrypts the provided credentials and replaces the stored ones.
+// It returns the updated Credentials object or an error if the operation fails.
+func (s *CredentialsService) UpdateCredentials(ctx context.Context, req model.CredentialsPutRequest) (model.Credentials, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.Credentials{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return model.Credentials{}, fmt.Errorf("failed to get userKey from context")
+	}
+
+	cred := model.CredentialsCryptData{
+		Login:    req.Login,
+		Password: req.Password,
+		TOTP:     req.TOTP,
+	}
+
+	cryptData, clientEncrypted, err := s.encryptPayload(userKey, req.CryptData, cred)
+	if err != nil {
+		return model.Credentials{}, fmt.Errorf("encrypt payload: %w", err)
+	}
+
+	dataToUpdate := model.Data{
+		ID:              req.ID,
+		OwnerID:         userID,
+		Type:            s.dataType,
+		Data:            cryptData,
+		MetaData:        req.MetaData,
+		ClientEncrypted: clientEncrypted,
+		Revision:        req.Revision,
+	}
+
+	updatedCredentials, err := s.repository.Update(ctx, dataToUpdate)
+	if err != nil {
+		return model.Credentials{}, fmt.Errorf("update credentials: %w", err)
+	}
+
+	return model.Credentials{
+		ID:        updatedCredentials.ID,
+		OwnerID:   updatedCredentials.OwnerID,
+		Login:     req.Login,
+		Password:  req.Password,
+		TOTP:      req.TOTP,
+		MetaData:  updatedCredentials.MetaData,
+		CreatedAt: updatedCredentials.CreatedAt,
+		Revision:  updatedCredentials.Revision,
+		CryptData: req.CryptData,
+	}, nil
+}
+
+// DeleteCredentials removes the credentials with the given dataID.
+func (s *CredentialsService) DeleteCredentials(ctx context.Context, dataID string) error {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get userID from context")
+	}
+
+	err := s.repository.Delete(ctx, userID, s.dataType, dataID)
+	if err != nil {
+		return fmt.Errorf("delete credentials: %w", err)
+	}
+
+	return nil
+}
+
+// newData returns a new data entry of the user holding the encrypted credentials.
+func (s *CredentialsService) newData(userID string, userKey []byte, req model.CredentialsPostRequest) (model.Data, error) {
+	id, err := lib.NewDataID(req.ID)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("new data id: %w", err)
+	}
+
+	cred := model.CredentialsCryptData{
+		Login:    req.Login,
+		Password: req.Password,
+		TOTP:     req.TOTP,
+	}
+
+	cryptData, clientEncrypted, err := s.encryptPayload(userKey, req.CryptData, cred)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("encrypt payload: %w", err)
+	}
+
+	return model.Data{
+		ID:              id,
+		OwnerID:         userID,
+		Type:            s.dataType,
+		Data:            cryptData,
+		MetaData:        req.MetaData,
+		ClientEncrypted: clientEncrypted,
+	}, nil
+}
+
+// encryptPayload returns the payload to be stored for the credentials data. A payload that was
+// already encrypted on the client side is stored as is, otherwise the data is encrypted with the user key.
+func (s *CredentialsService) encryptPayload(userKey, clientCryptData []byte, cred model.CredentialsCryptData) ([]byte, bool, error) {
+	if len(clientCryptData) > 0 {
+		return clientCryptData, true, nil
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return nil, false, fmt.Errorf("marshal: %w", err)
+	}
+
+	cryptData, err := s.crypt.Encrypt(userKey, data)
+	if err != nil {
+		return nil, false, fmt.Errorf("encrypt data: %w", err)
+	}
+
+	return cryptData, false, nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/encryption"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// fakeRepository keeps the data in memory. Like the database, it saves a batch as a whole or, when err is set,
+// nothing of it.
+type fakeRepository struct {
+	DataRepository
+	data  []model.Data
+	err   error
+	calls int
+}
+
+func (f *fakeRepository) InsertBatch(_ context.Context, data []model.Data) ([]model.Data, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	for i := range data {
+		data[i].Revision = 1
+	}
+	f.data = append(f.data, data...)
+	return data, nil
+}
+
+type CredentialsServiceTestSuite struct {
+	suite.Suite
+	repo    *fakeRepository
+	crypt   *encryption.Service
+	userKey []byte
+	service *CredentialsService
+	ctx     context.Context
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(CredentialsServiceTestSuite))
+}
+
+func (s *CredentialsServiceTestSuite) SetupTest() {
+	var err error
+	s.crypt, err = encryption.New([]byte("test-master-key"))
+	s.Require().NoError(err)
+	s.userKey, err = s.crypt.GenerateKey()
+	s.Require().NoError(err)
+
+	s.repo = &fakeRepository{}
+	s.service = New(s.repo, s.crypt, nil)
+	s.ctx = context.WithValue(context.WithValue(context.Background(), model.UserIDKey, "user-id"), model.UserKey, s.userKey)
+}
+
+// Test_SaveBatch saves server and client side encrypted credentials by a single call of the repository.
+func (s *CredentialsServiceTestSuite) Test_SaveBatch() {
+	clientID := "6f1f1f4e-3c1a-11ef-9a2b-0242ac120002"
+	creds, err := s.service.SaveCredentialsBatch(s.ctx, []model.CredentialsPostRequest{
+		{Login: "alice", Password: "secret", MetaData: "mail"},
+		{ID: clientID, MetaData: "bank", CryptData: []byte("sealed")},
+	})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), creds, 2)
+	assert.Equal(s.T(), 1, s.repo.calls)
+	require.Len(s.T(), s.repo.data, 2)
+
+	// The credentials are returned in the order of the requests
+	assert.Equal(s.T(), "alice", creds[0].Login)
+	assert.Equal(s.T(), "mail", creds[0].MetaData)
+	assert.Equal(s.T(), clientID, creds[1].ID)
+	assert.Equal(s.T(), []byte("sealed"), creds[1].CryptData)
+
+	server := s.repo.data[0]
+	assert.Equal(s.T(), "user-id", server.OwnerID)
+	assert.Equal(s.T(), credentials, server.Type)
+	assert.False(s.T(), server.ClientEncrypted)
+	assert.NotContains(s.T(), string(server.Data), "secret")
+	plain, err := s.crypt.Decrypt(s.userKey, server.Data)
+	require.NoError(s.T(), err)
+	var cred model.CredentialsCryptData
+	require.NoError(s.T(), json.Unmarshal(plain, &cred))
+	assert.Equal(s.T(), model.CredentialsCryptData{Login: "alice", Password: "secret"}, cred)
+
+	client := s.repo.data[1]
+	assert.Equal(s.T(), clientID, client.ID)
+	assert.True(s.T(), client.ClientEncrypted)
+	assert.Equal(s.T(), []byte("sealed"), client.Data)
+}
+
+// Test_FailedBatchSavesNothing checks that a failed batch returns no credentials and an empty one isn't written.
+func (s *CredentialsServiceTestSuite) Test_FailedBatchSavesNothing() {
+	creds, err := s.service.SaveCredentialsBatch(s.ctx, nil)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), creds)
+	assert.Zero(s.T(), s.repo.calls)
+
+	s.repo.err = errors.New("insert batch: unique violation")
+	creds, err = s.service.SaveCredentialsBatch(s.ctx, []model.CredentialsPostRequest{
+		{Login: "alice", Password: "secret"},
+		{Login: "bob", Password: "secret"},
+	})
+	require.Error(s.T(), err)
+	assert.Empty(s.T(), creds)
+	assert.Empty(s.T(), s.repo.data)
+}
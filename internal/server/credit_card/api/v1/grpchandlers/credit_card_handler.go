@@ -2,7 +2,10 @@ package grpchandlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -15,13 +18,19 @@ import (
 // CreditCardService defines the methods for operations related to credit cards.
 type CreditCardService interface {
 	SaveCreditCard(ctx context.Context, req model.CreditCardPostRequest) (model.CreditCard, error)
+	SaveCreditCardBatch(ctx context.Context, reqs []model.CreditCardPostRequest) ([]model.CreditCard, error)
 	LoadCreditCardData(ctx context.Context, dataID string) (model.CreditCard, error)
-	LoadAllCreditCardInfo(ctx context.Context) ([]model.DataInfo, error)
+	LoadAllCreditCardInfo(ctx context.Context, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCreditCard(ctx context.Context, req model.CreditCardPutRequest) (model.CreditCard, error)
+	DeleteCreditCard(ctx context.Context, dataID string) error
 }
 
 // Validator defines the method for validating credit card requests.
 type Validator interface {
 	ValidatePostRequest(req *model.CreditCardPostRequest) (map[string]string, bool)
+	ValidatePutRequest(req *model.CreditCardPutRequest) (map[string]string, bool)
+	ValidateDeleteRequest(req *model.DataDeleteRequest) (map[string]string, bool)
+	ValidatePageRequest(req *model.PageRequest) (map[string]string, bool)
 }
 
 // CreditCardHandler is the gRPC handler for credit card-related operations.
@@ -43,12 +52,17 @@ func New(creditCardService CreditCardService, validator Validator) *CreditCardHa
 // It validates the request and saves the credit card data.
 func (h *CreditCardHandler) PostSaveCreditCard(ctx context.Context, in *pb.PostCreditCardRequest) (*pb.PostCreditCardResponse, error) {
 	req := model.CreditCardPostRequest{
+		ID:        in.Id,
 		Number:    in.Number,
 		OwnerName: in.OwnerName,
 		ExpiresAt: in.ExpiresAt,
 		CVV:       in.CvvCode,
 		PinCode:   in.PinCode,
 		MetaData:  in.Metadata,
+		CryptData: in.CryptData,
+
+		ExpiryReminder: in.ExpiryReminder,
+		ExpiryMonth:    in.ExpiryMonth,
 	}
 
 	report, ok := h.validator.ValidatePostRequest(&req)
@@ -59,6 +73,16 @@ func (h *CreditCardHandler) PostSaveCreditCard(ctx context.Context, in *pb.PostC
 	}
 
 	creditCard, err := h.creditCardService.SaveCreditCard(ctx, req)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to save credit_card: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataExists) {
+		logrus.Info("Unable to save credit_card: id is taken")
+		return nil, status.Error(codes.AlreadyExists, "data with this id already exists")
+	}
+
 	if err != nil {
 		logrus.WithError(err).Error("Unable to save credit_card")
 		return nil, status.Error(codes.Internal, "internal error")
@@ -74,29 +98,136 @@ func (h *CreditCardHandler) PostSaveCreditCard(ctx context.Context, in *pb.PostC
 		PinCode:   creditCard.PinCode,
 		Metadata:  creditCard.MetaData,
 		CreatedAt: creditCard.CreatedAt.Format(time.RFC3339),
+		Revision:  creditCard.Revision,
 	}, nil
 }
 
-// GetLoadAllCreditCardDataInfo handles the gRPC call for loading all credit card information.
-func (h *CreditCardHandler) GetLoadAllCreditCardDataInfo(ctx context.Context, _ *pb.GetAllCreditCardInfoRequest) (*pb.GetAllCreditCardInfoResponse, error) {
+// PostSaveCreditCardBatch handles the gRPC call to save many credit cards at once. Every item is validated and
+// the violations are reported per item with the path of the field, like cards[3].Number.
+// The valid items are saved in a single transaction, an atomic batch is rejected as a whole when any item is invalid.
+func (h *CreditCardHandler) PostSaveCreditCardBatch(ctx context.Context, in *pb.PostCreditCardBatchRequest) (*pb.PostCreditCardBatchResponse, error) {
+	if len(in.Cards) > lib.MaxBatchSize {
+		logrus.Infof("Unable to save credit_card batch: %d items", len(in.Cards))
+		return nil, lib.ProcessValidationError("invalid credit_card batch request",
+			map[string]string{"cards": fmt.Sprintf("must hold at most %d items", lib.MaxBatchSize)})
+	}
+
+	results := make([]*pb.CreditCardBatchResult, len(in.Cards))
+	reqs := make([]model.CreditCardPostRequest, 0, len(in.Cards))
+	indexes := make([]int, 0, len(in.Cards))
+	batchReport := make(map[string]string)
+	for i, item := range in.Cards {
+		req := model.CreditCardPostRequest{
+			ID:        item.Id,
+			Number:    item.Number,
+			OwnerName: item.OwnerName,
+			ExpiresAt: item.ExpiresAt,
+			CVV:       item.CvvCode,
+			PinCode:   item.PinCode,
+			MetaData:  item.Metadata,
+			CryptData: item.CryptData,
+
+			ExpiryReminder: item.ExpiryReminder,
+			ExpiryMonth:    item.ExpiryMonth,
+		}
+
+		report, ok := h.validator.ValidatePostRequest(&req)
+		if ok {
+			reqs = append(reqs, req)
+			indexes = append(indexes, i)
+			continue
+		}
+
+		results[i] = &pb.CreditCardBatchResult{}
+		for _, violation := range lib.BatchViolations("cards", i, report) {
+			batchReport[violation.Field] = violation.Description
+			results[i].Violations = append(results[i].Violations, &pb.CreditCardFieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+	}
+
+	if len(batchReport) != 0 && in.Atomic {
+		logrus.Info("Unable to save credit_card batch: invalid items in an atomic batch")
+		logrus.Infof("violated_fields %v", batchReport)
+		return nil, lib.ProcessValidationError("invalid credit_card batch request", batchReport)
+	}
+
+	saved, err := h.creditCardService.SaveCreditCardBatch(ctx, reqs)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to save credit_card batch: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataExists) {
+		logrus.Info("Unable to save credit_card batch: id is taken")
+		return nil, status.Error(codes.AlreadyExists, "data with this id already exists")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to save credit_card batch")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	for j, v := range saved {
+		results[indexes[j]] = &pb.CreditCardBatchResult{Saved: &pb.PostCreditCardResponse{
+			Id:        v.ID,
+			OwnerId:   v.OwnerID,
+			Number:    v.Number,
+			OwnerName: v.OwnerName,
+			ExpiresAt: v.ExpiresAt,
+			CvvCode:   v.CVV,
+			PinCode:   v.PinCode,
+			Metadata:  v.MetaData,
+			CreatedAt: v.CreatedAt.Format(time.RFC3339),
+			Revision:  v.Revision,
+		}}
+	}
+
+	return &pb.PostCreditCardBatchResponse{Results: results}, nil
+}
+
+// GetLoadAllCreditCardDataInfo handles the gRPC call for loading a page of credit card information.
+func (h *CreditCardHandler) GetLoadAllCreditCardDataInfo(ctx context.Context, in *pb.GetAllCreditCardInfoRequest) (*pb.GetAllCreditCardInfoResponse, error) {
+	req := model.PageRequest{
+		PageSize:   int(in.PageSize),
+		PageToken:  in.PageToken,
+		SortBy:     in.SortBy,
+		Descending: in.Descending,
+	}
+
+	report, ok := h.validator.ValidatePageRequest(&req)
+	if !ok {
+		logrus.Info("Unable to load credit card info: invalid page request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid page request", report)
+	}
+
+	cardPage, err := h.creditCardService.LoadAllCreditCardInfo(ctx, req)
+	if errors.Is(err, cerrors.ErrInvalidPageToken) {
+		logrus.Info("Unable to load credit card info: invalid page token")
+		return nil, lib.ProcessValidationError("invalid page request", map[string]string{"PageToken": "is invalid or issued for another sort order"})
+	}
 
-	cardInfo, err := h.creditCardService.LoadAllCreditCardInfo(ctx)
 	if err != nil {
 		logrus.WithError(err).Error("Error while loading credit card data: ")
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	cardInfos := make([]*pb.CreditCardInfo, 0, len(cardInfo))
-	for _, v := range cardInfo {
+	cardInfos := make([]*pb.CreditCardInfo, 0, len(cardPage.Infos))
+	for _, v := range cardPage.Infos {
 		cardInfos = append(cardInfos, &pb.CreditCardInfo{
 			Id:        v.ID,
 			DataType:  v.DataType,
 			Metadata:  v.MetaData,
 			CreatedAt: v.CreatedAt.Format(time.RFC3339),
+			Revision:  v.Revision,
+			UpdatedAt: v.UpdatedAt.Format(time.RFC3339),
 		})
 	}
 
-	return &pb.GetAllCreditCardInfoResponse{Cards: cardInfos}, nil
+	return &pb.GetAllCreditCardInfoResponse{Cards: cardInfos, NextPageToken: cardPage.NextPageToken}, nil
 }
 
 // GetLoadCreditCard handles the gRPC call for loading a specific credit card's data.
@@ -119,6 +250,95 @@ func (h *CreditCardHandler) GetLoadCreditCard(ctx context.Context, in *pb.GetCre
 		PinCode:   cardData.PinCode,
 		Metadata:  cardData.MetaData,
 		CreatedAt: cardData.CreatedAt.Format(time.RFC3339Nano),
+		Revision:  cardData.Revision,
+		CryptData: cardData.CryptData,
+
+		ExpiryReminder: cardData.ExpiryReminder,
 	}
 	return &pb.GetCreditCardResponse{CardData: card}, nil
 }
+
+// PutUpdateCreditCard handles the gRPC call for updating an existing credit card.
+// It validates the request and replaces the stored credit card data.
+func (h *CreditCardHandler) PutUpdateCreditCard(ctx context.Context, in *pb.PutCreditCardRequest) (*pb.PutCreditCardResponse, error) {
+	req := model.CreditCardPutRequest{
+		ID:        in.Id,
+		Revision:  in.Revision,
+		Number:    in.Number,
+		OwnerName: in.OwnerName,
+		ExpiresAt: in.ExpiresAt,
+		CVV:       in.CvvCode,
+		PinCode:   in.PinCode,
+		MetaData:  in.Metadata,
+		CryptData: in.CryptData,
+
+		ExpiryReminder: in.ExpiryReminder,
+		ExpiryMonth:    in.ExpiryMonth,
+	}
+
+	report, ok := h.validator.ValidatePutRequest(&req)
+	if !ok {
+		logrus.Info("Unable to update credit_card: invalid credit_card request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid credit_card put request", report)
+	}
+
+	creditCard, err := h.creditCardService.UpdateCreditCard(ctx, req)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to update credit_card: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to update credit_card: credit_card %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "credit card not found")
+	}
+
+	if errors.Is(err, cerrors.ErrRevisionConflict) {
+		logrus.Infof("Unable to update credit_card: credit_card %s has a newer revision", req.ID)
+		return nil, status.Error(codes.Aborted, "credit card was changed by another client")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to update credit_card")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PutCreditCardResponse{
+		Id:        creditCard.ID,
+		OwnerId:   creditCard.OwnerID,
+		Number:    creditCard.Number,
+		OwnerName: creditCard.OwnerName,
+		ExpiresAt: creditCard.ExpiresAt,
+		CvvCode:   creditCard.CVV,
+		PinCode:   creditCard.PinCode,
+		Metadata:  creditCard.MetaData,
+		CreatedAt: creditCard.CreatedAt.Format(time.RFC3339),
+		Revision:  creditCard.Revision,
+	}, nil
+}
+
+// DeleteCreditCard handles the gRPC call for deleting a specific credit card.
+func (h *CreditCardHandler) DeleteCreditCard(ctx context.Context, in *pb.DeleteCreditCardRequest) (*pb.DeleteCreditCardResponse, error) {
+	req := model.DataDeleteRequest{ID: in.Id}
+
+	report, ok := h.validator.ValidateDeleteRequest(&req)
+	if !ok {
+		logrus.Info("Unable to delete credit_card: invalid credit_card request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid credit_card delete request", report)
+	}
+
+	err := h.creditCardService.DeleteCreditCard(ctx, req.ID)
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to delete credit_card: credit_card %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "credit card not found")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to delete credit_card")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.DeleteCreditCardResponse{}, nil
+}
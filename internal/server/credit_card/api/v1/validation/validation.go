@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/pagination"
 	"strings"
 	"time"
 	"unicode"
@@ -11,7 +12,10 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
-const expiresAtLayout = "02-01-2006" // Define the layout for the expiration date format
+const (
+	expiresAtLayout   = "02-01-2006" // Define the layout for the expiration date format
+	expiryMonthLayout = "01-2006"    // Layout of the expiry month of the reminder of client side encrypted cards
+)
 
 // Validator is a struct that holds the validator instance.
 type Validator struct {
@@ -47,9 +51,20 @@ func New(validator *validator.Validate) (*Validator, error) {
 		return nil, fmt.Errorf("register expires_at: %w", err)
 	}
 
+	err = v.validator.RegisterValidation("expiry_month", expiryMonth)
+	if err != nil {
+		return nil, fmt.Errorf("register expiry_month: %w", err)
+	}
+
 	return v, nil
 }
 
+// expiryMonth checks if the expiry month of the reminder is in the correct format (MM-YYYY).
+func expiryMonth(fl validator.FieldLevel) bool {
+	_, err := time.Parse(expiryMonthLayout, fl.Field().String())
+	return err == nil
+}
+
 // expiresAt checks if the expiration date is in the correct format (DD-MM-YYYY).
 func expiresAt(fl validator.FieldLevel) bool {
 	_, err := time.Parse(expiresAtLayout, fl.Field().String())
@@ -109,7 +124,46 @@ func owner(fl validator.FieldLevel) bool {
 
 // ValidatePostRequest validates the incoming CreditCardPostRequest.
 func (v *Validator) ValidatePostRequest(req *model.CreditCardPostRequest) (map[string]string, bool) {
-	err := v.validator.Struct(req)
+	if len(req.CryptData) > 0 {
+		// Client side encrypted payload is opaque to the server, only the ID it is bound to and the expiry month
+		// of the reminder can be checked
+		report, ok := processValidationErrors(v.validator.StructPartial(req, "ID", "ExpiryMonth"))
+		return checkReminderMonth(req.ExpiryReminder, req.ExpiryMonth, report, ok)
+	}
+	return processValidationErrors(v.validator.Struct(req))
+}
+
+// ValidatePutRequest validates the incoming CreditCardPutRequest.
+func (v *Validator) ValidatePutRequest(req *model.CreditCardPutRequest) (map[string]string, bool) {
+	if len(req.CryptData) > 0 {
+		// Client side encrypted payload is opaque to the server, only the ID and the expiry month can be checked
+		report, ok := processValidationErrors(v.validator.StructPartial(req, "ID", "ExpiryMonth"))
+		return checkReminderMonth(req.ExpiryReminder, req.ExpiryMonth, report, ok)
+	}
+	return processValidationErrors(v.validator.Struct(req))
+}
+
+// checkReminderMonth adds a violation to the report of a client side encrypted card with a reminder but
+// without the expiry month, the server can't read the expiry date of such cards.
+func checkReminderMonth(reminder bool, month string, report map[string]string, ok bool) (map[string]string, bool) {
+	if !reminder || month != "" {
+		return report, ok
+	}
+	if report == nil {
+		report = make(map[string]string)
+	}
+	report["ExpiryMonth"] = "is required for the expiry reminder of client side encrypted cards"
+
+	return report, false
+}
+
+// ValidateDeleteRequest validates the incoming DataDeleteRequest for a credit card.
+func (v *Validator) ValidateDeleteRequest(req *model.DataDeleteRequest) (map[string]string, bool) {
+	return processValidationErrors(v.validator.Struct(req))
+}
+
+// processValidationErrors converts validator errors into a field to message report.
+func processValidationErrors(err error) (map[string]string, bool) {
 	report := make(map[string]string)
 	if err != nil {
 		var validationErrors validator.ValidationErrors
@@ -126,8 +180,12 @@ func (v *Validator) ValidatePostRequest(req *model.CreditCardPostRequest) (map[s
 					report[validationErr.Field()] = "must be valid pin"
 				case "required":
 					report[validationErr.Field()] = "is required"
+				case "uuid":
+					report[validationErr.Field()] = "must be a UUID"
 				case "expires_at":
 					report[validationErr.Field()] = "expires_at must be in DD-MM-YYYY format"
+				case "expiry_month":
+					report[validationErr.Field()] = "must be in MM-YYYY format"
 				}
 			}
 			return report, false
@@ -136,3 +194,8 @@ func (v *Validator) ValidatePostRequest(req *model.CreditCardPostRequest) (map[s
 	}
 	return nil, true
 }
+
+// ValidatePageRequest validates the incoming PageRequest of a data listing.
+func (v *Validator) ValidatePageRequest(req *model.PageRequest) (map[string]string, bool) {
+	return pagination.Validate(v.validator, req)
+}
@@ -4,19 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/google/uuid"
+	"time"
 
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/pagination"
 	"github.com/DenisKhanov/PrivateKeeperV2/pkg/jwtmanager"
 )
 
-const creditCard = "credit_card" // Constant for the credit card data type
+const (
+	creditCard        = "credit_card" // Constant for the credit card data type
+	expiresAtLayout   = "02-01-2006"  // Layout of the expiry date of cards
+	expiryMonthLayout = "01-2006"     // Layout of the expiry month of the reminder of client side encrypted cards
+)
 
 // DataRepository interface defines methods for data access.
 type DataRepository interface {
-	Insert(ctx context.Context, data model.Data) (model.Data, error)
-	SelectAll(ctx context.Context, userID, dataType string) ([]model.Data, error)
+	InsertWithReminders(ctx context.Context, data []model.Data, reminders []model.ExpiryReminder) ([]model.Data, error)
+	SelectPage(ctx context.Context, userID, dataType string, page model.PageRequest, after *model.PageCursor, limit int) ([]model.Data, error)
 	SelectByID(ctx context.Context, userID, dataType, dataID string) (model.Data, error)
+	UpdateWithReminder(ctx context.Context, data model.Data, reminder *model.ExpiryReminder) (model.Data, error)
+	Delete(ctx context.Context, userID, dataType, dataID string) error
+	SelectExpiryReminder(ctx context.Context, userID, dataID string) (bool, error)
 }
 
 // CryptService interface defines methods for encryption and decryption.
@@ -56,41 +65,26 @@ func (s *CreditCardService) SaveCreditCard(ctx context.Context, req model.Credit
 		return model.CreditCard{}, fmt.Errorf("failed to get userKey from context")
 	}
 
-	id, err := uuid.NewUUID()
-	if err != nil {
-		return model.CreditCard{}, fmt.Errorf("new uuid: %w", err)
-	}
-
-	card := model.CreditCardCryptData{
-		Number:    req.Number,
-		OwnerName: req.OwnerName,
-		ExpiresAt: req.ExpiresAt,
-		CVV:       req.CVV,
-		PinCode:   req.PinCode,
-	}
-
-	data, err := json.Marshal(card)
-	if err != nil {
-		return model.CreditCard{}, fmt.Errorf("marshal: %w", err)
-	}
-
-	cryptData, err := s.crypt.Encrypt(userKey, data)
+	dataToSave, err := s.newData(userID, userKey, req)
 	if err != nil {
-		return model.CreditCard{}, fmt.Errorf("encrypt data: %w", err)
+		return model.CreditCard{}, err
 	}
 
-	dataToSave := model.Data{
-		ID:       id.String(),
-		OwnerID:  userID,
-		Type:     s.dataType,
-		Data:     cryptData,
-		MetaData: req.MetaData,
+	var reminders []model.ExpiryReminder
+	if req.ExpiryReminder {
+		reminder, err := newReminder(userID, dataToSave.ID, req.ExpiresAt, req.ExpiryMonth, req.CryptData)
+		if err != nil {
+			return model.CreditCard{}, err
+		}
+		reminders = append(reminders, reminder)
 	}
 
-	savedCreditCard, err := s.repository.Insert(ctx, dataToSave)
+	// The reminder is saved in the transaction of the card, so a failed reminder doesn't leave the card behind
+	saved, err := s.repository.InsertWithReminders(ctx, []model.Data{dataToSave}, reminders)
 	if err != nil {
 		return model.CreditCard{}, fmt.Errorf("insert credit card: %w", err)
 	}
+	savedCreditCard := saved[0]
 
 	return model.CreditCard{
 		ID:        savedCreditCard.ID,
@@ -102,21 +96,94 @@ func (s *CreditCardService) SaveCreditCard(ctx context.Context, req model.Credit
 		PinCode:   req.PinCode,
 		MetaData:  savedCreditCard.MetaData,
 		CreatedAt: savedCreditCard.CreatedAt,
+		Revision:  savedCreditCard.Revision,
+		CryptData: req.CryptData,
+
+		ExpiryReminder: req.ExpiryReminder,
 	}, nil
 }
 
-// LoadAllCreditCardInfo retrieves all credit card information for the user.
-func (s *CreditCardService) LoadAllCreditCardInfo(ctx context.Context) ([]model.DataInfo, error) {
+// SaveCreditCardBatch encrypts the provided credit cards and saves them to the repository in a single transaction.
+// It returns the saved CreditCard objects in the order of the requests, either all of them are saved or none.
+func (s *CreditCardService) SaveCreditCardBatch(ctx context.Context, reqs []model.CreditCardPostRequest) ([]model.CreditCard, error) {
 	userID, ok := ctx.Value(model.UserIDKey).(string)
 	if !ok {
 		return nil, fmt.Errorf("failed to get userID from context")
 	}
 
-	encryptedCardData, err := s.repository.SelectAll(ctx, userID, s.dataType)
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return nil, fmt.Errorf("failed to get userKey from context")
+	}
+
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	dataToSave := make([]model.Data, 0, len(reqs))
+	var reminders []model.ExpiryReminder
+	for _, req := range reqs {
+		data, err := s.newData(userID, userKey, req)
+		if err != nil {
+			return nil, err
+		}
+		dataToSave = append(dataToSave, data)
+
+		if req.ExpiryReminder {
+			reminder, err := newReminder(userID, data.ID, req.ExpiresAt, req.ExpiryMonth, req.CryptData)
+			if err != nil {
+				return nil, err
+			}
+			reminders = append(reminders, reminder)
+		}
+	}
+
+	savedData, err := s.repository.InsertWithReminders(ctx, dataToSave, reminders)
 	if err != nil {
-		return nil, fmt.Errorf("select all binary_data: %w", err)
+		return nil, fmt.Errorf("insert credit cards batch: %w", err)
+	}
+
+	result := make([]model.CreditCard, 0, len(savedData))
+	for i, saved := range savedData {
+		result = append(result, model.CreditCard{
+			ID:        saved.ID,
+			OwnerID:   saved.OwnerID,
+			Number:    reqs[i].Number,
+			OwnerName: reqs[i].OwnerName,
+			ExpiresAt: reqs[i].ExpiresAt,
+			CVV:       reqs[i].CVV,
+			PinCode:   reqs[i].PinCode,
+			MetaData:  saved.MetaData,
+			CreatedAt: saved.CreatedAt,
+			Revision:  saved.Revision,
+			CryptData: reqs[i].CryptData,
+
+			ExpiryReminder: reqs[i].ExpiryReminder,
+		})
 	}
 
+	return result, nil
+}
+
+// LoadAllCreditCardInfo retrieves a page of credit cards information for the user, sorted as requested.
+func (s *CreditCardService) LoadAllCreditCardInfo(ctx context.Context, page model.PageRequest) (model.DataInfoPage, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.DataInfoPage{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	page = pagination.Normalize(page)
+	after, err := pagination.DecodeToken(page)
+	if err != nil {
+		return model.DataInfoPage{}, err
+	}
+
+	encryptedCardData, err := s.repository.SelectPage(ctx, userID, s.dataType, page, after, page.PageSize+1)
+	if err != nil {
+		return model.DataInfoPage{}, fmt.Errorf("select page credit_card: %w", err)
+	}
+	encryptedCardData, nextPageToken := pagination.Page(page, encryptedCardData)
+
 	credCardDataInfo := make([]model.DataInfo, 0, len(encryptedCardData))
 	for _, encryptedBinary := range encryptedCardData {
 		credCardDataInfo = append(credCardDataInfo, model.DataInfo{
@@ -124,9 +191,11 @@ func (s *CreditCardService) LoadAllCreditCardInfo(ctx context.Context) ([]model.
 			DataType:  s.dataType,
 			MetaData:  encryptedBinary.MetaData,
 			CreatedAt: encryptedBinary.CreatedAt,
+			Revision:  encryptedBinary.Revision,
+			UpdatedAt: encryptedBinary.UpdatedAt,
 		})
 	}
-	return credCardDataInfo, nil
+	return model.DataInfoPage{Infos: credCardDataInfo, NextPageToken: nextPageToken}, nil
 }
 
 // LoadCreditCardData retrieves and decrypts a specific credit card's data.
@@ -145,6 +214,25 @@ func (s *CreditCardService) LoadCreditCardData(ctx context.Context, dataID strin
 	if err != nil {
 		return model.CreditCard{}, fmt.Errorf("select all credit_card_data: %w", err)
 	}
+
+	reminder, err := s.repository.SelectExpiryReminder(ctx, userID, dataID)
+	if err != nil {
+		return model.CreditCard{}, fmt.Errorf("select expiry reminder: %w", err)
+	}
+
+	if encryptedCardData.ClientEncrypted {
+		return model.CreditCard{
+			ID:        encryptedCardData.ID,
+			OwnerID:   encryptedCardData.OwnerID,
+			MetaData:  encryptedCardData.MetaData,
+			CreatedAt: encryptedCardData.CreatedAt,
+			Revision:  encryptedCardData.Revision,
+			CryptData: encryptedCardData.Data,
+
+			ExpiryReminder: reminder,
+		}, nil
+	}
+
 	decryptedData, err := s.crypt.Decrypt(userKey, encryptedCardData.Data)
 	if err != nil {
 		return model.CreditCard{}, fmt.Errorf("decrypt card: %w", err)
@@ -166,7 +254,162 @@ func (s *CreditCardService) LoadCreditCardData(ctx context.Context, dataID strin
 		PinCode:   decryptedCardData.PinCode,
 		MetaData:  encryptedCardData.MetaData,
 		CreatedAt: encryptedCardData.CreatedAt,
+		Revision:  encryptedCardData.Revision,
+
+		ExpiryReminder: reminder,
 	}
 
 	return card, nil
 }
+
+// UpdateCreditCard re-encrypts and replaces the data of an existing credit card.
+func (s *CreditCardService) UpdateCreditCard(ctx context.Context, req model.CreditCardPutRequest) (model.CreditCard, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.CreditCard{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return model.CreditCard{}, fmt.Errorf("failed to get userKey from context")
+	}
+
+	card := model.CreditCardCryptData{
+		Number:    req.Number,
+		OwnerName: req.OwnerName,
+		ExpiresAt: req.ExpiresAt,
+		CVV:       req.CVV,
+		PinCode:   req.PinCode,
+	}
+
+	cryptData, clientEncrypted, err := s.encryptPayload(userKey, req.CryptData, card)
+	if err != nil {
+		return model.CreditCard{}, fmt.Errorf("encrypt payload: %w", err)
+	}
+
+	dataToUpdate := model.Data{
+		ID:              req.ID,
+		OwnerID:         userID,
+		Type:            s.dataType,
+		Data:            cryptData,
+		MetaData:        req.MetaData,
+		ClientEncrypted: clientEncrypted,
+		Revision:        req.Revision,
+	}
+
+	// Without a reminder in the request the reminder of the card is turned off
+	var reminder *model.ExpiryReminder
+	if req.ExpiryReminder {
+		r, err := newReminder(userID, req.ID, req.ExpiresAt, req.ExpiryMonth, req.CryptData)
+		if err != nil {
+			return model.CreditCard{}, err
+		}
+		reminder = &r
+	}
+
+	updatedCreditCard, err := s.repository.UpdateWithReminder(ctx, dataToUpdate, reminder)
+	if err != nil {
+		return model.CreditCard{}, fmt.Errorf("update credit card: %w", err)
+	}
+
+	return model.CreditCard{
+		ID:        updatedCreditCard.ID,
+		OwnerID:   updatedCreditCard.OwnerID,
+		Number:    req.Number,
+		OwnerName: req.OwnerName,
+		ExpiresAt: req.ExpiresAt,
+		CVV:       req.CVV,
+		PinCode:   req.PinCode,
+		MetaData:  updatedCreditCard.MetaData,
+		CreatedAt: updatedCreditCard.CreatedAt,
+		Revision:  updatedCreditCard.Revision,
+		CryptData: req.CryptData,
+
+		ExpiryReminder: req.ExpiryReminder,
+	}, nil
+}
+
+// DeleteCreditCard removes a specific credit card of the user.
+func (s *CreditCardService) DeleteCreditCard(ctx context.Context, dataID string) error {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get userID from context")
+	}
+
+	err := s.repository.Delete(ctx, userID, s.dataType, dataID)
+	if err != nil {
+		return fmt.Errorf("delete credit card: %w", err)
+	}
+
+	return nil
+}
+
+// newData returns a new data entry of the user holding the encrypted credit card.
+func (s *CreditCardService) newData(userID string, userKey []byte, req model.CreditCardPostRequest) (model.Data, error) {
+	id, err := lib.NewDataID(req.ID)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("new data id: %w", err)
+	}
+
+	card := model.CreditCardCryptData{
+		Number:    req.Number,
+		OwnerName: req.OwnerName,
+		ExpiresAt: req.ExpiresAt,
+		CVV:       req.CVV,
+		PinCode:   req.PinCode,
+	}
+
+	cryptData, clientEncrypted, err := s.encryptPayload(userKey, req.CryptData, card)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("encrypt payload: %w", err)
+	}
+
+	return model.Data{
+		ID:              id,
+		OwnerID:         userID,
+		Type:            s.dataType,
+		Data:            cryptData,
+		MetaData:        req.MetaData,
+		ClientEncrypted: clientEncrypted,
+	}, nil
+}
+
+// newReminder returns the expiry reminder of the card. The expiry month is taken from the expiry date
+// of cards encrypted on the server and from the month sent along with cards encrypted on the client.
+func newReminder(userID, dataID, expiresAt, expiryMonth string, clientCryptData []byte) (model.ExpiryReminder, error) {
+	var (
+		month time.Time
+		err   error
+	)
+	if len(clientCryptData) > 0 {
+		month, err = time.Parse(expiryMonthLayout, expiryMonth)
+	} else {
+		month, err = time.Parse(expiresAtLayout, expiresAt)
+		month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	if err != nil {
+		return model.ExpiryReminder{}, fmt.Errorf("parse expiry month: %w", err)
+	}
+
+	return model.ExpiryReminder{DataID: dataID, OwnerID: userID, ExpiryMonth: month}, nil
+}
+
+// encryptPayload returns the payload to be stored for the card data. A payload that was
+// already encrypted on the client side is stored as is, otherwise the data is encrypted with the user key.
+func (s *CreditCardService) encryptPayload(userKey, clientCryptData []byte, card model.CreditCardCryptData) ([]byte, bool, error) {
+	if len(clientCryptData) > 0 {
+		return clientCryptData, true, nil
+	}
+
+	data, err := json.Marshal(card)
+	if err != nil {
+		return nil, false, fmt.Errorf("marshal: %w", err)
+	}
+
+	cryptData, err := s.crypt.Encrypt(userKey, data)
+	if err != nil {
+		return nil, false, fmt.Errorf("encrypt data: %w", err)
+	}
+
+	return cryptData, false, nil
+}
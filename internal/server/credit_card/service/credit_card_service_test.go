@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/encryption"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// fakeRepository keeps the cards and reminders in memory. Like the database, it saves the cards
+// and their reminders all together or, when err is set, none of them.
+type fakeRepository struct {
+	DataRepository
+	cards     map[string]model.Data
+	reminders map[string]model.ExpiryReminder
+	err       error
+	calls     int
+}
+
+func (f *fakeRepository) InsertWithReminders(_ context.Context, data []model.Data, reminders []model.ExpiryReminder) ([]model.Data, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, d := range data {
+		d.Revision = 1
+		f.cards[d.ID] = d
+	}
+	for _, r := range reminders {
+		f.reminders[r.DataID] = r
+	}
+	return data, nil
+}
+
+func (f *fakeRepository) UpdateWithReminder(_ context.Context, data model.Data, reminder *model.ExpiryReminder) (model.Data, error) {
+	f.calls++
+	if f.err != nil {
+		return model.Data{}, f.err
+	}
+	f.cards[data.ID] = data
+	delete(f.reminders, data.ID)
+	if reminder != nil {
+		f.reminders[data.ID] = *reminder
+	}
+	return data, nil
+}
+
+type CreditCardServiceTestSuite struct {
+	suite.Suite
+	repo    *fakeRepository
+	service *CreditCardService
+	ctx     context.Context
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(CreditCardServiceTestSuite))
+}
+
+func (s *CreditCardServiceTestSuite) SetupTest() {
+	crypt, err := encryption.New([]byte("test-master-key"))
+	s.Require().NoError(err)
+	userKey, err := crypt.GenerateKey()
+	s.Require().NoError(err)
+
+	s.repo = &fakeRepository{cards: make(map[string]model.Data), reminders: make(map[string]model.ExpiryReminder)}
+	s.service = New(s.repo, crypt, nil)
+	s.ctx = context.WithValue(context.WithValue(context.Background(), model.UserIDKey, "user-id"), model.UserKey, userKey)
+}
+
+func (s *CreditCardServiceTestSuite) Test_SaveBatchWithReminders() {
+	cards, err := s.service.SaveCreditCardBatch(s.ctx, []model.CreditCardPostRequest{
+		{Number: "4111111111111111", OwnerName: "ALICE", ExpiresAt: "31-03-2027", CVV: "123", PinCode: "1234"},
+		{Number: "5500000000000004", OwnerName: "ALICE", ExpiresAt: "15-11-2028", CVV: "456", PinCode: "5678", ExpiryReminder: true},
+	})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), cards, 2)
+
+	// Cards and reminders are saved by a single call of the repository
+	assert.Equal(s.T(), 1, s.repo.calls)
+	require.Len(s.T(), s.repo.reminders, 1)
+	assert.Equal(s.T(), model.ExpiryReminder{
+		DataID:      cards[1].ID,
+		OwnerID:     "user-id",
+		ExpiryMonth: time.Date(2028, 11, 1, 0, 0, 0, 0, time.UTC),
+	}, s.repo.reminders[cards[1].ID])
+}
+
+func (s *CreditCardServiceTestSuite) Test_ReminderFailureSavesNoCard() {
+	// An invalid expiry month of a client side encrypted card fails before anything is written
+	_, err := s.service.SaveCreditCardBatch(s.ctx, []model.CreditCardPostRequest{
+		{MetaData: "first", CryptData: []byte("sealed")},
+		{MetaData: "second", CryptData: []byte("sealed"), ExpiryReminder: true, ExpiryMonth: "13-2027"},
+	})
+	require.Error(s.T(), err)
+	assert.Zero(s.T(), s.repo.calls)
+
+	// A failed reminder fails the card saved along with it
+	s.repo.err = errors.New("upsert expiry reminder: foreign key violation")
+	_, err = s.service.SaveCreditCard(s.ctx, model.CreditCardPostRequest{
+		Number: "4111111111111111", OwnerName: "ALICE", ExpiresAt: "31-03-2027", CVV: "123", PinCode: "1234", ExpiryReminder: true,
+	})
+	require.Error(s.T(), err)
+	assert.Empty(s.T(), s.repo.cards)
+	assert.Empty(s.T(), s.repo.reminders)
+}
+
+func (s *CreditCardServiceTestSuite) Test_UpdateTurnsReminderOff() {
+	card, err := s.service.SaveCreditCard(s.ctx, model.CreditCardPostRequest{
+		Number: "4111111111111111", OwnerName: "ALICE", ExpiresAt: "31-03-2027", CVV: "123", PinCode: "1234", ExpiryReminder: true,
+	})
+	require.NoError(s.T(), err)
+	require.Contains(s.T(), s.repo.reminders, card.ID)
+
+	_, err = s.service.UpdateCreditCard(s.ctx, model.CreditCardPutRequest{
+		ID: card.ID, Number: "4111111111111111", OwnerName: "ALICE", ExpiresAt: "31-03-2027", CVV: "123", PinCode: "1234",
+		Revision: card.Revision,
+	})
+	require.NoError(s.T(), err)
+	assert.NotContains(s.T(), s.repo.reminders, card.ID)
+}
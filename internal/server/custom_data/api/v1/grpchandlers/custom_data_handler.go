@@ -0,0 +1,438 @@
+package grpchandlers
+
+import (
+	"context"
+	"errors"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sort"
+	"time"
+
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/custom_data"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// CustomDataService interface defines the methods for item template and custom data management.
+type CustomDataService interface {
+	SaveTemplate(ctx context.Context, req model.TemplateRequest) (model.Template, error)
+	LoadTemplates(ctx context.Context) ([]model.Template, error)
+	LoadTemplate(ctx context.Context, templateID string) (model.Template, error)
+	UpdateTemplate(ctx context.Context, req model.TemplateRequest) (model.Template, error)
+	DeleteTemplate(ctx context.Context, templateID string) error
+	SaveCustomData(ctx context.Context, req model.CustomDataPostRequest) (model.CustomData, error)
+	LoadCustomData(ctx context.Context, dataID string) (model.CustomData, error)
+	LoadAllCustomInfo(ctx context.Context, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateCustomData(ctx context.Context, req model.CustomDataPutRequest) (model.CustomData, error)
+	DeleteCustomData(ctx context.Context, dataID string) error
+}
+
+// Validator interface defines the method for validating requests.
+type Validator interface {
+	ValidateTemplateRequest(req *model.TemplateRequest) (map[string]string, bool)
+	ValidatePostRequest(req *model.CustomDataPostRequest) (map[string]string, bool)
+	ValidatePutRequest(req *model.CustomDataPutRequest) (map[string]string, bool)
+	ValidateFields(fields map[string]string, template model.Template) (map[string]string, bool)
+	ValidateDeleteRequest(req *model.DataDeleteRequest) (map[string]string, bool)
+	ValidatePageRequest(req *model.PageRequest) (map[string]string, bool)
+}
+
+// CustomDataHandler struct implements the gRPC handler for item template and custom data operations.
+type CustomDataHandler struct {
+	customDataService CustomDataService
+	pb.UnimplementedCustomDataServiceServer
+	validator Validator
+}
+
+// New creates a new instance of CustomDataHandler.
+func New(customDataService CustomDataService, validator Validator) *CustomDataHandler {
+	return &CustomDataHandler{
+		customDataService: customDataService,
+		validator:         validator,
+	}
+}
+
+// PostSaveTemplate handles the gRPC request to save an item template.
+func (h *CustomDataHandler) PostSaveTemplate(ctx context.Context, in *pb.PostTemplateRequest) (*pb.PostTemplateResponse, error) {
+	req := model.TemplateRequest{
+		Name:   in.Name,
+		Fields: fromPBTemplateFields(in.Fields),
+	}
+
+	report, ok := h.validator.ValidateTemplateRequest(&req)
+	if !ok {
+		logrus.Info("Unable to save template: invalid template request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid template post request", report)
+	}
+
+	template, err := h.customDataService.SaveTemplate(ctx, req)
+	if errors.Is(err, cerrors.ErrTemplateExists) {
+		logrus.Infof("Unable to save template: template %s already exists", req.Name)
+		return nil, status.Error(codes.AlreadyExists, "template with this name already exists")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to save template")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PostTemplateResponse{Template: toPBTemplate(template)}, nil
+}
+
+// GetLoadAllTemplates handles the gRPC request to load all item templates of the user.
+func (h *CustomDataHandler) GetLoadAllTemplates(ctx context.Context, _ *pb.GetAllTemplatesRequest) (*pb.GetAllTemplatesResponse, error) {
+	templates, err := h.customDataService.LoadTemplates(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("Error while loading templates: ")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	pbTemplates := make([]*pb.Template, 0, len(templates))
+	for _, template := range templates {
+		pbTemplates = append(pbTemplates, toPBTemplate(template))
+	}
+
+	return &pb.GetAllTemplatesResponse{Templates: pbTemplates}, nil
+}
+
+// PutUpdateTemplate handles the gRPC request to update an existing item template.
+func (h *CustomDataHandler) PutUpdateTemplate(ctx context.Context, in *pb.PutTemplateRequest) (*pb.PutTemplateResponse, error) {
+	req := model.TemplateRequest{
+		ID:     in.Id,
+		Name:   in.Name,
+		Fields: fromPBTemplateFields(in.Fields),
+	}
+
+	report, ok := h.validator.ValidateTemplateRequest(&req)
+	if req.ID == "" {
+		// Saved templates always have an ID, the request of a new template has none
+		report, ok = map[string]string{"ID": "is required"}, false
+	}
+	if !ok {
+		logrus.Info("Unable to update template: invalid template request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid template put request", report)
+	}
+
+	template, err := h.customDataService.UpdateTemplate(ctx, req)
+	if errors.Is(err, cerrors.ErrTemplateNotFound) {
+		logrus.Infof("Unable to update template: template %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "template not found")
+	}
+
+	if errors.Is(err, cerrors.ErrTemplateExists) {
+		logrus.Infof("Unable to update template: template %s already exists", req.Name)
+		return nil, status.Error(codes.AlreadyExists, "template with this name already exists")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to update template")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PutTemplateResponse{Template: toPBTemplate(template)}, nil
+}
+
+// DeleteTemplate handles the gRPC request to delete an item template by ID.
+func (h *CustomDataHandler) DeleteTemplate(ctx context.Context, in *pb.DeleteTemplateRequest) (*pb.DeleteTemplateResponse, error) {
+	req := model.DataDeleteRequest{ID: in.Id}
+
+	report, ok := h.validator.ValidateDeleteRequest(&req)
+	if !ok {
+		logrus.Info("Unable to delete template: invalid template request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid template delete request", report)
+	}
+
+	err := h.customDataService.DeleteTemplate(ctx, req.ID)
+	if errors.Is(err, cerrors.ErrTemplateNotFound) {
+		logrus.Infof("Unable to delete template: template %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "template not found")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to delete template")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.DeleteTemplateResponse{}, nil
+}
+
+// PostSaveCustomData handles the gRPC request to save a custom data item. The values of its fields
+// are checked against the template unless they were encrypted on the client side.
+func (h *CustomDataHandler) PostSaveCustomData(ctx context.Context, in *pb.PostCustomDataRequest) (*pb.PostCustomDataResponse, error) {
+	req := model.CustomDataPostRequest{
+		ID:         in.Id,
+		TemplateID: in.TemplateId,
+		Fields:     fromPBCustomFields(in.Fields),
+		MetaData:   in.Metadata,
+		CryptData:  in.CryptData,
+	}
+
+	report, ok := h.validator.ValidatePostRequest(&req)
+	if ok {
+		report, ok = h.validateFields(ctx, req.TemplateID, req.Fields, req.CryptData)
+	}
+	if !ok {
+		logrus.Info("Unable to save custom_data: invalid custom_data request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid custom_data post request", report)
+	}
+
+	custom, err := h.customDataService.SaveCustomData(ctx, req)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to save custom_data: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataExists) {
+		logrus.Info("Unable to save custom_data: id is taken")
+		return nil, status.Error(codes.AlreadyExists, "data with this id already exists")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to save custom_data")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PostCustomDataResponse{
+		Id:         custom.ID,
+		TemplateId: custom.TemplateID,
+		Metadata:   custom.MetaData,
+		CreatedAt:  custom.CreatedAt.Format(time.RFC3339),
+		Revision:   custom.Revision,
+	}, nil
+}
+
+// GetLoadAllCustomDataInfo handles the gRPC call for loading a page of custom data information.
+func (h *CustomDataHandler) GetLoadAllCustomDataInfo(ctx context.Context, in *pb.GetAllCustomInfoRequest) (*pb.GetAllCustomInfoResponse, error) {
+	req := model.PageRequest{
+		PageSize:   int(in.PageSize),
+		PageToken:  in.PageToken,
+		SortBy:     in.SortBy,
+		Descending: in.Descending,
+	}
+
+	report, ok := h.validator.ValidatePageRequest(&req)
+	if !ok {
+		logrus.Info("Unable to load custom data info: invalid page request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid page request", report)
+	}
+
+	customPage, err := h.customDataService.LoadAllCustomInfo(ctx, req)
+	if errors.Is(err, cerrors.ErrInvalidPageToken) {
+		logrus.Info("Unable to load custom data info: invalid page token")
+		return nil, lib.ProcessValidationError("invalid page request", map[string]string{"PageToken": "is invalid or issued for another sort order"})
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Error while loading custom data: ")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	customInfos := make([]*pb.CustomInfo, 0, len(customPage.Infos))
+	for _, v := range customPage.Infos {
+		customInfos = append(customInfos, &pb.CustomInfo{
+			Id:        v.ID,
+			DataType:  v.DataType,
+			Metadata:  v.MetaData,
+			CreatedAt: v.CreatedAt.Format(time.RFC3339),
+			Revision:  v.Revision,
+			UpdatedAt: v.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return &pb.GetAllCustomInfoResponse{Custom: customInfos, NextPageToken: customPage.NextPageToken}, nil
+}
+
+// GetLoadCustomData handles the gRPC request to load a custom data item by ID.
+func (h *CustomDataHandler) GetLoadCustomData(ctx context.Context, in *pb.GetCustomDataRequest) (*pb.GetCustomDataResponse, error) {
+	dataID := in.Id
+
+	customData, err := h.customDataService.LoadCustomData(ctx, dataID)
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to load custom_data: custom_data %s not found", dataID)
+		return nil, status.Error(codes.NotFound, "custom data not found")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Error while loading custom data: ")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	custom := &pb.CustomData{
+		Id:         customData.ID,
+		OwnerId:    customData.OwnerID,
+		TemplateId: customData.TemplateID,
+		Fields:     toPBCustomFields(customData.Fields),
+		Metadata:   customData.MetaData,
+		CreatedAt:  customData.CreatedAt.Format(time.RFC3339Nano),
+		Revision:   customData.Revision,
+		CryptData:  customData.CryptData,
+	}
+	return &pb.GetCustomDataResponse{CustomData: custom}, nil
+}
+
+// PutUpdateCustomData handles the gRPC request to update an existing custom data item. The values of its fields
+// are checked against the current fields of the template unless they were encrypted on the client side.
+func (h *CustomDataHandler) PutUpdateCustomData(ctx context.Context, in *pb.PutCustomDataRequest) (*pb.PutCustomDataResponse, error) {
+	req := model.CustomDataPutRequest{
+		ID:         in.Id,
+		TemplateID: in.TemplateId,
+		Fields:     fromPBCustomFields(in.Fields),
+		MetaData:   in.Metadata,
+		CryptData:  in.CryptData,
+		Revision:   in.Revision,
+	}
+
+	report, ok := h.validator.ValidatePutRequest(&req)
+	if ok {
+		report, ok = h.validateFields(ctx, req.TemplateID, req.Fields, req.CryptData)
+	}
+	if !ok {
+		logrus.Info("Unable to update custom_data: invalid custom_data request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid custom_data put request", report)
+	}
+
+	custom, err := h.customDataService.UpdateCustomData(ctx, req)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to update custom_data: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to update custom_data: custom_data %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "custom data not found")
+	}
+
+	if errors.Is(err, cerrors.ErrRevisionConflict) {
+		logrus.Infof("Unable to update custom_data: custom_data %s has a newer revision", req.ID)
+		return nil, status.Error(codes.Aborted, "custom data was changed by another client")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to update custom_data")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PutCustomDataResponse{
+		Id:         custom.ID,
+		TemplateId: custom.TemplateID,
+		Metadata:   custom.MetaData,
+		CreatedAt:  custom.CreatedAt.Format(time.RFC3339),
+		Revision:   custom.Revision,
+	}, nil
+}
+
+// DeleteCustomData handles the gRPC request to delete a custom data item by ID.
+func (h *CustomDataHandler) DeleteCustomData(ctx context.Context, in *pb.DeleteCustomDataRequest) (*pb.DeleteCustomDataResponse, error) {
+	req := model.DataDeleteRequest{ID: in.Id}
+
+	report, ok := h.validator.ValidateDeleteRequest(&req)
+	if !ok {
+		logrus.Info("Unable to delete custom_data: invalid custom_data request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid custom_data delete request", report)
+	}
+
+	err := h.customDataService.DeleteCustomData(ctx, req.ID)
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to delete custom_data: custom_data %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "custom data not found")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to delete custom_data")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.DeleteCustomDataResponse{}, nil
+}
+
+// validateFields loads the template of a custom data item and checks the values of its fields against it.
+// Values encrypted on the client side are opaque to the server, only the template is checked to exist.
+func (h *CustomDataHandler) validateFields(ctx context.Context, templateID string, fields map[string]string, cryptData []byte) (map[string]string, bool) {
+	template, err := h.customDataService.LoadTemplate(ctx, templateID)
+	if errors.Is(err, cerrors.ErrTemplateNotFound) {
+		return map[string]string{"TemplateID": "template not found"}, false
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Error while loading template: ")
+		return map[string]string{"TemplateID": "template can't be loaded"}, false
+	}
+
+	if len(cryptData) > 0 {
+		return nil, true
+	}
+
+	return h.validator.ValidateFields(fields, template)
+}
+
+// toPBTemplate converts a template into its gRPC message.
+func toPBTemplate(template model.Template) *pb.Template {
+	fields := make([]*pb.TemplateField, 0, len(template.Fields))
+	for _, f := range template.Fields {
+		fields = append(fields, &pb.TemplateField{
+			Name:     f.Name,
+			Type:     f.Type,
+			Required: f.Required,
+			Secret:   f.Secret,
+		})
+	}
+
+	return &pb.Template{
+		Id:        template.ID,
+		Name:      template.Name,
+		Fields:    fields,
+		CreatedAt: template.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: template.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// fromPBTemplateFields converts the fields of a template from their gRPC messages.
+func fromPBTemplateFields(in []*pb.TemplateField) []model.TemplateField {
+	fields := make([]model.TemplateField, 0, len(in))
+	for _, f := range in {
+		fields = append(fields, model.TemplateField{
+			Name:     f.Name,
+			Type:     f.Type,
+			Required: f.Required,
+			Secret:   f.Secret,
+		})
+	}
+
+	return fields
+}
+
+// fromPBCustomFields converts the values of the fields of a custom data item from their gRPC messages,
+// the last value of a repeated field wins.
+func fromPBCustomFields(in []*pb.CustomField) map[string]string {
+	fields := make(map[string]string, len(in))
+	for _, f := range in {
+		fields[f.Name] = f.Value
+	}
+
+	return fields
+}
+
+// toPBCustomFields converts the values of the fields of a custom data item into their gRPC messages sorted by name.
+func toPBCustomFields(fields map[string]string) []*pb.CustomField {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]*pb.CustomField, 0, len(fields))
+	for _, name := range names {
+		out = append(out, &pb.CustomField{Name: name, Value: fields[name]})
+	}
+
+	return out
+}
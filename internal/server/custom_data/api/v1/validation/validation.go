@@ -0,0 +1,184 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/pagination"
+)
+
+const (
+	dateLayout   = "02-01-2006" // Define the layout of the date fields, the same as of the card expiry date
+	maxFields    = 50           // Largest number of fields of a template
+	maxFieldName = 100          // Longest name of a field in characters
+)
+
+// fieldTypes holds the types of the fields of templates.
+var fieldTypes = map[string]struct{}{
+	model.FieldTypeText:   {},
+	model.FieldTypeNumber: {},
+	model.FieldTypeURL:    {},
+	model.FieldTypeEmail:  {},
+	model.FieldTypeDate:   {},
+}
+
+// Validator struct holds a validator instance for validating requests.
+type Validator struct {
+	validator *validator.Validate // Instance of the validator for performing validations
+}
+
+// New creates a new Validator instance.
+func New(validator *validator.Validate) (*Validator, error) {
+	v := &Validator{validator: validator}
+
+	return v, nil
+}
+
+// ValidateTemplateRequest validates the incoming request for saving or updating an item template.
+// The template must have a name and from 1 to 50 fields with unique names and known types.
+func (v *Validator) ValidateTemplateRequest(req *model.TemplateRequest) (map[string]string, bool) {
+	report, _ := v.validateStruct(req)
+	if report == nil {
+		report = make(map[string]string)
+	}
+
+	if len(req.Fields) == 0 || len(req.Fields) > maxFields {
+		report["Fields"] = fmt.Sprintf("must hold from 1 to %d fields", maxFields)
+	}
+
+	names := make(map[string]struct{}, len(req.Fields))
+	for i, field := range req.Fields {
+		name := strings.TrimSpace(field.Name)
+		switch {
+		case name == "":
+			report[fmt.Sprintf("Fields[%d].Name", i)] = "is required"
+		case name != field.Name || len([]rune(name)) > maxFieldName:
+			report[fmt.Sprintf("Fields[%d].Name", i)] = fmt.Sprintf("must be at most %d characters without surrounding spaces", maxFieldName)
+		default:
+			if _, ok := names[strings.ToLower(name)]; ok {
+				report[fmt.Sprintf("Fields[%d].Name", i)] = "must be unique"
+			}
+			names[strings.ToLower(name)] = struct{}{}
+		}
+
+		if _, ok := fieldTypes[field.Type]; !ok {
+			report[fmt.Sprintf("Fields[%d].Type", i)] = "must be text, number, url, email or date"
+		}
+	}
+
+	if len(report) != 0 {
+		return report, false
+	}
+	return nil, true
+}
+
+// ValidatePostRequest validates the incoming request for posting a custom data item,
+// the values of its fields are checked against the template by ValidateFields.
+func (v *Validator) ValidatePostRequest(req *model.CustomDataPostRequest) (map[string]string, bool) {
+	return v.validateStruct(req)
+}
+
+// ValidatePutRequest validates the incoming request for updating a custom data item,
+// the values of its fields are checked against the template by ValidateFields.
+func (v *Validator) ValidatePutRequest(req *model.CustomDataPutRequest) (map[string]string, bool) {
+	return v.validateStruct(req)
+}
+
+// ValidateFields checks the values of the fields of a custom data item against its template.
+// Required fields must be set, values must match the type of their field and the item can't have fields
+// the template doesn't define. Violations are reported by the path of the value, like Fields.Host.
+func (v *Validator) ValidateFields(fields map[string]string, template model.Template) (map[string]string, bool) {
+	report := make(map[string]string)
+	for name := range fields {
+		if _, ok := template.Field(name); !ok {
+			report["Fields."+name] = fmt.Sprintf("is not a field of template %s", template.Name)
+		}
+	}
+
+	for _, field := range template.Fields {
+		value := fields[field.Name]
+		if value == "" {
+			if field.Required {
+				report["Fields."+field.Name] = "is required"
+			}
+			continue
+		}
+
+		if msg, ok := checkValue(field.Type, value); !ok {
+			report["Fields."+field.Name] = msg
+		}
+	}
+
+	if len(report) != 0 {
+		return report, false
+	}
+	return nil, true
+}
+
+// ValidateDeleteRequest validates the incoming request for deleting a custom data item or a template.
+func (v *Validator) ValidateDeleteRequest(req *model.DataDeleteRequest) (map[string]string, bool) {
+	return v.validateStruct(req)
+}
+
+// ValidatePageRequest validates the incoming PageRequest of a data listing.
+func (v *Validator) ValidatePageRequest(req *model.PageRequest) (map[string]string, bool) {
+	return pagination.Validate(v.validator, req)
+}
+
+// validateStruct validates the request with the tags of its struct and returns a map of validation errors if any exist.
+func (v *Validator) validateStruct(req any) (map[string]string, bool) {
+	err := v.validator.Struct(req)
+	report := make(map[string]string)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				switch validationErr.Tag() {
+				case "required":
+					report[validationErr.Field()] = "is required"
+				case "uuid":
+					report[validationErr.Field()] = "must be a UUID"
+				case "max":
+					report[validationErr.Field()] = "must be at most " + validationErr.Param() + " characters"
+				}
+			}
+			return report, false
+		}
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+	return nil, true
+}
+
+// checkValue checks that the value matches the type of its field and returns the violation if it doesn't.
+func checkValue(fieldType, value string) (string, bool) {
+	switch fieldType {
+	case model.FieldTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "must be a number", false
+		}
+	case model.FieldTypeURL:
+		u, err := url.ParseRequestURI(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be an absolute URL", false
+		}
+	case model.FieldTypeEmail:
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Address != value {
+			return "must be an email address", false
+		}
+	case model.FieldTypeDate:
+		if _, err := time.Parse(dateLayout, value); err != nil {
+			return "must be a date as DD-MM-YYYY", false
+		}
+	}
+
+	return "", true
+}
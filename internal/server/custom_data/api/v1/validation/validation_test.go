@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+type ValidationTestSuite struct {
+	suite.Suite
+	validator *Validator
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(ValidationTestSuite))
+}
+
+func (s *ValidationTestSuite) SetupTest() {
+	v, err := New(validator.New())
+	require.NoError(s.T(), err)
+	s.validator = v
+}
+
+func (s *ValidationTestSuite) Test_ValidateTemplateRequest() {
+	report, ok := s.validator.ValidateTemplateRequest(&model.TemplateRequest{
+		Name: "Wi-Fi",
+		Fields: []model.TemplateField{
+			{Name: "SSID", Type: model.FieldTypeText, Required: true},
+			{Name: "Password", Type: model.FieldTypeText, Secret: true},
+		},
+	})
+	assert.True(s.T(), ok)
+	assert.Nil(s.T(), report)
+
+	report, ok = s.validator.ValidateTemplateRequest(&model.TemplateRequest{
+		Fields: []model.TemplateField{
+			{Name: "Host", Type: model.FieldTypeURL},
+			{Name: "host", Type: model.FieldTypeText},
+			{Name: " ", Type: model.FieldTypeText},
+			{Name: "Key ", Type: "blob"},
+		},
+	})
+	assert.False(s.T(), ok)
+	assert.Equal(s.T(), map[string]string{
+		"Name":           "is required",
+		"Fields[1].Name": "must be unique",
+		"Fields[2].Name": "is required",
+		"Fields[3].Name": "must be at most 100 characters without surrounding spaces",
+		"Fields[3].Type": "must be text, number, url, email or date",
+	}, report)
+
+	report, ok = s.validator.ValidateTemplateRequest(&model.TemplateRequest{Name: "Empty"})
+	assert.False(s.T(), ok)
+	assert.Equal(s.T(), map[string]string{"Fields": "must hold from 1 to 50 fields"}, report)
+}
+
+func (s *ValidationTestSuite) Test_ValidateFields() {
+	template := model.Template{
+		Name: "License",
+		Fields: []model.TemplateField{
+			{Name: "Key", Type: model.FieldTypeText, Required: true, Secret: true},
+			{Name: "Seats", Type: model.FieldTypeNumber},
+			{Name: "Site", Type: model.FieldTypeURL},
+			{Name: "Email", Type: model.FieldTypeEmail},
+			{Name: "Expires", Type: model.FieldTypeDate},
+		},
+	}
+
+	report, ok := s.validator.ValidateFields(map[string]string{
+		"Key":     "ABCD-EFGH",
+		"Seats":   "2.5",
+		"Site":    "https://example.com/buy",
+		"Email":   "admin@example.com",
+		"Expires": "31-12-2027",
+	}, template)
+	assert.True(s.T(), ok)
+	assert.Nil(s.T(), report)
+
+	// Optional fields may be left empty
+	_, ok = s.validator.ValidateFields(map[string]string{"Key": "ABCD-EFGH", "Site": ""}, template)
+	assert.True(s.T(), ok)
+
+	report, ok = s.validator.ValidateFields(map[string]string{
+		"Seats":   "two",
+		"Site":    "example.com",
+		"Email":   "Admin <admin@example.com>",
+		"Expires": "2027-12-31",
+		"Owner":   "Ivan",
+	}, template)
+	assert.False(s.T(), ok)
+	assert.Equal(s.T(), map[string]string{
+		"Fields.Key":     "is required",
+		"Fields.Seats":   "must be a number",
+		"Fields.Site":    "must be an absolute URL",
+		"Fields.Email":   "must be an email address",
+		"Fields.Expires": "must be a date as DD-MM-YYYY",
+		"Fields.Owner":   "is not a field of template License",
+	}, report)
+}
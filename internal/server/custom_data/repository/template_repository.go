@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/storage/postgresql"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// PostgresTemplateRepository defines a repository that interacts with PostgreSQL to manage item templates.
+type PostgresTemplateRepository struct {
+	postgresPool *postgresql.PostgresPool // Connection pool to PostgreSQL database
+}
+
+// New creates a new PostgresTemplateRepository instance with the provided PostgreSQL connection pool.
+func New(postgresPool *postgresql.PostgresPool) *PostgresTemplateRepository {
+	return &PostgresTemplateRepository{postgresPool: postgresPool}
+}
+
+// Insert saves a new template into the database and returns the saved template.
+// It returns cerrors.ErrTemplateExists if the user already has a template with the same name.
+func (r *PostgresTemplateRepository) Insert(ctx context.Context, template model.Template) (model.Template, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			insert into privatekeeper.item_template
+			    (id, owner_id, name, fields, created_at, updated_at)
+			values
+				($1, $2, $3, $4, now(), now())
+			returning id, owner_id, name, fields, created_at, updated_at;
+			`,
+		template.ID,
+		template.OwnerID,
+		template.Name,
+		template.Fields)
+	if err != nil {
+		return model.Template{}, fmt.Errorf("make query: %w", err)
+	}
+
+	savedTemplate, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.Template])
+	var e *pgconn.PgError
+	if errors.As(err, &e) && e.Code == pgerrcode.UniqueViolation {
+		return model.Template{}, fmt.Errorf("collect row: %w", cerrors.ErrTemplateExists)
+	}
+
+	if err != nil {
+		return model.Template{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return savedTemplate, nil
+}
+
+// SelectAll retrieves all templates of a user ordered by name.
+func (r *PostgresTemplateRepository) SelectAll(ctx context.Context, userID string) ([]model.Template, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    id, owner_id, name, fields, created_at, updated_at
+			from privatekeeper.item_template
+			where owner_id = $1
+			order by name, id;
+			`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("make query: %w", err)
+	}
+
+	templates, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.Template])
+	if err != nil {
+		return nil, fmt.Errorf("collect row: %w", err)
+	}
+
+	return templates, nil
+}
+
+// SelectByID retrieves a specific template by its ID for a user from the database.
+func (r *PostgresTemplateRepository) SelectByID(ctx context.Context, userID, templateID string) (model.Template, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    id, owner_id, name, fields, created_at, updated_at
+			from privatekeeper.item_template
+			where owner_id = $1 and id = $2;
+			`,
+		userID, templateID)
+	if err != nil {
+		return model.Template{}, fmt.Errorf("make query: %w", err)
+	}
+
+	template, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.Template])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Template{}, fmt.Errorf("collect row: %w", cerrors.ErrTemplateNotFound)
+	}
+
+	if err != nil {
+		return model.Template{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return template, nil
+}
+
+// Update replaces the name and the fields of an existing template and returns the updated template.
+func (r *PostgresTemplateRepository) Update(ctx context.Context, template model.Template) (model.Template, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			update privatekeeper.item_template
+			set name = $3, fields = $4, updated_at = now()
+			where owner_id = $1 and id = $2
+			returning id, owner_id, name, fields, created_at, updated_at;
+			`,
+		template.OwnerID,
+		template.ID,
+		template.Name,
+		template.Fields)
+	if err != nil {
+		return model.Template{}, fmt.Errorf("make query: %w", err)
+	}
+
+	updatedTemplate, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.Template])
+	var e *pgconn.PgError
+	if errors.As(err, &e) && e.Code == pgerrcode.UniqueViolation {
+		return model.Template{}, fmt.Errorf("collect row: %w", cerrors.ErrTemplateExists)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Template{}, fmt.Errorf("collect row: %w", cerrors.ErrTemplateNotFound)
+	}
+
+	if err != nil {
+		return model.Template{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return updatedTemplate, nil
+}
+
+// Delete removes a specific template by its ID for a user from the database.
+// The items of the template keep their values, they are encrypted and can't be checked here.
+func (r *PostgresTemplateRepository) Delete(ctx context.Context, userID, templateID string) error {
+	tag, err := r.postgresPool.DB.Exec(ctx,
+		`
+			delete from privatekeeper.item_template
+			where owner_id = $1 and id = $2;
+			`,
+		userID, templateID)
+	if err != nil {
+		return fmt.Errorf("make query: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete template: %w", cerrors.ErrTemplateNotFound)
+	}
+
+	return nil
+}
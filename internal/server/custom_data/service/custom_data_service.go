@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/pagination"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/jwtmanager"
+)
+
+const customData = "custom_data" // Define a constant for the data type
+
+// DataRepository interface defines methods for data persistence
+type DataRepository interface {
+	Insert(ctx context.Context, data model.Data) (model.Data, error)
+	SelectPage(ctx context.Context, userID, dataType string, page model.PageRequest, after *model.PageCursor, limit int) ([]model.Data, error)
+	SelectByID(ctx context.Context, userID, dataType, dataID string) (model.Data, error)
+	Update(ctx context.Context, data model.Data) (model.Data, error)
+	Delete(ctx context.Context, userID, dataType, dataID string) error
+}
+
+// TemplateRepository interface defines methods for item template persistence
+type TemplateRepository interface {
+	Insert(ctx context.Context, template model.Template) (model.Template, error)
+	SelectAll(ctx context.Context, userID string) ([]model.Template, error)
+	SelectByID(ctx context.Context, userID, templateID string) (model.Template, error)
+	Update(ctx context.Context, template model.Template) (model.Template, error)
+	Delete(ctx context.Context, userID, templateID string) error
+}
+
+// CryptService interface defines methods for encryption and decryption
+type CryptService interface {
+	Encrypt(key, data []byte) ([]byte, error)
+	Decrypt(key, data []byte) ([]byte, error)
+	GenerateKey() ([]byte, error)
+}
+
+// CustomDataService provides methods to handle item templates and the custom data items built from them
+type CustomDataService struct {
+	repository DataRepository         // Repository for data operations
+	templates  TemplateRepository     // Repository for template operations
+	crypt      CryptService           // Service for encryption and decryption
+	jwtManager *jwtmanager.JWTManager // JWT management
+	dataType   string                 // Type of data this service handles
+}
+
+// New initializes a new CustomDataService instance
+func New(repository DataRepository, templates TemplateRepository, crypt CryptService, jwtManager *jwtmanager.JWTManager) *CustomDataService {
+	return &CustomDataService{
+		repository: repository,
+		templates:  templates,
+		crypt:      crypt,
+		jwtManager: jwtManager,
+		dataType:   customData,
+	}
+}
+
+// SaveTemplate saves a new item template of the user
+func (s *CustomDataService) SaveTemplate(ctx context.Context, req model.TemplateRequest) (model.Template, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.Template{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return model.Template{}, fmt.Errorf("new uuid: %w", err)
+	}
+
+	savedTemplate, err := s.templates.Insert(ctx, model.Template{
+		ID:      id.String(),
+		OwnerID: userID,
+		Name:    req.Name,
+		Fields:  req.Fields,
+	})
+	if err != nil {
+		return model.Template{}, fmt.Errorf("insert template: %w", err)
+	}
+
+	return savedTemplate, nil
+}
+
+// LoadTemplates retrieves all item templates of the user ordered by name
+func (s *CustomDataService) LoadTemplates(ctx context.Context) ([]model.Template, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to get userID from context")
+	}
+
+	templates, err := s.templates.SelectAll(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select all templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// LoadTemplate retrieves an item template of the user by its ID
+func (s *CustomDataService) LoadTemplate(ctx context.Context, templateID string) (model.Template, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.Template{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	template, err := s.templates.SelectByID(ctx, userID, templateID)
+	if err != nil {
+		return model.Template{}, fmt.Errorf("select template: %w", err)
+	}
+
+	return template, nil
+}
+
+// UpdateTemplate replaces the name and the fields of an item template. The items saved before
+// are checked against the new fields the next time they are updated.
+func (s *CustomDataService) UpdateTemplate(ctx context.Context, req model.TemplateRequest) (model.Template, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.Template{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	updatedTemplate, err := s.templates.Update(ctx, model.Template{
+		ID:      req.ID,
+		OwnerID: userID,
+		Name:    req.Name,
+		Fields:  req.Fields,
+	})
+	if err != nil {
+		return model.Template{}, fmt.Errorf("update template: %w", err)
+	}
+
+	return updatedTemplate, nil
+}
+
+// DeleteTemplate removes an item template by its ID, the items of the template keep their values
+func (s *CustomDataService) DeleteTemplate(ctx context.Context, templateID string) error {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get userID from context")
+	}
+
+	err := s.templates.Delete(ctx, userID, templateID)
+	if err != nil {
+		return fmt.Errorf("delete template: %w", err)
+	}
+
+	return nil
+}
+
+// SaveCustomData encrypts the values of the fields of a custom data item and saves it to the repository
+func (s *CustomDataService) SaveCustomData(ctx context.Context, req model.CustomDataPostRequest) (model.CustomData, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.CustomData{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return model.CustomData{}, fmt.Errorf("failed to get userKey from context")
+	}
+
+	id, err := lib.NewDataID(req.ID)
+	if err != nil {
+		return model.CustomData{}, fmt.Errorf("new data id: %w", err)
+	}
+
+	custom := model.CustomCryptData{
+		TemplateID: req.TemplateID,
+		Fields:     req.Fields,
+	}
+
+	cryptData, clientEncrypted, err := s.encryptPayload(userKey, req.CryptData, custom)
+	if err != nil {
+		return model.CustomData{}, fmt.Errorf("encrypt payload: %w", err)
+	}
+
+	savedCustomData, err := s.repository.Insert(ctx, model.Data{
+		ID:              id,
+		OwnerID:         userID,
+		Type:            s.dataType,
+		Data:            cryptData,
+		MetaData:        req.MetaData,
+		ClientEncrypted: clientEncrypted,
+	})
+	if err != nil {
+		return model.CustomData{}, fmt.Errorf("insert custom data: %w", err)
+	}
+
+	return model.CustomData{
+		ID:         savedCustomData.ID,
+		OwnerID:    savedCustomData.OwnerID,
+		TemplateID: req.TemplateID,
+		Fields:     req.Fields,
+		MetaData:   savedCustomData.MetaData,
+		CreatedAt:  savedCustomData.CreatedAt,
+		Revision:   savedCustomData.Revision,
+		CryptData:  req.CryptData,
+	}, nil
+}
+
+// LoadAllCustomInfo retrieves a page of custom data information for the user, sorted as requested.
+func (s *CustomDataService) LoadAllCustomInfo(ctx context.Context, page model.PageRequest) (model.DataInfoPage, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.DataInfoPage{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	page = pagination.Normalize(page)
+	after, err := pagination.DecodeToken(page)
+	if err != nil {
+		return model.DataInfoPage{}, err
+	}
+
+	encryptedCustomData, err := s.repository.SelectPage(ctx, userID, s.dataType, page, after, page.PageSize+1)
+	if err != nil {
+		return model.DataInfoPage{}, fmt.Errorf("select page custom_data: %w", err)
+	}
+	encryptedCustomData, nextPageToken := pagination.Page(page, encryptedCustomData)
+
+	customDataInfo := make([]model.DataInfo, 0, len(encryptedCustomData))
+	for _, encryptedCustom := range encryptedCustomData {
+		customDataInfo = append(customDataInfo, model.DataInfo{
+			ID:        encryptedCustom.ID,
+			DataType:  s.dataType,
+			MetaData:  encryptedCustom.MetaData,
+			CreatedAt: encryptedCustom.CreatedAt,
+			Revision:  encryptedCustom.Revision,
+			UpdatedAt: encryptedCustom.UpdatedAt,
+		})
+	}
+	return model.DataInfoPage{Infos: customDataInfo, NextPageToken: nextPageToken}, nil
+}
+
+// LoadCustomData retrieves and decrypts a custom data item by its ID
+func (s *CustomDataService) LoadCustomData(ctx context.Context, dataID string) (model.CustomData, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.CustomData{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return model.CustomData{}, fmt.Errorf("failed to get userKey from context")
+	}
+
+	encryptedCustomData, err := s.repository.SelectByID(ctx, userID, s.dataType, dataID)
+	if err != nil {
+		return model.CustomData{}, fmt.Errorf("select custom_data: %w", err)
+	}
+	if encryptedCustomData.ClientEncrypted {
+		return model.CustomData{
+			ID:        encryptedCustomData.ID,
+			OwnerID:   encryptedCustomData.OwnerID,
+			MetaData:  encryptedCustomData.MetaData,
+			CreatedAt: encryptedCustomData.CreatedAt,
+			Revision:  encryptedCustomData.Revision,
+			CryptData: encryptedCustomData.Data,
+		}, nil
+	}
+
+	decryptedData, err := s.crypt.Decrypt(userKey, encryptedCustomData.Data)
+	if err != nil {
+		return model.CustomData{}, fmt.Errorf("decrypt custom data: %w", err)
+	}
+
+	var decryptedCustomData model.CustomCryptData
+	err = json.Unmarshal(decryptedData, &decryptedCustomData)
+	if err != nil {
+		return model.CustomData{}, fmt.Errorf("unmarshal custom data: %w", err)
+	}
+
+	return model.CustomData{
+		ID:         encryptedCustomData.ID,
+		OwnerID:    encryptedCustomData.OwnerID,
+		TemplateID: decryptedCustomData.TemplateID,
+		Fields:     decryptedCustomData.Fields,
+		MetaData:   encryptedCustomData.MetaData,
+		CreatedAt:  encryptedCustomData.CreatedAt,
+		Revision:   encryptedCustomData.Revision,
+	}, nil
+}
+
+// UpdateCustomData re-encrypts and replaces an existing custom data item
+func (s *CustomDataService) UpdateCustomData(ctx context.Context, req model.CustomDataPutRequest) (model.CustomData, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.CustomData{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return model.CustomData{}, fmt.Errorf("failed to get userKey from context")
+	}
+
+	custom := model.CustomCryptData{
+		TemplateID: req.TemplateID,
+		Fields:     req.Fields,
+	}
+
+	cryptData, clientEncrypted, err := s.encryptPayload(userKey, req.CryptData, custom)
+	if err != nil {
+		return model.CustomData{}, fmt.Errorf("encrypt payload: %w", err)
+	}
+
+	updatedCustomData, err := s.repository.Update(ctx, model.Data{
+		ID:              req.ID,
+		OwnerID:         userID,
+		Type:            s.dataType,
+		Data:            cryptData,
+		MetaData:        req.MetaData,
+		ClientEncrypted: clientEncrypted,
+		Revision:        req.Revision,
+	})
+	if err != nil {
+		return model.CustomData{}, fmt.Errorf("update custom data: %w", err)
+	}
+
+	return model.CustomData{
+		ID:         updatedCustomData.ID,
+		OwnerID:    updatedCustomData.OwnerID,
+		TemplateID: req.TemplateID,
+		Fields:     req.Fields,
+		MetaData:   updatedCustomData.MetaData,
+		CreatedAt:  updatedCustomData.CreatedAt,
+		Revision:   updatedCustomData.Revision,
+		CryptData:  req.CryptData,
+	}, nil
+}
+
+// DeleteCustomData removes a custom data item by its ID
+func (s *CustomDataService) DeleteCustomData(ctx context.Context, dataID string) error {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get userID from context")
+	}
+
+	err := s.repository.Delete(ctx, userID, s.dataType, dataID)
+	if err != nil {
+		return fmt.Errorf("delete custom data: %w", err)
+	}
+
+	return nil
+}
+
+// encryptPayload returns the payload to be stored for the custom data item. A payload that was
+// already encrypted on the client side is stored as is, otherwise the data is encrypted with the user key.
+func (s *CustomDataService) encryptPayload(userKey, clientCryptData []byte, custom model.CustomCryptData) ([]byte, bool, error) {
+	if len(clientCryptData) > 0 {
+		return clientCryptData, true, nil
+	}
+
+	data, err := json.Marshal(custom)
+	if err != nil {
+		return nil, false, fmt.Errorf("marshal: %w", err)
+	}
+
+	cryptData, err := s.crypt.Encrypt(userKey, data)
+	if err != nil {
+		return nil, false, fmt.Errorf("encrypt data: %w", err)
+	}
+
+	return cryptData, false, nil
+}
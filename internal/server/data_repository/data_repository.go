@@ -10,6 +10,8 @@ import (
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"io"
+	"time"
 )
 
 // PostgresDataRepository defines a repository that interacts with PostgreSQL to manage data.
@@ -22,55 +24,160 @@ func New(postgresPool *postgresql.PostgresPool) *PostgresDataRepository {
 	return &PostgresDataRepository{postgresPool: postgresPool}
 }
 
-// SelectAll retrieves all data entries of a specific type for a user from the database.
-func (r *PostgresDataRepository) SelectAll(ctx context.Context, userID, dataType string) ([]model.Data, error) {
+// sortColumns holds the sort key expressions of data listings and the types their cursor values are cast to.
+var sortColumns = map[string]struct{ expr, cast string }{
+	"created_at": {expr: "created_at", cast: "timestamp"},
+	"metadata":   {expr: "coalesce(metadata, '')", cast: "text"},
+}
+
+// SelectPage retrieves up to limit data entries of a specific type for a user, ordered by the sort field
+// of the page request and the ID. When after is not nil, entries up to and including it are skipped.
+func (r *PostgresDataRepository) SelectPage(ctx context.Context, userID, dataType string, page model.PageRequest, after *model.PageCursor, limit int) ([]model.Data, error) {
+	column, ok := sortColumns[page.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field %q", page.SortBy)
+	}
+
+	direction, op := "asc", ">"
+	if page.Descending {
+		direction, op = "desc", "<"
+	}
+
+	args := []any{userID, dataType, limit}
+	cursorCond := ""
+	if after != nil {
+		cursorCond = fmt.Sprintf("and (%s, id) %s ($4::%s, $5)", column.expr, op, column.cast)
+		args = append(args, after.Value, after.ID)
+	}
+
 	rows, err := r.postgresPool.DB.Query(ctx,
-		`
+		fmt.Sprintf(`
 			select
-			    id, owner_id, type, data, metadata, created_at
+			    id, owner_id, type, data, metadata, created_at, client_encrypted, revision, updated_at
 			from privatekeeper.data
-			where owner_id = $1 and type = $2;
-			`,
-		userID, dataType)
+			where owner_id = $1 and type = $2 %[1]s
+			order by %[2]s %[3]s, id %[3]s
+			limit $3;
+			`, cursorCond, column.expr, direction),
+		args...)
 	if err != nil {
 		return nil, fmt.Errorf("make query: %w", err)
 	}
 
-	cards, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.Data])
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.Data])
 	if err != nil {
 		return nil, fmt.Errorf("collect row: %w", err)
 	}
 
-	return cards, nil
+	return entries, nil
 }
 
 // Insert saves a new data entry into the database and returns the saved entry.
 func (r *PostgresDataRepository) Insert(ctx context.Context, data model.Data) (model.Data, error) {
-	rows, err := r.postgresPool.DB.Query(ctx,
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err = lockUserKey(ctx, tx, data); err != nil {
+		return model.Data{}, err
+	}
+
+	rows, err := tx.Query(ctx,
 		`
 			insert into privatekeeper.data
-			    (id, owner_id, type, data, metadata, created_at) 
+			    (id, owner_id, type, data, metadata, created_at, client_encrypted)
 			values
-				($1, $2, $3, $4, $5, now())
-			returning id, owner_id, type, data, metadata, created_at;
+				($1, $2, $3, $4, $5, now(), $6)
+			returning id, owner_id, type, data, metadata, created_at, client_encrypted, revision, updated_at;
 			`,
 		data.ID,
 		data.OwnerID,
 		data.Type,
 		data.Data,
-		data.MetaData)
+		data.MetaData,
+		data.ClientEncrypted)
 	if err != nil {
 		return model.Data{}, fmt.Errorf("make query: %w", err)
 	}
 
 	savedData, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.Data])
-	var e *pgconn.PgError
-	if errors.As(err, &e) && e.Code == pgerrcode.UniqueViolation {
-		return model.Data{}, fmt.Errorf("collect row: %w", cerrors.ErrUserAlreadyExists)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("collect row: %w", insertError(err))
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return model.Data{}, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return savedData, nil
+}
+
+// InsertBatch saves new data entries in a single transaction and returns the saved entries in the same order.
+// The inserts are sent to the database as one batch, either all entries are saved or none of them.
+func (r *PostgresDataRepository) InsertBatch(ctx context.Context, data []model.Data) ([]model.Data, error) {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
 	}
+	defer tx.Rollback(ctx) //nolint:errcheck
 
+	savedData, err := insertBatch(ctx, tx, data)
 	if err != nil {
-		return model.Data{}, fmt.Errorf("collect row: %w", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return savedData, nil
+}
+
+// insertBatch sends the inserts of the data entries to the database as one batch within the transaction.
+func insertBatch(ctx context.Context, tx pgx.Tx, data []model.Data) ([]model.Data, error) {
+	if err := lockUserKey(ctx, tx, data...); err != nil {
+		return nil, err
+	}
+
+	batch := &pgx.Batch{}
+	for _, d := range data {
+		batch.Queue(
+			`
+			insert into privatekeeper.data
+			    (id, owner_id, type, data, metadata, created_at, client_encrypted)
+			values
+				($1, $2, $3, $4, $5, now(), $6)
+			returning id, owner_id, type, data, metadata, created_at, client_encrypted, revision, updated_at;
+			`,
+			d.ID,
+			d.OwnerID,
+			d.Type,
+			d.Data,
+			d.MetaData,
+			d.ClientEncrypted)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	savedData := make([]model.Data, 0, len(data))
+	for range data {
+		rows, err := results.Query()
+		if err != nil {
+			_ = results.Close()
+			return nil, fmt.Errorf("make query: %w", err)
+		}
+
+		saved, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.Data])
+		if err != nil {
+			_ = results.Close()
+			return nil, fmt.Errorf("collect row: %w", insertError(err))
+		}
+		savedData = append(savedData, saved)
+	}
+
+	if err := results.Close(); err != nil {
+		return nil, fmt.Errorf("close batch: %w", err)
 	}
 
 	return savedData, nil
@@ -81,7 +188,7 @@ func (r *PostgresDataRepository) SelectByID(ctx context.Context, userID, dataTyp
 	row, err := r.postgresPool.DB.Query(ctx,
 		`
 			select
-			    id, owner_id, type, data, metadata, created_at
+			    id, owner_id, type, data, metadata, created_at, client_encrypted, revision, updated_at
 			from privatekeeper.data
 			where owner_id = $1 and type = $2 and id = $3;
 			`,
@@ -91,9 +198,442 @@ func (r *PostgresDataRepository) SelectByID(ctx context.Context, userID, dataTyp
 	}
 
 	data, err := pgx.CollectOneRow(row, pgx.RowToStructByPos[model.Data])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Data{}, fmt.Errorf("collect row: %w", cerrors.ErrDataNotFound)
+	}
+
 	if err != nil {
 		return model.Data{}, fmt.Errorf("collect row: %w", err)
 	}
 
 	return data, nil
 }
+
+// querier is implemented by the connection pool and by transactions.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// insertError returns cerrors.ErrDataExists if the insert failed because the ID of the entry is taken,
+// which happens when a client chose the ID, otherwise the error itself.
+func insertError(err error) error {
+	var e *pgconn.PgError
+	if errors.As(err, &e) && e.Code == pgerrcode.UniqueViolation {
+		return cerrors.ErrDataExists
+	}
+	return err
+}
+
+// lockUserKey locks the key of the owner of the entries with postgresql.LockUserKey, so data encrypted
+// with a key that is being replaced by a rotation is not written. Entries encrypted on the client side
+// don't depend on the user key and are written without the check.
+func lockUserKey(ctx context.Context, tx pgx.Tx, data ...model.Data) error {
+	for _, d := range data {
+		if !d.ClientEncrypted {
+			return postgresql.LockUserKey(ctx, tx, d.OwnerID)
+		}
+	}
+
+	return nil
+}
+
+// Update replaces the encrypted payload and metadata of an existing data entry and returns the updated entry.
+// The revision of the entry is incremented. A non zero data.Revision is the revision the client has seen,
+// the update is rejected with cerrors.ErrRevisionConflict if the entry has been changed since.
+func (r *PostgresDataRepository) Update(ctx context.Context, data model.Data) (model.Data, error) {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	updatedData, err := r.update(ctx, tx, data)
+	if err != nil {
+		return model.Data{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return model.Data{}, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return updatedData, nil
+}
+
+// UpdateInline replaces a binary data entry with a payload stored in one piece and removes the chunks
+// of its previous content in the same transaction. It fails like Update.
+func (r *PostgresDataRepository) UpdateInline(ctx context.Context, data model.Data) (model.Data, error) {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	updatedData, err := r.update(ctx, tx, data)
+	if err != nil {
+		return model.Data{}, err
+	}
+
+	_, err = tx.Exec(ctx,
+		`
+			delete from privatekeeper.binary_data_chunk
+			where owner_id = $1 and data_id = $2;
+			`,
+		data.OwnerID, data.ID)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("delete chunks: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return model.Data{}, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return updatedData, nil
+}
+
+// update runs the update statement of Update within the transaction.
+func (r *PostgresDataRepository) update(ctx context.Context, tx pgx.Tx, data model.Data) (model.Data, error) {
+	if err := lockUserKey(ctx, tx, data); err != nil {
+		return model.Data{}, err
+	}
+
+	rows, err := tx.Query(ctx,
+		`
+			update privatekeeper.data
+			set data = $4, metadata = $5, client_encrypted = $6,
+			    revision = revision + 1, updated_at = now(), change_seq = nextval('privatekeeper.data_change_seq')
+			where owner_id = $1 and type = $2 and id = $3 and ($7 = 0 or revision = $7)
+			returning id, owner_id, type, data, metadata, created_at, client_encrypted, revision, updated_at;
+			`,
+		data.OwnerID,
+		data.Type,
+		data.ID,
+		data.Data,
+		data.MetaData,
+		data.ClientEncrypted,
+		data.Revision)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("make query: %w", err)
+	}
+
+	updatedData, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.Data])
+	if errors.Is(err, pgx.ErrNoRows) && data.Revision != 0 {
+		if _, err = r.SelectByID(ctx, data.OwnerID, data.Type, data.ID); err != nil {
+			return model.Data{}, fmt.Errorf("select data: %w", err)
+		}
+		return model.Data{}, fmt.Errorf("collect row: %w", cerrors.ErrRevisionConflict)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Data{}, fmt.Errorf("collect row: %w", cerrors.ErrDataNotFound)
+	}
+
+	if err != nil {
+		return model.Data{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return updatedData, nil
+}
+
+// Delete removes a specific data entry by its ID for a user from the database.
+// A tombstone is left in place of the entry, so other clients learn about the deletion from the change feed.
+func (r *PostgresDataRepository) Delete(ctx context.Context, userID, dataType, dataID string) error {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var revision int64
+	err = tx.QueryRow(ctx,
+		`
+			delete from privatekeeper.data
+			where owner_id = $1 and type = $2 and id = $3
+			returning revision;
+			`,
+		userID, dataType, dataID).Scan(&revision)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("delete data: %w", cerrors.ErrDataNotFound)
+	}
+
+	if err != nil {
+		return fmt.Errorf("make query: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`
+			insert into privatekeeper.data_tombstone
+			    (id, type, owner_id, revision)
+			values
+				($1, $2, $3, $4)
+			on conflict (id, type) do update
+			set revision = excluded.revision, deleted_at = now(), change_seq = nextval('privatekeeper.data_change_seq');
+			`,
+		dataID, dataType, userID, revision+1)
+	if err != nil {
+		return fmt.Errorf("insert tombstone: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
+
+// SelectChangesSince retrieves up to limit created, updated and deleted data entries of a user
+// with a change cursor greater than the given one, ordered by the cursor. The cursors of a user are assigned
+// in commit order by a trigger, so a change committed later never gets a lower cursor than one already read.
+func (r *PostgresDataRepository) SelectChangesSince(ctx context.Context, userID string, cursor int64, limit int) ([]model.Change, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    id, type::text, metadata, revision, created_at, updated_at, deleted, change_seq
+			from (
+				select
+				    id, type, coalesce(metadata, '') as metadata, revision, created_at, updated_at,
+				    false as deleted, change_seq
+				from privatekeeper.data
+				where owner_id = $1 and change_seq > $2
+				union all
+				select
+				    id, type, '', revision, deleted_at, deleted_at, true, change_seq
+				from privatekeeper.data_tombstone
+				where owner_id = $1 and change_seq > $2
+			) changes
+			order by change_seq
+			limit $3;
+			`,
+		userID, cursor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("make query: %w", err)
+	}
+
+	changes, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.Change])
+	if err != nil {
+		return nil, fmt.Errorf("collect row: %w", err)
+	}
+
+	return changes, nil
+}
+
+// SearchItems retrieves up to filter.Limit data entries of a user matching the filter, skipping filter.Offset
+// entries. Entries are ordered by the last update time, the most recent first.
+func (r *PostgresDataRepository) SearchItems(ctx context.Context, userID string, filter model.SearchFilter) ([]model.Item, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    id, type::text, coalesce(metadata, ''), tags, revision, created_at, updated_at
+			from privatekeeper.data
+			where owner_id = $1
+			  and (coalesce(cardinality($2::text[]), 0) = 0 or type::text = any($2::text[]))
+			  and ($3 = '' or strpos(lower(coalesce(metadata, '')), lower($3)) > 0)
+			  and tags @> coalesce($4::text[], '{}')
+			  and ($5::timestamp is null or created_at >= $5)
+			  and ($6::timestamp is null or created_at < $6)
+			  and ($7::timestamp is null or updated_at >= $7)
+			  and ($8::timestamp is null or updated_at < $8)
+			order by updated_at desc, id
+			limit $9 offset $10;
+			`,
+		userID,
+		filter.Types,
+		filter.Query,
+		filter.Tags,
+		filter.CreatedFrom,
+		filter.CreatedTo,
+		filter.UpdatedFrom,
+		filter.UpdatedTo,
+		filter.Limit,
+		filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("make query: %w", err)
+	}
+
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.Item])
+	if err != nil {
+		return nil, fmt.Errorf("collect row: %w", err)
+	}
+
+	return items, nil
+}
+
+// staleUploadAge is the age after which an unfinished upload of binary data chunks is abandoned.
+const staleUploadAge = 24 * time.Hour
+
+// InsertWithChunks saves a new binary data entry together with its chunks. Chunks are read from next until
+// it returns io.EOF. They are staged one statement at a time while the upload runs, so a slow client doesn't
+// hold a transaction open. The entry is inserted and the staged chunks are moved to it by one short transaction
+// at the end. A failed upload removes its staged chunks.
+func (r *PostgresDataRepository) InsertWithChunks(ctx context.Context, data model.Data, next func() ([]byte, error)) (model.Data, error) {
+	// Uploads of the user left behind by a crashed server are removed first
+	_, err := r.postgresPool.DB.Exec(ctx,
+		`
+			delete from privatekeeper.binary_data_upload
+			where owner_id = $1 and started_at < now() - make_interval(secs => $2);
+			`,
+		data.OwnerID, staleUploadAge.Seconds())
+	if err != nil {
+		return model.Data{}, fmt.Errorf("delete stale uploads: %w", err)
+	}
+
+	_, err = r.postgresPool.DB.Exec(ctx,
+		`
+			insert into privatekeeper.binary_data_upload
+			    (id, owner_id)
+			values
+				($1, $2);
+			`,
+		data.ID, data.OwnerID)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("insert upload: %w", insertError(err))
+	}
+
+	savedData, err := r.stageChunks(ctx, data, next)
+	if err != nil {
+		// The upload is removed even if the client went away and canceled the context
+		if _, delErr := r.postgresPool.DB.Exec(context.WithoutCancel(ctx),
+			`delete from privatekeeper.binary_data_upload where id = $1;`, data.ID); delErr != nil {
+			return model.Data{}, errors.Join(err, fmt.Errorf("delete upload: %w", delErr))
+		}
+		return model.Data{}, err
+	}
+
+	return savedData, nil
+}
+
+// stageChunks stores the chunks read from next as chunks of the upload of the entry
+// and then completes the upload.
+func (r *PostgresDataRepository) stageChunks(ctx context.Context, data model.Data, next func() ([]byte, error)) (model.Data, error) {
+	for index := 0; ; index++ {
+		chunk, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return model.Data{}, fmt.Errorf("next chunk: %w", err)
+		}
+
+		_, err = r.postgresPool.DB.Exec(ctx,
+			`
+				insert into privatekeeper.binary_data_upload_chunk
+				    (upload_id, idx, data)
+				values
+					($1, $2, $3);
+				`,
+			data.ID,
+			index,
+			chunk)
+		if err != nil {
+			return model.Data{}, fmt.Errorf("insert chunk: %w", err)
+		}
+	}
+
+	return r.completeUpload(ctx, data)
+}
+
+// completeUpload inserts the entry, moves the staged chunks of its upload to it and removes the upload
+// in a single transaction.
+func (r *PostgresDataRepository) completeUpload(ctx context.Context, data model.Data) (model.Data, error) {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err = lockUserKey(ctx, tx, data); err != nil {
+		return model.Data{}, err
+	}
+
+	rows, err := tx.Query(ctx,
+		`
+			insert into privatekeeper.data
+			    (id, owner_id, type, data, metadata, created_at, client_encrypted)
+			values
+				($1, $2, $3, $4, $5, now(), $6)
+			returning id, owner_id, type, data, metadata, created_at, client_encrypted, revision, updated_at;
+			`,
+		data.ID,
+		data.OwnerID,
+		data.Type,
+		data.Data,
+		data.MetaData,
+		data.ClientEncrypted)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("make query: %w", err)
+	}
+
+	savedData, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.Data])
+	if err != nil {
+		return model.Data{}, fmt.Errorf("collect row: %w", insertError(err))
+	}
+
+	_, err = tx.Exec(ctx,
+		`
+			insert into privatekeeper.binary_data_chunk
+			    (data_id, owner_id, idx, data)
+			select upload_id, $2, idx, data
+			from privatekeeper.binary_data_upload_chunk
+			where upload_id = $1;
+			`,
+		data.ID, data.OwnerID)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("move chunks: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`
+			delete from privatekeeper.binary_data_upload
+			where id = $1;
+			`,
+		data.ID)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("delete upload: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return model.Data{}, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return savedData, nil
+}
+
+// SelectChunks reads the chunks of a binary data entry in order and passes each of them to yield
+// together with the total number of chunks. Chunks are read from the database one by one: the total
+// is counted once by a subquery that doesn't read the chunk payloads, so the first chunk is sent
+// without waiting for the others.
+func (r *PostgresDataRepository) SelectChunks(ctx context.Context, userID, dataID string, yield func(index, total int, chunk []byte) error) error {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    idx, data,
+			    (select count(*) from privatekeeper.binary_data_chunk where owner_id = $1 and data_id = $2)
+			from privatekeeper.binary_data_chunk
+			where owner_id = $1 and data_id = $2
+			order by idx;
+			`,
+		userID, dataID)
+	if err != nil {
+		return fmt.Errorf("make query: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		index, total int
+		chunk        []byte
+	)
+	for rows.Next() {
+		if err = rows.Scan(&index, &chunk, &total); err != nil {
+			return fmt.Errorf("scan chunk: %w", err)
+		}
+		if err = yield(index, total, chunk); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("read chunks: %w", err)
+	}
+
+	return nil
+}
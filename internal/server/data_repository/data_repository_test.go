@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/storage/postgresql"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// envTestDatabaseURI holds the database the tests of the repository are run against, they are skipped without it.
+const envTestDatabaseURI = "TEST_DATABASE_URI"
+
+type DataRepositoryTestSuite struct {
+	suite.Suite
+	pool   *postgresql.PostgresPool
+	repo   *PostgresDataRepository
+	userID string
+}
+
+func TestSuite(t *testing.T) {
+	if os.Getenv(envTestDatabaseURI) == "" {
+		t.Skipf("%s is not set", envTestDatabaseURI)
+	}
+	suite.Run(t, new(DataRepositoryTestSuite))
+}
+
+func (s *DataRepositoryTestSuite) SetupSuite() {
+	ctx := context.Background()
+	pool, err := postgresql.NewPool(ctx, os.Getenv(envTestDatabaseURI))
+	s.Require().NoError(err)
+	s.pool = pool
+
+	migrations, err := postgresql.NewMigrations(pool)
+	s.Require().NoError(err)
+	s.Require().NoError(migrations.Up())
+
+	s.repo = New(pool)
+	s.userID = uuid.NewString()
+	_, err = pool.DB.Exec(ctx,
+		`insert into privatekeeper.user (id, login, password, crypt_key, created_at) values ($1, $2, '', '', now());`,
+		s.userID, "repository-test-"+s.userID)
+	s.Require().NoError(err)
+}
+
+func (s *DataRepositoryTestSuite) TearDownSuite() {
+	ctx := context.Background()
+	_, err := s.pool.DB.Exec(ctx, `delete from privatekeeper.card_expiry_reminder where owner_id = $1;`, s.userID)
+	s.NoError(err)
+	_, err = s.pool.DB.Exec(ctx, `delete from privatekeeper.data where owner_id = $1;`, s.userID)
+	s.NoError(err)
+	_, err = s.pool.DB.Exec(ctx, `delete from privatekeeper.data_tombstone where owner_id = $1;`, s.userID)
+	s.NoError(err)
+	_, err = s.pool.DB.Exec(ctx, `delete from privatekeeper.user where id = $1;`, s.userID)
+	s.NoError(err)
+	s.pool.DB.Close()
+}
+
+// Test_ChangesInCommitOrder interleaves two writers of the same user: the first one takes its cursor
+// and stays open while the second one writes. The second writer must not commit a higher cursor
+// before the first one commits, or a client syncing in between would skip the first change.
+func (s *DataRepositoryTestSuite) Test_ChangesInCommitOrder() {
+	ctx := s.ctx()
+	after := s.lastCursor()
+	firstID, secondID := uuid.NewString(), uuid.NewString()
+
+	first, err := s.pool.DB.Begin(ctx)
+	require.NoError(s.T(), err)
+	defer first.Rollback(ctx) //nolint:errcheck
+
+	_, err = first.Exec(ctx,
+		`
+			insert into privatekeeper.data (id, owner_id, type, data, created_at)
+			values ($1, $2, 'text_data', 'first', now());
+			`,
+		firstID, s.userID)
+	require.NoError(s.T(), err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.repo.Insert(ctx, model.Data{ID: secondID, OwnerID: s.userID, Type: "text_data", Data: []byte("second")})
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+		s.FailNow("second writer committed while the first one was open", "error: %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	changes, err := s.repo.SelectChangesSince(ctx, s.userID, after, 10)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), changes)
+
+	require.NoError(s.T(), first.Commit(ctx))
+	require.NoError(s.T(), <-done)
+
+	changes, err = s.repo.SelectChangesSince(ctx, s.userID, after, 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), changes, 2)
+	assert.Equal(s.T(), firstID, changes[0].ID)
+	assert.Equal(s.T(), secondID, changes[1].ID)
+	assert.Less(s.T(), changes[0].Cursor, changes[1].Cursor)
+}
+
+// Test_ReminderFailureRollsBackCards checks that the cards of a batch are not saved when saving
+// one of their reminders fails.
+func (s *DataRepositoryTestSuite) Test_ReminderFailureRollsBackCards() {
+	ctx := s.ctx()
+	cards := []model.Data{
+		{ID: uuid.NewString(), OwnerID: s.userID, Type: "credit_card", Data: []byte("first")},
+		{ID: uuid.NewString(), OwnerID: s.userID, Type: "credit_card", Data: []byte("second")},
+	}
+	month := time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC)
+	reminders := []model.ExpiryReminder{
+		{DataID: cards[0].ID, OwnerID: s.userID, ExpiryMonth: month},
+		// The reminder of a card that isn't saved violates the foreign key of the reminder
+		{DataID: uuid.NewString(), OwnerID: s.userID, ExpiryMonth: month},
+	}
+
+	_, err := s.repo.InsertWithReminders(ctx, cards, reminders)
+	require.Error(s.T(), err)
+
+	for _, card := range cards {
+		_, err = s.repo.SelectByID(ctx, s.userID, "credit_card", card.ID)
+		assert.ErrorIs(s.T(), err, cerrors.ErrDataNotFound)
+	}
+
+	saved, err := s.repo.InsertWithReminders(ctx, cards, reminders[:1])
+	require.NoError(s.T(), err)
+	require.Len(s.T(), saved, 2)
+
+	on, err := s.repo.SelectExpiryReminder(ctx, s.userID, cards[0].ID)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), on)
+
+	// Turning the reminder off is part of the update of the card
+	cards[0].Data = []byte("updated")
+	_, err = s.repo.UpdateWithReminder(ctx, cards[0], nil)
+	require.NoError(s.T(), err)
+
+	on, err = s.repo.SelectExpiryReminder(ctx, s.userID, cards[0].ID)
+	require.NoError(s.T(), err)
+	assert.False(s.T(), on)
+}
+
+// Test_WriteDuringKeyRotation checks that data encrypted with the user key can't be written while the key
+// is being rotated and is rejected once the rotation commits, as the key it was encrypted with is gone.
+func (s *DataRepositoryTestSuite) Test_WriteDuringKeyRotation() {
+	ctx := s.ctx()
+	dataID := uuid.NewString()
+
+	rotation, err := s.pool.DB.Begin(ctx)
+	require.NoError(s.T(), err)
+	defer rotation.Rollback(ctx) //nolint:errcheck
+
+	_, err = rotation.Exec(ctx,
+		`
+			update privatekeeper.user
+			set crypt_key_generation = crypt_key_generation + 1
+			where id = $1;
+			`,
+		s.userID)
+	require.NoError(s.T(), err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.repo.Insert(ctx, model.Data{ID: dataID, OwnerID: s.userID, Type: "text_data", Data: []byte("old key")})
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+		s.FailNow("data was written during the rotation", "error: %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	require.NoError(s.T(), rotation.Commit(ctx))
+	assert.ErrorIs(s.T(), <-done, cerrors.ErrUserKeyRotated)
+
+	_, err = s.repo.SelectByID(ctx, s.userID, "text_data", dataID)
+	assert.ErrorIs(s.T(), err, cerrors.ErrDataNotFound)
+
+	// Data encrypted on the client side doesn't depend on the user key
+	_, err = s.repo.Insert(ctx, model.Data{ID: dataID, OwnerID: s.userID, Type: "text_data", Data: []byte("client"), ClientEncrypted: true})
+	assert.NoError(s.T(), err)
+
+	// A request served with the new key writes again
+	_, err = s.repo.Update(s.ctx(), model.Data{ID: dataID, OwnerID: s.userID, Type: "text_data", Data: []byte("new key")})
+	assert.NoError(s.T(), err)
+}
+
+// Test_SearchItems searches the data of a user, which is created by the test along with the data of another user
+// that no search may return.
+func (s *DataRepositoryTestSuite) Test_SearchItems() {
+	ctx := context.Background()
+	ownerID, otherID := s.newUser(), s.newUser()
+	day := func(month, day int) time.Time { return time.Date(2026, time.Month(month), day, 0, 0, 0, 0, time.UTC) }
+
+	mail := s.insertItem(ownerID, "credentials", "Work Mail #Work #mail", day(1, 1), day(3, 1))
+	list := s.insertItem(ownerID, "text_data", "shopping list #home", day(2, 1), day(2, 1))
+	card := s.insertItem(ownerID, "credit_card", "work card #work", day(3, 1), day(4, 1))
+	s.insertItem(otherID, "credentials", "work mail #work", day(1, 1), day(5, 1))
+
+	created, updated := day(2, 1), day(3, 1)
+	updatedTo := day(4, 1)
+	for name, tc := range map[string]struct {
+		filter model.SearchFilter
+		want   []string
+	}{
+		"all":                    {model.SearchFilter{}, []string{card, mail, list}},
+		"type":                   {model.SearchFilter{Types: []string{"text_data"}}, []string{list}},
+		"types":                  {model.SearchFilter{Types: []string{"credentials", "credit_card"}}, []string{card, mail}},
+		"query in any case":      {model.SearchFilter{Query: "WORK"}, []string{card, mail}},
+		"query substring":        {model.SearchFilter{Query: "ping li"}, []string{list}},
+		"tag in any case":        {model.SearchFilter{Tags: []string{"work"}}, []string{card, mail}},
+		"all tags":               {model.SearchFilter{Tags: []string{"work", "mail"}}, []string{mail}},
+		"tag prefix":             {model.SearchFilter{Tags: []string{"wor"}}, []string{}},
+		"created from inclusive": {model.SearchFilter{CreatedFrom: &created}, []string{card, list}},
+		"created to exclusive":   {model.SearchFilter{CreatedTo: &created}, []string{mail}},
+		"updated range":          {model.SearchFilter{UpdatedFrom: &updated, UpdatedTo: &updatedTo}, []string{mail}},
+		"page":                   {model.SearchFilter{Limit: 2, Offset: 1}, []string{mail, list}},
+	} {
+		if tc.filter.Limit == 0 {
+			tc.filter.Limit = 10
+		}
+		items, err := s.repo.SearchItems(ctx, ownerID, tc.filter)
+		require.NoError(s.T(), err, name)
+
+		ids := make([]string, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+		assert.Equal(s.T(), tc.want, ids, name)
+	}
+
+	items, err := s.repo.SearchItems(ctx, ownerID, model.SearchFilter{Types: []string{"credentials"}, Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), items, 1)
+	assert.Equal(s.T(), "Work Mail #Work #mail", items[0].MetaData)
+	assert.ElementsMatch(s.T(), []string{"work", "mail"}, items[0].Tags)
+}
+
+// newUser creates a user deleted with its data at the end of the test.
+func (s *DataRepositoryTestSuite) newUser() string {
+	userID := uuid.NewString()
+	_, err := s.pool.DB.Exec(context.Background(),
+		`insert into privatekeeper.user (id, login, password, crypt_key, created_at) values ($1, $2, '', '', now());`,
+		userID, "repository-test-"+userID)
+	s.Require().NoError(err)
+
+	s.T().Cleanup(func() {
+		ctx := context.Background()
+		_, err := s.pool.DB.Exec(ctx, `delete from privatekeeper.data where owner_id = $1;`, userID)
+		s.NoError(err)
+		_, err = s.pool.DB.Exec(ctx, `delete from privatekeeper.user where id = $1;`, userID)
+		s.NoError(err)
+	})
+	return userID
+}
+
+// insertItem inserts a data entry of the user with the given metadata and times and returns its ID.
+func (s *DataRepositoryTestSuite) insertItem(ownerID, dataType, metadata string, createdAt, updatedAt time.Time) string {
+	id := uuid.NewString()
+	_, err := s.pool.DB.Exec(context.Background(),
+		`
+			insert into privatekeeper.data (id, owner_id, type, data, metadata, created_at, updated_at)
+			values ($1, $2, $3, 'data', $4, $5, $6);
+			`,
+		id, ownerID, dataType, metadata, createdAt, updatedAt)
+	s.Require().NoError(err)
+	return id
+}
+
+// ctx returns the context of a request served with the current key of the user.
+func (s *DataRepositoryTestSuite) ctx() context.Context {
+	var generation int64
+	err := s.pool.DB.QueryRow(context.Background(),
+		`select crypt_key_generation from privatekeeper.user where id = $1;`, s.userID).Scan(&generation)
+	s.Require().NoError(err)
+	return context.WithValue(context.Background(), model.UserKeyGenerationKey, generation)
+}
+
+// lastCursor returns the cursor after the changes of the user made by the tests before.
+func (s *DataRepositoryTestSuite) lastCursor() int64 {
+	changes, err := s.repo.SelectChangesSince(context.Background(), s.userID, 0, 1000)
+	s.Require().NoError(err)
+	if len(changes) == 0 {
+		return 0
+	}
+	return changes[len(changes)-1].Cursor
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// InsertWithReminders saves new credit cards together with the expiry reminders of the cards that have one
+// in a single transaction, either all of them are saved or none. It returns the saved cards in the same order.
+func (r *PostgresDataRepository) InsertWithReminders(ctx context.Context, data []model.Data, reminders []model.ExpiryReminder) ([]model.Data, error) {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	savedData, err := insertBatch(ctx, tx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reminder := range reminders {
+		if err = upsertExpiryReminder(ctx, tx, reminder); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return savedData, nil
+}
+
+// UpdateWithReminder updates a credit card like Update and in the same transaction turns its expiry reminder on,
+// when the reminder is given, or off.
+func (r *PostgresDataRepository) UpdateWithReminder(ctx context.Context, data model.Data, reminder *model.ExpiryReminder) (model.Data, error) {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	updatedData, err := r.update(ctx, tx, data)
+	if err != nil {
+		return model.Data{}, err
+	}
+
+	if reminder != nil {
+		err = upsertExpiryReminder(ctx, tx, *reminder)
+	} else {
+		err = deleteExpiryReminder(ctx, tx, data.OwnerID, data.ID)
+	}
+	if err != nil {
+		return model.Data{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return model.Data{}, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return updatedData, nil
+}
+
+// upsertExpiryReminder turns the expiry reminder of a credit card of the user on or moves it to another month.
+// A card that was already notified is notified again only if its expiry month changes.
+func upsertExpiryReminder(ctx context.Context, q querier, reminder model.ExpiryReminder) error {
+	_, err := q.Exec(ctx,
+		`
+			insert into privatekeeper.card_expiry_reminder as reminder
+			    (data_id, owner_id, expiry_month)
+			values
+				($1, $2, $3)
+			on conflict (data_id) do update
+			set expiry_month = excluded.expiry_month,
+			    notified_at = case when reminder.expiry_month = excluded.expiry_month then reminder.notified_at end
+			where reminder.owner_id = excluded.owner_id;
+			`,
+		reminder.DataID,
+		reminder.OwnerID,
+		reminder.ExpiryMonth)
+	if err != nil {
+		return fmt.Errorf("upsert expiry reminder: %w", err)
+	}
+
+	return nil
+}
+
+// deleteExpiryReminder turns the expiry reminder of a credit card of the user off, a card without a reminder is left as is.
+func deleteExpiryReminder(ctx context.Context, q querier, userID, dataID string) error {
+	_, err := q.Exec(ctx,
+		`
+			delete from privatekeeper.card_expiry_reminder
+			where owner_id = $1 and data_id = $2;
+			`,
+		userID, dataID)
+	if err != nil {
+		return fmt.Errorf("delete expiry reminder: %w", err)
+	}
+
+	return nil
+}
+
+// SelectExpiryReminder reports whether the expiry reminder of a credit card of the user is on.
+func (r *PostgresDataRepository) SelectExpiryReminder(ctx context.Context, userID, dataID string) (bool, error) {
+	var exists bool
+	err := r.postgresPool.DB.QueryRow(ctx,
+		`
+			select exists (
+				select 1 from privatekeeper.card_expiry_reminder
+				where owner_id = $1 and data_id = $2
+			);
+			`,
+		userID, dataID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("make query: %w", err)
+	}
+
+	return exists, nil
+}
+
+// SelectDueExpiryReminders retrieves up to limit cards with a reminder that were not notified yet
+// and expire before the given time, that is their expiry month ends before it. The cards are ordered by ID,
+// cards up to and including afterID are skipped.
+func (r *PostgresDataRepository) SelectDueExpiryReminders(ctx context.Context, before time.Time, afterID string, limit int) ([]model.ExpiryNotification, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    reminder.data_id, reminder.owner_id, u.login, coalesce(d.metadata, ''), reminder.expiry_month
+			from privatekeeper.card_expiry_reminder reminder
+			join privatekeeper.data d on d.id = reminder.data_id and d.type = reminder.data_type
+			join privatekeeper.user u on u.id = reminder.owner_id
+			where reminder.notified_at is null
+			  and reminder.expiry_month + interval '1 month' <= $1
+			  and reminder.data_id > $2
+			order by reminder.data_id
+			limit $3;
+			`,
+		before, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("make query: %w", err)
+	}
+
+	notifications, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.ExpiryNotification])
+	if err != nil {
+		return nil, fmt.Errorf("collect row: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// MarkExpiryReminderNotified records that the owner of a card was notified about the expiry month,
+// unless the card was moved to another month in the meantime.
+func (r *PostgresDataRepository) MarkExpiryReminderNotified(ctx context.Context, dataID string, expiryMonth time.Time) error {
+	_, err := r.postgresPool.DB.Exec(ctx,
+		`
+			update privatekeeper.card_expiry_reminder
+			set notified_at = now()
+			where data_id = $1 and expiry_month = $2;
+			`,
+		dataID, expiryMonth)
+	if err != nil {
+		return fmt.Errorf("mark expiry reminder notified: %w", err)
+	}
+
+	return nil
+}
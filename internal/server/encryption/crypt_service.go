@@ -4,29 +4,76 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"fmt"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
-// Service struct holds the AEAD (Authenticated Encryption with Associated Data) instance.
+// LegacyMasterKeyVersion is the version of user keys wrapped before master key versioning was introduced.
+// Keys of this version are derived with plain SHA-256 to stay readable.
+const LegacyMasterKeyVersion = 0
+
+// ErrUnknownMasterKeyVersion is returned when the keyring has no master key of the requested version.
+var ErrUnknownMasterKeyVersion = errors.New("unknown master key version")
+
+// ErrCiphertextTooShort is returned when the data is too short to hold the nonce and the authentication tag.
+var ErrCiphertextTooShort = errors.New("ciphertext too short")
+
+// Service struct holds the AEAD (Authenticated Encryption with Associated Data) instances of the master keyring.
 type Service struct {
-	aead cipher.AEAD // AEAD interface for encryption/decryption
+	aead    cipher.AEAD         // AEAD interface of the current master key version
+	version int                 // Current master key version
+	keyring map[int]cipher.AEAD // AEAD interfaces of all known master key versions
 }
 
-// New initializes a new Service with the provided key.
-// It hashes the key using SHA-256 and creates a ChaCha20-Poly1305 AEAD instance.
+// New initializes a new Service with the provided key as master key version 1.
 func New(key []byte) (*Service, error) {
-	hash := sha256.Sum256(key)
-	aead, err := chacha20poly1305.NewX(hash[:])
-	if err != nil {
-		return nil, fmt.Errorf("chacha20poly1305.NewX: %w", err)
+	return NewKeyring(map[int][]byte{1: key}, 1)
+}
+
+// NewKeyring initializes a new Service with the provided master keys by version.
+// New user keys are wrapped with the current version, other versions are used only to unwrap existing keys.
+func NewKeyring(keys map[int][]byte, current int) (*Service, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current version %d: %w", current, ErrUnknownMasterKeyVersion)
+	}
+
+	keyring := make(map[int]cipher.AEAD, len(keys))
+	for version, key := range keys {
+		aead, err := chacha20poly1305.NewX(deriveMasterKey(version, key))
+		if err != nil {
+			return nil, fmt.Errorf("chacha20poly1305.NewX: %w", err)
+		}
+		keyring[version] = aead
+	}
+
+	return &Service{
+		aead:    keyring[current],
+		version: current,
+		keyring: keyring,
+	}, nil
+}
+
+// deriveMasterKey derives the master key of the given version from the key material using Argon2id.
+func deriveMasterKey(version int, key []byte) []byte {
+	if version == LegacyMasterKeyVersion {
+		hash := sha256.Sum256(key)
+		return hash[:]
 	}
 
-	return &Service{aead: aead}, nil
+	salt := []byte(fmt.Sprintf("privatekeeper-v2 master key v%d", version))
+	return argon2.IDKey(key, salt, 1, 64*1024, 4, chacha20poly1305.KeySize)
+}
+
+// Version returns the current master key version.
+func (e *Service) Version() int {
+	return e.version
 }
 
-// EncryptWithMasterKey encrypts the given data using the master key.
+// EncryptWithMasterKey encrypts the given data using the current master key.
 func (e *Service) EncryptWithMasterKey(data []byte) ([]byte, error) {
 	nonce := make([]byte, e.aead.NonceSize())
 	_, err := rand.Read(nonce)
@@ -38,10 +85,24 @@ func (e *Service) EncryptWithMasterKey(data []byte) ([]byte, error) {
 	return ciphertext, nil
 }
 
-// DecryptWithMasterKey decrypts the given data using the master key.
+// DecryptWithMasterKey decrypts the given data using the current master key.
 func (e *Service) DecryptWithMasterKey(data []byte) ([]byte, error) {
+	return e.DecryptWithMasterKeyVersion(e.version, data)
+}
+
+// DecryptWithMasterKeyVersion decrypts the given data using the master key of the given version.
+func (e *Service) DecryptWithMasterKeyVersion(version int, data []byte) ([]byte, error) {
+	aead, ok := e.keyring[version]
+	if !ok {
+		return nil, fmt.Errorf("version %d: %w", version, ErrUnknownMasterKeyVersion)
+	}
+
+	if len(data) < chacha20poly1305.NonceSizeX+chacha20poly1305.Overhead {
+		return nil, ErrCiphertextTooShort
+	}
+
 	nonce, ciphertext := data[:chacha20poly1305.NonceSizeX], data[chacha20poly1305.NonceSizeX:]
-	dec, err := e.aead.Open(nil, nonce, ciphertext, nil)
+	dec, err := aead.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
 		return nil, fmt.Errorf("aead.Open: %w", err)
 	}
@@ -49,6 +110,17 @@ func (e *Service) DecryptWithMasterKey(data []byte) ([]byte, error) {
 	return dec, nil
 }
 
+// RewrapWithMasterKey decrypts the given data using the master key of the given version
+// and encrypts it again using the current master key.
+func (e *Service) RewrapWithMasterKey(version int, data []byte) ([]byte, error) {
+	dec, err := e.DecryptWithMasterKeyVersion(version, data)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+
+	return e.EncryptWithMasterKey(dec)
+}
+
 // GenerateKey generates a new random encryption key.
 func (e *Service) GenerateKey() ([]byte, error) {
 	key := make([]byte, chacha20poly1305.KeySize)
@@ -83,6 +155,10 @@ func (e *Service) Decrypt(key, data []byte) ([]byte, error) {
 		return nil, fmt.Errorf("chacha20poly1305.NewX: %w", err)
 	}
 
+	if len(data) < chacha20poly1305.NonceSizeX+chacha20poly1305.Overhead {
+		return nil, ErrCiphertextTooShort
+	}
+
 	nonce, ciphertext := data[:chacha20poly1305.NonceSizeX], data[chacha20poly1305.NonceSizeX:]
 	dec, err := aead.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
@@ -91,3 +167,52 @@ func (e *Service) Decrypt(key, data []byte) ([]byte, error) {
 
 	return dec, nil
 }
+
+// EncryptChunk encrypts a chunk of a stream using the provided key.
+// The chunk index and the last chunk flag are authenticated, so reordered, dropped
+// or truncated chunks fail to decrypt.
+func (e *Service) EncryptChunk(key, data []byte, index int, last bool) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("chacha20poly1305.NewX: %w", err)
+	}
+
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+
+	ciphertext := aead.Seal(nonce, nonce, data, chunkAD(index, last))
+	return ciphertext, nil
+}
+
+// DecryptChunk decrypts a chunk of a stream using the provided key.
+func (e *Service) DecryptChunk(key, data []byte, index int, last bool) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("chacha20poly1305.NewX: %w", err)
+	}
+
+	if len(data) < chacha20poly1305.NonceSizeX {
+		return nil, errors.New("chunk too short")
+	}
+
+	nonce, ciphertext := data[:chacha20poly1305.NonceSizeX], data[chacha20poly1305.NonceSizeX:]
+	dec, err := aead.Open(nil, nonce, ciphertext, chunkAD(index, last))
+	if err != nil {
+		return nil, fmt.Errorf("aead.Open: %w", err)
+	}
+
+	return dec, nil
+}
+
+// chunkAD builds the additional data of a stream chunk from its index and the last chunk flag.
+func chunkAD(index int, last bool) []byte {
+	ad := make([]byte, 9)
+	binary.BigEndian.PutUint64(ad, uint64(index))
+	if last {
+		ad[8] = 1
+	}
+	return ad
+}
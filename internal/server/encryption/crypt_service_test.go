@@ -54,3 +54,70 @@ func (c *CryptServiceTestSuite) Test_EncryptDecrypt() {
 	assert.NoError(c.T(), err)
 	assert.Equal(c.T(), data, decryptedData)
 }
+
+func (c *CryptServiceTestSuite) Test_Keyring() {
+	oldService, err := NewKeyring(map[int][]byte{LegacyMasterKeyVersion: []byte("master-key")}, LegacyMasterKeyVersion)
+	require.NoError(c.T(), err)
+
+	data := []byte("user key")
+	legacyCryptData, err := oldService.EncryptWithMasterKey(data)
+	require.NoError(c.T(), err)
+
+	service, err := NewKeyring(map[int][]byte{
+		LegacyMasterKeyVersion: []byte("master-key"),
+		2:                      []byte("new-master-key"),
+	}, 2)
+	require.NoError(c.T(), err)
+	assert.Equal(c.T(), 2, service.Version())
+
+	decryptedData, err := service.DecryptWithMasterKeyVersion(LegacyMasterKeyVersion, legacyCryptData)
+	assert.NoError(c.T(), err)
+	assert.Equal(c.T(), data, decryptedData)
+
+	_, err = service.DecryptWithMasterKey(legacyCryptData)
+	assert.Error(c.T(), err)
+
+	rewrapped, err := service.RewrapWithMasterKey(LegacyMasterKeyVersion, legacyCryptData)
+	assert.NoError(c.T(), err)
+	decryptedData, err = service.DecryptWithMasterKey(rewrapped)
+	assert.NoError(c.T(), err)
+	assert.Equal(c.T(), data, decryptedData)
+
+	_, err = service.DecryptWithMasterKeyVersion(1, legacyCryptData)
+	assert.ErrorIs(c.T(), err, ErrUnknownMasterKeyVersion)
+
+	_, err = NewKeyring(map[int][]byte{1: []byte("master-key")}, 2)
+	assert.ErrorIs(c.T(), err, ErrUnknownMasterKeyVersion)
+}
+
+func (c *CryptServiceTestSuite) Test_EncryptDecryptChunk() {
+	key, err := c.cryptService.GenerateKey()
+	require.NoError(c.T(), err)
+
+	data := []byte("chunk data")
+	cryptData, err := c.cryptService.EncryptChunk(key, data, 1, false)
+	assert.NoError(c.T(), err)
+
+	decryptedData, err := c.cryptService.DecryptChunk(key, cryptData, 1, false)
+	assert.NoError(c.T(), err)
+	assert.Equal(c.T(), data, decryptedData)
+
+	_, err = c.cryptService.DecryptChunk(key, cryptData, 2, false)
+	assert.Error(c.T(), err)
+
+	_, err = c.cryptService.DecryptChunk(key, cryptData, 1, true)
+	assert.Error(c.T(), err)
+}
+
+func (c *CryptServiceTestSuite) Test_DecryptShortData() {
+	key, err := c.cryptService.GenerateKey()
+	require.NoError(c.T(), err)
+
+	for _, data := range [][]byte{nil, make([]byte, 10), make([]byte, 39)} {
+		_, err = c.cryptService.Decrypt(key, data)
+		assert.ErrorIs(c.T(), err, ErrCiphertextTooShort)
+
+		_, err = c.cryptService.DecryptWithMasterKey(data)
+		assert.ErrorIs(c.T(), err, ErrCiphertextTooShort)
+	}
+}
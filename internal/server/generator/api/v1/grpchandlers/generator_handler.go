@@ -0,0 +1,71 @@
+package grpchandlers
+
+import (
+	"context"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/generator"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// GeneratorService is an interface that defines the method for generating passwords.
+type GeneratorService interface {
+	GeneratePassword(req model.GeneratePasswordRequest) (model.GeneratedPassword, error)
+}
+
+// Validator is an interface for validating incoming requests.
+type Validator interface {
+	ValidateGenerateRequest(req *model.GeneratePasswordRequest) (map[string]string, bool)
+}
+
+// GeneratorHandler implements the gRPC server of the password generator.
+// It holds references to the generator service and validator.
+type GeneratorHandler struct {
+	generatorService                       GeneratorService // Service for generating passwords
+	pb.UnimplementedGeneratorServiceServer                  // Embed the unimplemented server for compliance with the gRPC server interface
+	validator                              Validator        // Validator for incoming requests
+}
+
+// New creates a new instance of GeneratorHandler with the provided services.
+func New(generatorService GeneratorService, validator Validator) *GeneratorHandler {
+	return &GeneratorHandler{
+		generatorService: generatorService,
+		validator:        validator,
+	}
+}
+
+// GeneratePassword handles the gRPC call to generate a password or a passphrase with crypto/rand,
+// so thin clients don't need a generator of their own. The password is not stored.
+func (h *GeneratorHandler) GeneratePassword(_ context.Context, in *pb.GeneratePasswordRequest) (*pb.GeneratePasswordResponse, error) {
+	req := model.GeneratePasswordRequest{
+		Mode:             in.Mode,
+		Length:           int(in.Length),
+		Lower:            in.Lower,
+		Upper:            in.Upper,
+		Digits:           in.Digits,
+		Symbols:          in.Symbols,
+		ExcludeAmbiguous: in.ExcludeAmbiguous,
+		Words:            int(in.Words),
+		Separator:        in.Separator,
+		Capitalize:       in.Capitalize,
+		Number:           in.Number,
+	}
+
+	report, ok := h.validator.ValidateGenerateRequest(&req)
+	if !ok {
+		logrus.Info("Unable to generate password: invalid generator options")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid generator options", report)
+	}
+
+	generated, err := h.generatorService.GeneratePassword(req)
+	if err != nil {
+		logrus.WithError(err).Error("Error while generating password: ")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.GeneratePasswordResponse{Password: generated.Password, Entropy: generated.Entropy}, nil
+}
@@ -0,0 +1,49 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/passgen"
+	"github.com/go-playground/validator/v10"
+)
+
+// Validator struct holds an instance of the validator.
+// It is used to validate the options of generated passwords.
+type Validator struct {
+	validator *validator.Validate // The validator instance for performing validation checks
+}
+
+// New initializes a new Validator instance with a validator.
+func New(validator *validator.Validate) *Validator {
+	return &Validator{validator: validator}
+}
+
+// ValidateGenerateRequest validates the incoming GeneratePasswordRequest.
+// It checks the mode and the limits of the length and returns a map of validation errors if any exist.
+func (v *Validator) ValidateGenerateRequest(req *model.GeneratePasswordRequest) (map[string]string, bool) {
+	err := v.validator.Struct(req)
+	report := make(map[string]string)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				switch validationErr.Tag() {
+				case "oneof":
+					report[validationErr.Field()] = "must be password or passphrase"
+				case "gte", "lte":
+					report[validationErr.Field()] = "must be between " + limits[validationErr.Field()]
+				}
+			}
+			return report, false
+		}
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+	return nil, true
+}
+
+// limits holds the allowed ranges of the numeric options for the validation messages.
+var limits = map[string]string{
+	"Length": fmt.Sprintf("%d and %d", passgen.MinLength, passgen.MaxLength),
+	"Words":  fmt.Sprintf("%d and %d", passgen.MinWords, passgen.MaxWords),
+}
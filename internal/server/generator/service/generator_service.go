@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/passgen"
+)
+
+// GeneratorService generates passwords and passphrases for clients that don't generate them on their own.
+type GeneratorService struct{}
+
+// New initializes a new GeneratorService instance
+func New() *GeneratorService {
+	return &GeneratorService{}
+}
+
+// GeneratePassword generates a password or a passphrase with the options of the request,
+// the options that are not set get the defaults of the generator.
+func (s *GeneratorService) GeneratePassword(req model.GeneratePasswordRequest) (model.GeneratedPassword, error) {
+	var (
+		secret passgen.Secret
+		err    error
+	)
+	if req.Mode == model.GeneratorModePassphrase {
+		opts := passgen.DefaultPassphraseOptions()
+		if req.Words != 0 {
+			opts.Words = req.Words
+		}
+		if req.Separator != "" {
+			opts.Separator = req.Separator
+		}
+		opts.Capitalize, opts.Number = req.Capitalize, req.Number
+		secret, err = passgen.Passphrase(opts)
+	} else {
+		opts := passgen.DefaultPasswordOptions()
+		if req.Length != 0 {
+			opts.Length = req.Length
+		}
+		// Without any class set all classes are used
+		if req.Lower || req.Upper || req.Digits || req.Symbols {
+			opts.Lower, opts.Upper, opts.Digits, opts.Symbols = req.Lower, req.Upper, req.Digits, req.Symbols
+		}
+		opts.ExcludeAmbiguous = req.ExcludeAmbiguous
+		secret, err = passgen.Password(opts)
+	}
+	if err != nil {
+		return model.GeneratedPassword{}, fmt.Errorf("generate password: %w", err)
+	}
+
+	return model.GeneratedPassword{Password: secret.Value, Entropy: secret.Entropy}, nil
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/cache"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
 	"github.com/sirupsen/logrus"
 	"log/slog"
@@ -17,36 +19,106 @@ import (
 // Define a map of methods that require authentication authMandatoryMethods.
 var authMandatoryMethods = map[string]struct{}{
 	"/proto.CreditCardService/PostSaveCreditCard":             {},
+	"/proto.CreditCardService/PostSaveCreditCardBatch":        {},
 	"/proto.CreditCardService/GetLoadCreditCard":              {},
 	"/proto.CreditCardService/GetLoadAllCreditCardDataInfo":   {},
+	"/proto.CreditCardService/PutUpdateCreditCard":            {},
+	"/proto.CreditCardService/DeleteCreditCard":               {},
 	"/proto.TextDataService/PostSaveTextData":                 {},
+	"/proto.TextDataService/PostSaveTextDataBatch":            {},
 	"/proto.TextDataService/GetLoadTextData":                  {},
 	"/proto.TextDataService/GetLoadAllTextDataInfo":           {},
+	"/proto.TextDataService/PutUpdateTextData":                {},
+	"/proto.TextDataService/DeleteTextData":                   {},
 	"/proto.BinaryDataService/PostSaveBinaryData":             {},
 	"/proto.BinaryDataService/GetLoadBinaryData":              {},
 	"/proto.BinaryDataService/GetLoadAllBinaryDataInfo":       {},
+	"/proto.BinaryDataService/PutUpdateBinaryData":            {},
+	"/proto.BinaryDataService/DeleteBinaryData":               {},
+	"/proto.BinaryDataService/PostSaveBinaryDataStream":       {},
+	"/proto.BinaryDataService/GetLoadBinaryDataStream":        {},
 	"/proto.CredentialsService/PostSaveCredentials":           {},
+	"/proto.CredentialsService/PostSaveCredentialsBatch":      {},
 	"/proto.CredentialsService/GetLoadCredentials":            {},
 	"/proto.CredentialsService/GetLoadAllCredentialsDataInfo": {},
+	"/proto.CredentialsService/PutUpdateCredentials":          {},
+	"/proto.CredentialsService/DeleteCredentials":             {},
+	"/proto.CustomDataService/PostSaveTemplate":               {},
+	"/proto.CustomDataService/GetLoadAllTemplates":            {},
+	"/proto.CustomDataService/PutUpdateTemplate":              {},
+	"/proto.CustomDataService/DeleteTemplate":                 {},
+	"/proto.CustomDataService/PostSaveCustomData":             {},
+	"/proto.CustomDataService/GetLoadCustomData":              {},
+	"/proto.CustomDataService/GetLoadAllCustomDataInfo":       {},
+	"/proto.CustomDataService/PutUpdateCustomData":            {},
+	"/proto.CustomDataService/DeleteCustomData":               {},
+	"/proto.UserService/PostRotateUserKey":                    {},
+	"/proto.UserService/PostLogout":                           {},
+	"/proto.UserService/PostLogoutAllSessions":                {},
+	"/proto.UserService/PostEnrollTOTP":                       {},
+	"/proto.UserService/PostConfirmTOTP":                      {},
+	"/proto.UserService/PostDisableTOTP":                      {},
+	"/proto.SyncService/GetChangesSince":                      {},
+	"/proto.SearchService/SearchItems":                        {},
+	"/proto.GeneratorService/GeneratePassword":                {},
+	"/proto.BreachService/CheckPasswordBreach":                {},
+	"/proto.CollectionService/PostCreateCollection":           {},
+	"/proto.CollectionService/GetLoadAllCollections":          {},
+	"/proto.CollectionService/PostInviteMember":               {},
+	"/proto.CollectionService/PostAcceptInvitation":           {},
+	"/proto.CollectionService/DeleteRevokeMember":             {},
+	"/proto.CollectionService/GetLoadAllMembers":              {},
+	"/proto.CollectionService/PostSaveCollectionItem":         {},
+	"/proto.CollectionService/GetLoadCollectionItem":          {},
+	"/proto.CollectionService/GetLoadAllCollectionItems":      {},
+	"/proto.CollectionService/PutUpdateCollectionItem":        {},
+	"/proto.CollectionService/DeleteCollectionItem":           {},
 }
 
-// JWTAuth struct holds the JWT manager for authentication.
+// JWTAuth struct holds the JWT manager for authentication and redis for checking revoked sessions.
 type JWTAuth struct {
 	jwtManager *jwtmanager.JWTManager // Instance of JWTManager for token handling
+	redis      *cache.Redis           // Instance of Redis holding revoked sessions
 }
 
-// New creates a new instance of JWTAuth with the provided JWT manager.
-func New(jwtManager *jwtmanager.JWTManager) *JWTAuth {
-	return &JWTAuth{jwtManager: jwtManager}
+// New creates a new instance of JWTAuth with the provided JWT manager and redis.
+func New(jwtManager *jwtmanager.JWTManager, redis *cache.Redis) *JWTAuth {
+	return &JWTAuth{
+		jwtManager: jwtManager,
+		redis:      redis,
+	}
 }
 
-// GRPCJWTAuth checks token from gRPC metadata and sets userID in the context.
+// GRPCJWTAuth checks token from gRPC metadata and sets userID and sessionID in the context.
+// Tokens of revoked sessions are rejected.
 // If authentication fails, it returns an error with the corresponding status code.
 func (j *JWTAuth) GRPCJWTAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	if _, ok := authMandatoryMethods[info.FullMethod]; !ok {
 		return handler(ctx, req)
 	}
 
+	ctx, err := j.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// GRPCJWTAuthStream is the stream counterpart of GRPCJWTAuth.
+func (j *JWTAuth) GRPCJWTAuthStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if _, ok := authMandatoryMethods[info.FullMethod]; !ok {
+		return handler(srv, ss)
+	}
+
+	ctx, err := j.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &lib.ServerStream{ServerStream: ss, Ctx: ctx})
+}
+
+// authenticate reads the token from gRPC metadata and returns a context with the userID and sessionID set.
+func (j *JWTAuth) authenticate(ctx context.Context) (context.Context, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
 		logrus.Info("Authentication failed: missing metadata")
@@ -59,12 +131,29 @@ func (j *JWTAuth) GRPCJWTAuth(ctx context.Context, req interface{}, info *grpc.U
 		return nil, status.Errorf(codes.Unauthenticated, "token not found")
 	}
 
-	userID, err := j.jwtManager.GetUserID(c[0])
+	userID, sessionID, err := j.jwtManager.GetSession(c[0])
 	if err != nil {
 		logrus.Info("Authentication failed: unable to get userID from token", slog.String("error", err.Error()))
 		return nil, status.Errorf(codes.Unauthenticated, "authentification by UserID failed")
 	}
+
+	if sessionID == "" {
+		logrus.Info("Authentication failed: token has no session")
+		return nil, status.Errorf(codes.Unauthenticated, "authentification by UserID failed")
+	}
+
+	revoked, err := j.redis.Client.Exists(ctx, cache.RevokedSessionKey(sessionID)).Result()
+	if err != nil {
+		logrus.WithError(err).Error("Authentication failed: unable to check session revocation")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	if revoked != 0 {
+		logrus.Infof("Authentication failed: session %s is revoked", sessionID)
+		return nil, status.Errorf(codes.Unauthenticated, "session is revoked")
+	}
+
 	logrus.Info("Authentication succeeded UserId is: ", userID)
 	ctx = context.WithValue(ctx, model.UserIDKey, userID)
-	return handler(ctx, req)
+	return context.WithValue(ctx, model.SessionIDKey, sessionID), nil
 }
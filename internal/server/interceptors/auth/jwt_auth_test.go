@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	rd "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/cache"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/jwtmanager"
+)
+
+// authMethod is a method that requires authentication.
+const authMethod = "/proto.CredentialsService/GetLoadCredentials"
+
+type JWTAuthTestSuite struct {
+	suite.Suite
+	redis      *miniredis.Miniredis
+	jwtManager *jwtmanager.JWTManager
+	auth       *JWTAuth
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(JWTAuthTestSuite))
+}
+
+func (s *JWTAuthTestSuite) SetupTest() {
+	s.redis = miniredis.RunT(s.T())
+	s.jwtManager = jwtmanager.New("token", "secret", time.Hour)
+	s.auth = New(s.jwtManager, &cache.Redis{Client: rd.NewClient(&rd.Options{Addr: s.redis.Addr()})})
+}
+
+// Test_Authenticate passes the user and the session of a valid token to the handler.
+func (s *JWTAuthTestSuite) Test_Authenticate() {
+	token, err := s.jwtManager.BuildJWTString("user-id", "session-id")
+	require.NoError(s.T(), err)
+
+	var userID, sessionID any
+	_, err = s.auth.GRPCJWTAuth(s.ctx(token), nil, &grpc.UnaryServerInfo{FullMethod: authMethod},
+		func(ctx context.Context, _ any) (any, error) {
+			userID, sessionID = ctx.Value(model.UserIDKey), ctx.Value(model.SessionIDKey)
+			return nil, nil
+		})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "user-id", userID)
+	assert.Equal(s.T(), "session-id", sessionID)
+}
+
+// Test_RevokedSession rejects the access tokens of a revoked session, like the ones of every session
+// after a logout on all devices, while tokens of other sessions still work.
+func (s *JWTAuthTestSuite) Test_RevokedSession() {
+	revoked, err := s.jwtManager.BuildJWTString("user-id", "session-id")
+	require.NoError(s.T(), err)
+	other, err := s.jwtManager.BuildJWTString("user-id", "other-session-id")
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.redis.Set(cache.RevokedSessionKey("session-id"), "1"))
+
+	_, err = s.auth.GRPCJWTAuth(s.ctx(revoked), nil, &grpc.UnaryServerInfo{FullMethod: authMethod}, s.mustNotCall)
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err))
+
+	_, err = s.auth.GRPCJWTAuth(s.ctx(other), nil, &grpc.UnaryServerInfo{FullMethod: authMethod},
+		func(context.Context, any) (any, error) { return nil, nil })
+	assert.NoError(s.T(), err)
+}
+
+// Test_InvalidToken rejects requests without a token, with a token of another secret or without a session.
+func (s *JWTAuthTestSuite) Test_InvalidToken() {
+	foreign, err := jwtmanager.New("token", "other secret", time.Hour).BuildJWTString("user-id", "session-id")
+	require.NoError(s.T(), err)
+	noSession, err := s.jwtManager.BuildJWTString("user-id", "")
+	require.NoError(s.T(), err)
+
+	for name, ctx := range map[string]context.Context{
+		"no token":   metadata.NewIncomingContext(context.Background(), metadata.MD{}),
+		"foreign":    s.ctx(foreign),
+		"no session": s.ctx(noSession),
+	} {
+		_, err = s.auth.GRPCJWTAuth(ctx, nil, &grpc.UnaryServerInfo{FullMethod: authMethod}, s.mustNotCall)
+		assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), name)
+	}
+}
+
+func (s *JWTAuthTestSuite) ctx(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("token", token))
+}
+
+func (s *JWTAuthTestSuite) mustNotCall(context.Context, any) (any, error) {
+	s.Fail("handler must not be called")
+	return nil, nil
+}
@@ -2,6 +2,7 @@ package keyextraction
 
 import (
 	"context"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
 	"github.com/sirupsen/logrus"
 	"time"
@@ -16,27 +17,54 @@ import (
 // Define a map of methods that require user key extraction userKeyExtractorMandatoryMethods.
 var userKeyExtractorMandatoryMethods = map[string]struct{}{
 	"/proto.CreditCardService/PostSaveCreditCard":             {},
+	"/proto.CreditCardService/PostSaveCreditCardBatch":        {},
 	"/proto.CreditCardService/GetLoadCreditCard":              {},
 	"/proto.CreditCardService/GetLoadAllCreditCardDataInfo":   {},
+	"/proto.CreditCardService/PutUpdateCreditCard":            {},
+	"/proto.CreditCardService/DeleteCreditCard":               {},
 	"/proto.TextDataService/PostSaveTextData":                 {},
+	"/proto.TextDataService/PostSaveTextDataBatch":            {},
 	"/proto.TextDataService/GetLoadTextData":                  {},
 	"/proto.TextDataService/GetLoadAllTextDataInfo":           {},
+	"/proto.TextDataService/PutUpdateTextData":                {},
+	"/proto.TextDataService/DeleteTextData":                   {},
 	"/proto.BinaryDataService/PostSaveBinaryData":             {},
 	"/proto.BinaryDataService/GetLoadBinaryData":              {},
 	"/proto.BinaryDataService/GetLoadAllBinaryDataInfo":       {},
+	"/proto.BinaryDataService/PutUpdateBinaryData":            {},
+	"/proto.BinaryDataService/DeleteBinaryData":               {},
+	"/proto.BinaryDataService/PostSaveBinaryDataStream":       {},
+	"/proto.BinaryDataService/GetLoadBinaryDataStream":        {},
 	"/proto.CredentialsService/PostSaveCredentials":           {},
+	"/proto.CredentialsService/PostSaveCredentialsBatch":      {},
 	"/proto.CredentialsService/GetLoadCredentials":            {},
 	"/proto.CredentialsService/GetLoadAllCredentialsDataInfo": {},
+	"/proto.CredentialsService/PutUpdateCredentials":          {},
+	"/proto.CredentialsService/DeleteCredentials":             {},
+	"/proto.CustomDataService/PostSaveCustomData":             {},
+	"/proto.CustomDataService/GetLoadCustomData":              {},
+	"/proto.CustomDataService/GetLoadAllCustomDataInfo":       {},
+	"/proto.CustomDataService/PutUpdateCustomData":            {},
+	"/proto.CustomDataService/DeleteCustomData":               {},
+	"/proto.UserService/PostEnrollTOTP":                       {},
+	"/proto.UserService/PostConfirmTOTP":                      {},
+	"/proto.UserService/PostDisableTOTP":                      {},
+	"/proto.CollectionService/PostCreateCollection":           {},
+	"/proto.CollectionService/PostInviteMember":               {},
+	"/proto.CollectionService/PostSaveCollectionItem":         {},
+	"/proto.CollectionService/GetLoadCollectionItem":          {},
+	"/proto.CollectionService/PutUpdateCollectionItem":        {},
 }
 
-// CryptService interface defines the method for decrypting data with a master key.
+// CryptService interface defines the method for decrypting data with a versioned master key.
 type CryptService interface {
-	DecryptWithMasterKey(data []byte) ([]byte, error)
+	DecryptWithMasterKeyVersion(version int, data []byte) ([]byte, error)
 }
 
-// UserRepository interface defines the method for fetching user keys from a repository.
+// UserRepository interface defines the methods for fetching user keys from a repository.
 type UserRepository interface {
-	SelectKeyByID(ctx context.Context, userID string) ([]byte, error)
+	SelectKeyGenerationByID(ctx context.Context, userID string) (int64, error)
+	SelectKeyByID(ctx context.Context, userID string) (model.UserCryptKey, error)
 }
 
 // UserKeyExtraction struct handles the extraction of user keys.
@@ -62,14 +90,45 @@ func (j *UserKeyExtraction) ExtractUserKey(ctx context.Context, req interface{},
 		return handler(ctx, req)
 	}
 
+	ctx, err := j.withUserKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// ExtractUserKeyStream is the stream counterpart of ExtractUserKey.
+func (j *UserKeyExtraction) ExtractUserKeyStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if _, ok := userKeyExtractorMandatoryMethods[info.FullMethod]; !ok {
+		return handler(srv, ss)
+	}
+
+	ctx, err := j.withUserKey(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &lib.ServerStream{ServerStream: ss, Ctx: ctx})
+}
+
+// withUserKey returns a context with the key of the user from the given context and its generation set.
+// The current generation is read from the database on every request and the key is cached in Redis
+// under it, so a key replaced by a rotation is never served from the cache. Repositories compare the
+// generation with the current one when they write data encrypted with the key.
+func (j *UserKeyExtraction) withUserKey(ctx context.Context) (context.Context, error) {
 	userID, ok := ctx.Value(model.UserIDKey).(string)
 	if !ok {
 		logrus.Error("Unable to extract user key: failed to get user id from context")
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
+	generation, err := j.userRepo.SelectKeyGenerationByID(ctx, userID)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to extract user key: failed to get user key generation from db")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
 	var key []byte
-	key, err := j.redis.Client.Get(ctx, userID).Bytes()
+	key, err = j.redis.Client.Get(ctx, cache.UserKeyKey(userID, generation)).Bytes()
 	if err != nil {
 		logrus.WithError(err).Error("Unable to extract user key: failed to get user key from redis")
 		cryptKey, err := j.userRepo.SelectKeyByID(ctx, userID)
@@ -78,17 +137,19 @@ func (j *UserKeyExtraction) ExtractUserKey(ctx context.Context, req interface{},
 			return nil, status.Error(codes.Internal, "internal error")
 		}
 
-		key, err = j.cryptService.DecryptWithMasterKey(cryptKey)
+		key, err = j.cryptService.DecryptWithMasterKeyVersion(cryptKey.Version, cryptKey.CryptKey)
 		if err != nil {
 			logrus.WithError(err).Error("Unable to extract user key: failed to decrypt user key")
 			return nil, status.Error(codes.Internal, "internal error")
 		}
-		st := j.redis.Client.Set(ctx, userID, key, 24*time.Hour)
+		generation = cryptKey.Generation
+
+		st := j.redis.Client.Set(ctx, cache.UserKeyKey(userID, generation), key, 24*time.Hour)
 		if st.Err() != nil {
 			return nil, status.Error(codes.Internal, "internal error")
 		}
 	}
 
-	ctx = context.WithValue(ctx, model.UserKey, key)
-	return handler(ctx, req)
+	ctx = context.WithValue(ctx, model.UserKeyGenerationKey, generation)
+	return context.WithValue(ctx, model.UserKey, key), nil
 }
@@ -0,0 +1,89 @@
+package keyrotation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// UserKeyRepository interface defines methods for batch access to wrapped user keys.
+type UserKeyRepository interface {
+	SelectKeysByVersionNot(ctx context.Context, version int, afterID string, limit int) ([]model.UserCryptKey, error)
+	UpdateKey(ctx context.Context, key model.UserCryptKey, oldVersion int) error
+}
+
+// MasterKeyCryptService interface defines methods for re-wrapping user keys with the current master key.
+type MasterKeyCryptService interface {
+	RewrapWithMasterKey(version int, data []byte) ([]byte, error)
+	Version() int
+}
+
+// RewrapResult holds the counters of a master key re-wrap run.
+type RewrapResult struct {
+	Rewrapped int // Keys re-wrapped with the current master key version
+	Skipped   int // Keys changed or deleted concurrently
+	Failed    int // Keys that could not be unwrapped
+}
+
+// MasterKeyRewrapper re-wraps user keys under the current master key version.
+type MasterKeyRewrapper struct {
+	repository UserKeyRepository     // Repository of wrapped user keys
+	crypt      MasterKeyCryptService // Crypt service holding the master keyring
+}
+
+// NewMasterKeyRewrapper creates a new instance of MasterKeyRewrapper.
+func NewMasterKeyRewrapper(repository UserKeyRepository, crypt MasterKeyCryptService) *MasterKeyRewrapper {
+	return &MasterKeyRewrapper{
+		repository: repository,
+		crypt:      crypt,
+	}
+}
+
+// Rewrap walks over all users in batches and re-wraps every key not wrapped with the current master key version.
+// Every key is updated on its own only if its version is unchanged, so running servers that
+// know both versions keep working during the run.
+func (r *MasterKeyRewrapper) Rewrap(ctx context.Context, batchSize int) (RewrapResult, error) {
+	var result RewrapResult
+	current := r.crypt.Version()
+	afterID := ""
+
+	for {
+		keys, err := r.repository.SelectKeysByVersionNot(ctx, current, afterID, batchSize)
+		if err != nil {
+			return result, fmt.Errorf("select keys: %w", err)
+		}
+		if len(keys) == 0 {
+			return result, nil
+		}
+
+		for _, key := range keys {
+			cryptKey, err := r.crypt.RewrapWithMasterKey(key.Version, key.CryptKey)
+			if err != nil {
+				logrus.WithError(err).Errorf("Unable to rewrap key of user %s", key.UserID)
+				result.Failed++
+				continue
+			}
+
+			err = r.repository.UpdateKey(ctx, model.UserCryptKey{
+				UserID:   key.UserID,
+				CryptKey: cryptKey,
+				Version:  current,
+			}, key.Version)
+			if errors.Is(err, cerrors.ErrUserNotFound) {
+				result.Skipped++
+				continue
+			}
+			if err != nil {
+				return result, fmt.Errorf("update key: %w", err)
+			}
+			result.Rewrapped++
+		}
+
+		afterID = keys[len(keys)-1].UserID
+	}
+}
@@ -0,0 +1,147 @@
+package keyrotation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/cache"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// UserKeyRotationRepository interface defines methods for rotating user keys in the storage.
+type UserKeyRotationRepository interface {
+	SelectKeyByID(ctx context.Context, userID string) (model.UserCryptKey, error)
+	SelectIDsByKeyRotatedBefore(ctx context.Context, before time.Time, afterID string, limit int) ([]string, error)
+	RotateKey(ctx context.Context, key model.UserCryptKey, reencrypt func(data []byte) ([]byte, error), batchSize int) (int, error)
+}
+
+// UserKeyCryptService interface defines cryptographic methods required for user key rotation.
+type UserKeyCryptService interface {
+	EncryptWithMasterKey(data []byte) ([]byte, error)
+	DecryptWithMasterKeyVersion(version int, data []byte) ([]byte, error)
+	GenerateKey() ([]byte, error)
+	Encrypt(key, data []byte) ([]byte, error)
+	Decrypt(key, data []byte) ([]byte, error)
+	Version() int
+}
+
+// UserKeyRotator generates new per-user keys and re-encrypts user data with them.
+type UserKeyRotator struct {
+	repository UserKeyRotationRepository // Repository of users and their data
+	crypt      UserKeyCryptService       // Crypt service for keys and data
+	redis      *cache.Redis              // Redis client holding cached user keys
+	batchSize  int                       // Number of data rows re-encrypted per batch
+}
+
+// NewUserKeyRotator creates a new instance of UserKeyRotator.
+func NewUserKeyRotator(repository UserKeyRotationRepository, crypt UserKeyCryptService, redis *cache.Redis, batchSize int) *UserKeyRotator {
+	return &UserKeyRotator{
+		repository: repository,
+		crypt:      crypt,
+		redis:      redis,
+		batchSize:  batchSize,
+	}
+}
+
+// Rotate replaces the key of the user with a new one and re-encrypts all server side encrypted user data.
+// The new key gets the next generation, so requests look it up under a new Redis key, and the replaced
+// key is removed from Redis. It returns the number of re-encrypted data rows.
+func (r *UserKeyRotator) Rotate(ctx context.Context, userID string) (int, error) {
+	oldCryptKey, err := r.repository.SelectKeyByID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("select key: %w", err)
+	}
+
+	oldKey, err := r.crypt.DecryptWithMasterKeyVersion(oldCryptKey.Version, oldCryptKey.CryptKey)
+	if err != nil {
+		return 0, fmt.Errorf("decrypt with master key: %w", err)
+	}
+
+	newKey, err := r.crypt.GenerateKey()
+	if err != nil {
+		return 0, fmt.Errorf("generate key: %w", err)
+	}
+
+	newCryptKey, err := r.crypt.EncryptWithMasterKey(newKey)
+	if err != nil {
+		return 0, fmt.Errorf("encrypt with master key: %w", err)
+	}
+
+	reencrypt := func(data []byte) ([]byte, error) {
+		dec, err := r.crypt.Decrypt(oldKey, data)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt: %w", err)
+		}
+		return r.crypt.Encrypt(newKey, dec)
+	}
+
+	count, err := r.repository.RotateKey(ctx, model.UserCryptKey{
+		UserID:     userID,
+		CryptKey:   newCryptKey,
+		Version:    r.crypt.Version(),
+		Generation: oldCryptKey.Generation,
+	}, reencrypt, r.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("rotate key: %w", err)
+	}
+
+	if err = r.redis.Client.Del(ctx, cache.UserKeyKey(userID, oldCryptKey.Generation)).Err(); err != nil {
+		return count, fmt.Errorf("redis del: %w", err)
+	}
+
+	return count, nil
+}
+
+// RotateExpired rotates the keys of all users whose key is older than maxAge.
+// A failed rotation is logged and does not stop the others. It returns the number of rotated keys.
+func (r *UserKeyRotator) RotateExpired(ctx context.Context, maxAge time.Duration) (int, error) {
+	before := time.Now().Add(-maxAge)
+	rotated := 0
+	afterID := ""
+
+	for {
+		ids, err := r.repository.SelectIDsByKeyRotatedBefore(ctx, before, afterID, r.batchSize)
+		if err != nil {
+			return rotated, fmt.Errorf("select users: %w", err)
+		}
+		if len(ids) == 0 {
+			return rotated, nil
+		}
+
+		for _, id := range ids {
+			count, err := r.Rotate(ctx, id)
+			if err != nil {
+				logrus.WithError(err).Errorf("Unable to rotate key of user %s", id)
+				continue
+			}
+			logrus.Infof("Rotated key of user %s, re-encrypted %d records", id, count)
+			rotated++
+		}
+
+		afterID = ids[len(ids)-1]
+	}
+}
+
+// Run rotates expired user keys every interval until the context is canceled.
+func (r *UserKeyRotator) Run(ctx context.Context, interval, maxAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rotated, err := r.RotateExpired(ctx, maxAge)
+			if err != nil {
+				logrus.WithError(err).Error("Unable to rotate expired user keys")
+			}
+			if rotated != 0 {
+				logrus.Infof("Rotated %d expired user keys", rotated)
+			}
+		}
+	}
+}
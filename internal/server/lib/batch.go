@@ -0,0 +1,30 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MaxBatchSize is the largest number of items saved by one batch call.
+const MaxBatchSize = 1000
+
+// FieldViolation is a violation of a field of an item of a batch.
+type FieldViolation struct {
+	Field       string // Path of the field in the request, like credentials[3].Password
+	Description string // Description of the violation
+}
+
+// BatchViolations returns the violations of the item at the index of a batch sorted by the field.
+// The fields of the validation report are prefixed with the path of the item in the request.
+func BatchViolations(collection string, index int, report map[string]string) []FieldViolation {
+	violations := make([]FieldViolation, 0, len(report))
+	for field, description := range report {
+		violations = append(violations, FieldViolation{
+			Field:       fmt.Sprintf("%s[%d].%s", collection, index, field),
+			Description: description,
+		})
+	}
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Field < violations[j].Field })
+
+	return violations
+}
@@ -0,0 +1,22 @@
+package lib
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// NewDataID returns the ID of a new data entry. The ID chosen by the client is kept, since the client side
+// encrypted payload is bound to it, otherwise a new one is generated.
+func NewDataID(clientID string) (string, error) {
+	if clientID != "" {
+		return clientID, nil
+	}
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", fmt.Errorf("new uuid: %w", err)
+	}
+
+	return id.String(), nil
+}
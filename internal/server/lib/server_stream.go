@@ -0,0 +1,19 @@
+package lib
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// ServerStream wraps grpc.ServerStream to replace its context,
+// so that stream interceptors can pass values down to the handler.
+type ServerStream struct {
+	grpc.ServerStream
+	Ctx context.Context // Context returned to the handler instead of the original one
+}
+
+// Context returns the context of the wrapped stream.
+func (s *ServerStream) Context() context.Context {
+	return s.Ctx
+}
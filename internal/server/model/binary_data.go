@@ -3,10 +3,30 @@ package model
 import "time"
 
 type BinaryDataPostRequest struct {
+	ID        string `validate:"omitempty,uuid"`
 	Name      string `validate:"required"`
 	Extension string `validate:"required"`
 	Data      []byte `validate:"required"`
 	MetaData  string
+	CryptData []byte
+}
+
+type BinaryDataPutRequest struct {
+	ID        string `validate:"required"`
+	Name      string `validate:"required"`
+	Extension string `validate:"required"`
+	Data      []byte `validate:"required"`
+	MetaData  string
+	CryptData []byte
+	Revision  int64
+}
+
+type BinaryDataStreamHeader struct {
+	ID        string `validate:"omitempty,uuid"`
+	Name      string `validate:"required"`
+	Extension string `validate:"required"`
+	MetaData  string
+	CryptData []byte
 }
 
 type BinaryData struct {
@@ -17,12 +37,15 @@ type BinaryData struct {
 	Data      []byte
 	MetaData  string
 	CreatedAt time.Time
+	CryptData []byte
+	Revision  int64
 }
 
 type BinaryCryptData struct {
 	Name      string
 	Extension string
 	Data      []byte
+	ChunkKey  []byte `json:",omitempty"`
 }
 
 type BinaryDataDB struct {
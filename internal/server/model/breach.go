@@ -0,0 +1,12 @@
+package model
+
+// BreachRangeRequest holds the first 5 hex characters of the SHA-1 hash of a checked password.
+type BreachRangeRequest struct {
+	Prefix string `validate:"len=5,hexadecimal"`
+}
+
+// BreachSuffix is a hash of a breach list range with the number of times it was seen in breaches.
+type BreachSuffix struct {
+	Suffix string
+	Count  int64
+}
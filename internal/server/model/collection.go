@@ -0,0 +1,95 @@
+package model
+
+import "time"
+
+// Roles of the members of collections, each role allows everything the previous one does.
+const (
+	RoleRead   = "read"   // Read the items
+	RoleWrite  = "write"  // Save, update and delete the items
+	RoleManage = "manage" // Invite and revoke members
+)
+
+// Statuses of the members of collections.
+const (
+	MemberInvited = "invited" // The user was invited and has not accepted yet
+	MemberActive  = "active"  // The user accepted the invitation
+)
+
+// roleRanks orders the roles of the members of collections.
+var roleRanks = map[string]int{RoleRead: 1, RoleWrite: 2, RoleManage: 3}
+
+// RoleAllows reports whether the role grants the required role.
+func RoleAllows(role, required string) bool {
+	return roleRanks[role] != 0 && roleRanks[role] >= roleRanks[required]
+}
+
+// Collection is a shared vault owned by several users. Its items are encrypted with the collection key,
+// which is stored wrapped with the key of every member.
+type Collection struct {
+	ID        string    `db:"id"`
+	Name      string    `db:"name"`
+	Role      string    `db:"role"`   // Role of the requesting user
+	Status    string    `db:"status"` // Membership status of the requesting user
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// CollectionMember is the membership of a user in a collection.
+type CollectionMember struct {
+	CollectionID string    `db:"collection_id"`
+	UserID       string    `db:"user_id"`
+	Login        string    `db:"login"`
+	Role         string    `db:"role"`
+	Status       string    `db:"status"`
+	CryptKey     []byte    `db:"crypt_key"` // Collection key wrapped with the key of the member
+	InvitedBy    string    `db:"invited_by"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+type CollectionRequest struct {
+	Name string `validate:"required,max=100"`
+}
+
+type CollectionInviteRequest struct {
+	CollectionID string `validate:"required"`
+	Login        string `validate:"required"`
+	Role         string `validate:"required,oneof=read write manage"`
+}
+
+type CollectionMemberRequest struct {
+	CollectionID string `validate:"required"`
+	Login        string // Login of the member, the requesting user if empty
+}
+
+type CollectionItemPostRequest struct {
+	CollectionID string `validate:"required"`
+	DataType     string `validate:"required,oneof=credit_card text_data credentials"`
+	Data         []byte `validate:"required,max=65536"` // Payload of the item serialized by the client
+	MetaData     string
+}
+
+type CollectionItemPutRequest struct {
+	ID           string `validate:"required"`
+	CollectionID string `validate:"required"`
+	Data         []byte `validate:"required,max=65536"`
+	MetaData     string
+	Revision     int64
+}
+
+type CollectionItemRequest struct {
+	ID           string `validate:"required"`
+	CollectionID string `validate:"required"`
+}
+
+// CollectionItem is an item of a collection. Data holds the payload encrypted with the collection key
+// in the storage and the decrypted payload elsewhere.
+type CollectionItem struct {
+	ID           string    `db:"id"`
+	CollectionID string    `db:"collection_id"`
+	Type         string    `db:"type"`
+	Data         []byte    `db:"data"`
+	MetaData     string    `db:"metadata"`
+	CreatedBy    string    `db:"created_by"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+	Revision     int64     `db:"revision"`
+}
@@ -4,6 +4,9 @@ package model
 type CTXKey string
 
 const (
-	UserIDKey CTXKey = "userID"  // UserIDKey is the specific key used in the context to store user ID.
-	UserKey   CTXKey = "userKey" // UserKey is the specific key used in the context to store user key.
+	UserIDKey    CTXKey = "userID"    // UserIDKey is the specific key used in the context to store user ID.
+	UserKey      CTXKey = "userKey"   // UserKey is the specific key used in the context to store user key.
+	SessionIDKey CTXKey = "sessionID" // SessionIDKey is the specific key used in the context to store session ID.
+
+	UserKeyGenerationKey CTXKey = "userKeyGeneration" // UserKeyGenerationKey is the key used in the context to store the generation of the user key.
 )
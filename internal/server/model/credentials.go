@@ -3,9 +3,22 @@ package model
 import "time"
 
 type CredentialsPostRequest struct {
-	Login    string `validate:"required"`
-	Password string `validate:"required"`
-	MetaData string
+	ID        string `validate:"omitempty,uuid"`
+	Login     string `validate:"required"`
+	Password  string `validate:"required"`
+	TOTP      string `validate:"omitempty,totp"`
+	MetaData  string
+	CryptData []byte
+}
+
+type CredentialsPutRequest struct {
+	ID        string `validate:"required"`
+	Login     string `validate:"required"`
+	Password  string `validate:"required"`
+	TOTP      string `validate:"omitempty,totp"`
+	MetaData  string
+	CryptData []byte
+	Revision  int64
 }
 
 type Credentials struct {
@@ -13,13 +26,17 @@ type Credentials struct {
 	OwnerID   string
 	Login     string
 	Password  string
+	TOTP      string
 	MetaData  string
 	CreatedAt time.Time
+	CryptData []byte
+	Revision  int64
 }
 
 type CredentialsCryptData struct {
 	Login    string
 	Password string
+	TOTP     string // Raw base32 secret or otpauth URI of the two-factor codes of the account
 }
 
 type CredentialsDB struct {
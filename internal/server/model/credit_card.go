@@ -3,12 +3,30 @@ package model
 import "time"
 
 type CreditCardPostRequest struct {
-	Number    string `validate:"required,card_number"`
-	OwnerName string `validate:"required,owner"`
-	ExpiresAt string `validate:"expires_at"`
-	CVV       string `validate:"cvv"`
-	PinCode   string `validate:"pin"`
-	MetaData  string `validate:"meta_data"`
+	ID             string `validate:"omitempty,uuid"` // Chosen by the client to bind the client side encrypted payload, generated if empty
+	Number         string `validate:"required,card_number"`
+	OwnerName      string `validate:"required,owner"`
+	ExpiresAt      string `validate:"expires_at"`
+	CVV            string `validate:"cvv"`
+	PinCode        string `validate:"pin"`
+	MetaData       string
+	CryptData      []byte
+	ExpiryReminder bool   // Notify the owner before the card expires
+	ExpiryMonth    string `validate:"omitempty,expiry_month"` // MM-YYYY, required for reminders of client side encrypted cards
+}
+
+type CreditCardPutRequest struct {
+	ID             string `validate:"required"`
+	Number         string `validate:"required,card_number"`
+	OwnerName      string `validate:"required,owner"`
+	ExpiresAt      string `validate:"expires_at"`
+	CVV            string `validate:"cvv"`
+	PinCode        string `validate:"pin"`
+	MetaData       string
+	CryptData      []byte
+	Revision       int64
+	ExpiryReminder bool   // Notify the owner before the card expires, false turns the reminder off
+	ExpiryMonth    string `validate:"omitempty,expiry_month"` // MM-YYYY, required for reminders of client side encrypted cards
 }
 
 type CreditCard struct {
@@ -21,6 +39,10 @@ type CreditCard struct {
 	PinCode   string
 	MetaData  string
 	CreatedAt time.Time
+	CryptData []byte
+	Revision  int64
+
+	ExpiryReminder bool
 }
 
 type CreditCardCryptData struct {
@@ -0,0 +1,82 @@
+package model
+
+import "time"
+
+// Types of the fields of item templates.
+const (
+	FieldTypeText   = "text"   // Any text
+	FieldTypeNumber = "number" // Integer or decimal number
+	FieldTypeURL    = "url"    // Absolute URL with a scheme and a host
+	FieldTypeEmail  = "email"  // Email address
+	FieldTypeDate   = "date"   // Date as DD-MM-YYYY
+)
+
+// TemplateField describes a field of the items of a template.
+type TemplateField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	Secret   bool   `json:"secret"` // Secret values are masked by the client until revealed
+}
+
+// TemplateRequest is a request to save or update an item template.
+type TemplateRequest struct {
+	ID     string
+	Name   string `validate:"required,max=100"`
+	Fields []TemplateField
+}
+
+// Template is a named schema of the fields of custom data items defined by a user.
+// Templates are stored unencrypted, the names of the fields must not hold secrets.
+type Template struct {
+	ID        string          `db:"id"`
+	OwnerID   string          `db:"owner_id"`
+	Name      string          `db:"name"`
+	Fields    []TemplateField `db:"fields"`
+	CreatedAt time.Time       `db:"created_at"`
+	UpdatedAt time.Time       `db:"updated_at"`
+}
+
+// Field returns the field of the template with the name.
+func (t Template) Field(name string) (TemplateField, bool) {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return TemplateField{}, false
+}
+
+type CustomDataPostRequest struct {
+	ID         string `validate:"omitempty,uuid"`
+	TemplateID string `validate:"required"`
+	Fields     map[string]string
+	MetaData   string
+	CryptData  []byte
+}
+
+type CustomDataPutRequest struct {
+	ID         string `validate:"required"`
+	TemplateID string `validate:"required"`
+	Fields     map[string]string
+	MetaData   string
+	CryptData  []byte
+	Revision   int64
+}
+
+type CustomData struct {
+	ID         string
+	OwnerID    string
+	TemplateID string
+	Fields     map[string]string
+	MetaData   string
+	CreatedAt  time.Time
+	CryptData  []byte
+	Revision   int64
+}
+
+type CustomCryptData struct {
+	TemplateID string
+	Fields     map[string]string
+}
@@ -3,10 +3,53 @@ package model
 import "time"
 
 type Data struct {
+	ID              string    `db:"id"`
+	OwnerID         string    `db:"owner_id"`
+	Type            string    `db:"type"`
+	Data            []byte    `db:"data"`
+	MetaData        string    `db:"meta_data"`
+	CreatedAt       time.Time `db:"created_at"`
+	ClientEncrypted bool      `db:"client_encrypted"`
+	Revision        int64     `db:"revision"`
+	UpdatedAt       time.Time `db:"updated_at"`
+}
+
+// Change describes a created, updated or deleted data entry in the change feed of a user.
+type Change struct {
+	ID        string    `db:"id"`
+	DataType  string    `db:"type"`
+	MetaData  string    `db:"metadata"`
+	Revision  int64     `db:"revision"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+	Deleted   bool      `db:"deleted"`
+	Cursor    int64     `db:"change_seq"`
+}
+
+type DataDeleteRequest struct {
+	ID string `validate:"required"`
+}
+
+// SearchFilter describes which data entries of a user are searched for. Empty fields don't filter.
+type SearchFilter struct {
+	Query       string     // Case-insensitive substring of the metadata
+	Types       []string   // Data types
+	Tags        []string   // Tags the entries must all have, written as #tag in the metadata
+	CreatedFrom *time.Time // Inclusive lower bound of the creation time
+	CreatedTo   *time.Time // Exclusive upper bound of the creation time
+	UpdatedFrom *time.Time // Inclusive lower bound of the last update time
+	UpdatedTo   *time.Time // Exclusive upper bound of the last update time
+	Limit       int
+	Offset      int
+}
+
+// Item is a data entry found by a search, without its payload.
+type Item struct {
 	ID        string    `db:"id"`
-	OwnerID   string    `db:"owner_id"`
-	Type      string    `db:"type"`
-	Data      []byte    `db:"data"`
-	MetaData  string    `db:"meta_data"`
+	DataType  string    `db:"type"`
+	MetaData  string    `db:"metadata"`
+	Tags      []string  `db:"tags"`
+	Revision  int64     `db:"revision"`
 	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
 }
@@ -7,4 +7,26 @@ type DataInfo struct {
 	DataType  string    `json:"data_type"`
 	MetaData  string    `json:"meta_data"`
 	CreatedAt time.Time `json:"created_at"`
+	Revision  int64     `json:"revision"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PageRequest describes a page of a data listing. An empty page token requests the first page.
+type PageRequest struct {
+	PageSize   int `validate:"gte=0,lte=500"`
+	PageToken  string
+	SortBy     string `validate:"omitempty,oneof=created_at metadata"`
+	Descending bool
+}
+
+// PageCursor is the position in a sorted data listing after which the next page starts.
+type PageCursor struct {
+	Value string // Value of the sort field of the last entry of the previous page
+	ID    string // ID of the last entry of the previous page
+}
+
+// DataInfoPage is a page of a data listing.
+type DataInfoPage struct {
+	Infos         []DataInfo
+	NextPageToken string // Token of the next page, empty on the last page
 }
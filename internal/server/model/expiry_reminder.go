@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// ExpiryReminder is the opt-in of a credit card to a notification before it expires. The expiry month is stored
+// apart from the encrypted card, so expiring cards are found without decrypting them.
+type ExpiryReminder struct {
+	DataID      string
+	OwnerID     string
+	ExpiryMonth time.Time // First day of the month the card expires in, the card is valid until the end of the month
+}
+
+// ExpiryNotification tells the owner of a credit card that the card expires soon.
+// It holds the metadata of the card but none of its encrypted fields.
+type ExpiryNotification struct {
+	DataID      string    `db:"data_id"`
+	OwnerID     string    `db:"owner_id"`
+	Login       string    `db:"login"`
+	MetaData    string    `db:"metadata"`
+	ExpiryMonth time.Time `db:"expiry_month"`
+}
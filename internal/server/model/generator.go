@@ -0,0 +1,29 @@
+package model
+
+// Modes of the password generator.
+const (
+	GeneratorModePassword   = "password"   // Random characters
+	GeneratorModePassphrase = "passphrase" // Random words of a wordlist
+)
+
+// GeneratePasswordRequest holds the options of a generated password. Zero options get the defaults:
+// a password of 20 characters of all classes or a passphrase of 6 words separated with dashes.
+type GeneratePasswordRequest struct {
+	Mode             string `validate:"omitempty,oneof=password passphrase"`
+	Length           int    `validate:"omitempty,gte=8,lte=128"`
+	Lower            bool
+	Upper            bool
+	Digits           bool
+	Symbols          bool
+	ExcludeAmbiguous bool
+	Words            int `validate:"omitempty,gte=3,lte=20"`
+	Separator        string
+	Capitalize       bool
+	Number           bool
+}
+
+// GeneratedPassword is a generated password with the estimate of its entropy in bits.
+type GeneratedPassword struct {
+	Password string
+	Entropy  float64
+}
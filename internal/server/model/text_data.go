@@ -3,8 +3,18 @@ package model
 import "time"
 
 type TextDataPostRequest struct {
-	Text     string `validate:"required"`
-	MetaData string
+	ID        string `validate:"omitempty,uuid"`
+	Text      string `validate:"required"`
+	MetaData  string
+	CryptData []byte
+}
+
+type TextDataPutRequest struct {
+	ID        string `validate:"required"`
+	Text      string `validate:"required"`
+	MetaData  string
+	CryptData []byte
+	Revision  int64
 }
 
 type TextData struct {
@@ -13,6 +23,8 @@ type TextData struct {
 	Text      string
 	MetaData  string
 	CreatedAt time.Time
+	CryptData []byte
+	Revision  int64
 }
 
 type TextCryptData struct {
@@ -3,19 +3,80 @@ package model
 import "time"
 
 type UserRegisterRequest struct {
-	Login    string `validate:"email"`
-	Password string `validate:"required"`
+	Login                string `validate:"email"`
+	Password             string `validate:"required"`
+	ClientSideEncryption bool
+	WrappedDataKey       []byte `validate:"required_if=ClientSideEncryption true"`
 }
 
 type UserLoginRequest struct {
 	Login    string `validate:"email"`
 	Password string `validate:"required"`
+	TOTPCode string // Second factor code or recovery code, required when two-factor authentication is enabled
+}
+
+type UserLoginResponse struct {
+	Token          string
+	RefreshToken   string
+	WrappedDataKey []byte
+}
+
+type UserRefreshRequest struct {
+	RefreshToken string `validate:"required"`
+}
+
+// TokenPair holds a short-lived access token and the refresh token of the same session.
+type TokenPair struct {
+	Token        string
+	RefreshToken string
+}
+
+// Session is a login session of a user on a device. Only a hash of the refresh token is stored.
+type Session struct {
+	ID               string     `db:"id"`
+	UserID           string     `db:"user_id"`
+	RefreshTokenHash []byte     `db:"refresh_token_hash"`
+	CreatedAt        time.Time  `db:"created_at"`
+	ExpiresAt        time.Time  `db:"expires_at"`
+	RevokedAt        *time.Time `db:"revoked_at"`
+}
+
+// UserTOTPCodeRequest holds a second factor code or a recovery code.
+type UserTOTPCodeRequest struct {
+	Code string `validate:"required"`
+}
+
+// TOTPEnrollment holds what the user needs to set up an authenticator app, shown only once.
+type TOTPEnrollment struct {
+	URI           string   // otpauth URI to import into an authenticator app
+	Secret        string   // Base32 secret to enter manually
+	RecoveryCodes []string // One-time codes to log in without the authenticator app
+}
+
+// UserTOTP is the second factor of a user. The secret is encrypted with the user key.
+type UserTOTP struct {
+	UserID       string    `db:"user_id"`
+	Secret       []byte    `db:"secret"`
+	Enabled      bool      `db:"enabled"`
+	LastUsedStep int64     `db:"last_used_step"`
+	CreatedAt    time.Time `db:"created_at"`
 }
 
 type User struct {
-	ID        string    `db:"id"`
-	Login     string    `db:"login"`
-	Password  []byte    `db:"password"`
-	CryptKey  []byte    `db:"crypt_key"`
-	CreatedAt time.Time `db:"created_at"`
+	ID                   string    `db:"id"`
+	Login                string    `db:"login"`
+	Password             []byte    `db:"password"`
+	CryptKey             []byte    `db:"crypt_key"`
+	CreatedAt            time.Time `db:"created_at"`
+	ClientSideEncryption bool      `db:"client_side_encryption"`
+	WrappedDataKey       []byte    `db:"wrapped_data_key"`
+	CryptKeyVersion      int       `db:"crypt_key_version"`
+	CryptKeyGeneration   int64     `db:"crypt_key_generation"` // Incremented whenever the user key is rotated
+}
+
+type UserCryptKey struct {
+	UserID     string `db:"id"`
+	CryptKey   []byte `db:"crypt_key"`
+	Version    int    `db:"crypt_key_version"`    // Version of the master key the user key is wrapped with
+	Generation int64  `db:"crypt_key_generation"` // Generation of the user key itself, unchanged by re-wrapping
 }
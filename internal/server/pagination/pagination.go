@@ -0,0 +1,123 @@
+// Package pagination implements cursor based pagination of data listings.
+// A page token is an opaque string holding the sort order of the listing and the sort key
+// of the last entry of the previous page, so pages stay stable while entries are added or removed.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+const (
+	SortCreatedAt   = "created_at" // Sort by the creation time
+	SortMetadata    = "metadata"   // Sort by the metadata
+	DefaultPageSize = 50           // Number of entries in a page when the client doesn't set a page size
+)
+
+// token is the content of a page token.
+type token struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	ID         string `json:"id"`
+}
+
+// Normalize fills the default page size and sort order of the page request.
+func Normalize(page model.PageRequest) model.PageRequest {
+	if page.PageSize <= 0 {
+		page.PageSize = DefaultPageSize
+	}
+	if page.SortBy == "" {
+		page.SortBy = SortCreatedAt
+	}
+
+	return page
+}
+
+// Validate validates the page request of a data listing with the validator.
+// It checks the page size and the sort field and returns a map of validation errors if any exist.
+func Validate(v *validator.Validate, page *model.PageRequest) (map[string]string, bool) {
+	err := v.Struct(page)
+	if err == nil {
+		return nil, true
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+
+	report := make(map[string]string)
+	for _, validationErr := range validationErrors {
+		switch validationErr.Tag() {
+		case "gte", "lte":
+			report[validationErr.Field()] = "must be between 0 and 500"
+		case "oneof":
+			report[validationErr.Field()] = "must be " + SortCreatedAt + " or " + SortMetadata
+		}
+	}
+
+	return report, false
+}
+
+// DecodeToken returns the cursor of the page token of a normalized page request, nil for the first page.
+// It returns cerrors.ErrInvalidPageToken if the token is malformed or was issued for another sort order.
+func DecodeToken(page model.PageRequest) (*model.PageCursor, error) {
+	if page.PageToken == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(page.PageToken)
+	if err != nil {
+		return nil, fmt.Errorf("decode token: %w", cerrors.ErrInvalidPageToken)
+	}
+
+	var t token
+	if err = json.Unmarshal(raw, &t); err != nil {
+		return nil, fmt.Errorf("unmarshal token: %w", cerrors.ErrInvalidPageToken)
+	}
+
+	if t.SortBy != page.SortBy || t.Descending != page.Descending || t.ID == "" {
+		return nil, fmt.Errorf("token of another listing: %w", cerrors.ErrInvalidPageToken)
+	}
+
+	if t.SortBy == SortCreatedAt {
+		if _, err = time.Parse(time.RFC3339Nano, t.Value); err != nil {
+			return nil, fmt.Errorf("parse token time: %w", cerrors.ErrInvalidPageToken)
+		}
+	}
+
+	return &model.PageCursor{Value: t.Value, ID: t.ID}, nil
+}
+
+// Page cuts the entries loaded with a limit one greater than the page size to the page
+// and returns the token of the next page, empty if there are no more entries.
+func Page(page model.PageRequest, entries []model.Data) ([]model.Data, string) {
+	if len(entries) <= page.PageSize {
+		return entries, ""
+	}
+
+	entries = entries[:page.PageSize]
+	last := entries[len(entries)-1]
+
+	t := token{
+		SortBy:     page.SortBy,
+		Descending: page.Descending,
+		Value:      last.CreatedAt.Format(time.RFC3339Nano),
+		ID:         last.ID,
+	}
+	if page.SortBy == SortMetadata {
+		t.Value = last.MetaData
+	}
+
+	raw, _ := json.Marshal(t) //nolint:errchkjson // token has only string and bool fields
+	return entries, base64.RawURLEncoding.EncodeToString(raw)
+}
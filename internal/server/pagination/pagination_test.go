@@ -0,0 +1,110 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+type PaginationTestSuite struct {
+	suite.Suite
+	entries []model.Data
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(PaginationTestSuite))
+}
+
+func (p *PaginationTestSuite) SetupTest() {
+	createdAt := time.Date(2026, 10, 17, 12, 0, 0, 123456000, time.UTC)
+	p.entries = []model.Data{
+		{ID: "a", MetaData: "alpha", CreatedAt: createdAt},
+		{ID: "b", MetaData: "beta", CreatedAt: createdAt.Add(time.Second)},
+		{ID: "c", MetaData: "gamma", CreatedAt: createdAt.Add(2 * time.Second)},
+	}
+}
+
+func (p *PaginationTestSuite) Test_Normalize() {
+	page := Normalize(model.PageRequest{})
+	assert.Equal(p.T(), DefaultPageSize, page.PageSize)
+	assert.Equal(p.T(), SortCreatedAt, page.SortBy)
+
+	page = Normalize(model.PageRequest{PageSize: 10, SortBy: SortMetadata, Descending: true})
+	assert.Equal(p.T(), model.PageRequest{PageSize: 10, SortBy: SortMetadata, Descending: true}, page)
+}
+
+func (p *PaginationTestSuite) Test_PageRoundTrip() {
+	page := Normalize(model.PageRequest{PageSize: 2})
+
+	entries, next := Page(page, p.entries)
+	assert.Len(p.T(), entries, 2)
+	require.NotEmpty(p.T(), next)
+
+	page.PageToken = next
+	cursor, err := DecodeToken(page)
+	require.NoError(p.T(), err)
+	assert.Equal(p.T(), "b", cursor.ID)
+
+	createdAt, err := time.Parse(time.RFC3339Nano, cursor.Value)
+	require.NoError(p.T(), err)
+	assert.True(p.T(), p.entries[1].CreatedAt.Equal(createdAt))
+
+	page = Normalize(model.PageRequest{PageSize: 2, SortBy: SortMetadata})
+	_, next = Page(page, p.entries)
+	page.PageToken = next
+	cursor, err = DecodeToken(page)
+	require.NoError(p.T(), err)
+	assert.Equal(p.T(), model.PageCursor{Value: "beta", ID: "b"}, *cursor)
+}
+
+func (p *PaginationTestSuite) Test_LastPage() {
+	page := Normalize(model.PageRequest{PageSize: 3})
+
+	entries, next := Page(page, p.entries)
+	assert.Len(p.T(), entries, 3)
+	assert.Empty(p.T(), next)
+
+	cursor, err := DecodeToken(page)
+	require.NoError(p.T(), err)
+	assert.Nil(p.T(), cursor)
+}
+
+func (p *PaginationTestSuite) Test_InvalidToken() {
+	page := Normalize(model.PageRequest{PageSize: 2})
+	_, next := Page(page, p.entries)
+
+	// A token is bound to the sort order of the listing it was issued for
+	other := Normalize(model.PageRequest{PageSize: 2, Descending: true, PageToken: next})
+	_, err := DecodeToken(other)
+	assert.ErrorIs(p.T(), err, cerrors.ErrInvalidPageToken)
+
+	other = Normalize(model.PageRequest{PageSize: 2, SortBy: SortMetadata, PageToken: next})
+	_, err = DecodeToken(other)
+	assert.ErrorIs(p.T(), err, cerrors.ErrInvalidPageToken)
+
+	page.PageToken = "not a token"
+	_, err = DecodeToken(page)
+	assert.ErrorIs(p.T(), err, cerrors.ErrInvalidPageToken)
+}
+
+func (p *PaginationTestSuite) Test_Validate() {
+	v := validator.New()
+
+	report, ok := Validate(v, &model.PageRequest{PageSize: 100, SortBy: SortMetadata})
+	assert.True(p.T(), ok)
+	assert.Nil(p.T(), report)
+
+	report, ok = Validate(v, &model.PageRequest{PageSize: 501, SortBy: "name"})
+	assert.False(p.T(), ok)
+	assert.Equal(p.T(), map[string]string{
+		"PageSize": "must be between 0 and 500",
+		"SortBy":   "must be created_at or metadata",
+	}, report)
+}
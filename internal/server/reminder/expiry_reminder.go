@@ -0,0 +1,93 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// DefaultBatchSize is the number of reminders selected per batch by default.
+const DefaultBatchSize = 100
+
+// ExpiryReminderRepository interface defines methods for access to the expiry reminders of credit cards.
+type ExpiryReminderRepository interface {
+	SelectDueExpiryReminders(ctx context.Context, before time.Time, afterID string, limit int) ([]model.ExpiryNotification, error)
+	MarkExpiryReminderNotified(ctx context.Context, dataID string, expiryMonth time.Time) error
+}
+
+// ExpiryReminder notifies the owners of credit cards that opted in to a reminder before their cards expire.
+type ExpiryReminder struct {
+	repository ExpiryReminderRepository // Repository of expiry reminders
+	notifier   Notifier                 // Channel notifications are delivered through
+	window     time.Duration            // Cards expiring within the window are notified
+	batchSize  int                      // Number of reminders selected per batch
+	now        func() time.Time         // Current time, replaced in tests
+}
+
+// NewExpiryReminder creates a new instance of ExpiryReminder.
+func NewExpiryReminder(repository ExpiryReminderRepository, notifier Notifier, window time.Duration, batchSize int) *ExpiryReminder {
+	return &ExpiryReminder{
+		repository: repository,
+		notifier:   notifier,
+		window:     window,
+		batchSize:  batchSize,
+		now:        time.Now,
+	}
+}
+
+// NotifyExpiring walks over the reminders of cards expiring within the window in batches and notifies their owners.
+// Every card is notified once per expiry month, cards that failed to be notified are retried on the next run.
+// It returns the number of sent notifications.
+func (r *ExpiryReminder) NotifyExpiring(ctx context.Context) (int, error) {
+	before := r.now().Add(r.window)
+	notified := 0
+	afterID := ""
+
+	for {
+		notifications, err := r.repository.SelectDueExpiryReminders(ctx, before, afterID, r.batchSize)
+		if err != nil {
+			return notified, fmt.Errorf("select expiry reminders: %w", err)
+		}
+		if len(notifications) == 0 {
+			return notified, nil
+		}
+
+		for _, n := range notifications {
+			if err = r.notifier.Notify(ctx, n); err != nil {
+				logrus.WithError(err).Errorf("Unable to notify user %s about expiring card %s", n.OwnerID, n.DataID)
+				continue
+			}
+			if err = r.repository.MarkExpiryReminderNotified(ctx, n.DataID, n.ExpiryMonth); err != nil {
+				return notified, fmt.Errorf("mark expiry reminder notified: %w", err)
+			}
+			notified++
+		}
+
+		afterID = notifications[len(notifications)-1].DataID
+	}
+}
+
+// Run notifies the owners of expiring cards every interval until the context is canceled.
+func (r *ExpiryReminder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			notified, err := r.NotifyExpiring(ctx)
+			if err != nil {
+				logrus.WithError(err).Error("Unable to notify about expiring credit cards")
+			}
+			if notified != 0 {
+				logrus.Infof("Sent %d credit card expiry notifications", notified)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package reminder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// fakeRepository keeps expiry reminders in memory and selects them the way the postgres repository does.
+type fakeRepository struct {
+	reminders map[string]model.ExpiryNotification
+	notified  map[string]bool
+}
+
+func (f *fakeRepository) SelectDueExpiryReminders(_ context.Context, before time.Time, afterID string, limit int) ([]model.ExpiryNotification, error) {
+	var due []model.ExpiryNotification
+	for id, n := range f.reminders {
+		if f.notified[id] || id <= afterID || n.ExpiryMonth.AddDate(0, 1, 0).After(before) {
+			continue
+		}
+		due = append(due, n)
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].DataID < due[j].DataID })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (f *fakeRepository) MarkExpiryReminderNotified(_ context.Context, dataID string, expiryMonth time.Time) error {
+	if f.reminders[dataID].ExpiryMonth.Equal(expiryMonth) {
+		f.notified[dataID] = true
+	}
+	return nil
+}
+
+type ExpiryReminderTestSuite struct {
+	suite.Suite
+	repository *fakeRepository
+	now        time.Time
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(ExpiryReminderTestSuite))
+}
+
+func (e *ExpiryReminderTestSuite) SetupTest() {
+	e.now = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	month := func(year int, m time.Month) time.Time { return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC) }
+	e.repository = &fakeRepository{
+		reminders: map[string]model.ExpiryNotification{
+			"a": {DataID: "a", OwnerID: "u1", Login: "one@example.com", MetaData: "visa", ExpiryMonth: month(2026, 10)},
+			"b": {DataID: "b", OwnerID: "u2", Login: "two", MetaData: "mastercard", ExpiryMonth: month(2026, 11)},
+			"c": {DataID: "c", OwnerID: "u1", Login: "one@example.com", MetaData: "mir", ExpiryMonth: month(2027, 3)},
+			"d": {DataID: "d", OwnerID: "u3", Login: "three", MetaData: "old", ExpiryMonth: month(2026, 9)},
+		},
+		notified: map[string]bool{},
+	}
+}
+
+func (e *ExpiryReminderTestSuite) newReminder(notifier Notifier, window time.Duration) *ExpiryReminder {
+	r := NewExpiryReminder(e.repository, notifier, window, 2)
+	r.now = func() time.Time { return e.now }
+	return r
+}
+
+func (e *ExpiryReminderTestSuite) Test_NotifyExpiring() {
+	notifier := NewFakeNotifier(nil)
+	r := e.newReminder(notifier, 30*24*time.Hour)
+
+	notified, err := r.NotifyExpiring(context.Background())
+	require.NoError(e.T(), err)
+	assert.Equal(e.T(), 2, notified)
+
+	var ids []string
+	for _, n := range notifier.Notifications() {
+		ids = append(ids, n.DataID)
+	}
+	assert.Equal(e.T(), []string{"a", "d"}, ids)
+
+	// Every card is notified once per expiry month.
+	notified, err = r.NotifyExpiring(context.Background())
+	require.NoError(e.T(), err)
+	assert.Equal(e.T(), 0, notified)
+
+	// A wider window reaches the cards expiring at the end of next month.
+	notified, err = e.newReminder(notifier, 60*24*time.Hour).NotifyExpiring(context.Background())
+	require.NoError(e.T(), err)
+	assert.Equal(e.T(), 1, notified)
+	assert.Len(e.T(), notifier.Notifications(), 3)
+}
+
+func (e *ExpiryReminderTestSuite) Test_NotifyExpiringFailed() {
+	r := e.newReminder(NewFakeNotifier(errors.New("unavailable")), 30*24*time.Hour)
+
+	notified, err := r.NotifyExpiring(context.Background())
+	require.NoError(e.T(), err)
+	assert.Equal(e.T(), 0, notified)
+	assert.Empty(e.T(), e.repository.notified)
+
+	// Cards that failed to be notified are retried on the next run.
+	notifier := NewFakeNotifier(nil)
+	notified, err = e.newReminder(notifier, 30*24*time.Hour).NotifyExpiring(context.Background())
+	require.NoError(e.T(), err)
+	assert.Equal(e.T(), 2, notified)
+}
+
+func (e *ExpiryReminderTestSuite) Test_WebhookNotifier() {
+	var payload webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(e.T(), http.MethodPost, r.Method)
+		assert.Equal(e.T(), "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(e.T(), json.NewDecoder(r.Body).Decode(&payload))
+		if payload.DataID == "fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL, server.Client())
+	err := notifier.Notify(context.Background(), e.repository.reminders["a"])
+	require.NoError(e.T(), err)
+	assert.Equal(e.T(), webhookPayload{
+		Event:       "credit_card.expiring",
+		DataID:      "a",
+		OwnerID:     "u1",
+		Login:       "one@example.com",
+		MetaData:    "visa",
+		ExpiryMonth: "10-2026",
+	}, payload)
+
+	err = notifier.Notify(context.Background(), model.ExpiryNotification{DataID: "fail"})
+	assert.Error(e.T(), err)
+}
+
+func (e *ExpiryReminderTestSuite) Test_SMTPNotifier() {
+	var sent []string
+	notifier := NewSMTPNotifier("localhost:25", "keeper@example.com", nil)
+	notifier.sendMail = func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+		assert.Equal(e.T(), "localhost:25", addr)
+		assert.Equal(e.T(), "keeper@example.com", from)
+		assert.Contains(e.T(), string(msg), "Subject: Your credit card expires in 10-2026")
+		sent = append(sent, to...)
+		return nil
+	}
+
+	require.NoError(e.T(), notifier.Notify(context.Background(), e.repository.reminders["a"]))
+	// Owners whose login is not an email address are skipped.
+	require.NoError(e.T(), notifier.Notify(context.Background(), e.repository.reminders["b"]))
+	assert.Equal(e.T(), []string{"one@example.com"}, sent)
+}
+
+func (e *ExpiryReminderTestSuite) Test_NewNotifier() {
+	n, err := NewNotifier(NotifierConfig{})
+	require.NoError(e.T(), err)
+	assert.IsType(e.T(), &LogNotifier{}, n)
+
+	_, err = NewNotifier(NotifierConfig{Kind: NotifierWebhook})
+	assert.Error(e.T(), err)
+
+	n, err = NewNotifier(NotifierConfig{Kind: NotifierSMTP, SMTPAddr: "localhost:25", SMTPFrom: "keeper@example.com"})
+	require.NoError(e.T(), err)
+	assert.IsType(e.T(), &SMTPNotifier{}, n)
+
+	_, err = NewNotifier(NotifierConfig{Kind: "pager"})
+	assert.Error(e.T(), err)
+}
@@ -0,0 +1,216 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// Notifier kinds accepted by NewNotifier.
+const (
+	NotifierLog     = "log"
+	NotifierWebhook = "webhook"
+	NotifierSMTP    = "smtp"
+)
+
+const expiryMonthLayout = "01-2006" // Layout of the expiry month in notifications
+
+// Notifier interface defines a channel the owners of expiring credit cards are notified through.
+type Notifier interface {
+	Notify(ctx context.Context, n model.ExpiryNotification) error
+}
+
+// NotifierConfig holds the settings of all notifier kinds, only the ones of the chosen kind are used.
+type NotifierConfig struct {
+	Kind         string // Notifier kind: log, webhook or smtp
+	WebhookURL   string // URL expiry notifications are posted to
+	SMTPAddr     string // Address of the SMTP server as host:port
+	SMTPUser     string // SMTP user, no authentication is done if empty
+	SMTPPassword string // SMTP password
+	SMTPFrom     string // Sender address of notification emails
+}
+
+// NewNotifier creates the notifier of the kind set in the config, the log notifier is used if no kind is set.
+func NewNotifier(cfg NotifierConfig) (Notifier, error) {
+	switch cfg.Kind {
+	case "", NotifierLog:
+		return NewLogNotifier(), nil
+	case NotifierWebhook:
+		if cfg.WebhookURL == "" {
+			return nil, fmt.Errorf("webhook notifier: url is not set")
+		}
+		return NewWebhookNotifier(cfg.WebhookURL, &http.Client{Timeout: 10 * time.Second}), nil
+	case NotifierSMTP:
+		if cfg.SMTPAddr == "" || cfg.SMTPFrom == "" {
+			return nil, fmt.Errorf("smtp notifier: address and sender must be set")
+		}
+		var auth smtp.Auth
+		if cfg.SMTPUser != "" {
+			host, _, _ := strings.Cut(cfg.SMTPAddr, ":")
+			auth = smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPassword, host)
+		}
+		return NewSMTPNotifier(cfg.SMTPAddr, cfg.SMTPFrom, auth), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", cfg.Kind)
+	}
+}
+
+// LogNotifier writes expiry notifications to the server log.
+type LogNotifier struct{}
+
+// NewLogNotifier creates a new instance of LogNotifier.
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Notify logs the notification.
+func (n *LogNotifier) Notify(_ context.Context, notification model.ExpiryNotification) error {
+	logrus.WithFields(logrus.Fields{
+		"user_id": notification.OwnerID,
+		"data_id": notification.DataID,
+	}).Infof("Credit card %q of user %s expires in %s", notification.MetaData, notification.Login,
+		notification.ExpiryMonth.Format(expiryMonthLayout))
+	return nil
+}
+
+// webhookPayload is the JSON body posted by WebhookNotifier.
+type webhookPayload struct {
+	Event       string `json:"event"`
+	DataID      string `json:"data_id"`
+	OwnerID     string `json:"owner_id"`
+	Login       string `json:"login"`
+	MetaData    string `json:"metadata"`
+	ExpiryMonth string `json:"expiry_month"`
+}
+
+// WebhookNotifier posts expiry notifications as JSON to a URL.
+type WebhookNotifier struct {
+	url    string       // URL notifications are posted to
+	client *http.Client // HTTP client used for the requests
+}
+
+// NewWebhookNotifier creates a new instance of WebhookNotifier.
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: client,
+	}
+}
+
+// Notify posts the notification, any response status other than 2xx is an error.
+func (n *WebhookNotifier) Notify(ctx context.Context, notification model.ExpiryNotification) error {
+	body, err := json.Marshal(webhookPayload{
+		Event:       "credit_card.expiring",
+		DataID:      notification.DataID,
+		OwnerID:     notification.OwnerID,
+		Login:       notification.Login,
+		MetaData:    notification.MetaData,
+		ExpiryMonth: notification.ExpiryMonth.Format(expiryMonthLayout),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("post webhook: unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// SendMailFunc sends an email, it has the signature of smtp.SendMail.
+type SendMailFunc func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+// SMTPNotifier emails expiry notifications to users whose login is an email address.
+type SMTPNotifier struct {
+	addr     string       // Address of the SMTP server
+	from     string       // Sender address
+	auth     smtp.Auth    // SMTP authentication, may be nil
+	sendMail SendMailFunc // Function sending the emails
+}
+
+// NewSMTPNotifier creates a new instance of SMTPNotifier sending emails with smtp.SendMail.
+func NewSMTPNotifier(addr, from string, auth smtp.Auth) *SMTPNotifier {
+	return &SMTPNotifier{
+		addr:     addr,
+		from:     from,
+		auth:     auth,
+		sendMail: smtp.SendMail,
+	}
+}
+
+// Notify emails the notification to the login of the owner. Owners whose login is not
+// an email address cannot be reached, the notification is logged and skipped for them.
+func (n *SMTPNotifier) Notify(_ context.Context, notification model.ExpiryNotification) error {
+	to, err := mail.ParseAddress(notification.Login)
+	if err != nil {
+		logrus.Warnf("Unable to email expiry notification to user %s: login is not an email address", notification.OwnerID)
+		return nil
+	}
+
+	month := notification.ExpiryMonth.Format(expiryMonthLayout)
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: Your credit card expires in %s\r\n\r\n"+
+		"Your credit card %q stored in PrivateKeeper expires at the end of %s.\r\n",
+		n.from, to.Address, month, notification.MetaData, month)
+
+	if err = n.sendMail(n.addr, n.auth, n.from, []string{to.Address}, []byte(msg)); err != nil {
+		return fmt.Errorf("send mail: %w", err)
+	}
+
+	return nil
+}
+
+// FakeNotifier records expiry notifications in memory, it is meant for tests.
+type FakeNotifier struct {
+	mu            sync.Mutex
+	notifications []model.ExpiryNotification
+	err           error
+}
+
+// NewFakeNotifier creates a new instance of FakeNotifier failing every notification with err if it is not nil.
+func NewFakeNotifier(err error) *FakeNotifier {
+	return &FakeNotifier{err: err}
+}
+
+// Notify records the notification, or returns the configured error.
+func (n *FakeNotifier) Notify(_ context.Context, notification model.ExpiryNotification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.err != nil {
+		return n.err
+	}
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+// Notifications returns the recorded notifications in the order they were sent.
+func (n *FakeNotifier) Notifications() []model.ExpiryNotification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return append([]model.ExpiryNotification(nil), n.notifications...)
+}
@@ -0,0 +1,114 @@
+package grpchandlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/search"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// dataTypes holds the data types that can be searched for.
+var dataTypes = map[string]struct{}{
+	"credit_card": {},
+	"text_data":   {},
+	"credentials": {},
+	"binary_data": {},
+	"custom_data": {},
+}
+
+// SearchService interface defines the method for searching data across all data types.
+type SearchService interface {
+	Search(ctx context.Context, filter model.SearchFilter) ([]model.Item, bool, error)
+}
+
+// SearchHandler struct implements the gRPC handler for search operations.
+type SearchHandler struct {
+	searchService SearchService
+	pb.UnimplementedSearchServiceServer
+}
+
+// New creates a new instance of SearchHandler.
+func New(searchService SearchService) *SearchHandler {
+	return &SearchHandler{searchService: searchService}
+}
+
+// SearchItems handles the gRPC request to search the data of the user by metadata, type, tags and dates.
+func (h *SearchHandler) SearchItems(ctx context.Context, in *pb.SearchItemsRequest) (*pb.SearchItemsResponse, error) {
+	report := make(map[string]string)
+	filter := model.SearchFilter{
+		Query:       in.Query,
+		Types:       in.Types,
+		Tags:        in.Tags,
+		CreatedFrom: parseTime(in.CreatedFrom, "CreatedFrom", report),
+		CreatedTo:   parseTime(in.CreatedTo, "CreatedTo", report),
+		UpdatedFrom: parseTime(in.UpdatedFrom, "UpdatedFrom", report),
+		UpdatedTo:   parseTime(in.UpdatedTo, "UpdatedTo", report),
+		Limit:       int(in.Limit),
+		Offset:      int(in.Offset),
+	}
+
+	for _, dataType := range in.Types {
+		if _, ok := dataTypes[dataType]; !ok {
+			report["Types"] = "must be one of credit_card, text_data, credentials, binary_data, custom_data"
+		}
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		report["CreatedTo"] = "must be after CreatedFrom"
+	}
+	if filter.UpdatedFrom != nil && filter.UpdatedTo != nil && !filter.UpdatedFrom.Before(*filter.UpdatedTo) {
+		report["UpdatedTo"] = "must be after UpdatedFrom"
+	}
+	if in.Limit < 0 {
+		report["Limit"] = "must not be negative"
+	}
+	if in.Offset < 0 {
+		report["Offset"] = "must not be negative"
+	}
+	if len(report) != 0 {
+		logrus.Info("Unable to search items: invalid search request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid search request", report)
+	}
+
+	items, hasMore, err := h.searchService.Search(ctx, filter)
+	if err != nil {
+		logrus.WithError(err).Error("Error while searching items: ")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	pbItems := make([]*pb.Item, 0, len(items))
+	for _, v := range items {
+		pbItems = append(pbItems, &pb.Item{
+			Id:        v.ID,
+			DataType:  v.DataType,
+			Metadata:  v.MetaData,
+			Tags:      v.Tags,
+			Revision:  v.Revision,
+			CreatedAt: v.CreatedAt.Format(time.RFC3339Nano),
+			UpdatedAt: v.UpdatedAt.Format(time.RFC3339Nano),
+		})
+	}
+
+	return &pb.SearchItemsResponse{Items: pbItems, HasMore: hasMore}, nil
+}
+
+// parseTime parses an optional RFC 3339 time of the request, an invalid time is added to the report.
+func parseTime(value, field string, report map[string]string) *time.Time {
+	if value == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		report[field] = "must be a time in RFC 3339 format"
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,107 @@
+package grpchandlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/search"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// fakeService returns its items and keeps the filter it was called with.
+type fakeService struct {
+	items   []model.Item
+	hasMore bool
+	filter  *model.SearchFilter
+}
+
+func (f *fakeService) Search(_ context.Context, filter model.SearchFilter) ([]model.Item, bool, error) {
+	f.filter = &filter
+	return f.items, f.hasMore, nil
+}
+
+type SearchHandlerTestSuite struct {
+	suite.Suite
+	service *fakeService
+	handler *SearchHandler
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(SearchHandlerTestSuite))
+}
+
+func (s *SearchHandlerTestSuite) SetupTest() {
+	s.service = &fakeService{}
+	s.handler = New(s.service)
+}
+
+// Test_SearchItems checks that the request is passed to the service as a filter and the found items are returned.
+func (s *SearchHandlerTestSuite) Test_SearchItems() {
+	updatedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.service.items = []model.Item{{ID: "id", DataType: "text_data", MetaData: "note #home", Tags: []string{"home"}, Revision: 2, UpdatedAt: updatedAt}}
+	s.service.hasMore = true
+
+	resp, err := s.handler.SearchItems(context.Background(), &pb.SearchItemsRequest{
+		Query:       "note",
+		Types:       []string{"text_data", "custom_data"},
+		Tags:        []string{"#home"},
+		CreatedFrom: "2026-01-01T00:00:00Z",
+		UpdatedTo:   "2026-04-01T00:00:00Z",
+		Limit:       10,
+		Offset:      20,
+	})
+	require.NoError(s.T(), err)
+
+	filter := s.service.filter
+	require.NotNil(s.T(), filter)
+	assert.Equal(s.T(), "note", filter.Query)
+	assert.Equal(s.T(), []string{"text_data", "custom_data"}, filter.Types)
+	assert.Equal(s.T(), []string{"#home"}, filter.Tags)
+	require.NotNil(s.T(), filter.CreatedFrom)
+	assert.True(s.T(), filter.CreatedFrom.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Nil(s.T(), filter.CreatedTo)
+	require.NotNil(s.T(), filter.UpdatedTo)
+	assert.Equal(s.T(), 10, filter.Limit)
+	assert.Equal(s.T(), 20, filter.Offset)
+
+	assert.True(s.T(), resp.HasMore)
+	require.Len(s.T(), resp.Items, 1)
+	assert.Equal(s.T(), "id", resp.Items[0].Id)
+	assert.Equal(s.T(), []string{"home"}, resp.Items[0].Tags)
+	assert.Equal(s.T(), updatedAt.Format(time.RFC3339Nano), resp.Items[0].UpdatedAt)
+}
+
+// Test_InvalidRequest checks that invalid requests are rejected with the violated fields and never searched.
+func (s *SearchHandlerTestSuite) Test_InvalidRequest() {
+	for field, in := range map[string]*pb.SearchItemsRequest{
+		"Types":       {Types: []string{"text_data", "photos"}},
+		"CreatedFrom": {CreatedFrom: "2026-01-01"},
+		"CreatedTo":   {CreatedFrom: "2026-02-01T00:00:00Z", CreatedTo: "2026-02-01T00:00:00Z"},
+		"UpdatedTo":   {UpdatedFrom: "2026-03-01T00:00:00Z", UpdatedTo: "2026-02-01T00:00:00Z"},
+		"Limit":       {Limit: -1},
+		"Offset":      {Offset: -1},
+	} {
+		_, err := s.handler.SearchItems(context.Background(), in)
+		st := status.Convert(err)
+		require.Equal(s.T(), codes.InvalidArgument, st.Code(), field)
+
+		var fields []string
+		for _, detail := range st.Details() {
+			if br, ok := detail.(*errdetails.BadRequest); ok {
+				for _, v := range br.FieldViolations {
+					fields = append(fields, v.Field)
+				}
+			}
+		}
+		assert.Equal(s.T(), []string{field}, fields)
+	}
+	assert.Nil(s.T(), s.service.filter)
+}
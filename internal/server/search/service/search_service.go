@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+const (
+	defaultLimit = 50  // Number of items returned when the client doesn't set a limit
+	maxLimit     = 500 // Maximum number of items returned at once
+)
+
+// SearchRepository interface defines the method for searching the data entries of a user
+type SearchRepository interface {
+	SearchItems(ctx context.Context, userID string, filter model.SearchFilter) ([]model.Item, error)
+}
+
+// SearchService provides methods to search the data of a user across all data types
+type SearchService struct {
+	repository SearchRepository // Repository of data entries
+}
+
+// New initializes a new SearchService instance
+func New(repository SearchRepository) *SearchService {
+	return &SearchService{repository: repository}
+}
+
+// Search returns a page of the user's data entries matching the filter and whether there are more entries
+// after this page. Tags are matched case-insensitively, with or without the leading '#'.
+func (s *SearchService) Search(ctx context.Context, filter model.SearchFilter) ([]model.Item, bool, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return nil, false, fmt.Errorf("failed to get userID from context")
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+	if filter.Limit > maxLimit {
+		filter.Limit = maxLimit
+	}
+
+	tags := make([]string, 0, len(filter.Tags))
+	for _, tag := range filter.Tags {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	filter.Tags = tags
+	filter.Query = strings.TrimSpace(filter.Query)
+
+	// One extra item is requested to know if there are more items after this page
+	limit := filter.Limit
+	filter.Limit++
+	items, err := s.repository.SearchItems(ctx, userID, filter)
+	if err != nil {
+		return nil, false, fmt.Errorf("search items: %w", err)
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	return items, hasMore, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// fakeRepository returns up to filter.Limit of its items and keeps the last filter it was called with.
+type fakeRepository struct {
+	items  []model.Item
+	userID string
+	filter model.SearchFilter
+}
+
+func (f *fakeRepository) SearchItems(_ context.Context, userID string, filter model.SearchFilter) ([]model.Item, error) {
+	f.userID, f.filter = userID, filter
+	if filter.Limit < len(f.items) {
+		return f.items[:filter.Limit], nil
+	}
+	return f.items, nil
+}
+
+type SearchServiceTestSuite struct {
+	suite.Suite
+	repo    *fakeRepository
+	service *SearchService
+	ctx     context.Context
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(SearchServiceTestSuite))
+}
+
+func (s *SearchServiceTestSuite) SetupTest() {
+	s.repo = &fakeRepository{}
+	s.service = New(s.repo)
+	s.ctx = context.WithValue(context.Background(), model.UserIDKey, "user-id")
+}
+
+// Test_Filter checks that the search is made for the user of the request with normalized tags and query.
+func (s *SearchServiceTestSuite) Test_Filter() {
+	_, _, err := s.service.Search(s.ctx, model.SearchFilter{
+		Query: "  mail ",
+		Tags:  []string{"#Work", " mail ", "#", ""},
+		Types: []string{"credentials"},
+	})
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), "user-id", s.repo.userID)
+	assert.Equal(s.T(), "mail", s.repo.filter.Query)
+	assert.Equal(s.T(), []string{"work", "mail"}, s.repo.filter.Tags)
+	assert.Equal(s.T(), []string{"credentials"}, s.repo.filter.Types)
+
+	_, _, err = s.service.Search(context.Background(), model.SearchFilter{})
+	assert.Error(s.T(), err)
+}
+
+// Test_Limit checks the default and the maximum limit, one more item is always asked for to know if there are more.
+func (s *SearchServiceTestSuite) Test_Limit() {
+	for limit, want := range map[int]int{0: defaultLimit, 10: 10, maxLimit: maxLimit, maxLimit + 1: maxLimit} {
+		_, _, err := s.service.Search(s.ctx, model.SearchFilter{Limit: limit})
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), want+1, s.repo.filter.Limit, "limit %d", limit)
+	}
+}
+
+// Test_HasMore checks that a page is cut to the limit and reports the items after it.
+func (s *SearchServiceTestSuite) Test_HasMore() {
+	for i := 0; i < 3; i++ {
+		s.repo.items = append(s.repo.items, model.Item{ID: fmt.Sprintf("item-%d", i)})
+	}
+
+	items, hasMore, err := s.service.Search(s.ctx, model.SearchFilter{Limit: 2})
+	require.NoError(s.T(), err)
+	assert.True(s.T(), hasMore)
+	assert.Len(s.T(), items, 2)
+
+	items, hasMore, err = s.service.Search(s.ctx, model.SearchFilter{Limit: 3})
+	require.NoError(s.T(), err)
+	assert.False(s.T(), hasMore)
+	assert.Len(s.T(), items, 3)
+}
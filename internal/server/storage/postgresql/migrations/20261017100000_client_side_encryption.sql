@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table privatekeeper.user
+    add column if not exists client_side_encryption boolean not null default false,
+    add column if not exists wrapped_data_key       bytea;
+
+alter table privatekeeper.data
+    add column if not exists client_encrypted boolean not null default false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table privatekeeper.data
+    drop column if exists client_encrypted;
+
+alter table privatekeeper.user
+    drop column if exists wrapped_data_key,
+    drop column if exists client_side_encryption;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table privatekeeper.user
+    add column if not exists crypt_key_version integer not null default 0;
+
+create index if not exists privatekeeper_user_crypt_key_version_idx on privatekeeper.user (crypt_key_version);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists privatekeeper.privatekeeper_user_crypt_key_version_idx;
+
+alter table privatekeeper.user
+    drop column if exists crypt_key_version;
+-- +goose StatementEnd
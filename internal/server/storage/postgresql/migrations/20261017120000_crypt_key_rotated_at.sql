@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table privatekeeper.user
+    add column if not exists crypt_key_rotated_at timestamp;
+
+update privatekeeper.user
+set crypt_key_rotated_at = created_at
+where crypt_key_rotated_at is null;
+
+alter table privatekeeper.user
+    alter column crypt_key_rotated_at set default now(),
+    alter column crypt_key_rotated_at set not null;
+
+create index if not exists privatekeeper_user_crypt_key_rotated_at_idx on privatekeeper.user (crypt_key_rotated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists privatekeeper.privatekeeper_user_crypt_key_rotated_at_idx;
+
+alter table privatekeeper.user
+    drop column if exists crypt_key_rotated_at;
+-- +goose StatementEnd
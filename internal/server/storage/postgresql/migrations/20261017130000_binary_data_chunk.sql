@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists privatekeeper.binary_data_chunk
+(
+    data_id                 text not null,
+    data_type               privatekeeper.data_type not null default 'binary_data',
+    owner_id                text not null,
+    idx                     integer not null,
+    data                    bytea not null,
+    constraint pk_binary_data_chunk primary key (data_id, idx),
+    constraint ck_binary_data_chunk__data_type check (data_type = 'binary_data'),
+    constraint fk_binary_data_chunk__data foreign key (data_id, data_type)
+        references privatekeeper.data (id, type) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists privatekeeper.binary_data_chunk;
+-- +goose StatementEnd
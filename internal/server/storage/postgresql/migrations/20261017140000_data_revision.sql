@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+create sequence if not exists privatekeeper.data_change_seq;
+
+alter table privatekeeper.data
+    add column if not exists revision   bigint not null default 1,
+    add column if not exists updated_at timestamp not null default now(),
+    add column if not exists change_seq bigint not null default nextval('privatekeeper.data_change_seq');
+
+create index if not exists ix_data__owner_id_change_seq on privatekeeper.data (owner_id, change_seq);
+
+create table if not exists privatekeeper.data_tombstone
+(
+    id                      text not null,
+    type                    privatekeeper.data_type not null,
+    owner_id                text not null,
+    revision                bigint not null,
+    deleted_at              timestamp not null default now(),
+    change_seq              bigint not null default nextval('privatekeeper.data_change_seq'),
+    constraint pk_data_tombstone primary key (id, type),
+    constraint fk_data_tombstone__owner_id foreign key (owner_id) references privatekeeper.user (id)
+);
+
+create index if not exists ix_data_tombstone__owner_id_change_seq on privatekeeper.data_tombstone (owner_id, change_seq);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists privatekeeper.data_tombstone;
+
+drop index if exists privatekeeper.ix_data__owner_id_change_seq;
+
+alter table privatekeeper.data
+    drop column if exists change_seq,
+    drop column if exists updated_at,
+    drop column if exists revision;
+
+drop sequence if exists privatekeeper.data_change_seq;
+-- +goose StatementEnd
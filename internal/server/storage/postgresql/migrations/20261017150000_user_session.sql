@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists privatekeeper.user_session
+(
+    id                      text,
+    user_id                 text not null,
+    refresh_token_hash      bytea not null,
+    created_at              timestamp not null default now(),
+    expires_at              timestamp not null,
+    revoked_at              timestamp,
+    constraint pk_user_session primary key (id),
+    constraint fk_user_session__user_id foreign key (user_id) references privatekeeper.user (id) on delete cascade
+);
+
+create index if not exists ix_user_session__user_id on privatekeeper.user_session (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists privatekeeper.user_session;
+-- +goose StatementEnd
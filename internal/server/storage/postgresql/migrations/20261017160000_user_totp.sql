@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists privatekeeper.user_totp
+(
+    user_id                 text,
+    secret                  bytea not null,
+    enabled                 boolean not null default false,
+    last_used_step          bigint not null default 0,
+    created_at              timestamp not null default now(),
+    constraint pk_user_totp primary key (user_id),
+    constraint fk_user_totp__user_id foreign key (user_id) references privatekeeper.user (id) on delete cascade
+);
+
+create table if not exists privatekeeper.user_recovery_code
+(
+    user_id                 text,
+    code_hash               bytea,
+    used_at                 timestamp,
+    constraint pk_user_recovery_code primary key (user_id, code_hash),
+    constraint fk_user_recovery_code__user_id foreign key (user_id) references privatekeeper.user (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists privatekeeper.user_recovery_code;
+drop table if exists privatekeeper.user_totp;
+-- +goose StatementEnd
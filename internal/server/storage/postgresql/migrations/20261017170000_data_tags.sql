@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+create or replace function privatekeeper.metadata_tags(metadata text) returns text[]
+    language sql
+    immutable
+    parallel safe
+as
+$$
+select coalesce(array_agg(distinct lower(tag[1])), '{}')
+from regexp_matches(coalesce(metadata, ''), '#([[:alnum:]_-]+)', 'g') as tag
+$$;
+
+alter table privatekeeper.data
+    add column if not exists tags text[] generated always as (privatekeeper.metadata_tags(metadata)) stored;
+
+create index if not exists ix_data__tags on privatekeeper.data using gin (tags);
+create index if not exists ix_data__owner_id_updated_at on privatekeeper.data (owner_id, updated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists privatekeeper.ix_data__owner_id_updated_at;
+drop index if exists privatekeeper.ix_data__tags;
+
+alter table privatekeeper.data
+    drop column if exists tags;
+
+drop function if exists privatekeeper.metadata_tags(text);
+-- +goose StatementEnd
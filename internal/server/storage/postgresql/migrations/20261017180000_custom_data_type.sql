@@ -0,0 +1,6 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+alter type privatekeeper.data_type add value if not exists 'custom_data';
+
+-- +goose Down
+-- Values can't be removed from an enum, the value stays unused once its partition is dropped
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists privatekeeper.data_custom_data partition of privatekeeper.data
+    for values in ('custom_data');
+
+create table if not exists privatekeeper.item_template
+(
+    id                      text,
+    owner_id                text not null,
+    name                    text not null,
+    fields                  jsonb not null,
+    created_at              timestamp not null default now(),
+    updated_at              timestamp not null default now(),
+    constraint pk_item_template primary key (id),
+    constraint ux_item_template__owner_id_name unique (owner_id, name),
+    constraint fk_item_template__owner_id foreign key (owner_id) references privatekeeper.user (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists privatekeeper.item_template;
+drop table if exists privatekeeper.data_custom_data;
+-- +goose StatementEnd
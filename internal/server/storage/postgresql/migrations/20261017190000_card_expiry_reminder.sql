@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists privatekeeper.card_expiry_reminder
+(
+    data_id                 text not null,
+    data_type               privatekeeper.data_type not null default 'credit_card',
+    owner_id                text not null,
+    expiry_month            date not null,
+    notified_at             timestamp,
+    constraint pk_card_expiry_reminder primary key (data_id),
+    constraint ck_card_expiry_reminder__data_type check (data_type = 'credit_card'),
+    constraint fk_card_expiry_reminder__data foreign key (data_id, data_type)
+        references privatekeeper.data (id, type) on delete cascade
+);
+
+create index if not exists ix_card_expiry_reminder__expiry_month on privatekeeper.card_expiry_reminder (expiry_month)
+    where notified_at is null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists privatekeeper.card_expiry_reminder;
+-- +goose StatementEnd
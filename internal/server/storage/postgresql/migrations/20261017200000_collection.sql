@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists privatekeeper.collection
+(
+    id                      text,
+    name                    text not null,
+    created_at              timestamp not null default now(),
+    constraint pk_collection primary key (id)
+);
+
+create table if not exists privatekeeper.collection_member
+(
+    collection_id           text not null,
+    user_id                 text not null,
+    role                    text not null,
+    status                  text not null,
+    crypt_key               bytea not null,
+    invited_by              text not null,
+    created_at              timestamp not null default now(),
+    updated_at              timestamp not null default now(),
+    constraint pk_collection_member primary key (collection_id, user_id),
+    constraint ck_collection_member__role check (role in ('read', 'write', 'manage')),
+    constraint ck_collection_member__status check (status in ('invited', 'active')),
+    constraint fk_collection_member__collection_id foreign key (collection_id)
+        references privatekeeper.collection (id) on delete cascade,
+    constraint fk_collection_member__user_id foreign key (user_id)
+        references privatekeeper.user (id) on delete cascade
+);
+
+create index if not exists ix_collection_member__user_id on privatekeeper.collection_member (user_id);
+
+create table if not exists privatekeeper.collection_item
+(
+    id                      text,
+    collection_id           text not null,
+    type                    privatekeeper.data_type not null,
+    data                    bytea not null,
+    metadata                text,
+    created_by              text not null,
+    created_at              timestamp not null default now(),
+    updated_at              timestamp not null default now(),
+    revision                bigint not null default 1,
+    constraint pk_collection_item primary key (id),
+    constraint fk_collection_item__collection_id foreign key (collection_id)
+        references privatekeeper.collection (id) on delete cascade
+);
+
+create index if not exists ix_collection_item__collection_id on privatekeeper.collection_item (collection_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists privatekeeper.collection_item;
+drop table if exists privatekeeper.collection_member;
+drop table if exists privatekeeper.collection;
+-- +goose StatementEnd
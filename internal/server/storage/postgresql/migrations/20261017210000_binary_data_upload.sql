@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists privatekeeper.binary_data_upload
+(
+    id                      text not null,
+    owner_id                text not null,
+    started_at              timestamp not null default now(),
+    constraint pk_binary_data_upload primary key (id),
+    constraint fk_binary_data_upload__owner_id foreign key (owner_id) references privatekeeper.user (id) on delete cascade
+);
+
+create index if not exists ix_binary_data_upload__owner_id_started_at on privatekeeper.binary_data_upload (owner_id, started_at);
+
+create table if not exists privatekeeper.binary_data_upload_chunk
+(
+    upload_id               text not null,
+    idx                     integer not null,
+    data                    bytea not null,
+    constraint pk_binary_data_upload_chunk primary key (upload_id, idx),
+    constraint fk_binary_data_upload_chunk__upload_id foreign key (upload_id)
+        references privatekeeper.binary_data_upload (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists privatekeeper.binary_data_upload_chunk;
+drop table if exists privatekeeper.binary_data_upload;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Change cursors are taken from the sequence while the owner's transaction-scoped lock is held, so
+-- the changes of an owner get their cursors in commit order. Otherwise a transaction that took a lower
+-- cursor could commit after a higher one was already read, and clients would skip its change.
+create or replace function privatekeeper.assign_data_change_seq() returns trigger
+    language plpgsql as
+$$
+begin
+    if tg_op = 'UPDATE' and new.change_seq is not distinct from old.change_seq then
+        return new;
+    end if;
+
+    perform pg_advisory_xact_lock(hashtext('privatekeeper.data_change_seq'), hashtext(new.owner_id));
+    new.change_seq := nextval('privatekeeper.data_change_seq');
+    return new;
+end;
+$$;
+
+create trigger tg_data__change_seq
+    before insert or update on privatekeeper.data
+    for each row execute function privatekeeper.assign_data_change_seq();
+
+create trigger tg_data_tombstone__change_seq
+    before insert or update on privatekeeper.data_tombstone
+    for each row execute function privatekeeper.assign_data_change_seq();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop trigger if exists tg_data_tombstone__change_seq on privatekeeper.data_tombstone;
+drop trigger if exists tg_data__change_seq on privatekeeper.data;
+drop function if exists privatekeeper.assign_data_change_seq();
+-- +goose StatementEnd
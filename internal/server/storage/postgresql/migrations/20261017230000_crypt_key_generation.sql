@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table privatekeeper.user
+    add column if not exists crypt_key_generation bigint not null default 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table privatekeeper.user
+    drop column if exists crypt_key_generation;
+-- +goose StatementEnd
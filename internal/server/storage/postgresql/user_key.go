@@ -0,0 +1,47 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// LockUserKey locks the row of the user for share until the end of the transaction and checks that the
+// generation of the user key the request was served with, taken from the context, is still the current one.
+// A key rotation locks the row for update while it re-encrypts the data of the user, so it can't commit
+// between reading the key and writing data encrypted with it within the transaction.
+// It returns cerrors.ErrUserKeyRotated if the key was rotated since it was read.
+func LockUserKey(ctx context.Context, tx pgx.Tx, userID string) error {
+	generation, ok := ctx.Value(model.UserKeyGenerationKey).(int64)
+	if !ok {
+		return fmt.Errorf("failed to get user key generation from context")
+	}
+
+	var current int64
+	err := tx.QueryRow(ctx,
+		`
+			select
+				crypt_key_generation
+			from privatekeeper.user
+			where id = $1
+			for share;
+			`,
+		userID).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("lock user key: %w", cerrors.ErrUserNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("lock user key: %w", err)
+	}
+
+	if current != generation {
+		return fmt.Errorf("lock user key: %w", cerrors.ErrUserKeyRotated)
+	}
+
+	return nil
+}
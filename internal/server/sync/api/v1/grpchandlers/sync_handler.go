@@ -0,0 +1,67 @@
+package grpchandlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/DenisKhanov/PrivateKeeperV2/internal/proto/sync"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+// SyncService interface defines the method for reading the change feed.
+type SyncService interface {
+	ChangesSince(ctx context.Context, cursor int64, limit int) ([]model.Change, int64, bool, error)
+}
+
+// SyncHandler struct implements the gRPC handler for sync operations.
+type SyncHandler struct {
+	syncService SyncService
+	pb.UnimplementedSyncServiceServer
+}
+
+// New creates a new instance of SyncHandler.
+func New(syncService SyncService) *SyncHandler {
+	return &SyncHandler{syncService: syncService}
+}
+
+// GetChangesSince handles the gRPC request to load the data changes made after the cursor.
+func (h *SyncHandler) GetChangesSince(ctx context.Context, in *pb.GetChangesSinceRequest) (*pb.GetChangesSinceResponse, error) {
+	report := make(map[string]string)
+	if in.Cursor < 0 {
+		report["Cursor"] = "must not be negative"
+	}
+	if in.Limit < 0 {
+		report["Limit"] = "must not be negative"
+	}
+	if len(report) != 0 {
+		logrus.Info("Unable to load changes: invalid sync request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid sync request", report)
+	}
+
+	changes, cursor, hasMore, err := h.syncService.ChangesSince(ctx, in.Cursor, int(in.Limit))
+	if err != nil {
+		logrus.WithError(err).Error("Error while loading changes: ")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	pbChanges := make([]*pb.Change, 0, len(changes))
+	for _, v := range changes {
+		pbChanges = append(pbChanges, &pb.Change{
+			Id:        v.ID,
+			DataType:  v.DataType,
+			Metadata:  v.MetaData,
+			Revision:  v.Revision,
+			CreatedAt: v.CreatedAt.Format(time.RFC3339Nano),
+			UpdatedAt: v.UpdatedAt.Format(time.RFC3339Nano),
+			Deleted:   v.Deleted,
+		})
+	}
+
+	return &pb.GetChangesSinceResponse{Changes: pbChanges, Cursor: cursor, HasMore: hasMore}, nil
+}
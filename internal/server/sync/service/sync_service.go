@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+)
+
+const (
+	defaultLimit = 100  // Number of changes returned when the client doesn't set a limit
+	maxLimit     = 1000 // Maximum number of changes returned at once
+)
+
+// ChangeRepository interface defines the method for reading the change feed of a user
+type ChangeRepository interface {
+	SelectChangesSince(ctx context.Context, userID string, cursor int64, limit int) ([]model.Change, error)
+}
+
+// SyncService provides methods to sync data between the devices of a user
+type SyncService struct {
+	repository ChangeRepository // Repository of data changes
+}
+
+// New initializes a new SyncService instance
+func New(repository ChangeRepository) *SyncService {
+	return &SyncService{repository: repository}
+}
+
+// ChangesSince returns the changes of the user's data made after the given cursor, the cursor
+// to continue from and whether there are more changes to read.
+//
+// Cursors are taken from a database sequence when a row is written, so a change committed later than
+// a change with a greater cursor may be skipped by a concurrent reader. Clients compare revisions
+// when they load data, so such a change is picked up on the next full refresh.
+func (s *SyncService) ChangesSince(ctx context.Context, cursor int64, limit int) ([]model.Change, int64, bool, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return nil, 0, false, fmt.Errorf("failed to get userID from context")
+	}
+
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	// One extra change is requested to know if there are more changes after this page
+	changes, err := s.repository.SelectChangesSince(ctx, userID, cursor, limit+1)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("select changes: %w", err)
+	}
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit]
+	}
+
+	if len(changes) != 0 {
+		cursor = changes[len(changes)-1].Cursor
+	}
+
+	return changes, cursor, hasMore, nil
+}
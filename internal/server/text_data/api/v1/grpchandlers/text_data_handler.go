@@ -2,7 +2,10 @@ package grpchandlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -15,13 +18,19 @@ import (
 // TextDataService interface defines the methods for text data management.
 type TextDataService interface {
 	SaveTextData(ctx context.Context, req model.TextDataPostRequest) (model.TextData, error)
+	SaveTextDataBatch(ctx context.Context, reqs []model.TextDataPostRequest) ([]model.TextData, error)
 	LoadTextData(ctx context.Context, dataID string) (model.TextData, error)
-	LoadAllTextInfo(ctx context.Context) ([]model.DataInfo, error)
+	LoadAllTextInfo(ctx context.Context, page model.PageRequest) (model.DataInfoPage, error)
+	UpdateTextData(ctx context.Context, req model.TextDataPutRequest) (model.TextData, error)
+	DeleteTextData(ctx context.Context, dataID string) error
 }
 
 // Validator interface defines the method for validating requests.
 type Validator interface {
 	ValidatePostRequest(req *model.TextDataPostRequest) (map[string]string, bool)
+	ValidatePutRequest(req *model.TextDataPutRequest) (map[string]string, bool)
+	ValidateDeleteRequest(req *model.DataDeleteRequest) (map[string]string, bool)
+	ValidatePageRequest(req *model.PageRequest) (map[string]string, bool)
 }
 
 // TextDataHandler struct implements the gRPC handler for text data operations.
@@ -42,8 +51,10 @@ func New(textDataService TextDataService, validator Validator) *TextDataHandler
 // PostSaveTextData handles the gRPC request to save text data.
 func (h *TextDataHandler) PostSaveTextData(ctx context.Context, in *pb.PostTextDataRequest) (*pb.PostTextDataResponse, error) {
 	req := model.TextDataPostRequest{
-		Text:     in.Text,
-		MetaData: in.Metadata,
+		ID:        in.Id,
+		Text:      in.Text,
+		MetaData:  in.Metadata,
+		CryptData: in.CryptData,
 	}
 
 	report, ok := h.validator.ValidatePostRequest(&req)
@@ -54,6 +65,16 @@ func (h *TextDataHandler) PostSaveTextData(ctx context.Context, in *pb.PostTextD
 	}
 
 	text, err := h.textDataService.SaveTextData(ctx, req)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to save text_data: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataExists) {
+		logrus.Info("Unable to save text_data: id is taken")
+		return nil, status.Error(codes.AlreadyExists, "data with this id already exists")
+	}
+
 	if err != nil {
 		logrus.WithError(err).Errorf("failed to save text_data")
 		return nil, status.Error(codes.Internal, "internal error")
@@ -64,29 +85,124 @@ func (h *TextDataHandler) PostSaveTextData(ctx context.Context, in *pb.PostTextD
 		Text:      text.Text,
 		Metadata:  text.MetaData,
 		CreatedAt: text.CreatedAt.Format(time.RFC3339),
+		Revision:  text.Revision,
 	}, nil
 }
 
-// GetLoadAllTextDataInfo handles the gRPC request to load all text data information.
-func (h *TextDataHandler) GetLoadAllTextDataInfo(ctx context.Context, _ *pb.GetAllTextInfoRequest) (*pb.GetAllTextInfoResponse, error) {
+// PostSaveTextDataBatch handles the gRPC request to save many text data at once. Every item is validated and
+// the violations are reported per item with the path of the field, like texts[3].Text.
+// The valid items are saved in a single transaction, an atomic batch is rejected as a whole when any item is invalid.
+func (h *TextDataHandler) PostSaveTextDataBatch(ctx context.Context, in *pb.PostTextDataBatchRequest) (*pb.PostTextDataBatchResponse, error) {
+	if len(in.Texts) > lib.MaxBatchSize {
+		logrus.Infof("Unable to save text_data batch: %d items", len(in.Texts))
+		return nil, lib.ProcessValidationError("invalid text_data batch request",
+			map[string]string{"texts": fmt.Sprintf("must hold at most %d items", lib.MaxBatchSize)})
+	}
+
+	results := make([]*pb.TextDataBatchResult, len(in.Texts))
+	reqs := make([]model.TextDataPostRequest, 0, len(in.Texts))
+	indexes := make([]int, 0, len(in.Texts))
+	batchReport := make(map[string]string)
+	for i, item := range in.Texts {
+		req := model.TextDataPostRequest{
+			ID:        item.Id,
+			Text:      item.Text,
+			MetaData:  item.Metadata,
+			CryptData: item.CryptData,
+		}
+
+		report, ok := h.validator.ValidatePostRequest(&req)
+		if ok {
+			reqs = append(reqs, req)
+			indexes = append(indexes, i)
+			continue
+		}
+
+		results[i] = &pb.TextDataBatchResult{}
+		for _, violation := range lib.BatchViolations("texts", i, report) {
+			batchReport[violation.Field] = violation.Description
+			results[i].Violations = append(results[i].Violations, &pb.TextDataFieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+	}
+
+	if len(batchReport) != 0 && in.Atomic {
+		logrus.Info("Unable to save text_data batch: invalid items in an atomic batch")
+		logrus.Infof("violated_fields %v", batchReport)
+		return nil, lib.ProcessValidationError("invalid text_data batch request", batchReport)
+	}
+
+	saved, err := h.textDataService.SaveTextDataBatch(ctx, reqs)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to save text_data batch: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataExists) {
+		logrus.Info("Unable to save text_data batch: id is taken")
+		return nil, status.Error(codes.AlreadyExists, "data with this id already exists")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("failed to save text_data batch")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	for j, v := range saved {
+		results[indexes[j]] = &pb.TextDataBatchResult{Saved: &pb.PostTextDataResponse{
+			Id:        v.ID,
+			Text:      v.Text,
+			Metadata:  v.MetaData,
+			CreatedAt: v.CreatedAt.Format(time.RFC3339),
+			Revision:  v.Revision,
+		}}
+	}
+
+	return &pb.PostTextDataBatchResponse{Results: results}, nil
+}
+
+// GetLoadAllTextDataInfo handles the gRPC call for loading a page of text data information.
+func (h *TextDataHandler) GetLoadAllTextDataInfo(ctx context.Context, in *pb.GetAllTextInfoRequest) (*pb.GetAllTextInfoResponse, error) {
+	req := model.PageRequest{
+		PageSize:   int(in.PageSize),
+		PageToken:  in.PageToken,
+		SortBy:     in.SortBy,
+		Descending: in.Descending,
+	}
+
+	report, ok := h.validator.ValidatePageRequest(&req)
+	if !ok {
+		logrus.Info("Unable to load text data info: invalid page request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid page request", report)
+	}
+
+	textPage, err := h.textDataService.LoadAllTextInfo(ctx, req)
+	if errors.Is(err, cerrors.ErrInvalidPageToken) {
+		logrus.Info("Unable to load text data info: invalid page token")
+		return nil, lib.ProcessValidationError("invalid page request", map[string]string{"PageToken": "is invalid or issued for another sort order"})
+	}
 
-	textInfo, err := h.textDataService.LoadAllTextInfo(ctx)
 	if err != nil {
 		logrus.WithError(err).Error("Error while loading text data: ")
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	textInfos := make([]*pb.TextInfo, 0, len(textInfo))
-	for _, v := range textInfo {
+	textInfos := make([]*pb.TextInfo, 0, len(textPage.Infos))
+	for _, v := range textPage.Infos {
 		textInfos = append(textInfos, &pb.TextInfo{
 			Id:        v.ID,
 			DataType:  v.DataType,
 			Metadata:  v.MetaData,
 			CreatedAt: v.CreatedAt.Format(time.RFC3339),
+			Revision:  v.Revision,
+			UpdatedAt: v.UpdatedAt.Format(time.RFC3339),
 		})
 	}
 
-	return &pb.GetAllTextInfoResponse{Text: textInfos}, nil
+	return &pb.GetAllTextInfoResponse{Text: textInfos, NextPageToken: textPage.NextPageToken}, nil
 }
 
 // GetLoadTextData handles the gRPC request to load text data by ID.
@@ -105,6 +221,80 @@ func (h *TextDataHandler) GetLoadTextData(ctx context.Context, in *pb.GetTextDat
 		Text:      textData.Text,
 		Metadata:  textData.MetaData,
 		CreatedAt: textData.CreatedAt.Format(time.RFC3339Nano),
+		Revision:  textData.Revision,
+		CryptData: textData.CryptData,
 	}
 	return &pb.GetTextDataResponse{TextData: text}, nil
 }
+
+// PutUpdateTextData handles the gRPC request to update existing text data.
+func (h *TextDataHandler) PutUpdateTextData(ctx context.Context, in *pb.PutTextDataRequest) (*pb.PutTextDataResponse, error) {
+	req := model.TextDataPutRequest{
+		ID:        in.Id,
+		Revision:  in.Revision,
+		Text:      in.Text,
+		MetaData:  in.Metadata,
+		CryptData: in.CryptData,
+	}
+
+	report, ok := h.validator.ValidatePutRequest(&req)
+	if !ok {
+		logrus.Info("Unable to update text_data: invalid text_data request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid text_data put request", report)
+	}
+
+	text, err := h.textDataService.UpdateTextData(ctx, req)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to update text_data: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to update text_data: text_data %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "text data not found")
+	}
+
+	if errors.Is(err, cerrors.ErrRevisionConflict) {
+		logrus.Infof("Unable to update text_data: text_data %s has a newer revision", req.ID)
+		return nil, status.Error(codes.Aborted, "text data was changed by another client")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to update text_data")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PutTextDataResponse{
+		Id:        text.ID,
+		Text:      text.Text,
+		Metadata:  text.MetaData,
+		CreatedAt: text.CreatedAt.Format(time.RFC3339),
+		Revision:  text.Revision,
+	}, nil
+}
+
+// DeleteTextData handles the gRPC request to delete text data by ID.
+func (h *TextDataHandler) DeleteTextData(ctx context.Context, in *pb.DeleteTextDataRequest) (*pb.DeleteTextDataResponse, error) {
+	req := model.DataDeleteRequest{ID: in.Id}
+
+	report, ok := h.validator.ValidateDeleteRequest(&req)
+	if !ok {
+		logrus.Info("Unable to delete text_data: invalid text_data request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid text_data delete request", report)
+	}
+
+	err := h.textDataService.DeleteTextData(ctx, req.ID)
+	if errors.Is(err, cerrors.ErrDataNotFound) {
+		logrus.Infof("Unable to delete text_data: text_data %s not found", req.ID)
+		return nil, status.Error(codes.NotFound, "text data not found")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to delete text_data")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.DeleteTextDataResponse{}, nil
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/google/uuid"
 
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/lib"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/pagination"
 	"github.com/DenisKhanov/PrivateKeeperV2/pkg/jwtmanager"
 )
 
@@ -15,8 +16,11 @@ const textData = "text_data" // Define a constant for the data type
 // DataRepository interface defines methods for data persistence
 type DataRepository interface {
 	Insert(ctx context.Context, data model.Data) (model.Data, error)
-	SelectAll(ctx context.Context, userID, dataType string) ([]model.Data, error)
+	InsertBatch(ctx context.Context, data []model.Data) ([]model.Data, error)
+	SelectPage(ctx context.Context, userID, dataType string, page model.PageRequest, after *model.PageCursor, limit int) ([]model.Data, error)
 	SelectByID(ctx context.Context, userID, dataType, dataID string) (model.Data, error)
+	Update(ctx context.Context, data model.Data) (model.Data, error)
+	Delete(ctx context.Context, userID, dataType, dataID string) error
 }
 
 // CryptService interface defines methods for encryption and decryption
@@ -56,31 +60,9 @@ func (s *TextDataService) SaveTextData(ctx context.Context, req model.TextDataPo
 		return model.TextData{}, fmt.Errorf("failed to get userKey from context")
 	}
 
-	id, err := uuid.NewUUID()
+	dataToSave, err := s.newData(userID, userKey, req)
 	if err != nil {
-		return model.TextData{}, fmt.Errorf("new uuid: %w", err)
-	}
-
-	text := model.TextCryptData{
-		Text: req.Text,
-	}
-
-	data, err := json.Marshal(text)
-	if err != nil {
-		return model.TextData{}, fmt.Errorf("marshal: %w", err)
-	}
-
-	cryptData, err := s.crypt.Encrypt(userKey, data)
-	if err != nil {
-		return model.TextData{}, fmt.Errorf("encrypt data: %w", err)
-	}
-
-	dataToSave := model.Data{
-		ID:       id.String(),
-		OwnerID:  userID,
-		Type:     s.dataType,
-		Data:     cryptData,
-		MetaData: req.MetaData,
+		return model.TextData{}, err
 	}
 
 	savedTextData, err := s.repository.Insert(ctx, dataToSave)
@@ -94,20 +76,76 @@ func (s *TextDataService) SaveTextData(ctx context.Context, req model.TextDataPo
 		Text:      req.Text,
 		MetaData:  savedTextData.MetaData,
 		CreatedAt: savedTextData.CreatedAt,
+		Revision:  savedTextData.Revision,
+		CryptData: req.CryptData,
 	}, nil
 }
 
-// LoadAllTextInfo retrieves all text data information for the user
-func (s *TextDataService) LoadAllTextInfo(ctx context.Context) ([]model.DataInfo, error) {
+// SaveTextDataBatch encrypts the provided text data and saves them to the repository in a single transaction.
+// It returns the saved TextData objects in the order of the requests, either all of them are saved or none.
+func (s *TextDataService) SaveTextDataBatch(ctx context.Context, reqs []model.TextDataPostRequest) ([]model.TextData, error) {
 	userID, ok := ctx.Value(model.UserIDKey).(string)
 	if !ok {
 		return nil, fmt.Errorf("failed to get userID from context")
 	}
 
-	encryptedTextData, err := s.repository.SelectAll(ctx, userID, s.dataType)
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return nil, fmt.Errorf("failed to get userKey from context")
+	}
+
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	dataToSave := make([]model.Data, 0, len(reqs))
+	for _, req := range reqs {
+		data, err := s.newData(userID, userKey, req)
+		if err != nil {
+			return nil, err
+		}
+		dataToSave = append(dataToSave, data)
+	}
+
+	savedData, err := s.repository.InsertBatch(ctx, dataToSave)
+	if err != nil {
+		return nil, fmt.Errorf("insert text data batch: %w", err)
+	}
+
+	result := make([]model.TextData, 0, len(savedData))
+	for i, saved := range savedData {
+		result = append(result, model.TextData{
+			ID:        saved.ID,
+			OwnerID:   saved.OwnerID,
+			Text:      reqs[i].Text,
+			MetaData:  saved.MetaData,
+			CreatedAt: saved.CreatedAt,
+			Revision:  saved.Revision,
+			CryptData: reqs[i].CryptData,
+		})
+	}
+
+	return result, nil
+}
+
+// LoadAllTextInfo retrieves a page of text data information for the user, sorted as requested.
+func (s *TextDataService) LoadAllTextInfo(ctx context.Context, page model.PageRequest) (model.DataInfoPage, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.DataInfoPage{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	page = pagination.Normalize(page)
+	after, err := pagination.DecodeToken(page)
+	if err != nil {
+		return model.DataInfoPage{}, err
+	}
+
+	encryptedTextData, err := s.repository.SelectPage(ctx, userID, s.dataType, page, after, page.PageSize+1)
 	if err != nil {
-		return nil, fmt.Errorf("select all text_data: %w", err)
+		return model.DataInfoPage{}, fmt.Errorf("select page text_data: %w", err)
 	}
+	encryptedTextData, nextPageToken := pagination.Page(page, encryptedTextData)
 
 	textDataInfo := make([]model.DataInfo, 0, len(encryptedTextData))
 	for _, encryptedText := range encryptedTextData {
@@ -116,9 +154,11 @@ func (s *TextDataService) LoadAllTextInfo(ctx context.Context) ([]model.DataInfo
 			DataType:  s.dataType,
 			MetaData:  encryptedText.MetaData,
 			CreatedAt: encryptedText.CreatedAt,
+			Revision:  encryptedText.Revision,
+			UpdatedAt: encryptedText.UpdatedAt,
 		})
 	}
-	return textDataInfo, nil
+	return model.DataInfoPage{Infos: textDataInfo, NextPageToken: nextPageToken}, nil
 }
 
 // LoadTextData retrieves and decrypts text data by its ID
@@ -137,6 +177,17 @@ func (s *TextDataService) LoadTextData(ctx context.Context, dataID string) (mode
 	if err != nil {
 		return model.TextData{}, fmt.Errorf("select all text_data: %w", err)
 	}
+	if encryptedTextData.ClientEncrypted {
+		return model.TextData{
+			ID:        encryptedTextData.ID,
+			OwnerID:   encryptedTextData.OwnerID,
+			MetaData:  encryptedTextData.MetaData,
+			CreatedAt: encryptedTextData.CreatedAt,
+			Revision:  encryptedTextData.Revision,
+			CryptData: encryptedTextData.Data,
+		}, nil
+	}
+
 	decryptedData, err := s.crypt.Decrypt(userKey, encryptedTextData.Data)
 	if err != nil {
 		return model.TextData{}, fmt.Errorf("decrypt text: %w", err)
@@ -154,7 +205,116 @@ func (s *TextDataService) LoadTextData(ctx context.Context, dataID string) (mode
 		Text:      decryptedTextData.Text,
 		MetaData:  encryptedTextData.MetaData,
 		CreatedAt: encryptedTextData.CreatedAt,
+		Revision:  encryptedTextData.Revision,
 	}
 
 	return text, nil
 }
+
+// UpdateTextData re-encrypts and replaces an existing text data entry
+func (s *TextDataService) UpdateTextData(ctx context.Context, req model.TextDataPutRequest) (model.TextData, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.TextData{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return model.TextData{}, fmt.Errorf("failed to get userKey from context")
+	}
+
+	text := model.TextCryptData{
+		Text: req.Text,
+	}
+
+	cryptData, clientEncrypted, err := s.encryptPayload(userKey, req.CryptData, text)
+	if err != nil {
+		return model.TextData{}, fmt.Errorf("encrypt payload: %w", err)
+	}
+
+	dataToUpdate := model.Data{
+		ID:              req.ID,
+		OwnerID:         userID,
+		Type:            s.dataType,
+		Data:            cryptData,
+		MetaData:        req.MetaData,
+		ClientEncrypted: clientEncrypted,
+		Revision:        req.Revision,
+	}
+
+	updatedTextData, err := s.repository.Update(ctx, dataToUpdate)
+	if err != nil {
+		return model.TextData{}, fmt.Errorf("update text data: %w", err)
+	}
+
+	return model.TextData{
+		ID:        updatedTextData.ID,
+		OwnerID:   updatedTextData.OwnerID,
+		Text:      req.Text,
+		MetaData:  updatedTextData.MetaData,
+		CreatedAt: updatedTextData.CreatedAt,
+		Revision:  updatedTextData.Revision,
+		CryptData: req.CryptData,
+	}, nil
+}
+
+// DeleteTextData removes a text data entry by its ID
+func (s *TextDataService) DeleteTextData(ctx context.Context, dataID string) error {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get userID from context")
+	}
+
+	err := s.repository.Delete(ctx, userID, s.dataType, dataID)
+	if err != nil {
+		return fmt.Errorf("delete text data: %w", err)
+	}
+
+	return nil
+}
+
+// newData returns a new data entry of the user holding the encrypted text data.
+func (s *TextDataService) newData(userID string, userKey []byte, req model.TextDataPostRequest) (model.Data, error) {
+	id, err := lib.NewDataID(req.ID)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("new data id: %w", err)
+	}
+
+	text := model.TextCryptData{
+		Text: req.Text,
+	}
+
+	cryptData, clientEncrypted, err := s.encryptPayload(userKey, req.CryptData, text)
+	if err != nil {
+		return model.Data{}, fmt.Errorf("encrypt payload: %w", err)
+	}
+
+	return model.Data{
+		ID:              id,
+		OwnerID:         userID,
+		Type:            s.dataType,
+		Data:            cryptData,
+		MetaData:        req.MetaData,
+		ClientEncrypted: clientEncrypted,
+	}, nil
+}
+
+// encryptPayload returns the payload to be stored for the text data. A payload that was
+// already encrypted on the client side is stored as is, otherwise the data is encrypted with the user key.
+func (s *TextDataService) encryptPayload(userKey, clientCryptData []byte, text model.TextCryptData) ([]byte, bool, error) {
+	if len(clientCryptData) > 0 {
+		return clientCryptData, true, nil
+	}
+
+	data, err := json.Marshal(text)
+	if err != nil {
+		return nil, false, fmt.Errorf("marshal: %w", err)
+	}
+
+	cryptData, err := s.crypt.Encrypt(userKey, data)
+	if err != nil {
+		return nil, false, fmt.Errorf("encrypt data: %w", err)
+	}
+
+	return cryptData, false, nil
+}
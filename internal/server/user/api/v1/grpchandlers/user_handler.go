@@ -15,14 +15,23 @@ import (
 
 // UserService interface defines methods for user-related operations
 type UserService interface {
-	Register(ctx context.Context, user model.UserRegisterRequest) (string, error)
-	Login(ctx context.Context, user model.UserLoginRequest) (string, error)
+	Register(ctx context.Context, user model.UserRegisterRequest) (model.TokenPair, error)
+	Login(ctx context.Context, user model.UserLoginRequest) (model.UserLoginResponse, error)
+	RotateKey(ctx context.Context) (int, error)
+	Refresh(ctx context.Context, refreshToken string) (model.TokenPair, error)
+	Logout(ctx context.Context) error
+	LogoutAll(ctx context.Context) (int, error)
+	EnrollTOTP(ctx context.Context) (model.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, code string) error
+	DisableTOTP(ctx context.Context, code string) error
 }
 
 // Validator interface defines methods for validating user requests
 type Validator interface {
 	ValidateLoginRequest(req *model.UserLoginRequest) (map[string]string, bool)
 	ValidateRegisterRequest(req *model.UserRegisterRequest) (map[string]string, bool)
+	ValidateRefreshRequest(req *model.UserRefreshRequest) (map[string]string, bool)
+	ValidateTOTPCodeRequest(req *model.UserTOTPCodeRequest) (map[string]string, bool)
 }
 
 // UserHandler handles user-related gRPC requests
@@ -43,8 +52,10 @@ func New(userService UserService, validator Validator) *UserHandler {
 // PostRegisterUser handles user registration via gRPC
 func (h *UserHandler) PostRegisterUser(ctx context.Context, in *pb.PostUserRegisterRequest) (*pb.PostUserRegisterResponse, error) {
 	req := model.UserRegisterRequest{
-		Login:    in.Login,
-		Password: in.Password,
+		Login:                in.Login,
+		Password:             in.Password,
+		ClientSideEncryption: in.ClientSideEncryption,
+		WrappedDataKey:       in.WrappedDataKey,
 	}
 
 	report, ok := h.validator.ValidateRegisterRequest(&req)
@@ -55,7 +66,7 @@ func (h *UserHandler) PostRegisterUser(ctx context.Context, in *pb.PostUserRegis
 		return nil, lib.ProcessValidationError("invalid user request", report)
 	}
 
-	token, err := h.userService.Register(ctx, req)
+	tokens, err := h.userService.Register(ctx, req)
 	if errors.Is(err, cerrors.ErrUserAlreadyExists) {
 		logrus.Info("Unable to register user: user already exists")
 		logrus.Infof("user_login %v", req.Login)
@@ -69,7 +80,7 @@ func (h *UserHandler) PostRegisterUser(ctx context.Context, in *pb.PostUserRegis
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	return &pb.PostUserRegisterResponse{Token: token}, nil
+	return &pb.PostUserRegisterResponse{Token: tokens.Token, RefreshToken: tokens.RefreshToken}, nil
 }
 
 // PostLoginUser handles user login via gRPC
@@ -77,6 +88,7 @@ func (h *UserHandler) PostLoginUser(ctx context.Context, in *pb.PostUserLoginReq
 	req := model.UserLoginRequest{
 		Login:    in.Login,
 		Password: in.Password,
+		TOTPCode: in.TotpCode,
 	}
 
 	report, ok := h.validator.ValidateLoginRequest(&req)
@@ -87,7 +99,7 @@ func (h *UserHandler) PostLoginUser(ctx context.Context, in *pb.PostUserLoginReq
 		return nil, lib.ProcessValidationError("invalid user request", report)
 	}
 
-	token, err := h.userService.Login(ctx, req)
+	resp, err := h.userService.Login(ctx, req)
 	if errors.Is(err, cerrors.ErrUserNotFound) {
 		logrus.Info("Unable to login user: user not found")
 		logrus.Infof("user_login %v", req.Login)
@@ -100,11 +112,175 @@ func (h *UserHandler) PostLoginUser(ctx context.Context, in *pb.PostUserLoginReq
 		return nil, status.Error(codes.Unauthenticated, "incorrect password")
 	}
 
+	if errors.Is(err, cerrors.ErrTOTPRequired) {
+		logrus.Info("Unable to login user: two-factor code required")
+		logrus.Infof("user_login %v", req.Login)
+		return nil, status.Error(codes.FailedPrecondition, "two-factor code required")
+	}
+
+	if errors.Is(err, cerrors.ErrInvalidTOTPCode) {
+		logrus.Info("Unable to login user: invalid two-factor code")
+		logrus.Infof("user_login %v", req.Login)
+		return nil, status.Error(codes.Unauthenticated, "invalid two-factor code")
+	}
+
+	if errors.Is(err, cerrors.ErrTooManyAttempts) {
+		logrus.Info("Unable to login user: too many two-factor attempts")
+		logrus.Infof("user_login %v", req.Login)
+		return nil, status.Error(codes.ResourceExhausted, "too many two-factor attempts, try again later")
+	}
+
 	if err != nil {
 		logrus.WithError(err).Error("Unable to login user: invalid user request")
 		logrus.Infof("user_login %v", req.Login)
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	return &pb.PostUserLoginResponse{Token: token}, nil
+	return &pb.PostUserLoginResponse{Token: resp.Token, RefreshToken: resp.RefreshToken, WrappedDataKey: resp.WrappedDataKey}, nil
+}
+
+// PostRotateUserKey handles rotation of the user key via gRPC
+func (h *UserHandler) PostRotateUserKey(ctx context.Context, _ *pb.PostRotateUserKeyRequest) (*pb.PostRotateUserKeyResponse, error) {
+	count, err := h.userService.RotateKey(ctx)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to rotate user key: rotated concurrently")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to rotate user key")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PostRotateUserKeyResponse{Reencrypted: int32(count)}, nil
+}
+
+// PostRefreshToken handles the exchange of a refresh token for new tokens via gRPC
+func (h *UserHandler) PostRefreshToken(ctx context.Context, in *pb.PostRefreshTokenRequest) (*pb.PostRefreshTokenResponse, error) {
+	req := model.UserRefreshRequest{RefreshToken: in.RefreshToken}
+
+	report, ok := h.validator.ValidateRefreshRequest(&req)
+	if !ok {
+		logrus.Info("Unable to refresh token: invalid refresh request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid refresh request", report)
+	}
+
+	tokens, err := h.userService.Refresh(ctx, req.RefreshToken)
+	if errors.Is(err, cerrors.ErrInvalidRefreshToken) {
+		logrus.Info("Unable to refresh token: refresh token is invalid, expired or revoked")
+		return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to refresh token")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PostRefreshTokenResponse{Token: tokens.Token, RefreshToken: tokens.RefreshToken}, nil
+}
+
+// PostLogout handles revocation of the current session via gRPC
+func (h *UserHandler) PostLogout(ctx context.Context, _ *pb.PostLogoutRequest) (*pb.PostLogoutResponse, error) {
+	if err := h.userService.Logout(ctx); err != nil {
+		logrus.WithError(err).Error("Unable to logout user")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PostLogoutResponse{}, nil
+}
+
+// PostLogoutAllSessions handles revocation of all sessions of the user via gRPC
+func (h *UserHandler) PostLogoutAllSessions(ctx context.Context, _ *pb.PostLogoutAllSessionsRequest) (*pb.PostLogoutAllSessionsResponse, error) {
+	count, err := h.userService.LogoutAll(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to logout all sessions of user")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PostLogoutAllSessionsResponse{Revoked: int32(count)}, nil
+}
+
+// PostEnrollTOTP handles enrollment of the second factor via gRPC
+func (h *UserHandler) PostEnrollTOTP(ctx context.Context, _ *pb.PostEnrollTOTPRequest) (*pb.PostEnrollTOTPResponse, error) {
+	enrollment, err := h.userService.EnrollTOTP(ctx)
+	if errors.Is(err, cerrors.ErrUserKeyRotated) {
+		logrus.Info("Unable to enroll two-factor authentication: user key was rotated")
+		return nil, status.Error(codes.Aborted, "user key was rotated, retry the request")
+	}
+
+	if errors.Is(err, cerrors.ErrTOTPAlreadyEnabled) {
+		logrus.Info("Unable to enroll two-factor authentication: already enabled")
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Unable to enroll two-factor authentication")
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.PostEnrollTOTPResponse{
+		Uri:           enrollment.URI,
+		Secret:        enrollment.Secret,
+		RecoveryCodes: enrollment.RecoveryCodes,
+	}, nil
+}
+
+// PostConfirmTOTP handles confirmation of the enrolled second factor via gRPC
+func (h *UserHandler) PostConfirmTOTP(ctx context.Context, in *pb.PostConfirmTOTPRequest) (*pb.PostConfirmTOTPResponse, error) {
+	req := model.UserTOTPCodeRequest{Code: in.Code}
+
+	report, ok := h.validator.ValidateTOTPCodeRequest(&req)
+	if !ok {
+		logrus.Info("Unable to confirm two-factor authentication: invalid code request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid code request", report)
+	}
+
+	err := h.userService.ConfirmTOTP(ctx, req.Code)
+	if err != nil {
+		return nil, h.processTOTPError("Unable to confirm two-factor authentication", err)
+	}
+
+	return &pb.PostConfirmTOTPResponse{}, nil
+}
+
+// PostDisableTOTP handles turning off the second factor via gRPC
+func (h *UserHandler) PostDisableTOTP(ctx context.Context, in *pb.PostDisableTOTPRequest) (*pb.PostDisableTOTPResponse, error) {
+	req := model.UserTOTPCodeRequest{Code: in.Code}
+
+	report, ok := h.validator.ValidateTOTPCodeRequest(&req)
+	if !ok {
+		logrus.Info("Unable to disable two-factor authentication: invalid code request")
+		logrus.Infof("violated_fields %v", report)
+		return nil, lib.ProcessValidationError("invalid code request", report)
+	}
+
+	err := h.userService.DisableTOTP(ctx, req.Code)
+	if err != nil {
+		return nil, h.processTOTPError("Unable to disable two-factor authentication", err)
+	}
+
+	return &pb.PostDisableTOTPResponse{}, nil
+}
+
+// processTOTPError converts an error of confirming or disabling the second factor into a gRPC status error
+func (h *UserHandler) processTOTPError(msg string, err error) error {
+	switch {
+	case errors.Is(err, cerrors.ErrTOTPNotEnrolled):
+		logrus.Infof("%s: not enrolled", msg)
+		return status.Error(codes.FailedPrecondition, "two-factor authentication is not enrolled")
+	case errors.Is(err, cerrors.ErrTOTPAlreadyEnabled):
+		logrus.Infof("%s: already enabled", msg)
+		return status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	case errors.Is(err, cerrors.ErrInvalidTOTPCode):
+		logrus.Infof("%s: invalid code", msg)
+		return status.Error(codes.InvalidArgument, "invalid two-factor code")
+	case errors.Is(err, cerrors.ErrTooManyAttempts):
+		logrus.Infof("%s: too many attempts", msg)
+		return status.Error(codes.ResourceExhausted, "too many two-factor attempts, try again later")
+	default:
+		logrus.WithError(err).Error(msg)
+		return status.Error(codes.Internal, "internal error")
+	}
 }
@@ -38,6 +38,44 @@ func (v *Validator) ValidateLoginRequest(req *model.UserLoginRequest) (map[strin
 	return nil, true
 }
 
+// ValidateRefreshRequest validates the refresh token request structure
+func (v *Validator) ValidateRefreshRequest(req *model.UserRefreshRequest) (map[string]string, bool) {
+	err := v.validator.Struct(req)
+	report := make(map[string]string)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				if validationErr.Tag() == "required" {
+					report[validationErr.Field()] = "is required"
+				}
+			}
+			return report, false
+		}
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+	return nil, true
+}
+
+// ValidateTOTPCodeRequest validates the second factor code request structure
+func (v *Validator) ValidateTOTPCodeRequest(req *model.UserTOTPCodeRequest) (map[string]string, bool) {
+	err := v.validator.Struct(req)
+	report := make(map[string]string)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				if validationErr.Tag() == "required" {
+					report[validationErr.Field()] = "is required"
+				}
+			}
+			return report, false
+		}
+		return map[string]string{"error": "unknown validation error"}, false
+	}
+	return nil, true
+}
+
 // ValidateRegisterRequest validates the registration request structure
 func (v *Validator) ValidateRegisterRequest(req *model.UserRegisterRequest) (map[string]string, bool) {
 	err := v.validator.Struct(req)
@@ -51,6 +89,8 @@ func (v *Validator) ValidateRegisterRequest(req *model.UserRegisterRequest) (map
 					report[validationErr.Field()] = "must be valid email"
 				case "required":
 					report[validationErr.Field()] = "is required"
+				case "required_if":
+					report[validationErr.Field()] = "is required when client side encryption is enabled"
 				}
 			}
 			return report, false
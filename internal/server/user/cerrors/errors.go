@@ -3,7 +3,29 @@ package cerrors
 import "errors"
 
 var (
-	ErrUserAlreadyExists = errors.New("user with this login already exists")
-	ErrUserNotFound      = errors.New("user not found")
-	ErrInvalidPassword   = errors.New("invalid password")
+	ErrUserAlreadyExists    = errors.New("user with this login already exists")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrInvalidPassword      = errors.New("invalid password")
+	ErrDataNotFound         = errors.New("data not found")
+	ErrDataExists           = errors.New("data with this id already exists")
+	ErrChunkedData          = errors.New("binary data is stored in chunks")
+	ErrRevisionConflict     = errors.New("data was changed by another client")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrInvalidRefreshToken  = errors.New("refresh token is invalid, expired or revoked")
+	ErrTOTPRequired         = errors.New("two-factor code required")
+	ErrInvalidTOTPCode      = errors.New("invalid two-factor code")
+	ErrTOTPNotEnrolled      = errors.New("two-factor authentication is not enrolled")
+	ErrTOTPAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrUserKeyRotated       = errors.New("user key was rotated")
+	ErrTooManyAttempts      = errors.New("too many two-factor attempts, try again later")
+	ErrInvalidPageToken     = errors.New("invalid page token")
+	ErrTemplateNotFound     = errors.New("template not found")
+	ErrTemplateExists       = errors.New("template with this name already exists")
+	ErrNoBreachList         = errors.New("breach list is not configured")
+	ErrCollectionNotFound   = errors.New("collection not found")
+	ErrPermissionDenied     = errors.New("role in the collection does not permit this")
+	ErrMemberExists         = errors.New("user is already a member of the collection")
+	ErrMemberNotFound       = errors.New("member not found")
+	ErrLastManager          = errors.New("collection must keep at least one manager")
+	ErrClientSideEncryption = errors.New("shared collections are not available with client side encryption")
 )
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// InsertSession adds a new login session of a user to the database
+func (r *PostgresUserRepository) InsertSession(ctx context.Context, session model.Session) error {
+	_, err := r.postgresPool.DB.Exec(ctx,
+		`
+			insert into privatekeeper.user_session
+			    (id, user_id, refresh_token_hash, created_at, expires_at)
+			values
+				($1, $2, $3, now(), $4);
+			`,
+		session.ID,
+		session.UserID,
+		session.RefreshTokenHash,
+		session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("insert session: %w", err)
+	}
+
+	return nil
+}
+
+// SelectSession retrieves a login session by its ID
+func (r *PostgresUserRepository) SelectSession(ctx context.Context, sessionID string) (model.Session, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    id, user_id, refresh_token_hash, created_at, expires_at, revoked_at
+			from privatekeeper.user_session
+			where id = $1;
+			`,
+		sessionID)
+	if err != nil {
+		return model.Session{}, fmt.Errorf("make query: %w", err)
+	}
+
+	session, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.Session])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Session{}, fmt.Errorf("collect row: %w", cerrors.ErrSessionNotFound)
+	}
+
+	if err != nil {
+		return model.Session{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return session, nil
+}
+
+// UpdateSessionToken replaces the refresh token hash of an active session if it still equals oldHash,
+// so a refresh token can be exchanged only once. It returns cerrors.ErrInvalidRefreshToken otherwise.
+func (r *PostgresUserRepository) UpdateSessionToken(ctx context.Context, sessionID string, oldHash, newHash []byte, expiresAt time.Time) error {
+	tag, err := r.postgresPool.DB.Exec(ctx,
+		`
+			update privatekeeper.user_session
+			set refresh_token_hash = $3, expires_at = $4
+			where id = $1 and refresh_token_hash = $2 and revoked_at is null and expires_at > now();
+			`,
+		sessionID, oldHash, newHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("make query: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update session: %w", cerrors.ErrInvalidRefreshToken)
+	}
+
+	return nil
+}
+
+// RevokeSession revokes a login session of a user
+func (r *PostgresUserRepository) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	_, err := r.postgresPool.DB.Exec(ctx,
+		`
+			update privatekeeper.user_session
+			set revoked_at = now()
+			where user_id = $1 and id = $2 and revoked_at is null;
+			`,
+		userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("make query: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes all active login sessions of a user and returns their IDs
+func (r *PostgresUserRepository) RevokeUserSessions(ctx context.Context, userID string) ([]string, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			update privatekeeper.user_session
+			set revoked_at = now()
+			where user_id = $1 and revoked_at is null and expires_at > now()
+			returning id;
+			`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("make query: %w", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return ids, nil
+}
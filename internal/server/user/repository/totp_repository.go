@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/storage/postgresql"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// SelectTOTP retrieves the second factor settings of a user
+func (r *PostgresUserRepository) SelectTOTP(ctx context.Context, userID string) (model.UserTOTP, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select
+			    user_id, secret, enabled, last_used_step, created_at
+			from privatekeeper.user_totp
+			where user_id = $1;
+			`,
+		userID)
+	if err != nil {
+		return model.UserTOTP{}, fmt.Errorf("make query: %w", err)
+	}
+
+	totp, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.UserTOTP])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.UserTOTP{}, fmt.Errorf("collect row: %w", cerrors.ErrTOTPNotEnrolled)
+	}
+
+	if err != nil {
+		return model.UserTOTP{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return totp, nil
+}
+
+// InsertTOTP saves a not yet enabled second factor of a user together with the hashes of its recovery codes,
+// replacing a previous unconfirmed enrollment. The secret is encrypted with the user key, which is locked
+// with postgresql.LockUserKey. It returns cerrors.ErrTOTPAlreadyEnabled if the second factor
+// of the user is already enabled.
+func (r *PostgresUserRepository) InsertTOTP(ctx context.Context, totp model.UserTOTP, recoveryCodeHashes [][]byte) error {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err = postgresql.LockUserKey(ctx, tx, totp.UserID); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx,
+		`
+			insert into privatekeeper.user_totp
+			    (user_id, secret, enabled, last_used_step, created_at)
+			values
+				($1, $2, false, 0, now())
+			on conflict (user_id) do update
+			set secret = excluded.secret, last_used_step = 0, created_at = now()
+			where user_totp.enabled = false;
+			`,
+		totp.UserID,
+		totp.Secret)
+	if err != nil {
+		return fmt.Errorf("insert totp: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("insert totp: %w", cerrors.ErrTOTPAlreadyEnabled)
+	}
+
+	_, err = tx.Exec(ctx,
+		`
+			delete from privatekeeper.user_recovery_code
+			where user_id = $1;
+			`,
+		totp.UserID)
+	if err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.Exec(ctx,
+			`
+				insert into privatekeeper.user_recovery_code
+				    (user_id, code_hash)
+				values
+					($1, $2);
+				`,
+			totp.UserID,
+			hash)
+		if err != nil {
+			return fmt.Errorf("insert recovery code: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
+
+// EnableTOTP enables the enrolled second factor of a user and remembers the time step of the confirming code.
+// It returns cerrors.ErrTOTPAlreadyEnabled if the second factor is already enabled.
+func (r *PostgresUserRepository) EnableTOTP(ctx context.Context, userID string, step int64) error {
+	tag, err := r.postgresPool.DB.Exec(ctx,
+		`
+			update privatekeeper.user_totp
+			set enabled = true, last_used_step = $2
+			where user_id = $1 and enabled = false;
+			`,
+		userID, step)
+	if err != nil {
+		return fmt.Errorf("make query: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("enable totp: %w", cerrors.ErrTOTPAlreadyEnabled)
+	}
+
+	return nil
+}
+
+// UseTOTPStep marks the time step of a code as used, so every code is accepted only once.
+// It returns cerrors.ErrInvalidTOTPCode if a code of the same or a later step has already been used.
+func (r *PostgresUserRepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	tag, err := r.postgresPool.DB.Exec(ctx,
+		`
+			update privatekeeper.user_totp
+			set last_used_step = $2
+			where user_id = $1 and last_used_step < $2;
+			`,
+		userID, step)
+	if err != nil {
+		return fmt.Errorf("make query: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("use totp step: %w", cerrors.ErrInvalidTOTPCode)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code of a user as used.
+// It returns cerrors.ErrInvalidTOTPCode if there is no such unused code.
+func (r *PostgresUserRepository) UseRecoveryCode(ctx context.Context, userID string, codeHash []byte) error {
+	tag, err := r.postgresPool.DB.Exec(ctx,
+		`
+			update privatekeeper.user_recovery_code
+			set used_at = now()
+			where user_id = $1 and code_hash = $2 and used_at is null;
+			`,
+		userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("make query: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("use recovery code: %w", cerrors.ErrInvalidTOTPCode)
+	}
+
+	return nil
+}
+
+// DeleteTOTP removes the second factor of a user together with its recovery codes
+func (r *PostgresUserRepository) DeleteTOTP(ctx context.Context, userID string) error {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	_, err = tx.Exec(ctx,
+		`
+			delete from privatekeeper.user_recovery_code
+			where user_id = $1;
+			`,
+		userID)
+	if err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`
+			delete from privatekeeper.user_totp
+			where user_id = $1;
+			`,
+		userID)
+	if err != nil {
+		return fmt.Errorf("delete totp: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
+
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/storage/postgresql"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
@@ -27,7 +29,7 @@ func (r *PostgresUserRepository) SelectByLogin(ctx context.Context, login string
 	rows, err := r.postgresPool.DB.Query(ctx,
 		`
 			select 
-				id, login, password, crypt_key, created_at
+				id, login, password, crypt_key, created_at, client_side_encryption, wrapped_data_key, crypt_key_version, crypt_key_generation
 			from privatekeeper.user
 			where login = $1;
 			`,
@@ -48,38 +50,358 @@ func (r *PostgresUserRepository) SelectByLogin(ctx context.Context, login string
 	return savedUser, nil
 }
 
-// SelectKeyByID retrieves the cryptographic key for a user by their ID
-func (r *PostgresUserRepository) SelectKeyByID(ctx context.Context, userID string) ([]byte, error) {
-	var userKey []byte
+// SelectByID retrieves a user from the database based on the ID
+func (r *PostgresUserRepository) SelectByID(ctx context.Context, userID string) (model.User, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select 
+				id, login, password, crypt_key, created_at, client_side_encryption, wrapped_data_key, crypt_key_version, crypt_key_generation
+			from privatekeeper.user
+			where id = $1;
+			`,
+		userID)
+	if err != nil {
+		return model.User{}, fmt.Errorf("select user: %w", err)
+	}
+
+	user, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[model.User])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.User{}, cerrors.ErrUserNotFound
+		}
+
+		return model.User{}, fmt.Errorf("select user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SelectKeyByID retrieves the cryptographic key and its master key version for a user by their ID
+func (r *PostgresUserRepository) SelectKeyByID(ctx context.Context, userID string) (model.UserCryptKey, error) {
+	var userKey model.UserCryptKey
 	err := r.postgresPool.DB.QueryRow(ctx,
 		`
 			select 
-				crypt_key
+				id, crypt_key, crypt_key_version, crypt_key_generation
 			from privatekeeper.user
 			where id = $1;
 			`,
-		userID).Scan(&userKey)
+		userID).Scan(&userKey.UserID, &userKey.CryptKey, &userKey.Version, &userKey.Generation)
 	if err != nil {
-		return nil, fmt.Errorf("query error: %w", err)
+		return model.UserCryptKey{}, fmt.Errorf("query error: %w", err)
 	}
 
 	return userKey, nil
 }
 
+// SelectKeyGenerationByID retrieves the generation of the key of a user by their ID.
+// It returns cerrors.ErrUserNotFound if there is no such user.
+func (r *PostgresUserRepository) SelectKeyGenerationByID(ctx context.Context, userID string) (int64, error) {
+	var generation int64
+	err := r.postgresPool.DB.QueryRow(ctx,
+		`
+			select 
+				crypt_key_generation
+			from privatekeeper.user
+			where id = $1;
+			`,
+		userID).Scan(&generation)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, cerrors.ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("query error: %w", err)
+	}
+
+	return generation, nil
+}
+
+// SelectKeysByVersionNot retrieves a batch of user keys not wrapped with the given master key version.
+// Users are ordered by id, afterID is the last id of the previous batch.
+func (r *PostgresUserRepository) SelectKeysByVersionNot(ctx context.Context, version int, afterID string, limit int) ([]model.UserCryptKey, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select 
+				id, crypt_key, crypt_key_version, crypt_key_generation
+			from privatekeeper.user
+			where crypt_key_version <> $1 and id > $2
+			order by id
+			limit $3;
+			`,
+		version,
+		afterID,
+		limit)
+	if err != nil {
+		return nil, fmt.Errorf("make query: %w", err)
+	}
+
+	keys, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.UserCryptKey])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return keys, nil
+}
+
+// UpdateKey replaces the wrapped cryptographic key of a user if it is still wrapped with oldVersion.
+// It returns cerrors.ErrUserNotFound if the user is gone or the key was changed concurrently.
+func (r *PostgresUserRepository) UpdateKey(ctx context.Context, key model.UserCryptKey, oldVersion int) error {
+	tag, err := r.postgresPool.DB.Exec(ctx,
+		`
+			update privatekeeper.user
+			set crypt_key = $1, crypt_key_version = $2
+			where id = $3 and crypt_key_version = $4;
+			`,
+		key.CryptKey,
+		key.Version,
+		key.UserID,
+		oldVersion)
+	if err != nil {
+		return fmt.Errorf("make query: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return cerrors.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SelectIDsByKeyRotatedBefore retrieves a batch of ids of users whose key was last rotated before the given time.
+// Users are ordered by id, afterID is the last id of the previous batch.
+func (r *PostgresUserRepository) SelectIDsByKeyRotatedBefore(ctx context.Context, before time.Time, afterID string, limit int) ([]string, error) {
+	rows, err := r.postgresPool.DB.Query(ctx,
+		`
+			select 
+				id
+			from privatekeeper.user
+			where crypt_key_rotated_at < $1 and id > $2
+			order by id
+			limit $3;
+			`,
+		before,
+		afterID,
+		limit)
+	if err != nil {
+		return nil, fmt.Errorf("make query: %w", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return ids, nil
+}
+
+// RotateKey re-encrypts in batches all server side encrypted data, the second factor secret and the collection keys
+// of the user with reencrypt and replaces the wrapped user key, all in a single transaction.
+// key.Generation is the generation of the replaced key, the new key gets the next one. The user row is locked
+// for the whole rotation, so concurrent rotations of the same user and writes of data encrypted with the
+// replaced key are serialized with it. It returns cerrors.ErrUserKeyRotated if the key was rotated since
+// its generation was read, and the number of re-encrypted data rows otherwise.
+func (r *PostgresUserRepository) RotateKey(ctx context.Context, key model.UserCryptKey, reencrypt func(data []byte) ([]byte, error), batchSize int) (int, error) {
+	tx, err := r.postgresPool.DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var generation int64
+	err = tx.QueryRow(ctx,
+		`
+			select 
+				crypt_key_generation
+			from privatekeeper.user
+			where id = $1
+			for update;
+			`,
+		key.UserID).Scan(&generation)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, cerrors.ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("lock user: %w", err)
+	}
+	if generation != key.Generation {
+		return 0, fmt.Errorf("lock user: %w", cerrors.ErrUserKeyRotated)
+	}
+
+	type dataRow struct {
+		ID   string `db:"id"`
+		Type string `db:"type"`
+		Data []byte `db:"data"`
+	}
+
+	total := 0
+	afterID, afterType := "", ""
+	for {
+		rows, err := tx.Query(ctx,
+			`
+				select 
+					id, type, data
+				from privatekeeper.data
+				where owner_id = $1 and client_encrypted = false and (id, type::text) > ($2, $3)
+				order by id, type::text
+				limit $4
+				for update;
+				`,
+			key.UserID,
+			afterID,
+			afterType,
+			batchSize)
+		if err != nil {
+			return 0, fmt.Errorf("select data: %w", err)
+		}
+
+		batch, err := pgx.CollectRows(rows, pgx.RowToStructByPos[dataRow])
+		if err != nil {
+			return 0, fmt.Errorf("collect rows: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, row := range batch {
+			data, err := reencrypt(row.Data)
+			if err != nil {
+				return 0, fmt.Errorf("reencrypt data %s: %w", row.ID, err)
+			}
+
+			_, err = tx.Exec(ctx,
+				`
+					update privatekeeper.data
+					set data = $1
+					where owner_id = $2 and type = $3 and id = $4;
+					`,
+				data,
+				key.UserID,
+				row.Type,
+				row.ID)
+			if err != nil {
+				return 0, fmt.Errorf("update data: %w", err)
+			}
+		}
+
+		total += len(batch)
+		last := batch[len(batch)-1]
+		afterID, afterType = last.ID, last.Type
+	}
+
+	var totpSecret []byte
+	err = tx.QueryRow(ctx,
+		`
+			select 
+				secret
+			from privatekeeper.user_totp
+			where user_id = $1
+			for update;
+			`,
+		key.UserID).Scan(&totpSecret)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("select totp: %w", err)
+	}
+
+	if err == nil {
+		totpSecret, err = reencrypt(totpSecret)
+		if err != nil {
+			return 0, fmt.Errorf("reencrypt totp secret: %w", err)
+		}
+
+		_, err = tx.Exec(ctx,
+			`
+				update privatekeeper.user_totp
+				set secret = $1
+				where user_id = $2;
+				`,
+			totpSecret,
+			key.UserID)
+		if err != nil {
+			return 0, fmt.Errorf("update totp: %w", err)
+		}
+	}
+
+	rows, err := tx.Query(ctx,
+		`
+			select
+				collection_id, crypt_key
+			from privatekeeper.collection_member
+			where user_id = $1
+			for update;
+			`,
+		key.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("select collection keys: %w", err)
+	}
+
+	type collectionKey struct {
+		CollectionID string `db:"collection_id"`
+		CryptKey     []byte `db:"crypt_key"`
+	}
+
+	collectionKeys, err := pgx.CollectRows(rows, pgx.RowToStructByPos[collectionKey])
+	if err != nil {
+		return 0, fmt.Errorf("collect rows: %w", err)
+	}
+
+	for _, ck := range collectionKeys {
+		cryptKey, err := reencrypt(ck.CryptKey)
+		if err != nil {
+			return 0, fmt.Errorf("reencrypt key of collection %s: %w", ck.CollectionID, err)
+		}
+
+		_, err = tx.Exec(ctx,
+			`
+				update privatekeeper.collection_member
+				set crypt_key = $1
+				where collection_id = $2 and user_id = $3;
+				`,
+			cryptKey,
+			ck.CollectionID,
+			key.UserID)
+		if err != nil {
+			return 0, fmt.Errorf("update collection key: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx,
+		`
+			update privatekeeper.user
+			set crypt_key = $1, crypt_key_version = $2, crypt_key_generation = crypt_key_generation + 1,
+			    crypt_key_rotated_at = NOW()
+			where id = $3;
+			`,
+		key.CryptKey,
+		key.Version,
+		key.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("update key: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return total, nil
+}
+
 // Insert adds a new user to the database
 func (r *PostgresUserRepository) Insert(ctx context.Context, user model.User) (model.User, error) {
 	rows, err := r.postgresPool.DB.Query(ctx,
 		`
 			insert into privatekeeper.user
-				(id, login, password, crypt_key, created_at)
+				(id, login, password, crypt_key, created_at, client_side_encryption, wrapped_data_key, crypt_key_version)
 			values
-				($1, $2, $3, $4, NOW())
-			returning id, login, password, crypt_key, created_at;
+				($1, $2, $3, $4, NOW(), $5, $6, $7)
+			returning id, login, password, crypt_key, created_at, client_side_encryption, wrapped_data_key, crypt_key_version, crypt_key_generation;
 			`,
 		user.ID,
 		user.Login,
 		user.Password,
-		user.CryptKey)
+		user.CryptKey,
+		user.ClientSideEncryption,
+		user.WrappedDataKey,
+		user.CryptKeyVersion)
 	if err != nil {
 		return model.User{}, fmt.Errorf("make query: %w", err)
 	}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/encryption"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/storage/postgresql"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+)
+
+// envTestDatabaseURI holds the database the tests of the repository are run against, they are skipped without it.
+const envTestDatabaseURI = "TEST_DATABASE_URI"
+
+type UserRepositoryTestSuite struct {
+	suite.Suite
+	pool         *postgresql.PostgresPool
+	repo         *PostgresUserRepository
+	crypt        *encryption.Service
+	userID       string
+	key          []byte            // Current unwrapped key of the user
+	collectionID string            // Collection the user is the manager of
+	plain        map[string][]byte // Payloads of the server side encrypted data by id and type
+	client       map[string][]byte // Stored payloads of the client side encrypted data by id and type
+}
+
+func TestSuite(t *testing.T) {
+	if os.Getenv(envTestDatabaseURI) == "" {
+		t.Skipf("%s is not set", envTestDatabaseURI)
+	}
+	suite.Run(t, new(UserRepositoryTestSuite))
+}
+
+func (s *UserRepositoryTestSuite) SetupSuite() {
+	ctx := context.Background()
+	pool, err := postgresql.NewPool(ctx, os.Getenv(envTestDatabaseURI))
+	s.Require().NoError(err)
+	s.pool = pool
+
+	migrations, err := postgresql.NewMigrations(pool)
+	s.Require().NoError(err)
+	s.Require().NoError(migrations.Up())
+
+	s.repo = New(pool)
+	s.crypt, err = encryption.New([]byte("test-master-key"))
+	s.Require().NoError(err)
+}
+
+func (s *UserRepositoryTestSuite) TearDownSuite() {
+	s.pool.DB.Close()
+}
+
+// SetupTest creates a user with server and client side encrypted data of several types, a second factor
+// and a collection, all encrypted with the key of the user.
+func (s *UserRepositoryTestSuite) SetupTest() {
+	ctx := context.Background()
+	var err error
+	s.key, err = s.crypt.GenerateKey()
+	s.Require().NoError(err)
+	cryptKey, err := s.crypt.EncryptWithMasterKey(s.key)
+	s.Require().NoError(err)
+
+	s.userID = uuid.NewString()
+	_, err = s.pool.DB.Exec(ctx,
+		`
+			insert into privatekeeper.user (id, login, password, crypt_key, created_at, crypt_key_version)
+			values ($1, $2, '', $3, now(), $4);
+			`,
+		s.userID, "repository-test-"+s.userID, cryptKey, s.crypt.Version())
+	s.Require().NoError(err)
+
+	s.plain = map[string][]byte{}
+	s.client = map[string][]byte{}
+	// The same id in two partitions checks that the batches are ordered by id and type
+	sharedID := uuid.NewString()
+	for i, entry := range []struct{ id, dataType string }{
+		{sharedID, "text_data"},
+		{sharedID, "credentials"},
+		{uuid.NewString(), "credit_card"},
+		{uuid.NewString(), "binary_data"},
+		{uuid.NewString(), "text_data"},
+		{uuid.NewString(), "credentials"},
+	} {
+		payload := []byte(fmt.Sprintf("payload %d", i))
+		s.insertData(entry.id, entry.dataType, s.encrypt(payload), false)
+		s.plain[entry.id+"/"+entry.dataType] = payload
+	}
+	for i := 0; i < 2; i++ {
+		id, payload := uuid.NewString(), []byte(fmt.Sprintf("client ciphertext %d", i))
+		s.insertData(id, "text_data", payload, true)
+		s.client[id+"/text_data"] = payload
+	}
+
+	_, err = s.pool.DB.Exec(ctx,
+		`
+			insert into privatekeeper.user_totp (user_id, secret, enabled, last_used_step, created_at)
+			values ($1, $2, true, 0, now());
+			`,
+		s.userID, s.encrypt([]byte("totp secret")))
+	s.Require().NoError(err)
+
+	s.collectionID = uuid.NewString()
+	_, err = s.pool.DB.Exec(ctx,
+		`insert into privatekeeper.collection (id, name, created_at) values ($1, 'team', now());`, s.collectionID)
+	s.Require().NoError(err)
+	_, err = s.pool.DB.Exec(ctx,
+		`
+			insert into privatekeeper.collection_member (collection_id, user_id, role, status, crypt_key, invited_by)
+			values ($1, $2, $3, $4, $5, $2);
+			`,
+		s.collectionID, s.userID, model.RoleManage, model.MemberActive, s.encrypt([]byte("collection key")))
+	s.Require().NoError(err)
+}
+
+func (s *UserRepositoryTestSuite) TearDownTest() {
+	ctx := context.Background()
+	_, err := s.pool.DB.Exec(ctx, `delete from privatekeeper.data where owner_id = $1;`, s.userID)
+	s.NoError(err)
+	_, err = s.pool.DB.Exec(ctx, `delete from privatekeeper.collection where id = $1;`, s.collectionID)
+	s.NoError(err)
+	_, err = s.pool.DB.Exec(ctx, `delete from privatekeeper.user where id = $1;`, s.userID)
+	s.NoError(err)
+}
+
+// Test_RotateKey rotates the key several times with batch sizes below, at and above the number of rows,
+// every rotation must re-encrypt everything the previous key encrypted and leave client side encrypted data as is.
+func (s *UserRepositoryTestSuite) Test_RotateKey() {
+	ctx := context.Background()
+	for _, batchSize := range []int{1, 2, 3, 6, 7} {
+		oldKey := s.key
+		generation := s.generation()
+
+		newKey, err := s.crypt.GenerateKey()
+		require.NoError(s.T(), err)
+		count, err := s.repo.RotateKey(ctx, s.newCryptKey(newKey, generation), s.reencrypt(oldKey, newKey, -1), batchSize)
+		require.NoError(s.T(), err, "batch size %d", batchSize)
+		assert.Equal(s.T(), len(s.plain), count, "batch size %d", batchSize)
+		assert.Equal(s.T(), generation+1, s.generation())
+		s.key = newKey
+
+		s.assertEncryptedWith(newKey)
+		_, err = s.crypt.Decrypt(oldKey, s.totpSecret())
+		assert.Error(s.T(), err, "the old key must not decrypt the second factor secret")
+	}
+}
+
+// Test_FailedRotationKeepsOldKey fails the rotation in the second batch, nothing may change then.
+func (s *UserRepositoryTestSuite) Test_FailedRotationKeepsOldKey() {
+	ctx := context.Background()
+	generation := s.generation()
+	newKey, err := s.crypt.GenerateKey()
+	require.NoError(s.T(), err)
+
+	_, err = s.repo.RotateKey(ctx, s.newCryptKey(newKey, generation), s.reencrypt(s.key, newKey, 3), 2)
+	require.Error(s.T(), err)
+
+	assert.Equal(s.T(), generation, s.generation())
+	s.assertEncryptedWith(s.key)
+}
+
+// Test_RotateReplacedKey rejects a rotation of a key that was rotated since it was read.
+func (s *UserRepositoryTestSuite) Test_RotateReplacedKey() {
+	ctx := context.Background()
+	generation := s.generation()
+	newKey, err := s.crypt.GenerateKey()
+	require.NoError(s.T(), err)
+
+	_, err = s.repo.RotateKey(ctx, s.newCryptKey(newKey, generation+1), s.reencrypt(s.key, newKey, -1), 2)
+	assert.ErrorIs(s.T(), err, cerrors.ErrUserKeyRotated)
+
+	assert.Equal(s.T(), generation, s.generation())
+	s.assertEncryptedWith(s.key)
+}
+
+// assertEncryptedWith checks that the wrapped key of the user is key and that the data, the second factor secret
+// and the collection key are encrypted with it, while the client side encrypted data is unchanged.
+func (s *UserRepositoryTestSuite) assertEncryptedWith(key []byte) {
+	ctx := context.Background()
+	userKey, err := s.repo.SelectKeyByID(ctx, s.userID)
+	require.NoError(s.T(), err)
+	unwrapped, err := s.crypt.DecryptWithMasterKeyVersion(userKey.Version, userKey.CryptKey)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), key, unwrapped)
+
+	rows, err := s.pool.DB.Query(ctx,
+		`select id, type, data, client_encrypted from privatekeeper.data where owner_id = $1;`, s.userID)
+	require.NoError(s.T(), err)
+	defer rows.Close()
+
+	seen := 0
+	for rows.Next() {
+		var (
+			id, dataType    string
+			data            []byte
+			clientEncrypted bool
+		)
+		require.NoError(s.T(), rows.Scan(&id, &dataType, &data, &clientEncrypted))
+		seen++
+
+		if clientEncrypted {
+			assert.Equal(s.T(), s.client[id+"/"+dataType], data, "client side encrypted data must be byte-identical")
+			continue
+		}
+		plain, err := s.crypt.Decrypt(key, data)
+		require.NoError(s.T(), err, "data %s/%s", id, dataType)
+		assert.Equal(s.T(), s.plain[id+"/"+dataType], plain)
+	}
+	require.NoError(s.T(), rows.Err())
+	assert.Equal(s.T(), len(s.plain)+len(s.client), seen)
+
+	secret, err := s.crypt.Decrypt(key, s.totpSecret())
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []byte("totp secret"), secret)
+
+	var memberKey []byte
+	err = s.pool.DB.QueryRow(ctx,
+		`select crypt_key from privatekeeper.collection_member where collection_id = $1 and user_id = $2;`,
+		s.collectionID, s.userID).Scan(&memberKey)
+	require.NoError(s.T(), err)
+	collectionKey, err := s.crypt.Decrypt(key, memberKey)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []byte("collection key"), collectionKey)
+}
+
+// reencrypt returns the re-encryption of a rotation from oldKey to newKey, failing on the call
+// with the given number when it is not negative.
+func (s *UserRepositoryTestSuite) reencrypt(oldKey, newKey []byte, failOn int) func(data []byte) ([]byte, error) {
+	calls := 0
+	return func(data []byte) ([]byte, error) {
+		calls++
+		if calls == failOn {
+			return nil, errors.New("reencrypt failed")
+		}
+		dec, err := s.crypt.Decrypt(oldKey, data)
+		if err != nil {
+			return nil, err
+		}
+		return s.crypt.Encrypt(newKey, dec)
+	}
+}
+
+// newCryptKey returns key wrapped with the master key as the replacement of the key of the given generation.
+func (s *UserRepositoryTestSuite) newCryptKey(key []byte, generation int64) model.UserCryptKey {
+	cryptKey, err := s.crypt.EncryptWithMasterKey(key)
+	s.Require().NoError(err)
+	return model.UserCryptKey{UserID: s.userID, CryptKey: cryptKey, Version: s.crypt.Version(), Generation: generation}
+}
+
+func (s *UserRepositoryTestSuite) insertData(id, dataType string, data []byte, clientEncrypted bool) {
+	_, err := s.pool.DB.Exec(context.Background(),
+		`
+			insert into privatekeeper.data (id, owner_id, type, data, created_at, client_encrypted)
+			values ($1, $2, $3, $4, now(), $5);
+			`,
+		id, s.userID, dataType, data, clientEncrypted)
+	s.Require().NoError(err)
+}
+
+func (s *UserRepositoryTestSuite) encrypt(data []byte) []byte {
+	cryptData, err := s.crypt.Encrypt(s.key, data)
+	s.Require().NoError(err)
+	return cryptData
+}
+
+func (s *UserRepositoryTestSuite) generation() int64 {
+	userKey, err := s.repo.SelectKeyByID(context.Background(), s.userID)
+	s.Require().NoError(err)
+	return userKey.Generation
+}
+
+func (s *UserRepositoryTestSuite) totpSecret() []byte {
+	totp, err := s.repo.SelectTOTP(context.Background(), s.userID)
+	s.Require().NoError(err)
+	return totp.Secret
+}
@@ -2,28 +2,66 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"time"
 
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/cache"
 	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
 	"github.com/DenisKhanov/PrivateKeeperV2/pkg/jwtmanager"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/totp"
+	rd "github.com/redis/go-redis/v9"
+)
+
+const (
+	totpIssuer         = "PrivateKeeper" // Issuer shown by authenticator apps
+	recoveryCodesCount = 10              // Number of recovery codes issued on enrollment
+	recoveryCodeLength = 10              // Number of characters in a recovery code
+
+	maxSecondFactorAttempts = 5                // Second factor attempts allowed before the lockout
+	secondFactorLockout     = 15 * time.Minute // Lockout after the last failed second factor attempt
 )
 
 // UserRepository interface defines methods for user-related database operations
 type UserRepository interface {
 	Insert(ctx context.Context, user model.User) (model.User, error)
 	SelectByLogin(ctx context.Context, login string) (model.User, error)
+	SelectByID(ctx context.Context, userID string) (model.User, error)
+	InsertSession(ctx context.Context, session model.Session) error
+	SelectSession(ctx context.Context, sessionID string) (model.Session, error)
+	UpdateSessionToken(ctx context.Context, sessionID string, oldHash, newHash []byte, expiresAt time.Time) error
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeUserSessions(ctx context.Context, userID string) ([]string, error)
+	SelectTOTP(ctx context.Context, userID string) (model.UserTOTP, error)
+	InsertTOTP(ctx context.Context, totp model.UserTOTP, recoveryCodeHashes [][]byte) error
+	EnableTOTP(ctx context.Context, userID string, step int64) error
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	UseRecoveryCode(ctx context.Context, userID string, codeHash []byte) error
+	DeleteTOTP(ctx context.Context, userID string) error
 }
 
 // CryptService interface defines methods for cryptographic operations
 type CryptService interface {
 	EncryptWithMasterKey(data []byte) ([]byte, error)
-	DecryptWithMasterKey(data []byte) ([]byte, error)
+	DecryptWithMasterKeyVersion(version int, data []byte) ([]byte, error)
 	GenerateKey() ([]byte, error)
+	Version() int
+	Encrypt(key, data []byte) ([]byte, error)
+	Decrypt(key, data []byte) ([]byte, error)
+}
+
+// KeyRotator interface defines the method for rotating the user key
+type KeyRotator interface {
+	Rotate(ctx context.Context, userID string) (int, error)
 }
 
 // UserService struct handles user-related business logic and dependencies
@@ -32,91 +70,513 @@ type UserService struct {
 	crypt      CryptService           // Cryptographic service for data encryption/decryption
 	jwtManager *jwtmanager.JWTManager // JWT manager for token generation
 	redis      *cache.Redis           // Redis client for caching user data
+	rotator    KeyRotator             // User key rotator
+	refreshExp time.Duration          // Lifetime of refresh tokens
 }
 
 // New creates a new instance of UserService with the provided dependencies
-func New(repository UserRepository, crypt CryptService, jwtManager *jwtmanager.JWTManager, redis *cache.Redis) *UserService {
+func New(repository UserRepository, crypt CryptService, jwtManager *jwtmanager.JWTManager, redis *cache.Redis, rotator KeyRotator, refreshExp time.Duration) *UserService {
 	return &UserService{
 		repository: repository,
 		crypt:      crypt,
 		jwtManager: jwtManager,
 		redis:      redis,
+		rotator:    rotator,
+		refreshExp: refreshExp,
 	}
 }
 
-// Login authenticates a user and returns a JWT token
-func (u *UserService) Login(ctx context.Context, req model.UserLoginRequest) (string, error) {
+// Login authenticates a user, starts a new session and returns its access and refresh tokens
+// together with the user's wrapped data key when client side encryption is enabled.
+// When two-factor authentication is enabled a valid code or an unused recovery code is required as well.
+func (u *UserService) Login(ctx context.Context, req model.UserLoginRequest) (model.UserLoginResponse, error) {
 	user, err := u.repository.SelectByLogin(ctx, req.Login)
 	if err != nil {
-		return "", fmt.Errorf("login SelectByLogin: %w", err)
+		return model.UserLoginResponse{}, fmt.Errorf("login SelectByLogin: %w", err)
 	}
 
 	err = bcrypt.CompareHashAndPassword(user.Password, []byte(req.Password))
 	if err != nil {
-		return "", cerrors.ErrInvalidPassword
+		return model.UserLoginResponse{}, cerrors.ErrInvalidPassword
 	}
 
-	token, err := u.jwtManager.BuildJWTString(user.ID)
+	userKey, err := u.crypt.DecryptWithMasterKeyVersion(user.CryptKeyVersion, user.CryptKey)
 	if err != nil {
-		return "", fmt.Errorf("login build jwt: %w", err)
+		return model.UserLoginResponse{}, fmt.Errorf("decryptWithMasterKey: %w", err)
 	}
 
-	userKey, err := u.crypt.DecryptWithMasterKey(user.CryptKey)
+	if err = u.checkSecondFactor(ctx, user.ID, userKey, req.TOTPCode); err != nil {
+		return model.UserLoginResponse{}, fmt.Errorf("login second factor: %w", err)
+	}
+
+	tokens, err := u.newSession(ctx, user.ID)
 	if err != nil {
-		return "", fmt.Errorf("decryptWithMasterKey: %w", err)
+		return model.UserLoginResponse{}, fmt.Errorf("login new session: %w", err)
 	}
 
-	st := u.redis.Client.Set(ctx, user.ID, userKey, 24*time.Hour)
+	st := u.redis.Client.Set(ctx, cache.UserKeyKey(user.ID, user.CryptKeyGeneration), userKey, 24*time.Hour)
 	if st.Err() != nil {
-		return "", fmt.Errorf("login redis set: %w", st.Err())
+		return model.UserLoginResponse{}, fmt.Errorf("login redis set: %w", st.Err())
 	}
 
-	return token, nil
+	return model.UserLoginResponse{
+		Token:          tokens.Token,
+		RefreshToken:   tokens.RefreshToken,
+		WrappedDataKey: user.WrappedDataKey,
+	}, nil
 }
 
-// Register creates a new user and returns a JWT token
-func (u *UserService) Register(ctx context.Context, req model.UserRegisterRequest) (string, error) {
+// Register creates a new user, starts a new session and returns its access and refresh tokens
+func (u *UserService) Register(ctx context.Context, req model.UserRegisterRequest) (model.TokenPair, error) {
 	id, err := uuid.NewUUID()
 	if err != nil {
-		return "", fmt.Errorf("new uuid: %w", err)
+		return model.TokenPair{}, fmt.Errorf("new uuid: %w", err)
 	}
 
 	userKey, err := u.crypt.GenerateKey()
 	if err != nil {
-		return "", fmt.Errorf("genereate key: %w", err)
+		return model.TokenPair{}, fmt.Errorf("genereate key: %w", err)
 	}
 
 	cryptUserKey, err := u.crypt.EncryptWithMasterKey(userKey)
 	if err != nil {
-		return "", fmt.Errorf("encrypt with master key: %w", err)
+		return model.TokenPair{}, fmt.Errorf("encrypt with master key: %w", err)
 	}
 
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return "", fmt.Errorf("generate hash from password: %w", err)
+		return model.TokenPair{}, fmt.Errorf("generate hash from password: %w", err)
 	}
 
 	userToSave := model.User{
-		ID:       id.String(),
-		Login:    req.Login,
-		Password: passwordHash,
-		CryptKey: cryptUserKey,
+		ID:                   id.String(),
+		Login:                req.Login,
+		Password:             passwordHash,
+		CryptKey:             cryptUserKey,
+		CryptKeyVersion:      u.crypt.Version(),
+		ClientSideEncryption: req.ClientSideEncryption,
+		WrappedDataKey:       req.WrappedDataKey,
 	}
 
 	user, err := u.repository.Insert(ctx, userToSave)
 	if err != nil {
-		return "", fmt.Errorf("register user: %w", err)
+		return model.TokenPair{}, fmt.Errorf("register user: %w", err)
 	}
 
-	st := u.redis.Client.Set(ctx, user.ID, userKey, 24*time.Hour)
+	st := u.redis.Client.Set(ctx, cache.UserKeyKey(user.ID, user.CryptKeyGeneration), userKey, 24*time.Hour)
 	if st.Err() != nil {
-		return "", fmt.Errorf("redis set: %w", st.Err())
+		return model.TokenPair{}, fmt.Errorf("redis set: %w", st.Err())
+	}
+
+	tokens, err := u.newSession(ctx, user.ID)
+	if err != nil {
+		return model.TokenPair{}, fmt.Errorf("new session: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RotateKey replaces the key of the current user and re-encrypts the user data with the new key.
+// It returns the number of re-encrypted records.
+func (u *UserService) RotateKey(ctx context.Context) (int, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return 0, fmt.Errorf("failed to get userID from context")
+	}
+
+	count, err := u.rotator.Rotate(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("rotate key: %w", err)
+	}
+
+	return count, nil
+}
+
+// Refresh exchanges a refresh token for a new pair of access and refresh tokens of the same session.
+// Every refresh token can be used once. Using it again means it was stolen, so the session is revoked.
+func (u *UserService) Refresh(ctx context.Context, refreshToken string) (model.TokenPair, error) {
+	sessionID, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return model.TokenPair{}, cerrors.ErrInvalidRefreshToken
+	}
+
+	session, err := u.repository.SelectSession(ctx, sessionID)
+	if errors.Is(err, cerrors.ErrSessionNotFound) {
+		return model.TokenPair{}, cerrors.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return model.TokenPair{}, fmt.Errorf("select session: %w", err)
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return model.TokenPair{}, cerrors.ErrInvalidRefreshToken
+	}
+
+	if subtle.ConstantTimeCompare(hashRefreshSecret(secret), session.RefreshTokenHash) != 1 {
+		if err = u.revoke(ctx, session.UserID, session.ID); err != nil {
+			return model.TokenPair{}, fmt.Errorf("revoke reused session: %w", err)
+		}
+		return model.TokenPair{}, cerrors.ErrInvalidRefreshToken
+	}
+
+	newSecret, err := newRefreshSecret()
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	err = u.repository.UpdateSessionToken(ctx, session.ID, session.RefreshTokenHash, hashRefreshSecret(newSecret), time.Now().Add(u.refreshExp))
+	if err != nil {
+		return model.TokenPair{}, fmt.Errorf("update session: %w", err)
+	}
+
+	token, err := u.jwtManager.BuildJWTString(session.UserID, session.ID)
+	if err != nil {
+		return model.TokenPair{}, fmt.Errorf("build jwt: %w", err)
+	}
+
+	return model.TokenPair{Token: token, RefreshToken: session.ID + "." + newSecret}, nil
+}
+
+// Logout revokes the session of the current access token
+func (u *UserService) Logout(ctx context.Context) error {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get userID from context")
+	}
+
+	sessionID, ok := ctx.Value(model.SessionIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get sessionID from context")
 	}
 
-	token, err := u.jwtManager.BuildJWTString(user.ID)
+	return u.revoke(ctx, userID, sessionID)
+}
+
+// LogoutAll revokes all sessions of the current user on all devices and returns their number
+func (u *UserService) LogoutAll(ctx context.Context) (int, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return 0, fmt.Errorf("failed to get userID from context")
+	}
+
+	sessionIDs, err := u.repository.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("revoke sessions: %w", err)
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err = u.denySession(ctx, sessionID); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(sessionIDs), nil
+}
+
+// EnrollTOTP generates a new second factor secret and recovery codes of the current user.
+// The second factor is enabled only after ConfirmTOTP, so an unconfirmed enrollment can be repeated.
+func (u *UserService) EnrollTOTP(ctx context.Context) (model.TOTPEnrollment, error) {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return model.TOTPEnrollment{}, fmt.Errorf("failed to get userID from context")
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return model.TOTPEnrollment{}, fmt.Errorf("failed to get userKey from context")
+	}
+
+	user, err := u.repository.SelectByID(ctx, userID)
+	if err != nil {
+		return model.TOTPEnrollment{}, fmt.Errorf("select user: %w", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return model.TOTPEnrollment{}, fmt.Errorf("generate secret: %w", err)
+	}
+
+	encryptedSecret, err := u.crypt.Encrypt(userKey, []byte(secret))
+	if err != nil {
+		return model.TOTPEnrollment{}, fmt.Errorf("encrypt secret: %w", err)
+	}
+
+	recoveryCodes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return model.TOTPEnrollment{}, err
+	}
+
+	err = u.repository.InsertTOTP(ctx, model.UserTOTP{UserID: userID, Secret: encryptedSecret}, hashes)
+	if err != nil {
+		return model.TOTPEnrollment{}, fmt.Errorf("insert totp: %w", err)
+	}
+
+	return model.TOTPEnrollment{
+		URI:           totp.URI(totpIssuer, user.Login, secret),
+		Secret:        secret,
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// ConfirmTOTP enables the enrolled second factor of the current user if the code from the authenticator app is valid
+func (u *UserService) ConfirmTOTP(ctx context.Context, code string) error {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get userID from context")
+	}
+
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return fmt.Errorf("failed to get userKey from context")
+	}
+
+	userTOTP, err := u.repository.SelectTOTP(ctx, userID)
 	if err != nil {
-		return "", fmt.Errorf("build jwt: %w", err)
+		return fmt.Errorf("select totp: %w", err)
+	}
+
+	if userTOTP.Enabled {
+		return cerrors.ErrTOTPAlreadyEnabled
+	}
+
+	return u.limitSecondFactorAttempts(ctx, userID, func() error {
+		step, err := u.verifyTOTPCode(userTOTP, userKey, code)
+		if err != nil {
+			return err
+		}
+
+		if err = u.repository.EnableTOTP(ctx, userID, step); err != nil {
+			return fmt.Errorf("enable totp: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// DisableTOTP turns off two-factor authentication of the current user.
+// A valid code or an unused recovery code is required, so a stolen access token is not enough.
+func (u *UserService) DisableTOTP(ctx context.Context, code string) error {
+	userID, ok := ctx.Value(model.UserIDKey).(string)
+	if !ok {
+		return fmt.Errorf("failed to get userID from context")
 	}
 
-	return token, nil
+	userKey, ok := ctx.Value(model.UserKey).([]byte)
+	if !ok {
+		return fmt.Errorf("failed to get userKey from context")
+	}
+
+	userTOTP, err := u.repository.SelectTOTP(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("select totp: %w", err)
+	}
+
+	if !userTOTP.Enabled {
+		return cerrors.ErrTOTPNotEnrolled
+	}
+
+	if err = u.useSecondFactorCode(ctx, userTOTP, userKey, code); err != nil {
+		return err
+	}
+
+	if err = u.repository.DeleteTOTP(ctx, userID); err != nil {
+		return fmt.Errorf("delete totp: %w", err)
+	}
+
+	return nil
+}
+
+// checkSecondFactor verifies the second factor code of the user if two-factor authentication is enabled
+func (u *UserService) checkSecondFactor(ctx context.Context, userID string, userKey []byte, code string) error {
+	userTOTP, err := u.repository.SelectTOTP(ctx, userID)
+	if errors.Is(err, cerrors.ErrTOTPNotEnrolled) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("select totp: %w", err)
+	}
+
+	if !userTOTP.Enabled {
+		return nil
+	}
+
+	if strings.TrimSpace(code) == "" {
+		return cerrors.ErrTOTPRequired
+	}
+
+	return u.useSecondFactorCode(ctx, userTOTP, userKey, code)
+}
+
+// useSecondFactorCode accepts a code from the authenticator app or an unused recovery code.
+// Both are accepted only once.
+func (u *UserService) useSecondFactorCode(ctx context.Context, userTOTP model.UserTOTP, userKey []byte, code string) error {
+	code = strings.TrimSpace(code)
+
+	return u.limitSecondFactorAttempts(ctx, userTOTP.UserID, func() error {
+		if !isTOTPCode(code) {
+			return u.repository.UseRecoveryCode(ctx, userTOTP.UserID, hashRecoveryCode(code))
+		}
+
+		step, err := u.verifyTOTPCode(userTOTP, userKey, code)
+		if err != nil {
+			return err
+		}
+
+		return u.repository.UseTOTPStep(ctx, userTOTP.UserID, step)
+	})
+}
+
+// limitSecondFactorAttempts counts the attempt in Redis before checking the code with check
+// and returns cerrors.ErrTooManyAttempts without checking it once the user has made
+// maxSecondFactorAttempts attempts in a row that did not succeed.
+// The count expires secondFactorLockout after the last attempt and is reset by a successful one.
+func (u *UserService) limitSecondFactorAttempts(ctx context.Context, userID string, check func() error) error {
+	key := cache.SecondFactorAttemptsKey(userID)
+
+	var attempts *rd.IntCmd
+	_, err := u.redis.Client.TxPipelined(ctx, func(pipe rd.Pipeliner) error {
+		attempts = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, secondFactorLockout)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("count second factor attempt: %w", err)
+	}
+
+	if attempts.Val() > maxSecondFactorAttempts {
+		return cerrors.ErrTooManyAttempts
+	}
+
+	if err = check(); err != nil {
+		return err
+	}
+
+	if err = u.redis.Client.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("reset second factor attempts: %w", err)
+	}
+
+	return nil
+}
+
+// verifyTOTPCode checks the code from the authenticator app against the user secret and returns its time step
+func (u *UserService) verifyTOTPCode(userTOTP model.UserTOTP, userKey []byte, code string) (int64, error) {
+	secret, err := u.crypt.Decrypt(userKey, userTOTP.Secret)
+	if err != nil {
+		return 0, fmt.Errorf("decrypt secret: %w", err)
+	}
+
+	step, ok, err := totp.Verify(string(secret), code, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("verify code: %w", err)
+	}
+
+	if !ok {
+		return 0, cerrors.ErrInvalidTOTPCode
+	}
+
+	return step, nil
+}
+
+// newSession starts a new session of the user and returns its access and refresh tokens.
+// The refresh token is the session ID and a random secret, only a hash of the secret is stored.
+func (u *UserService) newSession(ctx context.Context, userID string) (model.TokenPair, error) {
+	secret, err := newRefreshSecret()
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	session := model.Session{
+		ID:               uuid.NewString(),
+		UserID:           userID,
+		RefreshTokenHash: hashRefreshSecret(secret),
+		ExpiresAt:        time.Now().Add(u.refreshExp),
+	}
+	if err = u.repository.InsertSession(ctx, session); err != nil {
+		return model.TokenPair{}, fmt.Errorf("insert session: %w", err)
+	}
+
+	token, err := u.jwtManager.BuildJWTString(userID, session.ID)
+	if err != nil {
+		return model.TokenPair{}, fmt.Errorf("build jwt: %w", err)
+	}
+
+	return model.TokenPair{Token: token, RefreshToken: session.ID + "." + secret}, nil
+}
+
+// revoke revokes the session so that neither its refresh token nor its access tokens are accepted any more
+func (u *UserService) revoke(ctx context.Context, userID, sessionID string) error {
+	if err := u.repository.RevokeSession(ctx, userID, sessionID); err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+
+	return u.denySession(ctx, sessionID)
+}
+
+// denySession marks the session as revoked in redis for the lifetime of access tokens,
+// so the access tokens already issued for it are rejected
+func (u *UserService) denySession(ctx context.Context, sessionID string) error {
+	st := u.redis.Client.Set(ctx, cache.RevokedSessionKey(sessionID), 1, u.jwtManager.TokenExp())
+	if st.Err() != nil {
+		return fmt.Errorf("redis set: %w", st.Err())
+	}
+
+	return nil
+}
+
+// newRefreshSecret generates the random secret part of a refresh token
+func newRefreshSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generate refresh token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashRefreshSecret returns the hash of the refresh token secret stored in the database
+func hashRefreshSecret(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// isTOTPCode reports whether the code looks like a code from an authenticator app rather than a recovery code
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRecoveryCodes generates the recovery codes shown to the user and their hashes stored in the database
+func newRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([][]byte, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		raw := make([]byte, 8)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("generate recovery code: %w", err)
+		}
+
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(raw))[:recoveryCodeLength]
+		code = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode returns the hash of the recovery code stored in the database.
+// The code is normalized first, so case and separators typed by the user do not matter.
+func hashRecoveryCode(code string) []byte {
+	code = strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	sum := sha256.Sum256([]byte(code))
+	return sum[:]
 }
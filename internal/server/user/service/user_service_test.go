@@ -0,0 +1,275 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	rd "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/cache"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/encryption"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/interceptors/auth"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/model"
+	"github.com/DenisKhanov/PrivateKeeperV2/internal/server/user/cerrors"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/jwtmanager"
+	"github.com/DenisKhanov/PrivateKeeperV2/pkg/totp"
+)
+
+// fakeRepository keeps the sessions and the second factors in memory and exchanges refresh tokens
+// and uses second factor codes like the database does.
+type fakeRepository struct {
+	UserRepository
+	sessions      map[string]model.Session
+	totp          map[string]model.UserTOTP // Second factors by user ID
+	recoveryCodes map[string]bool           // Unused recovery codes by hex of their hash
+}
+
+func (f *fakeRepository) InsertSession(_ context.Context, session model.Session) error {
+	f.sessions[session.ID] = session
+	return nil
+}
+
+func (f *fakeRepository) SelectSession(_ context.Context, sessionID string) (model.Session, error) {
+	session, ok := f.sessions[sessionID]
+	if !ok {
+		return model.Session{}, cerrors.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (f *fakeRepository) UpdateSessionToken(_ context.Context, sessionID string, oldHash, newHash []byte, expiresAt time.Time) error {
+	session, ok := f.sessions[sessionID]
+	if !ok || session.RevokedAt != nil || !bytes.Equal(session.RefreshTokenHash, oldHash) {
+		return fmt.Errorf("update session: %w", cerrors.ErrInvalidRefreshToken)
+	}
+	session.RefreshTokenHash, session.ExpiresAt = newHash, expiresAt
+	f.sessions[sessionID] = session
+	return nil
+}
+
+func (f *fakeRepository) RevokeSession(_ context.Context, userID, sessionID string) error {
+	session, ok := f.sessions[sessionID]
+	if ok && session.UserID == userID && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+		f.sessions[sessionID] = session
+	}
+	return nil
+}
+
+func (f *fakeRepository) RevokeUserSessions(_ context.Context, userID string) ([]string, error) {
+	var ids []string
+	for id, session := range f.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			now := time.Now()
+			session.RevokedAt = &now
+			f.sessions[id] = session
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeRepository) UseTOTPStep(_ context.Context, userID string, step int64) error {
+	userTOTP := f.totp[userID]
+	if userTOTP.LastUsedStep >= step {
+		return fmt.Errorf("use totp step: %w", cerrors.ErrInvalidTOTPCode)
+	}
+	userTOTP.LastUsedStep = step
+	f.totp[userID] = userTOTP
+	return nil
+}
+
+func (f *fakeRepository) UseRecoveryCode(_ context.Context, _ string, codeHash []byte) error {
+	if !f.recoveryCodes[hex.EncodeToString(codeHash)] {
+		return fmt.Errorf("use recovery code: %w", cerrors.ErrInvalidTOTPCode)
+	}
+	delete(f.recoveryCodes, hex.EncodeToString(codeHash))
+	return nil
+}
+
+type UserServiceTestSuite struct {
+	suite.Suite
+	redis   *miniredis.Miniredis
+	repo    *fakeRepository
+	service *UserService
+	userKey []byte
+	secret  string // Second factor secret of user-id
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(UserServiceTestSuite))
+}
+
+func (s *UserServiceTestSuite) SetupTest() {
+	crypt, err := encryption.New([]byte("test-master-key"))
+	s.Require().NoError(err)
+	s.userKey, err = crypt.GenerateKey()
+	s.Require().NoError(err)
+	s.secret, err = totp.GenerateSecret()
+	s.Require().NoError(err)
+	encryptedSecret, err := crypt.Encrypt(s.userKey, []byte(s.secret))
+	s.Require().NoError(err)
+
+	s.redis = miniredis.RunT(s.T())
+	s.repo = &fakeRepository{
+		sessions:      make(map[string]model.Session),
+		totp:          map[string]model.UserTOTP{"user-id": {UserID: "user-id", Secret: encryptedSecret, Enabled: true}},
+		recoveryCodes: map[string]bool{hex.EncodeToString(hashRecoveryCode("abcde-fghij")): true},
+	}
+	s.service = New(s.repo, crypt, jwtmanager.New("token", "secret", time.Hour),
+		&cache.Redis{Client: rd.NewClient(&rd.Options{Addr: s.redis.Addr()})}, nil, 24*time.Hour)
+}
+
+// Test_RefreshTokenSingleUse exchanges a refresh token once, a second exchange of it is rejected.
+func (s *UserServiceTestSuite) Test_RefreshTokenSingleUse() {
+	ctx := context.Background()
+	tokens, err := s.service.newSession(ctx, "user-id")
+	require.NoError(s.T(), err)
+
+	refreshed, err := s.service.Refresh(ctx, tokens.RefreshToken)
+	require.NoError(s.T(), err)
+	assert.NotEqual(s.T(), tokens.RefreshToken, refreshed.RefreshToken)
+
+	userID, sessionID, err := s.service.jwtManager.GetSession(refreshed.Token)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "user-id", userID)
+	assert.Equal(s.T(), s.sessionID(tokens), sessionID, "a refresh keeps the session")
+
+	_, err = s.service.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(s.T(), err, cerrors.ErrInvalidRefreshToken)
+
+	for _, token := range []string{"", "no-separator", "unknown.secret"} {
+		_, err = s.service.Refresh(ctx, token)
+		assert.ErrorIs(s.T(), err, cerrors.ErrInvalidRefreshToken, token)
+	}
+}
+
+// Test_RefreshTokenReuse revokes the session when a used refresh token is presented again, so the refresh token
+// issued after it and the access tokens of the session stop working as well.
+func (s *UserServiceTestSuite) Test_RefreshTokenReuse() {
+	ctx := context.Background()
+	tokens, err := s.service.newSession(ctx, "user-id")
+	require.NoError(s.T(), err)
+	other, err := s.service.newSession(ctx, "user-id")
+	require.NoError(s.T(), err)
+
+	refreshed, err := s.service.Refresh(ctx, tokens.RefreshToken)
+	require.NoError(s.T(), err)
+
+	_, err = s.service.Refresh(ctx, tokens.RefreshToken)
+	require.ErrorIs(s.T(), err, cerrors.ErrInvalidRefreshToken)
+
+	assert.NotNil(s.T(), s.repo.sessions[s.sessionID(tokens)].RevokedAt)
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(s.authenticate(refreshed.Token)))
+
+	_, err = s.service.Refresh(ctx, refreshed.RefreshToken)
+	assert.ErrorIs(s.T(), err, cerrors.ErrInvalidRefreshToken)
+
+	// Other sessions of the user are not affected
+	assert.Nil(s.T(), s.repo.sessions[s.sessionID(other)].RevokedAt)
+	_, err = s.service.Refresh(ctx, other.RefreshToken)
+	assert.NoError(s.T(), err)
+}
+
+// Test_LogoutAll revokes every session of the user, their access tokens are denied for the lifetime of access tokens.
+func (s *UserServiceTestSuite) Test_LogoutAll() {
+	ctx := context.Background()
+	var sessions []model.TokenPair
+	for i := 0; i < 2; i++ {
+		tokens, err := s.service.newSession(ctx, "user-id")
+		require.NoError(s.T(), err)
+		sessions = append(sessions, tokens)
+	}
+	stranger, err := s.service.newSession(ctx, "other-user-id")
+	require.NoError(s.T(), err)
+
+	count, err := s.service.LogoutAll(context.WithValue(ctx, model.UserIDKey, "user-id"))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 2, count)
+
+	for _, tokens := range sessions {
+		assert.Equal(s.T(), time.Hour, s.redis.TTL(cache.RevokedSessionKey(s.sessionID(tokens))))
+		assert.Equal(s.T(), codes.Unauthenticated, status.Code(s.authenticate(tokens.Token)))
+
+		_, err = s.service.Refresh(ctx, tokens.RefreshToken)
+		assert.ErrorIs(s.T(), err, cerrors.ErrInvalidRefreshToken)
+	}
+
+	assert.NoError(s.T(), s.authenticate(stranger.Token))
+	_, err = s.service.Refresh(ctx, stranger.RefreshToken)
+	assert.NoError(s.T(), err)
+}
+
+// Test_SecondFactorLockout rejects the second factor after maxSecondFactorAttempts failed attempts,
+// even with a valid code or recovery code, until secondFactorLockout passes after the last attempt.
+func (s *UserServiceTestSuite) Test_SecondFactorLockout() {
+	code, wrongCode := s.codes()
+	for i := 0; i < maxSecondFactorAttempts; i++ {
+		assert.ErrorIs(s.T(), s.useCode(wrongCode), cerrors.ErrInvalidTOTPCode, "attempt %d", i+1)
+	}
+
+	assert.ErrorIs(s.T(), s.useCode(code), cerrors.ErrTooManyAttempts)
+	assert.ErrorIs(s.T(), s.useCode("ABCDE-FGHIJ"), cerrors.ErrTooManyAttempts)
+	assert.Zero(s.T(), s.repo.totp["user-id"].LastUsedStep, "a locked out code must not be checked")
+	assert.Len(s.T(), s.repo.recoveryCodes, 1, "a locked out recovery code must not be used")
+	assert.Equal(s.T(), secondFactorLockout, s.redis.TTL(cache.SecondFactorAttemptsKey("user-id")))
+
+	s.redis.FastForward(secondFactorLockout)
+	assert.NoError(s.T(), s.useCode(code))
+}
+
+// Test_SecondFactorReset resets the count of failed attempts on a successful one.
+func (s *UserServiceTestSuite) Test_SecondFactorReset() {
+	code, wrongCode := s.codes()
+	for i := 0; i < maxSecondFactorAttempts-1; i++ {
+		require.ErrorIs(s.T(), s.useCode("wrong-code"), cerrors.ErrInvalidTOTPCode)
+	}
+	require.NoError(s.T(), s.useCode("ABCDE-FGHIJ"))
+	assert.False(s.T(), s.redis.Exists(cache.SecondFactorAttemptsKey("user-id")))
+
+	for i := 0; i < maxSecondFactorAttempts; i++ {
+		assert.ErrorIs(s.T(), s.useCode(wrongCode), cerrors.ErrInvalidTOTPCode, "attempt %d", i+1)
+	}
+	assert.ErrorIs(s.T(), s.useCode(code), cerrors.ErrTooManyAttempts)
+}
+
+// codes returns the current code of the second factor of user-id and a code differing from it in the last digit.
+func (s *UserServiceTestSuite) codes() (string, string) {
+	code, err := totp.Code(s.secret, time.Now())
+	s.Require().NoError(err)
+	return code, code[:len(code)-1] + string('0'+(code[len(code)-1]-'0'+1)%10)
+}
+
+// useCode uses a second factor code or a recovery code of user-id.
+func (s *UserServiceTestSuite) useCode(code string) error {
+	return s.service.useSecondFactorCode(context.Background(), s.repo.totp["user-id"], s.userKey, code)
+}
+
+// authenticate passes the access token through the authentication interceptor of the server.
+func (s *UserServiceTestSuite) authenticate(token string) error {
+	interceptor := auth.New(s.service.jwtManager, s.service.redis)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("token", token))
+	_, err := interceptor.GRPCJWTAuth(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/proto.UserService/PostLogout"},
+		func(context.Context, any) (any, error) { return nil, nil })
+	return err
+}
+
+// sessionID returns the ID of the session the tokens were issued for.
+func (s *UserServiceTestSuite) sessionID(tokens model.TokenPair) string {
+	_, sessionID, err := s.service.jwtManager.GetSession(tokens.Token)
+	s.Require().NoError(err)
+	return sessionID
+}
@@ -0,0 +1,218 @@
+// Package breach checks passwords against a local list of breached password hashes in the format
+// of Have I Been Pwned. Lookups use k-anonymity ranges: only the first 5 characters of the SHA-1 hash
+// of a password are looked up and the rest of the hash is compared by the caller, so the list, or the server
+// holding it, never sees the password or its full hash.
+package breach
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Lengths of the parts of an uppercase hex SHA-1 hash.
+const (
+	PrefixLen = 5               // Characters of the hash looked up in a range
+	SuffixLen = 2*sha1.Size - 5 // Characters of the hash compared with the suffixes of the range
+	hashLen   = PrefixLen + SuffixLen
+)
+
+// ErrInvalidPrefix is returned when a range is looked up with a prefix that is not 5 hex characters.
+var ErrInvalidPrefix = errors.New("prefix must be 5 hex characters")
+
+// Suffix is a hash of the range of a prefix with the number of times it was seen in breaches.
+type Suffix struct {
+	Suffix string // Last 35 uppercase hex characters of the SHA-1 hash
+	Count  int64
+}
+
+// Ranger looks up the suffixes of the hashes starting with a prefix.
+type Ranger interface {
+	Range(ctx context.Context, prefix string) ([]Suffix, error)
+}
+
+// Hash returns the prefix and the suffix of the uppercase hex SHA-1 hash of the password.
+func Hash(password string) (prefix, suffix string) {
+	sum := sha1.Sum([]byte(password))
+	h := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	return h[:PrefixLen], h[PrefixLen:]
+}
+
+// Find returns the number of times the suffix was seen in the range, zero if it's not in the range.
+func Find(suffixes []Suffix, suffix string) int64 {
+	for _, s := range suffixes {
+		if strings.EqualFold(s.Suffix, suffix) {
+			return s.Count
+		}
+	}
+
+	return 0
+}
+
+// Count returns the number of times the password was seen in breaches, zero if it's not in the list.
+func Count(ctx context.Context, r Ranger, password string) (int64, error) {
+	prefix, suffix := Hash(password)
+	suffixes, err := r.Range(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	return Find(suffixes, suffix), nil
+}
+
+// ValidPrefix reports whether the prefix is 5 hex characters.
+func ValidPrefix(prefix string) bool {
+	if len(prefix) != PrefixLen {
+		return false
+	}
+	_, err := hex.DecodeString(prefix + "0")
+
+	return err == nil
+}
+
+// List is a breach list held in memory.
+type List struct {
+	ranges map[string][]Suffix // Suffixes by uppercase prefix
+}
+
+// Load reads a breach list of 'HASH:COUNT' lines, one full uppercase or lowercase hex SHA-1 hash per line,
+// the format of the single file written by the Pwned Passwords downloader. Empty lines are skipped.
+func Load(r io.Reader) (*List, error) {
+	l := &List{ranges: make(map[string][]Suffix)}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		hash, count, err := parseLine(line, hashLen)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		prefix := hash[:PrefixLen]
+		l.ranges[prefix] = append(l.ranges[prefix], Suffix{Suffix: hash[PrefixLen:], Count: count})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read breach list: %w", err)
+	}
+
+	return l, nil
+}
+
+// Len returns the number of hashes of the list.
+func (l *List) Len() int {
+	n := 0
+	for _, suffixes := range l.ranges {
+		n += len(suffixes)
+	}
+
+	return n
+}
+
+// Range returns the suffixes of the hashes of the list starting with the prefix.
+func (l *List) Range(_ context.Context, prefix string) ([]Suffix, error) {
+	if !ValidPrefix(prefix) {
+		return nil, ErrInvalidPrefix
+	}
+
+	return l.ranges[strings.ToUpper(prefix)], nil
+}
+
+// Dir is a breach list stored as one range file per prefix, like 'dir/21BD1' or 'dir/21BD1.txt',
+// each holding the 'SUFFIX:COUNT' lines returned by the range API of Have I Been Pwned. The files are read
+// on every lookup, so the full list doesn't have to fit in memory.
+type Dir struct {
+	path string
+}
+
+// Range reads the range file of the prefix, a missing file is an empty range.
+func (d Dir) Range(_ context.Context, prefix string) ([]Suffix, error) {
+	if !ValidPrefix(prefix) {
+		return nil, ErrInvalidPrefix
+	}
+	prefix = strings.ToUpper(prefix)
+
+	file, err := os.Open(filepath.Join(d.path, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open(filepath.Join(d.path, prefix+".txt"))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open range %s: %w", prefix, err)
+	}
+	defer file.Close()
+
+	var suffixes []Suffix
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		suffix, count, err := parseLine(line, SuffixLen)
+		if err != nil {
+			return nil, fmt.Errorf("range %s line %d: %w", prefix, n, err)
+		}
+		suffixes = append(suffixes, Suffix{Suffix: suffix, Count: count})
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read range %s: %w", prefix, err)
+	}
+
+	return suffixes, nil
+}
+
+// Open opens the breach list at the path: a directory of range files is read on every lookup
+// and a single file is loaded into memory.
+func Open(path string) (Ranger, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breach list: %w", err)
+	}
+	if info.IsDir() {
+		return Dir{path: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breach list: %w", err)
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+// parseLine parses a 'HEX:COUNT' line with n hex characters and returns the uppercase hex and the count.
+func parseLine(line string, n int) (string, int64, error) {
+	h, c, found := strings.Cut(line, ":")
+	if !found {
+		return "", 0, fmt.Errorf("expected HASH:COUNT, got %q", line)
+	}
+
+	if len(h) != n {
+		return "", 0, fmt.Errorf("hash %q must be %d hex characters", h, n)
+	}
+	if _, err := hex.DecodeString(h + strings.Repeat("0", n%2)); err != nil {
+		return "", 0, fmt.Errorf("hash %q must be %d hex characters", h, n)
+	}
+
+	count, err := strconv.ParseInt(strings.TrimSpace(c), 10, 64)
+	if err != nil || count < 0 {
+		return "", 0, fmt.Errorf("invalid count %q", c)
+	}
+
+	return strings.ToUpper(h), count, nil
+}
@@ -0,0 +1,92 @@
+package breach
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type BreachTestSuite struct {
+	suite.Suite
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(BreachTestSuite))
+}
+
+func (s *BreachTestSuite) Test_Hash() {
+	prefix, suffix := Hash("password")
+	assert.Equal(s.T(), "5BAA6", prefix)
+	assert.Equal(s.T(), "1E4C9B93F3F0682250B6CF8331B7EE68FD8", suffix)
+}
+
+func (s *BreachTestSuite) Test_List() {
+	r, err := Open("testdata/breached.txt")
+	require.NoError(s.T(), err)
+	require.IsType(s.T(), &List{}, r)
+	assert.Equal(s.T(), 3, r.(*List).Len())
+
+	ctx := context.Background()
+	count, err := Count(ctx, r, "password")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(9545824), count)
+
+	// Lowercase hashes of the file and prefixes of the lookup are accepted
+	count, err = Count(ctx, r, "123456")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(37359195), count)
+	suffixes, err := r.Range(ctx, "7c4a8")
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), suffixes, 1)
+
+	count, err = Count(ctx, r, "correct horse battery staple")
+	require.NoError(s.T(), err)
+	assert.Zero(s.T(), count)
+
+	_, err = r.Range(ctx, "5BAA61E4")
+	assert.ErrorIs(s.T(), err, ErrInvalidPrefix)
+	_, err = r.Range(ctx, "XYZ12")
+	assert.ErrorIs(s.T(), err, ErrInvalidPrefix)
+}
+
+func (s *BreachTestSuite) Test_Dir() {
+	r, err := Open("testdata/ranges")
+	require.NoError(s.T(), err)
+
+	ctx := context.Background()
+	suffixes, err := r.Range(ctx, "5baa6")
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), suffixes, 2)
+
+	count, err := Count(ctx, r, "password")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(9545824), count)
+
+	count, err = Count(ctx, r, "123456")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(37359195), count)
+
+	// Prefixes without a range file have no breached hashes
+	count, err = Count(ctx, r, "qwerty")
+	require.NoError(s.T(), err)
+	assert.Zero(s.T(), count)
+}
+
+func (s *BreachTestSuite) Test_LoadInvalid() {
+	for _, data := range []string{
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8",
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD:1",
+		"ZBAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1",
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:many",
+	} {
+		_, err := Load(strings.NewReader(data))
+		assert.Error(s.T(), err, data)
+	}
+
+	_, err := Open("testdata/missing.txt")
+	assert.Error(s.T(), err)
+}
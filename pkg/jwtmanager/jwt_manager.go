@@ -20,24 +20,31 @@ type JWTManager struct {
 type claims struct {
 	jwt.RegisteredClaims        // Embedding RegisteredClaims from the jwt package
 	UserID               string // Custom field to store the UserID
+	SessionID            string // Custom field to store the ID of the session the token is issued for
 }
 
-// New returns a new instance of JWTManager.
-func New(tokenName string, secretKey string, hours int) *JWTManager {
+// New returns a new instance of JWTManager issuing tokens with the given lifetime.
+func New(tokenName string, secretKey string, tokenExp time.Duration) *JWTManager {
 	return &JWTManager{
 		TokenName: tokenName,
 		secretKey: secretKey,
-		tokenExp:  time.Duration(hours * int(time.Hour)),
+		tokenExp:  tokenExp,
 	}
 }
 
-// BuildJWTString creates a JWT token with the provided userID.
-func (j *JWTManager) BuildJWTString(userID string) (string, error) {
+// TokenExp returns the lifetime of issued tokens.
+func (j *JWTManager) TokenExp() time.Duration {
+	return j.tokenExp
+}
+
+// BuildJWTString creates a JWT token with the provided userID and sessionID.
+func (j *JWTManager) BuildJWTString(userID, sessionID string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.tokenExp)),
 		},
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
 	})
 
 	tokenString, err := token.SignedString([]byte(j.secretKey))
@@ -50,6 +57,12 @@ func (j *JWTManager) BuildJWTString(userID string) (string, error) {
 
 // GetUserID returns the userID from the provided JWT token.
 func (j *JWTManager) GetUserID(tokenString string) (string, error) {
+	userID, _, err := j.GetSession(tokenString)
+	return userID, err
+}
+
+// GetSession returns the userID and the sessionID from the provided JWT token.
+func (j *JWTManager) GetSession(tokenString string) (string, string, error) {
 	jwtClaims := &claims{}
 	token, err := jwt.ParseWithClaims(tokenString, jwtClaims,
 		func(t *jwt.Token) (interface{}, error) {
@@ -59,14 +72,14 @@ func (j *JWTManager) GetUserID(tokenString string) (string, error) {
 			return []byte(j.secretKey), nil
 		})
 	if err != nil {
-		return "", fmt.Errorf("buildJWTString parse token %w", err)
+		return "", "", fmt.Errorf("buildJWTString parse token %w", err)
 	}
 
 	if !token.Valid {
 		logrus.Warnf("JWTManager token invalid")
 		logrus.Infof("token %s", tokenString)
-		return "", fmt.Errorf("buildJWTString signstring %w", errors.New("token is not valid"))
+		return "", "", fmt.Errorf("buildJWTString signstring %w", errors.New("token is not valid"))
 	}
 
-	return jwtClaims.UserID, nil
+	return jwtClaims.UserID, jwtClaims.SessionID, nil
 }
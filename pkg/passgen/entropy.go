@@ -0,0 +1,45 @@
+package passgen
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Entropy estimates the entropy in bits of a password chosen by a person. Every character adds the bits
+// of the pool of the character classes the password uses, while repeated characters and runs like
+// 'abc' or '321' add one bit only. Dictionary words are not detected, so the estimate is an upper bound.
+func Entropy(password string) float64 {
+	pool := 0
+	for _, class := range []struct {
+		size int
+		is   func(r rune) bool
+	}{
+		{len(lowerChars), func(r rune) bool { return r >= 'a' && r <= 'z' }},
+		{len(upperChars), func(r rune) bool { return r >= 'A' && r <= 'Z' }},
+		{len(digitChars), func(r rune) bool { return r >= '0' && r <= '9' }},
+		{len(symbolChars), func(r rune) bool { return r < unicode.MaxASCII && strings.ContainsRune(symbolChars, r) }},
+		{100, func(r rune) bool { return r > unicode.MaxASCII }}, // Letters of other alphabets, a rough size
+	} {
+		if strings.IndexFunc(password, class.is) >= 0 {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	bits := math.Log2(float64(pool))
+	entropy := 0.0
+	var prev rune = -1
+	for _, r := range password {
+		if diff := r - prev; diff >= -1 && diff <= 1 {
+			entropy++
+		} else {
+			entropy += bits
+		}
+		prev = r
+	}
+
+	return entropy
+}
@@ -0,0 +1,184 @@
+// Package passgen generates random passwords and diceware passphrases with crypto/rand
+// and estimates their entropy.
+package passgen
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Limits of the options.
+const (
+	MinLength = 8   // Smallest number of characters of a password
+	MaxLength = 128 // Largest number of characters of a password
+	MinWords  = 3   // Smallest number of words of a passphrase
+	MaxWords  = 20  // Largest number of words of a passphrase
+)
+
+// Character classes of passwords.
+const (
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars  = "0123456789"
+	symbolChars = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+	// ambiguousChars are easy to confuse with each other in many fonts or hard to tell apart when read aloud
+	ambiguousChars = "Il1|O0o`'\".,:;"
+)
+
+// ErrInvalidOptions is returned when the options can't produce a password.
+var ErrInvalidOptions = errors.New("invalid generator options")
+
+// Secret is a generated password or passphrase.
+type Secret struct {
+	Value   string
+	Entropy float64 // Estimated entropy in bits, as if every character or word was chosen uniformly
+}
+
+// Strength returns a rating of the entropy for the user: weak below 50 bits, fair below 70 bits,
+// strong below 100 bits and very strong from 100 bits.
+func Strength(entropy float64) string {
+	switch {
+	case entropy < 50:
+		return "weak"
+	case entropy < 70:
+		return "fair"
+	case entropy < 100:
+		return "strong"
+	default:
+		return "very strong"
+	}
+}
+
+// PasswordOptions configures the characters of a password. A password holds at least one character
+// of every enabled class.
+type PasswordOptions struct {
+	Length           int  // Number of characters
+	Lower            bool // Lowercase letters
+	Upper            bool // Uppercase letters
+	Digits           bool // Digits
+	Symbols          bool // ASCII punctuation
+	ExcludeAmbiguous bool // Leave out characters that are easy to confuse, like O and 0 or l and 1
+}
+
+// DefaultPasswordOptions returns 20 characters of all classes.
+func DefaultPasswordOptions() PasswordOptions {
+	return PasswordOptions{Length: 20, Lower: true, Upper: true, Digits: true, Symbols: true}
+}
+
+// Classes lists the names of the character classes accepted by SetClasses.
+var Classes = []string{"lower", "upper", "digits", "symbols"}
+
+// SetClasses enables the named character classes and disables the others.
+func (o *PasswordOptions) SetClasses(names []string) error {
+	o.Lower, o.Upper, o.Digits, o.Symbols = false, false, false, false
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "lower":
+			o.Lower = true
+		case "upper":
+			o.Upper = true
+		case "digits":
+			o.Digits = true
+		case "symbols":
+			o.Symbols = true
+		default:
+			return fmt.Errorf("%w: unknown character class %q, expected %s", ErrInvalidOptions, name, strings.Join(Classes, ", "))
+		}
+	}
+
+	return nil
+}
+
+// Password generates a password with the options.
+func Password(opts PasswordOptions) (Secret, error) {
+	if opts.Length < MinLength || opts.Length > MaxLength {
+		return Secret{}, fmt.Errorf("%w: length must be between %d and %d", ErrInvalidOptions, MinLength, MaxLength)
+	}
+
+	classes := opts.classes()
+	if len(classes) == 0 {
+		return Secret{}, fmt.Errorf("%w: at least one character class must be enabled", ErrInvalidOptions)
+	}
+
+	all := strings.Join(classes, "")
+	chars := make([]byte, 0, opts.Length)
+	for _, class := range classes {
+		c, err := pick(class)
+		if err != nil {
+			return Secret{}, err
+		}
+		chars = append(chars, c)
+	}
+	for len(chars) < opts.Length {
+		c, err := pick(all)
+		if err != nil {
+			return Secret{}, err
+		}
+		chars = append(chars, c)
+	}
+
+	// The characters of the classes are moved from the start to random positions
+	for i := len(chars) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return Secret{}, err
+		}
+		chars[i], chars[j] = chars[j], chars[i]
+	}
+
+	return Secret{Value: string(chars), Entropy: float64(opts.Length) * math.Log2(float64(len(all)))}, nil
+}
+
+// classes returns the characters of the enabled classes.
+func (o PasswordOptions) classes() []string {
+	var classes []string
+	for _, class := range []struct {
+		enabled bool
+		chars   string
+	}{
+		{o.Lower, lowerChars},
+		{o.Upper, upperChars},
+		{o.Digits, digitChars},
+		{o.Symbols, symbolChars},
+	} {
+		if !class.enabled {
+			continue
+		}
+		chars := class.chars
+		if o.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguousChars, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		classes = append(classes, chars)
+	}
+
+	return classes
+}
+
+// pick returns a random character of the string.
+func pick(chars string) (byte, error) {
+	i, err := randInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+
+	return chars[i], nil
+}
+
+// randInt returns a uniform random number in [0, n).
+func randInt(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("rand.Int: %w", err)
+	}
+
+	return int(i.Int64()), nil
+}
@@ -0,0 +1,100 @@
+package passgen
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type PassgenTestSuite struct {
+	suite.Suite
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(PassgenTestSuite))
+}
+
+func (s *PassgenTestSuite) Test_Password() {
+	secret, err := Password(DefaultPasswordOptions())
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), secret.Value, 20)
+	// 20 characters of 94 printable ASCII characters
+	assert.InDelta(s.T(), 131.1, secret.Entropy, 0.1)
+
+	// Every enabled class is present even in the shortest password
+	for i := 0; i < 100; i++ {
+		secret, err = Password(PasswordOptions{Length: MinLength, Lower: true, Upper: true, Digits: true, Symbols: true})
+		require.NoError(s.T(), err)
+		assert.True(s.T(), strings.IndexFunc(secret.Value, unicode.IsLower) >= 0, secret.Value)
+		assert.True(s.T(), strings.IndexFunc(secret.Value, unicode.IsUpper) >= 0, secret.Value)
+		assert.True(s.T(), strings.IndexFunc(secret.Value, unicode.IsDigit) >= 0, secret.Value)
+		assert.True(s.T(), strings.ContainsAny(secret.Value, symbolChars), secret.Value)
+	}
+
+	secret, err = Password(PasswordOptions{Length: 64, Digits: true, ExcludeAmbiguous: true})
+	require.NoError(s.T(), err)
+	assert.NotContains(s.T(), secret.Value, "0")
+	assert.NotContains(s.T(), secret.Value, "1")
+	assert.InDelta(s.T(), 64*3, secret.Entropy, 0.001)
+
+	opts := DefaultPasswordOptions()
+	require.NoError(s.T(), opts.SetClasses([]string{"digits", " Upper"}))
+	assert.Equal(s.T(), PasswordOptions{Length: 20, Upper: true, Digits: true}, opts)
+	assert.ErrorIs(s.T(), opts.SetClasses([]string{"emoji"}), ErrInvalidOptions)
+
+	for _, opts := range []PasswordOptions{
+		{Length: MinLength - 1, Lower: true},
+		{Length: MaxLength + 1, Lower: true},
+		{Length: 20},
+	} {
+		_, err = Password(opts)
+		assert.ErrorIs(s.T(), err, ErrInvalidOptions)
+	}
+}
+
+func (s *PassgenTestSuite) Test_Passphrase() {
+	require.Len(s.T(), words, 7776)
+
+	secret, err := Passphrase(DefaultPassphraseOptions())
+	require.NoError(s.T(), err)
+	assert.GreaterOrEqual(s.T(), len(strings.Split(secret.Value, "-")), 6)
+	assert.InDelta(s.T(), 77.5, secret.Entropy, 0.1)
+
+	secret, err = Passphrase(PassphraseOptions{Words: 4, Separator: " ", Capitalize: true, Number: true})
+	require.NoError(s.T(), err)
+	parts := strings.Split(secret.Value, " ")
+	require.Len(s.T(), parts, 4)
+	for _, part := range parts {
+		assert.True(s.T(), unicode.IsUpper(rune(part[0])), part)
+	}
+	assert.True(s.T(), strings.IndexFunc(secret.Value, unicode.IsDigit) >= 0, secret.Value)
+	assert.InDelta(s.T(), 4*12.925+5.32, secret.Entropy, 0.01)
+
+	_, err = Passphrase(PassphraseOptions{Words: MinWords - 1})
+	assert.ErrorIs(s.T(), err, ErrInvalidOptions)
+}
+
+func (s *PassgenTestSuite) Test_Strength() {
+	assert.Equal(s.T(), "weak", Strength(40))
+	assert.Equal(s.T(), "fair", Strength(64))
+	assert.Equal(s.T(), "strong", Strength(77.5))
+	assert.Equal(s.T(), "very strong", Strength(131))
+}
+
+func (s *PassgenTestSuite) Test_Entropy() {
+	assert.Zero(s.T(), Entropy(""))
+	assert.InDelta(s.T(), 8*4.7, Entropy("qwrtzxvn"), 0.1)
+	// The repeated s and the run 123 add a bit each
+	assert.InDelta(s.T(), 8*5.17+3, Entropy("password123"), 0.1)
+	assert.Equal(s.T(), "weak", Strength(Entropy("password123")))
+	assert.InDelta(s.T(), 1*3.32+5, Entropy("111111"), 0.1)
+
+	secret, err := Password(DefaultPasswordOptions())
+	require.NoError(s.T(), err)
+	assert.LessOrEqual(s.T(), Entropy(secret.Value), secret.Entropy+0.001)
+	assert.Equal(s.T(), "very strong", Strength(Entropy(secret.Value)))
+}
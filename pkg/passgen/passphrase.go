@@ -0,0 +1,67 @@
+package passgen
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The large wordlist of the EFF for five dice, https://www.eff.org/dice
+//
+//go:embed eff_large_wordlist.txt
+var wordlist string
+
+// words holds the words of the wordlist.
+var words = strings.Fields(wordlist)
+
+// PassphraseOptions configures the words of a passphrase.
+type PassphraseOptions struct {
+	Words      int    // Number of words
+	Separator  string // Written between the words
+	Capitalize bool   // Capitalize the first letter of every word
+	Number     bool   // Append a random digit to one of the words, for sites that require digits
+}
+
+// DefaultPassphraseOptions returns 6 words separated with dashes, the length recommended by the EFF.
+func DefaultPassphraseOptions() PassphraseOptions {
+	return PassphraseOptions{Words: 6, Separator: "-"}
+}
+
+// Passphrase generates a passphrase of words chosen uniformly from the wordlist, like rolls of five dice.
+func Passphrase(opts PassphraseOptions) (Secret, error) {
+	if opts.Words < MinWords || opts.Words > MaxWords {
+		return Secret{}, fmt.Errorf("%w: words must be between %d and %d", ErrInvalidOptions, MinWords, MaxWords)
+	}
+
+	chosen := make([]string, opts.Words)
+	for i := range chosen {
+		j, err := randInt(len(words))
+		if err != nil {
+			return Secret{}, err
+		}
+		chosen[i] = words[j]
+		if opts.Capitalize {
+			r, size := utf8.DecodeRuneInString(chosen[i])
+			chosen[i] = string(unicode.ToUpper(r)) + chosen[i][size:]
+		}
+	}
+
+	entropy := float64(opts.Words) * math.Log2(float64(len(words)))
+	if opts.Number {
+		i, err := randInt(opts.Words)
+		if err != nil {
+			return Secret{}, err
+		}
+		digit, err := pick(digitChars)
+		if err != nil {
+			return Secret{}, err
+		}
+		chosen[i] += string(digit)
+		entropy += math.Log2(float64(len(digitChars) * opts.Words))
+	}
+
+	return Secret{Value: strings.Join(chosen, opts.Separator), Entropy: entropy}, nil
+}
@@ -0,0 +1,126 @@
+package totp
+
+import (
+	"crypto/sha1" //nolint:gosec // HMAC-SHA1 is the algorithm of RFC 6238 supported by authenticator apps
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Algorithms of the HMAC of the codes of keys.
+const (
+	AlgorithmSHA1   = "SHA1"
+	AlgorithmSHA256 = "SHA256"
+	AlgorithmSHA512 = "SHA512"
+)
+
+// hashes holds the hash functions of the algorithms.
+var hashes = map[string]func() hash.Hash{
+	AlgorithmSHA1:   sha1.New,
+	AlgorithmSHA256: sha256.New,
+	AlgorithmSHA512: sha512.New,
+}
+
+// Key is the secret of an account of another service together with the parameters of its codes,
+// as written in the otpauth URIs of the QR codes shown by services enabling two-factor authentication.
+type Key struct {
+	Secret    string // Base32 secret
+	Algorithm string // HMAC algorithm, SHA1, SHA256 or SHA512
+	Digits    int    // Number of digits in a code, 6 or 8
+	Period    int    // Length of a time step in seconds
+	Issuer    string // Name of the service, empty for a raw secret
+	Account   string // Name of the account, empty for a raw secret
+}
+
+// ParseKey parses a raw base32 secret or an otpauth://totp URI. A raw secret and the parameters missing
+// from the URI get the defaults of authenticator apps: SHA1, 6 digits and 30 second steps.
+func ParseKey(s string) (Key, error) {
+	s = strings.TrimSpace(s)
+	key := Key{Secret: s, Algorithm: AlgorithmSHA1, Digits: Digits, Period: Period}
+
+	if strings.HasPrefix(strings.ToLower(s), "otpauth:") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return Key{}, fmt.Errorf("parse otpauth uri: %w", err)
+		}
+		if !strings.EqualFold(u.Host, "totp") {
+			return Key{}, fmt.Errorf("otpauth uri of type %q is not supported, expected totp", u.Host)
+		}
+
+		label := strings.TrimPrefix(u.Path, "/")
+		if issuer, account, ok := strings.Cut(label, ":"); ok {
+			key.Issuer, key.Account = strings.TrimSpace(issuer), strings.TrimSpace(account)
+		} else {
+			key.Account = label
+		}
+
+		params := u.Query()
+		key.Secret = params.Get("secret")
+		if issuer := params.Get("issuer"); issuer != "" {
+			key.Issuer = issuer
+		}
+		if algorithm := params.Get("algorithm"); algorithm != "" {
+			key.Algorithm = strings.ToUpper(algorithm)
+		}
+		if key.Digits, err = intParam(params, "digits", Digits); err != nil {
+			return Key{}, err
+		}
+		if key.Period, err = intParam(params, "period", Period); err != nil {
+			return Key{}, err
+		}
+	}
+
+	if _, err := decodeSecret(key.Secret); err != nil {
+		return Key{}, err
+	}
+	if _, ok := hashes[key.Algorithm]; !ok {
+		return Key{}, fmt.Errorf("algorithm %q is not supported, expected SHA1, SHA256 or SHA512", key.Algorithm)
+	}
+	if key.Digits != 6 && key.Digits != 8 {
+		return Key{}, fmt.Errorf("%d digits are not supported, expected 6 or 8", key.Digits)
+	}
+	if key.Period <= 0 {
+		return Key{}, fmt.Errorf("period %d is not positive", key.Period)
+	}
+
+	return key, nil
+}
+
+// Generate returns the code of the key for the given time and the time left until the next code.
+func (k Key) Generate(t time.Time) (string, time.Duration, error) {
+	secret, err := decodeSecret(k.Secret)
+	if err != nil {
+		return "", 0, err
+	}
+
+	h, ok := hashes[k.Algorithm]
+	if !ok || k.Period <= 0 {
+		return "", 0, fmt.Errorf("key has invalid parameters")
+	}
+
+	period := int64(k.Period)
+	counter := t.Unix() / period
+	remaining := time.Unix((counter+1)*period, 0).Sub(t)
+
+	return hmacOTP(h, secret, uint64(counter), k.Digits), remaining, nil
+}
+
+// intParam returns the integer parameter of the URI, or the default when it is not set.
+func intParam(params url.Values, name string, def int) (int, error) {
+	v := params.Get(name)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("parameter %s %q is not a number", name, v)
+	}
+
+	return n, nil
+}
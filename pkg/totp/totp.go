@@ -0,0 +1,130 @@
+// Package totp implements time-based one-time passwords as defined in RFC 6238
+// with the parameters supported by common authenticator apps: HMAC-SHA1, 30 second steps and 6 digits.
+// Key generates the codes of other services, which may use SHA-256, SHA-512, 8 digits or other steps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // HMAC-SHA1 is the algorithm of RFC 6238 supported by authenticator apps
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Period     = 30 // Length of a time step in seconds
+	Digits     = 6  // Number of digits in a code
+	Skew       = 1  // Number of adjacent time steps accepted to tolerate clock drift
+	secretSize = 20 // Size of generated secrets in bytes
+)
+
+// ErrInvalidSecret is returned when the secret is not a valid base32 string.
+var ErrInvalidSecret = errors.New("invalid totp secret")
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a new random secret encoded in base32 without padding.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("rand.Read: %w", err)
+	}
+
+	return encoding.EncodeToString(secret), nil
+}
+
+// Step returns the number of the time step the given time belongs to.
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code returns the code of the secret for the given time.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return hotp(key, uint64(Step(t)), Digits), nil
+}
+
+// Verify checks the code against the secret at the given time, accepting the codes of Skew adjacent steps.
+// It returns the time step of the matching code, so the caller can reject codes of already used steps.
+func Verify(secret, code string, t time.Time) (int64, bool, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false, err
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false, nil
+	}
+
+	current := Step(t)
+	for step := current - Skew; step <= current+Skew; step++ {
+		expected := hotp(key, uint64(step), Digits)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// URI builds the otpauth URI of the secret that authenticator apps import, usually from a QR code.
+func URI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(Period))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// decodeSecret decodes a base32 secret ignoring case, spaces and padding.
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := encoding.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+
+	return key, nil
+}
+
+// hotp computes the HOTP value of RFC 4226 for the key and the counter.
+func hotp(key []byte, counter uint64, digits int) string {
+	return hmacOTP(sha1.New, key, counter, digits)
+}
+
+// hmacOTP computes the HOTP value of RFC 4226 for the key and the counter with the hash of the HMAC,
+// RFC 6238 allows SHA-256 and SHA-512 besides SHA-1.
+func hmacOTP(h func() hash.Hash, key []byte, counter uint64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(h, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
@@ -0,0 +1,158 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TOTPTestSuite struct {
+	suite.Suite
+	secret string
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(TOTPTestSuite))
+}
+
+func (s *TOTPTestSuite) SetupSuite() {
+	// The SHA1 secret of the RFC 6238 test vectors
+	s.secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+}
+
+func (s *TOTPTestSuite) Test_RFC6238Vectors() {
+	key, err := decodeSecret(s.secret)
+	require.NoError(s.T(), err)
+
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, expected := range vectors {
+		assert.Equal(s.T(), expected, hotp(key, uint64(Step(time.Unix(unix, 0))), 8), "time %d", unix)
+
+		code, err := Code(s.secret, time.Unix(unix, 0))
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), expected[2:], code, "time %d", unix)
+	}
+}
+
+func (s *TOTPTestSuite) Test_Verify() {
+	now := time.Unix(1234567890, 0)
+	code, err := Code(s.secret, now)
+	require.NoError(s.T(), err)
+
+	step, ok, err := Verify(s.secret, code, now)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), Step(now), step)
+
+	// Codes of the adjacent steps are accepted to tolerate clock drift
+	step, ok, err = Verify(s.secret, code, now.Add(Period*time.Second))
+	require.NoError(s.T(), err)
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), Step(now), step)
+
+	_, ok, err = Verify(s.secret, code, now.Add(2*Period*time.Second))
+	require.NoError(s.T(), err)
+	assert.False(s.T(), ok)
+
+	_, ok, err = Verify(s.secret, "12345", now)
+	require.NoError(s.T(), err)
+	assert.False(s.T(), ok)
+
+	_, _, err = Verify("not base32!", code, now)
+	assert.ErrorIs(s.T(), err, ErrInvalidSecret)
+}
+
+func (s *TOTPTestSuite) Test_GenerateSecret() {
+	secret, err := GenerateSecret()
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), secret, 32)
+
+	other, err := GenerateSecret()
+	require.NoError(s.T(), err)
+	assert.NotEqual(s.T(), secret, other)
+
+	_, err = Code(secret, time.Now())
+	assert.NoError(s.T(), err)
+}
+
+func (s *TOTPTestSuite) Test_URI() {
+	uri, err := url.Parse(URI("PrivateKeeper", "user@example.com", "JBSWY3DPEHPK3PXP"))
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), "otpauth", uri.Scheme)
+	assert.Equal(s.T(), "totp", uri.Host)
+	assert.Equal(s.T(), "/PrivateKeeper:user@example.com", uri.Path)
+	assert.Equal(s.T(), "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(s.T(), "PrivateKeeper", uri.Query().Get("issuer"))
+	assert.Equal(s.T(), "6", uri.Query().Get("digits"))
+}
+
+func (s *TOTPTestSuite) Test_KeyRFC6238Vectors() {
+	sha256Secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012"))
+	sha512Secret := base32.StdEncoding.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234"))
+
+	for _, tc := range []struct {
+		key      Key
+		unix     int64
+		expected string
+	}{
+		{Key{Secret: s.secret, Algorithm: AlgorithmSHA1, Digits: 8, Period: 30}, 59, "94287082"},
+		{Key{Secret: sha256Secret, Algorithm: AlgorithmSHA256, Digits: 8, Period: 30}, 59, "46119246"},
+		{Key{Secret: sha512Secret, Algorithm: AlgorithmSHA512, Digits: 8, Period: 30}, 59, "90693936"},
+		{Key{Secret: sha256Secret, Algorithm: AlgorithmSHA256, Digits: 8, Period: 30}, 1111111109, "68084774"},
+		{Key{Secret: sha512Secret, Algorithm: AlgorithmSHA512, Digits: 8, Period: 30}, 1111111109, "25091201"},
+	} {
+		code, remaining, err := tc.key.Generate(time.Unix(tc.unix, 0))
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), tc.expected, code, "%s at %d", tc.key.Algorithm, tc.unix)
+		assert.Equal(s.T(), time.Duration(30-tc.unix%30)*time.Second, remaining)
+	}
+}
+
+func (s *TOTPTestSuite) Test_ParseKey() {
+	key, err := ParseKey(" jbsw y3dp ehpk 3pxp ")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), Key{Secret: "jbsw y3dp ehpk 3pxp", Algorithm: AlgorithmSHA1, Digits: 6, Period: 30}, key)
+
+	key, err = ParseKey("otpauth://totp/ACME%20Co:ops@example.com?secret=" + s.secret + "&issuer=ACME&algorithm=sha256&digits=8&period=60")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), Key{
+		Secret: s.secret, Algorithm: AlgorithmSHA256, Digits: 8, Period: 60, Issuer: "ACME", Account: "ops@example.com",
+	}, key)
+
+	code, remaining, err := key.Generate(time.Unix(90, 0))
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), code, 8)
+	assert.Equal(s.T(), 30*time.Second, remaining)
+
+	// The URI of the two-factor authentication of the keeper itself is accepted
+	key, err = ParseKey(URI("PrivateKeeper", "ivan", s.secret))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "PrivateKeeper", key.Issuer)
+	assert.Equal(s.T(), "ivan", key.Account)
+
+	for _, invalid := range []string{
+		"",
+		"not base32!",
+		"otpauth://hotp/ACME?secret=" + s.secret,
+		"otpauth://totp/ACME?secret=" + s.secret + "&digits=7",
+		"otpauth://totp/ACME?secret=" + s.secret + "&algorithm=MD5",
+		"otpauth://totp/ACME?secret=" + s.secret + "&period=0",
+		"otpauth://totp/ACME",
+	} {
+		_, err = ParseKey(invalid)
+		assert.Error(s.T(), err, invalid)
+	}
+}